@@ -0,0 +1,96 @@
+// Command workflow-lint checks workflow templates for problems that would otherwise only
+// surface at runtime: unknown plugin types, plugin configurations that cannot be built,
+// references to missing node templates, dependency cycles, and malformed
+// workflow_template_v2 graphs. It prints every issue with its path and exits with status
+// 1 if any were found.
+//
+// Usage:
+//
+//	workflow-lint [-db] [-json] [templates.json ...]
+//
+// Each file holds a JSON object with the keys nodeTemplates, workflowTemplates and
+// workflowTemplatesV2. Without files the templates in the database are linted, reading
+// the same environment as the server. With -db the files are linted together with the
+// database, and a template in a file replaces the stored template with the same ID, so
+// a change can be checked before it is deployed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+
+	"github.com/OpenNSW/nsw/internal/config"
+	"github.com/OpenNSW/nsw/internal/database"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/lint"
+)
+
+func main() {
+	useDB := flag.Bool("db", false, "lint the templates in the database (default when no files are given)")
+	asJSON := flag.Bool("json", false, "print the issues as JSON")
+	flag.Parse()
+	files := flag.Args()
+
+	// Building the task factory logs at info level; keep it out of the report.
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	ctx := context.Background()
+	var bundle lint.Bundle
+	var factory plugin.TaskFactory
+	if *useDB || len(files) == 0 {
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalf("failed to load configuration: %v", err)
+		}
+		db, err := database.New(cfg.Database)
+		if err != nil {
+			log.Fatalf("failed to connect to database: %v", err)
+		}
+		defer func() {
+			if err := database.Close(db); err != nil {
+				log.Printf("failed to close database: %v", err)
+			}
+		}()
+		if bundle, err = lint.Load(ctx, db); err != nil {
+			log.Fatalf("failed to load templates: %v", err)
+		}
+		factory = plugin.NewTaskFactory(cfg, db, nil)
+	} else {
+		// Plugin constructors only parse their configuration, so no services are needed.
+		factory = plugin.NewTaskFactory(&config.Config{}, nil, nil)
+	}
+
+	for _, file := range files {
+		fromFile, err := lint.LoadFile(file)
+		if err != nil {
+			log.Fatalf("failed to load templates: %v", err)
+		}
+		bundle.Merge(fromFile)
+	}
+
+	issues := lint.NewLinter(factory).Lint(ctx, bundle)
+	if *asJSON {
+		if issues == nil {
+			issues = []lint.Issue{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			log.Fatalf("failed to encode issues: %v", err)
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+		fmt.Printf("checked %d node templates, %d workflow templates and %d workflow_template_v2 definitions: %d issues\n",
+			len(bundle.NodeTemplates), len(bundle.WorkflowTemplates), len(bundle.WorkflowTemplatesV2), len(issues))
+	}
+	if len(issues) > 0 {
+		os.Exit(1)
+	}
+}
@@ -5,19 +5,32 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/OpenNSW/nsw/internal/audit"
 	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/auth/revocation"
 	"github.com/OpenNSW/nsw/internal/config"
 	"github.com/OpenNSW/nsw/internal/database"
+	"github.com/OpenNSW/nsw/internal/form"
 	"github.com/OpenNSW/nsw/internal/middleware"
-	"github.com/OpenNSW/nsw/internal/payments"
+	"github.com/OpenNSW/nsw/internal/organization"
+	"github.com/OpenNSW/nsw/internal/paymentsv2"
+	mockpayment "github.com/OpenNSW/nsw/internal/paymentsv2/providers/mock"
 	"github.com/OpenNSW/nsw/internal/profile/user"
 	taskmanager "github.com/OpenNSW/nsw/internal/task/manager"
+	"github.com/OpenNSW/nsw/internal/task/persistence"
 	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/task/policy"
+	"github.com/OpenNSW/nsw/internal/task/sla"
 	"github.com/OpenNSW/nsw/internal/temporal"
 	"github.com/OpenNSW/nsw/internal/uploads"
 	"github.com/OpenNSW/nsw/internal/uploads/drivers"
+	"github.com/OpenNSW/nsw/internal/workflow/lint"
 	"github.com/OpenNSW/nsw/internal/workflow/router"
 	workflowruntime "github.com/OpenNSW/nsw/internal/workflow/runtime"
 	"github.com/OpenNSW/nsw/internal/workflow/service"
@@ -69,17 +82,69 @@ func Build(ctx context.Context, cfg *config.Config) (*App, error) {
 		return nil, fmt.Errorf("database health check failed: %w", err)
 	}
 
-	paymentRepo := payments.NewPaymentRepository(db)
-	paymentService := payments.NewPaymentService(paymentRepo)
+	// Storage holds uploaded documents and issued payment receipts.
+	storageDriver, err := uploads.NewStorageFromConfig(ctx, cfg.Storage)
+	if err != nil {
+		_ = database.Close(db)
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	paymentRegistry, err := paymentsv2.NewRegistry(cfg.Payments.MethodsConfigPath, map[string]paymentsv2.PaymentProvider{
+		mockpayment.ProviderID: mockpayment.NewProvider(),
+	})
+	if err != nil {
+		_ = database.Close(db)
+		return nil, fmt.Errorf("failed to load payment methods: %w", err)
+	}
+	var (
+		exchangeRates *paymentsv2.FileExchangeRates
+		rateProvider  paymentsv2.ExchangeRateProvider
+	)
+	if cfg.Payments.ExchangeRatesPath != "" {
+		exchangeRates, err = paymentsv2.NewFileExchangeRates(cfg.Payments.ExchangeRatesPath)
+		if err != nil {
+			_ = database.Close(db)
+			return nil, fmt.Errorf("failed to load exchange rates: %w", err)
+		}
+		rateProvider = exchangeRates
+	}
+	businessCalendar, err := sla.NewFileCalendar(cfg.Tasks.BusinessCalendarPath)
+	if err != nil {
+		_ = database.Close(db)
+		return nil, fmt.Errorf("failed to load business calendar: %w", err)
+	}
+	// Every state-changing operation is recorded in the append-only audit log.
+	auditStore := audit.NewStore(db)
+	var checkpointSigner *audit.Signer
+	if cfg.Audit.CheckpointKeyPath != "" {
+		checkpointSigner, err = audit.LoadSigner(cfg.Audit.CheckpointKeyID, cfg.Audit.CheckpointKeyPath)
+		if err != nil {
+			_ = database.Close(db)
+			return nil, fmt.Errorf("failed to load audit checkpoint key: %w", err)
+		}
+	}
+
+	paymentRepo := paymentsv2.NewPaymentRepository(db)
+	paymentService := paymentsv2.NewPaymentService(paymentRepo, paymentRegistry, rateProvider, storageDriver, auditStore)
 
 	factory := plugin.NewTaskFactory(cfg, db, paymentService)
-	tm, err := taskmanager.NewTaskManager(db, factory)
+	tm, err := taskmanager.NewTaskManager(db, factory, auditStore, taskmanager.CacheConfig{
+		Capacity: cfg.Tasks.ContainerCacheCapacity,
+		IdleTTL:  cfg.Tasks.ContainerCacheIdleTTL,
+	})
 	if err != nil {
 		_ = database.Close(db)
 		return nil, fmt.Errorf("failed to create task manager: %w", err)
 	}
+	// Verified gateway webhooks advance PAYMENT tasks as a system actor.
+	paymentService.RegisterEventHandler(taskmanager.NewPaymentEventHandler(tm))
+	// Tasks whose node template declares an SLA are timed in the states it covers.
+	slaStore := sla.NewStore(db, businessCalendar)
+	tm.RegisterStateObserver(slaStore)
 
 	templateService := service.NewTemplateService(db)
+	// Templates authored through the admin API are linted with the same factory that runs them.
+	templateAdminRouter := router.NewTemplateAdminRouter(service.NewTemplateAdminService(db, lint.NewLinter(factory), auditStore))
 	chaService := service.NewCHAService(db)
 	hsCodeService := service.NewHSCodeService(db)
 
@@ -89,10 +154,16 @@ func Build(ctx context.Context, cfg *config.Config) (*App, error) {
 		return nil, fmt.Errorf("failed to create temporal client: %w", err)
 	}
 
-	consignmentService := service.NewConsignmentService(db, templateService)
-	consignmentRouter := router.NewConsignmentRouter(consignmentService, chaService)
+	consignmentService := service.NewConsignmentService(db, templateService, auditStore)
 
-	workflowRuntime, err := workflowruntime.NewRuntime(temporalClient, tm, templateService, consignmentService)
+	userProfileService := user.NewService(db)
+	preConsignmentService := service.NewPreConsignmentService(db, templateService, userProfileService)
+
+	// Consignments and pre-consignments share the runtime; each completed workflow is handed to
+	// the service that owns it.
+	upstreams := workflowruntime.Upstreams{consignmentService, preConsignmentService}
+	workflowInstanceService := service.NewWorkflowInstanceService(db, auditStore)
+	workflowRuntime, err := workflowruntime.NewRuntime(temporalClient, tm, templateService, workflowInstanceService, upstreams)
 	if err != nil {
 		temporalClient.Close()
 		_ = database.Close(db)
@@ -106,28 +177,33 @@ func Build(ctx context.Context, cfg *config.Config) (*App, error) {
 		_ = database.Close(db)
 		return nil, fmt.Errorf("failed to register workflow manager with consignment service: %w", registererr)
 	}
-	// TODO: Pre-consignment wiring is intentionally disabled until it is migrated to Temporal.
-	// preConsignmentService := service.NewPreConsignmentService(db, templateService, wm)
-	// preConsignmentRouter := router.NewPreConsignmentRouter(preConsignmentService)
-
-	hsCodeRouter := router.NewHSCodeRouter(hsCodeService)
-	chaRouter := router.NewCHARouter(chaService)
-
-	storageDriver, err := uploads.NewStorageFromConfig(ctx, cfg.Storage)
-	if err != nil {
+	if err := preConsignmentService.RegisterWorkflowManager(workflowRuntime.Manager()); err != nil {
 		_ = workflowRuntime.Close()
 		temporalClient.Close()
 		_ = database.Close(db)
-		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+		return nil, fmt.Errorf("failed to register workflow manager with pre-consignment service: %w", err)
 	}
-	uploadService := uploads.NewUploadService(storageDriver)
-	uploadHandler := uploads.NewHTTPHandler(uploadService)
+	if err := workflowInstanceService.RegisterWorkflowManager(workflowRuntime.Manager()); err != nil {
+		_ = workflowRuntime.Close()
+		temporalClient.Close()
+		_ = database.Close(db)
+		return nil, fmt.Errorf("failed to register workflow manager with workflow instance service: %w", err)
+	}
+	workflowInstanceRouter := router.NewWorkflowInstanceRouter(workflowInstanceService)
+
+	hsCodeRouter := router.NewHSCodeRouter(hsCodeService)
+	chaRouter := router.NewCHARouter(chaService)
 
-	paymentHandler := payments.NewHTTPHandler(paymentService)
+	uploadService := uploads.NewUploadService(storageDriver, auditStore)
+	uploadHandler := uploads.NewHTTPHandler(uploadService)
 
-	userProfileService := user.NewService(db)
+	webhookAuth := paymentsv2.NewWebhookAuthenticator(paymentRegistry, paymentsv2.NewNonceStore(db), paymentsv2.NewWebhookAuditRepository(db))
+	userHandler := user.NewHTTPHandler(userProfileService)
+	orgHandler := organization.NewHTTPHandler(organization.NewService(db))
+	auditHandler := audit.NewHTTPHandler(auditStore)
+	slaHandler := sla.NewHTTPHandler(slaStore)
 
-	authManager, err := auth.NewManager(userProfileService, cfg.Auth)
+	authManager, err := auth.NewManager(userProfileService, cfg.Auth, auth.WithRevocationStore(revocation.NewStore(db)))
 	if err != nil {
 		_ = workflowRuntime.Close()
 		temporalClient.Close()
@@ -159,10 +235,28 @@ func Build(ctx context.Context, cfg *config.Config) (*App, error) {
 	// smsChannel := channels.NewSMSChannel(...)
 	// notificationManager.RegisterSMSChannel(smsChannel)
 
-	tmHandler := taskmanager.NewHTTPHandler(tm)
+	taskStore, err := persistence.NewTaskStore(db)
+	if err != nil {
+		_ = workflowRuntime.Close()
+		temporalClient.Close()
+		_ = authManager.Close()
+		_ = database.Close(db)
+		return nil, fmt.Errorf("failed to create task store: %w", err)
+	}
+	taskPolicy := policy.New(policy.DefaultRules(cfg.Tasks.OGAClientIDs), taskStore, policy.NewDBPartyResolver(db))
+	tmHandler := taskmanager.NewHTTPHandler(tm, taskPolicy)
+	paymentHandler := paymentsv2.NewHTTPHandler(paymentService, webhookAuth, taskPolicy)
+	consignmentRouter := router.NewConsignmentRouter(consignmentService, taskPolicy)
+	preConsignmentRouter := router.NewPreConsignmentRouter(preConsignmentService, taskPolicy)
+	formHandler := form.NewHTTPHandler(form.NewFormService(db))
 
 	// withAuth wraps an individual handler with the authentication middleware.
 	withAuth := authManager.Middleware()
+	// withPermission additionally rejects M2M clients that were not granted permission
+	// in the client permissions file. Users are passed through to the handler's own checks.
+	withPermission := func(permission auth.Permission, handler http.HandlerFunc) http.Handler {
+		return withAuth(auth.RequirePermission(permission)(handler))
+	}
 
 	mux := http.NewServeMux()
 
@@ -194,29 +288,93 @@ func Build(ctx context.Context, cfg *config.Config) (*App, error) {
 		})
 	})
 
+	// Metrics are public like the health check, for Prometheus to scrape; they expose
+	// counters only.
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := taskmanager.WriteCacheMetrics(w, tm.CacheStats()); err != nil {
+			slog.Error("failed to write metrics", "error", err)
+		}
+	})
+
 	// API v1 routes. Each handler is individually wrapped with auth,
 	// so public or differently-authenticated routes can be added
 	// alongside these without restructuring the mux.
-	mux.Handle("POST /api/v1/tasks", withAuth(http.HandlerFunc(tmHandler.HandleExecuteTask)))
-	mux.Handle("GET /api/v1/tasks/{id}", withAuth(http.HandlerFunc(tmHandler.HandleGetTask)))
-	mux.Handle("GET /api/v1/hscodes", withAuth(http.HandlerFunc(hsCodeRouter.HandleGetAllHSCodes)))
-	mux.Handle("GET /api/v1/chas", withAuth(http.HandlerFunc(chaRouter.HandleGetCHAs)))
-	mux.Handle("POST /api/v1/consignments", withAuth(http.HandlerFunc(consignmentRouter.HandleCreateConsignment)))
-	mux.Handle("GET /api/v1/consignments/{id}", withAuth(http.HandlerFunc(consignmentRouter.HandleGetConsignmentByID)))
-	mux.Handle("PUT /api/v1/consignments/{id}", withAuth(http.HandlerFunc(consignmentRouter.HandleInitializeConsignment)))
-	mux.Handle("GET /api/v1/consignments", withAuth(http.HandlerFunc(consignmentRouter.HandleGetConsignments)))
-	// TODO: Add pre-consignment routes once migrated to Temporal.
-	// mux.Handle("POST /api/v1/pre-consignments", withAuth(http.HandlerFunc(preConsignmentRouter.HandleCreatePreConsignment)))
-	// mux.Handle("GET /api/v1/pre-consignments/{preConsignmentId}", withAuth(http.HandlerFunc(preConsignmentRouter.HandleGetPreConsignmentByID)))
-	// mux.Handle("GET /api/v1/pre-consignments", withAuth(http.HandlerFunc(preConsignmentRouter.HandleGetTraderPreConsignments)))
-	mux.Handle("POST /api/v1/uploads", withAuth(http.HandlerFunc(uploadHandler.Upload)))
-	mux.Handle("GET /api/v1/uploads/{key}", withAuth(http.HandlerFunc(uploadHandler.Download)))
-	mux.Handle("DELETE /api/v1/uploads/{key}", withAuth(http.HandlerFunc(uploadHandler.Delete)))
+	mux.Handle("POST /api/v1/auth/logout", withAuth(http.HandlerFunc(authManager.HandleLogout)))
+	mux.Handle("POST /api/v1/auth/logout-everywhere", withAuth(http.HandlerFunc(authManager.HandleLogoutEverywhere)))
+	mux.Handle("GET /api/v1/me", withAuth(http.HandlerFunc(userHandler.HandleGetMe)))
+	mux.Handle("PUT /api/v1/me", withAuth(http.HandlerFunc(userHandler.HandleUpdateMe)))
+	mux.Handle("GET /api/v1/me/history", withAuth(http.HandlerFunc(userHandler.HandleGetMeHistory)))
+	mux.Handle("POST /api/v1/organizations", withAuth(http.HandlerFunc(orgHandler.HandleCreateOrganization)))
+	mux.Handle("GET /api/v1/organizations", withAuth(http.HandlerFunc(orgHandler.HandleListOrganizations)))
+	mux.Handle("GET /api/v1/organizations/{orgId}", withAuth(http.HandlerFunc(orgHandler.HandleGetOrganization)))
+	mux.Handle("PUT /api/v1/organizations/{orgId}/members/{userId}", withAuth(http.HandlerFunc(orgHandler.HandleUpdateMember)))
+	mux.Handle("DELETE /api/v1/organizations/{orgId}/members/{userId}", withAuth(http.HandlerFunc(orgHandler.HandleRemoveMember)))
+	mux.Handle("POST /api/v1/organizations/{orgId}/invitations", withAuth(http.HandlerFunc(orgHandler.HandleCreateInvitation)))
+	mux.Handle("GET /api/v1/organizations/{orgId}/invitations", withAuth(http.HandlerFunc(orgHandler.HandleListInvitations)))
+	mux.Handle("DELETE /api/v1/organizations/{orgId}/invitations/{invitationId}", withAuth(http.HandlerFunc(orgHandler.HandleRevokeInvitation)))
+	mux.Handle("POST /api/v1/invitations/accept", withAuth(http.HandlerFunc(orgHandler.HandleAcceptInvitation)))
+	mux.Handle("POST /api/v1/admin/principals/{subject}/logout-everywhere", withPermission(auth.PermissionAdmin, authManager.HandleAdminLogoutEverywhere))
+	mux.Handle("POST /api/v1/tasks", withPermission(auth.PermissionTasksExecute, tmHandler.HandleExecuteTask))
+	mux.Handle("GET /api/v1/tasks/{id}", withPermission(auth.PermissionTasksRead, tmHandler.HandleGetTask))
+	mux.Handle("GET /api/v1/hscodes", withPermission(auth.PermissionReferenceRead, hsCodeRouter.HandleGetAllHSCodes))
+	mux.Handle("GET /api/v1/chas", withPermission(auth.PermissionReferenceRead, chaRouter.HandleGetCHAs))
+	mux.Handle("POST /api/v1/consignments", withPermission(auth.PermissionConsignmentsWrite, consignmentRouter.HandleCreateConsignment))
+	mux.Handle("GET /api/v1/consignments/{id}", withPermission(auth.PermissionConsignmentsRead, consignmentRouter.HandleGetConsignmentByID))
+	mux.Handle("PUT /api/v1/consignments/{id}", withPermission(auth.PermissionConsignmentsWrite, consignmentRouter.HandleInitializeConsignment))
+	mux.Handle("GET /api/v1/consignments", withPermission(auth.PermissionConsignmentsRead, consignmentRouter.HandleGetConsignments))
+	mux.Handle("POST /api/v1/consignments/{id}/delegations", withAuth(http.HandlerFunc(orgHandler.HandleCreateDelegation)))
+	mux.Handle("GET /api/v1/consignments/{id}/delegations", withAuth(http.HandlerFunc(orgHandler.HandleListDelegations)))
+	mux.Handle("DELETE /api/v1/consignments/{id}/delegations/{delegationId}", withAuth(http.HandlerFunc(orgHandler.HandleRevokeDelegation)))
+	mux.Handle("POST /api/v1/pre-consignments", withPermission(auth.PermissionConsignmentsWrite, preConsignmentRouter.HandleCreatePreConsignment))
+	mux.Handle("GET /api/v1/pre-consignments/{preConsignmentId}", withPermission(auth.PermissionConsignmentsRead, preConsignmentRouter.HandleGetPreConsignmentByID))
+	mux.Handle("GET /api/v1/pre-consignments", withPermission(auth.PermissionConsignmentsRead, preConsignmentRouter.HandleGetTraderPreConsignments))
+	mux.Handle("POST /api/v1/uploads", withPermission(auth.PermissionUploadsWrite, uploadHandler.Upload))
+	mux.Handle("GET /api/v1/uploads/{key}", withPermission(auth.PermissionUploadsRead, uploadHandler.Download))
+	mux.Handle("DELETE /api/v1/uploads/{key}", withPermission(auth.PermissionUploadsWrite, uploadHandler.Delete))
+	mux.Handle("POST /api/v1/admin/forms/{formId}/versions", withPermission(auth.PermissionAdmin, formHandler.HandlePublishVersion))
+	mux.Handle("GET /api/v1/admin/forms/{formId}/versions", withPermission(auth.PermissionAdmin, formHandler.HandleListVersions))
+	mux.Handle("GET /api/v1/admin/forms/{formId}/tasks", withPermission(auth.PermissionAdmin, tmHandler.HandleListFormTasks))
+	mux.Handle("GET /api/v1/admin/forms", withPermission(auth.PermissionAdmin, formHandler.HandleListForms))
+	mux.Handle("POST /api/v1/admin/forms", withPermission(auth.PermissionAdmin, formHandler.HandleCreateForm))
+	mux.Handle("GET /api/v1/admin/forms/{formId}", withPermission(auth.PermissionAdmin, formHandler.HandleGetForm))
+	mux.Handle("PUT /api/v1/admin/forms/{formId}", withPermission(auth.PermissionAdmin, formHandler.HandleUpdateForm))
+	mux.Handle("DELETE /api/v1/admin/forms/{formId}", withPermission(auth.PermissionAdmin, formHandler.HandleDeleteForm))
+	mux.Handle("GET /api/v1/admin/node-templates", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleListNodeTemplates))
+	mux.Handle("POST /api/v1/admin/node-templates", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleCreateNodeTemplate))
+	mux.Handle("GET /api/v1/admin/node-templates/{id}", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleGetNodeTemplate))
+	mux.Handle("PUT /api/v1/admin/node-templates/{id}", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleUpdateNodeTemplate))
+	mux.Handle("DELETE /api/v1/admin/node-templates/{id}", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleDeleteNodeTemplate))
+	mux.Handle("POST /api/v1/admin/node-templates/{id}/publish", withPermission(auth.PermissionAdmin, templateAdminRouter.HandlePublishNodeTemplate))
+	mux.Handle("GET /api/v1/admin/workflow-templates", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleListWorkflowTemplates))
+	mux.Handle("POST /api/v1/admin/workflow-templates", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleCreateWorkflowTemplate))
+	mux.Handle("GET /api/v1/admin/workflow-templates/{id}", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleGetWorkflowTemplate))
+	mux.Handle("PUT /api/v1/admin/workflow-templates/{id}", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleUpdateWorkflowTemplate))
+	mux.Handle("DELETE /api/v1/admin/workflow-templates/{id}", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleDeleteWorkflowTemplate))
+	mux.Handle("POST /api/v1/admin/workflow-templates/{id}/publish", withPermission(auth.PermissionAdmin, templateAdminRouter.HandlePublishWorkflowTemplate))
+	mux.Handle("GET /api/v1/admin/workflow-templates/{id}/instances", withPermission(auth.PermissionAdmin, workflowInstanceRouter.HandleListWorkflowInstances))
+	mux.Handle("POST /api/v1/admin/workflow-migrations", withPermission(auth.PermissionAdmin, workflowInstanceRouter.HandleMigrateWorkflows))
+	mux.Handle("GET /api/v1/admin/workflow-template-maps", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleListWorkflowTemplateMaps))
+	mux.Handle("POST /api/v1/admin/workflow-template-maps", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleCreateWorkflowTemplateMap))
+	mux.Handle("PUT /api/v1/admin/workflow-template-maps/{id}", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleUpdateWorkflowTemplateMap))
+	mux.Handle("DELETE /api/v1/admin/workflow-template-maps/{id}", withPermission(auth.PermissionAdmin, templateAdminRouter.HandleDeleteWorkflowTemplateMap))
+	mux.Handle("GET /api/v1/admin/audit", withPermission(auth.PermissionAdmin, auditHandler.HandleQuery))
+	mux.Handle("GET /api/v1/admin/reports/sla", withPermission(auth.PermissionAdmin, slaHandler.HandleReport))
+	mux.Handle("GET /api/v1/admin/task-cache", withPermission(auth.PermissionAdmin, tmHandler.HandleGetTaskCache))
+	mux.Handle("GET /api/v1/payments/methods", withPermission(auth.PermissionPaymentsRead, paymentHandler.HandleListMethods))
+	mux.Handle("POST /api/v1/payments/fees/dry-run", withPermission(auth.PermissionPaymentsRead, tmHandler.HandlePaymentFeeDryRun))
+	mux.Handle("POST /api/v1/payments/{providerId}/settlements", withPermission(auth.PermissionPaymentsWrite, paymentHandler.HandleImportSettlement))
+	mux.Handle("GET /api/v1/payments/settlements/{reportId}", withPermission(auth.PermissionPaymentsWrite, paymentHandler.HandleGetSettlementReport))
+	mux.Handle("POST /api/v1/payments/transactions/{referenceNumber}/refunds", withPermission(auth.PermissionPaymentsWrite, paymentHandler.HandleRefund))
+	mux.Handle("POST /api/v1/payments/transactions/{referenceNumber}/refunds/{refundId}/approve", withPermission(auth.PermissionPaymentsWrite, paymentHandler.HandleApproveRefund))
+	mux.Handle("POST /api/v1/payments/transactions/{referenceNumber}/refunds/{refundId}/reject", withPermission(auth.PermissionPaymentsWrite, paymentHandler.HandleRejectRefund))
+	mux.Handle("GET /api/v1/payments/transactions/{referenceNumber}/refunds", withPermission(auth.PermissionPaymentsRead, paymentHandler.HandleListRefunds))
+	mux.Handle("GET /api/v1/payments/transactions/{referenceNumber}/receipt", withPermission(auth.PermissionPaymentsRead, paymentHandler.HandleDownloadReceipt))
 
 	// External Webhooks bypass standard JWT auth.
-	// They should use webhook signatures, implemented in the handler directly or via specialized middleware.
-	mux.Handle("POST /api/v1/payments/webhook", http.HandlerFunc(paymentHandler.HandleWebhook))
-	mux.Handle("POST /api/v1/payments/validate", http.HandlerFunc(paymentHandler.HandleValidateReference))
+	// The handler verifies the provider's HMAC signature, timestamp and nonce instead.
+	mux.Handle("POST /api/v1/payments/{providerId}/webhook", http.HandlerFunc(paymentHandler.HandleWebhook))
+	mux.Handle("POST /api/v1/payments/{providerId}/validate", http.HandlerFunc(paymentHandler.HandleValidateReference))
 
 	// When using local storage, these endpoints serve as mocks for S3.
 	if _, ok := storageDriver.(*drivers.LocalFSDriver); ok {
@@ -224,16 +382,70 @@ func Build(ctx context.Context, cfg *config.Config) (*App, error) {
 		mux.HandleFunc("GET /api/v1/uploads/{key}/content", uploadHandler.DownloadContent)
 	}
 
-	handler := middleware.CORS(&cfg.CORS)(mux)
+	handler := middleware.CORS(&cfg.CORS)(audit.RequestMetadata(cfg.Server.TrustProxyHeaders)(mux))
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
 		Handler: handler,
 	}
 
+	// payment_methods.json, exchange_rates.json and the business calendar are reloaded on SIGHUP
+	// without restarting the server.
+	reloadSignals := make(chan os.Signal, 1)
+	signal.Notify(reloadSignals, syscall.SIGHUP)
+	go func() {
+		for range reloadSignals {
+			if err := paymentRegistry.Reload(); err != nil {
+				slog.Error("failed to reload payment methods, keeping previous configuration", "error", err)
+			}
+			if exchangeRates != nil {
+				if err := exchangeRates.Reload(); err != nil {
+					slog.Error("failed to reload exchange rates, keeping previous rates", "error", err)
+				}
+			}
+			if err := businessCalendar.Reload(); err != nil {
+				slog.Error("failed to reload business calendar, keeping previous calendar", "error", err)
+			}
+		}
+	}()
+
+	// PENDING payments whose webhook never arrived are recovered or expired in the background.
+	var reconciliationWorker *paymentsv2.ReconciliationWorker
+	if cfg.Payments.ReconcileInterval > 0 {
+		reconciliationWorker = paymentsv2.NewReconciliationWorker(paymentService, cfg.Payments.ReconcileInterval)
+		reconciliationWorker.Start(ctx)
+	}
+
+	// The head of the audit chain is periodically pinned by a signed checkpoint.
+	var checkpointWorker *audit.CheckpointWorker
+	if checkpointSigner != nil {
+		checkpointWorker = audit.NewCheckpointWorker(db, checkpointSigner, cfg.Audit.CheckpointInterval)
+		checkpointWorker.Start(ctx)
+	}
+
+	// Tasks past their SLA deadline are escalated in the background.
+	var slaWorker *sla.Worker
+	if cfg.Tasks.SLACheckInterval > 0 {
+		slaMonitor := sla.NewMonitor(slaStore, taskmanager.NewSLATaskActions(tm), notificationManager, auditStore)
+		slaWorker = sla.NewWorker(slaMonitor, cfg.Tasks.SLACheckInterval)
+		slaWorker.Start(ctx)
+	}
+
 	closeFn := func() error {
 		var closeErrs []error
 
+		signal.Stop(reloadSignals)
+		close(reloadSignals)
+		if reconciliationWorker != nil {
+			reconciliationWorker.Stop()
+		}
+		if checkpointWorker != nil {
+			checkpointWorker.Stop()
+		}
+		if slaWorker != nil {
+			slaWorker.Stop()
+		}
+
 		if err := workflowRuntime.Close(); err != nil {
 			closeErrs = append(closeErrs, fmt.Errorf("failed to close workflow runtime: %w", err))
 		}
@@ -0,0 +1,191 @@
+// Package audit keeps an append-only trail of state-changing operations: consignment
+// creation and initialization, task actions (including OGA reviews), uploads and deletes,
+// and payment status changes.
+//
+// Services describe what changed in an Entry and hand it to Record, which stamps it with
+// the principal and HTTP request (see RequestMetadata) found in the context. A change made
+// in the database is recorded with RecordTx in the transaction that makes it, so the two
+// commit or roll back together; other operations fail when their entry cannot be written.
+//
+// Entries form a hash chain that is periodically pinned by signed checkpoints; Verify walks
+// the chain and reports the first broken link.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// Action is the kind of operation an entry records.
+type Action string
+
+const (
+	ActionConsignmentCreate     Action = "consignment.create"
+	ActionConsignmentInitialize Action = "consignment.initialize"
+	// ActionTaskExecute is an action executed on a task through TaskManager.ExecuteTask.
+	ActionTaskExecute Action = "task.execute"
+	// ActionOGAReview is a task action by which an OGA verifies or returns a submission.
+	ActionOGAReview           Action = "oga.review"
+	ActionUploadCreate        Action = "upload.create"
+	ActionUploadDelete        Action = "upload.delete"
+	ActionPaymentStatusChange Action = "payment.status_change"
+	// ActionTemplatePublish is the publishing of a node or workflow template through the admin API.
+	ActionTemplatePublish Action = "template.publish"
+	// ActionTemplateMapChange is a change to which workflow template an HS code and flow use.
+	ActionTemplateMapChange Action = "template_map.change"
+	// ActionWorkflowMigrate is the move of a running workflow to another workflow template.
+	ActionWorkflowMigrate Action = "workflow.migrate"
+	// ActionTaskEscalate is an escalation fired by the SLA monitor for a task past its deadline.
+	ActionTaskEscalate Action = "task.escalate"
+)
+
+// ActorKind is the kind of principal that performed an operation.
+type ActorKind string
+
+const (
+	ActorUser   ActorKind = "user"
+	ActorClient ActorKind = "client"
+	ActorSystem ActorKind = "system"
+	// ActorProvider is a payment provider reporting through a verified webhook.
+	ActorProvider  ActorKind = "provider"
+	ActorAnonymous ActorKind = "anonymous"
+)
+
+// Resource types of entries.
+const (
+	ResourceConsignment      = "consignment"
+	ResourceTask             = "task"
+	ResourceUpload           = "upload"
+	ResourcePayment          = "payment"
+	ResourceNodeTemplate     = "workflow_node_template"
+	ResourceWorkflowTemplate = "workflow_template"
+	ResourceTemplateMap      = "workflow_template_map"
+	ResourceWorkflow         = "workflow"
+)
+
+// Entry is one record of the audit log.
+type Entry struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	OccurredAt time.Time `gorm:"column:occurred_at;not null" json:"occurredAt"`
+	Action     Action    `gorm:"column:action;not null" json:"action"`
+	ActorKind  ActorKind `gorm:"column:actor_kind;not null" json:"actorKind"`
+	ActorID    string    `gorm:"column:actor_id" json:"actorId"`
+	IP         string    `gorm:"column:ip" json:"ip,omitempty"`
+	RequestID  string    `gorm:"column:request_id" json:"requestId,omitempty"`
+	// ConsignmentID is the consignment or pre-consignment the operation belongs to. For
+	// entries with a TaskID it is resolved from the task when left empty.
+	ConsignmentID string `gorm:"column:consignment_id" json:"consignmentId,omitempty"`
+	TaskID        string `gorm:"column:task_id" json:"taskId,omitempty"`
+	ResourceType  string `gorm:"column:resource_type;not null" json:"resourceType"`
+	ResourceID    string `gorm:"column:resource_id;not null" json:"resourceId"`
+	// Operation refines Action, e.g. the task action executed.
+	Operation   string         `gorm:"column:operation" json:"operation,omitempty"`
+	StateBefore string         `gorm:"column:state_before" json:"stateBefore,omitempty"`
+	StateAfter  string         `gorm:"column:state_after" json:"stateAfter,omitempty"`
+	PayloadHash string         `gorm:"column:payload_hash" json:"payloadHash,omitempty"`
+	Details     map[string]any `gorm:"column:details;serializer:json" json:"details,omitempty"`
+	// PrevHash and EntryHash link the entry into the hash chain (see chain.go). Both are
+	// set by the store.
+	PrevHash  string `gorm:"column:prev_hash" json:"prevHash"`
+	EntryHash string `gorm:"column:entry_hash" json:"entryHash"`
+}
+
+// TableName specifies the database table for this model.
+func (e *Entry) TableName() string {
+	return "audit_log"
+}
+
+// Recorder appends entries to the audit log.
+type Recorder interface {
+	Append(ctx context.Context, entry *Entry) error
+}
+
+// TxRecorder is a Recorder that can append within a caller's database transaction.
+type TxRecorder interface {
+	Recorder
+	// WithTx returns a Recorder that appends within tx.
+	WithTx(tx *gorm.DB) Recorder
+}
+
+// Record appends entry with recorder, filling in the actor from the principal in ctx unless
+// entry names one, and the client IP and request ID of the HTTP request ctx belongs to.
+// A nil recorder records nothing.
+func Record(ctx context.Context, recorder Recorder, entry Entry) error {
+	if recorder == nil {
+		return nil
+	}
+	stamp(ctx, &entry)
+	// The entry describes an operation already under way, so it is written even if the
+	// request was cancelled since.
+	return appendEntry(context.WithoutCancel(ctx), recorder, &entry)
+}
+
+// RecordTx is Record within tx, the transaction making the change entry describes. A
+// recorder that cannot join tx appends on its own.
+func RecordTx(ctx context.Context, tx *gorm.DB, recorder Recorder, entry Entry) error {
+	if recorder == nil {
+		return nil
+	}
+	if txRecorder, ok := recorder.(TxRecorder); ok {
+		recorder = txRecorder.WithTx(tx)
+	}
+	stamp(ctx, &entry)
+	return appendEntry(ctx, recorder, &entry)
+}
+
+// stamp fills in the actor, request and time of entry that the caller left empty.
+func stamp(ctx context.Context, entry *Entry) {
+	if entry.ActorKind == "" {
+		entry.ActorKind, entry.ActorID = actorFrom(ctx)
+	}
+	if info := GetRequestInfo(ctx); info != nil {
+		entry.IP, entry.RequestID = info.ClientIP, info.ID
+	}
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now()
+	}
+}
+
+func appendEntry(ctx context.Context, recorder Recorder, entry *Entry) error {
+	if err := recorder.Append(ctx, entry); err != nil {
+		return fmt.Errorf("failed to write audit log entry %s for %s %s: %w", entry.Action, entry.ResourceType, entry.ResourceID, err)
+	}
+	return nil
+}
+
+func actorFrom(ctx context.Context) (ActorKind, string) {
+	authCtx := auth.GetAuthContext(ctx)
+	switch {
+	case authCtx == nil:
+		return ActorAnonymous, ""
+	case authCtx.User != nil:
+		return ActorUser, authCtx.User.ID
+	case authCtx.Client != nil:
+		return ActorClient, authCtx.Client.ClientID
+	case authCtx.System != nil:
+		return ActorSystem, authCtx.System.Actor
+	}
+	return ActorAnonymous, ""
+}
+
+// HashPayload returns the hex SHA-256 of the JSON encoding of payload, or "" for a nil
+// payload or one that cannot be encoded.
+func HashPayload(payload any) string {
+	if payload == nil {
+		return ""
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
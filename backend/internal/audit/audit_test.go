@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+type recorderFunc func(ctx context.Context, entry *Entry) error
+
+func (f recorderFunc) Append(ctx context.Context, entry *Entry) error { return f(ctx, entry) }
+
+func TestRecord_Actor(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		entry     Entry
+		wantKind  ActorKind
+		wantActor string
+	}{
+		{"anonymous", context.Background(), Entry{}, ActorAnonymous, ""},
+		{"user", context.WithValue(context.Background(), auth.AuthContextKey, &auth.AuthContext{User: &auth.UserContext{ID: "user-1"}}), Entry{}, ActorUser, "user-1"},
+		{"client", context.WithValue(context.Background(), auth.AuthContextKey, &auth.AuthContext{Client: &auth.ClientContext{ClientID: "NPQS_TO_NSW"}}), Entry{}, ActorClient, "NPQS_TO_NSW"},
+		{"system", auth.WithSystemActor(context.Background(), "payments-reconciliation"), Entry{}, ActorSystem, "payments-reconciliation"},
+		{"explicit actor", context.WithValue(context.Background(), auth.AuthContextKey, &auth.AuthContext{User: &auth.UserContext{ID: "user-1"}}), Entry{ActorKind: ActorProvider, ActorID: "govpay"}, ActorProvider, "govpay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *Entry
+			Record(tt.ctx, recorderFunc(func(ctx context.Context, entry *Entry) error {
+				got = entry
+				return nil
+			}), tt.entry)
+			if got == nil {
+				t.Fatal("expected an entry to be appended")
+			}
+			if got.ActorKind != tt.wantKind || got.ActorID != tt.wantActor {
+				t.Fatalf("expected actor %s/%q, got %s/%q", tt.wantKind, tt.wantActor, got.ActorKind, got.ActorID)
+			}
+			if got.OccurredAt.IsZero() {
+				t.Fatal("expected OccurredAt to be set")
+			}
+		})
+	}
+}
+
+func TestRecord_RequestInfo(t *testing.T) {
+	var got *Entry
+	recorder := recorderFunc(func(ctx context.Context, entry *Entry) error {
+		got = entry
+		return nil
+	})
+	handler := RequestMetadata(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Record(r.Context(), recorder, Entry{Action: ActionUploadCreate})
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/uploads", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set(RequestIDHeader, "req-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got == nil {
+		t.Fatal("expected an entry to be appended")
+	}
+	if got.IP != "203.0.113.7" || got.RequestID != "req-123" {
+		t.Fatalf("unexpected request info: ip=%q requestId=%q", got.IP, got.RequestID)
+	}
+}
+
+func TestRecord_Errors(t *testing.T) {
+	// A nil recorder records nothing.
+	if err := Record(context.Background(), nil, Entry{Action: ActionTaskExecute}); err != nil {
+		t.Fatalf("expected no error from a nil recorder, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	err := Record(ctx, recorderFunc(func(ctx context.Context, entry *Entry) error {
+		called = true
+		if ctx.Err() != nil {
+			t.Errorf("expected the append context not to be cancelled, got %v", ctx.Err())
+		}
+		return errors.New("boom")
+	}), Entry{Action: ActionTaskExecute})
+	if !called {
+		t.Fatal("expected the recorder to be called")
+	}
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected the append error to be returned, got %v", err)
+	}
+}
+
+// txRecorder collects appended entries; the recorders it binds to a transaction by WithTx
+// are kept in bound.
+type txRecorder struct {
+	tx      *gorm.DB
+	entries []Entry
+	bound   []*txRecorder
+}
+
+func (r *txRecorder) Append(ctx context.Context, entry *Entry) error {
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *txRecorder) WithTx(tx *gorm.DB) Recorder {
+	bound := &txRecorder{tx: tx}
+	r.bound = append(r.bound, bound)
+	return bound
+}
+
+func TestRecordTx(t *testing.T) {
+	tx := &gorm.DB{}
+	recorder := &txRecorder{}
+	if err := RecordTx(context.Background(), tx, recorder, Entry{Action: ActionConsignmentCreate}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recorder.entries) != 0 || len(recorder.bound) != 1 {
+		t.Fatalf("expected the entry to be appended only within tx, got %+v", recorder)
+	}
+	bound := recorder.bound[0]
+	if bound.tx != tx || len(bound.entries) != 1 {
+		t.Fatalf("expected one entry appended within tx, got %+v", bound)
+	}
+	if bound.entries[0].ActorKind != ActorAnonymous || bound.entries[0].OccurredAt.IsZero() {
+		t.Fatalf("expected the entry to be stamped, got %+v", bound.entries[0])
+	}
+
+	// A recorder that cannot join the transaction appends on its own.
+	appended := false
+	err := RecordTx(context.Background(), tx, recorderFunc(func(ctx context.Context, entry *Entry) error {
+		appended = true
+		return nil
+	}), Entry{Action: ActionConsignmentCreate})
+	if err != nil || !appended {
+		t.Fatalf("expected a plain recorder to append, got appended=%v err=%v", appended, err)
+	}
+}
+
+func TestHashPayload(t *testing.T) {
+	a := HashPayload(map[string]any{"action": "submit", "content": map[string]any{"a": 1}})
+	b := HashPayload(map[string]any{"content": map[string]any{"a": 1}, "action": "submit"})
+	if len(a) != 64 || a != b {
+		t.Fatalf("expected equal 64-character hashes, got %q and %q", a, b)
+	}
+	if HashPayload(map[string]any{"action": "draft"}) == a {
+		t.Fatal("expected different payloads to hash differently")
+	}
+	if HashPayload(nil) != "" || HashPayload(func() {}) != "" {
+		t.Fatal("expected empty hash for nil or unencodable payloads")
+	}
+}
@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The audit log is a set of hash chains in id order, one per consignment (entries without a
+// consignment, such as template and upload changes, form one more). Each entry's EntryHash
+// is the SHA-256 of its PrevHash (the EntryHash of the entry before it in its chain, "" for
+// the first) and the JSON encoding of its contents, with its details in the form they read
+// back from the database (see canonicalDetails), so editing, deleting or reordering an entry
+// invalidates every link after it in its chain.
+//
+// Appends lock only their own chain, so audited writes about different consignments do not
+// wait on each other even when the entry is appended in a long business transaction. They
+// also hold chainLockKey shared, which checkpoints take exclusively: a checkpoint therefore
+// sees every entry below the head it pins committed, and pins the whole log through a digest
+// of every entry hash in id order (see extendLogDigest).
+
+// chainLockKey is the pg_advisory_xact_lock key appends hold shared and checkpoints exclusively.
+const chainLockKey int64 = 0x4e535741554454 // "NSWAUDT"
+
+// chainLockClass is the first key of the advisory locks serializing appends to one chain;
+// the second is the hash of the consignment ID.
+const chainLockClass int32 = 0x4e535743 // "NSWC"
+
+// lockChain takes the locks appending to the chain of consignmentID requires, for the rest
+// of tx. A transaction appending to several chains should do so in a stable order, such as
+// by consignment ID, so that it cannot deadlock with another.
+func lockChain(tx *gorm.DB, consignmentID string) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock_shared(?)", chainLockKey).Error; err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", chainLockClass, consignmentID).Error; err != nil {
+		return fmt.Errorf("failed to lock audit chain: %w", err)
+	}
+	return nil
+}
+
+// extendLogDigest returns the digest of the log up to an entry hashed entryHash, given the
+// digest of the log up to the chained entry before it ("" for none). Unlike the chains, the
+// digest runs over all consignments in id order, so a checkpoint pinning it pins every chain.
+func extendLogDigest(digest, entryHash string) string {
+	sum := sha256.Sum256([]byte(digest + "\n" + entryHash))
+	return hex.EncodeToString(sum[:])
+}
+
+// chainedContent is the part of an entry covered by its hash. Fields are encoded in
+// declaration order; changing them invalidates existing chains.
+type chainedContent struct {
+	PrevHash      string         `json:"prevHash"`
+	OccurredAt    string         `json:"occurredAt"`
+	Action        Action         `json:"action"`
+	ActorKind     ActorKind      `json:"actorKind"`
+	ActorID       string         `json:"actorId"`
+	IP            string         `json:"ip"`
+	RequestID     string         `json:"requestId"`
+	ConsignmentID string         `json:"consignmentId"`
+	TaskID        string         `json:"taskId"`
+	ResourceType  string         `json:"resourceType"`
+	ResourceID    string         `json:"resourceId"`
+	Operation     string         `json:"operation"`
+	StateBefore   string         `json:"stateBefore"`
+	StateAfter    string         `json:"stateAfter"`
+	PayloadHash   string         `json:"payloadHash"`
+	Details       map[string]any `json:"details"`
+}
+
+// chainTime normalizes t to the microsecond precision and zone the database returns, so
+// a hash computed before insert matches the one recomputed from the stored row.
+func chainTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// canonicalDetails returns details as they read back from the stored row: structs and
+// slices of structs become maps with their keys sorted, and numbers become float64. Hashing
+// the canonical form lets Verify recompute the hash of entries whose details held structs.
+func canonicalDetails(details map[string]any) (map[string]any, error) {
+	if details == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit entry details: %w", err)
+	}
+	var canonical map[string]any
+	if err := json.Unmarshal(raw, &canonical); err != nil {
+		return nil, fmt.Errorf("failed to decode audit entry details: %w", err)
+	}
+	return canonical, nil
+}
+
+// computeEntryHash returns the hex SHA-256 linking entry to the entry hashed prevHash.
+func computeEntryHash(prevHash string, entry *Entry) (string, error) {
+	details, err := canonicalDetails(entry.Details)
+	if err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal(chainedContent{
+		PrevHash:      prevHash,
+		OccurredAt:    chainTime(entry.OccurredAt).Format(time.RFC3339Nano),
+		Action:        entry.Action,
+		ActorKind:     entry.ActorKind,
+		ActorID:       entry.ActorID,
+		IP:            entry.IP,
+		RequestID:     entry.RequestID,
+		ConsignmentID: entry.ConsignmentID,
+		TaskID:        entry.TaskID,
+		ResourceType:  entry.ResourceType,
+		ResourceID:    entry.ResourceID,
+		Operation:     entry.Operation,
+		StateBefore:   entry.StateBefore,
+		StateAfter:    entry.StateAfter,
+		PayloadHash:   entry.PayloadHash,
+		Details:       details,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
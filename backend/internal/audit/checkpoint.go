@@ -0,0 +1,255 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Checkpoint is a signed snapshot of the head of the audit log. It pins the digest of every
+// chained entry up to its last one, across all consignment chains, so rewriting any chain up
+// to a checkpointed entry requires the signing key.
+type Checkpoint struct {
+	ID            int64     `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	CreatedAt     time.Time `gorm:"column:created_at;not null" json:"createdAt"`
+	LastEntryID   int64     `gorm:"column:last_entry_id;not null" json:"lastEntryId"`
+	LastEntryHash string    `gorm:"column:last_entry_hash;not null" json:"lastEntryHash"`
+	EntryCount    int64     `gorm:"column:entry_count;not null" json:"entryCount"` // Chained entries up to and including LastEntryID
+	LogDigest     string    `gorm:"column:log_digest;not null" json:"logDigest"`   // See extendLogDigest
+	KeyID         string    `gorm:"column:key_id;not null" json:"keyId"`
+	Signature     string    `gorm:"column:signature;not null" json:"signature"` // Base64 Ed25519 signature over message()
+}
+
+// TableName specifies the database table for this model.
+func (c *Checkpoint) TableName() string {
+	return "audit_checkpoints"
+}
+
+// message returns the bytes a checkpoint's signature covers.
+func (c *Checkpoint) message() []byte {
+	return fmt.Appendf(nil, "nsw-audit-checkpoint/v2\n%d\n%s\n%d\n%s\n%s\n%s",
+		c.LastEntryID, c.LastEntryHash, c.EntryCount, c.LogDigest, chainTime(c.CreatedAt).Format(time.RFC3339Nano), c.KeyID)
+}
+
+// verifySignature reports whether the checkpoint was signed with the private half of key.
+func (c *Checkpoint) verifySignature(key ed25519.PublicKey) bool {
+	sig, err := base64.StdEncoding.DecodeString(c.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, c.message(), sig)
+}
+
+// Signer signs checkpoints.
+type Signer struct {
+	KeyID string
+	Key   ed25519.PrivateKey
+}
+
+// LoadSigner reads the PEM-encoded PKCS #8 Ed25519 private key at path, as written by
+// `openssl genpkey -algorithm ed25519`.
+func LoadSigner(keyID, path string) (*Signer, error) {
+	key, err := readPEMKey(path)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s does not hold an Ed25519 private key", path)
+	}
+	return &Signer{KeyID: keyID, Key: private}, nil
+}
+
+// LoadPublicKey reads the Ed25519 public key checkpoints are verified with from the PEM file
+// at path. The file may hold the public key (PKIX) or the private key it belongs to.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	key, err := readPEMKey(path)
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k.Public().(ed25519.PublicKey), nil
+	}
+	return nil, fmt.Errorf("%s does not hold an Ed25519 key", path)
+}
+
+func readPEMKey(path string) (any, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not PEM-encoded", path)
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key in %s: %w", path, err)
+		}
+		return key, nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key in %s: %w", path, err)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+}
+
+// checkpointLockTimeout bounds how long a checkpoint waits for in-flight appends to commit.
+// Appends queue behind a waiting checkpoint, so the wait is kept short.
+const checkpointLockTimeout = "2s"
+
+// pgLockNotAvailable is the SQLSTATE of a lock wait that exceeded lock_timeout.
+const pgLockNotAvailable = "55P03"
+
+// ErrCheckpointBusy is returned by WriteCheckpoint when appends held the audit log for longer
+// than it waits; the next checkpoint retries.
+var ErrCheckpointBusy = errors.New("audit log busy")
+
+// WriteCheckpoint signs the current head of the audit log: its last chained entry and the
+// number of chained entries up to it. It returns nil if no entry has been chained since the
+// last checkpoint. The log is locked exclusively while the head is read, so every entry
+// below the head has committed and checkpoints written by several replicas never contradict
+// each other.
+func WriteCheckpoint(ctx context.Context, db *gorm.DB, signer *Signer) (*Checkpoint, error) {
+	var checkpoint *Checkpoint
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET LOCAL lock_timeout = '" + checkpointLockTimeout + "'").Error; err != nil {
+			return fmt.Errorf("failed to set audit log lock timeout: %w", err)
+		}
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", chainLockKey).Error; err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == pgLockNotAvailable {
+				return ErrCheckpointBusy
+			}
+			return fmt.Errorf("failed to lock audit log: %w", err)
+		}
+
+		var last Checkpoint
+		err := tx.Order("id DESC").Take(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to read last audit checkpoint: %w", err)
+		}
+
+		// Extend the last checkpoint's digest over the entries chained since.
+		head, digest, added := Entry{ID: last.LastEntryID}, last.LogDigest, int64(0)
+		for {
+			var batch []Entry
+			if err := tx.Select("id", "entry_hash").Where("id > ? AND entry_hash <> ''", head.ID).Order("id").Limit(verifyBatchSize).Find(&batch).Error; err != nil {
+				return fmt.Errorf("failed to read audit entries: %w", err)
+			}
+			for _, entry := range batch {
+				digest = extendLogDigest(digest, entry.EntryHash)
+				head = entry
+			}
+			added += int64(len(batch))
+			if len(batch) < verifyBatchSize {
+				break
+			}
+		}
+		if added == 0 {
+			return nil
+		}
+
+		checkpoint = &Checkpoint{
+			CreatedAt:     chainTime(time.Now()),
+			LastEntryID:   head.ID,
+			LastEntryHash: head.EntryHash,
+			EntryCount:    last.EntryCount + added,
+			LogDigest:     digest,
+			KeyID:         signer.KeyID,
+		}
+		checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(signer.Key, checkpoint.message()))
+		return tx.Create(checkpoint).Error
+	})
+	if errors.Is(err, ErrCheckpointBusy) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audit checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// CheckpointWorker runs WriteCheckpoint periodically in the background.
+type CheckpointWorker struct {
+	db       *gorm.DB
+	signer   *Signer
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewCheckpointWorker creates a worker that checkpoints the audit chain every interval.
+func NewCheckpointWorker(db *gorm.DB, signer *Signer, interval time.Duration) *CheckpointWorker {
+	return &CheckpointWorker{
+		db:       db,
+		signer:   signer,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the background loop. It returns immediately.
+func (w *CheckpointWorker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		slog.Info("audit checkpoint worker started", "interval", w.interval, "keyId", w.signer.KeyID)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				checkpoint, err := WriteCheckpoint(ctx, w.db, w.signer)
+				if errors.Is(err, ErrCheckpointBusy) {
+					slog.WarnContext(ctx, "audit checkpoint skipped; appends held the audit log", "lockTimeout", checkpointLockTimeout)
+					continue
+				}
+				if err != nil {
+					slog.ErrorContext(ctx, "audit checkpoint failed", "error", err)
+					continue
+				}
+				if checkpoint != nil {
+					slog.InfoContext(ctx, "audit checkpoint written",
+						"checkpointId", checkpoint.ID, "lastEntryId", checkpoint.LastEntryID, "entryCount", checkpoint.EntryCount)
+				}
+			}
+		}
+	}()
+}
+
+// Stop cancels the loop and waits for an in-flight checkpoint to finish.
+func (w *CheckpointWorker) Stop() {
+	w.once.Do(func() {
+		if w.cancel == nil {
+			close(w.done)
+			return
+		}
+		w.cancel()
+		<-w.done
+	})
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestWriteCheckpoint(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := &Signer{KeyID: "audit-2026", Key: privateKey}
+	lastCheckpoint := `SELECT \* FROM "audit_checkpoints" ORDER BY id DESC LIMIT \$1`
+	newEntries := `SELECT "id","entry_hash" FROM "audit_log" WHERE id > \$1 AND entry_hash <> '' ORDER BY id LIMIT \$2`
+	expectLock := func(mock sqlmock.Sqlmock) *sqlmock.ExpectedExec {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SET LOCAL lock_timeout = '2s'`).WillReturnResult(sqlmock.NewResult(0, 0))
+		return mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1\)`).WithArgs(chainLockKey)
+	}
+
+	t.Run("signs the new head", func(t *testing.T) {
+		s, mock := setupTestStore(t)
+		expectLock(mock).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(lastCheckpoint).WillReturnRows(checkpointRows(Checkpoint{ID: 1, LastEntryID: 6, EntryCount: 5, LogDigest: "digest-6"}))
+		mock.ExpectQuery(newEntries).
+			WithArgs(6, verifyBatchSize).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "entry_hash"}).AddRow(8, "hash-8").AddRow(9, "head-hash"))
+		mock.ExpectQuery(`INSERT INTO "audit_checkpoints"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectCommit()
+
+		cp, err := WriteCheckpoint(context.Background(), s.(*store).db, signer)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cp == nil || cp.LastEntryID != 9 || cp.LastEntryHash != "head-hash" || cp.EntryCount != 7 || cp.KeyID != "audit-2026" {
+			t.Fatalf("unexpected checkpoint: %+v", cp)
+		}
+		if want := extendLogDigest(extendLogDigest("digest-6", "hash-8"), "head-hash"); cp.LogDigest != want {
+			t.Fatalf("expected log digest %s, got %s", want, cp.LogDigest)
+		}
+		if !cp.verifySignature(publicKey) {
+			t.Fatal("expected the checkpoint signature to verify")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("nothing new since the last checkpoint", func(t *testing.T) {
+		s, mock := setupTestStore(t)
+		expectLock(mock).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(lastCheckpoint).WillReturnRows(checkpointRows(Checkpoint{ID: 2, LastEntryID: 9, EntryCount: 8}))
+		mock.ExpectQuery(newEntries).
+			WithArgs(9, verifyBatchSize).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "entry_hash"}))
+		mock.ExpectCommit()
+
+		cp, err := WriteCheckpoint(context.Background(), s.(*store).db, signer)
+		if err != nil || cp != nil {
+			t.Fatalf("expected no checkpoint, got %+v, %v", cp, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("appends hold the log", func(t *testing.T) {
+		s, mock := setupTestStore(t)
+		expectLock(mock).WillReturnError(&pgconn.PgError{Code: pgLockNotAvailable})
+		mock.ExpectRollback()
+
+		cp, err := WriteCheckpoint(context.Background(), s.(*store).db, signer)
+		if !errors.Is(err, ErrCheckpointBusy) || cp != nil {
+			t.Fatalf("expected ErrCheckpointBusy, got %+v, %v", cp, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	})
+}
+
+func TestLoadKeys(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dir := t.TempDir()
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	privatePath := writePEM("private.pem", "PRIVATE KEY", privateDER)
+	publicPath := writePEM("public.pem", "PUBLIC KEY", publicDER)
+
+	signer, err := LoadSigner("audit-2026", privatePath)
+	if err != nil || !signer.Key.Equal(privateKey) || signer.KeyID != "audit-2026" {
+		t.Fatalf("unexpected signer %+v, %v", signer, err)
+	}
+	if _, err := LoadSigner("audit-2026", publicPath); err == nil {
+		t.Fatal("expected an error loading a signer from a public key")
+	}
+	for _, path := range []string{privatePath, publicPath} {
+		key, err := LoadPublicKey(path)
+		if err != nil || !key.Equal(publicKey) {
+			t.Fatalf("unexpected public key from %s: %v", path, err)
+		}
+	}
+	if _, err := LoadPublicKey(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Fatal("expected an error for a missing key file")
+	}
+}
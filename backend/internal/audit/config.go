@@ -0,0 +1,27 @@
+package audit
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config holds audit chain checkpoint configuration.
+type Config struct {
+	CheckpointKeyPath  string        // PEM file with the Ed25519 private key checkpoints are signed with; empty disables checkpoints
+	CheckpointKeyID    string        // Identifier recorded with each checkpoint, so verifiers can pick the matching public key
+	CheckpointInterval time.Duration // How often the head of the chain is checkpointed
+}
+
+// Validate checks the checkpoint configuration.
+func (c Config) Validate() error {
+	if c.CheckpointKeyPath == "" {
+		return nil
+	}
+	if c.CheckpointKeyID == "" {
+		return fmt.Errorf("AUDIT_CHECKPOINT_KEY_ID is required when AUDIT_CHECKPOINT_KEY_PATH is set")
+	}
+	if c.CheckpointInterval <= 0 {
+		return fmt.Errorf("AUDIT_CHECKPOINT_INTERVAL must be positive when AUDIT_CHECKPOINT_KEY_PATH is set")
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/utils"
+)
+
+// HTTPHandler exposes the audit log to administrators.
+type HTTPHandler struct {
+	store Store
+}
+
+// NewHTTPHandler creates a new HTTPHandler for the audit log
+func NewHTTPHandler(store Store) *HTTPHandler {
+	return &HTTPHandler{store: store}
+}
+
+// HandleQuery handles GET /api/v1/admin/audit
+// Optional filters: consignmentId, actorId, action, and the RFC 3339 time range from
+// (inclusive) and to (exclusive). Pagination: offset, limit.
+func (h *HTTPHandler) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "the audit log is only available to administrators")
+		return
+	}
+
+	query := r.URL.Query()
+	offset, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := Filter{
+		ConsignmentID: query.Get("consignmentId"),
+		ActorID:       query.Get("actorId"),
+		Action:        Action(query.Get("action")),
+		Offset:        offset,
+		Limit:         limit,
+	}
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "query param "+name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*dst = &t
+	}
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		writeJSONError(w, http.StatusBadRequest, "query param to must be after from")
+		return
+	}
+
+	page, err := h.store.Query(r.Context(), filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to query audit log", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to query audit log")
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, page)
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+// writeJSONError sets Content-Type: application/json and writes a consistent JSON error body.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSONResponse(w, status, map[string]string{"error": message})
+}
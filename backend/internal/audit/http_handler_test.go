@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// fakeStore records the filter it is queried with; the embedded nil Store panics on Append.
+type fakeStore struct {
+	Store
+	filter *Filter
+}
+
+func (f *fakeStore) Query(ctx context.Context, filter Filter) (*Page, error) {
+	f.filter = &filter
+	return &Page{Items: []Entry{}}, nil
+}
+
+func TestHTTPHandler_HandleQuery(t *testing.T) {
+	admin := &auth.AuthContext{User: &auth.UserContext{ID: "admin-1", Roles: []string{auth.RoleAdmin}}}
+	trader := &auth.AuthContext{User: &auth.UserContext{ID: "trader-1"}}
+
+	tests := []struct {
+		name    string
+		authCtx *auth.AuthContext
+		query   string
+		want    int
+	}{
+		{"admin", admin, "?consignmentId=c-1&actorId=user-1&action=task.execute&from=2026-03-01T00:00:00Z&to=2026-03-02T00:00:00Z", http.StatusOK},
+		{"unauthenticated", nil, "", http.StatusForbidden},
+		{"non-admin", trader, "", http.StatusForbidden},
+		{"bad from", admin, "?from=yesterday", http.StatusBadRequest},
+		{"empty range", admin, "?from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z", http.StatusBadRequest},
+		{"bad limit", admin, "?limit=abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{}
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit"+tt.query, nil)
+			if tt.authCtx != nil {
+				req = req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, tt.authCtx))
+			}
+			recorder := httptest.NewRecorder()
+			NewHTTPHandler(store).HandleQuery(recorder, req)
+			if recorder.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, recorder.Code, recorder.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				if store.filter != nil {
+					t.Fatal("expected the store not to be queried")
+				}
+				return
+			}
+			f := store.filter
+			if f == nil || f.ConsignmentID != "c-1" || f.ActorID != "user-1" || f.Action != ActionTaskExecute || f.From == nil || f.To == nil {
+				t.Fatalf("unexpected filter: %+v", f)
+			}
+		})
+	}
+}
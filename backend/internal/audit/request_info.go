@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the ID of a request. An ID set by the caller or a proxy is kept,
+// so the request can be traced across services; otherwise one is generated.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from callers.
+const maxRequestIDLength = 128
+
+type requestInfoKey struct{}
+
+// RequestInfo identifies the HTTP request an operation is performed in.
+type RequestInfo struct {
+	ID       string
+	ClientIP string
+}
+
+// RequestMetadata creates a middleware that stores the request ID and client IP in the
+// request context (see GetRequestInfo) and echoes the request ID in the response. With
+// trustProxyHeaders, the client IP is taken from the last X-Forwarded-For entry, the one
+// added by the reverse proxy in front of the server.
+func RequestMetadata(trustProxyHeaders bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := &RequestInfo{
+				ID:       r.Header.Get(RequestIDHeader),
+				ClientIP: clientIP(r, trustProxyHeaders),
+			}
+			if !validRequestID(info.ID) {
+				info.ID = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, info.ID)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)))
+		})
+	}
+}
+
+// GetRequestInfo returns the RequestInfo of the request ctx belongs to, or nil outside of
+// an HTTP request, e.g. in background workers.
+func GetRequestInfo(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	return info
+}
+
+func clientIP(r *http.Request, trustProxyHeaders bool) string {
+	if trustProxyHeaders {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if last := strings.TrimSpace(forwarded[len(forwarded)-1]); net.ParseIP(last) != nil {
+			return last
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so they are safe to log and store.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestMetadata(t *testing.T) {
+	tests := []struct {
+		name       string
+		trust      bool
+		requestID  string
+		forwarded  string
+		wantIP     string
+		wantSameID bool
+	}{
+		{"caller request ID is kept", false, "req-123", "", "192.0.2.10", true},
+		{"invalid request ID is replaced", false, "bad id", "", "192.0.2.10", false},
+		{"overlong request ID is replaced", false, strings.Repeat("a", maxRequestIDLength+1), "", "192.0.2.10", false},
+		{"forwarded header ignored when untrusted", false, "", "203.0.113.7", "192.0.2.10", false},
+		{"last forwarded entry used when trusted", true, "", "198.51.100.1, 203.0.113.7", "203.0.113.7", false},
+		{"malformed forwarded header falls back to peer", true, "", "unknown", "192.0.2.10", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *RequestInfo
+			handler := RequestMetadata(tt.trust)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = GetRequestInfo(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/consignments", nil)
+			req.RemoteAddr = "192.0.2.10:51234"
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if got == nil {
+				t.Fatal("expected request info in context")
+			}
+			if got.ClientIP != tt.wantIP {
+				t.Errorf("expected client IP %q, got %q", tt.wantIP, got.ClientIP)
+			}
+			if got.ID == "" || (got.ID == tt.requestID) != tt.wantSameID {
+				t.Errorf("unexpected request ID %q for header %q", got.ID, tt.requestID)
+			}
+			if recorder.Header().Get(RequestIDHeader) != got.ID {
+				t.Errorf("expected request ID %q in response, got %q", got.ID, recorder.Header().Get(RequestIDHeader))
+			}
+		})
+	}
+}
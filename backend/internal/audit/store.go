@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/utils"
+)
+
+// Filter selects audit entries. Empty fields do not filter; From is inclusive, To exclusive.
+type Filter struct {
+	ConsignmentID string
+	ActorID       string
+	Action        Action
+	From          *time.Time
+	To            *time.Time
+	Offset        *int
+	Limit         *int
+}
+
+// Page is a page of entries, oldest first.
+type Page struct {
+	TotalCount int64   `json:"totalCount"`
+	Items      []Entry `json:"items"`
+	Offset     int     `json:"offset"`
+	Limit      int     `json:"limit"`
+}
+
+// Store appends to and queries the audit log.
+type Store interface {
+	TxRecorder
+	// Query returns the entries matching filter, oldest first.
+	Query(ctx context.Context, filter Filter) (*Page, error)
+}
+
+type store struct {
+	db *gorm.DB
+}
+
+// NewStore creates a Store backed by the audit_log table.
+func NewStore(db *gorm.DB) Store {
+	return &store{db: db}
+}
+
+// WithTx returns a store that appends within tx.
+func (s *store) WithTx(tx *gorm.DB) Recorder {
+	return &store{db: tx}
+}
+
+// Append links entry into the hash chain of its consignment and inserts it. Entries about a
+// task whose consignment is unknown to the caller, such as payment status changes, are
+// attributed to the task's workflow. Appended within a caller's transaction, the chain stays
+// locked until that transaction ends, so the next entry about the same consignment links to
+// this one only once it has committed; entries about other consignments do not wait.
+func (s *store) Append(ctx context.Context, entry *Entry) error {
+	db := s.db.WithContext(ctx)
+	if entry.ConsignmentID == "" && entry.TaskID != "" {
+		var workflowIDs []string
+		if err := db.Table("task_infos").Where("id = ?", entry.TaskID).Limit(1).Pluck("workflow_id", &workflowIDs).Error; err != nil {
+			return fmt.Errorf("failed to resolve workflow of task %s: %w", entry.TaskID, err)
+		}
+		if len(workflowIDs) > 0 {
+			entry.ConsignmentID = workflowIDs[0]
+		}
+	}
+	entry.OccurredAt = chainTime(entry.OccurredAt)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockChain(tx, entry.ConsignmentID); err != nil {
+			return err
+		}
+		prevHash, err := chainHead(tx, entry.ConsignmentID)
+		if err != nil {
+			return err
+		}
+		entry.PrevHash = prevHash
+		if entry.EntryHash, err = computeEntryHash(prevHash, entry); err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	return nil
+}
+
+// chainHead returns the hash of the last chained entry about consignmentID, or "" if its
+// chain is empty.
+func chainHead(tx *gorm.DB, consignmentID string) (string, error) {
+	var hashes []string
+	if err := tx.Model(&Entry{}).Where("consignment_id = ? AND entry_hash <> ''", consignmentID).Order("id DESC").Limit(1).Pluck("entry_hash", &hashes).Error; err != nil {
+		return "", fmt.Errorf("failed to read head of audit chain: %w", err)
+	}
+	if len(hashes) == 0 {
+		return "", nil
+	}
+	return hashes[0], nil
+}
+
+func (s *store) Query(ctx context.Context, filter Filter) (*Page, error) {
+	query := s.db.WithContext(ctx).Model(&Entry{})
+	if filter.ConsignmentID != "" {
+		query = query.Where("consignment_id = ?", filter.ConsignmentID)
+	}
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("occurred_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("occurred_at < ?", *filter.To)
+	}
+
+	offset, limit := utils.GetPaginationParams(filter.Offset, filter.Limit)
+	page := &Page{Items: []Entry{}, Offset: offset, Limit: limit}
+	if err := query.Count(&page.TotalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+	if page.TotalCount == 0 {
+		return page, nil
+	}
+	if err := query.Order("occurred_at, id").Offset(offset).Limit(limit).Find(&page.Items).Error; err != nil {
+		return nil, fmt.Errorf("failed to query audit entries: %w", err)
+	}
+	return page, nil
+}
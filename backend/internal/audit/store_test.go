@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupTestStore(t *testing.T) (Store, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	return NewStore(gormDB), mock
+}
+
+func TestStore_Append_ResolvesConsignmentFromTask(t *testing.T) {
+	store, mock := setupTestStore(t)
+
+	mock.ExpectQuery(`SELECT "workflow_id" FROM "task_infos" WHERE id = \$1 LIMIT \$2`).
+		WithArgs("task-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"workflow_id"}).AddRow("consignment-1"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock_shared\(\$1\)`).
+		WithArgs(chainLockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1, hashtext\(\$2\)\)`).
+		WithArgs(chainLockClass, "consignment-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT "entry_hash" FROM "audit_log" WHERE consignment_id = \$1 AND entry_hash <> '' ORDER BY id DESC LIMIT \$2`).
+		WithArgs("consignment-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"entry_hash"}).AddRow("prev-hash"))
+	mock.ExpectQuery(`INSERT INTO "audit_log"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	entry := &Entry{
+		OccurredAt:   time.Now(),
+		Action:       ActionPaymentStatusChange,
+		ActorKind:    ActorSystem,
+		TaskID:       "task-1",
+		ResourceType: ResourcePayment,
+		ResourceID:   "PAY-1",
+	}
+	if err := store.Append(context.Background(), entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.ConsignmentID != "consignment-1" || entry.ID != 1 {
+		t.Fatalf("unexpected entry after append: %+v", entry)
+	}
+	want, err := computeEntryHash("prev-hash", entry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.PrevHash != "prev-hash" || entry.EntryHash != want {
+		t.Fatalf("expected entry to be linked to the chain head, got prev=%q hash=%q", entry.PrevHash, entry.EntryHash)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestStore_Query(t *testing.T) {
+	store, mock := setupTestStore(t)
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	where := `WHERE consignment_id = \$1 AND actor_id = \$2 AND occurred_at >= \$3 AND occurred_at < \$4`
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "audit_log" `+where).
+		WithArgs("consignment-1", "user-1", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT \* FROM "audit_log" `+where+` ORDER BY occurred_at, id LIMIT \$5`).
+		WithArgs("consignment-1", "user-1", from, to, 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "occurred_at", "action", "actor_kind", "actor_id", "consignment_id", "resource_type", "resource_id"}).
+			AddRow(7, from.Add(time.Hour), string(ActionConsignmentCreate), string(ActorUser), "user-1", "consignment-1", ResourceConsignment, "consignment-1"))
+
+	limit := 50
+	page, err := store.Query(context.Background(), Filter{ConsignmentID: "consignment-1", ActorID: "user-1", From: &from, To: &to, Limit: &limit})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.TotalCount != 1 || len(page.Items) != 1 || page.Items[0].Action != ActionConsignmentCreate {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestStore_Query_Empty(t *testing.T) {
+	store, mock := setupTestStore(t)
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "audit_log" WHERE action = \$1`).
+		WithArgs(ActionUploadDelete).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	page, err := store.Query(context.Background(), Filter{Action: ActionUploadDelete})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.TotalCount != 0 || page.Items == nil || len(page.Items) != 0 {
+		t.Fatalf("expected an empty page, got %+v", page)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestStore_WithTx_AppendsInCallerTransaction(t *testing.T) {
+	auditStore, mock := setupTestStore(t)
+	db := auditStore.(*store).db
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "consignments"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`SAVEPOINT`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock_shared\(\$1\)`).
+		WithArgs(chainLockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1, hashtext\(\$2\)\)`).
+		WithArgs(chainLockClass, "").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT "entry_hash" FROM "audit_log"`).
+		WillReturnRows(sqlmock.NewRows([]string{"entry_hash"}))
+	mock.ExpectQuery(`INSERT INTO "audit_log"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`UPDATE "consignments" SET state = 'IN_PROGRESS'`).Error; err != nil {
+			return err
+		}
+		return RecordTx(context.Background(), tx, auditStore, Entry{
+			Action:       ActionConsignmentInitialize,
+			ResourceType: ResourceConsignment,
+			ResourceID:   "consignment-1",
+		})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// verifyBatchSize bounds the entries loaded per query while walking the chain.
+const verifyBatchSize = 1000
+
+// VerifyReport is the outcome of walking the audit chain.
+type VerifyReport struct {
+	Entries     int64 `json:"entries"`     // Chained entries whose links were checked
+	Unchained   int64 `json:"unchained"`   // Entries written before hash chaining was introduced
+	Checkpoints int   `json:"checkpoints"` // Checkpoints checked
+	// SignaturesChecked is false when no public key was given; checkpoints then still pin
+	// entry hashes and counts, but a forged checkpoint would go unnoticed.
+	SignaturesChecked bool        `json:"signaturesChecked"`
+	Break             *ChainBreak `json:"break,omitempty"` // First broken link; nil if the chain is intact
+}
+
+// ChainBreak describes the first broken link of the audit chain.
+type ChainBreak struct {
+	EntryID      int64  `json:"entryId,omitempty"`
+	CheckpointID int64  `json:"checkpointId,omitempty"`
+	Reason       string `json:"reason"`
+}
+
+func (b *ChainBreak) String() string {
+	switch {
+	case b.EntryID != 0 && b.CheckpointID != 0:
+		return fmt.Sprintf("entry %d, checkpoint %d: %s", b.EntryID, b.CheckpointID, b.Reason)
+	case b.CheckpointID != 0:
+		return fmt.Sprintf("checkpoint %d: %s", b.CheckpointID, b.Reason)
+	}
+	return fmt.Sprintf("entry %d: %s", b.EntryID, b.Reason)
+}
+
+// Verify walks the audit log in id order, recomputing every entry hash and its link to the
+// entry before it in its consignment's chain, and checks each checkpoint against the entries
+// it pins. It stops at the first broken link,
+// which is returned in the report; the error is reserved for failures to read the log.
+// publicKey may be nil, in which case checkpoint signatures are not checked.
+func Verify(ctx context.Context, db *gorm.DB, publicKey ed25519.PublicKey) (*VerifyReport, error) {
+	db = db.WithContext(ctx)
+
+	var checkpoints []Checkpoint
+	if err := db.Order("id").Find(&checkpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to load audit checkpoints: %w", err)
+	}
+	report := &VerifyReport{Checkpoints: len(checkpoints), SignaturesChecked: publicKey != nil}
+
+	// pinned maps an entry ID to the checkpoints that pin it.
+	pinned := make(map[int64][]*Checkpoint, len(checkpoints))
+	var prev *Checkpoint
+	for i := range checkpoints {
+		cp := &checkpoints[i]
+		if publicKey != nil && !cp.verifySignature(publicKey) {
+			report.Break = &ChainBreak{CheckpointID: cp.ID, Reason: fmt.Sprintf("signature does not verify (key %q)", cp.KeyID)}
+			return report, nil
+		}
+		if prev != nil && (cp.LastEntryID < prev.LastEntryID || cp.EntryCount < prev.EntryCount) {
+			report.Break = &ChainBreak{CheckpointID: cp.ID, Reason: fmt.Sprintf("pins an earlier chain head than checkpoint %d", prev.ID)}
+			return report, nil
+		}
+		pinned[cp.LastEntryID] = append(pinned[cp.LastEntryID], cp)
+		prev = cp
+	}
+
+	// prevHashes maps a consignment ID to the hash of the last entry seen in its chain.
+	prevHashes := make(map[string]string)
+	digest := ""
+	chained := false
+	var lastID int64
+	for {
+		var batch []Entry
+		if err := db.Where("id > ?", lastID).Order("id").Limit(verifyBatchSize).Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to load audit entries: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for i := range batch {
+			entry := &batch[i]
+			lastID = entry.ID
+			if entry.EntryHash == "" {
+				if chained {
+					report.Break = &ChainBreak{EntryID: entry.ID, Reason: "entry has no hash but follows chained entries"}
+					return report, nil
+				}
+				report.Unchained++
+				continue
+			}
+			chained = true
+
+			if entry.PrevHash != prevHashes[entry.ConsignmentID] {
+				report.Break = &ChainBreak{EntryID: entry.ID, Reason: "prev_hash does not match the hash of the preceding entry of its consignment; an entry was removed, inserted or reordered"}
+				return report, nil
+			}
+			hash, err := computeEntryHash(entry.PrevHash, entry)
+			if err != nil {
+				return nil, err
+			}
+			if hash != entry.EntryHash {
+				report.Break = &ChainBreak{EntryID: entry.ID, Reason: "entry contents do not match its hash; the entry was edited"}
+				return report, nil
+			}
+			report.Entries++
+			digest = extendLogDigest(digest, entry.EntryHash)
+
+			for _, cp := range pinned[entry.ID] {
+				if cp.LastEntryHash != entry.EntryHash {
+					report.Break = &ChainBreak{EntryID: entry.ID, CheckpointID: cp.ID, Reason: "entry hash differs from the hash the checkpoint pins; the chain was rewritten"}
+					return report, nil
+				}
+				if cp.EntryCount != report.Entries {
+					report.Break = &ChainBreak{EntryID: entry.ID, CheckpointID: cp.ID, Reason: fmt.Sprintf("checkpoint counts %d chained entries up to here, found %d", cp.EntryCount, report.Entries)}
+					return report, nil
+				}
+				if cp.LogDigest != digest {
+					report.Break = &ChainBreak{EntryID: entry.ID, CheckpointID: cp.ID, Reason: "log digest differs from the digest the checkpoint pins; an earlier entry was rewritten"}
+					return report, nil
+				}
+			}
+			delete(pinned, entry.ID)
+			prevHashes[entry.ConsignmentID] = entry.EntryHash
+		}
+	}
+
+	// Checkpoints still pinned reference entries that no longer exist.
+	var missing *Checkpoint
+	for _, cps := range pinned {
+		if missing == nil || cps[0].ID < missing.ID {
+			missing = cps[0]
+		}
+	}
+	if missing != nil {
+		report.Break = &ChainBreak{CheckpointID: missing.ID, EntryID: missing.LastEntryID, Reason: "checkpoint pins an entry that no longer exists"}
+	}
+	return report, nil
+}
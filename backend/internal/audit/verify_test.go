@@ -0,0 +1,190 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var chainStart = time.Date(2026, 3, 1, 9, 0, 0, 123456000, time.UTC)
+
+// testChain returns n correctly linked entries about one consignment.
+func testChain(t *testing.T, n int) []Entry {
+	t.Helper()
+	entries := make([]Entry, n)
+	for i := range entries {
+		entries[i] = Entry{
+			ID:            int64(i + 1),
+			OccurredAt:    chainStart.Add(time.Duration(i) * time.Minute),
+			Action:        ActionOGAReview,
+			ActorKind:     ActorClient,
+			ActorID:       "NPQS_TO_NSW",
+			IP:            "203.0.113.7",
+			RequestID:     "req-1",
+			ConsignmentID: "consignment-1",
+			TaskID:        "task-1",
+			ResourceType:  ResourceTask,
+			ResourceID:    "task-1",
+			Operation:     "OGA_VERIFICATION_FEEDBACK",
+			StateBefore:   "OGA_REVIEW",
+			StateAfter:    "OGA_FEEDBACK",
+			PayloadHash:   HashPayload(map[string]any{"round": i + 1}),
+			Details:       map[string]any{"feedbackRound": i + 1},
+		}
+	}
+	return relink(t, entries)
+}
+
+// relink recomputes the links and hashes of entries, chaining each to the entry before it
+// about the same consignment.
+func relink(t *testing.T, entries []Entry) []Entry {
+	t.Helper()
+	prev := make(map[string]string)
+	for i := range entries {
+		e := &entries[i]
+		e.PrevHash = prev[e.ConsignmentID]
+		hash, err := computeEntryHash(e.PrevHash, e)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		e.EntryHash = hash
+		prev[e.ConsignmentID] = hash
+	}
+	return entries
+}
+
+func entryRows(t *testing.T, entries []Entry) *sqlmock.Rows {
+	t.Helper()
+	rows := sqlmock.NewRows([]string{"id", "occurred_at", "action", "actor_kind", "actor_id", "ip", "request_id", "consignment_id", "task_id",
+		"resource_type", "resource_id", "operation", "state_before", "state_after", "payload_hash", "details", "prev_hash", "entry_hash"})
+	for _, e := range entries {
+		details, err := json.Marshal(e.Details)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rows.AddRow(e.ID, e.OccurredAt, string(e.Action), string(e.ActorKind), e.ActorID, e.IP, e.RequestID, e.ConsignmentID, e.TaskID,
+			e.ResourceType, e.ResourceID, e.Operation, e.StateBefore, e.StateAfter, e.PayloadHash, details, e.PrevHash, e.EntryHash)
+	}
+	return rows
+}
+
+// signedCheckpoint pins the last of entries, counting count entries and digesting all of them.
+func signedCheckpoint(id int64, entries []Entry, count int64, key ed25519.PrivateKey) Checkpoint {
+	digest := ""
+	for _, e := range entries {
+		digest = extendLogDigest(digest, e.EntryHash)
+	}
+	entry := entries[len(entries)-1]
+	cp := Checkpoint{ID: id, CreatedAt: chainStart.Add(time.Hour), LastEntryID: entry.ID, LastEntryHash: entry.EntryHash, EntryCount: count, LogDigest: digest, KeyID: "audit-2026"}
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, cp.message()))
+	return cp
+}
+
+func checkpointRows(checkpoints ...Checkpoint) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "created_at", "last_entry_id", "last_entry_hash", "entry_count", "log_digest", "key_id", "signature"})
+	for _, cp := range checkpoints {
+		rows.AddRow(cp.ID, cp.CreatedAt, cp.LastEntryID, cp.LastEntryHash, cp.EntryCount, cp.LogDigest, cp.KeyID, cp.Signature)
+	}
+	return rows
+}
+
+func TestVerify(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	chain := testChain(t, 3)
+
+	edited := testChain(t, 3)
+	edited[1].StateAfter = "OGA_APPROVED"
+
+	// Struct details read back as maps with sorted keys, as workflow migrations store them.
+	type nodeMigration struct {
+		NodeID string `json:"nodeId"`
+		Status string `json:"status"`
+		Action string `json:"action"`
+	}
+	structured := testChain(t, 2)
+	structured[1].Details = map[string]any{"nodes": []nodeMigration{{NodeID: "n-1", Status: "COMPLETED", Action: "keep"}}}
+	relink(t, structured)
+
+	// Two consignments' chains interleave in id order; each entry links within its own.
+	interleaved := testChain(t, 4)
+	interleaved[1].ConsignmentID, interleaved[3].ConsignmentID = "consignment-2", "consignment-2"
+	relink(t, interleaved)
+	crossLinked := append([]Entry(nil), interleaved...)
+	crossLinked[1].PrevHash = crossLinked[0].EntryHash
+
+	// Rewriting one chain leaves the other, and the entry a checkpoint pins, intact; the
+	// checkpoint's log digest still catches it.
+	rewritten := append([]Entry(nil), interleaved...)
+	rewritten[0].StateAfter = "OGA_APPROVED"
+	relink(t, rewritten)
+
+	legacy := append([]Entry{{ID: 1, OccurredAt: chainStart, Action: ActionUploadCreate, ActorKind: ActorUser, ResourceType: ResourceUpload, ResourceID: "u-1"}}, testChain(t, 2)...)
+	legacy[1].ID, legacy[2].ID = 2, 3
+
+	tests := []struct {
+		name        string
+		entries     []Entry
+		checkpoints []Checkpoint
+		key         ed25519.PublicKey
+		wantBreak   string // substring of the break; empty for an intact chain
+		wantEntries int64
+	}{
+		{"intact", chain, []Checkpoint{signedCheckpoint(1, chain[:2], 2, privateKey)}, publicKey, "", 3},
+		{"intact without key", chain, []Checkpoint{signedCheckpoint(1, chain[:2], 2, otherKey)}, nil, "", 3},
+		{"legacy entries before chaining", legacy, nil, publicKey, "", 2},
+		{"struct-valued details", structured, nil, publicKey, "", 2},
+		{"interleaved consignments", interleaved, []Checkpoint{signedCheckpoint(1, interleaved, 4, privateKey)}, publicKey, "", 4},
+		{"link across consignments", crossLinked, nil, publicKey, "entry 2: prev_hash does not match", 0},
+		{"rewritten consignment chain", rewritten, []Checkpoint{signedCheckpoint(1, interleaved, 4, privateKey)}, publicKey, "entry 4, checkpoint 1: log digest differs", 0},
+		{"edited entry", edited, nil, publicKey, "entry 2: entry contents do not match its hash", 0},
+		{"removed entry", []Entry{chain[0], chain[2]}, nil, publicKey, "entry 3: prev_hash does not match", 0},
+		{"forged checkpoint", chain, []Checkpoint{signedCheckpoint(1, chain[:2], 2, otherKey)}, publicKey, "checkpoint 1: signature does not verify", 0},
+		{"checkpoint count", chain, []Checkpoint{signedCheckpoint(1, chain[:2], 5, privateKey)}, publicKey, "entry 2, checkpoint 1: checkpoint counts 5", 0},
+		{"truncated tail", chain[:2], []Checkpoint{signedCheckpoint(1, chain, 3, privateKey)}, publicKey, "checkpoint 1: checkpoint pins an entry that no longer exists", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := setupTestStore(t)
+			mock.ExpectQuery(`SELECT \* FROM "audit_checkpoints" ORDER BY id`).
+				WillReturnRows(checkpointRows(tt.checkpoints...))
+			mock.ExpectQuery(`SELECT \* FROM "audit_log" WHERE id > \$1 ORDER BY id LIMIT \$2`).
+				WithArgs(0, verifyBatchSize).
+				WillReturnRows(entryRows(t, tt.entries))
+			mock.ExpectQuery(`SELECT \* FROM "audit_log" WHERE id > \$1 ORDER BY id LIMIT \$2`).
+				WithArgs(tt.entries[len(tt.entries)-1].ID, verifyBatchSize).
+				WillReturnRows(entryRows(t, nil))
+
+			report, err := Verify(context.Background(), s.(*store).db, tt.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantBreak == "" {
+				if report.Break != nil {
+					t.Fatalf("expected an intact chain, got break %s", report.Break)
+				}
+			} else if report.Break == nil || !strings.Contains(report.Break.String(), tt.wantBreak) {
+				t.Fatalf("expected break %q, got %+v", tt.wantBreak, report.Break)
+			}
+			if tt.wantEntries != 0 && report.Entries != tt.wantEntries {
+				t.Fatalf("expected %d chained entries, got %d", tt.wantEntries, report.Entries)
+			}
+			if report.SignaturesChecked != (tt.key != nil) {
+				t.Fatalf("unexpected SignaturesChecked %v", report.SignaturesChecked)
+			}
+		})
+	}
+}
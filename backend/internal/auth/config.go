@@ -2,6 +2,8 @@ package auth
 
 import (
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/OpenNSW/nsw/internal/validation"
 )
@@ -12,6 +14,32 @@ type Config struct {
 	Audience              string
 	ClientIDs             []string
 	InsecureSkipTLSVerify bool
+
+	// Introspection optionally confirms tokens with the IdP, so tokens revoked there stop
+	// working before they expire.
+	Introspection IntrospectionConfig
+	// RevocationClientIDs lists the client IDs whose tokens are checked against the local
+	// deny-list of revoked token IDs (jti) and the per-principal logout-everywhere timestamps.
+	RevocationClientIDs []string
+	// RevocationCacheTTL bounds how long a deny-list or logout-everywhere lookup is reused, and
+	// so how long a revocation made on another server replica takes to apply.
+	RevocationCacheTTL time.Duration
+	// PermissionsPath is the client permissions file mapping client IDs and OAuth scopes to
+	// permissions. Without it, M2M clients hold no permissions.
+	PermissionsPath string
+}
+
+// IntrospectionConfig configures RFC 7662 token introspection. Only tokens issued to
+// ClientIDs are introspected; the others are validated offline against the JWKS alone.
+type IntrospectionConfig struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	ClientIDs    []string
+	// CacheTTL bounds how long an active result is reused; it never outlives the token.
+	CacheTTL time.Duration
+	// NegativeCacheTTL bounds how long an inactive result is reused.
+	NegativeCacheTTL time.Duration
 }
 
 func (c Config) Validate() error {
@@ -34,5 +62,36 @@ func (c Config) Validate() error {
 	if len(c.ClientIDs) == 0 {
 		return fmt.Errorf("AUTH_CLIENT_IDS is required")
 	}
+
+	if len(c.Introspection.ClientIDs) > 0 {
+		if c.Introspection.URL == "" {
+			return fmt.Errorf("AUTH_INTROSPECTION_URL is required when AUTH_INTROSPECTION_CLIENT_IDS is set")
+		}
+		if err := validation.HTTPURL("AUTH_INTROSPECTION_URL", c.Introspection.URL); err != nil {
+			return err
+		}
+		if c.Introspection.ClientID == "" {
+			return fmt.Errorf("AUTH_INTROSPECTION_CLIENT_ID is required when AUTH_INTROSPECTION_CLIENT_IDS is set")
+		}
+		if c.Introspection.CacheTTL < 0 || c.Introspection.NegativeCacheTTL < 0 {
+			return fmt.Errorf("introspection cache TTLs must not be negative")
+		}
+		if err := c.checkKnownClientIDs("AUTH_INTROSPECTION_CLIENT_IDS", c.Introspection.ClientIDs); err != nil {
+			return err
+		}
+	}
+	if c.RevocationCacheTTL < 0 {
+		return fmt.Errorf("AUTH_REVOCATION_CACHE_TTL must not be negative")
+	}
+	return c.checkKnownClientIDs("AUTH_REVOCATION_CLIENT_IDS", c.RevocationClientIDs)
+}
+
+// checkKnownClientIDs rejects client IDs that are not accepted at all, which usually means a typo.
+func (c Config) checkKnownClientIDs(name string, clientIDs []string) error {
+	for _, clientID := range clientIDs {
+		if !slices.Contains(c.ClientIDs, clientID) {
+			return fmt.Errorf("%s contains %q, which is not in AUTH_CLIENT_IDS", name, clientID)
+		}
+	}
 	return nil
 }
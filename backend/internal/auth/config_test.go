@@ -24,6 +24,11 @@ func TestConfigValidate(t *testing.T) {
 		{name: "missing issuer", config: Config{JWKSURL: valid.JWKSURL, Audience: valid.Audience, ClientIDs: valid.ClientIDs}, wantErr: true},
 		{name: "missing audience", config: Config{JWKSURL: valid.JWKSURL, Issuer: valid.Issuer, ClientIDs: valid.ClientIDs}, wantErr: true},
 		{name: "missing client ids", config: Config{JWKSURL: valid.JWKSURL, Issuer: valid.Issuer, Audience: valid.Audience}, wantErr: true},
+		{name: "introspection", config: withIntrospection(valid, IntrospectionConfig{URL: "https://localhost/oauth2/introspect", ClientID: "NSW_BACKEND", ClientIDs: valid.ClientIDs})},
+		{name: "introspection without url", config: withIntrospection(valid, IntrospectionConfig{ClientID: "NSW_BACKEND", ClientIDs: valid.ClientIDs}), wantErr: true},
+		{name: "introspection without credentials", config: withIntrospection(valid, IntrospectionConfig{URL: "https://localhost/oauth2/introspect", ClientIDs: valid.ClientIDs}), wantErr: true},
+		{name: "introspection of unknown client", config: withIntrospection(valid, IntrospectionConfig{URL: "https://localhost/oauth2/introspect", ClientID: "NSW_BACKEND", ClientIDs: []string{"OTHER_APP"}}), wantErr: true},
+		{name: "revocation of unknown client", config: Config{JWKSURL: valid.JWKSURL, Issuer: valid.Issuer, Audience: valid.Audience, ClientIDs: valid.ClientIDs, RevocationClientIDs: []string{"OTHER_APP"}}, wantErr: true},
 	}
 
 	for _, tt := range tests {
@@ -38,3 +43,8 @@ func TestConfigValidate(t *testing.T) {
 		})
 	}
 }
+
+func withIntrospection(c Config, introspection IntrospectionConfig) Config {
+	c.Introspection = introspection
+	return c
+}
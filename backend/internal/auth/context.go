@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"slices"
 )
 
 // UserProfileService defines the contract for managing user profiles.
@@ -61,18 +62,51 @@ type UserContext struct {
 	Roles       []string `json:"roles"`
 }
 
+// User roles assigned by the IdP.
+const (
+	// RoleAdmin grants access to platform administration APIs.
+	RoleAdmin = "admin"
+	// RoleTrader is held by users who lodge consignments on behalf of their organisation.
+	RoleTrader = "Trader"
+	// RoleCHA is held by users acting for a customs house agent.
+	RoleCHA = "CHA"
+	// RoleFinance is held by finance officers, who request and approve payment refunds.
+	RoleFinance = "finance"
+)
+
+// HasRole reports whether the user holds role.
+func (u *UserContext) HasRole(role string) bool {
+	return slices.Contains(u.Roles, role)
+}
+
 // ClientContext represents a machine client's context.
+// Permissions and TaskCodes are resolved from the client permissions file for the client
+// ID and the scopes of its token.
 type ClientContext struct {
-	ClientID string
+	ClientID    string
+	Scopes      []string
+	Permissions []Permission
+	TaskCodes   []string
+}
+
+// SystemContext represents the platform itself acting without an external principal,
+// e.g. when a verified payment webhook advances a task.
+// It is never populated by the auth middleware.
+type SystemContext struct {
+	Actor string
 }
 
 // AuthContext is the transient authentication context injected into each request
 // by the auth middleware.
 // For user principals, User contains identity fields and roles.
 // For client principals (M2M), Client is set.
+// For internal system actions, System is set.
+// Token describes the bearer token of user and client principals.
 type AuthContext struct {
 	User   *UserContext
 	Client *ClientContext
+	System *SystemContext
+	Token  *TokenInfo
 }
 
 // ContextKey is a custom type for context keys to avoid collisions.
@@ -98,3 +132,21 @@ func GetAuthContext(ctx context.Context) *AuthContext {
 	}
 	return authCtx
 }
+
+// IsAdmin reports whether ctx was authenticated as an administrator: an M2M client granted
+// PermissionAdmin, or a user holding RoleAdmin.
+func IsAdmin(ctx context.Context) bool {
+	authCtx := GetAuthContext(ctx)
+	if authCtx == nil {
+		return false
+	}
+	return (authCtx.Client != nil && authCtx.Client.HasPermission(PermissionAdmin)) ||
+		(authCtx.User != nil && authCtx.User.HasRole(RoleAdmin))
+}
+
+// WithSystemActor returns a copy of ctx carrying a system AuthContext for actor.
+// Use it when internal components call into services that would otherwise expect
+// an authenticated principal.
+func WithSystemActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, AuthContextKey, &AuthContext{System: &SystemContext{Actor: actor}})
+}
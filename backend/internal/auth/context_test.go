@@ -49,6 +49,47 @@ func TestGetAuthContext_WrongType(t *testing.T) {
 	}
 }
 
+func TestWithSystemActor(t *testing.T) {
+	ctx := WithSystemActor(context.Background(), "payments")
+
+	retrieved := GetAuthContext(ctx)
+	if retrieved == nil || retrieved.System == nil {
+		t.Fatal("expected system auth context")
+	}
+	if retrieved.System.Actor != "payments" {
+		t.Errorf("expected actor payments, got %q", retrieved.System.Actor)
+	}
+	if retrieved.User != nil || retrieved.Client != nil {
+		t.Errorf("expected no user or client principal, got %+v", retrieved)
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	tests := []struct {
+		name    string
+		authCtx *AuthContext
+		want    bool
+	}{
+		{"no principal", nil, false},
+		{"admin client", &AuthContext{Client: &ClientContext{ClientID: "back-office", Permissions: []Permission{PermissionAdmin}}}, true},
+		{"client without admin permission", &AuthContext{Client: &ClientContext{ClientID: "NPQS_TO_NSW", Permissions: []Permission{PermissionTasksExecute}}}, false},
+		{"admin user", &AuthContext{User: &UserContext{ID: "u1", Roles: []string{"exporter", RoleAdmin}}}, true},
+		{"regular user", &AuthContext{User: &UserContext{ID: "u2", Roles: []string{"exporter"}}}, false},
+		{"system actor", &AuthContext{System: &SystemContext{Actor: "payments"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authCtx != nil {
+				ctx = context.WithValue(ctx, AuthContextKey, tt.authCtx)
+			}
+			if got := IsAdmin(ctx); got != tt.want {
+				t.Errorf("IsAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestUserContext_JSONUnmarshaling tests UserContext structure.
 func TestUserContext_Structure(t *testing.T) {
 	uc := &UserContext{
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIntrospectionCacheTTL         = 30 * time.Second
+	defaultIntrospectionNegativeCacheTTL = 10 * time.Second
+	// maxIntrospectionCacheEntries triggers a sweep of expired entries once exceeded.
+	maxIntrospectionCacheEntries = 10000
+)
+
+// ErrTokenRevoked is returned when a token is inactive at the IdP or revoked locally.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// ErrAuthUnavailable is returned when a token could not be checked because the IdP or the
+// revocation store could not be reached. The request must not be treated as authenticated.
+var ErrAuthUnavailable = errors.New("authentication dependency unavailable")
+
+// introspectionResponse is the subset of an RFC 7662 introspection response that is used.
+type introspectionResponse struct {
+	Active bool `json:"active"`
+}
+
+type introspectionResult struct {
+	active    bool
+	expiresAt time.Time
+}
+
+// introspector asks the IdP whether tokens are still active and caches the answers briefly,
+// so each token is introspected at most once per TTL.
+type introspector struct {
+	url          string
+	clientID     string
+	clientSecret string
+	clientIDs    []string
+	ttl          time.Duration
+	negativeTTL  time.Duration
+	httpClient   *http.Client
+	now          func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]introspectionResult
+}
+
+func newIntrospector(cfg IntrospectionConfig, httpClient *http.Client) *introspector {
+	ttl := cfg.CacheTTL
+	if ttl == 0 {
+		ttl = defaultIntrospectionCacheTTL
+	}
+	negativeTTL := cfg.NegativeCacheTTL
+	if negativeTTL == 0 {
+		negativeTTL = defaultIntrospectionNegativeCacheTTL
+	}
+	return &introspector{
+		url:          strings.TrimSpace(cfg.URL),
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		clientIDs:    cfg.ClientIDs,
+		ttl:          ttl,
+		negativeTTL:  negativeTTL,
+		httpClient:   httpClient,
+		now:          time.Now,
+		cache:        make(map[string]introspectionResult),
+	}
+}
+
+// active reports whether the IdP considers token active. tokenExpiry caps how long a positive
+// answer is cached.
+func (i *introspector) active(ctx context.Context, token string, tokenExpiry time.Time) (bool, error) {
+	key := tokenCacheKey(token)
+	now := i.now()
+
+	i.mu.Lock()
+	cached, ok := i.cache[key]
+	i.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.active, nil
+	}
+
+	active, err := i.introspect(ctx, token)
+	if err != nil {
+		return false, err
+	}
+
+	expiresAt := now.Add(i.negativeTTL)
+	if active {
+		expiresAt = now.Add(i.ttl)
+		if tokenExpiry.Before(expiresAt) {
+			expiresAt = tokenExpiry
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.cache) >= maxIntrospectionCacheEntries {
+		for k, v := range i.cache {
+			if !now.Before(v.expiresAt) {
+				delete(i.cache, k)
+			}
+		}
+	}
+	i.cache[key] = introspectionResult{active: active, expiresAt: expiresAt}
+	return active, nil
+}
+
+func (i *introspector) introspect(ctx context.Context, token string) (bool, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, i.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, fmt.Errorf("failed to build introspection request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(url.QueryEscape(i.clientID), url.QueryEscape(i.clientSecret))
+
+	response, err := i.httpClient.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("%w: failed to call introspection endpoint: %v", ErrAuthUnavailable, err)
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("%w: introspection endpoint returned status %d", ErrAuthUnavailable, response.StatusCode)
+	}
+
+	var body introspectionResponse
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return false, fmt.Errorf("%w: failed to decode introspection response: %v", ErrAuthUnavailable, err)
+	}
+	return body.Active, nil
+}
+
+// tokenCacheKey avoids keeping raw bearer tokens in memory longer than the request.
+func tokenCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIntrospectionClientID     = "NSW_BACKEND"
+	testIntrospectionClientSecret = "secret"
+)
+
+// newIntrospectionServer reports the tokens in inactive as inactive and all others as active.
+func newIntrospectionServer(t *testing.T, status int, inactive map[string]bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		clientID, secret, ok := r.BasicAuth()
+		if !ok || clientID != testIntrospectionClientID || secret != testIntrospectionClientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("token") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if inactive[r.PostForm.Get("token")] {
+			_, _ = w.Write([]byte(`{"active":false}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"active":true,"client_id":"` + testClientID + `"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func enableIntrospection(extractor *TokenExtractor, url string, clientIDs []string) *introspector {
+	extractor.introspector = newIntrospector(IntrospectionConfig{
+		URL:          url,
+		ClientID:     testIntrospectionClientID,
+		ClientSecret: testIntrospectionClientSecret,
+		ClientIDs:    clientIDs,
+	}, http.DefaultClient)
+	return extractor.introspector
+}
+
+func TestTokenExtractor_Introspection(t *testing.T) {
+	extractor, privateKey, cleanup := newTokenExtractor(t)
+	defer cleanup()
+
+	activeToken := newUserToken(t, privateKey)
+	revokedToken := newClientToken(t, privateKey)
+	server, calls := newIntrospectionServer(t, http.StatusOK, map[string]bool{revokedToken: true})
+	enableIntrospection(extractor, server.URL, []string{testClientID})
+
+	for range 2 {
+		if _, err := extractor.ExtractPrincipal(context.Background(), "Bearer "+activeToken); err != nil {
+			t.Fatalf("expected active token to be accepted, got %v", err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected the active result to be cached, got %d introspection calls", got)
+	}
+
+	for range 2 {
+		_, err := extractor.ExtractPrincipal(context.Background(), "Bearer "+revokedToken)
+		if !errors.Is(err, ErrTokenRevoked) {
+			t.Fatalf("expected ErrTokenRevoked for inactive token, got %v", err)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected the inactive result to be cached, got %d introspection calls", got)
+	}
+}
+
+func TestTokenExtractor_Introspection_CacheExpires(t *testing.T) {
+	extractor, privateKey, cleanup := newTokenExtractor(t)
+	defer cleanup()
+
+	server, calls := newIntrospectionServer(t, http.StatusOK, nil)
+	introspector := enableIntrospection(extractor, server.URL, []string{testClientID})
+	now := time.Now()
+	introspector.now = func() time.Time { return now }
+
+	header := "Bearer " + newUserToken(t, privateKey)
+	if _, err := extractor.ExtractPrincipal(context.Background(), header); err != nil {
+		t.Fatalf("expected token to be accepted, got %v", err)
+	}
+	now = now.Add(defaultIntrospectionCacheTTL + time.Second)
+	if _, err := extractor.ExtractPrincipal(context.Background(), header); err != nil {
+		t.Fatalf("expected token to be accepted, got %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected the token to be introspected again after the TTL, got %d calls", got)
+	}
+}
+
+func TestTokenExtractor_Introspection_OnlyConfiguredClients(t *testing.T) {
+	extractor, privateKey, cleanup := newTokenExtractor(t)
+	defer cleanup()
+
+	server, calls := newIntrospectionServer(t, http.StatusOK, nil)
+	enableIntrospection(extractor, server.URL, []string{"NPQS_TO_NSW"})
+
+	if _, err := extractor.ExtractPrincipal(context.Background(), "Bearer "+newUserToken(t, privateKey)); err != nil {
+		t.Fatalf("expected token to be accepted, got %v", err)
+	}
+	if got := calls.Load(); got != 0 {
+		t.Fatalf("expected no introspection for other client IDs, got %d calls", got)
+	}
+}
+
+func TestTokenExtractor_Introspection_Unavailable(t *testing.T) {
+	extractor, privateKey, cleanup := newTokenExtractor(t)
+	defer cleanup()
+
+	server, _ := newIntrospectionServer(t, http.StatusServiceUnavailable, nil)
+	enableIntrospection(extractor, server.URL, []string{testClientID})
+
+	_, err := extractor.ExtractPrincipal(context.Background(), "Bearer "+newUserToken(t, privateKey))
+	if !errors.Is(err, ErrAuthUnavailable) {
+		t.Fatalf("expected ErrAuthUnavailable, got %v", err)
+	}
+}
+
+func TestAuthMiddleware_IntrospectionUnavailable(t *testing.T) {
+	extractor, privateKey, cleanup := newTokenExtractor(t)
+	defer cleanup()
+
+	server, _ := newIntrospectionServer(t, http.StatusInternalServerError, nil)
+	enableIntrospection(extractor, server.URL, []string{testClientID})
+
+	handlerCalled := false
+	handler := Middleware(nil, extractor)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+	}))
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/test", nil)
+	req.Header.Set("Authorization", "Bearer "+newUserToken(t, privateKey))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", recorder.Code)
+	}
+	if handlerCalled {
+		t.Fatalf("expected handler not to be called")
+	}
+}
@@ -19,6 +19,19 @@ type Manager struct {
 	userProfileService UserProfileService
 	tokenExtractor     *TokenExtractor
 	middleware         func(http.Handler) http.Handler
+	revocations        RevocationStore
+}
+
+// ManagerOption customises a Manager created by NewManager.
+type ManagerOption func(*Manager)
+
+// WithRevocationStore sets the store of revoked tokens and logout-everywhere timestamps.
+// Without it, a Manager with revocation checks enabled keeps them in memory, which only
+// suits a single server replica.
+func WithRevocationStore(store RevocationStore) ManagerOption {
+	return func(m *Manager) {
+		m.revocations = store
+	}
 }
 
 // NewManager creates and initializes a new auth manager.
@@ -41,7 +54,11 @@ type Manager struct {
 //	// With custom user profile service
 //	customService := &MyCustomUserService{}
 //	authManager := auth.NewManager(customService, cfg.Auth)
-func NewManager(userProfileService UserProfileService, authConfig Config) (*Manager, error) {
+//
+// Token introspection and revocation checks are enabled for the client IDs listed in
+// authConfig.Introspection.ClientIDs and authConfig.RevocationClientIDs respectively.
+// Client permissions are loaded from authConfig.PermissionsPath.
+func NewManager(userProfileService UserProfileService, authConfig Config, opts ...ManagerOption) (*Manager, error) {
 	slog.Info("initializing auth manager", "user_profile_service_enabled", userProfileService != nil)
 
 	httpClient := &http.Client{Timeout: 10 * time.Second}
@@ -61,11 +78,39 @@ func NewManager(userProfileService UserProfileService, authConfig Config) (*Mana
 		return nil, fmt.Errorf("token extractor not initialized")
 	}
 
-	return &Manager{
+	m := &Manager{
 		userProfileService: userProfileService,
 		tokenExtractor:     tokenExtractor,
-		middleware:         Middleware(userProfileService, tokenExtractor),
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if len(authConfig.Introspection.ClientIDs) > 0 {
+		tokenExtractor.introspector = newIntrospector(authConfig.Introspection, httpClient)
+		slog.Info("token introspection enabled", "client_ids", authConfig.Introspection.ClientIDs)
+	}
+	if len(authConfig.RevocationClientIDs) > 0 {
+		if m.revocations == nil {
+			m.revocations = NewMemoryRevocationStore()
+		}
+		m.revocations = newCachedRevocationStore(m.revocations, authConfig.RevocationCacheTTL)
+		tokenExtractor.revocations = m.revocations
+		tokenExtractor.revocationClientIDs = authConfig.RevocationClientIDs
+		slog.Info("token revocation checks enabled", "client_ids", authConfig.RevocationClientIDs)
+	}
+
+	if authConfig.PermissionsPath != "" {
+		permissions, err := LoadPermissions(authConfig.PermissionsPath)
+		if err != nil {
+			return nil, err
+		}
+		tokenExtractor.permissions = permissions
+		slog.Info("client permissions loaded", "path", authConfig.PermissionsPath, "clients", len(permissions.Clients), "scopes", len(permissions.Scopes))
+	}
+
+	m.middleware = Middleware(userProfileService, tokenExtractor)
+	return m, nil
 }
 
 // Middleware returns the auth middleware function.
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 )
@@ -15,8 +16,8 @@ import (
 //
 // Behavior summary:
 // - Missing Authorization header: request proceeds without auth context.
-// - Invalid token: request is rejected with 401.
-// - Auth dependencies unavailable: request is rejected with 500.
+// - Invalid or revoked token: request is rejected with 401.
+// - Auth dependencies unavailable (including introspection and the revocation store): request is rejected with 500.
 // - User principal on first login: resolves (get-or-create) user profile if service is provided.
 //
 // This design allows:
@@ -42,7 +43,14 @@ func Middleware(userProfileService UserProfileService, tokenExtractor *TokenExtr
 				return
 			}
 
-			principal, err := tokenExtractor.ExtractPrincipalFromHeader(authHeader)
+			principal, err := tokenExtractor.ExtractPrincipal(r.Context(), authHeader)
+			if errors.Is(err, ErrAuthUnavailable) {
+				slog.Error("auth middleware: failed to check token", "error", err)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = w.Write([]byte(`{"error":"internal_server_error","message":"authentication subsystem unavailable"}`))
+				return
+			}
 			if err != nil {
 				slog.Warn("failed to extract principal from token", "error", err)
 				w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+)
+
+// Permission is an operation an M2M client may be granted. Users are not subject to
+// permissions; their access is decided by the handlers from their roles.
+type Permission string
+
+const (
+	PermissionTasksExecute Permission = "tasks:execute"
+	PermissionTasksRead    Permission = "tasks:read"
+	// PermissionConsignmentsRead covers reading consignments and pre-consignments.
+	PermissionConsignmentsRead Permission = "consignments:read"
+	// PermissionConsignmentsWrite covers creating and initializing consignments and pre-consignments.
+	PermissionConsignmentsWrite Permission = "consignments:write"
+	PermissionUploadsRead       Permission = "uploads:read"
+	PermissionUploadsWrite      Permission = "uploads:write"
+	// PermissionReferenceRead covers reference data such as HS codes and CHAs.
+	PermissionReferenceRead Permission = "reference:read"
+	PermissionPaymentsRead  Permission = "payments:read"
+	// PermissionPaymentsWrite covers settlement imports and refunds.
+	PermissionPaymentsWrite Permission = "payments:write"
+	// PermissionAdmin covers the platform administration APIs.
+	PermissionAdmin Permission = "admin"
+)
+
+var knownPermissions = []Permission{
+	PermissionTasksExecute, PermissionTasksRead,
+	PermissionConsignmentsRead, PermissionConsignmentsWrite,
+	PermissionUploadsRead, PermissionUploadsWrite,
+	PermissionReferenceRead,
+	PermissionPaymentsRead, PermissionPaymentsWrite,
+	PermissionAdmin,
+}
+
+// Grant is a set of permissions given to a client ID or an OAuth scope.
+type Grant struct {
+	Permissions []Permission `json:"permissions"`
+	// TaskCodes are path.Match patterns, e.g. "npqs_*", of the task codes
+	// (submission.request.taskCode) whose tasks the client may complete.
+	TaskCodes []string `json:"task_codes,omitempty"`
+}
+
+// PermissionsConfig is the root document of client_permissions.json. A client holds the
+// union of the grant of its client ID and the grants of the scopes in its token.
+type PermissionsConfig struct {
+	Clients map[string]Grant `json:"clients"`
+	Scopes  map[string]Grant `json:"scopes,omitempty"`
+}
+
+// LoadPermissions reads and validates a client permissions file.
+func LoadPermissions(file string) (*PermissionsConfig, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client permissions file %s: %w", file, err)
+	}
+	var cfg PermissionsConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse client permissions file %s: %w", file, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid client permissions file %s: %w", file, err)
+	}
+	return &cfg, nil
+}
+
+// Validate rejects unknown permissions and malformed task code patterns.
+func (c *PermissionsConfig) Validate() error {
+	validate := func(kind, name string, grant Grant) error {
+		for _, permission := range grant.Permissions {
+			if !slices.Contains(knownPermissions, permission) {
+				return fmt.Errorf("%s %s: unknown permission %q", kind, name, permission)
+			}
+		}
+		for _, pattern := range grant.TaskCodes {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s %s: invalid task code pattern %q", kind, name, pattern)
+			}
+		}
+		return nil
+	}
+	for clientID, grant := range c.Clients {
+		if err := validate("client", clientID, grant); err != nil {
+			return err
+		}
+	}
+	for scope, grant := range c.Scopes {
+		if err := validate("scope", scope, grant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the grant of a client holding scopes. A nil config grants nothing.
+func (c *PermissionsConfig) resolve(clientID string, scopes []string) Grant {
+	var grant Grant
+	if c == nil {
+		return grant
+	}
+	add := func(g Grant) {
+		for _, permission := range g.Permissions {
+			if !slices.Contains(grant.Permissions, permission) {
+				grant.Permissions = append(grant.Permissions, permission)
+			}
+		}
+		grant.TaskCodes = append(grant.TaskCodes, g.TaskCodes...)
+	}
+	if g, ok := c.Clients[clientID]; ok {
+		add(g)
+	}
+	for _, scope := range scopes {
+		if g, ok := c.Scopes[scope]; ok {
+			add(g)
+		}
+	}
+	return grant
+}
+
+// HasPermission reports whether the client was granted permission.
+func (c *ClientContext) HasPermission(permission Permission) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// MayCompleteTask reports whether the client was granted the tasks with code taskCode.
+func (c *ClientContext) MayCompleteTask(taskCode string) bool {
+	if taskCode == "" {
+		return false
+	}
+	for _, pattern := range c.TaskCodes {
+		if ok, _ := path.Match(pattern, taskCode); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission returns a middleware that rejects M2M clients lacking permission with
+// 403 Forbidden. Users and unauthenticated requests are passed through unchanged, so it
+// composes with the auth middleware and with the handlers' own checks.
+//
+// Usage:
+//
+//	mux.Handle("GET /api/v1/consignments", withAuth(auth.RequirePermission(auth.PermissionConsignmentsRead)(handler)))
+func RequirePermission(permission Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authCtx := GetAuthContext(r.Context())
+			if authCtx != nil && authCtx.Client != nil && !authCtx.Client.HasPermission(permission) {
+				writeAuthError(w, http.StatusForbidden, fmt.Sprintf("client %s lacks permission %s", authCtx.Client.ClientID, permission))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writePermissionsFile(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "client_permissions.json")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write permissions file: %v", err)
+	}
+	return file
+}
+
+func TestLoadPermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"clients": {"NPQS_TO_NSW": {"permissions": ["tasks:execute"], "task_codes": ["npqs_*"]}}, "scopes": {"nsw:admin": {"permissions": ["admin"]}}}`, false},
+		{"unknown client permission", `{"clients": {"NPQS_TO_NSW": {"permissions": ["tasks:delete"]}}}`, true},
+		{"unknown scope permission", `{"scopes": {"nsw:admin": {"permissions": ["root"]}}}`, true},
+		{"invalid task code pattern", `{"clients": {"NPQS_TO_NSW": {"permissions": [], "task_codes": ["npqs_["]}}}`, true},
+		{"malformed JSON", `{"clients": `, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadPermissions(writePermissionsFile(t, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPermissionsConfig_Resolve(t *testing.T) {
+	cfg := &PermissionsConfig{
+		Clients: map[string]Grant{
+			"NPQS_TO_NSW": {Permissions: []Permission{PermissionTasksExecute, PermissionUploadsRead}, TaskCodes: []string{"npqs_*"}},
+		},
+		Scopes: map[string]Grant{
+			"nsw:uploads:write": {Permissions: []Permission{PermissionUploadsRead, PermissionUploadsWrite}},
+		},
+	}
+
+	grant := cfg.resolve("NPQS_TO_NSW", []string{"openid", "nsw:uploads:write"})
+	want := []Permission{PermissionTasksExecute, PermissionUploadsRead, PermissionUploadsWrite}
+	if !slices.Equal(grant.Permissions, want) {
+		t.Fatalf("expected permissions %v, got %v", want, grant.Permissions)
+	}
+	if !slices.Equal(grant.TaskCodes, []string{"npqs_*"}) {
+		t.Fatalf("expected task codes [npqs_*], got %v", grant.TaskCodes)
+	}
+
+	if grant := cfg.resolve("IRD_TO_NSW", nil); len(grant.Permissions) != 0 {
+		t.Fatalf("expected unknown client to hold no permissions, got %v", grant.Permissions)
+	}
+	var nilConfig *PermissionsConfig
+	if grant := nilConfig.resolve("NPQS_TO_NSW", nil); len(grant.Permissions) != 0 {
+		t.Fatalf("expected no permissions without a config, got %v", grant.Permissions)
+	}
+}
+
+func TestClientContext_MayCompleteTask(t *testing.T) {
+	client := &ClientContext{ClientID: "NPQS_TO_NSW", TaskCodes: []string{"npqs_*", "ship_departure_v1"}}
+
+	tests := []struct {
+		taskCode string
+		want     bool
+	}{
+		{"npqs_phytosanitary_v1", true},
+		{"ship_departure_v1", true},
+		{"fcau_health_certificate_v1", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := client.MayCompleteTask(tt.taskCode); got != tt.want {
+			t.Errorf("MayCompleteTask(%q) = %v, want %v", tt.taskCode, got, tt.want)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name    string
+		authCtx *AuthContext
+		want    int
+	}{
+		{"client with permission", &AuthContext{Client: &ClientContext{ClientID: "NPQS_TO_NSW", Permissions: []Permission{PermissionUploadsRead}}}, http.StatusOK},
+		{"client without permission", &AuthContext{Client: &ClientContext{ClientID: "NPQS_TO_NSW", Permissions: []Permission{PermissionTasksRead}}}, http.StatusForbidden},
+		{"user", &AuthContext{User: &UserContext{ID: "trader-1"}}, http.StatusOK},
+		{"unauthenticated", nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequirePermission(PermissionUploadsRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/api/v1/uploads/key", nil)
+			if tt.authCtx != nil {
+				req = req.WithContext(context.WithValue(req.Context(), AuthContextKey, tt.authCtx))
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			if recorder.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestTokenExtractor_ResolvesClientPermissions(t *testing.T) {
+	extractor, privateKey, cleanup := newTokenExtractor(t)
+	defer cleanup()
+
+	extractor.permissions = &PermissionsConfig{
+		Clients: map[string]Grant{
+			testClientID: {Permissions: []Permission{PermissionTasksExecute}, TaskCodes: []string{"fcau_*"}},
+		},
+		Scopes: map[string]Grant{
+			"nsw:uploads:write": {Permissions: []Permission{PermissionUploadsWrite}},
+		},
+	}
+
+	claims := newBaseClaims(ClientCredentialsGrant)
+	claims["sub"] = testClientID
+	claims["scope"] = "openid nsw:uploads:write"
+	principal, err := extractor.ExtractPrincipal(context.Background(), "Bearer "+signToken(t, privateKey, claims))
+	if err != nil {
+		t.Fatalf("expected token to be accepted, got %v", err)
+	}
+	client := principal.ClientPrincipal
+	if client == nil {
+		t.Fatalf("expected a client principal")
+	}
+	if !slices.Equal(client.Scopes, []string{"openid", "nsw:uploads:write"}) {
+		t.Fatalf("unexpected scopes: %v", client.Scopes)
+	}
+	if !slices.Equal(client.Permissions, []Permission{PermissionTasksExecute, PermissionUploadsWrite}) {
+		t.Fatalf("unexpected permissions: %v", client.Permissions)
+	}
+	if !slices.Equal(client.TaskCodes, []string{"fcau_*"}) {
+		t.Fatalf("unexpected task codes: %v", client.TaskCodes)
+	}
+}
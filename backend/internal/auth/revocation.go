@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RevocationStore records locally revoked tokens and logout-everywhere timestamps.
+// Implementations shared by several server replicas must be backed by shared storage.
+type RevocationStore interface {
+	// RevokeToken adds a token ID (jti) to the deny-list until the token expires.
+	RevokeToken(ctx context.Context, tokenID, subject string, expiresAt time.Time) error
+	// IsTokenRevoked reports whether a token ID is on the deny-list.
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+	// LogoutEverywhere revokes every token of subject issued before at.
+	LogoutEverywhere(ctx context.Context, subject string, at time.Time) error
+	// LoggedOutAt returns the latest logout-everywhere timestamp of subject, or the zero
+	// time if there is none.
+	LoggedOutAt(ctx context.Context, subject string) (time.Time, error)
+}
+
+// MemoryRevocationStore is a RevocationStore for a single server process.
+type MemoryRevocationStore struct {
+	mu         sync.RWMutex
+	revoked    map[string]time.Time
+	loggedOut  map[string]time.Time
+	now        func() time.Time
+	lastPurged time.Time
+}
+
+// NewMemoryRevocationStore creates an empty in-memory RevocationStore.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked:   make(map[string]time.Time),
+		loggedOut: make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// RevokeToken adds tokenID to the deny-list. Entries of tokens that have since expired are
+// purged at most once a minute.
+func (s *MemoryRevocationStore) RevokeToken(_ context.Context, tokenID, _ string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.lastPurged) > time.Minute {
+		for id, exp := range s.revoked {
+			if exp.Before(now) {
+				delete(s.revoked, id)
+			}
+		}
+		s.lastPurged = now
+	}
+	s.revoked[tokenID] = expiresAt
+	return nil
+}
+
+// IsTokenRevoked reports whether tokenID is on the deny-list.
+func (s *MemoryRevocationStore) IsTokenRevoked(_ context.Context, tokenID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.revoked[tokenID]
+	return ok, nil
+}
+
+// LogoutEverywhere records at as the logout-everywhere timestamp of subject unless a later one exists.
+func (s *MemoryRevocationStore) LogoutEverywhere(_ context.Context, subject string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if at.After(s.loggedOut[subject]) {
+		s.loggedOut[subject] = at
+	}
+	return nil
+}
+
+// LoggedOutAt returns the logout-everywhere timestamp of subject.
+func (s *MemoryRevocationStore) LoggedOutAt(_ context.Context, subject string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loggedOut[subject], nil
+}
+
+const (
+	defaultRevocationCacheTTL = 5 * time.Second
+	// maxRevocationCacheEntries triggers a sweep of expired entries once exceeded.
+	maxRevocationCacheEntries = 10000
+)
+
+type revocationLookup struct {
+	revoked     bool
+	loggedOutAt time.Time
+	expiresAt   time.Time
+}
+
+// cachedRevocationStore reuses the deny-list and logout-everywhere lookups of a
+// RevocationStore for a short TTL, so the store is not queried on every request.
+// Revocations made through it take effect at once; those made on other replicas take up
+// to the TTL.
+type cachedRevocationStore struct {
+	store RevocationStore
+	ttl   time.Duration
+	now   func() time.Time
+
+	mu        sync.Mutex
+	revoked   map[string]revocationLookup // by token ID
+	loggedOut map[string]revocationLookup // by subject
+}
+
+func newCachedRevocationStore(store RevocationStore, ttl time.Duration) *cachedRevocationStore {
+	if ttl == 0 {
+		ttl = defaultRevocationCacheTTL
+	}
+	return &cachedRevocationStore{
+		store:     store,
+		ttl:       ttl,
+		now:       time.Now,
+		revoked:   make(map[string]revocationLookup),
+		loggedOut: make(map[string]revocationLookup),
+	}
+}
+
+// RevokeToken revokes tokenID in the underlying store.
+func (c *cachedRevocationStore) RevokeToken(ctx context.Context, tokenID, subject string, expiresAt time.Time) error {
+	if err := c.store.RevokeToken(ctx, tokenID, subject, expiresAt); err != nil {
+		return err
+	}
+	c.put(c.revoked, tokenID, revocationLookup{revoked: true})
+	return nil
+}
+
+// IsTokenRevoked reports whether tokenID is on the deny-list, reusing a recent answer.
+func (c *cachedRevocationStore) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if cached, ok := c.get(c.revoked, tokenID); ok {
+		return cached.revoked, nil
+	}
+	revoked, err := c.store.IsTokenRevoked(ctx, tokenID)
+	if err != nil {
+		return false, err
+	}
+	c.put(c.revoked, tokenID, revocationLookup{revoked: revoked})
+	return revoked, nil
+}
+
+// LogoutEverywhere records the logout in the underlying store.
+func (c *cachedRevocationStore) LogoutEverywhere(ctx context.Context, subject string, at time.Time) error {
+	if err := c.store.LogoutEverywhere(ctx, subject, at); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	delete(c.loggedOut, subject)
+	c.mu.Unlock()
+	return nil
+}
+
+// LoggedOutAt returns the logout-everywhere timestamp of subject, reusing a recent answer.
+func (c *cachedRevocationStore) LoggedOutAt(ctx context.Context, subject string) (time.Time, error) {
+	if cached, ok := c.get(c.loggedOut, subject); ok {
+		return cached.loggedOutAt, nil
+	}
+	loggedOutAt, err := c.store.LoggedOutAt(ctx, subject)
+	if err != nil {
+		return time.Time{}, err
+	}
+	c.put(c.loggedOut, subject, revocationLookup{loggedOutAt: loggedOutAt})
+	return loggedOutAt, nil
+}
+
+func (c *cachedRevocationStore) get(cache map[string]revocationLookup, key string) (revocationLookup, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := cache[key]
+	if !ok || !c.now().Before(cached.expiresAt) {
+		return revocationLookup{}, false
+	}
+	return cached, true
+}
+
+func (c *cachedRevocationStore) put(cache map[string]revocationLookup, key string, lookup revocationLookup) {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(cache) >= maxRevocationCacheEntries {
+		for k, v := range cache {
+			if !now.Before(v.expiresAt) {
+				delete(cache, k)
+			}
+		}
+	}
+	lookup.expiresAt = now.Add(c.ttl)
+	cache[key] = lookup
+}
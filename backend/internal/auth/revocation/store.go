@@ -0,0 +1,98 @@
+// Package revocation persists revoked tokens and logout-everywhere timestamps, so that
+// revocations made through one server replica are enforced by all of them.
+package revocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// RevokedToken is a token ID on the deny-list.
+type RevokedToken struct {
+	TokenID   string    `gorm:"type:varchar(255);column:jti;primaryKey"`
+	Subject   string    `gorm:"type:varchar(255);column:subject;not null"`
+	ExpiresAt time.Time `gorm:"column:expires_at;not null"`
+	RevokedAt time.Time `gorm:"column:revoked_at;not null;autoCreateTime"`
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+// PrincipalLogout records when a principal last logged out everywhere.
+type PrincipalLogout struct {
+	Subject     string    `gorm:"type:varchar(255);column:subject;primaryKey"`
+	LoggedOutAt time.Time `gorm:"column:logged_out_at;not null"`
+}
+
+func (PrincipalLogout) TableName() string {
+	return "principal_logouts"
+}
+
+type store struct {
+	db *gorm.DB
+}
+
+// NewStore creates a database-backed auth.RevocationStore.
+func NewStore(db *gorm.DB) auth.RevocationStore {
+	return &store{db: db}
+}
+
+// RevokeToken adds tokenID to the deny-list. Tokens that have expired since they were
+// revoked are purged on the way, since they are rejected regardless.
+func (s *store) RevokeToken(ctx context.Context, tokenID, subject string, expiresAt time.Time) error {
+	db := s.db.WithContext(ctx)
+	if err := db.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error; err != nil {
+		return fmt.Errorf("failed to purge expired revoked tokens: %w", err)
+	}
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&RevokedToken{
+		TokenID:   tokenID,
+		Subject:   subject,
+		ExpiresAt: expiresAt,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke token %s: %w", tokenID, err)
+	}
+	return nil
+}
+
+// IsTokenRevoked reports whether tokenID is on the deny-list.
+func (s *store) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&RevokedToken{}).Where("jti = ?", tokenID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to look up revoked token %s: %w", tokenID, err)
+	}
+	return count > 0, nil
+}
+
+// LogoutEverywhere records at as the logout timestamp of subject, keeping a later one if present.
+func (s *store) LogoutEverywhere(ctx context.Context, subject string, at time.Time) error {
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subject"}},
+		DoUpdates: clause.Assignments(map[string]any{"logged_out_at": gorm.Expr("GREATEST(principal_logouts.logged_out_at, EXCLUDED.logged_out_at)")}),
+	}).Create(&PrincipalLogout{Subject: subject, LoggedOutAt: at}).Error
+	if err != nil {
+		return fmt.Errorf("failed to record logout of %s: %w", subject, err)
+	}
+	return nil
+}
+
+// LoggedOutAt returns the logout timestamp of subject, or the zero time if there is none.
+func (s *store) LoggedOutAt(ctx context.Context, subject string) (time.Time, error) {
+	var logout PrincipalLogout
+	err := s.db.WithContext(ctx).Where("subject = ?", subject).Take(&logout).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to look up logout of %s: %w", subject, err)
+	}
+	return logout.LoggedOutAt, nil
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ErrRevocationDisabled is returned when revocation checks are not enabled for a token's client ID,
+// so revoking it would have no effect.
+var ErrRevocationDisabled = errors.New("token revocation is not enabled for this client")
+
+// errInvalidRevocation marks revocation requests that can never succeed as made.
+var errInvalidRevocation = errors.New("invalid revocation request")
+
+// RevokeToken adds token to the deny-list until it expires.
+func (m *Manager) RevokeToken(ctx context.Context, token *TokenInfo) error {
+	if err := m.checkRevocable(token); err != nil {
+		return err
+	}
+	if token.ID == "" {
+		return fmt.Errorf("%w: token has no jti claim and cannot be revoked individually", errInvalidRevocation)
+	}
+	return m.revocations.RevokeToken(ctx, token.ID, token.Subject, token.ExpiresAt)
+}
+
+// LogoutEverywhere revokes all tokens of subject issued until now.
+func (m *Manager) LogoutEverywhere(ctx context.Context, subject string) error {
+	if m.revocations == nil {
+		return ErrRevocationDisabled
+	}
+	if subject == "" {
+		return fmt.Errorf("%w: subject is required", errInvalidRevocation)
+	}
+	return m.revocations.LogoutEverywhere(ctx, subject, time.Now())
+}
+
+func (m *Manager) checkRevocable(token *TokenInfo) error {
+	if token == nil {
+		return fmt.Errorf("%w: request has no bearer token", errInvalidRevocation)
+	}
+	if m.revocations == nil || !slices.Contains(m.tokenExtractor.revocationClientIDs, token.ClientID) {
+		return ErrRevocationDisabled
+	}
+	return nil
+}
+
+// HandleLogout handles POST /api/v1/auth/logout by revoking the token the request was made with.
+func (m *Manager) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	authCtx := GetAuthContext(r.Context())
+	if authCtx == nil || authCtx.Token == nil {
+		writeAuthError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	if err := m.RevokeToken(r.Context(), authCtx.Token); err != nil {
+		writeRevocationError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "token revoked", "subject", authCtx.Token.Subject, "jti", authCtx.Token.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleLogoutEverywhere handles POST /api/v1/auth/logout-everywhere by revoking every token of
+// the requesting principal, including the one the request was made with.
+func (m *Manager) HandleLogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	authCtx := GetAuthContext(r.Context())
+	if authCtx == nil || authCtx.Token == nil {
+		writeAuthError(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	if err := m.checkRevocable(authCtx.Token); err != nil {
+		writeRevocationError(w, r, err)
+		return
+	}
+	if err := m.LogoutEverywhere(r.Context(), authCtx.Token.Subject); err != nil {
+		writeRevocationError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "principal logged out everywhere", "subject", authCtx.Token.Subject)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleAdminLogoutEverywhere handles POST /api/v1/admin/principals/{subject}/logout-everywhere,
+// letting an administrator revoke every token of another principal, e.g. a departed officer.
+func (m *Manager) HandleAdminLogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	if !IsAdmin(r.Context()) {
+		writeAuthError(w, http.StatusForbidden, "logging out another principal requires an administrator")
+		return
+	}
+	subject := r.PathValue("subject")
+	if err := m.LogoutEverywhere(r.Context(), subject); err != nil {
+		writeRevocationError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "principal logged out everywhere by administrator", "subject", subject)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeRevocationError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrRevocationDisabled):
+		writeAuthError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, errInvalidRevocation):
+		writeAuthError(w, http.StatusBadRequest, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "failed to record token revocation", "error", err)
+		writeAuthError(w, http.StatusInternalServerError, "failed to revoke token")
+	}
+}
+
+func writeAuthError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": message}); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenExtractor_RevocationChecks(t *testing.T) {
+	extractor, privateKey, cleanup := newTokenExtractor(t)
+	defer cleanup()
+
+	store := NewMemoryRevocationStore()
+	extractor.revocations = store
+	extractor.revocationClientIDs = []string{testClientID}
+	ctx := context.Background()
+
+	claims := newBaseClaims(AuthorizationCodeGrant)
+	claims["sub"] = testUserID
+	claims["email"] = testEmail
+	claims["ouId"] = testOUID
+	claims["jti"] = "token-1"
+	header := "Bearer " + signToken(t, privateKey, claims)
+
+	principal, err := extractor.ExtractPrincipal(ctx, header)
+	if err != nil {
+		t.Fatalf("expected token to be accepted, got %v", err)
+	}
+	if principal.Token == nil || principal.Token.ID != "token-1" || principal.Token.Subject != testUserID {
+		t.Fatalf("unexpected token info: %+v", principal.Token)
+	}
+
+	// A logout before the token was issued does not affect it.
+	if err := store.LogoutEverywhere(ctx, testUserID, time.Now().Add(-2*time.Minute)); err != nil {
+		t.Fatalf("failed to log out: %v", err)
+	}
+	if _, err := extractor.ExtractPrincipal(ctx, header); err != nil {
+		t.Fatalf("expected token issued after the logout to be accepted, got %v", err)
+	}
+
+	if err := store.RevokeToken(ctx, "token-1", testUserID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if _, err := extractor.ExtractPrincipal(ctx, header); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked for revoked jti, got %v", err)
+	}
+
+	claims["jti"] = "token-2"
+	header = "Bearer " + signToken(t, privateKey, claims)
+	if err := store.LogoutEverywhere(ctx, testUserID, time.Now()); err != nil {
+		t.Fatalf("failed to log out: %v", err)
+	}
+	if _, err := extractor.ExtractPrincipal(ctx, header); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked for token issued before logout, got %v", err)
+	}
+
+	// Revocation checks are not enabled for other client IDs.
+	extractor.revocationClientIDs = []string{"NPQS_TO_NSW"}
+	if _, err := extractor.ExtractPrincipal(ctx, header); err != nil {
+		t.Fatalf("expected token of unchecked client to be accepted, got %v", err)
+	}
+}
+
+func TestManager_HandleLogout(t *testing.T) {
+	extractor, privateKey, cleanup := newTokenExtractor(t)
+	defer cleanup()
+
+	store := NewMemoryRevocationStore()
+	extractor.revocations = store
+	extractor.revocationClientIDs = []string{testClientID}
+	manager := &Manager{tokenExtractor: extractor, revocations: store}
+
+	claims := newBaseClaims(AuthorizationCodeGrant)
+	claims["sub"] = testUserID
+	claims["email"] = testEmail
+	claims["ouId"] = testOUID
+	claims["jti"] = "token-1"
+	token := signToken(t, privateKey, claims)
+
+	serve := func(handler http.HandlerFunc) int {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/api/v1/auth/logout", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		manager.RequireAuthMiddleware()(handler).ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := serve(manager.HandleLogout); code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", code)
+	}
+	if code := serve(manager.HandleLogout); code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked token to be rejected with 401, got %d", code)
+	}
+}
+
+func TestManager_HandleLogoutEverywhere(t *testing.T) {
+	extractor, privateKey, cleanup := newTokenExtractor(t)
+	defer cleanup()
+
+	store := NewMemoryRevocationStore()
+	extractor.revocations = store
+	extractor.revocationClientIDs = []string{testClientID}
+	manager := &Manager{tokenExtractor: extractor, revocations: store}
+
+	token := newUserToken(t, privateKey)
+	serve := func() int {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/api/v1/auth/logout-everywhere", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		manager.RequireAuthMiddleware()(http.HandlerFunc(manager.HandleLogoutEverywhere)).ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if code := serve(); code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", code)
+	}
+	if code := serve(); code != http.StatusUnauthorized {
+		t.Fatalf("expected token issued before logout to be rejected with 401, got %d", code)
+	}
+}
+
+func TestManager_HandleAdminLogoutEverywhere(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	manager := &Manager{tokenExtractor: &TokenExtractor{}, revocations: store}
+
+	tests := []struct {
+		name    string
+		authCtx *AuthContext
+		want    int
+	}{
+		{"admin", &AuthContext{User: &UserContext{ID: "admin-1", Roles: []string{RoleAdmin}}}, http.StatusNoContent},
+		{"admin client", &AuthContext{Client: &ClientContext{ClientID: "BACK_OFFICE", Permissions: []Permission{PermissionAdmin}}}, http.StatusNoContent},
+		{"other client", &AuthContext{Client: &ClientContext{ClientID: "NPQS_TO_NSW"}}, http.StatusForbidden},
+		{"non-admin", &AuthContext{User: &UserContext{ID: "trader-1"}}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/api/v1/admin/principals/officer-1/logout-everywhere", nil)
+			req.SetPathValue("subject", "officer-1")
+			req = req.WithContext(context.WithValue(req.Context(), AuthContextKey, tt.authCtx))
+			recorder := httptest.NewRecorder()
+			manager.HandleAdminLogoutEverywhere(recorder, req)
+			if recorder.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, recorder.Code)
+			}
+		})
+	}
+
+	loggedOutAt, err := store.LoggedOutAt(context.Background(), "officer-1")
+	if err != nil || loggedOutAt.IsZero() {
+		t.Fatalf("expected logout of officer-1 to be recorded, got %v, %v", loggedOutAt, err)
+	}
+}
+
+func TestTokenExtractor_LogoutComparedToTheSecond(t *testing.T) {
+	extractor, privateKey, cleanup := newTokenExtractor(t)
+	defer cleanup()
+
+	store := NewMemoryRevocationStore()
+	extractor.revocations = store
+	extractor.revocationClientIDs = []string{testClientID}
+	ctx := context.Background()
+
+	issuedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	claims := newBaseClaims(AuthorizationCodeGrant)
+	claims["sub"] = testUserID
+	claims["email"] = testEmail
+	claims["ouId"] = testOUID
+	claims["iat"] = issuedAt.Unix()
+
+	// iat cannot tell a token issued later in the second of the logout from one issued
+	// earlier in it, so both are revoked.
+	if err := store.LogoutEverywhere(ctx, testUserID, issuedAt.Add(500*time.Millisecond)); err != nil {
+		t.Fatalf("failed to log out: %v", err)
+	}
+	if _, err := extractor.ExtractPrincipal(ctx, "Bearer "+signToken(t, privateKey, claims)); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked for token issued in the second of the logout, got %v", err)
+	}
+
+	claims["iat"] = issuedAt.Add(time.Second).Unix()
+	if _, err := extractor.ExtractPrincipal(ctx, "Bearer "+signToken(t, privateKey, claims)); err != nil {
+		t.Fatalf("expected token issued the second after the logout to be accepted, got %v", err)
+	}
+}
+
+// countingRevocationStore counts the lookups that reach a MemoryRevocationStore, or fails them with err.
+type countingRevocationStore struct {
+	*MemoryRevocationStore
+	lookups int
+	err     error
+}
+
+func (s *countingRevocationStore) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	s.lookups++
+	if s.err != nil {
+		return false, s.err
+	}
+	return s.MemoryRevocationStore.IsTokenRevoked(ctx, tokenID)
+}
+
+func (s *countingRevocationStore) LoggedOutAt(ctx context.Context, subject string) (time.Time, error) {
+	s.lookups++
+	if s.err != nil {
+		return time.Time{}, s.err
+	}
+	return s.MemoryRevocationStore.LoggedOutAt(ctx, subject)
+}
+
+func TestCachedRevocationStore(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingRevocationStore{MemoryRevocationStore: NewMemoryRevocationStore()}
+	cache := newCachedRevocationStore(backing, time.Second)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if revoked, err := cache.IsTokenRevoked(ctx, "token-1"); err != nil || revoked {
+			t.Fatalf("expected token not revoked, got %v, %v", revoked, err)
+		}
+		if loggedOutAt, err := cache.LoggedOutAt(ctx, testUserID); err != nil || !loggedOutAt.IsZero() {
+			t.Fatalf("expected no logout, got %v, %v", loggedOutAt, err)
+		}
+	}
+	if backing.lookups != 2 {
+		t.Fatalf("expected repeated lookups to be cached, got %d store lookups", backing.lookups)
+	}
+
+	// A revocation made on another replica applies once the cached answer expires.
+	if err := backing.RevokeToken(ctx, "token-1", testUserID, now.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if revoked, _ := cache.IsTokenRevoked(ctx, "token-1"); revoked {
+		t.Fatal("expected the cached answer to be reused within the TTL")
+	}
+	now = now.Add(time.Second)
+	if revoked, _ := cache.IsTokenRevoked(ctx, "token-1"); !revoked {
+		t.Fatal("expected the revocation to apply after the TTL")
+	}
+
+	// Revocations made through the cache apply at once.
+	if err := cache.RevokeToken(ctx, "token-2", testUserID, now.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to revoke token: %v", err)
+	}
+	if revoked, _ := cache.IsTokenRevoked(ctx, "token-2"); !revoked {
+		t.Fatal("expected token revoked through the cache to be revoked at once")
+	}
+	if _, err := cache.LoggedOutAt(ctx, testUserID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cache.LogoutEverywhere(ctx, testUserID, now); err != nil {
+		t.Fatalf("failed to log out: %v", err)
+	}
+	if loggedOutAt, _ := cache.LoggedOutAt(ctx, testUserID); !loggedOutAt.Equal(now) {
+		t.Fatalf("expected the logout through the cache to apply at once, got %v", loggedOutAt)
+	}
+
+	// Failed lookups are not cached.
+	backing.err = errors.New("database unavailable")
+	if _, err := cache.IsTokenRevoked(ctx, "token-3"); err == nil {
+		t.Fatal("expected the store error to be returned")
+	}
+	backing.err = nil
+	if revoked, err := cache.IsTokenRevoked(ctx, "token-3"); err != nil || revoked {
+		t.Fatalf("expected a fresh lookup after the failure, got %v, %v", revoked, err)
+	}
+}
+
+func TestManager_RevokeToken_Disabled(t *testing.T) {
+	manager := &Manager{tokenExtractor: &TokenExtractor{}}
+	err := manager.RevokeToken(context.Background(), &TokenInfo{ID: "token-1", ClientID: testClientID})
+	if !errors.Is(err, ErrRevocationDisabled) {
+		t.Fatalf("expected ErrRevocationDisabled, got %v", err)
+	}
+}
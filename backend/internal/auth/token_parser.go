@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -27,6 +28,7 @@ type tokenClaims struct {
 	PhoneNumber *string          `json:"phone_number,omitempty"`
 	OUID        *string          `json:"ouId,omitempty"`
 	Roles       []string         `json:"roles,omitempty"`
+	Scope       string           `json:"scope,omitempty"`
 }
 
 type PrincipalType string
@@ -37,7 +39,10 @@ const (
 )
 
 type ClientPrincipal struct {
-	ClientID string `json:"clientId"`
+	ClientID    string       `json:"clientId"`
+	Scopes      []string     `json:"scopes,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+	TaskCodes   []string     `json:"taskCodes,omitempty"`
 }
 
 type UserPrincipal struct {
@@ -52,6 +57,19 @@ type Principal struct {
 	Type            PrincipalType    `json:"type"`
 	UserPrincipal   *UserPrincipal   `json:"userPrincipal,omitempty"`
 	ClientPrincipal *ClientPrincipal `json:"clientPrincipal,omitempty"`
+	Token           *TokenInfo       `json:"token,omitempty"`
+}
+
+// TokenInfo identifies the token a principal authenticated with, for revoking it later.
+type TokenInfo struct {
+	// ID is the jti claim; empty when the IdP does not issue one.
+	ID string `json:"id,omitempty"`
+	// Subject is the sub claim, or the client ID for client tokens without one. Logging out
+	// everywhere applies to all tokens of a subject.
+	Subject   string    `json:"subject"`
+	ClientID  string    `json:"clientId"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
 
 type jwksResponse struct {
@@ -83,6 +101,14 @@ type TokenExtractor struct {
 	cachedJWKS    *jwksResponse
 	lastJWKSFetch time.Time
 	jwksCacheTTL  time.Duration
+
+	// introspector, when set, confirms tokens of its client IDs with the IdP.
+	introspector *introspector
+	// revocations, when set, is checked for the tokens of revocationClientIDs.
+	revocations         RevocationStore
+	revocationClientIDs []string
+	// permissions grants client principals their permissions; nil grants none.
+	permissions *PermissionsConfig
 }
 
 func NewTokenExtractor(jwksURL, issuer, audience string, expectedClientIDs []string) (*TokenExtractor, error) {
@@ -150,6 +176,14 @@ func (te *TokenExtractor) validateConfig() error {
 // JWT signature is validated against configured JWKS endpoint, then claims are
 // mapped into either UserPrincipal or ClientPrincipal.
 func (te *TokenExtractor) ExtractPrincipalFromHeader(authHeader string) (*Principal, error) {
+	return te.ExtractPrincipal(context.Background(), authHeader)
+}
+
+// ExtractPrincipal is ExtractPrincipalFromHeader with a request context. Besides validating
+// the JWT offline, it rejects tokens that were revoked locally or, for the client IDs
+// configured for introspection, that the IdP no longer reports as active. Errors wrapping
+// ErrAuthUnavailable mean the token could not be checked rather than that it is invalid.
+func (te *TokenExtractor) ExtractPrincipal(ctx context.Context, authHeader string) (*Principal, error) {
 	if authHeader == "" {
 		return nil, fmt.Errorf("authorization header is empty")
 	}
@@ -187,28 +221,88 @@ func (te *TokenExtractor) ExtractPrincipalFromHeader(authHeader string) (*Princi
 		return nil, fmt.Errorf("unexpected client_id claim: %q", claims.ClientID)
 	}
 
+	var principal *Principal
 	switch claims.GrantType {
 	case AuthorizationCodeGrant:
 		userPrincipal, err := te.userPrincipalFromClaims(claims)
 		if err != nil {
 			return nil, err
 		}
-		return &Principal{
+		principal = &Principal{
 			Type:          UserPrincipalType,
 			UserPrincipal: userPrincipal,
-		}, nil
+		}
 	case ClientCredentialsGrant:
 		clientPrincipal, err := te.clientPrincipalFromClaims(claims)
 		if err != nil {
 			return nil, err
 		}
-		return &Principal{
+		principal = &Principal{
 			Type:            ClientPrincipalType,
 			ClientPrincipal: clientPrincipal,
-		}, nil
+		}
 	default:
 		return nil, fmt.Errorf("unsupported grant type: %q", claims.GrantType)
 	}
+
+	principal.Token = tokenInfoFromClaims(claims)
+	if err := te.checkRevocation(ctx, tokenString, principal.Token); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+func tokenInfoFromClaims(claims *tokenClaims) *TokenInfo {
+	info := &TokenInfo{
+		ID:        claims.ID,
+		Subject:   claims.Subject,
+		ClientID:  claims.ClientID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if info.Subject == "" {
+		info.Subject = claims.ClientID
+	}
+	if claims.IssuedAt != nil {
+		info.IssuedAt = claims.IssuedAt.Time
+	}
+	return info
+}
+
+// checkRevocation applies the deny-list, logout-everywhere and introspection checks enabled
+// for the token's client ID. Local checks run first so revoked tokens never reach the IdP.
+func (te *TokenExtractor) checkRevocation(ctx context.Context, tokenString string, token *TokenInfo) error {
+	if te.revocations != nil && slices.Contains(te.revocationClientIDs, token.ClientID) {
+		if token.ID != "" {
+			revoked, err := te.revocations.IsTokenRevoked(ctx, token.ID)
+			if err != nil {
+				return fmt.Errorf("%w: failed to check revoked tokens: %v", ErrAuthUnavailable, err)
+			}
+			if revoked {
+				return ErrTokenRevoked
+			}
+		}
+		loggedOutAt, err := te.revocations.LoggedOutAt(ctx, token.Subject)
+		if err != nil {
+			return fmt.Errorf("%w: failed to check logout timestamp: %v", ErrAuthUnavailable, err)
+		}
+		// iat has second precision, so the two are compared to the second: a token issued in
+		// the second of the logout is revoked with it. Tokens without iat cannot be shown to
+		// postdate the logout.
+		if !loggedOutAt.IsZero() && !token.IssuedAt.Truncate(time.Second).After(loggedOutAt.Truncate(time.Second)) {
+			return fmt.Errorf("%w: issued before the principal logged out everywhere", ErrTokenRevoked)
+		}
+	}
+
+	if te.introspector != nil && slices.Contains(te.introspector.clientIDs, token.ClientID) {
+		active, err := te.introspector.active(ctx, tokenString, token.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		if !active {
+			return fmt.Errorf("%w: token is not active at the identity provider", ErrTokenRevoked)
+		}
+	}
+	return nil
 }
 
 func (te *TokenExtractor) userPrincipalFromClaims(claims *tokenClaims) (*UserPrincipal, error) {
@@ -240,8 +334,13 @@ func (te *TokenExtractor) clientPrincipalFromClaims(claims *tokenClaims) (*Clien
 	if claims.ClientID == "" {
 		return nil, fmt.Errorf("jwt missing client_id claim for client principal")
 	}
+	scopes := strings.Fields(claims.Scope)
+	grant := te.permissions.resolve(claims.ClientID, scopes)
 	return &ClientPrincipal{
-		ClientID: claims.ClientID,
+		ClientID:    claims.ClientID,
+		Scopes:      scopes,
+		Permissions: grant.Permissions,
+		TaskCodes:   grant.TaskCodes,
 	}, nil
 }
 
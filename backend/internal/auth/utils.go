@@ -32,13 +32,20 @@ func buildAuthContext(principal *Principal) *AuthContext {
 				OUID:        principal.UserPrincipal.OUID,
 				Roles:       principal.UserPrincipal.Roles,
 			},
+			Token: principal.Token,
 		}
 	case ClientPrincipalType:
 		if principal.ClientPrincipal == nil {
 			return &AuthContext{}
 		}
 		return &AuthContext{
-			Client: &ClientContext{ClientID: principal.ClientPrincipal.ClientID},
+			Client: &ClientContext{
+				ClientID:    principal.ClientPrincipal.ClientID,
+				Scopes:      principal.ClientPrincipal.Scopes,
+				Permissions: principal.ClientPrincipal.Permissions,
+				TaskCodes:   principal.ClientPrincipal.TaskCodes,
+			},
+			Token: principal.Token,
 		}
 	default:
 		return &AuthContext{}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/OpenNSW/nsw/internal/audit"
 	"github.com/OpenNSW/nsw/internal/auth"
 	"github.com/OpenNSW/nsw/internal/database"
 	"github.com/OpenNSW/nsw/internal/temporal"
@@ -24,6 +25,9 @@ type Config struct {
 	Auth         auth.Config
 	Notification NotificationConfig
 	Temporal     temporal.Config
+	Payments     PaymentsConfig
+	Tasks        TasksConfig
+	Audit        audit.Config
 }
 
 // ServerConfig holds server configuration
@@ -33,6 +37,9 @@ type ServerConfig struct {
 	ServicesConfigPath string
 	Debug              bool
 	LogLevel           slog.Level
+	// TrustProxyHeaders takes the client IP recorded in the audit log from the
+	// X-Forwarded-For header set by the reverse proxy in front of the server.
+	TrustProxyHeaders bool
 }
 
 // CORSConfig holds CORS configuration
@@ -53,6 +60,22 @@ type NotificationConfig struct {
 	TemplateRoot string
 }
 
+// PaymentsConfig holds payment orchestration configuration
+type PaymentsConfig struct {
+	MethodsConfigPath string        // Path to payment_methods.json
+	ReconcileInterval time.Duration // How often PENDING transactions are reconciled; 0 disables the worker
+	ExchangeRatesPath string        // Path to exchange_rates.json; empty disables currency conversion
+}
+
+// TasksConfig holds task authorization and container cache configuration
+type TasksConfig struct {
+	OGAClientIDs           []string      // M2M clients allowed to send OGA verification actions
+	ContainerCacheCapacity int           // Task containers kept in memory before the least recently used is evicted
+	ContainerCacheIdleTTL  time.Duration // Cached containers idle for longer are evicted; 0 disables idle eviction
+	BusinessCalendarPath   string        // Weekends and public holidays skipped by business-day SLA deadlines
+	SLACheckInterval       time.Duration // How often breached SLAs are escalated; 0 disables the SLA monitor
+}
+
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
 	serverPort := getIntEnvOrDefault("SERVER_PORT", 8080)
@@ -75,6 +98,7 @@ func Load() (*Config, error) {
 			ServicesConfigPath: getEnvOrDefault("SERVICES_CONFIG_PATH", "configs/services.json"),
 			Debug:              getBoolOrDefault("SERVER_DEBUG", true),
 			LogLevel:           parseLogLevel(getEnvOrDefault("SERVER_LOG_LEVEL", "info")),
+			TrustProxyHeaders:  getBoolOrDefault("SERVER_TRUST_PROXY_HEADERS", false),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins:   parseCommaSeparated(getEnvOrDefault("CORS_ALLOWED_ORIGINS", "*")),
@@ -103,6 +127,17 @@ func Load() (*Config, error) {
 			Audience:              getEnvOrDefault("AUTH_AUDIENCE", "NSW_API"),
 			ClientIDs:             parseCommaSeparated(getEnvOrDefault("AUTH_CLIENT_IDS", "TRADER_PORTAL_APP,FCAU_TO_NSW,NPQS_TO_NSW,IRD_TO_NSW")),
 			InsecureSkipTLSVerify: getBoolOrDefault("AUTH_JWKS_INSECURE_SKIP_VERIFY", false),
+			Introspection: auth.IntrospectionConfig{
+				URL:              getEnvOrDefault("AUTH_INTROSPECTION_URL", "https://localhost:8090/oauth2/introspect"),
+				ClientID:         getEnvOrDefault("AUTH_INTROSPECTION_CLIENT_ID", ""),
+				ClientSecret:     os.Getenv("AUTH_INTROSPECTION_CLIENT_SECRET"),
+				ClientIDs:        parseCommaSeparated(getEnvOrDefault("AUTH_INTROSPECTION_CLIENT_IDS", "")),
+				CacheTTL:         getDurationOrDefault("AUTH_INTROSPECTION_CACHE_TTL", 30*time.Second),
+				NegativeCacheTTL: getDurationOrDefault("AUTH_INTROSPECTION_NEGATIVE_CACHE_TTL", 10*time.Second),
+			},
+			RevocationClientIDs: parseCommaSeparated(getEnvOrDefault("AUTH_REVOCATION_CLIENT_IDS", "TRADER_PORTAL_APP")),
+			RevocationCacheTTL:  getDurationOrDefault("AUTH_REVOCATION_CACHE_TTL", 5*time.Second),
+			PermissionsPath:     getEnvOrDefault("AUTH_PERMISSIONS_CONFIG_PATH", "configs/client_permissions.json"),
 		},
 		Notification: NotificationConfig{
 			SMTPHost:     getEnvOrDefault("EMAIL_SMTP_HOST", "localhost"),
@@ -117,6 +152,23 @@ func Load() (*Config, error) {
 			Port:      getIntEnvOrDefault("TEMPORAL_PORT", 7233),
 			Namespace: getEnvOrDefault("TEMPORAL_NAMESPACE", "default"),
 		},
+		Payments: PaymentsConfig{
+			MethodsConfigPath: getEnvOrDefault("PAYMENT_METHODS_CONFIG_PATH", "configs/payment_methods.json"),
+			ReconcileInterval: getDurationOrDefault("PAYMENT_RECONCILE_INTERVAL", 5*time.Minute),
+			ExchangeRatesPath: getEnvOrDefault("PAYMENT_EXCHANGE_RATES_PATH", "configs/exchange_rates.json"),
+		},
+		Tasks: TasksConfig{
+			OGAClientIDs:           parseCommaSeparated(getEnvOrDefault("TASK_OGA_CLIENT_IDS", "FCAU_TO_NSW,NPQS_TO_NSW,IRD_TO_NSW,CDA_TO_NSW")),
+			ContainerCacheCapacity: getIntEnvOrDefault("TASK_CONTAINER_CACHE_CAPACITY", 100),
+			ContainerCacheIdleTTL:  getDurationOrDefault("TASK_CONTAINER_CACHE_IDLE_TTL", 0),
+			BusinessCalendarPath:   getEnvOrDefault("TASK_BUSINESS_CALENDAR_PATH", "configs/business_calendar.json"),
+			SLACheckInterval:       getDurationOrDefault("TASK_SLA_CHECK_INTERVAL", time.Minute),
+		},
+		Audit: audit.Config{
+			CheckpointKeyPath:  getEnvOrDefault("AUDIT_CHECKPOINT_KEY_PATH", ""),
+			CheckpointKeyID:    getEnvOrDefault("AUDIT_CHECKPOINT_KEY_ID", ""),
+			CheckpointInterval: getDurationOrDefault("AUDIT_CHECKPOINT_INTERVAL", time.Hour),
+		},
 	}
 
 	// Validate required fields
@@ -147,6 +199,18 @@ func (c *Config) Validate() error {
 	if err := c.Temporal.Validate(); err != nil {
 		return fmt.Errorf("invalid temporal configuration: %w", err)
 	}
+	if c.Tasks.ContainerCacheCapacity <= 0 {
+		return fmt.Errorf("TASK_CONTAINER_CACHE_CAPACITY must be positive")
+	}
+	if c.Tasks.ContainerCacheIdleTTL < 0 {
+		return fmt.Errorf("TASK_CONTAINER_CACHE_IDLE_TTL cannot be negative")
+	}
+	if c.Tasks.SLACheckInterval < 0 {
+		return fmt.Errorf("TASK_SLA_CHECK_INTERVAL cannot be negative")
+	}
+	if err := c.Audit.Validate(); err != nil {
+		return fmt.Errorf("invalid audit configuration: %w", err)
+	}
 	if len(c.CORS.AllowedOrigins) == 0 {
 		return fmt.Errorf("CORS_ALLOWED_ORIGINS is required")
 	}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 )
 
 func TestLoadTemporalDefaults(t *testing.T) {
@@ -60,3 +61,70 @@ func TestLoadTemporalInvalidPortFallsBackToDefault(t *testing.T) {
 		t.Fatalf("Port = %d, want default %d", cfg.Temporal.Port, 7233)
 	}
 }
+
+func TestLoadAuditCheckpointKeyRequiresKeyID(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "test")
+	t.Setenv("AUDIT_CHECKPOINT_KEY_PATH", "/etc/nsw/audit-checkpoint.pem")
+	t.Setenv("AUDIT_CHECKPOINT_KEY_ID", "")
+
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() error = nil, want error for missing AUDIT_CHECKPOINT_KEY_ID")
+	}
+
+	t.Setenv("AUDIT_CHECKPOINT_KEY_ID", "audit-2026")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Audit.CheckpointKeyID != "audit-2026" || cfg.Audit.CheckpointInterval <= 0 {
+		t.Fatalf("unexpected audit config %+v", cfg.Audit)
+	}
+}
+
+func TestLoadTaskContainerCache(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "test")
+	t.Setenv("TASK_CONTAINER_CACHE_CAPACITY", "")
+	t.Setenv("TASK_CONTAINER_CACHE_IDLE_TTL", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Tasks.ContainerCacheCapacity != 100 || cfg.Tasks.ContainerCacheIdleTTL != 0 {
+		t.Fatalf("unexpected cache defaults %+v", cfg.Tasks)
+	}
+
+	t.Setenv("TASK_CONTAINER_CACHE_CAPACITY", "500")
+	t.Setenv("TASK_CONTAINER_CACHE_IDLE_TTL", "30m")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Tasks.ContainerCacheCapacity != 500 || cfg.Tasks.ContainerCacheIdleTTL != 30*time.Minute {
+		t.Fatalf("unexpected cache overrides %+v", cfg.Tasks)
+	}
+
+	t.Setenv("TASK_CONTAINER_CACHE_CAPACITY", "0")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() error = nil, want error for zero TASK_CONTAINER_CACHE_CAPACITY")
+	}
+}
+
+func TestLoadTaskSLA(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "test")
+	t.Setenv("TASK_BUSINESS_CALENDAR_PATH", "")
+	t.Setenv("TASK_SLA_CHECK_INTERVAL", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Tasks.BusinessCalendarPath != "configs/business_calendar.json" || cfg.Tasks.SLACheckInterval != time.Minute {
+		t.Fatalf("unexpected SLA defaults %+v", cfg.Tasks)
+	}
+
+	t.Setenv("TASK_SLA_CHECK_INTERVAL", "-1m")
+	if _, err := Load(); err == nil {
+		t.Fatal("Load() error = nil, want error for negative TASK_SLA_CHECK_INTERVAL")
+	}
+}
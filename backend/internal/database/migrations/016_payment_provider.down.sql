@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_payment_tx_provider_id;
+ALTER TABLE payment_transactions DROP COLUMN IF EXISTS provider_id;
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE payment_transactions ADD COLUMN IF NOT EXISTS provider_id VARCHAR(100);
+
+COMMENT ON COLUMN payment_transactions.provider_id IS 'Payment method/provider ID from payment_methods.json that owns the gateway session';
+COMMENT ON COLUMN payment_transactions.reference_number IS 'NSW payment reference (NSW-PR-YYYY-XXXXX), unique across all providers';
+
+CREATE INDEX IF NOT EXISTS idx_payment_tx_provider_id ON payment_transactions (provider_id);
+
+COMMIT;
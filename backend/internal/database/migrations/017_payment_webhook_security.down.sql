@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS payment_webhook_audit_logs;
+DROP TABLE IF EXISTS payment_webhook_nonces;
+
+COMMIT;
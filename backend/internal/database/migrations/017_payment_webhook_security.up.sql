@@ -0,0 +1,37 @@
+BEGIN;
+
+-- Nonces of verified gateway callbacks, kept until their signed timestamp leaves the
+-- allowed clock-skew window. A second insert of the same nonce is a replay.
+CREATE TABLE IF NOT EXISTS payment_webhook_nonces (
+    provider_id VARCHAR(100) NOT NULL,
+    nonce VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (provider_id, nonce)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_webhook_nonces_expires_at ON payment_webhook_nonces (expires_at);
+
+COMMENT ON TABLE payment_webhook_nonces IS 'Replay protection for signed payment gateway callbacks';
+COMMENT ON COLUMN payment_webhook_nonces.nonce IS 'Gateway nonce header, or the request signature when the provider sends no nonce';
+
+-- Gateway callbacks rejected by signature, timestamp or replay checks.
+CREATE TABLE IF NOT EXISTS payment_webhook_audit_logs (
+    id text NOT NULL PRIMARY KEY,
+    provider_id VARCHAR(100) NOT NULL,
+    endpoint VARCHAR(50) NOT NULL,
+    reason TEXT NOT NULL,
+    remote_addr VARCHAR(255),
+    headers JSONB,
+    body_sha256 VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_webhook_audit_provider_created ON payment_webhook_audit_logs (provider_id, created_at);
+
+COMMENT ON TABLE payment_webhook_audit_logs IS 'Audit trail of payment webhook and validate requests that failed authentication (HTTP 401)';
+COMMENT ON COLUMN payment_webhook_audit_logs.endpoint IS 'Gateway endpoint that was called: webhook or validate';
+COMMENT ON COLUMN payment_webhook_audit_logs.headers IS 'Request headers as received, including the presented signature and timestamp';
+COMMENT ON COLUMN payment_webhook_audit_logs.body_sha256 IS 'Hex SHA-256 of the raw request body; the body itself is not stored';
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS payment_settlement_reports;
+DROP INDEX IF EXISTS idx_payment_tx_provider_status_updated;
+DROP INDEX IF EXISTS idx_payment_tx_status_expiry;
+
+COMMIT;
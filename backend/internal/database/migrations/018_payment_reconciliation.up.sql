@@ -0,0 +1,27 @@
+BEGIN;
+
+-- Supports the reconciliation job scanning PENDING transactions by expiry.
+CREATE INDEX IF NOT EXISTS idx_payment_tx_status_expiry ON payment_transactions (status, expiry_date);
+-- Supports listing a provider's confirmed payments for a settlement date.
+CREATE INDEX IF NOT EXISTS idx_payment_tx_provider_status_updated ON payment_transactions (provider_id, status, updated_at);
+
+COMMENT ON COLUMN payment_transactions.status IS 'PENDING, SUCCESS, FAILED or EXPIRED. EXPIRED is set by reconciliation and may still become SUCCESS on a late confirmation';
+
+CREATE TABLE IF NOT EXISTS payment_settlement_reports (
+    id text NOT NULL PRIMARY KEY,
+    provider_id VARCHAR(100) NOT NULL,
+    settlement_date DATE NOT NULL,
+    total_lines INTEGER NOT NULL DEFAULT 0,
+    matched INTEGER NOT NULL DEFAULT 0,
+    corrected INTEGER NOT NULL DEFAULT 0,
+    discrepancies JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_settlement_reports_provider_date ON payment_settlement_reports (provider_id, settlement_date);
+
+COMMENT ON TABLE payment_settlement_reports IS 'Outcome of each imported gateway settlement file';
+COMMENT ON COLUMN payment_settlement_reports.corrected IS 'Settled payments NSW had not recorded as SUCCESS; these were corrected and their tasks notified';
+COMMENT ON COLUMN payment_settlement_reports.discrepancies IS 'JSON array of discrepancies (UNKNOWN_REFERENCE, STATUS_CORRECTED, AMOUNT_MISMATCH, DUPLICATE_LINE, NOT_SETTLED, INVALID_LINE)';
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS payment_refunds;
+DROP FUNCTION IF EXISTS payment_refunds_append_only();
+
+COMMENT ON COLUMN payment_transactions.status IS 'PENDING, SUCCESS, FAILED or EXPIRED. EXPIRED is set by reconciliation and may still become SUCCESS on a late confirmation';
+
+COMMIT;
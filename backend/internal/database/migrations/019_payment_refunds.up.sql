@@ -0,0 +1,40 @@
+BEGIN;
+
+COMMENT ON COLUMN payment_transactions.status IS 'PENDING, SUCCESS, FAILED, EXPIRED, PARTIALLY_REFUNDED or REFUNDED. EXPIRED is set by reconciliation and may still become SUCCESS on a late confirmation';
+
+CREATE TABLE IF NOT EXISTS payment_refunds (
+    id text NOT NULL PRIMARY KEY,
+    transaction_id text NOT NULL REFERENCES payment_transactions (id),
+    reference_number VARCHAR(255) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(10) NOT NULL,
+    reason_code VARCHAR(50) NOT NULL,
+    reason_note TEXT,
+    requested_by VARCHAR(255) NOT NULL,
+    approved_by VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    gateway_refund_id VARCHAR(255),
+    failure_reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT chk_payment_refunds_four_eyes CHECK (requested_by <> approved_by)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_transaction ON payment_refunds (transaction_id, created_at);
+
+-- The refund ledger is append-only: corrections are new entries, never edits.
+CREATE OR REPLACE FUNCTION payment_refunds_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'payment_refunds is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_payment_refunds_append_only ON payment_refunds;
+CREATE TRIGGER trg_payment_refunds_append_only
+    BEFORE UPDATE OR DELETE ON payment_refunds
+    FOR EACH ROW EXECUTE FUNCTION payment_refunds_append_only();
+
+COMMENT ON TABLE payment_refunds IS 'Append-only ledger of refund attempts against payment transactions';
+COMMENT ON COLUMN payment_refunds.reason_code IS 'OGA_REJECTED, CONSIGNMENT_CANCELLED, DUPLICATE_PAYMENT, OVERCHARGE or OTHER (requires reason_note)';
+COMMENT ON COLUMN payment_refunds.status IS 'SUCCEEDED or FAILED. Only SUCCEEDED entries reduce the refundable balance';
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE payment_transactions
+    DROP COLUMN IF EXISTS rate_as_of,
+    DROP COLUMN IF EXISTS rate_source,
+    DROP COLUMN IF EXISTS exchange_rate,
+    DROP COLUMN IF EXISTS base_currency,
+    DROP COLUMN IF EXISTS base_amount;
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+ALTER TABLE payment_transactions
+    ADD COLUMN IF NOT EXISTS base_amount NUMERIC(15, 2),
+    ADD COLUMN IF NOT EXISTS base_currency VARCHAR(10),
+    ADD COLUMN IF NOT EXISTS exchange_rate NUMERIC(20, 8),
+    ADD COLUMN IF NOT EXISTS rate_source VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS rate_as_of TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN payment_transactions.base_amount IS 'Base-currency equivalent of amount, snapshotted at checkout. NULL when currency is the base currency';
+COMMENT ON COLUMN payment_transactions.exchange_rate IS 'Units of base_currency per unit of currency used for base_amount';
+COMMENT ON COLUMN payment_transactions.rate_source IS 'Publisher of exchange_rate, e.g. CBSL';
+COMMENT ON COLUMN payment_transactions.rate_as_of IS 'When the source published exchange_rate';
+
+COMMIT;
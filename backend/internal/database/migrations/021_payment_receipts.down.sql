@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS payment_receipts;
+DROP TABLE IF EXISTS payment_receipt_counters;
+
+COMMIT;
@@ -0,0 +1,32 @@
+BEGIN;
+
+-- One counter row per calendar year. Incrementing it inside the issuing transaction keeps
+-- receipt numbers gapless: a rolled-back issue releases its number.
+CREATE TABLE IF NOT EXISTS payment_receipt_counters (
+    year INTEGER NOT NULL PRIMARY KEY,
+    last_number BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS payment_receipts (
+    id text NOT NULL PRIMARY KEY,
+    receipt_number VARCHAR(50) NOT NULL,
+    transaction_id text NOT NULL REFERENCES payment_transactions (id),
+    reference_number VARCHAR(255) NOT NULL,
+    task_id VARCHAR(255),
+    amount NUMERIC(15, 2) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    pdf_key VARCHAR(255) NOT NULL,
+    json_key VARCHAR(255) NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_receipts_receipt_number ON payment_receipts (receipt_number);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_receipts_reference_number ON payment_receipts (reference_number);
+
+COMMENT ON TABLE payment_receipt_counters IS 'Last receipt number issued in each calendar year';
+COMMENT ON TABLE payment_receipts IS 'Official receipts issued for successful payments, at most one per transaction';
+COMMENT ON COLUMN payment_receipts.receipt_number IS 'NSW-RC-YYYY-NNNNNN, sequential within the year';
+COMMENT ON COLUMN payment_receipts.pdf_key IS 'Storage key of the PDF receipt';
+COMMENT ON COLUMN payment_receipts.json_key IS 'Storage key of the structured (JSON) receipt';
+
+COMMIT;
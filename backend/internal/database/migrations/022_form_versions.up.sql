@@ -0,0 +1,26 @@
+BEGIN;
+
+-- Published form definitions are immutable. forms keeps the latest published version as
+-- its current definition; tasks pin the version they started with and read it from here.
+CREATE TABLE IF NOT EXISTS form_versions (
+    id text NOT NULL PRIMARY KEY,
+    form_id text NOT NULL REFERENCES forms (id),
+    version VARCHAR(50) NOT NULL,
+    schema JSONB NOT NULL,
+    ui_schema JSONB NOT NULL,
+    published_by VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_form_versions_form_id_version ON form_versions (form_id, version);
+
+COMMENT ON TABLE form_versions IS 'Immutable published versions of each form definition';
+COMMENT ON COLUMN form_versions.published_by IS 'User or client that published the version; NULL for versions backfilled from forms';
+
+-- Every existing form becomes its own first version.
+INSERT INTO form_versions (id, form_id, version, schema, ui_schema, created_at)
+SELECT gen_random_uuid()::text, id, version, schema, ui_schema, updated_at
+FROM forms
+ON CONFLICT (form_id, version) DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS oga_agencies;
+
+DROP INDEX IF EXISTS idx_customs_house_agents_ou_id;
+ALTER TABLE customs_house_agents DROP COLUMN IF EXISTS ou_id;
+
+COMMIT;
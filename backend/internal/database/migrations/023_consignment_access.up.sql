@@ -0,0 +1,35 @@
+BEGIN;
+
+-- CHA users are scoped to the consignments of the CHA whose organisation unit they belong to.
+ALTER TABLE customs_house_agents ADD COLUMN IF NOT EXISTS ou_id VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_customs_house_agents_ou_id ON customs_house_agents (ou_id);
+
+COMMENT ON COLUMN customs_house_agents.ou_id IS 'IdP organisation unit of the CHA; users in this OU act for the CHA. NULL falls back to matching the CHA email';
+
+-- OGA officers see the consignments whose workflows include tasks of their agency. Tasks
+-- name their agency by code (task_infos.config->>'agency'); officers are identified by OU.
+CREATE TABLE IF NOT EXISTS oga_agencies (
+    code VARCHAR(50) NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    ou_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oga_agencies_ou_id ON oga_agencies (ou_id);
+
+COMMENT ON TABLE oga_agencies IS 'Other Government Agencies that own workflow tasks';
+COMMENT ON COLUMN oga_agencies.code IS 'Agency code used in the agency field of task configurations';
+COMMENT ON COLUMN oga_agencies.ou_id IS 'IdP organisation unit of the agency officers; set once the agency OU is provisioned';
+
+INSERT INTO oga_agencies (code, name)
+VALUES
+    ('NPQS', 'National Plant Quarantine Service'),
+    ('FCAU', 'Food Control Administration Unit'),
+    ('IRD', 'Inland Revenue Department'),
+    ('CDA', 'Coconut Development Authority'),
+    ('EDB', 'Export Development Board')
+ON CONFLICT (code) DO NOTHING;
+
+COMMIT;
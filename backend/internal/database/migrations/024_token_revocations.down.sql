@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS principal_logouts;
+DROP TABLE IF EXISTS revoked_tokens;
+
+COMMIT;
@@ -0,0 +1,24 @@
+BEGIN;
+
+-- Deny-list of individually revoked access tokens, kept until the tokens expire.
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(255) NOT NULL PRIMARY KEY,
+    subject VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+COMMENT ON TABLE revoked_tokens IS 'Access tokens revoked before expiry, identified by their jti claim';
+COMMENT ON COLUMN revoked_tokens.subject IS 'sub claim of the token, or its client_id for client tokens';
+
+-- Tokens of a principal issued at or before logged_out_at are rejected.
+CREATE TABLE IF NOT EXISTS principal_logouts (
+    subject VARCHAR(255) NOT NULL PRIMARY KEY,
+    logged_out_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+COMMENT ON TABLE principal_logouts IS 'Latest logout-everywhere timestamp of each principal';
+
+COMMIT;
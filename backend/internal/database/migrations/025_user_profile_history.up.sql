@@ -0,0 +1,22 @@
+BEGIN;
+
+-- Append-only history of changes to the company profile stored in user_records.data.
+CREATE TABLE IF NOT EXISTS user_profile_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(100) NOT NULL REFERENCES user_records(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('trader', 'verified')),
+    changed_by VARCHAR(255) NOT NULL,
+    changed_fields JSONB NOT NULL DEFAULT '[]'::jsonb,
+    profile JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_profile_history_user_id ON user_profile_history (user_id, id DESC);
+
+COMMENT ON TABLE user_profile_history IS 'Append-only history of user company profile changes';
+COMMENT ON COLUMN user_profile_history.source IS 'trader for edits through the API, verified for fields written by the platform (e.g. a completed pre-consignment)';
+COMMENT ON COLUMN user_profile_history.changed_by IS 'User ID of the trader, or the verified source (e.g. pre-consignment/<id>)';
+COMMENT ON COLUMN user_profile_history.changed_fields IS 'JSON array of the profile fields changed';
+COMMENT ON COLUMN user_profile_history.profile IS 'Snapshot of the profile after the change';
+
+COMMIT;
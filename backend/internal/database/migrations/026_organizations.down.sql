@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS consignment_delegations;
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
+
+COMMIT;
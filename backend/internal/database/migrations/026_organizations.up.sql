@@ -0,0 +1,72 @@
+BEGIN;
+
+-- Companies of traders and CHAs, with several staff each.
+CREATE TABLE IF NOT EXISTS organizations (
+    id VARCHAR(100) NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('trader', 'cha')),
+    cha_id TEXT REFERENCES customs_house_agents(id),
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (cha_id IS NULL OR kind = 'cha')
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_cha_id ON organizations (cha_id) WHERE cha_id IS NOT NULL;
+
+COMMENT ON TABLE organizations IS 'Trader and CHA companies whose staff share access to consignments';
+COMMENT ON COLUMN organizations.cha_id IS 'Registered CHA the organisation acts as; its members act for the CHA on consignments assigned to it';
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id VARCHAR(100) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id VARCHAR(100) NOT NULL REFERENCES user_records(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'clerk', 'viewer')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members (user_id);
+
+COMMENT ON TABLE organization_members IS 'Staff of organisations';
+COMMENT ON COLUMN organization_members.role IS 'admin manages the organisation and acts on consignments, clerk acts on consignments, viewer only reads them';
+
+CREATE TABLE IF NOT EXISTS organization_invitations (
+    id VARCHAR(100) NOT NULL PRIMARY KEY,
+    organization_id VARCHAR(100) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'clerk', 'viewer')),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by VARCHAR(100) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    accepted_by VARCHAR(100),
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_invitations_organization_id ON organization_invitations (organization_id);
+
+COMMENT ON TABLE organization_invitations IS 'Invitations to join an organisation, accepted by the user holding the token and the invited email';
+COMMENT ON COLUMN organization_invitations.token_hash IS 'SHA-256 of the invitation token; the token itself is only returned when the invitation is created';
+
+-- Time-bound grants of CHA rights over a consignment to a CHA organisation.
+CREATE TABLE IF NOT EXISTS consignment_delegations (
+    id VARCHAR(100) NOT NULL PRIMARY KEY,
+    consignment_id TEXT NOT NULL REFERENCES consignments(id) ON DELETE CASCADE,
+    organization_id VARCHAR(100) NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    granted_by VARCHAR(100) NOT NULL,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_by VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (expires_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consignment_delegations_consignment_id ON consignment_delegations (consignment_id);
+CREATE INDEX IF NOT EXISTS idx_consignment_delegations_organization_id ON consignment_delegations (organization_id);
+
+COMMENT ON TABLE consignment_delegations IS 'Delegations by a trader of CHA rights over a consignment to the members of a CHA organisation, between starts_at and expires_at unless revoked';
+
+COMMIT;
@@ -0,0 +1,53 @@
+BEGIN;
+
+-- Append-only trail of state-changing operations: who did what, when, from where.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    action VARCHAR(50) NOT NULL,
+    actor_kind VARCHAR(20) NOT NULL CHECK (actor_kind IN ('user', 'client', 'system', 'provider', 'anonymous')),
+    actor_id VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    consignment_id TEXT NOT NULL DEFAULT '',
+    task_id TEXT NOT NULL DEFAULT '',
+    resource_type VARCHAR(50) NOT NULL,
+    resource_id TEXT NOT NULL,
+    operation VARCHAR(100) NOT NULL DEFAULT '',
+    state_before TEXT NOT NULL DEFAULT '',
+    state_after TEXT NOT NULL DEFAULT '',
+    payload_hash VARCHAR(64) NOT NULL DEFAULT '',
+    details JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_consignment_id ON audit_log (consignment_id, occurred_at) WHERE consignment_id <> '';
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log (occurred_at);
+
+-- Entries are never edited or removed.
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_log_append_only ON audit_log;
+CREATE TRIGGER trg_audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS trg_audit_log_no_truncate ON audit_log;
+CREATE TRIGGER trg_audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+COMMENT ON TABLE audit_log IS 'Append-only audit trail of consignment, task, upload and payment state changes';
+COMMENT ON COLUMN audit_log.action IS 'consignment.create, consignment.initialize, task.execute, oga.review, upload.create, upload.delete or payment.status_change';
+COMMENT ON COLUMN audit_log.actor_id IS 'User ID, client ID, system actor or payment provider ID, depending on actor_kind';
+COMMENT ON COLUMN audit_log.consignment_id IS 'Consignment or pre-consignment the operation belongs to (the workflow ID), if any';
+COMMENT ON COLUMN audit_log.operation IS 'Task action or other sub-operation, e.g. OGA_VERIFICATION';
+COMMENT ON COLUMN audit_log.state_before IS 'Plugin state of a task, status of a payment or state of a consignment before the operation';
+COMMENT ON COLUMN audit_log.state_after IS 'Plugin state of a task, status of a payment or state of a consignment after the operation';
+COMMENT ON COLUMN audit_log.payload_hash IS 'Hex SHA-256 of the JSON-encoded request payload';
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS audit_checkpoints;
+
+ALTER TABLE audit_log
+    DROP COLUMN IF EXISTS entry_hash,
+    DROP COLUMN IF EXISTS prev_hash;
+
+COMMIT;
@@ -0,0 +1,38 @@
+BEGIN;
+
+-- Each audit entry carries the hash of the entry before it, so an edited, removed or
+-- reordered entry breaks every link after it.
+ALTER TABLE audit_log
+    ADD COLUMN IF NOT EXISTS prev_hash VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS entry_hash VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Signed checkpoints pin the head of the chain at a point in time, so the chain cannot be
+-- rewritten wholesale from an earlier entry onwards.
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_entry_id BIGINT NOT NULL,
+    last_entry_hash VARCHAR(64) NOT NULL,
+    entry_count BIGINT NOT NULL,
+    key_id VARCHAR(100) NOT NULL,
+    signature TEXT NOT NULL
+);
+
+DROP TRIGGER IF EXISTS trg_audit_checkpoints_append_only ON audit_checkpoints;
+CREATE TRIGGER trg_audit_checkpoints_append_only
+    BEFORE UPDATE OR DELETE ON audit_checkpoints
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS trg_audit_checkpoints_no_truncate ON audit_checkpoints;
+CREATE TRIGGER trg_audit_checkpoints_no_truncate
+    BEFORE TRUNCATE ON audit_checkpoints
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+COMMENT ON COLUMN audit_log.prev_hash IS 'entry_hash of the previous chained entry; empty for the first';
+COMMENT ON COLUMN audit_log.entry_hash IS 'Hex SHA-256 over prev_hash and the entry contents; empty for entries written before chaining';
+COMMENT ON TABLE audit_checkpoints IS 'Append-only, Ed25519-signed snapshots of the head of the audit_log hash chain';
+COMMENT ON COLUMN audit_checkpoints.entry_count IS 'Number of chained audit_log entries up to and including last_entry_id';
+COMMENT ON COLUMN audit_checkpoints.key_id IS 'Identifier of the key the checkpoint was signed with';
+COMMENT ON COLUMN audit_checkpoints.signature IS 'Base64 Ed25519 signature over the checkpoint message';
+
+COMMIT;
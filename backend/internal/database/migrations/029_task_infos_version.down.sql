@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE task_infos
+    DROP COLUMN IF EXISTS version;
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Every task state update bumps the version; plugin state and local state are written with
+-- compare-and-swap updates that expect the version the writer read, so a write based on
+-- stale state is rejected instead of overwriting a newer one.
+ALTER TABLE task_infos
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN task_infos.version IS 'Bumped by every state update; compare-and-swap updates expect the version they read';
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE pre_consignment_templates
+    DROP COLUMN IF EXISTS workflow_template_v2_id;
+
+ALTER TABLE pre_consignments
+    DROP COLUMN IF EXISTS trader_context;
+
+DELETE FROM workflow_template_v2
+WHERE id IN ('pre-consignment-basic-details-v1', 'pre-consignment-general-trader-verification-v1');
+
+COMMIT;
@@ -0,0 +1,95 @@
+BEGIN;
+
+-- Pre-consignment workflows run on the Temporal runtime, which executes the definitions in
+-- workflow_template_v2. Each pre-consignment template gains a reference to the v2 template of
+-- its workflow; workflow_template_id keeps pointing at the legacy template.
+ALTER TABLE pre_consignment_templates
+    ADD COLUMN IF NOT EXISTS workflow_template_v2_id text
+        CONSTRAINT fk_pre_consignment_templates_workflow_template_v2
+            REFERENCES workflow_template_v2
+                ON UPDATE CASCADE ON DELETE RESTRICT;
+
+COMMENT ON COLUMN pre_consignment_templates.workflow_template_v2_id IS 'Temporal workflow template (workflow_template_v2) started for pre-consignments of this template';
+
+-- The final workflow context of a completed pre-consignment, used to seed the context of the
+-- pre-consignments that depend on it.
+ALTER TABLE pre_consignments
+    ADD COLUMN IF NOT EXISTS trader_context jsonb DEFAULT '{}'::jsonb NOT NULL;
+
+COMMENT ON COLUMN pre_consignments.trader_context IS 'Final workflow context of a completed pre-consignment; seeds the context of its dependants';
+
+INSERT INTO workflow_template_v2 (id, name, version, workflow_definition)
+VALUES
+    (
+        'pre-consignment-basic-details-v1',
+        'Basic Details Workflow',
+        '1',
+        '{
+            "id": "pre-consignment-basic-details-v1",
+            "name": "Basic Details Workflow",
+            "version": 1,
+            "nodes": [
+                { "id": "start", "type": "START" },
+                {
+                    "id": "basic_details",
+                    "type": "TASK",
+                    "task_template_id": "d0000002-0001-0001-0001-000000000004",
+                    "output_mapping": {
+                        "bi:businessName": "bi:businessName",
+                        "bi:businessAddress": "bi:businessAddress",
+                        "bi:businessType": "bi:businessType",
+                        "bi:phoneNumber": "bi:phoneNumber",
+                        "bi:email": "bi:email"
+                    }
+                },
+                { "id": "end", "type": "END" }
+            ],
+            "edges": [
+                { "id": "e_start_to_basic_details", "source_id": "start", "target_id": "basic_details" },
+                { "id": "e_basic_details_to_end", "source_id": "basic_details", "target_id": "end" }
+            ]
+        }'::jsonb
+    ),
+    (
+        'pre-consignment-general-trader-verification-v1',
+        'General Trader Verification Workflow',
+        '1',
+        '{
+            "id": "pre-consignment-general-trader-verification-v1",
+            "name": "General Trader Verification Workflow",
+            "version": 1,
+            "nodes": [
+                { "id": "start", "type": "START" },
+                {
+                    "id": "general_trader_verification",
+                    "type": "TASK",
+                    "task_template_id": "d0000002-0001-0001-0001-000000000005",
+                    "input_mapping": {
+                        "bi:businessName": "bi:businessName",
+                        "bi:businessType": "bi:businessType",
+                        "bi:businessAddress": "bi:businessAddress"
+                    },
+                    "output_mapping": {
+                        "br:registrationNumber": "br:registrationNumber",
+                        "br:tinNumber": "br:tinNumber",
+                        "br:tinCertificate": "br:tinCertificate",
+                        "br:vatNumber": "br:vatNumber",
+                        "br:vatCertificate": "br:vatCertificate"
+                    }
+                },
+                { "id": "end", "type": "END" }
+            ],
+            "edges": [
+                { "id": "e_start_to_verification", "source_id": "start", "target_id": "general_trader_verification" },
+                { "id": "e_verification_to_end", "source_id": "general_trader_verification", "target_id": "end" }
+            ]
+        }'::jsonb
+    ) ON CONFLICT (id) DO NOTHING;
+
+UPDATE pre_consignment_templates SET workflow_template_v2_id = 'pre-consignment-basic-details-v1'
+WHERE id = '0c000004-0001-0001-0001-000000000001';
+
+UPDATE pre_consignment_templates SET workflow_template_v2_id = 'pre-consignment-general-trader-verification-v1'
+WHERE id = '0c000004-0001-0001-0001-000000000002';
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+DROP INDEX IF EXISTS workflow_template_maps_v2_hs_code_flow_key;
+
+ALTER TABLE workflow_template_v2
+    DROP COLUMN IF EXISTS published_by,
+    DROP COLUMN IF EXISTS published_at,
+    DROP COLUMN IF EXISTS based_on_id,
+    DROP COLUMN IF EXISTS status;
+
+ALTER TABLE workflow_node_templates
+    DROP COLUMN IF EXISTS published_by,
+    DROP COLUMN IF EXISTS published_at,
+    DROP COLUMN IF EXISTS based_on_id,
+    DROP COLUMN IF EXISTS status;
+
+COMMIT;
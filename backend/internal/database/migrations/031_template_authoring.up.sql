@@ -0,0 +1,33 @@
+BEGIN;
+
+-- Templates authored through the admin API start as drafts, which can be edited and deleted,
+-- and become immutable once published. Rows seeded by earlier migrations are published.
+-- based_on_id names the published template a draft revises; publishing the draft moves the
+-- HS-code mappings and pre-consignment templates that used that template over to it.
+ALTER TABLE workflow_node_templates
+    ADD COLUMN IF NOT EXISTS status varchar(20) DEFAULT 'PUBLISHED' NOT NULL
+        CONSTRAINT workflow_node_templates_status_check CHECK (status IN ('DRAFT', 'PUBLISHED')),
+    ADD COLUMN IF NOT EXISTS based_on_id text,
+    ADD COLUMN IF NOT EXISTS published_at timestamp with time zone,
+    ADD COLUMN IF NOT EXISTS published_by varchar(255);
+
+ALTER TABLE workflow_template_v2
+    ADD COLUMN IF NOT EXISTS status varchar(20) DEFAULT 'PUBLISHED' NOT NULL
+        CONSTRAINT workflow_template_v2_status_check CHECK (status IN ('DRAFT', 'PUBLISHED')),
+    ADD COLUMN IF NOT EXISTS based_on_id text,
+    ADD COLUMN IF NOT EXISTS published_at timestamp with time zone,
+    ADD COLUMN IF NOT EXISTS published_by varchar(255);
+
+UPDATE workflow_node_templates SET published_at = created_at WHERE published_at IS NULL;
+UPDATE workflow_template_v2 SET published_at = created_at WHERE published_at IS NULL;
+
+COMMENT ON COLUMN workflow_node_templates.status IS 'DRAFT templates can be edited; PUBLISHED templates are immutable';
+COMMENT ON COLUMN workflow_node_templates.based_on_id IS 'Published node template this one was drafted from';
+COMMENT ON COLUMN workflow_template_v2.status IS 'DRAFT templates can be edited; PUBLISHED templates are immutable and can be mapped to HS codes';
+COMMENT ON COLUMN workflow_template_v2.based_on_id IS 'Published template this one revises; publishing it moves that template''s mappings here';
+
+-- An HS code and flow resolve to exactly one workflow template.
+CREATE UNIQUE INDEX IF NOT EXISTS workflow_template_maps_v2_hs_code_flow_key
+    ON workflow_template_maps_v2 (hs_code_id, consignment_flow);
+
+COMMIT;
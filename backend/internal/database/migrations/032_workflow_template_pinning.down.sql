@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_workflows_workflow_template_id_status;
+
+ALTER TABLE workflows DROP CONSTRAINT IF EXISTS fk_workflows_workflow_template;
+
+ALTER TABLE workflows
+    DROP COLUMN IF EXISTS migrated_at,
+    DROP COLUMN IF EXISTS task_template_overrides,
+    DROP COLUMN IF EXISTS node_mapping,
+    DROP COLUMN IF EXISTS workflow_template_version,
+    DROP COLUMN IF EXISTS workflow_template_id;
+
+COMMIT;
@@ -0,0 +1,46 @@
+BEGIN;
+
+-- Every workflow records the workflow_template_v2 it runs, so versions of a template can run
+-- side by side and live workflows can be migrated to a corrected version. A migration cannot
+-- change the graph the workflow engine is interpreting, only the node templates used by its
+-- task nodes: node_mapping maps the engine's node IDs to the nodes of the pinned template and
+-- task_template_overrides holds the node template each migrated node now starts.
+ALTER TABLE workflows
+    ADD COLUMN IF NOT EXISTS workflow_template_id text,
+    ADD COLUMN IF NOT EXISTS workflow_template_version varchar(50),
+    ADD COLUMN IF NOT EXISTS node_mapping jsonb,
+    ADD COLUMN IF NOT EXISTS task_template_overrides jsonb,
+    ADD COLUMN IF NOT EXISTS migrated_at timestamp with time zone;
+
+ALTER TABLE workflows ADD CONSTRAINT fk_workflows_workflow_template
+    FOREIGN KEY (workflow_template_id) REFERENCES workflow_template_v2(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS idx_workflows_workflow_template_id_status ON workflows (workflow_template_id, status);
+
+COMMENT ON COLUMN workflows.workflow_template_id IS 'workflow_template_v2 the workflow was started from, or last migrated to';
+COMMENT ON COLUMN workflows.workflow_template_version IS 'Version of the workflow template at the time it was pinned';
+COMMENT ON COLUMN workflows.node_mapping IS 'Engine node ID to node ID in the pinned template, for nodes renamed by a migration';
+COMMENT ON COLUMN workflows.task_template_overrides IS 'Engine node ID to the node template it starts, for nodes whose template was changed by a migration';
+COMMENT ON COLUMN workflows.migrated_at IS 'When the workflow was last migrated to another template';
+
+-- Workflows started on the workflow engine before this migration have no row yet. Pin them to
+-- the template their HS code or pre-consignment template resolves to now, which is the one
+-- they started from as long as no template has been revised since.
+INSERT INTO workflows (id, status, global_context, workflow_template_id, workflow_template_version, created_at, updated_at)
+SELECT c.id, 'IN_PROGRESS', '{}'::jsonb, t.id, t.version, c.created_at, c.updated_at
+FROM consignments c
+JOIN workflow_template_maps_v2 m ON m.hs_code_id = c.items->0->>'hsCodeId' AND m.consignment_flow = c.flow
+JOIN workflow_template_v2 t ON t.id = m.workflow_template_id
+WHERE c.state = 'IN_PROGRESS'
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO workflows (id, status, global_context, workflow_template_id, workflow_template_version, created_at, updated_at)
+SELECT pc.id, 'IN_PROGRESS', '{}'::jsonb, t.id, t.version, pc.created_at, pc.updated_at
+FROM pre_consignments pc
+JOIN pre_consignment_templates pct ON pct.id = pc.pre_consignment_template_id
+JOIN workflow_template_v2 t ON t.id = pct.workflow_template_v2_id
+WHERE pc.state = 'IN_PROGRESS'
+ON CONFLICT (id) DO NOTHING;
+
+COMMIT;
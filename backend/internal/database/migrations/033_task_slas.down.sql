@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE oga_agencies
+    DROP COLUMN IF EXISTS supervisor_email,
+    DROP COLUMN IF EXISTS officer_email;
+
+DROP TABLE IF EXISTS task_sla_timers;
+
+ALTER TABLE task_infos DROP COLUMN IF EXISTS sla;
+ALTER TABLE workflow_node_templates DROP COLUMN IF EXISTS sla;
+
+COMMIT;
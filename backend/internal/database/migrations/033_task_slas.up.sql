@@ -0,0 +1,48 @@
+BEGIN;
+
+-- Node templates may declare an SLA: how long a task may stay in the states it covers, measured
+-- in calendar or business time, and the escalations fired once that time is exceeded. The policy
+-- is copied onto each task when it starts, so later template revisions do not move deadlines.
+ALTER TABLE workflow_node_templates ADD COLUMN IF NOT EXISTS sla jsonb;
+ALTER TABLE task_infos ADD COLUMN IF NOT EXISTS sla jsonb;
+
+COMMENT ON COLUMN workflow_node_templates.sla IS 'SLA policy of tasks started from the template: duration, covered plugin states and escalations';
+COMMENT ON COLUMN task_infos.sla IS 'SLA policy copied from the node template when the task started';
+
+-- Each period a task spends in the states its SLA covers runs one clock. A task that leaves those
+-- states and returns (e.g. after an OGA feedback round) starts a new clock.
+CREATE TABLE IF NOT EXISTS task_sla_timers (
+    id uuid NOT NULL PRIMARY KEY,
+    task_id text NOT NULL REFERENCES task_infos(id) ON DELETE CASCADE,
+    workflow_id text NOT NULL,
+    plugin_state varchar(100),
+    started_at timestamp with time zone NOT NULL,
+    due_at timestamp with time zone NOT NULL,
+    stopped_at timestamp with time zone,
+    breached_at timestamp with time zone,
+    escalations integer NOT NULL DEFAULT 0,
+    next_escalation_at timestamp with time zone
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_task_sla_timers_running ON task_sla_timers (task_id) WHERE stopped_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_task_sla_timers_next_escalation_at ON task_sla_timers (next_escalation_at) WHERE stopped_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_task_sla_timers_workflow_id ON task_sla_timers (workflow_id);
+CREATE INDEX IF NOT EXISTS idx_task_sla_timers_started_at ON task_sla_timers (started_at);
+
+COMMENT ON TABLE task_sla_timers IS 'SLA clocks of tasks, one per period spent in the states the task SLA covers';
+COMMENT ON COLUMN task_sla_timers.plugin_state IS 'Plugin state the task was in when the clock started';
+COMMENT ON COLUMN task_sla_timers.due_at IS 'Deadline, with non-business days skipped when the SLA uses the business calendar';
+COMMENT ON COLUMN task_sla_timers.stopped_at IS 'When the task left the covered states; NULL while the clock runs';
+COMMENT ON COLUMN task_sla_timers.breached_at IS 'Deadline of a clock that ran past it';
+COMMENT ON COLUMN task_sla_timers.escalations IS 'Number of the SLA escalations fired so far, in policy order';
+COMMENT ON COLUMN task_sla_timers.next_escalation_at IS 'When the SLA monitor next acts on the clock: the deadline, then each pending escalation';
+
+-- Breach notifications address the agency officers or their supervisor.
+ALTER TABLE oga_agencies
+    ADD COLUMN IF NOT EXISTS officer_email varchar(255),
+    ADD COLUMN IF NOT EXISTS supervisor_email varchar(255);
+
+COMMENT ON COLUMN oga_agencies.officer_email IS 'Address notified of tasks of the agency that breach their SLA';
+COMMENT ON COLUMN oga_agencies.supervisor_email IS 'Address of the agency supervisor that SLA breaches escalate to';
+
+COMMIT;
@@ -0,0 +1,28 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS trg_payment_refunds_guard ON payment_refunds;
+DROP FUNCTION IF EXISTS payment_refunds_guard();
+
+-- Refunds that never reached a final status cannot be represented in the append-only ledger.
+DELETE FROM payment_refunds WHERE status IN ('PENDING_APPROVAL', 'PENDING', 'REJECTED');
+
+ALTER TABLE payment_refunds ALTER COLUMN approved_by DROP DEFAULT;
+ALTER TABLE payment_refunds
+    DROP COLUMN IF EXISTS rejected_by,
+    DROP COLUMN IF EXISTS updated_at;
+
+CREATE OR REPLACE FUNCTION payment_refunds_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'payment_refunds is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_payment_refunds_append_only
+    BEFORE UPDATE OR DELETE ON payment_refunds
+    FOR EACH ROW EXECUTE FUNCTION payment_refunds_append_only();
+
+COMMENT ON TABLE payment_refunds IS 'Append-only ledger of refund attempts against payment transactions';
+COMMENT ON COLUMN payment_refunds.status IS 'SUCCEEDED or FAILED. Only SUCCEEDED entries reduce the refundable balance';
+COMMENT ON COLUMN payment_refunds.approved_by IS NULL;
+
+COMMIT;
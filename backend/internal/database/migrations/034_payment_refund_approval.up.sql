@@ -0,0 +1,49 @@
+BEGIN;
+
+-- Refunds are requested by one officer and approved by another before the gateway is
+-- called, so an entry now moves PENDING_APPROVAL -> PENDING -> SUCCEEDED or FAILED, or
+-- PENDING_APPROVAL -> REJECTED. approved_by is empty until the refund is approved.
+ALTER TABLE payment_refunds
+    ADD COLUMN IF NOT EXISTS rejected_by VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW();
+ALTER TABLE payment_refunds ALTER COLUMN approved_by SET DEFAULT '';
+
+-- Entries are still never deleted, and a final entry never changes. An open entry may only
+-- change its status, decision and gateway outcome, and its approver once set is kept.
+CREATE OR REPLACE FUNCTION payment_refunds_guard() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        RAISE EXCEPTION 'payment_refunds entries cannot be deleted';
+    END IF;
+    IF OLD.status NOT IN ('PENDING_APPROVAL', 'PENDING') THEN
+        RAISE EXCEPTION 'payment_refunds entry % is final', OLD.id;
+    END IF;
+    IF NEW.id <> OLD.id
+        OR NEW.transaction_id <> OLD.transaction_id
+        OR NEW.reference_number <> OLD.reference_number
+        OR NEW.amount <> OLD.amount
+        OR NEW.currency <> OLD.currency
+        OR NEW.reason_code <> OLD.reason_code
+        OR NEW.reason_note IS DISTINCT FROM OLD.reason_note
+        OR NEW.requested_by <> OLD.requested_by
+        OR NEW.created_at IS DISTINCT FROM OLD.created_at
+        OR (OLD.approved_by <> '' AND NEW.approved_by <> OLD.approved_by) THEN
+        RAISE EXCEPTION 'only the status, decision and gateway outcome of payment_refunds entry % may change', OLD.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_payment_refunds_append_only ON payment_refunds;
+DROP FUNCTION IF EXISTS payment_refunds_append_only();
+DROP TRIGGER IF EXISTS trg_payment_refunds_guard ON payment_refunds;
+CREATE TRIGGER trg_payment_refunds_guard
+    BEFORE UPDATE OR DELETE ON payment_refunds
+    FOR EACH ROW EXECUTE FUNCTION payment_refunds_guard();
+
+COMMENT ON TABLE payment_refunds IS 'Ledger of refunds against payment transactions. Entries are never deleted; final entries never change';
+COMMENT ON COLUMN payment_refunds.status IS 'PENDING_APPROVAL, PENDING, SUCCEEDED, FAILED or REJECTED. All but FAILED and REJECTED reserve the refundable balance';
+COMMENT ON COLUMN payment_refunds.approved_by IS 'Refund officer who approved the refund; empty until approved and never the requester';
+COMMENT ON COLUMN payment_refunds.rejected_by IS 'Refund officer who rejected the refund';
+
+COMMIT;
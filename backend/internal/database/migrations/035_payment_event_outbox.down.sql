@@ -0,0 +1,9 @@
+BEGIN;
+
+COMMENT ON COLUMN payment_transactions.status IS 'PENDING, SUCCESS, FAILED, EXPIRED, PARTIALLY_REFUNDED or REFUNDED. EXPIRED is set by reconciliation and may still become SUCCESS on a late confirmation';
+
+DROP INDEX IF EXISTS idx_payment_tx_event_pending;
+ALTER TABLE payment_transactions
+    DROP COLUMN IF EXISTS event_pending;
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+ALTER TABLE payment_transactions
+    ADD COLUMN IF NOT EXISTS event_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Supports reconciliation finding final outcomes whose task event was not delivered.
+CREATE INDEX IF NOT EXISTS idx_payment_tx_event_pending ON payment_transactions (updated_at) WHERE event_pending;
+
+COMMENT ON COLUMN payment_transactions.event_pending IS 'Set with a final status in the same update, cleared once the owning task has received the outcome. Reconciliation delivers pending events again';
+COMMENT ON COLUMN payment_transactions.status IS 'PENDING, SUCCESS, FAILED, EXPIRED, AMOUNT_MISMATCH, PARTIALLY_REFUNDED or REFUNDED. EXPIRED is set by reconciliation and may still become SUCCESS on a late confirmation. AMOUNT_MISMATCH holds a confirmation whose amount or currency differs from the transaction until a matching settlement corrects it';
+
+COMMIT;
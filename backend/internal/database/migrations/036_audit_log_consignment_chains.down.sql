@@ -0,0 +1,10 @@
+BEGIN;
+
+COMMENT ON COLUMN audit_log.prev_hash IS 'entry_hash of the previous chained entry; empty for the first';
+
+ALTER TABLE audit_checkpoints
+    DROP COLUMN IF EXISTS log_digest;
+
+DROP INDEX IF EXISTS idx_audit_log_chain_head;
+
+COMMIT;
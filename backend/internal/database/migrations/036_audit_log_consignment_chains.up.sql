@@ -0,0 +1,14 @@
+BEGIN;
+
+-- The audit log is chained per consignment: each entry links to the previous entry about the
+-- same consignment, so appends about different consignments do not serialize.
+CREATE INDEX IF NOT EXISTS idx_audit_log_chain_head ON audit_log (consignment_id, id) WHERE entry_hash <> '';
+
+-- Checkpoints pin a digest over every entry hash in id order, so they cover every chain.
+ALTER TABLE audit_checkpoints
+    ADD COLUMN IF NOT EXISTS log_digest VARCHAR(64) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN audit_log.prev_hash IS 'entry_hash of the previous chained entry with the same consignment_id; empty for the first';
+COMMENT ON COLUMN audit_checkpoints.log_digest IS 'Hex SHA-256 chain over the entry_hash of every chained audit_log entry up to and including last_entry_id, in id order';
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+UPDATE workflow_node_templates
+SET config = config - 'orgName'
+WHERE type = 'PAYMENT' AND config->>'orgId' = 'CUSTOMS';
+
+UPDATE task_infos
+SET config = config - 'orgName'
+WHERE type = 'PAYMENT' AND config->>'orgId' = 'CUSTOMS';
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+-- Payment configs name the collecting agency for the receipt. Running tasks keep a copy
+-- of their template's config, so both are updated.
+UPDATE workflow_node_templates
+SET config = config || '{"orgName": "Sri Lanka Customs"}'::jsonb
+WHERE type = 'PAYMENT' AND config->>'orgId' = 'CUSTOMS' AND NOT config ? 'orgName';
+
+UPDATE task_infos
+SET config = config || '{"orgName": "Sri Lanka Customs"}'::jsonb
+WHERE type = 'PAYMENT' AND config->>'orgId' = 'CUSTOMS' AND NOT config ? 'orgName';
+
+COMMIT;
@@ -11,6 +11,28 @@ MIGRATION_DB_HOST="${MIGRATION_DB_HOST:-$DB_HOST}"
 MIGRATION_DB_HOST="${MIGRATION_DB_HOST//host.docker.internal/localhost}"
 
 DOWNS=(
+  "037_payment_config_org_name.down.sql"
+  "036_audit_log_consignment_chains.down.sql"
+  "035_payment_event_outbox.down.sql"
+  "034_payment_refund_approval.down.sql"
+  "033_task_slas.down.sql"
+  "032_workflow_template_pinning.down.sql"
+  "031_template_authoring.down.sql"
+  "030_pre_consignment_temporal_workflows.down.sql"
+  "029_task_infos_version.down.sql"
+  "028_audit_log_chain.down.sql"
+  "027_audit_log.down.sql"
+  "026_organizations.down.sql"
+  "025_user_profile_history.down.sql"
+  "024_token_revocations.down.sql"
+  "023_consignment_access.down.sql"
+  "022_form_versions.down.sql"
+  "021_payment_receipts.down.sql"
+  "020_payment_exchange_rates.down.sql"
+  "019_payment_refunds.down.sql"
+  "018_payment_reconciliation.down.sql"
+  "017_payment_webhook_security.down.sql"
+  "016_payment_provider.down.sql"
   "015_fcau_workflow_seed.down.sql"
   "014_fcau_workflow_nodes_seed.down.sql"
   "013_fcau_forms_seed.down.sql"
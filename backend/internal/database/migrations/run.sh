@@ -61,6 +61,28 @@ MIGRATIONS=(
     "013_fcau_forms_seed.up.sql"
     "014_fcau_workflow_nodes_seed.up.sql"
     "015_fcau_workflow_seed.up.sql"
+    "016_payment_provider.up.sql"
+    "017_payment_webhook_security.up.sql"
+    "018_payment_reconciliation.up.sql"
+    "019_payment_refunds.up.sql"
+    "020_payment_exchange_rates.up.sql"
+    "021_payment_receipts.up.sql"
+    "022_form_versions.up.sql"
+    "023_consignment_access.up.sql"
+    "024_token_revocations.up.sql"
+    "025_user_profile_history.up.sql"
+    "026_organizations.up.sql"
+    "027_audit_log.up.sql"
+    "028_audit_log_chain.up.sql"
+    "029_task_infos_version.up.sql"
+    "030_pre_consignment_temporal_workflows.up.sql"
+    "031_template_authoring.up.sql"
+    "032_workflow_template_pinning.up.sql"
+    "033_task_slas.up.sql"
+    "034_payment_refund_approval.up.sql"
+    "035_payment_event_outbox.up.sql"
+    "036_audit_log_consignment_chains.up.sql"
+    "037_payment_config_org_name.up.sql"
 )
 
 echo "Starting database migrations..."
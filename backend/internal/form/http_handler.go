@@ -0,0 +1,209 @@
+package form
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	formmodel "github.com/OpenNSW/nsw/internal/form/model"
+)
+
+// HTTPHandler exposes the admin API for authoring forms and publishing their versions.
+type HTTPHandler struct {
+	service FormService
+}
+
+// NewHTTPHandler creates a new HTTPHandler for form administration
+func NewHTTPHandler(service FormService) *HTTPHandler {
+	return &HTTPHandler{service: service}
+}
+
+// HandlePublishVersion handles POST /api/v1/admin/forms/{formId}/versions
+func (h *HTTPHandler) HandlePublishVersion(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "publishing a form version requires an administrator")
+		return
+	}
+
+	var req formmodel.PublishFormVersionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	formID := r.PathValue("formId")
+	version, err := h.service.PublishFormVersion(r.Context(), formID, principalID(r), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrFormNotFound):
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrFormVersionExists):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, ErrInvalidFormVersion):
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		default:
+			slog.ErrorContext(r.Context(), "failed to publish form version", "formId", formID, "error", err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to publish form version")
+		}
+		return
+	}
+
+	slog.InfoContext(r.Context(), "form version published", "formId", formID, "version", version.Version, "publishedBy", principalID(r))
+	writeJSONResponse(w, http.StatusCreated, version)
+}
+
+// HandleListVersions handles GET /api/v1/admin/forms/{formId}/versions
+func (h *HTTPHandler) HandleListVersions(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "listing form versions requires an administrator")
+		return
+	}
+
+	formID := r.PathValue("formId")
+	versions, err := h.service.ListFormVersions(r.Context(), formID)
+	if err != nil {
+		if errors.Is(err, ErrFormNotFound) {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to list form versions", "formId", formID, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to list form versions")
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, versions)
+}
+
+// HandleListForms handles GET /api/v1/admin/forms
+func (h *HTTPHandler) HandleListForms(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "listing forms requires an administrator")
+		return
+	}
+
+	forms, err := h.service.ListForms(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list forms", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to list forms")
+		return
+	}
+	if forms == nil {
+		forms = []formmodel.Form{}
+	}
+	writeJSONResponse(w, http.StatusOK, forms)
+}
+
+// HandleGetForm handles GET /api/v1/admin/forms/{formId}
+func (h *HTTPHandler) HandleGetForm(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "reading a form requires an administrator")
+		return
+	}
+
+	form, err := h.service.GetForm(r.Context(), r.PathValue("formId"))
+	if err != nil {
+		h.writeFormError(w, r, "failed to retrieve form", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, form)
+}
+
+// HandleCreateForm handles POST /api/v1/admin/forms
+func (h *HTTPHandler) HandleCreateForm(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "creating a form requires an administrator")
+		return
+	}
+
+	var req formmodel.FormRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	form, err := h.service.CreateForm(r.Context(), req)
+	if err != nil {
+		h.writeFormError(w, r, "failed to create form", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusCreated, form)
+}
+
+// HandleUpdateForm handles PUT /api/v1/admin/forms/{formId}
+func (h *HTTPHandler) HandleUpdateForm(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "updating a form requires an administrator")
+		return
+	}
+
+	var req formmodel.FormRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	form, err := h.service.UpdateForm(r.Context(), r.PathValue("formId"), req)
+	if err != nil {
+		h.writeFormError(w, r, "failed to update form", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, form)
+}
+
+// HandleDeleteForm handles DELETE /api/v1/admin/forms/{formId}
+func (h *HTTPHandler) HandleDeleteForm(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "deleting a form requires an administrator")
+		return
+	}
+
+	if err := h.service.DeleteForm(r.Context(), r.PathValue("formId")); err != nil {
+		h.writeFormError(w, r, "failed to delete form", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeFormError maps the errors of form operations to responses; message is returned for
+// unexpected errors, which are logged.
+func (h *HTTPHandler) writeFormError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, ErrFormNotFound):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrFormExists), errors.Is(err, ErrFormPublished):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvalidForm):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), message, "formId", r.PathValue("formId"), "error", err)
+		writeJSONError(w, http.StatusInternalServerError, message)
+	}
+}
+
+// principalID identifies the user or client making the request.
+func principalID(r *http.Request) string {
+	authCtx := auth.GetAuthContext(r.Context())
+	switch {
+	case authCtx == nil:
+		return ""
+	case authCtx.User != nil:
+		return authCtx.User.ID
+	case authCtx.Client != nil:
+		return authCtx.Client.ClientID
+	}
+	return ""
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+// writeJSONError sets Content-Type: application/json and writes a consistent JSON error body.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSONResponse(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,157 @@
+package form
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	formmodel "github.com/OpenNSW/nsw/internal/form/model"
+)
+
+// stubFormService publishes into an in-memory list of versions.
+type stubFormService struct {
+	FormService
+	versions    []formmodel.FormVersion
+	publishedBy string
+}
+
+func (s *stubFormService) PublishFormVersion(ctx context.Context, formID, publishedBy string, req formmodel.PublishFormVersionRequest) (*formmodel.FormVersion, error) {
+	if formID != "form-1" {
+		return nil, fmt.Errorf("form with ID %s not found: %w", formID, ErrFormNotFound)
+	}
+	for _, v := range s.versions {
+		if v.Version == req.Version {
+			return nil, fmt.Errorf("version %s of form %s: %w", req.Version, formID, ErrFormVersionExists)
+		}
+	}
+	if req.Version == "" {
+		return nil, fmt.Errorf("%w: version is required", ErrInvalidFormVersion)
+	}
+	v := formmodel.FormVersion{ID: "v-" + req.Version, FormID: formID, Version: req.Version, Schema: req.Schema}
+	s.versions = append(s.versions, v)
+	s.publishedBy = publishedBy
+	return &v, nil
+}
+
+func (s *stubFormService) CreateForm(ctx context.Context, req formmodel.FormRequest) (*formmodel.Form, error) {
+	if req.ID == "form-1" {
+		return nil, fmt.Errorf("form with ID %s: %w", req.ID, ErrFormExists)
+	}
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidForm)
+	}
+	return &formmodel.Form{BaseModel: formmodel.BaseModel{ID: req.ID}, Name: req.Name}, nil
+}
+
+func (s *stubFormService) DeleteForm(ctx context.Context, formID string) error {
+	switch formID {
+	case "form-1":
+		return fmt.Errorf("form with ID %s: %w", formID, ErrFormPublished)
+	case "draft-form":
+		return nil
+	}
+	return fmt.Errorf("form with ID %s not found: %w", formID, ErrFormNotFound)
+}
+
+func TestHTTPHandler_HandlePublishVersion(t *testing.T) {
+	admin := &auth.AuthContext{User: &auth.UserContext{ID: "admin-1", Roles: []string{auth.RoleAdmin}}}
+	trader := &auth.AuthContext{User: &auth.UserContext{ID: "trader-1", Roles: []string{"exporter"}}}
+
+	tests := []struct {
+		name    string
+		formID  string
+		body    string
+		authCtx *auth.AuthContext
+		want    int
+	}{
+		{"Published", "form-1", `{"version": "2.0", "schema": {"type": "object"}}`, admin, http.StatusCreated},
+		{"Duplicate Version", "form-1", `{"version": "1.0", "schema": {"type": "object"}}`, admin, http.StatusConflict},
+		{"Missing Version", "form-1", `{"schema": {"type": "object"}}`, admin, http.StatusBadRequest},
+		{"Unknown Form", "form-2", `{"version": "2.0", "schema": {"type": "object"}}`, admin, http.StatusNotFound},
+		{"Invalid Body", "form-1", `not json`, admin, http.StatusBadRequest},
+		{"Non-Admin", "form-1", `{"version": "2.0", "schema": {"type": "object"}}`, trader, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &stubFormService{versions: []formmodel.FormVersion{{ID: "v-1.0", FormID: "form-1", Version: "1.0"}}}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/forms/"+tt.formID+"/versions", bytes.NewBufferString(tt.body))
+			req.SetPathValue("formId", tt.formID)
+			req = req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, tt.authCtx))
+			w := httptest.NewRecorder()
+
+			NewHTTPHandler(service).HandlePublishVersion(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.want == http.StatusCreated && service.publishedBy != "admin-1" {
+				t.Errorf("expected version published by admin-1, got %q", service.publishedBy)
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_HandleCreateForm(t *testing.T) {
+	admin := &auth.AuthContext{User: &auth.UserContext{ID: "admin-1", Roles: []string{auth.RoleAdmin}}}
+	trader := &auth.AuthContext{User: &auth.UserContext{ID: "trader-1", Roles: []string{"exporter"}}}
+
+	tests := []struct {
+		name    string
+		body    string
+		authCtx *auth.AuthContext
+		want    int
+	}{
+		{"Created", `{"id": "form-2", "name": "Health Certificate"}`, admin, http.StatusCreated},
+		{"Duplicate ID", `{"id": "form-1", "name": "Health Certificate"}`, admin, http.StatusConflict},
+		{"Missing Name", `{"id": "form-2"}`, admin, http.StatusBadRequest},
+		{"Non-Admin", `{"id": "form-2", "name": "Health Certificate"}`, trader, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/forms", bytes.NewBufferString(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, tt.authCtx))
+			w := httptest.NewRecorder()
+
+			NewHTTPHandler(&stubFormService{}).HandleCreateForm(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_HandleDeleteForm(t *testing.T) {
+	admin := &auth.AuthContext{User: &auth.UserContext{ID: "admin-1", Roles: []string{auth.RoleAdmin}}}
+
+	tests := []struct {
+		name   string
+		formID string
+		want   int
+	}{
+		{"Draft Deleted", "draft-form", http.StatusNoContent},
+		{"Published Form", "form-1", http.StatusConflict},
+		{"Unknown Form", "form-9", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/forms/"+tt.formID, nil)
+			req.SetPathValue("formId", tt.formID)
+			req = req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, admin))
+			w := httptest.NewRecorder()
+
+			NewHTTPHandler(&stubFormService{}).HandleDeleteForm(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -51,6 +51,35 @@ func (f *Form) TableName() string {
 	return "forms"
 }
 
+// FormVersion is an immutable, published revision of a form definition.
+type FormVersion struct {
+	ID          string          `gorm:"type:text;column:id;not null;primaryKey" json:"id"`
+	FormID      string          `gorm:"type:text;column:form_id;not null" json:"formId"`
+	Version     string          `gorm:"type:varchar(50);column:version;not null" json:"version"`
+	Schema      json.RawMessage `gorm:"type:jsonb;column:schema;not null" json:"schema"`
+	UISchema    json.RawMessage `gorm:"type:jsonb;column:ui_schema;not null" json:"uiSchema"`
+	PublishedBy *string         `gorm:"type:varchar(255);column:published_by" json:"publishedBy,omitempty"`
+	CreatedAt   time.Time       `gorm:"type:timestamptz;column:created_at;not null" json:"createdAt"`
+}
+
+func (v *FormVersion) TableName() string {
+	return "form_versions"
+}
+
+// PublishFormVersionRequest is the body of a request to publish a new form version.
+type PublishFormVersionRequest struct {
+	Version  string          `json:"version"`
+	Schema   json.RawMessage `json:"schema"`
+	UISchema json.RawMessage `json:"uiSchema"`
+}
+
+// FormRequest is the body of a request to create a form or change its name and description.
+type FormRequest struct {
+	ID          string `json:"id"` // Optional on create; generated when empty. Ignored on update.
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
 // FormResponse represents the response structure for form retrieval
 // This is what portals receive - they don't need to know about Task/FormType
 type FormResponse struct {
@@ -2,16 +2,36 @@ package form
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	formmodel "github.com/OpenNSW/nsw/internal/form/model"
+	"github.com/OpenNSW/nsw/pkg/jsonform"
 )
 
-// ErrFormNotFound is returned when a form is not found
-var ErrFormNotFound = errors.New("form not found")
+var (
+	// ErrFormNotFound is returned when a form is not found
+	ErrFormNotFound = errors.New("form not found")
+	// ErrFormVersionNotFound is returned when a form has no version with the requested name
+	ErrFormVersionNotFound = errors.New("form version not found")
+	// ErrFormVersionExists is returned when publishing a version name that is already taken
+	ErrFormVersionExists = errors.New("form version already exists")
+	// ErrInvalidFormVersion is returned when a version to publish is incomplete or its schema does not compile
+	ErrInvalidFormVersion = errors.New("invalid form version")
+	// ErrFormExists is returned when creating a form whose ID is already taken
+	ErrFormExists = errors.New("form already exists")
+	// ErrFormPublished is returned when deleting a form that has been published
+	ErrFormPublished = errors.New("form has been published")
+	// ErrInvalidForm is returned when a form to create or update is incomplete
+	ErrInvalidForm = errors.New("invalid form")
+)
 
 // FormService provides methods to retrieve form definitions
 // FormService is a pure domain service that only works with forms.
@@ -21,6 +41,34 @@ type FormService interface {
 	// GetFormByID retrieves a form by its UUID
 	// Returns the JSON Schema and UI Schema that portals can directly use with JSON Forms
 	GetFormByID(ctx context.Context, formID string) (*formmodel.FormResponse, error)
+
+	// GetFormVersion retrieves a specific published version of a form, whether or not
+	// it is still the current one. Tasks use it to keep rendering the version they pinned.
+	GetFormVersion(ctx context.Context, formID, version string) (*formmodel.FormResponse, error)
+
+	// ListFormVersions returns every published version of a form, newest first.
+	ListFormVersions(ctx context.Context, formID string) ([]formmodel.FormVersion, error)
+
+	// PublishFormVersion publishes a new immutable version and makes it the form's current
+	// definition. Tasks already pinned to an earlier version are unaffected.
+	PublishFormVersion(ctx context.Context, formID, publishedBy string, req formmodel.PublishFormVersionRequest) (*formmodel.FormVersion, error)
+
+	// ListForms returns every form, including those not yet published.
+	ListForms(ctx context.Context) ([]formmodel.Form, error)
+
+	// GetForm retrieves a form whether or not it has been published.
+	GetForm(ctx context.Context, formID string) (*formmodel.Form, error)
+
+	// CreateForm creates an inactive form with no definition. Publishing its first version
+	// activates it.
+	CreateForm(ctx context.Context, req formmodel.FormRequest) (*formmodel.Form, error)
+
+	// UpdateForm changes a form's name and description. Definitions change only by
+	// publishing versions.
+	UpdateForm(ctx context.Context, formID string, req formmodel.FormRequest) (*formmodel.Form, error)
+
+	// DeleteForm deletes a form that has never been published.
+	DeleteForm(ctx context.Context, formID string) error
 }
 
 type formService struct {
@@ -58,3 +106,230 @@ func (s *formService) GetFormByID(ctx context.Context, formID string) (*formmode
 		Version:  form.Version,
 	}, nil
 }
+
+// GetFormVersion retrieves a specific published version of a form
+func (s *formService) GetFormVersion(ctx context.Context, formID, version string) (*formmodel.FormResponse, error) {
+	if formID == "" || version == "" {
+		return nil, fmt.Errorf("formID and version are required")
+	}
+
+	var form formmodel.Form
+	if err := s.db.WithContext(ctx).Select("id", "name").Where("id = ?", formID).First(&form).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("form with ID %s not found: %w", formID, ErrFormNotFound)
+		}
+		return nil, fmt.Errorf("failed to retrieve form: %w", err)
+	}
+
+	var formVersion formmodel.FormVersion
+	if err := s.db.WithContext(ctx).
+		Where("form_id = ? AND version = ?", formID, version).
+		First(&formVersion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("version %s of form %s not found: %w", version, formID, ErrFormVersionNotFound)
+		}
+		return nil, fmt.Errorf("failed to retrieve form version: %w", err)
+	}
+
+	return &formmodel.FormResponse{
+		ID:       form.ID,
+		Name:     form.Name,
+		Schema:   formVersion.Schema,
+		UISchema: formVersion.UISchema,
+		Version:  formVersion.Version,
+	}, nil
+}
+
+// ListFormVersions returns every published version of a form, newest first
+func (s *formService) ListFormVersions(ctx context.Context, formID string) ([]formmodel.FormVersion, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&formmodel.Form{}).Where("id = ?", formID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve form: %w", err)
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("form with ID %s not found: %w", formID, ErrFormNotFound)
+	}
+
+	var versions []formmodel.FormVersion
+	if err := s.db.WithContext(ctx).
+		Where("form_id = ?", formID).
+		Order("created_at DESC").
+		Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list form versions: %w", err)
+	}
+	return versions, nil
+}
+
+// PublishFormVersion publishes a new immutable version of a form
+func (s *formService) PublishFormVersion(ctx context.Context, formID, publishedBy string, req formmodel.PublishFormVersionRequest) (*formmodel.FormVersion, error) {
+	req.Version = strings.TrimSpace(req.Version)
+	if req.Version == "" {
+		return nil, fmt.Errorf("%w: version is required", ErrInvalidFormVersion)
+	}
+	if len(req.Schema) == 0 {
+		return nil, fmt.Errorf("%w: schema is required", ErrInvalidFormVersion)
+	}
+	if err := jsonform.CheckSchema(req.Schema); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFormVersion, err)
+	}
+	if len(req.UISchema) == 0 {
+		req.UISchema = json.RawMessage(`{}`)
+	}
+
+	formVersion := &formmodel.FormVersion{
+		ID:        uuid.NewString(),
+		FormID:    formID,
+		Version:   req.Version,
+		Schema:    req.Schema,
+		UISchema:  req.UISchema,
+		CreatedAt: time.Now().UTC(),
+	}
+	if publishedBy != "" {
+		formVersion.PublishedBy = &publishedBy
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the form so concurrent publishes are serialised.
+		var form formmodel.Form
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", formID).First(&form).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("form with ID %s not found: %w", formID, ErrFormNotFound)
+			}
+			return fmt.Errorf("failed to retrieve form: %w", err)
+		}
+
+		var existing int64
+		if err := tx.Model(&formmodel.FormVersion{}).
+			Where("form_id = ? AND version = ?", formID, req.Version).
+			Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to check form version: %w", err)
+		}
+		if existing > 0 {
+			return fmt.Errorf("version %s of form %s: %w", req.Version, formID, ErrFormVersionExists)
+		}
+
+		if err := tx.Create(formVersion).Error; err != nil {
+			return fmt.Errorf("failed to create form version: %w", err)
+		}
+		if err := tx.Model(&form).Updates(map[string]any{
+			"schema":     formVersion.Schema,
+			"ui_schema":  formVersion.UISchema,
+			"version":    formVersion.Version,
+			"active":     true,
+			"updated_at": formVersion.CreatedAt,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update current form definition: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return formVersion, nil
+}
+
+// ListForms returns every form, including those not yet published
+func (s *formService) ListForms(ctx context.Context) ([]formmodel.Form, error) {
+	var forms []formmodel.Form
+	if err := s.db.WithContext(ctx).Order("name").Find(&forms).Error; err != nil {
+		return nil, fmt.Errorf("failed to list forms: %w", err)
+	}
+	return forms, nil
+}
+
+// GetForm retrieves a form whether or not it has been published
+func (s *formService) GetForm(ctx context.Context, formID string) (*formmodel.Form, error) {
+	var form formmodel.Form
+	if err := s.db.WithContext(ctx).Where("id = ?", formID).First(&form).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("form with ID %s not found: %w", formID, ErrFormNotFound)
+		}
+		return nil, fmt.Errorf("failed to retrieve form: %w", err)
+	}
+	return &form, nil
+}
+
+// CreateForm creates an inactive form with no definition
+func (s *formService) CreateForm(ctx context.Context, req formmodel.FormRequest) (*formmodel.Form, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidForm)
+	}
+
+	form := &formmodel.Form{
+		BaseModel:   formmodel.BaseModel{ID: strings.TrimSpace(req.ID)},
+		Name:        req.Name,
+		Description: req.Description,
+		Schema:      json.RawMessage(`{}`),
+		UISchema:    json.RawMessage(`{}`),
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if form.ID != "" {
+			var count int64
+			if err := tx.Model(&formmodel.Form{}).Where("id = ?", form.ID).Count(&count).Error; err != nil {
+				return fmt.Errorf("failed to check form: %w", err)
+			}
+			if count > 0 {
+				return fmt.Errorf("form with ID %s: %w", form.ID, ErrFormExists)
+			}
+		}
+		// Select every column so the zero values of version and active are written instead
+		// of the column defaults, which describe a published form.
+		if err := tx.Select("*").Create(form).Error; err != nil {
+			return fmt.Errorf("failed to create form: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+// UpdateForm changes a form's name and description
+func (s *formService) UpdateForm(ctx context.Context, formID string, req formmodel.FormRequest) (*formmodel.Form, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidForm)
+	}
+
+	form, err := s.GetForm(ctx, formID)
+	if err != nil {
+		return nil, err
+	}
+	form.Name, form.Description = req.Name, req.Description
+	if err := s.db.WithContext(ctx).Model(form).Updates(map[string]any{
+		"name":        form.Name,
+		"description": form.Description,
+		"updated_at":  time.Now().UTC(),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update form: %w", err)
+	}
+	return form, nil
+}
+
+// DeleteForm deletes a form that has never been published
+func (s *formService) DeleteForm(ctx context.Context, formID string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var form formmodel.Form
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", formID).First(&form).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("form with ID %s not found: %w", formID, ErrFormNotFound)
+			}
+			return fmt.Errorf("failed to retrieve form: %w", err)
+		}
+
+		var versions int64
+		if err := tx.Model(&formmodel.FormVersion{}).Where("form_id = ?", formID).Count(&versions).Error; err != nil {
+			return fmt.Errorf("failed to check form versions: %w", err)
+		}
+		if form.Active || versions > 0 {
+			return fmt.Errorf("form with ID %s: %w", formID, ErrFormPublished)
+		}
+
+		if err := tx.Delete(&form).Error; err != nil {
+			return fmt.Errorf("failed to delete form: %w", err)
+		}
+		return nil
+	})
+}
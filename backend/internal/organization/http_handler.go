@@ -0,0 +1,274 @@
+package organization
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// HTTPHandler exposes organisations, invitations and consignment delegations to users.
+type HTTPHandler struct {
+	service Service
+}
+
+// NewHTTPHandler creates a new HTTPHandler for organisations
+func NewHTTPHandler(service Service) *HTTPHandler {
+	return &HTTPHandler{service: service}
+}
+
+// HandleCreateOrganization handles POST /api/v1/organizations
+func (h *HTTPHandler) HandleCreateOrganization(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	var req CreateOrganizationRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	organization, err := h.service.CreateOrganization(r.Context(), actor, req)
+	if err != nil {
+		writeServiceError(w, r, "failed to create organization", err)
+		return
+	}
+	slog.InfoContext(r.Context(), "organization created", "organizationId", organization.ID, "kind", organization.Kind, "createdBy", actor.UserID)
+	writeJSONResponse(w, http.StatusCreated, organization)
+}
+
+// HandleListOrganizations handles GET /api/v1/organizations
+func (h *HTTPHandler) HandleListOrganizations(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	organizations, err := h.service.ListOrganizations(r.Context(), actor)
+	if err != nil {
+		writeServiceError(w, r, "failed to list organizations", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, organizations)
+}
+
+// HandleGetOrganization handles GET /api/v1/organizations/{orgId}
+func (h *HTTPHandler) HandleGetOrganization(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	organization, err := h.service.GetOrganization(r.Context(), actor, r.PathValue("orgId"))
+	if err != nil {
+		writeServiceError(w, r, "failed to retrieve organization", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, organization)
+}
+
+// HandleUpdateMember handles PUT /api/v1/organizations/{orgId}/members/{userId}
+func (h *HTTPHandler) HandleUpdateMember(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	var req UpdateMemberRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	member, err := h.service.UpdateMemberRole(r.Context(), actor, r.PathValue("orgId"), r.PathValue("userId"), req.Role)
+	if err != nil {
+		writeServiceError(w, r, "failed to update member", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, member)
+}
+
+// HandleRemoveMember handles DELETE /api/v1/organizations/{orgId}/members/{userId}
+func (h *HTTPHandler) HandleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.RemoveMember(r.Context(), actor, r.PathValue("orgId"), r.PathValue("userId")); err != nil {
+		writeServiceError(w, r, "failed to remove member", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleCreateInvitation handles POST /api/v1/organizations/{orgId}/invitations
+func (h *HTTPHandler) HandleCreateInvitation(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	var req CreateInvitationRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	invitation, err := h.service.CreateInvitation(r.Context(), actor, r.PathValue("orgId"), req)
+	if err != nil {
+		writeServiceError(w, r, "failed to create invitation", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusCreated, invitation)
+}
+
+// HandleListInvitations handles GET /api/v1/organizations/{orgId}/invitations
+func (h *HTTPHandler) HandleListInvitations(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	invitations, err := h.service.ListInvitations(r.Context(), actor, r.PathValue("orgId"))
+	if err != nil {
+		writeServiceError(w, r, "failed to list invitations", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, invitations)
+}
+
+// HandleRevokeInvitation handles DELETE /api/v1/organizations/{orgId}/invitations/{invitationId}
+func (h *HTTPHandler) HandleRevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.RevokeInvitation(r.Context(), actor, r.PathValue("orgId"), r.PathValue("invitationId")); err != nil {
+		writeServiceError(w, r, "failed to revoke invitation", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleAcceptInvitation handles POST /api/v1/invitations/accept
+func (h *HTTPHandler) HandleAcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	var req AcceptInvitationRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	member, err := h.service.AcceptInvitation(r.Context(), actor, req.Token)
+	if err != nil {
+		writeServiceError(w, r, "failed to accept invitation", err)
+		return
+	}
+	slog.InfoContext(r.Context(), "invitation accepted", "organizationId", member.OrganizationID, "userId", member.UserID, "role", member.Role)
+	writeJSONResponse(w, http.StatusOK, member)
+}
+
+// HandleCreateDelegation handles POST /api/v1/consignments/{id}/delegations
+func (h *HTTPHandler) HandleCreateDelegation(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	var req CreateDelegationRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	delegation, err := h.service.CreateDelegation(r.Context(), actor, r.PathValue("id"), req)
+	if err != nil {
+		writeServiceError(w, r, "failed to create delegation", err)
+		return
+	}
+	slog.InfoContext(r.Context(), "consignment delegated", "consignmentId", delegation.ConsignmentID, "organizationId", delegation.OrganizationID, "expiresAt", delegation.ExpiresAt, "grantedBy", actor.UserID)
+	writeJSONResponse(w, http.StatusCreated, delegation)
+}
+
+// HandleListDelegations handles GET /api/v1/consignments/{id}/delegations
+func (h *HTTPHandler) HandleListDelegations(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	delegations, err := h.service.ListDelegations(r.Context(), actor, r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, r, "failed to list delegations", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, delegations)
+}
+
+// HandleRevokeDelegation handles DELETE /api/v1/consignments/{id}/delegations/{delegationId}
+func (h *HTTPHandler) HandleRevokeDelegation(w http.ResponseWriter, r *http.Request) {
+	actor, ok := requireActor(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.RevokeDelegation(r.Context(), actor, r.PathValue("id"), r.PathValue("delegationId")); err != nil {
+		writeServiceError(w, r, "failed to revoke delegation", err)
+		return
+	}
+	slog.InfoContext(r.Context(), "consignment delegation revoked", "consignmentId", r.PathValue("id"), "delegationId", r.PathValue("delegationId"), "revokedBy", actor.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireActor returns the requesting user as an Actor, writing an error response unless the
+// request was made by a user with a persisted record.
+func requireActor(w http.ResponseWriter, r *http.Request) (Actor, bool) {
+	authCtx := auth.GetAuthContext(r.Context())
+	switch {
+	case authCtx == nil || (authCtx.User == nil && authCtx.Client == nil):
+		writeJSONError(w, http.StatusUnauthorized, "authentication required")
+		return Actor{}, false
+	case authCtx.User == nil:
+		writeJSONError(w, http.StatusForbidden, "organizations are only available to users")
+		return Actor{}, false
+	case authCtx.User.ID == "":
+		// The auth middleware could not resolve the user record.
+		writeJSONError(w, http.StatusServiceUnavailable, "user profile is unavailable")
+		return Actor{}, false
+	}
+	return Actor{
+		UserID:        authCtx.User.ID,
+		Email:         authCtx.User.Email,
+		PlatformAdmin: authCtx.User.HasRole(auth.RoleAdmin),
+	}, true
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	defer func() { _ = r.Body.Close() }()
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeServiceError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, ErrOrganizationNotFound), errors.Is(err, ErrMemberNotFound),
+		errors.Is(err, ErrInvitationNotFound), errors.Is(err, ErrDelegationNotFound),
+		errors.Is(err, ErrConsignmentNotFound):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden):
+		writeJSONError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrInvalidRequest):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrAlreadyMember), errors.Is(err, ErrLastAdmin):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), message, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, message)
+	}
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+// writeJSONError sets Content-Type: application/json and writes a consistent JSON error body.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSONResponse(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,96 @@
+package organization
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// fakeService implements the operations the tests call; the embedded nil Service panics on the others.
+type fakeService struct {
+	Service
+	actor Actor
+	err   error
+}
+
+func (f *fakeService) CreateOrganization(ctx context.Context, actor Actor, req CreateOrganizationRequest) (*OrganizationDetail, error) {
+	f.actor = actor
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &OrganizationDetail{Organization: Organization{ID: "org-1", Name: req.Name, Kind: req.Kind}}, nil
+}
+
+func (f *fakeService) RemoveMember(ctx context.Context, actor Actor, orgID, userID string) error {
+	f.actor = actor
+	return f.err
+}
+
+func withAuthContext(r *http.Request, authCtx *auth.AuthContext) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), auth.AuthContextKey, authCtx))
+}
+
+func TestHTTPHandler_HandleCreateOrganization(t *testing.T) {
+	user := &auth.AuthContext{User: &auth.UserContext{ID: "user-1", Email: "user@example.com", Roles: []string{auth.RoleAdmin}}}
+
+	tests := []struct {
+		name    string
+		authCtx *auth.AuthContext
+		body    string
+		err     error
+		want    int
+	}{
+		{"created", user, `{"name":"Acme","kind":"trader"}`, nil, http.StatusCreated},
+		{"unauthenticated", nil, `{"name":"Acme","kind":"trader"}`, nil, http.StatusUnauthorized},
+		{"client", &auth.AuthContext{Client: &auth.ClientContext{ClientID: "NPQS_TO_NSW"}}, `{"name":"Acme","kind":"trader"}`, nil, http.StatusForbidden},
+		{"unknown field", user, `{"name":"Acme","kind":"trader","owner":"x"}`, nil, http.StatusBadRequest},
+		{"invalid", user, `{"name":"","kind":"trader"}`, fmt.Errorf("%w: name is required", ErrInvalidRequest), http.StatusBadRequest},
+		{"forbidden", user, `{"name":"Acme","kind":"cha","chaId":"cha-1"}`, ErrForbidden, http.StatusForbidden},
+		{"store failure", user, `{"name":"Acme","kind":"trader"}`, fmt.Errorf("failed to create organization: boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeService{err: tt.err}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/organizations", strings.NewReader(tt.body))
+			if tt.authCtx != nil {
+				req = withAuthContext(req, tt.authCtx)
+			}
+			recorder := httptest.NewRecorder()
+			NewHTTPHandler(svc).HandleCreateOrganization(recorder, req)
+			if recorder.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, recorder.Code, recorder.Body.String())
+			}
+			if tt.want == http.StatusCreated && (svc.actor.UserID != "user-1" || !svc.actor.PlatformAdmin) {
+				t.Fatalf("unexpected actor: %+v", svc.actor)
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_HandleRemoveMember(t *testing.T) {
+	user := &auth.AuthContext{User: &auth.UserContext{ID: "user-1"}}
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusNoContent},
+		{fmt.Errorf("%w: org-1", ErrOrganizationNotFound), http.StatusNotFound},
+		{ErrLastAdmin, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/organizations/org-1/members/user-1", nil)
+		req.SetPathValue("orgId", "org-1")
+		req.SetPathValue("userId", "user-1")
+		recorder := httptest.NewRecorder()
+		NewHTTPHandler(&fakeService{err: tt.err}).HandleRemoveMember(recorder, withAuthContext(req, user))
+		if recorder.Code != tt.want {
+			t.Fatalf("expected status %d for %v, got %d", tt.want, tt.err, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package organization
+
+import "time"
+
+// Kind is the kind of company an organisation is.
+type Kind string
+
+const (
+	// KindTrader is an importer or exporter lodging consignments.
+	KindTrader Kind = "trader"
+	// KindCHA is a customs house agent acting for traders.
+	KindCHA Kind = "cha"
+)
+
+// Role is the role of a member within an organisation.
+type Role string
+
+const (
+	// RoleAdmin manages the organisation's members, invitations and delegations, and acts on consignments.
+	RoleAdmin Role = "admin"
+	// RoleClerk acts on the organisation's consignments.
+	RoleClerk Role = "clerk"
+	// RoleViewer only reads the organisation's consignments.
+	RoleViewer Role = "viewer"
+)
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	return r == RoleAdmin || r == RoleClerk || r == RoleViewer
+}
+
+// Organization is a trader or CHA company with several staff.
+type Organization struct {
+	ID   string `gorm:"type:varchar(100);column:id;primaryKey" json:"id"`
+	Name string `gorm:"type:varchar(255);column:name;not null" json:"name"`
+	Kind Kind   `gorm:"type:varchar(20);column:kind;not null" json:"kind"`
+	// CHAID binds a CHA organisation to a registered CHA; its members act for that CHA.
+	CHAID     *string   `gorm:"type:text;column:cha_id" json:"chaId,omitempty"`
+	CreatedBy string    `gorm:"type:varchar(100);column:created_by;not null" json:"createdBy"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName specifies the database table for this model.
+func (o *Organization) TableName() string {
+	return "organizations"
+}
+
+// Member is the membership of a user in an organisation.
+type Member struct {
+	OrganizationID string    `gorm:"type:varchar(100);column:organization_id;primaryKey" json:"organizationId"`
+	UserID         string    `gorm:"type:varchar(100);column:user_id;primaryKey" json:"userId"`
+	Role           Role      `gorm:"type:varchar(20);column:role;not null" json:"role"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+// TableName specifies the database table for this model.
+func (m *Member) TableName() string {
+	return "organization_members"
+}
+
+// Invitation invites the holder of an email address to join an organisation.
+type Invitation struct {
+	ID             string     `gorm:"type:varchar(100);column:id;primaryKey" json:"id"`
+	OrganizationID string     `gorm:"type:varchar(100);column:organization_id;not null" json:"organizationId"`
+	Email          string     `gorm:"type:varchar(255);column:email;not null" json:"email"`
+	Role           Role       `gorm:"type:varchar(20);column:role;not null" json:"role"`
+	TokenHash      string     `gorm:"type:varchar(64);column:token_hash;not null" json:"-"`
+	InvitedBy      string     `gorm:"type:varchar(100);column:invited_by;not null" json:"invitedBy"`
+	ExpiresAt      time.Time  `gorm:"column:expires_at;not null" json:"expiresAt"`
+	AcceptedAt     *time.Time `gorm:"column:accepted_at" json:"acceptedAt,omitempty"`
+	AcceptedBy     *string    `gorm:"type:varchar(100);column:accepted_by" json:"acceptedBy,omitempty"`
+	RevokedAt      *time.Time `gorm:"column:revoked_at" json:"revokedAt,omitempty"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName specifies the database table for this model.
+func (i *Invitation) TableName() string {
+	return "organization_invitations"
+}
+
+// Pending reports whether the invitation can still be accepted at now.
+func (i *Invitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// Delegation grants the members of a CHA organisation CHA rights over a consignment
+// between StartsAt and ExpiresAt, unless revoked.
+type Delegation struct {
+	ID             string     `gorm:"type:varchar(100);column:id;primaryKey" json:"id"`
+	ConsignmentID  string     `gorm:"type:text;column:consignment_id;not null" json:"consignmentId"`
+	OrganizationID string     `gorm:"type:varchar(100);column:organization_id;not null" json:"organizationId"`
+	GrantedBy      string     `gorm:"type:varchar(100);column:granted_by;not null" json:"grantedBy"`
+	StartsAt       time.Time  `gorm:"column:starts_at;not null" json:"startsAt"`
+	ExpiresAt      time.Time  `gorm:"column:expires_at;not null" json:"expiresAt"`
+	RevokedAt      *time.Time `gorm:"column:revoked_at" json:"revokedAt,omitempty"`
+	RevokedBy      *string    `gorm:"type:varchar(100);column:revoked_by" json:"revokedBy,omitempty"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName specifies the database table for this model.
+func (d *Delegation) TableName() string {
+	return "consignment_delegations"
+}
+
+// Active reports whether the delegation is in effect at now.
+func (d *Delegation) Active(now time.Time) bool {
+	return d.RevokedAt == nil && !now.Before(d.StartsAt) && now.Before(d.ExpiresAt)
+}
+
+// OrganizationDetail is an organisation with its members.
+type OrganizationDetail struct {
+	Organization
+	Members []Member `json:"members"`
+}
+
+// CreateOrganizationRequest is the body of POST /api/v1/organizations.
+type CreateOrganizationRequest struct {
+	Name string `json:"name"`
+	Kind Kind   `json:"kind"`
+	// CHAID may only be set by platform administrators.
+	CHAID *string `json:"chaId,omitempty"`
+}
+
+// CreateInvitationRequest is the body of POST /api/v1/organizations/{orgId}/invitations.
+type CreateInvitationRequest struct {
+	Email string `json:"email"`
+	Role  Role   `json:"role"`
+}
+
+// CreatedInvitation is an invitation with its token, which is only ever returned on creation.
+type CreatedInvitation struct {
+	Invitation
+	Token string `json:"token"`
+}
+
+// AcceptInvitationRequest is the body of POST /api/v1/invitations/accept.
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// UpdateMemberRequest is the body of PUT /api/v1/organizations/{orgId}/members/{userId}.
+type UpdateMemberRequest struct {
+	Role Role `json:"role"`
+}
+
+// CreateDelegationRequest is the body of POST /api/v1/consignments/{id}/delegations.
+type CreateDelegationRequest struct {
+	OrganizationID string `json:"organizationId"`
+	// StartsAt defaults to now.
+	StartsAt  *time.Time `json:"startsAt,omitempty"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+}
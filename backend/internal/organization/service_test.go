@@ -0,0 +1,248 @@
+package organization
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var testNow = time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func setupTestService(t *testing.T) (*service, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	return &service{db: gormDB, now: func() time.Time { return testNow }}, mock
+}
+
+func memberRows(orgID, userID string, role Role) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"organization_id", "user_id", "role", "created_at", "updated_at"}).
+		AddRow(orgID, userID, string(role), testNow, testNow)
+}
+
+func TestService_CreateOrganization_Validation(t *testing.T) {
+	svc, mock := setupTestService(t)
+	chaID := "cha-1"
+
+	tests := []struct {
+		name  string
+		actor Actor
+		req   CreateOrganizationRequest
+		want  error
+	}{
+		{"missing name", Actor{UserID: "user-1"}, CreateOrganizationRequest{Name: " ", Kind: KindTrader}, ErrInvalidRequest},
+		{"unknown kind", Actor{UserID: "user-1"}, CreateOrganizationRequest{Name: "Acme", Kind: "bank"}, ErrInvalidRequest},
+		{"cha binding on trader organisation", Actor{UserID: "user-1", PlatformAdmin: true}, CreateOrganizationRequest{Name: "Acme", Kind: KindTrader, CHAID: &chaID}, ErrInvalidRequest},
+		{"cha binding by non-admin", Actor{UserID: "user-1"}, CreateOrganizationRequest{Name: "Acme", Kind: KindCHA, CHAID: &chaID}, ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.CreateOrganization(context.Background(), tt.actor, tt.req); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expected no queries: %v", err)
+	}
+}
+
+func TestService_CreateOrganization(t *testing.T) {
+	svc, mock := setupTestService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "organizations"`).
+		WithArgs(sqlmock.AnyArg(), "Acme Imports", "trader", nil, "user-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "organization_members"`).
+		WithArgs(sqlmock.AnyArg(), "user-1", "admin", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	detail, err := svc.CreateOrganization(context.Background(), Actor{UserID: "user-1"}, CreateOrganizationRequest{Name: " Acme Imports ", Kind: KindTrader})
+	if err != nil {
+		t.Fatalf("expected organization to be created, got %v", err)
+	}
+	if detail.Name != "Acme Imports" || len(detail.Members) != 1 || detail.Members[0].Role != RoleAdmin {
+		t.Fatalf("unexpected organization: %+v", detail)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestService_GetOrganization_NonMember(t *testing.T) {
+	svc, mock := setupTestService(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "organization_members" WHERE organization_id = \$1 AND user_id = \$2`).
+		WithArgs("org-1", "outsider", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id", "user_id", "role"}))
+
+	if _, err := svc.GetOrganization(context.Background(), Actor{UserID: "outsider"}, "org-1"); !errors.Is(err, ErrOrganizationNotFound) {
+		t.Fatalf("expected ErrOrganizationNotFound, got %v", err)
+	}
+}
+
+func TestService_UpdateMemberRole_LastAdmin(t *testing.T) {
+	svc, mock := setupTestService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "organization_members" WHERE organization_id = \$1 AND user_id = \$2`).
+		WithArgs("org-1", "admin-1", 1).
+		WillReturnRows(memberRows("org-1", "admin-1", RoleAdmin))
+	mock.ExpectQuery(`SELECT \* FROM "organization_members" WHERE organization_id = \$1 AND user_id = \$2 .*FOR UPDATE`).
+		WithArgs("org-1", "admin-1", 1).
+		WillReturnRows(memberRows("org-1", "admin-1", RoleAdmin))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "organization_members" WHERE organization_id = \$1 AND role = \$2 AND user_id <> \$3`).
+		WithArgs("org-1", RoleAdmin, "admin-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectRollback()
+
+	_, err := svc.UpdateMemberRole(context.Background(), Actor{UserID: "admin-1"}, "org-1", "admin-1", RoleClerk)
+	if !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("expected ErrLastAdmin, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestService_CreateInvitation_RequiresAdmin(t *testing.T) {
+	svc, mock := setupTestService(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "organization_members" WHERE organization_id = \$1 AND user_id = \$2`).
+		WithArgs("org-1", "clerk-1", 1).
+		WillReturnRows(memberRows("org-1", "clerk-1", RoleClerk))
+
+	_, err := svc.CreateInvitation(context.Background(), Actor{UserID: "clerk-1"}, "org-1", CreateInvitationRequest{Email: "new@example.com", Role: RoleClerk})
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+}
+
+func TestService_AcceptInvitation(t *testing.T) {
+	token, tokenHash, err := newInvitationToken()
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	invitationRows := func(email string, expiresAt time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "organization_id", "email", "role", "token_hash", "invited_by", "expires_at"}).
+			AddRow("inv-1", "org-1", email, "clerk", tokenHash, "admin-1", expiresAt)
+	}
+
+	t.Run("accepted", func(t *testing.T) {
+		svc, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "organization_invitations" WHERE token_hash = \$1 .*FOR UPDATE`).
+			WithArgs(tokenHash, 1).
+			WillReturnRows(invitationRows("New@Example.com", testNow.Add(time.Hour)))
+		mock.ExpectQuery(`SELECT \* FROM "organization_members" WHERE organization_id = \$1 AND user_id = \$2`).
+			WithArgs("org-1", "user-2", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"organization_id", "user_id", "role"}))
+		mock.ExpectExec(`INSERT INTO "organization_members"`).
+			WithArgs("org-1", "user-2", "clerk", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(`UPDATE "organization_invitations" SET "accepted_at"=\$1,"accepted_by"=\$2 WHERE "id" = \$3`).
+			WithArgs(testNow, "user-2", "inv-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		member, err := svc.AcceptInvitation(context.Background(), Actor{UserID: "user-2", Email: "new@example.com"}, token)
+		if err != nil {
+			t.Fatalf("expected invitation to be accepted, got %v", err)
+		}
+		if member.OrganizationID != "org-1" || member.Role != RoleClerk {
+			t.Fatalf("unexpected member: %+v", member)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	})
+
+	for name, rows := range map[string]*sqlmock.Rows{
+		"other email": invitationRows("someone@example.com", testNow.Add(time.Hour)),
+		"expired":     invitationRows("new@example.com", testNow.Add(-time.Hour)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc, mock := setupTestService(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT \* FROM "organization_invitations"`).WithArgs(tokenHash, 1).WillReturnRows(rows)
+			mock.ExpectRollback()
+
+			_, err := svc.AcceptInvitation(context.Background(), Actor{UserID: "user-2", Email: "new@example.com"}, token)
+			if !errors.Is(err, ErrInvitationNotFound) {
+				t.Fatalf("expected ErrInvitationNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_CreateDelegation(t *testing.T) {
+	expiresAt := testNow.Add(30 * 24 * time.Hour)
+
+	t.Run("invalid period", func(t *testing.T) {
+		svc, _ := setupTestService(t)
+		for _, req := range []CreateDelegationRequest{
+			{ExpiresAt: expiresAt},
+			{OrganizationID: "cha-org", ExpiresAt: testNow.Add(-time.Hour)},
+			{OrganizationID: "cha-org", ExpiresAt: testNow.Add(400 * 24 * time.Hour)},
+		} {
+			if _, err := svc.CreateDelegation(context.Background(), Actor{UserID: "trader-1"}, "consignment-1", req); !errors.Is(err, ErrInvalidRequest) {
+				t.Fatalf("expected ErrInvalidRequest for %+v, got %v", req, err)
+			}
+		}
+	})
+
+	t.Run("not a manager of the consignment", func(t *testing.T) {
+		svc, mock := setupTestService(t)
+		mock.ExpectQuery(`SELECT "trader_id" FROM "consignments" WHERE id = \$1`).
+			WithArgs("consignment-1", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"trader_id"}).AddRow("trader-1"))
+		mock.ExpectQuery(`SELECT count\(\*\) FROM organization_members AS admin JOIN organizations o .* WHERE admin.user_id = \$2 AND admin.role = \$3 AND trader.user_id = \$4`).
+			WithArgs(KindTrader, "clerk-1", RoleAdmin, "trader-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		_, err := svc.CreateDelegation(context.Background(), Actor{UserID: "clerk-1"}, "consignment-1", CreateDelegationRequest{OrganizationID: "cha-org", ExpiresAt: expiresAt})
+		if !errors.Is(err, ErrConsignmentNotFound) {
+			t.Fatalf("expected ErrConsignmentNotFound, got %v", err)
+		}
+	})
+
+	t.Run("created by trader", func(t *testing.T) {
+		svc, mock := setupTestService(t)
+		mock.ExpectQuery(`SELECT "trader_id" FROM "consignments" WHERE id = \$1`).
+			WithArgs("consignment-1", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"trader_id"}).AddRow("trader-1"))
+		mock.ExpectQuery(`SELECT \* FROM "organizations" WHERE id = \$1`).
+			WithArgs("cha-org", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "kind"}).AddRow("cha-org", "Agents Ltd", "cha"))
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO "consignment_delegations"`).
+			WithArgs(sqlmock.AnyArg(), "consignment-1", "cha-org", "trader-1", testNow, expiresAt, nil, nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		delegation, err := svc.CreateDelegation(context.Background(), Actor{UserID: "trader-1"}, "consignment-1", CreateDelegationRequest{OrganizationID: "cha-org", ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatalf("expected delegation to be created, got %v", err)
+		}
+		if !delegation.Active(testNow) || delegation.Active(expiresAt) {
+			t.Fatalf("expected delegation to be active until %v: %+v", expiresAt, delegation)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+	})
+}
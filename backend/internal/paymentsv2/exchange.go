@@ -0,0 +1,161 @@
+package paymentsv2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrRateUnavailable is returned when no usable exchange rate exists for a currency.
+var ErrRateUnavailable = errors.New("exchange rate unavailable")
+
+// ExchangeRate converts one unit of Currency into the base currency.
+type ExchangeRate struct {
+	Currency string          `json:"currency"`
+	Rate     decimal.Decimal `json:"rate"`
+	Source   string          `json:"source"` // e.g. "CBSL"
+	AsOf     time.Time       `json:"as_of"`  // When the source published the rate
+}
+
+// ExchangeRateProvider supplies the rates used to record foreign-currency payments in
+// the base currency.
+type ExchangeRateProvider interface {
+	// BaseCurrency is the currency NSW records equivalent amounts in, e.g. "LKR".
+	BaseCurrency() string
+	// Rate returns the current rate for currency, or ErrRateUnavailable.
+	Rate(ctx context.Context, currency string) (*ExchangeRate, error)
+}
+
+// CurrencyConversion is an amount with its base-currency equivalent and the rate used.
+// It is snapshotted when a payment is initiated, so later rate changes do not alter
+// what the trader was shown or what was recorded.
+type CurrencyConversion struct {
+	Amount        decimal.Decimal `json:"amount"`
+	Currency      string          `json:"currency"`
+	BaseAmount    decimal.Decimal `json:"base_amount"`
+	BaseCurrency  string          `json:"base_currency"`
+	Rate          decimal.Decimal `json:"rate"`
+	RateSource    string          `json:"rate_source"`
+	RateAsOf      time.Time       `json:"rate_as_of"`
+	SnapshottedAt time.Time       `json:"snapshotted_at"`
+}
+
+// convert returns the base-currency equivalent of amount, or nil when amount is already
+// in the base currency or no rate provider is configured.
+func convert(ctx context.Context, rates ExchangeRateProvider, amount decimal.Decimal, currency string) (*CurrencyConversion, error) {
+	if rates == nil || strings.EqualFold(currency, rates.BaseCurrency()) {
+		return nil, nil
+	}
+	rate, err := rates.Rate(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &CurrencyConversion{
+		Amount:        amount,
+		Currency:      currency,
+		BaseAmount:    amount.Mul(rate.Rate).Round(2),
+		BaseCurrency:  rates.BaseCurrency(),
+		Rate:          rate.Rate,
+		RateSource:    rate.Source,
+		RateAsOf:      rate.AsOf,
+		SnapshottedAt: time.Now().UTC(),
+	}, nil
+}
+
+// ExchangeRatesConfig is the root document of exchange_rates.json.
+type ExchangeRatesConfig struct {
+	BaseCurrency string         `json:"base_currency"`
+	MaxAgeHours  int            `json:"max_age_hours,omitempty"` // Optional: rates older than this are refused
+	Rates        []ExchangeRate `json:"rates"`
+}
+
+// FileExchangeRates is a file-backed ExchangeRateProvider for deployments without a live
+// rate feed. Operators publish the file (e.g. from the daily central bank rates) and
+// refresh it at runtime with Reload.
+type FileExchangeRates struct {
+	path string
+	now  func() time.Time
+
+	mu     sync.RWMutex
+	base   string
+	maxAge time.Duration
+	rates  map[string]ExchangeRate
+}
+
+// NewFileExchangeRates loads the exchange rates file at path.
+func NewFileExchangeRates(path string) (*FileExchangeRates, error) {
+	f := &FileExchangeRates{path: path, now: time.Now}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload re-reads the exchange rates file. If the file cannot be read or is invalid,
+// the previously loaded rates are kept and an error is returned.
+func (f *FileExchangeRates) Reload() error {
+	raw, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read exchange rates file %s: %w", f.path, err)
+	}
+
+	var cfg ExchangeRatesConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return fmt.Errorf("failed to parse exchange rates file %s: %w", f.path, err)
+	}
+	if cfg.BaseCurrency == "" {
+		return fmt.Errorf("invalid exchange rates file %s: base_currency is required", f.path)
+	}
+
+	rates := make(map[string]ExchangeRate, len(cfg.Rates))
+	for _, r := range cfg.Rates {
+		code := strings.ToUpper(r.Currency)
+		if code == "" || !r.Rate.IsPositive() {
+			return fmt.Errorf("invalid exchange rates file %s: every rate needs a currency and a positive rate", f.path)
+		}
+		if _, dup := rates[code]; dup {
+			return fmt.Errorf("invalid exchange rates file %s: duplicate rate for %s", f.path, code)
+		}
+		r.Currency = code
+		rates[code] = r
+	}
+
+	f.mu.Lock()
+	f.base = strings.ToUpper(cfg.BaseCurrency)
+	f.maxAge = time.Duration(cfg.MaxAgeHours) * time.Hour
+	f.rates = rates
+	f.mu.Unlock()
+
+	slog.Info("exchange rates loaded", "path", f.path, "base_currency", cfg.BaseCurrency, "rates", len(rates))
+	return nil
+}
+
+// BaseCurrency returns the base currency of the loaded file.
+func (f *FileExchangeRates) BaseCurrency() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.base
+}
+
+// Rate returns the loaded rate for currency. Rates older than max_age_hours are refused.
+func (f *FileExchangeRates) Rate(_ context.Context, currency string) (*ExchangeRate, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	rate, ok := f.rates[strings.ToUpper(currency)]
+	if !ok {
+		return nil, fmt.Errorf("%w: no rate for %s", ErrRateUnavailable, currency)
+	}
+	if f.maxAge > 0 && f.now().Sub(rate.AsOf) > f.maxAge {
+		return nil, fmt.Errorf("%w: rate for %s from %s is older than %s", ErrRateUnavailable, currency, rate.AsOf.Format(time.RFC3339), f.maxAge)
+	}
+	return &rate, nil
+}
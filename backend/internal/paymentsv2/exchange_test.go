@@ -0,0 +1,123 @@
+package paymentsv2
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// staticRates serves fixed rates into LKR.
+type staticRates map[string]string
+
+func (r staticRates) BaseCurrency() string { return "LKR" }
+
+func (r staticRates) Rate(ctx context.Context, currency string) (*ExchangeRate, error) {
+	rate, ok := r[currency]
+	if !ok {
+		return nil, ErrRateUnavailable
+	}
+	return &ExchangeRate{Currency: currency, Rate: decimal.RequireFromString(rate), Source: "TEST", AsOf: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)}, nil
+}
+
+func writeRatesFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "exchange_rates.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write rates file: %v", err)
+	}
+	return path
+}
+
+func TestFileExchangeRates(t *testing.T) {
+	path := writeRatesFile(t, `{"base_currency": "LKR", "max_age_hours": 48, "rates": [
+		{"currency": "usd", "rate": "299.50", "source": "CBSL", "as_of": "2026-10-16T00:00:00Z"}]}`)
+	rates, err := NewFileExchangeRates(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	rates.now = func() time.Time { return time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC) }
+
+	rate, err := rates.Rate(context.Background(), "USD")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if rate.Rate.String() != "299.5" || rate.Source != "CBSL" {
+		t.Fatalf("unexpected rate: %+v", rate)
+	}
+	if _, err := rates.Rate(context.Background(), "EUR"); !errors.Is(err, ErrRateUnavailable) {
+		t.Fatalf("expected ErrRateUnavailable for unknown currency, got %v", err)
+	}
+
+	rates.now = func() time.Time { return time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC) }
+	if _, err := rates.Rate(context.Background(), "USD"); !errors.Is(err, ErrRateUnavailable) {
+		t.Fatalf("expected ErrRateUnavailable for stale rate, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"rates": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := rates.Reload(); err == nil {
+		t.Fatal("expected reload of a file without base_currency to fail")
+	}
+	if rates.BaseCurrency() != "LKR" {
+		t.Fatalf("expected previous rates to be kept, got base %q", rates.BaseCurrency())
+	}
+}
+
+func TestCreateCheckoutSession_ForeignCurrency(t *testing.T) {
+	newService := func(repo *mockRepository) *paymentService {
+		registry := &mockRegistry{order: []string{"mock"}, providers: map[string]PaymentProvider{"mock": successfulProvider()}}
+		return NewPaymentService(repo, registry, staticRates{"USD": "299.50"}, nil, nil).(*paymentService)
+	}
+	req := CreateCheckoutRequest{
+		Amount:    decimal.RequireFromString("12.35"),
+		Currency:  "USD",
+		ExpiresAt: time.Now().Add(time.Hour),
+		Metadata:  map[string]string{"task_id": "TASK-123"},
+	}
+
+	t.Run("snapshots rate on transaction", func(t *testing.T) {
+		repo := newMockRepository()
+		resp, err := newService(repo).CreateCheckoutSession(context.Background(), req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Conversion == nil || resp.Conversion.BaseAmount.String() != "3698.83" {
+			t.Fatalf("expected conversion of 12.35 USD to 3698.83 LKR, got %+v", resp.Conversion)
+		}
+		tx := repo.txs[resp.ReferenceNumber]
+		if tx.BaseAmount == nil || !tx.BaseAmount.Equal(resp.Conversion.BaseAmount) || tx.BaseCurrency != "LKR" || tx.RateSource != "TEST" || tx.RateAsOf == nil {
+			t.Fatalf("expected rate snapshot on transaction, got %+v", tx)
+		}
+	})
+
+	t.Run("base currency is not converted", func(t *testing.T) {
+		repo := newMockRepository()
+		lkr := req
+		lkr.Currency = "LKR"
+		resp, err := newService(repo).CreateCheckoutSession(context.Background(), lkr)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Conversion != nil || repo.txs[resp.ReferenceNumber].BaseAmount != nil {
+			t.Fatal("expected no conversion for a base-currency payment")
+		}
+	})
+
+	t.Run("missing rate blocks checkout", func(t *testing.T) {
+		repo := newMockRepository()
+		eur := req
+		eur.Currency = "EUR"
+		if _, err := newService(repo).CreateCheckoutSession(context.Background(), eur); !errors.Is(err, ErrRateUnavailable) {
+			t.Fatalf("expected ErrRateUnavailable, got %v", err)
+		}
+		if len(repo.txs) != 0 {
+			t.Fatal("expected no transaction without a rate")
+		}
+	})
+}
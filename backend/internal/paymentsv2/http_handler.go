@@ -1,20 +1,59 @@
 package paymentsv2
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"time"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+const (
+	// maxGatewayBodyBytes limits the size of gateway callbacks.
+	maxGatewayBodyBytes = 1 << 20 // 1MB
+	// maxSettlementFileBytes limits the size of an uploaded settlement file.
+	maxSettlementFileBytes = 20 << 20 // 20MB
 )
 
 // HTTPHandler handles public HTTP requests for the Payment Service.
 type HTTPHandler struct {
 	service PaymentService
+	auth    *WebhookAuthenticator
+	access  TaskAccess
+}
+
+// TaskAccess decides whether the principal in ctx may read a task, and with it the
+// payments made for the task. The Task Engine's authorization policy implements it.
+type TaskAccess interface {
+	MayReadTask(ctx context.Context, taskID string) (bool, error)
 }
 
-// NewHTTPHandler creates a new handler.
-func NewHTTPHandler(service PaymentService) *HTTPHandler {
-	return &HTTPHandler{service: service}
+// NewHTTPHandler creates a new handler. Gateway endpoints (webhook and validate) are
+// authenticated with auth. Users other than refund officers only read the transactions of
+// tasks access lets them read; when access is nil, they read none.
+func NewHTTPHandler(service PaymentService, auth *WebhookAuthenticator, access TaskAccess) *HTTPHandler {
+	return &HTTPHandler{service: service, auth: auth, access: access}
+}
+
+// HandleListMethods handles GET /api/v1/payments/methods
+// Returns the active payment methods for portals to render a method picker.
+func (h *HTTPHandler) HandleListMethods(w http.ResponseWriter, r *http.Request) {
+	methods, err := h.service.ListAvailableMethods(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list payment methods", "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(methods); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
 }
 
 // HandleValidateReference handles POST /api/v1/payments/:providerId/validate
@@ -27,8 +66,13 @@ func (h *HTTPHandler) HandleValidateReference(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	body, verified, ok := h.authenticateGateway(w, r, providerID, WebhookEndpointValidate)
+	if !ok {
+		return
+	}
+
 	var req ValidateReferenceRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		http.Error(w, "invalid request payload", http.StatusBadRequest)
 		return
 	}
@@ -36,6 +80,7 @@ func (h *HTTPHandler) HandleValidateReference(w http.ResponseWriter, r *http.Req
 	resp, err := h.service.ValidateReference(r.Context(), providerID, req)
 	if err != nil {
 		slog.ErrorContext(r.Context(), "failed to validate reference", "provider", providerID, "error", err)
+		h.auth.Release(r.Context(), providerID, verified)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -56,16 +101,25 @@ func (h *HTTPHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB limit
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "request body too large or unreadable", http.StatusBadRequest)
+	body, verified, ok := h.authenticateGateway(w, r, providerID, WebhookEndpointWebhook)
+	if !ok {
 		return
 	}
 
-	err = h.service.ProcessWebhook(r.Context(), providerID, body, r.Header)
+	err := h.service.ProcessWebhook(r.Context(), providerID, body, r.Header)
 	if err != nil {
 		slog.ErrorContext(r.Context(), "webhook processing failed", "provider", providerID, "error", err)
+		if errors.Is(err, ErrProviderNotFound) || errors.Is(err, ErrProviderInactive) {
+			http.Error(w, "unknown payment provider", http.StatusNotFound)
+			return
+		}
+		// A retry cannot find the reference either, so the nonce stays consumed.
+		if errors.Is(err, ErrTransactionNotFound) {
+			http.Error(w, "unknown payment reference", http.StatusNotFound)
+			return
+		}
+		// The gateway retries after a 5xx; its retry must not be taken for a replay.
+		h.auth.Release(r.Context(), providerID, verified)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -73,3 +127,320 @@ func (h *HTTPHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte(`{"status": "accepted"}`))
 }
+
+// authenticateGateway reads the raw body and verifies the provider's signature over it.
+// On failure it writes the error response and returns false.
+func (h *HTTPHandler) authenticateGateway(w http.ResponseWriter, r *http.Request, providerID, endpoint string) ([]byte, *VerifiedWebhook, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxGatewayBodyBytes)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "request body too large or unreadable", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	verified, err := h.auth.Authenticate(r.Context(), WebhookRequest{
+		ProviderID: providerID,
+		Endpoint:   endpoint,
+		RemoteAddr: r.RemoteAddr,
+		Body:       body,
+		Headers:    r.Header,
+	})
+	switch {
+	case err == nil:
+		return body, verified, true
+	case errors.Is(err, ErrWebhookUnauthorized):
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	case errors.Is(err, ErrProviderNotFound) || errors.Is(err, ErrProviderInactive):
+		http.Error(w, "unknown payment provider", http.StatusNotFound)
+	default:
+		slog.ErrorContext(r.Context(), "failed to authenticate gateway request", "provider", providerID, "endpoint", endpoint, "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+	return nil, nil, false
+}
+
+// HandleImportSettlement handles POST /api/v1/payments/:providerId/settlements?date=YYYY-MM-DD
+// The body is the provider's daily settlement file as CSV. Settlement files are delivered
+// by back-office integrations, so only M2M clients may call it.
+func (h *HTTPHandler) HandleImportSettlement(w http.ResponseWriter, r *http.Request) {
+	if !isClientPrincipal(r) {
+		http.Error(w, "settlement import requires a client principal", http.StatusForbidden)
+		return
+	}
+
+	providerID := r.PathValue("providerId")
+	if providerID == "" {
+		http.Error(w, "provider ID is required in URL", http.StatusBadRequest)
+		return
+	}
+	settlementDate, err := time.Parse(time.DateOnly, r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "date query parameter is required (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSettlementFileBytes)
+	report, err := h.service.ImportSettlement(r.Context(), providerID, settlementDate, r.Body)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "settlement import failed", "provider", providerID, "error", err)
+		if errors.Is(err, ErrProviderNotFound) {
+			http.Error(w, "unknown payment provider", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to import settlement file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode settlement report", "error", err)
+	}
+}
+
+// HandleGetSettlementReport handles GET /api/v1/payments/settlements/:reportId
+func (h *HTTPHandler) HandleGetSettlementReport(w http.ResponseWriter, r *http.Request) {
+	if !isClientPrincipal(r) {
+		http.Error(w, "settlement reports require a client principal", http.StatusForbidden)
+		return
+	}
+
+	report, err := h.service.GetSettlementReport(r.Context(), r.PathValue("reportId"))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to get settlement report", "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if report == nil {
+		http.Error(w, "settlement report not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// HandleRefund handles POST /api/v1/payments/transactions/:referenceNumber/refunds
+// Requests a refund of all of the remaining balance, or of the given amount. Only finance
+// officers and administrators may request refunds; the authenticated user is recorded as
+// the requester, and the refund waits for approval by another officer.
+func (h *HTTPHandler) HandleRefund(w http.ResponseWriter, r *http.Request) {
+	requester, ok := refundOfficer(w, r)
+	if !ok {
+		return
+	}
+
+	var req RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request payload", http.StatusBadRequest)
+		return
+	}
+	req.ReferenceNumber = r.PathValue("referenceNumber")
+	req.RequestedBy = requester
+
+	refund, err := h.service.RequestRefund(r.Context(), req)
+	if err != nil {
+		writeRefundError(w, r, req.ReferenceNumber, refund, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(refund); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode refund", "error", err)
+	}
+}
+
+// HandleApproveRefund handles POST /api/v1/payments/transactions/:referenceNumber/refunds/:refundId/approve
+// The authenticated finance officer or administrator is recorded as the approver and must
+// not be the requester. The refund is sent to the gateway before the response is written.
+func (h *HTTPHandler) HandleApproveRefund(w http.ResponseWriter, r *http.Request) {
+	h.decideRefund(w, r, h.service.ApproveRefund)
+}
+
+// HandleRejectRefund handles POST /api/v1/payments/transactions/:referenceNumber/refunds/:refundId/reject
+func (h *HTTPHandler) HandleRejectRefund(w http.ResponseWriter, r *http.Request) {
+	h.decideRefund(w, r, h.service.RejectRefund)
+}
+
+func (h *HTTPHandler) decideRefund(w http.ResponseWriter, r *http.Request, decide func(context.Context, RefundDecision) (*PaymentRefund, error)) {
+	officer, ok := refundOfficer(w, r)
+	if !ok {
+		return
+	}
+
+	d := RefundDecision{
+		ReferenceNumber: r.PathValue("referenceNumber"),
+		RefundID:        r.PathValue("refundId"),
+		DecidedBy:       officer,
+	}
+	refund, err := decide(r.Context(), d)
+	if err != nil {
+		writeRefundError(w, r, d.ReferenceNumber, refund, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(refund); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode refund", "error", err)
+	}
+}
+
+// writeRefundError maps a refund service error to its HTTP response.
+func writeRefundError(w http.ResponseWriter, r *http.Request, referenceNumber string, refund *PaymentRefund, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidRefund):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrTransactionNotFound):
+		http.Error(w, "payment transaction not found", http.StatusNotFound)
+	case errors.Is(err, ErrRefundNotFound):
+		http.Error(w, "refund not found", http.StatusNotFound)
+	case errors.Is(err, ErrRefundNotAllowed) || errors.Is(err, ErrRefundExceedsBalance) || errors.Is(err, ErrRefundNotPending):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrRefundRejected):
+		// The rejected attempt is part of the ledger; return it so the caller sees its ID.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		if err := json.NewEncoder(w).Encode(refund); err != nil {
+			slog.ErrorContext(r.Context(), "failed to encode refund", "error", err)
+		}
+	default:
+		slog.ErrorContext(r.Context(), "refund failed", "reference", referenceNumber, "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleListRefunds handles GET /api/v1/payments/transactions/:referenceNumber/refunds
+func (h *HTTPHandler) HandleListRefunds(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeTransactionRead(w, r, r.PathValue("referenceNumber")) {
+		return
+	}
+
+	refunds, err := h.service.ListRefunds(r.Context(), r.PathValue("referenceNumber"))
+	if err != nil {
+		if errors.Is(err, ErrTransactionNotFound) {
+			http.Error(w, "payment transaction not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to list refunds", "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if refunds == nil {
+		refunds = []PaymentRefund{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(refunds); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// HandleDownloadReceipt handles GET /api/v1/payments/transactions/:referenceNumber/receipt
+// It streams the receipt PDF, or the structured receipt with ?format=json.
+func (h *HTTPHandler) HandleDownloadReceipt(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeTransactionRead(w, r, r.PathValue("referenceNumber")) {
+		return
+	}
+
+	format := ReceiptFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		format = ReceiptFormatPDF
+	}
+	contentType := "application/pdf"
+	switch format {
+	case ReceiptFormatPDF:
+	case ReceiptFormatJSON:
+		contentType = "application/json"
+	default:
+		http.Error(w, "format must be pdf or json", http.StatusBadRequest)
+		return
+	}
+
+	receipt, body, err := h.service.OpenReceipt(r.Context(), r.PathValue("referenceNumber"), format)
+	if err != nil {
+		if errors.Is(err, ErrReceiptNotFound) || errors.Is(err, ErrReceiptsDisabled) {
+			http.Error(w, "payment receipt not found", http.StatusNotFound)
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to open receipt", "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = body.Close() }()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", receipt.ReceiptNumber+"."+string(format)))
+	if _, err := io.Copy(w, body); err != nil {
+		slog.ErrorContext(r.Context(), "failed to stream receipt", "receipt_number", receipt.ReceiptNumber, "error", err)
+	}
+}
+
+// authorizeTransactionRead checks that the principal may read the transaction of
+// referenceNumber: refund officers and M2M clients read every transaction, other users
+// only those of tasks they may read. Otherwise it writes the error response and returns false.
+func (h *HTTPHandler) authorizeTransactionRead(w http.ResponseWriter, r *http.Request, referenceNumber string) bool {
+	authCtx := auth.GetAuthContext(r.Context())
+	switch {
+	case authCtx == nil || (authCtx.User == nil && authCtx.Client == nil):
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	case authCtx.Client != nil || isRefundOfficer(authCtx.User):
+		return true
+	case h.access == nil:
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+
+	tx, err := h.service.GetTransaction(r.Context(), referenceNumber)
+	if err != nil {
+		if errors.Is(err, ErrTransactionNotFound) {
+			http.Error(w, "payment transaction not found", http.StatusNotFound)
+			return false
+		}
+		slog.ErrorContext(r.Context(), "failed to read payment transaction", "reference", referenceNumber, "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return false
+	}
+	allowed, err := h.access.MayReadTask(r.Context(), tx.TaskID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to authorize payment transaction read", "reference", referenceNumber, "task_id", tx.TaskID, "error", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if !allowed {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// isRefundOfficer reports whether user may request and decide refunds.
+func isRefundOfficer(user *auth.UserContext) bool {
+	return user != nil && (user.HasRole(auth.RoleFinance) || user.HasRole(auth.RoleAdmin))
+}
+
+// refundOfficer returns the ID of the authenticated user if they may request and decide
+// refunds: finance officers and administrators. Otherwise it writes the error response
+// and returns false. M2M clients are never refund officers, so that every refund is
+// requested and approved by two named people.
+func refundOfficer(w http.ResponseWriter, r *http.Request) (string, bool) {
+	authCtx := auth.GetAuthContext(r.Context())
+	switch {
+	case authCtx == nil || (authCtx.User == nil && authCtx.Client == nil):
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	case !isRefundOfficer(authCtx.User):
+		http.Error(w, "refunds require a finance officer or administrator", http.StatusForbidden)
+	default:
+		return authCtx.User.ID, true
+	}
+	return "", false
+}
+
+// isClientPrincipal reports whether the request was authenticated as an M2M client.
+func isClientPrincipal(r *http.Request) bool {
+	authCtx := auth.GetAuthContext(r.Context())
+	return authCtx != nil && authCtx.Client != nil
+}
@@ -0,0 +1,144 @@
+package paymentsv2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func newTestHandler(t *testing.T) (*HTTPHandler, *mockRepository, *HMACVerifier) {
+	t.Helper()
+	repo := newMockRepository()
+	repo.txs["NSW-PR-2026-AB234"] = &PaymentTransaction{
+		ReferenceNumber: "NSW-PR-2026-AB234",
+		TaskID:          "TASK-123",
+		ProviderID:      "mock",
+		Status:          PaymentStatusPending,
+		Amount:          decimal.NewFromFloat(100.0),
+		Currency:        "LKR",
+		ExpiryDate:      time.Now().Add(time.Hour),
+	}
+	provider := &stubProvider{
+		parseFn: func(ctx context.Context, body []byte, headers map[string][]string) (*WebhookPayload, error) {
+			return &WebhookPayload{ReferenceNumber: "NSW-PR-2026-AB234", Status: PaymentStatusSuccess, Amount: decimal.NewFromInt(100), Currency: "LKR"}, nil
+		},
+		validateResp: &ValidateReferenceResponse{IsPayable: true},
+	}
+	service := newTestService(repo, provider)
+
+	v := newTestVerifier()
+	auth := NewWebhookAuthenticator(staticVerifierSource{"mock": v}, newMemoryNonceStore(), &memoryAuditRepository{})
+	return NewHTTPHandler(service, auth, nil), repo, v
+}
+
+func serveGateway(h *HTTPHandler, handler http.HandlerFunc, providerID string, body []byte, headers map[string][]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/payments/"+providerID+"/webhook", bytes.NewReader(body))
+	req.SetPathValue("providerId", providerID)
+	for k, vs := range headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+	return rr
+}
+
+func TestHTTPHandler_HandleWebhook(t *testing.T) {
+	body := []byte(`{"reference_number":"NSW-PR-2026-AB234","status":"SUCCESS"}`)
+
+	t.Run("signed webhook is processed", func(t *testing.T) {
+		h, repo, v := newTestHandler(t)
+		rr := serveGateway(h, h.HandleWebhook, "mock", body, signedHeaders(v, body, time.Now()))
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if repo.txs["NSW-PR-2026-AB234"].Status != PaymentStatusSuccess {
+			t.Fatalf("expected transaction to be SUCCESS, got %s", repo.txs["NSW-PR-2026-AB234"].Status)
+		}
+	})
+
+	t.Run("unsigned webhook is rejected", func(t *testing.T) {
+		h, repo, _ := newTestHandler(t)
+		rr := serveGateway(h, h.HandleWebhook, "mock", body, nil)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", rr.Code)
+		}
+		if repo.txs["NSW-PR-2026-AB234"].Status != PaymentStatusPending {
+			t.Fatalf("expected transaction to stay PENDING, got %s", repo.txs["NSW-PR-2026-AB234"].Status)
+		}
+	})
+
+	t.Run("replayed webhook is rejected", func(t *testing.T) {
+		h, _, v := newTestHandler(t)
+		headers := signedHeaders(v, body, time.Now())
+		serveGateway(h, h.HandleWebhook, "mock", body, headers)
+
+		if rr := serveGateway(h, h.HandleWebhook, "mock", body, headers); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401 for replay, got %d", rr.Code)
+		}
+	})
+
+	t.Run("retry after processing failure is accepted", func(t *testing.T) {
+		h, repo, v := newTestHandler(t)
+		headers := signedHeaders(v, body, time.Now())
+		repo.updateErr = errors.New("database unavailable")
+		if rr := serveGateway(h, h.HandleWebhook, "mock", body, headers); rr.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status 500, got %d", rr.Code)
+		}
+
+		repo.updateErr = nil
+		if rr := serveGateway(h, h.HandleWebhook, "mock", body, headers); rr.Code != http.StatusOK {
+			t.Fatalf("expected retry to be processed, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if repo.txs["NSW-PR-2026-AB234"].Status != PaymentStatusSuccess {
+			t.Fatalf("expected transaction to be SUCCESS, got %s", repo.txs["NSW-PR-2026-AB234"].Status)
+		}
+	})
+
+	t.Run("unknown reference keeps the nonce consumed", func(t *testing.T) {
+		h, repo, v := newTestHandler(t)
+		delete(repo.txs, "NSW-PR-2026-AB234")
+		headers := signedHeaders(v, body, time.Now())
+		if rr := serveGateway(h, h.HandleWebhook, "mock", body, headers); rr.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", rr.Code)
+		}
+
+		if rr := serveGateway(h, h.HandleWebhook, "mock", body, headers); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401 for the replayed request, got %d", rr.Code)
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		h, _, v := newTestHandler(t)
+		if rr := serveGateway(h, h.HandleWebhook, "unknown", body, signedHeaders(v, body, time.Now())); rr.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", rr.Code)
+		}
+	})
+}
+
+func TestHTTPHandler_HandleValidateReference(t *testing.T) {
+	body := []byte(`{"paymentReference":"NSW-PR-2026-AB234","serviceType":"NSW_IMPORT_PERMIT_CD"}`)
+
+	t.Run("signed request", func(t *testing.T) {
+		h, _, v := newTestHandler(t)
+		if rr := serveGateway(h, h.HandleValidateReference, "mock", body, signedHeaders(v, body, time.Now())); rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("unsigned request", func(t *testing.T) {
+		h, _, _ := newTestHandler(t)
+		if rr := serveGateway(h, h.HandleValidateReference, "mock", body, nil); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status 401, got %d", rr.Code)
+		}
+	})
+}
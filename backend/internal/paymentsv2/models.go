@@ -12,6 +12,15 @@ const (
 	PaymentStatusPending PaymentStatus = "PENDING"
 	PaymentStatusSuccess PaymentStatus = "SUCCESS"
 	PaymentStatusFailed  PaymentStatus = "FAILED"
+	PaymentStatusExpired PaymentStatus = "EXPIRED"
+
+	// PaymentStatusAmountMismatch holds a confirmation whose amount or currency differs
+	// from the transaction. The task is not advanced; a settlement line for the expected
+	// amount corrects it to SUCCESS.
+	PaymentStatusAmountMismatch PaymentStatus = "AMOUNT_MISMATCH"
+
+	PaymentStatusPartiallyRefunded PaymentStatus = "PARTIALLY_REFUNDED"
+	PaymentStatusRefunded          PaymentStatus = "REFUNDED"
 )
 
 // PaymentTransaction represents the internal state of a payment
@@ -27,8 +36,21 @@ type PaymentTransaction struct {
 	PaymentMethod   string            `json:"payment_method"` // CC, BANK_TRANSFER (populated on webhook)
 	ExpiryDate      time.Time         `json:"expiry_date"`
 	GatewayMetadata map[string]string `json:"gateway_metadata" gorm:"serializer:json"`
-	CreatedAt       time.Time         `json:"created_at"`
-	UpdatedAt       time.Time         `json:"updated_at"`
+
+	// EventPending is set with a final status and cleared once the owning task has
+	// received the outcome. Reconciliation delivers pending events again.
+	EventPending bool `json:"event_pending"`
+
+	// Base-currency equivalent of a foreign-currency payment, snapshotted at checkout.
+	// All are empty when Currency is the base currency.
+	BaseAmount   *decimal.Decimal `json:"base_amount,omitempty"`
+	BaseCurrency string           `json:"base_currency,omitempty"`
+	ExchangeRate *decimal.Decimal `json:"exchange_rate,omitempty"`
+	RateSource   string           `json:"rate_source,omitempty"`
+	RateAsOf     *time.Time       `json:"rate_as_of,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // --------------------------------------------------------
@@ -37,7 +59,8 @@ type PaymentTransaction struct {
 
 // CreateCheckoutRequest is the payload sent to initialize a session.
 type CreateCheckoutRequest struct {
-	ProviderID         string            `json:"provider_id"`
+	ProviderID         string            `json:"provider_id"`                // Optional: falls back to the registry default
+	ReferenceNumber    string            `json:"reference_number,omitempty"` // Assigned by the Payment Service before dispatch to a provider
 	Amount             decimal.Decimal   `json:"amount"`
 	Currency           string            `json:"currency"`
 	SuccessRedirectURL string            `json:"success_redirect_url,omitempty"` // Optional: User redirect on success
@@ -49,9 +72,13 @@ type CreateCheckoutRequest struct {
 // CreateCheckoutResponse is the expected reply from LankaPay.
 type CreateCheckoutResponse struct {
 	ReferenceNumber string `json:"reference_number"` // The generated NSW reference
+	ProviderID      string `json:"provider_id"`      // The provider that owns the session
 	SessionID       string `json:"session_id"`
 	CheckoutURL     string `json:"checkout_url"` // The hosted URL to redirect the user to
 	ExpiresIn       int    `json:"expires_in_seconds"`
+
+	// Conversion is the exchange-rate snapshot recorded for a foreign-currency payment.
+	Conversion *CurrencyConversion `json:"conversion,omitempty"`
 }
 
 // --------------------------------------------------------
@@ -92,6 +119,14 @@ type WebhookPayload struct {
 	Metadata             map[string]string `json:"metadata"`
 }
 
+// Internal payment event types emitted to the Task Engine.
+const (
+	PaymentEventConfirmed = "PAYMENT_CONFIRMED"
+	PaymentEventFailed    = "PAYMENT_FAILED"
+	PaymentEventExpired   = "PAYMENT_EXPIRED"
+	PaymentEventRefunded  = "PAYMENT_REFUNDED"
+)
+
 type EventData struct {
 	TaskID               string          `json:"task_id"`
 	ReferenceNumber      string          `json:"reference_number"`
@@ -100,6 +135,11 @@ type EventData struct {
 	AmountPaid           decimal.Decimal `json:"amount_paid"`
 	Currency             string          `json:"currency"`
 	ConfirmedAt          string          `json:"confirmed_at"`
+	Source               string          `json:"source"` // webhook, reconciliation, settlement or refund
+
+	// Set on PAYMENT_REFUNDED only.
+	RefundID       string           `json:"refund_id,omitempty"`
+	RefundedAmount *decimal.Decimal `json:"refunded_amount,omitempty"`
 }
 
 // InternalPaymentEvent represents the internal event the Payment Service fires for the Task Engine.
@@ -107,3 +147,280 @@ type InternalPaymentEvent struct {
 	EventType string    `json:"event_type"`
 	Data      EventData `json:"data"`
 }
+
+// --------------------------------------------------------
+// Webhook Authentication
+// --------------------------------------------------------
+
+// WebhookNonce records a verified gateway request so that it cannot be replayed.
+type WebhookNonce struct {
+	ProviderID string    `gorm:"primaryKey"`
+	Nonce      string    `gorm:"primaryKey"`
+	ExpiresAt  time.Time `gorm:"index"`
+	CreatedAt  time.Time
+}
+
+// TableName returns the table name for WebhookNonce.
+func (WebhookNonce) TableName() string {
+	return "payment_webhook_nonces"
+}
+
+// WebhookAuditLog records a gateway request that failed authentication.
+type WebhookAuditLog struct {
+	ID         string              `json:"id" gorm:"type:text;not null;primaryKey"`
+	ProviderID string              `json:"provider_id"`
+	Endpoint   string              `json:"endpoint"` // webhook or validate
+	Reason     string              `json:"reason"`
+	RemoteAddr string              `json:"remote_addr"`
+	Headers    map[string][]string `json:"headers" gorm:"serializer:json"`
+	BodySHA256 string              `json:"body_sha256" gorm:"column:body_sha256"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// TableName returns the table name for WebhookAuditLog.
+func (WebhookAuditLog) TableName() string {
+	return "payment_webhook_audit_logs"
+}
+
+// --------------------------------------------------------
+// Reconciliation
+// --------------------------------------------------------
+
+// Sources of a transaction status change, recorded in EventData.Source and GatewayMetadata.
+const (
+	StatusSourceWebhook        = "webhook"
+	StatusSourceReconciliation = "reconciliation"
+	StatusSourceSettlement     = "settlement"
+	StatusSourceRefund         = "refund"
+)
+
+// ReconciliationResult summarises one pass over PENDING transactions.
+type ReconciliationResult struct {
+	Checked int `json:"checked"`
+	Updated int `json:"updated"` // moved to a final status reported by the provider
+	Expired int `json:"expired"`
+	Failed  int `json:"failed"` // provider queries or updates that returned an error
+
+	Redelivered int `json:"redelivered"` // final outcomes delivered to their task again
+}
+
+// DiscrepancyType classifies a mismatch between a settlement file and NSW records.
+type DiscrepancyType string
+
+const (
+	// DiscrepancyUnknownReference: the gateway settled a reference NSW does not know for this provider.
+	DiscrepancyUnknownReference DiscrepancyType = "UNKNOWN_REFERENCE"
+	// DiscrepancyStatusCorrected: the payment was settled but NSW had not recorded it as SUCCESS.
+	// The transaction is corrected and the owning task notified.
+	DiscrepancyStatusCorrected DiscrepancyType = "STATUS_CORRECTED"
+	// DiscrepancyAmountMismatch: the settled amount or currency differs from the transaction.
+	DiscrepancyAmountMismatch DiscrepancyType = "AMOUNT_MISMATCH"
+	// DiscrepancyDuplicateLine: the same reference appears more than once in the file.
+	DiscrepancyDuplicateLine DiscrepancyType = "DUPLICATE_LINE"
+	// DiscrepancyNotSettled: NSW recorded SUCCESS on the settlement date but the gateway did not settle it.
+	DiscrepancyNotSettled DiscrepancyType = "NOT_SETTLED"
+	// DiscrepancyInvalidLine: the line could not be parsed.
+	DiscrepancyInvalidLine DiscrepancyType = "INVALID_LINE"
+)
+
+// Discrepancy is a single finding of a settlement import.
+type Discrepancy struct {
+	Type            DiscrepancyType  `json:"type"`
+	Line            int              `json:"line,omitempty"` // 1-based line in the file, header included
+	ReferenceNumber string           `json:"reference_number,omitempty"`
+	NSWStatus       PaymentStatus    `json:"nsw_status,omitempty"`
+	NSWAmount       *decimal.Decimal `json:"nsw_amount,omitempty"`
+	SettledAmount   *decimal.Decimal `json:"settled_amount,omitempty"`
+	Currency        string           `json:"currency,omitempty"`
+	Detail          string           `json:"detail,omitempty"`
+}
+
+// SettlementReport is the persisted outcome of importing a daily settlement file.
+type SettlementReport struct {
+	ID             string        `json:"id" gorm:"type:text;not null;primaryKey"`
+	ProviderID     string        `json:"provider_id"`
+	SettlementDate time.Time     `json:"settlement_date" gorm:"type:date"`
+	TotalLines     int           `json:"total_lines"`
+	Matched        int           `json:"matched"`
+	Corrected      int           `json:"corrected"`
+	Discrepancies  []Discrepancy `json:"discrepancies" gorm:"serializer:json"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+// TableName returns the table name for SettlementReport.
+func (SettlementReport) TableName() string {
+	return "payment_settlement_reports"
+}
+
+// --------------------------------------------------------
+// Refunds
+// --------------------------------------------------------
+
+// RefundReasonCode classifies why a payment is returned.
+type RefundReasonCode string
+
+const (
+	RefundReasonOGARejected          RefundReasonCode = "OGA_REJECTED"
+	RefundReasonConsignmentCancelled RefundReasonCode = "CONSIGNMENT_CANCELLED"
+	RefundReasonDuplicatePayment     RefundReasonCode = "DUPLICATE_PAYMENT"
+	RefundReasonOvercharge           RefundReasonCode = "OVERCHARGE"
+	RefundReasonOther                RefundReasonCode = "OTHER" // Requires a reason note
+)
+
+// RefundStatus is the state of a refund in the ledger.
+type RefundStatus string
+
+const (
+	// RefundStatusPendingApproval: requested by one refund officer, awaiting approval by another.
+	RefundStatusPendingApproval RefundStatus = "PENDING_APPROVAL"
+	// RefundStatusPending: approved and handed, or about to be handed, to the gateway.
+	RefundStatusPending   RefundStatus = "PENDING"
+	RefundStatusSucceeded RefundStatus = "SUCCEEDED"
+	RefundStatusFailed    RefundStatus = "FAILED"
+	// RefundStatusRejected: declined by a refund officer before reaching the gateway.
+	RefundStatusRejected RefundStatus = "REJECTED"
+)
+
+// PaymentRefund is an entry of the refund ledger. Every refund is recorded, including
+// those rejected by an officer or the gateway. Entries are never deleted, and only the
+// status, decision and gateway outcome of an entry that is not final may change.
+type PaymentRefund struct {
+	ID              string           `json:"id" gorm:"type:text;not null;primaryKey"`
+	TransactionID   string           `json:"transaction_id" gorm:"index"` // PaymentTransaction.ID
+	ReferenceNumber string           `json:"reference_number"`
+	Amount          decimal.Decimal  `json:"amount"`
+	Currency        string           `json:"currency"`
+	ReasonCode      RefundReasonCode `json:"reason_code"`
+	ReasonNote      string           `json:"reason_note,omitempty"`
+	RequestedBy     string           `json:"requested_by"`
+	ApprovedBy      string           `json:"approved_by,omitempty"` // Empty until approved
+	RejectedBy      string           `json:"rejected_by,omitempty"`
+	Status          RefundStatus     `json:"status"`
+	GatewayRefundID string           `json:"gateway_refund_id,omitempty"`
+	FailureReason   string           `json:"failure_reason,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// TableName returns the table name for PaymentRefund.
+func (PaymentRefund) TableName() string {
+	return "payment_refunds"
+}
+
+// RefundRequest asks the Payment Service to return all or part of a confirmed payment.
+// The refund waits for approval by a second refund officer.
+type RefundRequest struct {
+	ReferenceNumber string           `json:"-"`                // From the URL
+	Amount          *decimal.Decimal `json:"amount,omitempty"` // Optional: the full refundable balance when omitted
+	ReasonCode      RefundReasonCode `json:"reason_code"`
+	ReasonNote      string           `json:"reason_note,omitempty"`
+	RequestedBy     string           `json:"-"` // From the authenticated principal
+}
+
+// RefundDecision approves or rejects a requested refund.
+type RefundDecision struct {
+	ReferenceNumber string // From the URL
+	RefundID        string // From the URL
+	DecidedBy       string // From the authenticated principal
+}
+
+// ProviderRefundRequest is sent to the gateway that captured the payment.
+type ProviderRefundRequest struct {
+	RefundID             string          `json:"refund_id"` // Idempotency key for the gateway
+	ReferenceNumber      string          `json:"reference_number"`
+	SessionID            string          `json:"session_id"`
+	GatewayTransactionID string          `json:"gateway_transaction_id"`
+	Amount               decimal.Decimal `json:"amount"`
+	Currency             string          `json:"currency"`
+	Reason               string          `json:"reason"`
+}
+
+// ProviderRefundResponse is the gateway's acknowledgement of a refund.
+type ProviderRefundResponse struct {
+	GatewayRefundID string `json:"gateway_refund_id"`
+}
+
+// --------------------------------------------------------
+// Receipts
+// --------------------------------------------------------
+
+// ReceiptFormat is a rendition of a receipt held in storage.
+type ReceiptFormat string
+
+const (
+	ReceiptFormatPDF  ReceiptFormat = "pdf"
+	ReceiptFormatJSON ReceiptFormat = "json"
+)
+
+// PaymentReceipt is the official receipt issued once for a successful payment. Receipt
+// numbers are sequential within a calendar year and have no gaps.
+type PaymentReceipt struct {
+	ID              string          `json:"id" gorm:"type:text;not null;primaryKey"`
+	ReceiptNumber   string          `json:"receipt_number" gorm:"uniqueIndex"` // e.g. NSW-RC-2026-000042
+	TransactionID   string          `json:"transaction_id"`                    // PaymentTransaction.ID
+	ReferenceNumber string          `json:"reference_number" gorm:"uniqueIndex"`
+	TaskID          string          `json:"task_id"`
+	Amount          decimal.Decimal `json:"amount"`
+	Currency        string          `json:"currency"`
+	PDFKey          string          `json:"pdf_key" gorm:"column:pdf_key"`   // uploads.StorageDriver key of the PDF
+	JSONKey         string          `json:"json_key" gorm:"column:json_key"` // uploads.StorageDriver key of the ReceiptDocument
+	IssuedAt        time.Time       `json:"issued_at"`
+}
+
+// TableName returns the table name for PaymentReceipt.
+func (PaymentReceipt) TableName() string {
+	return "payment_receipts"
+}
+
+// StorageKey returns the storage key of the receipt in format.
+func (r *PaymentReceipt) StorageKey(format ReceiptFormat) (string, bool) {
+	switch format {
+	case ReceiptFormatPDF:
+		return r.PDFKey, true
+	case ReceiptFormatJSON:
+		return r.JSONKey, true
+	}
+	return "", false
+}
+
+// IssueReceiptRequest asks the Payment Service for the receipt of a successful payment.
+// The payer, agency ID and service are taken from the checkout metadata of the transaction.
+type IssueReceiptRequest struct {
+	ReferenceNumber string        `json:"reference_number"`
+	AgencyName      string        `json:"agency_name,omitempty"`
+	Breakdown       []ReceiptLine `json:"breakdown"` // The resolved fee breakdown of the task
+}
+
+// ReceiptParty identifies the payer or the collecting agency on a receipt.
+type ReceiptParty struct {
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// ReceiptLine is a line of the fee breakdown printed on a receipt.
+type ReceiptLine struct {
+	Description string          `json:"description"`
+	Category    string          `json:"category"` // ADDITION or DEDUCTION
+	Quantity    decimal.Decimal `json:"quantity"`
+	UnitPrice   decimal.Decimal `json:"unit_price"`
+	Amount      decimal.Decimal `json:"amount"`
+}
+
+// ReceiptDocument is the structured receipt stored alongside the PDF.
+type ReceiptDocument struct {
+	ReceiptNumber        string              `json:"receipt_number"`
+	IssuedAt             time.Time           `json:"issued_at"`
+	ReferenceNumber      string              `json:"reference_number"`
+	Payer                ReceiptParty        `json:"payer"`
+	Agency               ReceiptParty        `json:"agency"`
+	ServiceType          string              `json:"service_type,omitempty"`
+	Breakdown            []ReceiptLine       `json:"breakdown"`
+	TotalAmount          decimal.Decimal     `json:"total_amount"`
+	Currency             string              `json:"currency"`
+	ProviderID           string              `json:"provider_id"`
+	PaymentMethod        string              `json:"payment_method,omitempty"`
+	GatewayTransactionID string              `json:"gateway_transaction_id,omitempty"`
+	PaidAt               time.Time           `json:"paid_at"`
+	Conversion           *CurrencyConversion `json:"conversion,omitempty"`
+}
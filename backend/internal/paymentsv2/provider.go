@@ -31,6 +31,27 @@ type PaymentProvider interface {
 	// HandleValidateReference handles gateway-specific validation logic.
 	// This is called when a gateway queries if a reference is valid and payable.
 	HandleValidateReference(ctx context.Context, tx *PaymentTransaction) (*ValidateReferenceResponse, error)
+
+	// Refund returns all or part of a captured payment to the payer.
+	// It must be idempotent on req.RefundID.
+	Refund(ctx context.Context, req ProviderRefundRequest) (*ProviderRefundResponse, error)
+}
+
+// ConfigurableProvider is implemented by providers that read gateway settings
+// (e.g. gateway_url) from their payment_methods.json entry. The registry calls
+// Configure on every load and reload of the file.
+type ConfigurableProvider interface {
+	PaymentProvider
+	Configure(method PaymentMethodConfig) error
+}
+
+// StatusQueryProvider is implemented by providers whose gateway exposes a transaction
+// status API. The reconciliation job uses it to recover webhooks that never arrived.
+type StatusQueryProvider interface {
+	PaymentProvider
+	// QueryStatus returns the gateway's view of tx. A PENDING status means the gateway
+	// has no final outcome yet.
+	QueryStatus(ctx context.Context, tx *PaymentTransaction) (*WebhookPayload, error)
 }
 
 // PaymentRegistry manages the discovery and lookup of payment providers.
@@ -43,4 +64,8 @@ type PaymentRegistry interface {
 
 	// GetDefault returns the primary provider implementation.
 	GetDefault() (PaymentProvider, error)
+
+	// Provider retrieves a provider implementation whether or not its method is active.
+	// It serves operations on existing transactions, such as refunds.
+	Provider(id string) (PaymentProvider, error)
 }
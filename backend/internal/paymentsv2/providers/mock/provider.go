@@ -0,0 +1,117 @@
+// Package mock provides a paymentsv2.PaymentProvider for local development.
+//
+// It never contacts a real gateway: sessions are generated locally, and webhooks are
+// plain JSON documents in the paymentsv2.WebhookPayload shape, so a gateway callback
+// can be simulated with curl against POST /api/v1/payments/{providerId}/webhook.
+// Like any provider, the request must carry the HMAC headers configured in the
+// method's webhook block (see the paymentsv2 README).
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenNSW/nsw/internal/paymentsv2"
+)
+
+// ProviderID is the method ID the mock provider is conventionally registered under.
+const ProviderID = "mock"
+
+// Provider is an in-process payment gateway simulator.
+type Provider struct {
+	mu         sync.RWMutex
+	gatewayURL string
+}
+
+// NewProvider creates a mock provider. The checkout URL base is taken from the
+// gateway_url of its payment_methods.json entry. Without one, sessions have no checkout
+// URL and the payment is completed by posting the webhook by hand.
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+// Configure applies the payment method entry loaded by the registry.
+func (p *Provider) Configure(method paymentsv2.PaymentMethodConfig) error {
+	if method.GatewayURL != "" {
+		if _, err := url.Parse(method.GatewayURL); err != nil {
+			return fmt.Errorf("invalid gateway_url: %w", err)
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gatewayURL = method.GatewayURL
+	return nil
+}
+
+// CreateSession returns a locally generated session pointing at the configured gateway URL,
+// if any.
+func (p *Provider) CreateSession(_ context.Context, req paymentsv2.CreateCheckoutRequest) (*paymentsv2.CreateCheckoutResponse, error) {
+	if req.ReferenceNumber == "" {
+		return nil, fmt.Errorf("reference number is required")
+	}
+
+	p.mu.RLock()
+	gatewayURL := p.gatewayURL
+	p.mu.RUnlock()
+
+	sessionID := "mock_sess_" + uuid.NewString()
+	checkoutURL := ""
+	if gatewayURL != "" {
+		query := url.Values{}
+		query.Set("reference", req.ReferenceNumber)
+		query.Set("session_id", sessionID)
+		checkoutURL = gatewayURL + "?" + query.Encode()
+	}
+
+	return &paymentsv2.CreateCheckoutResponse{
+		ReferenceNumber: req.ReferenceNumber,
+		SessionID:       sessionID,
+		CheckoutURL:     checkoutURL,
+		ExpiresIn:       int(time.Until(req.ExpiresAt).Seconds()),
+	}, nil
+}
+
+// ParseWebhook decodes a JSON WebhookPayload.
+func (p *Provider) ParseWebhook(_ context.Context, body []byte, _ map[string][]string) (*paymentsv2.WebhookPayload, error) {
+	var payload paymentsv2.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid mock webhook payload: %w", err)
+	}
+	if payload.ReferenceNumber == "" {
+		return nil, fmt.Errorf("reference_number is required")
+	}
+	switch payload.Status {
+	case paymentsv2.PaymentStatusPending, paymentsv2.PaymentStatusSuccess, paymentsv2.PaymentStatusFailed:
+	default:
+		return nil, fmt.Errorf("unsupported payment status %q", payload.Status)
+	}
+	if payload.Timestamp == "" {
+		payload.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+	return &payload, nil
+}
+
+// Refund accepts every refund and returns a locally generated refund ID.
+func (p *Provider) Refund(_ context.Context, req paymentsv2.ProviderRefundRequest) (*paymentsv2.ProviderRefundResponse, error) {
+	if req.RefundID == "" {
+		return nil, fmt.Errorf("refund ID is required")
+	}
+	return &paymentsv2.ProviderRefundResponse{GatewayRefundID: "mock_refund_" + req.RefundID}, nil
+}
+
+// HandleValidateReference reports a reference as payable while it is pending and unexpired.
+func (p *Provider) HandleValidateReference(_ context.Context, tx *paymentsv2.PaymentTransaction) (*paymentsv2.ValidateReferenceResponse, error) {
+	return &paymentsv2.ValidateReferenceResponse{
+		Amount:     tx.Amount,
+		Currency:   tx.Currency,
+		ExpiryDate: tx.ExpiryDate.Format(time.RFC3339),
+		IsPayable:  tx.Status == paymentsv2.PaymentStatusPending && time.Now().Before(tx.ExpiryDate),
+		Remarks:    fmt.Sprintf("Current status: %s", tx.Status),
+	}, nil
+}
@@ -0,0 +1,191 @@
+package paymentsv2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrReceiptsDisabled is returned when the service was built without receipt storage.
+	ErrReceiptsDisabled = errors.New("payment receipts are not configured")
+	// ErrReceiptNotIssuable is returned when the transaction has not been paid.
+	ErrReceiptNotIssuable = errors.New("payment is not eligible for a receipt")
+	// ErrReceiptNotFound is returned when no receipt has been issued for a transaction.
+	ErrReceiptNotFound = errors.New("payment receipt not found")
+)
+
+// Checkout metadata keys the receipt reads the payer and agency from.
+const (
+	MetadataPayerID     = "payer_id"
+	MetadataPayerEmail  = "payer_email"
+	MetadataOrgID       = "org_id"
+	MetadataServiceType = "service_type"
+)
+
+// IssueReceipt issues the receipt of a paid transaction, or returns the one already issued.
+//
+// The receipt number is allocated, both renditions are written to storage and the receipt
+// is recorded in one database transaction with the payment row locked, so a payment never
+// has two receipts and a rolled-back issue does not consume a number.
+func (s *paymentService) IssueReceipt(ctx context.Context, req IssueReceiptRequest) (*PaymentReceipt, error) {
+	if s.storage == nil {
+		return nil, ErrReceiptsDisabled
+	}
+
+	var receipt *PaymentReceipt
+	err := s.repo.RunInTransaction(ctx, func(repo PaymentRepository) error {
+		tx, err := repo.GetByReferenceNumberForUpdate(ctx, req.ReferenceNumber)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve payment by reference: %w", err)
+		}
+		if tx == nil {
+			return fmt.Errorf("%w: %s", ErrTransactionNotFound, req.ReferenceNumber)
+		}
+		if !isPaidStatus(tx.Status) {
+			return fmt.Errorf("%w: status is %s", ErrReceiptNotIssuable, tx.Status)
+		}
+
+		receipt, err = repo.GetReceiptByReference(ctx, tx.ReferenceNumber)
+		if err != nil {
+			return fmt.Errorf("failed to read receipt: %w", err)
+		}
+		if receipt != nil {
+			return nil
+		}
+
+		issuedAt := time.Now().UTC()
+		seq, err := repo.NextReceiptSequence(ctx, issuedAt.Year())
+		if err != nil {
+			return fmt.Errorf("failed to allocate receipt number: %w", err)
+		}
+		doc := newReceiptDocument(tx, req, fmt.Sprintf("NSW-RC-%d-%06d", issuedAt.Year(), seq), issuedAt)
+
+		receipt = &PaymentReceipt{
+			ID:              uuid.NewString(),
+			ReceiptNumber:   doc.ReceiptNumber,
+			TransactionID:   tx.ID,
+			ReferenceNumber: tx.ReferenceNumber,
+			TaskID:          tx.TaskID,
+			Amount:          tx.Amount,
+			Currency:        tx.Currency,
+			PDFKey:          uuid.NewString() + ".pdf",
+			JSONKey:         uuid.NewString() + ".json",
+			IssuedAt:        issuedAt,
+		}
+		if err := s.storeReceipt(ctx, receipt, doc); err != nil {
+			return err
+		}
+		if err := repo.CreateReceipt(ctx, receipt); err != nil {
+			return fmt.Errorf("failed to record receipt: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "payment receipt issued", "reference", receipt.ReferenceNumber, "receipt_number", receipt.ReceiptNumber)
+	return receipt, nil
+}
+
+// GetReceipt returns the receipt issued for a transaction.
+func (s *paymentService) GetReceipt(ctx context.Context, referenceNumber string) (*PaymentReceipt, error) {
+	receipt, err := s.repo.GetReceiptByReference(ctx, referenceNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read receipt: %w", err)
+	}
+	if receipt == nil {
+		return nil, fmt.Errorf("%w: %s", ErrReceiptNotFound, referenceNumber)
+	}
+	return receipt, nil
+}
+
+// OpenReceipt streams a rendition of the receipt issued for a transaction. The caller
+// must close the returned reader.
+func (s *paymentService) OpenReceipt(ctx context.Context, referenceNumber string, format ReceiptFormat) (*PaymentReceipt, io.ReadCloser, error) {
+	if s.storage == nil {
+		return nil, nil, ErrReceiptsDisabled
+	}
+	receipt, err := s.GetReceipt(ctx, referenceNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := receipt.StorageKey(format)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported receipt format %q", format)
+	}
+	body, _, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read receipt %s: %w", receipt.ReceiptNumber, err)
+	}
+	return receipt, body, nil
+}
+
+// storeReceipt writes the JSON and PDF renditions of doc under the keys of receipt.
+func (s *paymentService) storeReceipt(ctx context.Context, receipt *PaymentReceipt, doc *ReceiptDocument) error {
+	raw, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode receipt: %w", err)
+	}
+	if err := s.storage.Save(ctx, receipt.JSONKey, bytes.NewReader(raw), "application/json"); err != nil {
+		return fmt.Errorf("failed to store receipt JSON: %w", err)
+	}
+	if err := s.storage.Save(ctx, receipt.PDFKey, bytes.NewReader(renderReceiptPDF(doc)), "application/pdf"); err != nil {
+		return fmt.Errorf("failed to store receipt PDF: %w", err)
+	}
+	return nil
+}
+
+// newReceiptDocument builds the receipt content from the transaction and its checkout metadata.
+func newReceiptDocument(tx *PaymentTransaction, req IssueReceiptRequest, number string, issuedAt time.Time) *ReceiptDocument {
+	doc := &ReceiptDocument{
+		ReceiptNumber:   number,
+		IssuedAt:        issuedAt,
+		ReferenceNumber: tx.ReferenceNumber,
+		Payer: ReceiptParty{
+			ID:    tx.GatewayMetadata[MetadataPayerID],
+			Email: tx.GatewayMetadata[MetadataPayerEmail],
+		},
+		Agency: ReceiptParty{
+			ID:   tx.GatewayMetadata[MetadataOrgID],
+			Name: req.AgencyName,
+		},
+		ServiceType:          tx.GatewayMetadata[MetadataServiceType],
+		Breakdown:            req.Breakdown,
+		TotalAmount:          tx.Amount,
+		Currency:             tx.Currency,
+		ProviderID:           tx.ProviderID,
+		PaymentMethod:        tx.PaymentMethod,
+		GatewayTransactionID: tx.GatewayMetadata["gateway_transaction_id"],
+		PaidAt:               tx.UpdatedAt,
+	}
+	if doc.Breakdown == nil {
+		doc.Breakdown = []ReceiptLine{}
+	}
+	if ts, err := time.Parse(time.RFC3339, tx.GatewayMetadata["webhook_timestamp"]); err == nil {
+		doc.PaidAt = ts
+	}
+	if tx.BaseAmount != nil && tx.ExchangeRate != nil {
+		doc.Conversion = &CurrencyConversion{
+			Amount:       tx.Amount,
+			Currency:     tx.Currency,
+			BaseAmount:   *tx.BaseAmount,
+			BaseCurrency: tx.BaseCurrency,
+			Rate:         *tx.ExchangeRate,
+			RateSource:   tx.RateSource,
+		}
+		if tx.RateAsOf != nil {
+			doc.Conversion.RateAsOf = *tx.RateAsOf
+		}
+		doc.Conversion.SnapshottedAt = tx.CreatedAt
+	}
+	return doc
+}
@@ -0,0 +1,196 @@
+package paymentsv2
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A4 page geometry in PDF points.
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 50
+	pdfLineHeight = 16
+)
+
+// pdfText is a run of text placed at a fixed x position on a row.
+type pdfText struct {
+	X    float64
+	Text string
+	Bold bool
+	Size float64
+}
+
+// pdfRow is one line of a receipt. Gap adds vertical space before the row.
+type pdfRow struct {
+	Texts []pdfText
+	Gap   float64
+}
+
+// renderReceiptPDF renders doc as an A4 PDF. Receipts are plain text, so the document is
+// written directly rather than through a layout library: Helvetica is one of the PDF
+// standard fonts and needs no embedding.
+func renderReceiptPDF(doc *ReceiptDocument) []byte {
+	return writePDF(paginate(receiptRows(doc)))
+}
+
+// receiptRows lays out the content of a receipt from top to bottom.
+func receiptRows(doc *ReceiptDocument) []pdfRow {
+	text := func(x float64, s string) pdfText { return pdfText{X: x, Text: s, Size: 10} }
+	bold := func(x float64, s string) pdfText { return pdfText{X: x, Text: s, Bold: true, Size: 10} }
+	field := func(label, value string) pdfRow {
+		return pdfRow{Texts: []pdfText{bold(pdfMargin, label), text(pdfMargin+130, value)}}
+	}
+	spaced := func(r pdfRow) pdfRow { r.Gap = 18; return r }
+
+	rows := []pdfRow{
+		{Texts: []pdfText{{X: pdfMargin, Text: "OFFICIAL RECEIPT", Bold: true, Size: 18}}},
+		{Texts: []pdfText{text(pdfMargin, "National Single Window")}, Gap: 4},
+		spaced(field("Receipt number", doc.ReceiptNumber)),
+		field("Issued at", doc.IssuedAt.UTC().Format(time.RFC1123)),
+		field("Payment reference", doc.ReferenceNumber),
+	}
+
+	rows = append(rows, field("Payer", partyLabel(doc.Payer)))
+	rows = append(rows, field("Collecting agency", partyLabel(doc.Agency)))
+	if doc.ServiceType != "" {
+		rows = append(rows, field("Service", doc.ServiceType))
+	}
+
+	rows = append(rows, spaced(pdfRow{Texts: []pdfText{
+		bold(pdfMargin, "Description"), bold(330, "Quantity"), bold(400, "Unit price"), bold(480, "Amount"),
+	}}))
+	for _, line := range doc.Breakdown {
+		value := line.Amount.StringFixed(2)
+		if line.Category == "DEDUCTION" {
+			value = "-" + value
+		}
+		rows = append(rows, pdfRow{Texts: []pdfText{
+			text(pdfMargin, line.Description),
+			text(330, line.Quantity.String()),
+			text(400, line.UnitPrice.StringFixed(2)),
+			text(480, value),
+		}})
+	}
+	rows = append(rows, pdfRow{Texts: []pdfText{bold(400, "Total paid"), bold(480, doc.TotalAmount.StringFixed(2)+" "+doc.Currency)}, Gap: 6})
+
+	if c := doc.Conversion; c != nil {
+		rows = append(rows, field("Equivalent", c.BaseAmount.StringFixed(2)+" "+c.BaseCurrency))
+		rows = append(rows, field("Exchange rate", fmt.Sprintf("1 %s = %s %s (%s, %s)",
+			c.Currency, c.Rate.String(), c.BaseCurrency, c.RateSource, c.RateAsOf.UTC().Format("2006-01-02"))))
+	}
+
+	rows = append(rows,
+		spaced(field("Paid at", doc.PaidAt.UTC().Format(time.RFC1123))),
+		field("Payment provider", doc.ProviderID),
+	)
+	if doc.PaymentMethod != "" {
+		rows = append(rows, field("Payment method", doc.PaymentMethod))
+	}
+	if doc.GatewayTransactionID != "" {
+		rows = append(rows, field("Gateway transaction", doc.GatewayTransactionID))
+	}
+
+	return append(rows, pdfRow{Texts: []pdfText{{X: pdfMargin, Text: "This receipt was issued electronically and is valid without a signature.", Size: 8}}, Gap: 24})
+}
+
+// partyLabel formats a payer or agency as "Name (ID)", falling back to whichever is known.
+func partyLabel(p ReceiptParty) string {
+	name := p.Name
+	if name == "" {
+		name = p.Email
+	}
+	switch {
+	case name != "" && p.ID != "":
+		return name + " (" + p.ID + ")"
+	case name != "":
+		return name
+	case p.ID != "":
+		return p.ID
+	}
+	return "-"
+}
+
+// paginate splits rows into pages and renders each page's content stream.
+func paginate(rows []pdfRow) [][]byte {
+	var (
+		pages [][]byte
+		page  bytes.Buffer
+		y     = float64(pdfPageHeight - pdfMargin)
+	)
+	for _, row := range rows {
+		y -= row.Gap + pdfLineHeight
+		if y < pdfMargin && page.Len() > 0 {
+			pages = append(pages, append([]byte(nil), page.Bytes()...))
+			page.Reset()
+			y = pdfPageHeight - pdfMargin - pdfLineHeight
+		}
+		for _, t := range row.Texts {
+			font := "F1"
+			if t.Bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&page, "BT /%s %.0f Tf %.2f %.2f Td (%s) Tj ET\n", font, t.Size, t.X, y, pdfEscape(t.Text))
+		}
+	}
+	return append(pages, page.Bytes())
+}
+
+// writePDF assembles the page content streams into a PDF file with a cross-reference table.
+func writePDF(pages [][]byte) []byte {
+	// Objects 1-4 are the catalog, page tree and two fonts; each page adds a page object
+	// followed by its content stream.
+	objects := make([]string, 4, 4+2*len(pages))
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+	objects[2] = "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"
+	objects[3] = "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"
+	for i, content := range pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// pdfEscape encodes s as the body of a PDF literal string in WinAnsiEncoding.
+// Characters outside Latin-1 are replaced with '?'.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
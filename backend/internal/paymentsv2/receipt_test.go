@@ -0,0 +1,201 @@
+package paymentsv2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// memStorage is an in-memory uploads.StorageDriver.
+type memStorage struct {
+	files map[string][]byte
+	types map[string]string
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{files: make(map[string][]byte), types: make(map[string]string)}
+}
+
+func (m *memStorage) Save(ctx context.Context, key string, body io.Reader, contentType string) error {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	m.files[key] = b
+	m.types[key] = contentType
+	return nil
+}
+
+func (m *memStorage) Get(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	b, ok := m.files[key]
+	if !ok {
+		return nil, "", errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(b)), m.types[key], nil
+}
+
+func (m *memStorage) Delete(ctx context.Context, key string) error {
+	delete(m.files, key)
+	return nil
+}
+
+func (m *memStorage) GetDownloadURL(ctx context.Context, key string) (string, error) {
+	return "https://storage.example.com/" + key, nil
+}
+
+func (m *memStorage) GetUploadURL(ctx context.Context, key string, contentType string, maxSizeBytes int64) (string, error) {
+	return "https://storage.example.com/" + key, nil
+}
+
+func newReceiptFixture() (*paymentService, *mockRepository, *memStorage) {
+	repo := newMockRepository()
+	storage := newMemStorage()
+	registry := &mockRegistry{order: []string{"mock"}, providers: map[string]PaymentProvider{"mock": successfulProvider()}}
+	service := NewPaymentService(repo, registry, nil, storage, nil).(*paymentService)
+
+	addTransaction(repo, "PAID", PaymentStatusSuccess, time.Now().Add(-time.Hour))
+	repo.txs["PAID"].GatewayMetadata = map[string]string{
+		MetadataPayerID:          "trader-1",
+		MetadataPayerEmail:       "trader@example.com",
+		MetadataOrgID:            "CUSTOMS",
+		"gateway_transaction_id": "GW-1",
+	}
+	addTransaction(repo, "OPEN", PaymentStatusPending, time.Now().Add(time.Hour))
+	return service, repo, storage
+}
+
+func receiptRequest(ref string) IssueReceiptRequest {
+	return IssueReceiptRequest{
+		ReferenceNumber: ref,
+		AgencyName:      "Sri Lanka Customs",
+		Breakdown: []ReceiptLine{
+			{Description: "Processing fee", Category: "ADDITION", Quantity: decimal.NewFromInt(1), UnitPrice: decimal.NewFromInt(100), Amount: decimal.NewFromInt(100)},
+		},
+	}
+}
+
+func TestIssueReceipt(t *testing.T) {
+	t.Run("stores PDF and JSON", func(t *testing.T) {
+		service, repo, storage := newReceiptFixture()
+
+		receipt, err := service.IssueReceipt(context.Background(), receiptRequest("PAID"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		wantNumber := "NSW-RC-" + time.Now().UTC().Format("2006") + "-000001"
+		if receipt.ReceiptNumber != wantNumber || repo.receipts["PAID"] != receipt {
+			t.Fatalf("expected receipt %s to be recorded, got %+v", wantNumber, receipt)
+		}
+
+		pdf := storage.files[receipt.PDFKey]
+		if !bytes.HasPrefix(pdf, []byte("%PDF-")) || !bytes.Contains(pdf, []byte(wantNumber)) || storage.types[receipt.PDFKey] != "application/pdf" {
+			t.Fatalf("expected a PDF naming the receipt, got %q", pdf)
+		}
+
+		var doc ReceiptDocument
+		if err := json.Unmarshal(storage.files[receipt.JSONKey], &doc); err != nil {
+			t.Fatalf("expected JSON receipt, got %v", err)
+		}
+		if doc.Payer.ID != "trader-1" || doc.Agency.ID != "CUSTOMS" || doc.Agency.Name != "Sri Lanka Customs" ||
+			doc.GatewayTransactionID != "GW-1" || len(doc.Breakdown) != 1 || !doc.TotalAmount.Equal(decimal.NewFromInt(100)) {
+			t.Fatalf("unexpected receipt document: %+v", doc)
+		}
+	})
+
+	t.Run("is issued once per payment", func(t *testing.T) {
+		service, repo, storage := newReceiptFixture()
+		addTransaction(repo, "PAID-2", PaymentStatusSuccess, time.Now().Add(-time.Hour))
+
+		first, _ := service.IssueReceipt(context.Background(), receiptRequest("PAID"))
+		again, err := service.IssueReceipt(context.Background(), receiptRequest("PAID"))
+		if err != nil || again.ReceiptNumber != first.ReceiptNumber {
+			t.Fatalf("expected the existing receipt, got %+v, %v", again, err)
+		}
+		if len(storage.files) != 2 {
+			t.Fatalf("expected one PDF and one JSON file, got %d files", len(storage.files))
+		}
+
+		second, err := service.IssueReceipt(context.Background(), receiptRequest("PAID-2"))
+		if err != nil || !strings.HasSuffix(second.ReceiptNumber, "-000002") {
+			t.Fatalf("expected the next sequential number, got %+v, %v", second, err)
+		}
+	})
+
+	t.Run("rejects unpaid and unknown payments", func(t *testing.T) {
+		service, _, _ := newReceiptFixture()
+
+		if _, err := service.IssueReceipt(context.Background(), receiptRequest("OPEN")); !errors.Is(err, ErrReceiptNotIssuable) {
+			t.Fatalf("expected ErrReceiptNotIssuable, got %v", err)
+		}
+		if _, err := service.IssueReceipt(context.Background(), receiptRequest("MISSING")); !errors.Is(err, ErrTransactionNotFound) {
+			t.Fatalf("expected ErrTransactionNotFound, got %v", err)
+		}
+	})
+
+	t.Run("disabled without storage", func(t *testing.T) {
+		service, _, _ := newReceiptFixture()
+		service.storage = nil
+
+		if _, err := service.IssueReceipt(context.Background(), receiptRequest("PAID")); !errors.Is(err, ErrReceiptsDisabled) {
+			t.Fatalf("expected ErrReceiptsDisabled, got %v", err)
+		}
+	})
+}
+
+func TestHTTPHandler_HandleDownloadReceipt(t *testing.T) {
+	service, _, _ := newReceiptFixture()
+	receipt, err := service.IssueReceipt(context.Background(), receiptRequest("PAID"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// Only trader-1 is a party of the paying task.
+	h := NewHTTPHandler(service, nil, taskAccessFunc(func(ctx context.Context, taskID string) (bool, error) {
+		return auth.GetAuthContext(ctx).User.ID == "trader-1", nil
+	}))
+
+	serveAs := func(userID, ref, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/payments/transactions/"+ref+"/receipt"+query, nil)
+		req.SetPathValue("referenceNumber", ref)
+		authCtx := &auth.AuthContext{User: &auth.UserContext{ID: userID, Roles: []string{auth.RoleTrader}}}
+		rr := httptest.NewRecorder()
+		h.HandleDownloadReceipt(rr, req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, authCtx)))
+		return rr
+	}
+	serve := func(ref, query string) *httptest.ResponseRecorder {
+		return serveAs("trader-1", ref, query)
+	}
+
+	rr := serve("PAID", "")
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/pdf" || !bytes.HasPrefix(rr.Body.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("expected PDF download, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rr.Header().Get("Content-Disposition"), receipt.ReceiptNumber+".pdf") {
+		t.Fatalf("expected receipt number in filename, got %q", rr.Header().Get("Content-Disposition"))
+	}
+
+	rr = serve("PAID", "?format=json")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), receipt.ReceiptNumber) {
+		t.Fatalf("expected JSON receipt, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := serve("PAID", "?format=xml"); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unsupported format, got %d", rr.Code)
+	}
+	if rr := serve("OPEN", ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without a receipt, got %d", rr.Code)
+	}
+	if rr := serveAs("trader-2", "PAID", ""); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for another trader's receipt, got %d", rr.Code)
+	}
+}
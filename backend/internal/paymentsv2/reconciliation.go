@@ -0,0 +1,411 @@
+package paymentsv2
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+const (
+	// reconcileBatchSize bounds the PENDING transactions examined per pass.
+	reconcileBatchSize = 200
+	// expiryGracePeriod leaves room for a webhook that is in flight when a transaction
+	// reaches its expiry date before the transaction is marked EXPIRED.
+	expiryGracePeriod = 5 * time.Minute
+	// redeliveryDelay leaves room for the delivery that follows a status change before
+	// reconciliation delivers its event again.
+	redeliveryDelay = time.Minute
+)
+
+// Reconcile examines PENDING transactions. A provider that implements StatusQueryProvider
+// is asked for the gateway status, which recovers webhooks NSW never received. Transactions
+// still pending past their expiry date (plus a grace period) are moved to EXPIRED. Every
+// correction is fed back to the owning task through the registered EventHandler, and
+// final outcomes the task has not yet received are delivered again.
+func (s *paymentService) Reconcile(ctx context.Context) (*ReconciliationResult, error) {
+	pending, err := s.repo.ListByStatus(ctx, PaymentStatusPending, reconcileBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending payment transactions: %w", err)
+	}
+
+	result := &ReconciliationResult{}
+	now := time.Now()
+	for i := range pending {
+		tx := &pending[i]
+		result.Checked++
+
+		updated, err := s.reconcileTransaction(ctx, tx, now)
+		if err != nil {
+			result.Failed++
+			slog.ErrorContext(ctx, "failed to reconcile payment transaction", "reference", tx.ReferenceNumber, "provider", tx.ProviderID, "error", err)
+			continue
+		}
+		switch {
+		case !updated:
+		case tx.Status == PaymentStatusExpired:
+			result.Expired++
+		default:
+			result.Updated++
+		}
+	}
+
+	if err := s.redeliverEvents(ctx, now, result); err != nil {
+		return nil, err
+	}
+
+	if result.Updated > 0 || result.Expired > 0 || result.Redelivered > 0 || result.Failed > 0 {
+		slog.InfoContext(ctx, "payment reconciliation completed",
+			"checked", result.Checked, "updated", result.Updated, "expired", result.Expired, "redelivered", result.Redelivered, "failed", result.Failed)
+	}
+	return result, nil
+}
+
+// redeliverEvents delivers the final outcomes whose event is still pending to their task.
+func (s *paymentService) redeliverEvents(ctx context.Context, now time.Time, result *ReconciliationResult) error {
+	undelivered, err := s.repo.ListEventPending(ctx, now.Add(-redeliveryDelay), reconcileBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to list undelivered payment events: %w", err)
+	}
+	for i := range undelivered {
+		tx := &undelivered[i]
+		if err := s.deliver(ctx, tx); err != nil {
+			result.Failed++
+			slog.ErrorContext(ctx, "failed to redeliver payment event", "reference", tx.ReferenceNumber, "task_id", tx.TaskID, "error", err)
+			continue
+		}
+		result.Redelivered++
+	}
+	return nil
+}
+
+// reconcileTransaction applies the provider's status or expiry to a single PENDING
+// transaction and reports whether it changed.
+func (s *paymentService) reconcileTransaction(ctx context.Context, tx *PaymentTransaction, now time.Time) (bool, error) {
+	provider, err := s.registry.Get(tx.ProviderID)
+	if err != nil && !errors.Is(err, ErrProviderInactive) && !errors.Is(err, ErrProviderNotFound) {
+		return false, err
+	}
+
+	if querier, ok := provider.(StatusQueryProvider); ok {
+		payload, err := querier.QueryStatus(ctx, tx)
+		if err != nil {
+			return false, fmt.Errorf("provider %s status query failed: %w", tx.ProviderID, err)
+		}
+		if payload != nil && payload.Status != PaymentStatusPending && canTransition(tx.Status, payload.Status) {
+			if err := s.applyStatus(ctx, tx, payload, StatusSourceReconciliation); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+	}
+
+	if now.Before(tx.ExpiryDate.Add(expiryGracePeriod)) {
+		return false, nil
+	}
+	payload := &WebhookPayload{
+		ReferenceNumber: tx.ReferenceNumber,
+		Status:          PaymentStatusExpired,
+		Timestamp:       now.UTC().Format(time.RFC3339),
+	}
+	if err := s.applyStatus(ctx, tx, payload, StatusSourceReconciliation); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// isPaidStatus reports whether the gateway captured the payment, including payments
+// refunded since.
+func isPaidStatus(status PaymentStatus) bool {
+	return status == PaymentStatusSuccess || status == PaymentStatusPartiallyRefunded || status == PaymentStatusRefunded
+}
+
+// settlementLine is a parsed row of a settlement file.
+type settlementLine struct {
+	line                 int
+	referenceNumber      string
+	gatewayTransactionID string
+	amount               decimal.Decimal
+	currency             string
+	settledAt            string
+}
+
+// Settlement file columns. reference_number, amount and currency are required; the
+// header row may list columns in any order and may include columns NSW ignores.
+const (
+	settlementColReference = "reference_number"
+	settlementColGatewayTx = "gateway_transaction_id"
+	settlementColAmount    = "amount"
+	settlementColCurrency  = "currency"
+	settlementColSettledAt = "settled_at"
+)
+
+// ImportSettlement reconciles the CSV settlement file of providerID for settlementDate.
+// Every line is a payment the gateway settled. Settled payments NSW had not recorded as
+// SUCCESS are corrected and their tasks notified; other mismatches are only reported.
+// The report is persisted and returned.
+func (s *paymentService) ImportSettlement(ctx context.Context, providerID string, settlementDate time.Time, file io.Reader) (*SettlementReport, error) {
+	if _, err := s.registry.Get(providerID); err != nil && !errors.Is(err, ErrProviderInactive) {
+		return nil, fmt.Errorf("provider %s not found: %w", providerID, err)
+	}
+
+	lines, invalid, err := parseSettlementFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	day := time.Date(settlementDate.Year(), settlementDate.Month(), settlementDate.Day(), 0, 0, 0, 0, time.UTC)
+	report := &SettlementReport{
+		ID:             uuid.NewString(),
+		ProviderID:     providerID,
+		SettlementDate: day,
+		TotalLines:     len(lines) + len(invalid),
+		Discrepancies:  invalid,
+	}
+
+	settled := make(map[string]struct{}, len(lines))
+	for _, l := range lines {
+		if _, dup := settled[l.referenceNumber]; dup {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Type:            DiscrepancyDuplicateLine,
+				Line:            l.line,
+				ReferenceNumber: l.referenceNumber,
+			})
+			continue
+		}
+		settled[l.referenceNumber] = struct{}{}
+
+		d, err := s.reconcileSettlementLine(ctx, providerID, l)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case d == nil:
+			report.Matched++
+		case d.Type == DiscrepancyStatusCorrected:
+			report.Corrected++
+			report.Discrepancies = append(report.Discrepancies, *d)
+		default:
+			report.Discrepancies = append(report.Discrepancies, *d)
+		}
+	}
+
+	confirmed, err := s.repo.ListByProviderAndStatus(ctx, providerID, PaymentStatusSuccess, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list confirmed payments: %w", err)
+	}
+	for _, tx := range confirmed {
+		if _, ok := settled[tx.ReferenceNumber]; ok {
+			continue
+		}
+		amount := tx.Amount
+		report.Discrepancies = append(report.Discrepancies, Discrepancy{
+			Type:            DiscrepancyNotSettled,
+			ReferenceNumber: tx.ReferenceNumber,
+			NSWStatus:       tx.Status,
+			NSWAmount:       &amount,
+			Currency:        tx.Currency,
+		})
+	}
+
+	if err := s.repo.CreateSettlementReport(ctx, report); err != nil {
+		return nil, fmt.Errorf("failed to save settlement report: %w", err)
+	}
+
+	slog.InfoContext(ctx, "settlement file reconciled",
+		"provider", providerID, "date", day.Format(time.DateOnly), "report_id", report.ID,
+		"lines", report.TotalLines, "matched", report.Matched, "corrected", report.Corrected, "discrepancies", len(report.Discrepancies))
+	return report, nil
+}
+
+// reconcileSettlementLine compares one settled payment with its transaction and returns
+// the discrepancy found, or nil if the records agree.
+func (s *paymentService) reconcileSettlementLine(ctx context.Context, providerID string, l settlementLine) (*Discrepancy, error) {
+	settledAmount := l.amount
+	tx, err := s.repo.GetByReferenceNumber(ctx, l.referenceNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve payment by reference: %w", err)
+	}
+	if tx == nil || tx.ProviderID != providerID {
+		return &Discrepancy{
+			Type:            DiscrepancyUnknownReference,
+			Line:            l.line,
+			ReferenceNumber: l.referenceNumber,
+			SettledAmount:   &settledAmount,
+			Currency:        l.currency,
+		}, nil
+	}
+
+	nswAmount := tx.Amount
+	d := &Discrepancy{
+		Line:            l.line,
+		ReferenceNumber: tx.ReferenceNumber,
+		NSWStatus:       tx.Status,
+		NSWAmount:       &nswAmount,
+		SettledAmount:   &settledAmount,
+		Currency:        l.currency,
+	}
+
+	// An amount mismatch is never corrected automatically: confirming an under-payment
+	// would release the task.
+	if !tx.Amount.Equal(l.amount) || !strings.EqualFold(tx.Currency, l.currency) {
+		d.Type = DiscrepancyAmountMismatch
+		d.Detail = fmt.Sprintf("NSW expects %s %s", tx.Amount.StringFixed(2), tx.Currency)
+		return d, nil
+	}
+	if isPaidStatus(tx.Status) {
+		return nil, nil
+	}
+
+	// The gateway settled the funds, so SUCCESS is authoritative over any other status.
+	d.Type = DiscrepancyStatusCorrected
+	payload := &WebhookPayload{
+		ReferenceNumber:      tx.ReferenceNumber,
+		GatewayTransactionID: l.gatewayTransactionID,
+		Status:               PaymentStatusSuccess,
+		Amount:               l.amount,
+		Currency:             l.currency,
+		Timestamp:            l.settledAt,
+	}
+	if err := s.applyStatus(ctx, tx, payload, StatusSourceSettlement); err != nil {
+		// The transaction is corrected even if the task rejects the event; surface it for follow-up.
+		d.Detail = err.Error()
+		slog.ErrorContext(ctx, "settlement correction not delivered to task", "reference", tx.ReferenceNumber, "error", err)
+	}
+	return d, nil
+}
+
+// parseSettlementFile reads the settlement CSV. Unparseable rows are returned as
+// INVALID_LINE discrepancies; a missing header or required column is an error.
+func parseSettlementFile(file io.Reader) ([]settlementLine, []Discrepancy, error) {
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read settlement file header: %w", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{settlementColReference, settlementColAmount, settlementColCurrency} {
+		if _, ok := cols[required]; !ok {
+			return nil, nil, fmt.Errorf("settlement file is missing required column %q", required)
+		}
+	}
+	field := func(record []string, col string) string {
+		if i, ok := cols[col]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var lines []settlementLine
+	var invalid []Discrepancy
+	for lineNo := 2; ; lineNo++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			invalid = append(invalid, Discrepancy{Type: DiscrepancyInvalidLine, Line: lineNo, Detail: err.Error()})
+			continue
+		}
+
+		ref := field(record, settlementColReference)
+		amount, amountErr := decimal.NewFromString(field(record, settlementColAmount))
+		switch {
+		case ref == "":
+			invalid = append(invalid, Discrepancy{Type: DiscrepancyInvalidLine, Line: lineNo, Detail: "reference_number is empty"})
+			continue
+		case amountErr != nil:
+			invalid = append(invalid, Discrepancy{Type: DiscrepancyInvalidLine, Line: lineNo, ReferenceNumber: ref, Detail: "invalid amount"})
+			continue
+		}
+
+		lines = append(lines, settlementLine{
+			line:                 lineNo,
+			referenceNumber:      ref,
+			gatewayTransactionID: field(record, settlementColGatewayTx),
+			amount:               amount,
+			currency:             field(record, settlementColCurrency),
+			settledAt:            field(record, settlementColSettledAt),
+		})
+	}
+	return lines, invalid, nil
+}
+
+// GetSettlementReport returns a persisted settlement report.
+func (s *paymentService) GetSettlementReport(ctx context.Context, id string) (*SettlementReport, error) {
+	return s.repo.GetSettlementReport(ctx, id)
+}
+
+// ReconciliationActor is the system actor background reconciliation passes run as.
+const ReconciliationActor = "payments-reconciliation"
+
+// ReconciliationWorker runs PaymentService.Reconcile periodically in the background.
+// Passes are safe to run concurrently on several replicas: status changes are applied
+// with a compare-and-update, so each correction is emitted once.
+type ReconciliationWorker struct {
+	service  PaymentService
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewReconciliationWorker creates a worker that reconciles every interval.
+func NewReconciliationWorker(service PaymentService, interval time.Duration) *ReconciliationWorker {
+	return &ReconciliationWorker{
+		service:  service,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the background loop. It returns immediately.
+func (w *ReconciliationWorker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		slog.Info("payment reconciliation worker started", "interval", w.interval)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := w.service.Reconcile(auth.WithSystemActor(ctx, ReconciliationActor)); err != nil {
+					slog.ErrorContext(ctx, "payment reconciliation pass failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop cancels the loop and waits for an in-flight pass to finish.
+func (w *ReconciliationWorker) Stop() {
+	w.once.Do(func() {
+		if w.cancel == nil {
+			close(w.done)
+			return
+		}
+		w.cancel()
+		<-w.done
+	})
+}
@@ -0,0 +1,269 @@
+package paymentsv2
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// queryingProvider is a stubProvider whose gateway exposes a status API.
+type queryingProvider struct {
+	stubProvider
+	status PaymentStatus
+}
+
+func (p *queryingProvider) QueryStatus(ctx context.Context, tx *PaymentTransaction) (*WebhookPayload, error) {
+	return &WebhookPayload{ReferenceNumber: tx.ReferenceNumber, Status: p.status, GatewayTransactionID: "GW-Q", Amount: tx.Amount, Currency: tx.Currency}, nil
+}
+
+func newReconcileFixture(provider PaymentProvider) (*paymentService, *mockRepository, *[]InternalPaymentEvent) {
+	repo := newMockRepository()
+	registry := &mockRegistry{order: []string{"mock"}, providers: map[string]PaymentProvider{"mock": provider}}
+	service := NewPaymentService(repo, registry, nil, nil, nil).(*paymentService)
+	var events []InternalPaymentEvent
+	service.RegisterEventHandler(func(ctx context.Context, event InternalPaymentEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	return service, repo, &events
+}
+
+func addTransaction(repo *mockRepository, ref string, status PaymentStatus, expiry time.Time) {
+	repo.txs[ref] = &PaymentTransaction{
+		ID:              "id-" + ref,
+		ReferenceNumber: ref,
+		TaskID:          "task-" + ref,
+		ProviderID:      "mock",
+		Status:          status,
+		Amount:          decimal.NewFromFloat(100.0),
+		Currency:        "LKR",
+		ExpiryDate:      expiry,
+		UpdatedAt:       time.Now(),
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	t.Run("expires overdue pending transactions", func(t *testing.T) {
+		service, repo, events := newReconcileFixture(&stubProvider{})
+		addTransaction(repo, "OVERDUE", PaymentStatusPending, time.Now().Add(-time.Hour))
+		addTransaction(repo, "IN-GRACE", PaymentStatusPending, time.Now().Add(-time.Minute))
+		addTransaction(repo, "ACTIVE", PaymentStatusPending, time.Now().Add(time.Hour))
+
+		result, err := service.Reconcile(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result.Checked != 3 || result.Expired != 1 || result.Updated != 0 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		if repo.txs["OVERDUE"].Status != PaymentStatusExpired {
+			t.Fatalf("expected OVERDUE to be EXPIRED, got %s", repo.txs["OVERDUE"].Status)
+		}
+		if repo.txs["IN-GRACE"].Status != PaymentStatusPending || repo.txs["ACTIVE"].Status != PaymentStatusPending {
+			t.Fatal("expected transactions within expiry or grace period to stay PENDING")
+		}
+		if len(*events) != 1 || (*events)[0].EventType != PaymentEventExpired || (*events)[0].Data.Source != StatusSourceReconciliation {
+			t.Fatalf("expected one PAYMENT_EXPIRED event from reconciliation, got %+v", *events)
+		}
+	})
+
+	t.Run("recovers missed webhook from provider status", func(t *testing.T) {
+		service, repo, events := newReconcileFixture(&queryingProvider{status: PaymentStatusSuccess})
+		addTransaction(repo, "PAID", PaymentStatusPending, time.Now().Add(-time.Hour))
+
+		result, err := service.Reconcile(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result.Updated != 1 || result.Expired != 0 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		if repo.txs["PAID"].Status != PaymentStatusSuccess || repo.txs["PAID"].GatewayMetadata["gateway_transaction_id"] != "GW-Q" {
+			t.Fatalf("unexpected transaction: %+v", repo.txs["PAID"])
+		}
+		if len(*events) != 1 || (*events)[0].EventType != PaymentEventConfirmed {
+			t.Fatalf("expected one PAYMENT_CONFIRMED event, got %+v", *events)
+		}
+	})
+
+	t.Run("redelivers undelivered outcomes", func(t *testing.T) {
+		service, repo, events := newReconcileFixture(&stubProvider{})
+		addTransaction(repo, "STUCK", PaymentStatusSuccess, time.Now().Add(-time.Hour))
+		repo.txs["STUCK"].EventPending = true
+		repo.txs["STUCK"].UpdatedAt = time.Now().Add(-time.Hour)
+		repo.txs["STUCK"].GatewayMetadata = map[string]string{"status_source": StatusSourceWebhook}
+		addTransaction(repo, "IN-FLIGHT", PaymentStatusFailed, time.Now().Add(-time.Hour))
+		repo.txs["IN-FLIGHT"].EventPending = true
+
+		result, err := service.Reconcile(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result.Redelivered != 1 {
+			t.Fatalf("unexpected result: %+v", result)
+		}
+		if len(*events) != 1 || (*events)[0].EventType != PaymentEventConfirmed || (*events)[0].Data.ReferenceNumber != "STUCK" {
+			t.Fatalf("expected one PAYMENT_CONFIRMED event for STUCK, got %+v", *events)
+		}
+		if repo.txs["STUCK"].EventPending || !repo.txs["IN-FLIGHT"].EventPending {
+			t.Fatal("expected only the redelivered event to be cleared")
+		}
+	})
+
+	t.Run("provider still pending falls back to expiry", func(t *testing.T) {
+		service, repo, _ := newReconcileFixture(&queryingProvider{status: PaymentStatusPending})
+		addTransaction(repo, "OVERDUE", PaymentStatusPending, time.Now().Add(-time.Hour))
+
+		if _, err := service.Reconcile(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if repo.txs["OVERDUE"].Status != PaymentStatusExpired {
+			t.Fatalf("expected EXPIRED, got %s", repo.txs["OVERDUE"].Status)
+		}
+	})
+}
+
+func TestProcessWebhook_LateConfirmation(t *testing.T) {
+	webhook := func(status PaymentStatus) *stubProvider {
+		return &stubProvider{
+			parseFn: func(ctx context.Context, body []byte, headers map[string][]string) (*WebhookPayload, error) {
+				return &WebhookPayload{ReferenceNumber: string(body), Status: status, Amount: decimal.NewFromInt(100), Currency: "LKR"}, nil
+			},
+		}
+	}
+
+	t.Run("success after expiry is applied", func(t *testing.T) {
+		service, repo, events := newReconcileFixture(webhook(PaymentStatusSuccess))
+		addTransaction(repo, "LATE", PaymentStatusExpired, time.Now().Add(-time.Hour))
+
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("LATE"), nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if repo.txs["LATE"].Status != PaymentStatusSuccess || len(*events) != 1 {
+			t.Fatalf("expected late confirmation to be applied, got %s and %d events", repo.txs["LATE"].Status, len(*events))
+		}
+	})
+
+	t.Run("failure after expiry is ignored", func(t *testing.T) {
+		service, repo, events := newReconcileFixture(webhook(PaymentStatusFailed))
+		addTransaction(repo, "LATE", PaymentStatusExpired, time.Now().Add(-time.Hour))
+
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("LATE"), nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if repo.txs["LATE"].Status != PaymentStatusExpired || len(*events) != 0 {
+			t.Fatalf("expected EXPIRED to be kept, got %s and %d events", repo.txs["LATE"].Status, len(*events))
+		}
+	})
+}
+
+func TestImportSettlement(t *testing.T) {
+	today := time.Now().UTC()
+
+	t.Run("produces discrepancy report and corrects settled payments", func(t *testing.T) {
+		service, repo, events := newReconcileFixture(&stubProvider{})
+		addTransaction(repo, "MATCHED", PaymentStatusSuccess, today)
+		addTransaction(repo, "MISSED", PaymentStatusPending, today)
+		addTransaction(repo, "SHORT", PaymentStatusPending, today)
+		addTransaction(repo, "UNSETTLED", PaymentStatusSuccess, today)
+
+		file := strings.Join([]string{
+			"reference_number,gateway_transaction_id,amount,currency,settled_at",
+			"MATCHED,GW-1,100.00,LKR,2026-03-01T10:00:00Z",
+			"MISSED,GW-2,100.00,LKR,2026-03-01T11:00:00Z",
+			"SHORT,GW-3,90.00,LKR,2026-03-01T12:00:00Z",
+			"UNKNOWN,GW-4,50.00,LKR,2026-03-01T13:00:00Z",
+			"MATCHED,GW-1,100.00,LKR,2026-03-01T10:00:00Z",
+			"BROKEN,GW-5,abc,LKR,2026-03-01T14:00:00Z",
+		}, "\n")
+
+		report, err := service.ImportSettlement(context.Background(), "mock", today, strings.NewReader(file))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if report.TotalLines != 6 || report.Matched != 1 || report.Corrected != 1 {
+			t.Fatalf("unexpected totals: %+v", report)
+		}
+		found := make(map[DiscrepancyType][]string)
+		for _, d := range report.Discrepancies {
+			found[d.Type] = append(found[d.Type], d.ReferenceNumber)
+		}
+		expected := map[DiscrepancyType]string{
+			DiscrepancyStatusCorrected:  "MISSED",
+			DiscrepancyAmountMismatch:   "SHORT",
+			DiscrepancyUnknownReference: "UNKNOWN",
+			DiscrepancyDuplicateLine:    "MATCHED",
+			DiscrepancyInvalidLine:      "BROKEN",
+			DiscrepancyNotSettled:       "UNSETTLED",
+		}
+		for typ, ref := range expected {
+			if len(found[typ]) != 1 || found[typ][0] != ref {
+				t.Errorf("expected %s discrepancy for %s, got %v", typ, ref, found[typ])
+			}
+		}
+
+		if repo.txs["MISSED"].Status != PaymentStatusSuccess {
+			t.Fatalf("expected MISSED to be corrected to SUCCESS, got %s", repo.txs["MISSED"].Status)
+		}
+		if repo.txs["SHORT"].Status != PaymentStatusPending {
+			t.Fatalf("expected SHORT to stay PENDING, got %s", repo.txs["SHORT"].Status)
+		}
+		if len(*events) != 1 || (*events)[0].Data.ReferenceNumber != "MISSED" || (*events)[0].Data.Source != StatusSourceSettlement {
+			t.Fatalf("expected one settlement event for MISSED, got %+v", *events)
+		}
+		if repo.reports[report.ID] == nil {
+			t.Fatal("expected report to be persisted")
+		}
+	})
+
+	t.Run("missing required column", func(t *testing.T) {
+		service, _, _ := newReconcileFixture(&stubProvider{})
+		file := "reference_number,amount\nMATCHED,100.00\n"
+
+		if _, err := service.ImportSettlement(context.Background(), "mock", today, strings.NewReader(file)); err == nil {
+			t.Fatal("expected error for missing currency column, got nil")
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		service, _, _ := newReconcileFixture(&stubProvider{})
+		file := "reference_number,amount,currency\n"
+
+		if _, err := service.ImportSettlement(context.Background(), "unknown", today, strings.NewReader(file)); err == nil {
+			t.Fatal("expected error for unknown provider, got nil")
+		}
+	})
+}
+
+// signallingService reports each Reconcile call on a channel.
+type signallingService struct {
+	PaymentService
+	calls chan struct{}
+}
+
+func (s *signallingService) Reconcile(ctx context.Context) (*ReconciliationResult, error) {
+	select {
+	case s.calls <- struct{}{}:
+	default:
+	}
+	return &ReconciliationResult{}, nil
+}
+
+func TestReconciliationWorker(t *testing.T) {
+	service := &signallingService{calls: make(chan struct{}, 1)}
+	worker := NewReconciliationWorker(service, 10*time.Millisecond)
+	worker.Start(context.Background())
+
+	select {
+	case <-service.calls:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the worker to run a reconciliation pass")
+	}
+
+	worker.Stop()
+	worker.Stop() // idempotent
+}
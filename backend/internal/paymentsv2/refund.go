@@ -0,0 +1,399 @@
+package paymentsv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrTransactionNotFound is returned when no transaction exists for a reference number.
+	ErrTransactionNotFound = errors.New("payment transaction not found")
+	// ErrInvalidRefund is returned when a refund request is incomplete or malformed.
+	ErrInvalidRefund = errors.New("invalid refund request")
+	// ErrRefundNotAllowed is returned when the transaction is not in a refundable status.
+	ErrRefundNotAllowed = errors.New("payment is not refundable")
+	// ErrRefundExceedsBalance is returned when the amount is above what remains refundable.
+	ErrRefundExceedsBalance = errors.New("refund amount exceeds refundable balance")
+	// ErrRefundRejected is returned when the gateway declined the refund. The attempt is
+	// still recorded in the ledger.
+	ErrRefundRejected = errors.New("refund rejected by payment provider")
+	// ErrRefundNotFound is returned when a transaction has no refund with the given ID.
+	ErrRefundNotFound = errors.New("refund not found")
+	// ErrRefundNotPending is returned when a refund has already been decided or finalised.
+	ErrRefundNotPending = errors.New("refund is not awaiting a decision")
+)
+
+// RequestRefund records a request to return all or part of a confirmed payment. The
+// amount is reserved against the refundable balance at once, but nothing is sent to
+// the gateway until a second refund officer approves it with ApproveRefund.
+func (s *paymentService) RequestRefund(ctx context.Context, req RefundRequest) (*PaymentRefund, error) {
+	if err := validateRefundRequest(req); err != nil {
+		return nil, err
+	}
+
+	var refund *PaymentRefund
+	err := s.repo.RunInTransaction(ctx, func(repo PaymentRepository) error {
+		tx, err := lockRefundableTransaction(ctx, repo, req.ReferenceNumber)
+		if err != nil {
+			return err
+		}
+
+		reserved, err := reservedAmount(ctx, repo, tx.ID)
+		if err != nil {
+			return err
+		}
+		balance := tx.Amount.Sub(reserved)
+		amount := balance
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+		if !amount.IsPositive() || amount.GreaterThan(balance) {
+			return fmt.Errorf("%w: requested %s, refundable %s %s", ErrRefundExceedsBalance, amount.StringFixed(2), balance.StringFixed(2), tx.Currency)
+		}
+
+		refund = &PaymentRefund{
+			ID:              uuid.NewString(),
+			TransactionID:   tx.ID,
+			ReferenceNumber: tx.ReferenceNumber,
+			Amount:          amount,
+			Currency:        tx.Currency,
+			ReasonCode:      req.ReasonCode,
+			ReasonNote:      req.ReasonNote,
+			RequestedBy:     req.RequestedBy,
+			Status:          RefundStatusPendingApproval,
+		}
+		if err := repo.CreateRefund(ctx, refund); err != nil {
+			return fmt.Errorf("failed to record refund: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "payment refund requested",
+		"reference", refund.ReferenceNumber, "refund_id", refund.ID, "amount", refund.Amount.StringFixed(2),
+		"reason", refund.ReasonCode, "requested_by", refund.RequestedBy)
+	return refund, nil
+}
+
+// ApproveRefund approves a requested refund and sends it to the provider that captured
+// the payment. The approver must not be the requester.
+//
+// The approval is committed, with the refund PENDING, before the gateway is called, and
+// the gateway's answer is recorded in a second transaction. No row is locked while the
+// gateway is called. A refund left PENDING by a failure in between is sent again, under
+// the same ID, when it is approved again; providers must treat the ID as idempotency key.
+// A successful refund marks the transaction's event pending in the transaction that
+// records it, and the owning task is notified once it has been committed; an event the
+// task fails to receive is delivered again by reconciliation.
+func (s *paymentService) ApproveRefund(ctx context.Context, d RefundDecision) (*PaymentRefund, error) {
+	if d.DecidedBy == "" {
+		return nil, fmt.Errorf("%w: approver is unknown", ErrInvalidRefund)
+	}
+
+	var (
+		refund *PaymentRefund
+		tx     *PaymentTransaction
+	)
+	err := s.repo.RunInTransaction(ctx, func(repo PaymentRepository) error {
+		var err error
+		tx, refund, err = lockRefund(ctx, repo, d)
+		if err != nil {
+			return err
+		}
+		if d.DecidedBy == refund.RequestedBy {
+			return fmt.Errorf("%w: a refund cannot be approved by its requester", ErrInvalidRefund)
+		}
+		switch refund.Status {
+		case RefundStatusPendingApproval:
+			refund.ApprovedBy = d.DecidedBy
+			refund.Status = RefundStatusPending
+			return updateRefund(ctx, repo, refund, RefundStatusPendingApproval)
+		case RefundStatusPending:
+			// Approved before, but the outcome was never recorded: send it again.
+			return nil
+		default:
+			return fmt.Errorf("%w: refund %s is %s", ErrRefundNotPending, refund.ID, refund.Status)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// A method disabled since the payment can still refund what it captured.
+	provider, err := s.registry.Provider(tx.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s not available: %w", tx.ProviderID, err)
+	}
+	resp, providerErr := provider.Refund(ctx, ProviderRefundRequest{
+		RefundID:             refund.ID,
+		ReferenceNumber:      tx.ReferenceNumber,
+		SessionID:            tx.SessionID,
+		GatewayTransactionID: tx.GatewayMetadata["gateway_transaction_id"],
+		Amount:               refund.Amount,
+		Currency:             refund.Currency,
+		Reason:               string(refund.ReasonCode),
+	})
+
+	var previous PaymentStatus
+	err = s.repo.RunInTransaction(ctx, func(repo PaymentRepository) error {
+		var err error
+		tx, refund, err = lockRefund(ctx, repo, d)
+		if err != nil {
+			return err
+		}
+		if refund.Status != RefundStatusPending {
+			// Finalised by a concurrent approval that sent the same refund.
+			return nil
+		}
+		if providerErr != nil {
+			refund.Status = RefundStatusFailed
+			refund.FailureReason = providerErr.Error()
+			return updateRefund(ctx, repo, refund, RefundStatusPending)
+		}
+
+		refund.Status = RefundStatusSucceeded
+		refund.GatewayRefundID = resp.GatewayRefundID
+		if err := updateRefund(ctx, repo, refund, RefundStatusPending); err != nil {
+			return err
+		}
+
+		refunded, err := refundedAmount(ctx, repo, tx.ID)
+		if err != nil {
+			return err
+		}
+		previous = tx.Status
+		tx.Status = PaymentStatusPartiallyRefunded
+		if refunded.GreaterThanOrEqual(tx.Amount) {
+			tx.Status = PaymentStatusRefunded
+		}
+		if tx.GatewayMetadata == nil {
+			tx.GatewayMetadata = make(map[string]string)
+		}
+		tx.GatewayMetadata["refunded_amount"] = refunded.StringFixed(2)
+		tx.EventPending = true
+		updated, err := repo.CompareAndUpdate(ctx, tx, previous)
+		if err != nil {
+			return fmt.Errorf("failed to update payment transaction status: %w", err)
+		}
+		if !updated {
+			return fmt.Errorf("payment transaction %s changed while refunding", tx.ReferenceNumber)
+		}
+		return s.recordStatusChange(ctx, repo, tx, previous, StatusSourceRefund, refund)
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "refund outcome not recorded, approve the refund again to resend it",
+			"reference", d.ReferenceNumber, "refund_id", d.RefundID, "gateway_error", providerErr, "error", err)
+		return nil, err
+	}
+
+	switch {
+	case refund.Status == RefundStatusFailed:
+		slog.WarnContext(ctx, "payment refund rejected by provider", "reference", refund.ReferenceNumber, "refund_id", refund.ID, "reason", refund.FailureReason)
+		return refund, fmt.Errorf("%w: %s", ErrRefundRejected, refund.FailureReason)
+	case previous == "":
+		// Nothing was changed by this call.
+		return refund, nil
+	}
+
+	slog.InfoContext(ctx, "payment refunded",
+		"reference", refund.ReferenceNumber, "refund_id", refund.ID, "amount", refund.Amount.StringFixed(2),
+		"reason", refund.ReasonCode, "requested_by", refund.RequestedBy, "approved_by", refund.ApprovedBy)
+
+	// The money has been returned; a task that cannot record it must not fail the refund.
+	// The event stays pending for reconciliation.
+	if err := s.deliver(ctx, tx); err != nil {
+		slog.ErrorContext(ctx, "refund not delivered to task, reconciliation will retry", "reference", refund.ReferenceNumber, "refund_id", refund.ID, "error", err)
+	}
+	return refund, nil
+}
+
+// RejectRefund declines a requested refund and releases the amount it reserved.
+func (s *paymentService) RejectRefund(ctx context.Context, d RefundDecision) (*PaymentRefund, error) {
+	if d.DecidedBy == "" {
+		return nil, fmt.Errorf("%w: officer is unknown", ErrInvalidRefund)
+	}
+
+	var refund *PaymentRefund
+	err := s.repo.RunInTransaction(ctx, func(repo PaymentRepository) error {
+		var err error
+		_, refund, err = lockRefund(ctx, repo, d)
+		if err != nil {
+			return err
+		}
+		if refund.Status != RefundStatusPendingApproval {
+			return fmt.Errorf("%w: refund %s is %s", ErrRefundNotPending, refund.ID, refund.Status)
+		}
+		refund.RejectedBy = d.DecidedBy
+		refund.Status = RefundStatusRejected
+		return updateRefund(ctx, repo, refund, RefundStatusPendingApproval)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "payment refund rejected", "reference", refund.ReferenceNumber, "refund_id", refund.ID, "rejected_by", refund.RejectedBy)
+	return refund, nil
+}
+
+// lockRefundableTransaction locks the transaction of referenceNumber and checks that
+// it has been paid and not yet refunded in full.
+func lockRefundableTransaction(ctx context.Context, repo PaymentRepository, referenceNumber string) (*PaymentTransaction, error) {
+	tx, err := repo.GetByReferenceNumberForUpdate(ctx, referenceNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve payment by reference: %w", err)
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTransactionNotFound, referenceNumber)
+	}
+	if tx.Status != PaymentStatusSuccess && tx.Status != PaymentStatusPartiallyRefunded {
+		return nil, fmt.Errorf("%w: status is %s", ErrRefundNotAllowed, tx.Status)
+	}
+	return tx, nil
+}
+
+// lockRefund locks the transaction a decision is about and reads the refund decided on.
+// Refunds of a transaction only change while its row is locked.
+func lockRefund(ctx context.Context, repo PaymentRepository, d RefundDecision) (*PaymentTransaction, *PaymentRefund, error) {
+	tx, err := repo.GetByReferenceNumberForUpdate(ctx, d.ReferenceNumber)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve payment by reference: %w", err)
+	}
+	if tx == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrTransactionNotFound, d.ReferenceNumber)
+	}
+	refund, err := repo.GetRefund(ctx, d.RefundID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read refund: %w", err)
+	}
+	if refund == nil || refund.TransactionID != tx.ID {
+		return nil, nil, fmt.Errorf("%w: %s", ErrRefundNotFound, d.RefundID)
+	}
+	return tx, refund, nil
+}
+
+// updateRefund saves refund, which must still be in status expected.
+func updateRefund(ctx context.Context, repo PaymentRepository, refund *PaymentRefund, expected RefundStatus) error {
+	updated, err := repo.UpdateRefund(ctx, refund, expected)
+	if err != nil {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+	if !updated {
+		return fmt.Errorf("%w: refund %s changed concurrently", ErrRefundNotPending, refund.ID)
+	}
+	return nil
+}
+
+// ListRefunds returns the refund ledger of a transaction.
+func (s *paymentService) ListRefunds(ctx context.Context, referenceNumber string) ([]PaymentRefund, error) {
+	tx, err := s.GetTransaction(ctx, referenceNumber)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListRefunds(ctx, tx.ID)
+}
+
+// GetTransaction returns the transaction of referenceNumber, or ErrTransactionNotFound.
+func (s *paymentService) GetTransaction(ctx context.Context, referenceNumber string) (*PaymentTransaction, error) {
+	tx, err := s.repo.GetByReferenceNumber(ctx, referenceNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve payment by reference: %w", err)
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTransactionNotFound, referenceNumber)
+	}
+	return tx, nil
+}
+
+// emitRefunds notifies the registered EventHandler of every successful refund of tx. The
+// task acknowledges a refund it has already recorded as a repeat.
+func (s *paymentService) emitRefunds(ctx context.Context, tx *PaymentTransaction) error {
+	refunds, err := s.repo.ListRefunds(ctx, tx.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read refund ledger: %w", err)
+	}
+	for i := range refunds {
+		if refunds[i].Status != RefundStatusSucceeded {
+			continue
+		}
+		if err := s.emitRefund(ctx, tx, &refunds[i]); err != nil {
+			return fmt.Errorf("failed to deliver %s event %s for task %s: %w", PaymentEventRefunded, refunds[i].ID, tx.TaskID, err)
+		}
+	}
+	return nil
+}
+
+// emitRefund notifies the registered EventHandler of a committed refund.
+func (s *paymentService) emitRefund(ctx context.Context, tx *PaymentTransaction, refund *PaymentRefund) error {
+	amount := refund.Amount
+	return s.eventHandler(ctx, InternalPaymentEvent{
+		EventType: PaymentEventRefunded,
+		Data: EventData{
+			TaskID:          tx.TaskID,
+			ReferenceNumber: tx.ReferenceNumber,
+			Status:          tx.Status,
+			Currency:        tx.Currency,
+			Source:          StatusSourceRefund,
+			RefundID:        refund.ID,
+			RefundedAmount:  &amount,
+		},
+	})
+}
+
+// refundedAmount sums the successful refunds of a transaction.
+func refundedAmount(ctx context.Context, repo PaymentRepository, transactionID string) (decimal.Decimal, error) {
+	return sumRefunds(ctx, repo, transactionID, func(status RefundStatus) bool {
+		return status == RefundStatusSucceeded
+	})
+}
+
+// reservedAmount sums the refunds of a transaction that succeeded or may still succeed.
+func reservedAmount(ctx context.Context, repo PaymentRepository, transactionID string) (decimal.Decimal, error) {
+	return sumRefunds(ctx, repo, transactionID, func(status RefundStatus) bool {
+		return status != RefundStatusFailed && status != RefundStatusRejected
+	})
+}
+
+func sumRefunds(ctx context.Context, repo PaymentRepository, transactionID string, include func(RefundStatus) bool) (decimal.Decimal, error) {
+	refunds, err := repo.ListRefunds(ctx, transactionID)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to read refund ledger: %w", err)
+	}
+	total := decimal.Zero
+	for _, r := range refunds {
+		if include(r.Status) {
+			total = total.Add(r.Amount)
+		}
+	}
+	return total, nil
+}
+
+// validateRefundRequest checks the fields a refund needs before any row is locked.
+func validateRefundRequest(req RefundRequest) error {
+	switch req.ReasonCode {
+	case RefundReasonOGARejected, RefundReasonConsignmentCancelled, RefundReasonDuplicatePayment, RefundReasonOvercharge:
+	case RefundReasonOther:
+		if strings.TrimSpace(req.ReasonNote) == "" {
+			return fmt.Errorf("%w: reason_note is required for reason_code %s", ErrInvalidRefund, RefundReasonOther)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported reason_code %q", ErrInvalidRefund, req.ReasonCode)
+	}
+	if req.ReferenceNumber == "" {
+		return fmt.Errorf("%w: reference number is required", ErrInvalidRefund)
+	}
+	if req.RequestedBy == "" {
+		return fmt.Errorf("%w: requester is unknown", ErrInvalidRefund)
+	}
+	if req.Amount != nil && !req.Amount.IsPositive() {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidRefund)
+	}
+	return nil
+}
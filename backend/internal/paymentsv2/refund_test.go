@@ -0,0 +1,436 @@
+package paymentsv2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+func refundingProvider() *stubProvider {
+	return &stubProvider{
+		refundFn: func(ctx context.Context, req ProviderRefundRequest) (*ProviderRefundResponse, error) {
+			return &ProviderRefundResponse{GatewayRefundID: "GW-" + req.RefundID}, nil
+		},
+	}
+}
+
+func newRefundFixture(provider PaymentProvider) (*paymentService, *mockRepository, *[]InternalPaymentEvent) {
+	service, repo, events := newReconcileFixture(provider)
+	addTransaction(repo, "PAID", PaymentStatusSuccess, time.Now().Add(-time.Hour))
+	return service, repo, events
+}
+
+func refundRequest(amount string) RefundRequest {
+	req := RefundRequest{
+		ReferenceNumber: "PAID",
+		ReasonCode:      RefundReasonOGARejected,
+		RequestedBy:     "officer-1",
+	}
+	if amount != "" {
+		d := decimal.RequireFromString(amount)
+		req.Amount = &d
+	}
+	return req
+}
+
+func approval(refund *PaymentRefund, officer string) RefundDecision {
+	return RefundDecision{ReferenceNumber: refund.ReferenceNumber, RefundID: refund.ID, DecidedBy: officer}
+}
+
+// requestAndApprove requests a refund of amount and has it approved by supervisor-1.
+func requestAndApprove(t *testing.T, service *paymentService, amount string) (*PaymentRefund, error) {
+	t.Helper()
+	refund, err := service.RequestRefund(context.Background(), refundRequest(amount))
+	if err != nil {
+		t.Fatalf("expected refund request to be recorded, got %v", err)
+	}
+	return service.ApproveRefund(context.Background(), approval(refund, "supervisor-1"))
+}
+
+func TestRefundPayment(t *testing.T) {
+	t.Run("full refund", func(t *testing.T) {
+		service, repo, events := newRefundFixture(refundingProvider())
+
+		requested, err := service.RequestRefund(context.Background(), refundRequest(""))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if requested.Status != RefundStatusPendingApproval || requested.ApprovedBy != "" || len(*events) != 0 {
+			t.Fatalf("expected refund awaiting approval, got %+v", requested)
+		}
+
+		refund, err := service.ApproveRefund(context.Background(), approval(requested, "supervisor-1"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !refund.Amount.Equal(decimal.NewFromInt(100)) || refund.Status != RefundStatusSucceeded || refund.GatewayRefundID != "GW-"+refund.ID || refund.ApprovedBy != "supervisor-1" {
+			t.Fatalf("unexpected refund: %+v", refund)
+		}
+		if repo.txs["PAID"].Status != PaymentStatusRefunded {
+			t.Fatalf("expected REFUNDED, got %s", repo.txs["PAID"].Status)
+		}
+		// The confirmation is repeated ahead of the refund; the task acknowledges it.
+		if len(*events) != 2 || (*events)[0].EventType != PaymentEventConfirmed ||
+			(*events)[1].EventType != PaymentEventRefunded || (*events)[1].Data.RefundID != refund.ID {
+			t.Fatalf("expected PAYMENT_CONFIRMED then PAYMENT_REFUNDED, got %+v", *events)
+		}
+		if repo.txs["PAID"].EventPending {
+			t.Fatal("expected the delivered refund event to be cleared")
+		}
+	})
+
+	t.Run("undelivered refund is redelivered by reconciliation", func(t *testing.T) {
+		service, repo, _ := newRefundFixture(refundingProvider())
+		var events []InternalPaymentEvent
+		down := true
+		service.RegisterEventHandler(func(ctx context.Context, event InternalPaymentEvent) error {
+			if down {
+				return errors.New("task manager unavailable")
+			}
+			events = append(events, event)
+			return nil
+		})
+
+		refund, err := requestAndApprove(t, service, "40")
+		if err != nil {
+			t.Fatalf("expected the refund to succeed without its task, got %v", err)
+		}
+		if !repo.txs["PAID"].EventPending {
+			t.Fatal("expected the refund event to stay pending")
+		}
+
+		down = false
+		repo.txs["PAID"].UpdatedAt = time.Now().Add(-time.Hour)
+		result, err := service.Reconcile(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result.Redelivered != 1 || repo.txs["PAID"].EventPending {
+			t.Fatalf("expected the refund event to be redelivered and cleared, got %+v", result)
+		}
+		last := events[len(events)-1]
+		if last.EventType != PaymentEventRefunded || last.Data.RefundID != refund.ID || !last.Data.RefundedAmount.Equal(decimal.NewFromInt(40)) {
+			t.Fatalf("expected the refund to be redelivered, got %+v", events)
+		}
+	})
+
+	t.Run("partial refunds until balance is exhausted", func(t *testing.T) {
+		service, repo, _ := newRefundFixture(refundingProvider())
+
+		if _, err := requestAndApprove(t, service, "40"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if repo.txs["PAID"].Status != PaymentStatusPartiallyRefunded {
+			t.Fatalf("expected PARTIALLY_REFUNDED, got %s", repo.txs["PAID"].Status)
+		}
+		if got := repo.txs["PAID"].GatewayMetadata["refunded_amount"]; got != "40.00" {
+			t.Fatalf("expected refunded_amount 40.00, got %q", got)
+		}
+
+		_, err := service.RequestRefund(context.Background(), refundRequest("60.01"))
+		if !errors.Is(err, ErrRefundExceedsBalance) {
+			t.Fatalf("expected ErrRefundExceedsBalance, got %v", err)
+		}
+
+		refund, err := requestAndApprove(t, service, "")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !refund.Amount.Equal(decimal.NewFromInt(60)) || repo.txs["PAID"].Status != PaymentStatusRefunded {
+			t.Fatalf("expected remaining 60 refunded in full, got %s and %s", refund.Amount, repo.txs["PAID"].Status)
+		}
+
+		if _, err := service.RequestRefund(context.Background(), refundRequest("")); !errors.Is(err, ErrRefundNotAllowed) {
+			t.Fatalf("expected ErrRefundNotAllowed on a refunded payment, got %v", err)
+		}
+	})
+
+	t.Run("requested refunds reserve the balance until rejected", func(t *testing.T) {
+		service, _, _ := newRefundFixture(refundingProvider())
+
+		pending, err := service.RequestRefund(context.Background(), refundRequest("70"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := service.RequestRefund(context.Background(), refundRequest("40")); !errors.Is(err, ErrRefundExceedsBalance) {
+			t.Fatalf("expected ErrRefundExceedsBalance while 70 is reserved, got %v", err)
+		}
+
+		rejected, err := service.RejectRefund(context.Background(), approval(pending, "supervisor-1"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if rejected.Status != RefundStatusRejected || rejected.RejectedBy != "supervisor-1" {
+			t.Fatalf("unexpected rejected refund: %+v", rejected)
+		}
+		if _, err := service.ApproveRefund(context.Background(), approval(pending, "supervisor-1")); !errors.Is(err, ErrRefundNotPending) {
+			t.Fatalf("expected ErrRefundNotPending for a rejected refund, got %v", err)
+		}
+		if _, err := service.RequestRefund(context.Background(), refundRequest("40")); err != nil {
+			t.Fatalf("expected the rejected amount to be released, got %v", err)
+		}
+	})
+
+	t.Run("requester cannot approve", func(t *testing.T) {
+		service, repo, _ := newRefundFixture(refundingProvider())
+
+		refund, err := service.RequestRefund(context.Background(), refundRequest(""))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := service.ApproveRefund(context.Background(), approval(refund, "officer-1")); !errors.Is(err, ErrInvalidRefund) {
+			t.Fatalf("expected ErrInvalidRefund, got %v", err)
+		}
+		if repo.refunds[0].Status != RefundStatusPendingApproval {
+			t.Fatalf("expected refund to stay PENDING_APPROVAL, got %s", repo.refunds[0].Status)
+		}
+	})
+
+	t.Run("gateway is called outside the database transaction", func(t *testing.T) {
+		var repo *mockRepository
+		provider := &stubProvider{
+			refundFn: func(ctx context.Context, req ProviderRefundRequest) (*ProviderRefundResponse, error) {
+				if repo.inTransaction {
+					t.Error("gateway called while the transaction row was locked")
+				}
+				if len(repo.refunds) != 1 || repo.refunds[0].Status != RefundStatusPending {
+					t.Errorf("expected the refund to be committed as PENDING before the gateway call, got %+v", repo.refunds)
+				}
+				return &ProviderRefundResponse{GatewayRefundID: "GW-" + req.RefundID}, nil
+			},
+		}
+		var service *paymentService
+		service, repo, _ = newRefundFixture(provider)
+
+		if _, err := requestAndApprove(t, service, "10"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("refund left pending is resent on approval", func(t *testing.T) {
+		var sent []string
+		provider := &stubProvider{
+			refundFn: func(ctx context.Context, req ProviderRefundRequest) (*ProviderRefundResponse, error) {
+				sent = append(sent, req.RefundID)
+				return &ProviderRefundResponse{GatewayRefundID: "GW-" + req.RefundID}, nil
+			},
+		}
+		service, repo, _ := newRefundFixture(provider)
+		refund, err := service.RequestRefund(context.Background(), refundRequest("10"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		// Approved, but the process stopped before the gateway's answer was recorded.
+		repo.refunds[0].Status = RefundStatusPending
+		repo.refunds[0].ApprovedBy = "supervisor-1"
+
+		resent, err := service.ApproveRefund(context.Background(), approval(refund, "supervisor-2"))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resent.Status != RefundStatusSucceeded || resent.ApprovedBy != "supervisor-1" || len(sent) != 1 || sent[0] != refund.ID {
+			t.Fatalf("expected the refund to be resent under its ID, got %+v, sent %v", resent, sent)
+		}
+	})
+
+	t.Run("provider rejection is recorded", func(t *testing.T) {
+		provider := &stubProvider{
+			refundFn: func(ctx context.Context, req ProviderRefundRequest) (*ProviderRefundResponse, error) {
+				return nil, errors.New("insufficient merchant balance")
+			},
+		}
+		service, repo, events := newRefundFixture(provider)
+
+		refund, err := requestAndApprove(t, service, "10")
+		if !errors.Is(err, ErrRefundRejected) {
+			t.Fatalf("expected ErrRefundRejected, got %v", err)
+		}
+		if refund == nil || refund.Status != RefundStatusFailed {
+			t.Fatalf("expected failed ledger entry, got %+v", refund)
+		}
+		if len(repo.refunds) != 1 || repo.txs["PAID"].Status != PaymentStatusSuccess || len(*events) != 0 {
+			t.Fatalf("expected only a failed ledger entry, got refunds=%d status=%s events=%d", len(repo.refunds), repo.txs["PAID"].Status, len(*events))
+		}
+		if _, err := service.RequestRefund(context.Background(), refundRequest("100")); err != nil {
+			t.Fatalf("expected a failed refund not to reserve the balance, got %v", err)
+		}
+	})
+
+	t.Run("pending payment is not refundable", func(t *testing.T) {
+		service, repo, _ := newRefundFixture(refundingProvider())
+		repo.txs["PAID"].Status = PaymentStatusPending
+
+		if _, err := service.RequestRefund(context.Background(), refundRequest("")); !errors.Is(err, ErrRefundNotAllowed) {
+			t.Fatalf("expected ErrRefundNotAllowed, got %v", err)
+		}
+	})
+
+	t.Run("unknown reference", func(t *testing.T) {
+		service, _, _ := newRefundFixture(refundingProvider())
+		req := refundRequest("")
+		req.ReferenceNumber = "MISSING"
+
+		if _, err := service.RequestRefund(context.Background(), req); !errors.Is(err, ErrTransactionNotFound) {
+			t.Fatalf("expected ErrTransactionNotFound, got %v", err)
+		}
+	})
+
+	t.Run("unknown refund", func(t *testing.T) {
+		service, _, _ := newRefundFixture(refundingProvider())
+
+		_, err := service.ApproveRefund(context.Background(), RefundDecision{ReferenceNumber: "PAID", RefundID: "missing", DecidedBy: "supervisor-1"})
+		if !errors.Is(err, ErrRefundNotFound) {
+			t.Fatalf("expected ErrRefundNotFound, got %v", err)
+		}
+	})
+
+	invalid := []struct {
+		name   string
+		mutate func(*RefundRequest)
+	}{
+		{"missing requester", func(r *RefundRequest) { r.RequestedBy = "" }},
+		{"unknown reason", func(r *RefundRequest) { r.ReasonCode = "CHANGED_MIND" }},
+		{"other without note", func(r *RefundRequest) { r.ReasonCode = RefundReasonOther }},
+		{"zero amount", func(r *RefundRequest) { zero := decimal.Zero; r.Amount = &zero }},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			service, repo, _ := newRefundFixture(refundingProvider())
+			req := refundRequest("")
+			tc.mutate(&req)
+
+			if _, err := service.RequestRefund(context.Background(), req); !errors.Is(err, ErrInvalidRefund) {
+				t.Fatalf("expected ErrInvalidRefund, got %v", err)
+			}
+			if len(repo.refunds) != 0 {
+				t.Fatal("expected no ledger entry for an invalid request")
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_HandleRefund(t *testing.T) {
+	withUser := func(req *http.Request, userID string, roles ...string) *http.Request {
+		if userID == "" {
+			return req
+		}
+		authCtx := &auth.AuthContext{User: &auth.UserContext{ID: userID, Roles: roles}}
+		return req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, authCtx))
+	}
+	newHandler := func() (*HTTPHandler, *mockRepository) {
+		service, repo, _ := newRefundFixture(refundingProvider())
+		return NewHTTPHandler(service, nil, nil), repo
+	}
+
+	requests := []struct {
+		name   string
+		userID string
+		roles  []string
+		body   string
+		want   int
+	}{
+		{"refund requested by finance officer", "officer-1", []string{auth.RoleFinance}, `{"amount":"25","reason_code":"OVERCHARGE"}`, http.StatusCreated},
+		{"refund requested by administrator", "admin-1", []string{auth.RoleAdmin}, `{"reason_code":"OVERCHARGE"}`, http.StatusCreated},
+		{"trader cannot request refund", "trader-1", []string{auth.RoleTrader}, `{"reason_code":"OVERCHARGE"}`, http.StatusForbidden},
+		{"exceeds balance", "officer-1", []string{auth.RoleFinance}, `{"amount":"250","reason_code":"OVERCHARGE"}`, http.StatusConflict},
+		{"unauthenticated", "", nil, `{"reason_code":"OVERCHARGE"}`, http.StatusUnauthorized},
+	}
+	for _, tc := range requests {
+		t.Run(tc.name, func(t *testing.T) {
+			h, _ := newHandler()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/payments/transactions/PAID/refunds", bytes.NewReader([]byte(tc.body)))
+			req.SetPathValue("referenceNumber", "PAID")
+			rr := httptest.NewRecorder()
+			h.HandleRefund(rr, withUser(req, tc.userID, tc.roles...))
+			if rr.Code != tc.want {
+				t.Fatalf("expected status %d, got %d: %s", tc.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	decisions := []struct {
+		name   string
+		userID string
+		roles  []string
+		want   int
+	}{
+		{"approved by second officer", "supervisor-1", []string{auth.RoleFinance}, http.StatusOK},
+		{"requester cannot approve", "officer-1", []string{auth.RoleFinance}, http.StatusBadRequest},
+		{"trader cannot approve", "trader-1", []string{auth.RoleTrader}, http.StatusForbidden},
+	}
+	for _, tc := range decisions {
+		t.Run(tc.name, func(t *testing.T) {
+			h, repo := newHandler()
+			refund, err := h.service.RequestRefund(context.Background(), refundRequest("25"))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/payments/transactions/PAID/refunds/"+refund.ID+"/approve", nil)
+			req.SetPathValue("referenceNumber", "PAID")
+			req.SetPathValue("refundId", refund.ID)
+			rr := httptest.NewRecorder()
+			h.HandleApproveRefund(rr, withUser(req, tc.userID, tc.roles...))
+			if rr.Code != tc.want {
+				t.Fatalf("expected status %d, got %d: %s", tc.want, rr.Code, rr.Body.String())
+			}
+			if tc.want == http.StatusOK && repo.refunds[0].ApprovedBy != tc.userID {
+				t.Fatalf("expected approver %s to be recorded, got %q", tc.userID, repo.refunds[0].ApprovedBy)
+			}
+		})
+	}
+}
+
+// taskAccessFunc adapts a function to TaskAccess.
+type taskAccessFunc func(ctx context.Context, taskID string) (bool, error)
+
+func (f taskAccessFunc) MayReadTask(ctx context.Context, taskID string) (bool, error) {
+	return f(ctx, taskID)
+}
+
+func TestHTTPHandler_HandleListRefunds(t *testing.T) {
+	// Only trader-1 is a party of the paying task.
+	access := taskAccessFunc(func(ctx context.Context, taskID string) (bool, error) {
+		authCtx := auth.GetAuthContext(ctx)
+		return taskID == "task-PAID" && authCtx.User.ID == "trader-1", nil
+	})
+
+	tests := []struct {
+		name    string
+		authCtx *auth.AuthContext
+		ref     string
+		access  TaskAccess
+		want    int
+	}{
+		{"party of the task", &auth.AuthContext{User: &auth.UserContext{ID: "trader-1", Roles: []string{auth.RoleTrader}}}, "PAID", access, http.StatusOK},
+		{"other trader", &auth.AuthContext{User: &auth.UserContext{ID: "trader-2", Roles: []string{auth.RoleTrader}}}, "PAID", access, http.StatusForbidden},
+		{"finance officer", &auth.AuthContext{User: &auth.UserContext{ID: "officer-1", Roles: []string{auth.RoleFinance}}}, "PAID", access, http.StatusOK},
+		{"client", &auth.AuthContext{Client: &auth.ClientContext{ClientID: "oga-portal"}}, "PAID", access, http.StatusOK},
+		{"unknown transaction", &auth.AuthContext{User: &auth.UserContext{ID: "trader-1"}}, "MISSING", access, http.StatusNotFound},
+		{"no task access configured", &auth.AuthContext{User: &auth.UserContext{ID: "trader-1"}}, "PAID", nil, http.StatusForbidden},
+		{"unauthenticated", nil, "PAID", access, http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _, _ := newRefundFixture(refundingProvider())
+			h := NewHTTPHandler(service, nil, tc.access)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/payments/transactions/"+tc.ref+"/refunds", nil)
+			req.SetPathValue("referenceNumber", tc.ref)
+			if tc.authCtx != nil {
+				req = req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, tc.authCtx))
+			}
+			rr := httptest.NewRecorder()
+			h.HandleListRefunds(rr, req)
+			if rr.Code != tc.want {
+				t.Fatalf("expected status %d, got %d: %s", tc.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package paymentsv2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrProviderNotFound is returned when no payment method is configured for an ID.
+	ErrProviderNotFound = errors.New("payment provider not found")
+	// ErrProviderInactive is returned when a payment method exists but is disabled.
+	ErrProviderInactive = errors.New("payment provider is not active")
+)
+
+// PaymentMethodConfig is a single entry of payment_methods.json.
+type PaymentMethodConfig struct {
+	ID         string            `json:"id"`
+	IsActive   bool              `json:"is_active"`
+	RenderInfo PaymentRenderInfo `json:"render_info"`
+	Type       string            `json:"type"`
+	GatewayURL string            `json:"gateway_url"`
+	Webhook    WebhookAuthConfig `json:"webhook"`
+}
+
+// PaymentMethodsConfig is the root document of payment_methods.json.
+type PaymentMethodsConfig struct {
+	Version string                `json:"version"`
+	Methods []PaymentMethodConfig `json:"methods"`
+}
+
+// Registry is a file-backed PaymentRegistry.
+// Method metadata is read from a JSON file and can be refreshed at runtime with Reload;
+// provider implementations are supplied in code and never change after construction.
+type Registry struct {
+	path      string
+	providers map[string]PaymentProvider
+
+	mu        sync.RWMutex
+	methods   []PaymentMethodConfig // sorted by display order
+	verifiers map[string]WebhookVerifier
+}
+
+// NewRegistry loads the payment methods file at path and binds each method ID to
+// its provider implementation. Every active method must have a provider and a way
+// to authenticate its webhooks.
+func NewRegistry(path string, providers map[string]PaymentProvider) (*Registry, error) {
+	r := &Registry{
+		path:      path,
+		providers: providers,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the payment methods file. If the file cannot be read or fails
+// validation, the previously loaded configuration is kept and an error is returned.
+func (r *Registry) Reload() error {
+	raw, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to read payment methods file %s: %w", r.path, err)
+	}
+
+	var cfg PaymentMethodsConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return fmt.Errorf("failed to parse payment methods file %s: %w", r.path, err)
+	}
+
+	methods, err := r.validate(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid payment methods file %s: %w", r.path, err)
+	}
+	verifiers, err := r.buildVerifiers(methods)
+	if err != nil {
+		return fmt.Errorf("invalid payment methods file %s: %w", r.path, err)
+	}
+	for _, m := range methods {
+		if p, ok := r.providers[m.ID].(ConfigurableProvider); ok {
+			if err := p.Configure(m); err != nil {
+				return fmt.Errorf("failed to configure payment provider %s: %w", m.ID, err)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	r.methods = methods
+	r.verifiers = verifiers
+	r.mu.Unlock()
+
+	slog.Info("payment methods loaded", "path", r.path, "version", cfg.Version, "methods", len(methods))
+	return nil
+}
+
+// validate checks cfg against the registered providers and returns the methods
+// sorted by display order.
+func (r *Registry) validate(cfg PaymentMethodsConfig) ([]PaymentMethodConfig, error) {
+	seen := make(map[string]struct{}, len(cfg.Methods))
+	methods := make([]PaymentMethodConfig, 0, len(cfg.Methods))
+	for i, m := range cfg.Methods {
+		if m.ID == "" {
+			return nil, fmt.Errorf("methods[%d]: id is required", i)
+		}
+		if _, dup := seen[m.ID]; dup {
+			return nil, fmt.Errorf("methods[%d]: duplicate id %q", i, m.ID)
+		}
+		seen[m.ID] = struct{}{}
+		if _, ok := r.providers[m.ID]; m.IsActive && !ok {
+			return nil, fmt.Errorf("methods[%d]: no provider implementation registered for active method %q", i, m.ID)
+		}
+		methods = append(methods, m)
+	}
+	sort.SliceStable(methods, func(i, j int) bool {
+		return methods[i].RenderInfo.DisplayOrder < methods[j].RenderInfo.DisplayOrder
+	})
+	return methods, nil
+}
+
+// buildVerifiers resolves the webhook verifier of every active method. A provider that
+// implements WebhookVerifier verifies its own webhooks; otherwise the verifier is built
+// from the method's webhook block. There is no unauthenticated option.
+func (r *Registry) buildVerifiers(methods []PaymentMethodConfig) (map[string]WebhookVerifier, error) {
+	verifiers := make(map[string]WebhookVerifier, len(methods))
+	for _, m := range methods {
+		if !m.IsActive {
+			continue
+		}
+		if v, ok := r.providers[m.ID].(WebhookVerifier); ok {
+			verifiers[m.ID] = v
+			continue
+		}
+		v, err := newWebhookVerifier(m.Webhook)
+		if err != nil {
+			return nil, fmt.Errorf("method %q: %w", m.ID, err)
+		}
+		verifiers[m.ID] = v
+	}
+	return verifiers, nil
+}
+
+// Get retrieves an active provider implementation by its method ID.
+func (r *Registry) Get(id string) (PaymentProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.checkActive(id); err != nil {
+		return nil, err
+	}
+	return r.providers[id], nil
+}
+
+// Provider retrieves a provider implementation by method ID, including inactive methods.
+func (r *Registry) Provider(id string) (PaymentProvider, error) {
+	if p, ok := r.providers[id]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, id)
+}
+
+// Verifier retrieves the webhook verifier of an active method.
+func (r *Registry) Verifier(id string) (WebhookVerifier, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if err := r.checkActive(id); err != nil {
+		return nil, err
+	}
+	return r.verifiers[id], nil
+}
+
+// checkActive reports whether id is a configured, active method. Callers must hold r.mu.
+func (r *Registry) checkActive(id string) error {
+	for _, m := range r.methods {
+		if m.ID != id {
+			continue
+		}
+		if !m.IsActive {
+			return fmt.Errorf("%w: %s", ErrProviderInactive, id)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrProviderNotFound, id)
+}
+
+// ListInfo returns the metadata of all active methods in display order.
+func (r *Registry) ListInfo() []PaymentProviderInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]PaymentProviderInfo, 0, len(r.methods))
+	for _, m := range r.methods {
+		if !m.IsActive {
+			continue
+		}
+		infos = append(infos, PaymentProviderInfo{
+			ID:         m.ID,
+			IsActive:   m.IsActive,
+			RenderInfo: m.RenderInfo,
+		})
+	}
+	return infos
+}
+
+// GetDefault returns the first active provider in display order.
+func (r *Registry) GetDefault() (PaymentProvider, error) {
+	infos := r.ListInfo()
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("%w: no active payment methods configured", ErrProviderNotFound)
+	}
+	return r.Get(infos[0].ID)
+}
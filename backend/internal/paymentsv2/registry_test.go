@@ -0,0 +1,153 @@
+package paymentsv2
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type stubProvider struct {
+	configured   []PaymentMethodConfig
+	createFn     func(ctx context.Context, req CreateCheckoutRequest) (*CreateCheckoutResponse, error)
+	parseFn      func(ctx context.Context, body []byte, headers map[string][]string) (*WebhookPayload, error)
+	refundFn     func(ctx context.Context, req ProviderRefundRequest) (*ProviderRefundResponse, error)
+	validateResp *ValidateReferenceResponse
+}
+
+func (p *stubProvider) Configure(method PaymentMethodConfig) error {
+	p.configured = append(p.configured, method)
+	return nil
+}
+
+func (p *stubProvider) CreateSession(ctx context.Context, req CreateCheckoutRequest) (*CreateCheckoutResponse, error) {
+	return p.createFn(ctx, req)
+}
+
+func (p *stubProvider) ParseWebhook(ctx context.Context, body []byte, headers map[string][]string) (*WebhookPayload, error) {
+	return p.parseFn(ctx, body, headers)
+}
+
+func (p *stubProvider) HandleValidateReference(ctx context.Context, tx *PaymentTransaction) (*ValidateReferenceResponse, error) {
+	return p.validateResp, nil
+}
+
+func (p *stubProvider) Refund(ctx context.Context, req ProviderRefundRequest) (*ProviderRefundResponse, error) {
+	return p.refundFn(ctx, req)
+}
+
+func writeMethodsFile(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "payment_methods.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write methods file: %v", err)
+	}
+	return path
+}
+
+const testMethodsJSON = `{
+  "version": "1.0",
+  "methods": [
+    {"id": "lankapay", "is_active": true, "render_info": {"display_name": "LankaPay", "display_order": 2}, "gateway_url": "https://lankapay.example",
+     "webhook": {"scheme": "hmac-sha256", "secret_env": "TEST_PAYMENT_WEBHOOK_SECRET"}},
+    {"id": "mock", "is_active": true, "render_info": {"display_name": "Mock", "display_order": 1},
+     "webhook": {"scheme": "hmac-sha256", "secret_env": "TEST_PAYMENT_WEBHOOK_SECRET", "max_skew_seconds": 60}},
+    {"id": "govpay", "is_active": false, "render_info": {"display_name": "GovPay", "display_order": 0}}
+  ]
+}`
+
+const testWebhookBlock = `"webhook": {"scheme": "hmac-sha256", "secret_env": "TEST_PAYMENT_WEBHOOK_SECRET"}`
+
+func TestRegistry_Load(t *testing.T) {
+	t.Setenv("TEST_PAYMENT_WEBHOOK_SECRET", "s3cret")
+	lankapay, mock := &stubProvider{}, &stubProvider{}
+	path := writeMethodsFile(t, t.TempDir(), testMethodsJSON)
+
+	registry, err := NewRegistry(path, map[string]PaymentProvider{"lankapay": lankapay, "mock": mock})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	infos := registry.ListInfo()
+	if len(infos) != 2 || infos[0].ID != "mock" || infos[1].ID != "lankapay" {
+		t.Fatalf("expected active methods in display order [mock lankapay], got %+v", infos)
+	}
+
+	if p, err := registry.GetDefault(); err != nil || p != mock {
+		t.Fatalf("expected mock as default provider, got %v, %v", p, err)
+	}
+	if _, err := registry.Get("govpay"); !errors.Is(err, ErrProviderInactive) {
+		t.Fatalf("expected ErrProviderInactive, got %v", err)
+	}
+	if _, err := registry.Get("unknown"); !errors.Is(err, ErrProviderNotFound) {
+		t.Fatalf("expected ErrProviderNotFound, got %v", err)
+	}
+	verifier, err := registry.Verifier("mock")
+	if err != nil {
+		t.Fatalf("expected verifier for mock, got %v", err)
+	}
+	if hv, ok := verifier.(*HMACVerifier); !ok || hv.MaxSkew != time.Minute || hv.SignatureHeader != defaultSignatureHeader {
+		t.Fatalf("expected HMAC verifier built from the webhook block, got %+v", verifier)
+	}
+	if _, err := registry.Verifier("govpay"); !errors.Is(err, ErrProviderInactive) {
+		t.Fatalf("expected ErrProviderInactive for verifier, got %v", err)
+	}
+	if len(lankapay.configured) != 1 || lankapay.configured[0].GatewayURL != "https://lankapay.example" {
+		t.Fatalf("expected lankapay to be configured from its entry, got %+v", lankapay.configured)
+	}
+}
+
+func TestRegistry_Validation(t *testing.T) {
+	t.Setenv("TEST_PAYMENT_WEBHOOK_SECRET", "s3cret")
+	cases := map[string]string{
+		"active method without provider": `{"methods": [{"id": "unknown", "is_active": true, ` + testWebhookBlock + `}]}`,
+		"active method without webhook":  `{"methods": [{"id": "mock", "is_active": true}]}`,
+		"unsupported webhook scheme":     `{"methods": [{"id": "mock", "is_active": true, "webhook": {"scheme": "none"}}]}`,
+		"webhook secret not set":         `{"methods": [{"id": "mock", "is_active": true, "webhook": {"scheme": "hmac-sha256", "secret_env": "TEST_PAYMENT_WEBHOOK_SECRET_UNSET"}}]}`,
+		"duplicate id":                   `{"methods": [{"id": "mock", "is_active": true}, {"id": "mock"}]}`,
+		"missing id":                     `{"methods": [{"is_active": false}]}`,
+		"malformed json":                 `{"methods": [`,
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := writeMethodsFile(t, t.TempDir(), content)
+			if _, err := NewRegistry(path, map[string]PaymentProvider{"mock": &stubProvider{}}); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestRegistry_Reload(t *testing.T) {
+	t.Setenv("TEST_PAYMENT_WEBHOOK_SECRET", "s3cret")
+	dir := t.TempDir()
+	path := writeMethodsFile(t, dir, testMethodsJSON)
+	providers := map[string]PaymentProvider{"lankapay": &stubProvider{}, "mock": &stubProvider{}}
+
+	registry, err := NewRegistry(path, providers)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	t.Run("picks up changes", func(t *testing.T) {
+		writeMethodsFile(t, dir, `{"methods": [{"id": "lankapay", "is_active": true, `+testWebhookBlock+`}, {"id": "mock", "is_active": false}]}`)
+		if err := registry.Reload(); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if infos := registry.ListInfo(); len(infos) != 1 || infos[0].ID != "lankapay" {
+			t.Fatalf("expected only lankapay after reload, got %+v", infos)
+		}
+	})
+
+	t.Run("keeps previous config on invalid file", func(t *testing.T) {
+		writeMethodsFile(t, dir, `{"methods": [{"id": "unknown", "is_active": true}]}`)
+		if err := registry.Reload(); err == nil {
+			t.Fatal("expected error for invalid file, got nil")
+		}
+		if infos := registry.ListInfo(); len(infos) != 1 || infos[0].ID != "lankapay" {
+			t.Fatalf("expected previous config to be kept, got %+v", infos)
+		}
+	})
+}
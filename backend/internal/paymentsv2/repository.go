@@ -3,10 +3,21 @@ package paymentsv2
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/audit"
 )
 
+// ErrDuplicateReference is returned by Create when the reference number is already taken.
+var ErrDuplicateReference = errors.New("payment reference number already exists")
+
+// pgUniqueViolation is the PostgreSQL SQLSTATE for unique constraint violations.
+const pgUniqueViolation = "23505"
+
 // PaymentRepository defines the interface for managing PaymentTransactions.
 type PaymentRepository interface {
 	Create(ctx context.Context, tx *PaymentTransaction) error
@@ -14,6 +25,40 @@ type PaymentRepository interface {
 	GetByTaskID(ctx context.Context, taskID string) (*PaymentTransaction, error)
 	Update(ctx context.Context, tx *PaymentTransaction) error
 	UpdateStatus(ctx context.Context, referenceNumber string, status PaymentStatus) error
+	// CompareAndUpdate saves the status, payment method, gateway metadata and pending event of tx only if
+	// the stored status still equals expected. It reports whether the row was updated.
+	CompareAndUpdate(ctx context.Context, tx *PaymentTransaction, expected PaymentStatus) (bool, error)
+	// MarkEventDelivered clears the pending event of the transaction with id.
+	MarkEventDelivered(ctx context.Context, id string) error
+	// ListEventPending returns up to limit transactions whose event is still pending and
+	// that were last updated before the given time, oldest first.
+	ListEventPending(ctx context.Context, before time.Time, limit int) ([]PaymentTransaction, error)
+	// ListByStatus returns up to limit transactions in status, oldest expiry first.
+	ListByStatus(ctx context.Context, status PaymentStatus, limit int) ([]PaymentTransaction, error)
+	// ListByProviderAndStatus returns the transactions of providerID in status last updated within [from, to).
+	ListByProviderAndStatus(ctx context.Context, providerID string, status PaymentStatus, from, to time.Time) ([]PaymentTransaction, error)
+	// GetByReferenceNumberForUpdate reads a transaction and locks its row until the
+	// surrounding RunInTransaction completes.
+	GetByReferenceNumberForUpdate(ctx context.Context, referenceNumber string) (*PaymentTransaction, error)
+	// RunInTransaction runs fn with a repository bound to a single database transaction.
+	RunInTransaction(ctx context.Context, fn func(repo PaymentRepository) error) error
+	// RecordAudit appends entry to the audit log with recorder, within the repository's
+	// transaction when it is bound to one.
+	RecordAudit(ctx context.Context, recorder audit.Recorder, entry audit.Entry) error
+	CreateRefund(ctx context.Context, refund *PaymentRefund) error
+	// GetRefund returns a ledger entry by ID, or nil if there is none.
+	GetRefund(ctx context.Context, id string) (*PaymentRefund, error)
+	// UpdateRefund saves the status, decision and gateway outcome of refund only if the
+	// stored status still equals expected. It reports whether the row was updated.
+	UpdateRefund(ctx context.Context, refund *PaymentRefund, expected RefundStatus) (bool, error)
+	ListRefunds(ctx context.Context, transactionID string) ([]PaymentRefund, error)
+	CreateSettlementReport(ctx context.Context, report *SettlementReport) error
+	GetSettlementReport(ctx context.Context, id string) (*SettlementReport, error)
+	// NextReceiptSequence allocates the next receipt number of year. The counter row stays
+	// locked until the surrounding transaction completes, so numbers are never skipped.
+	NextReceiptSequence(ctx context.Context, year int) (int64, error)
+	CreateReceipt(ctx context.Context, receipt *PaymentReceipt) error
+	GetReceiptByReference(ctx context.Context, referenceNumber string) (*PaymentReceipt, error)
 	WithTx(tx *gorm.DB) PaymentRepository
 }
 
@@ -32,8 +77,14 @@ func (r *paymentRepository) WithTx(tx *gorm.DB) PaymentRepository {
 }
 
 // Create inserts a new PaymentTransaction into the database.
+// Returns ErrDuplicateReference if the reference number is already in use.
 func (r *paymentRepository) Create(ctx context.Context, ptx *PaymentTransaction) error {
-	return r.db.WithContext(ctx).Create(ptx).Error
+	err := r.db.WithContext(ctx).Create(ptx).Error
+	var pgErr *pgconn.PgError
+	if errors.Is(err, gorm.ErrDuplicatedKey) || (errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation) {
+		return ErrDuplicateReference
+	}
+	return err
 }
 
 // GetByReferenceNumber retrieves a PaymentTransaction by its reference number.
@@ -69,3 +120,168 @@ func (r *paymentRepository) Update(ctx context.Context, ptx *PaymentTransaction)
 func (r *paymentRepository) UpdateStatus(ctx context.Context, referenceNumber string, status PaymentStatus) error {
 	return r.db.WithContext(ctx).Model(&PaymentTransaction{}).Where("reference_number = ?", referenceNumber).Updates(map[string]interface{}{"status": status}).Error
 }
+
+// CompareAndUpdate performs a conditional update so that concurrent webhooks and
+// reconciliation passes on different replicas cannot both apply a status change.
+func (r *paymentRepository) CompareAndUpdate(ctx context.Context, ptx *PaymentTransaction, expected PaymentStatus) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(ptx).
+		Where("status = ?", expected).
+		Select("status", "payment_method", "gateway_metadata", "event_pending", "updated_at").
+		Updates(ptx)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// MarkEventDelivered clears event_pending without touching updated_at, which settlement
+// imports match on.
+func (r *paymentRepository) MarkEventDelivered(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&PaymentTransaction{}).Where("id = ?", id).UpdateColumn("event_pending", false).Error
+}
+
+// ListEventPending returns up to limit transactions with an undelivered event last updated before the given time.
+func (r *paymentRepository) ListEventPending(ctx context.Context, before time.Time, limit int) ([]PaymentTransaction, error) {
+	var txs []PaymentTransaction
+	err := r.db.WithContext(ctx).
+		Where("event_pending AND updated_at < ?", before).
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&txs).Error
+	return txs, err
+}
+
+// ListByStatus returns up to limit transactions in the given status, oldest expiry first.
+func (r *paymentRepository) ListByStatus(ctx context.Context, status PaymentStatus, limit int) ([]PaymentTransaction, error) {
+	var txs []PaymentTransaction
+	err := r.db.WithContext(ctx).
+		Where("status = ?", status).
+		Order("expiry_date ASC").
+		Limit(limit).
+		Find(&txs).Error
+	return txs, err
+}
+
+// ListByProviderAndStatus returns the provider's transactions in status last updated within [from, to).
+func (r *paymentRepository) ListByProviderAndStatus(ctx context.Context, providerID string, status PaymentStatus, from, to time.Time) ([]PaymentTransaction, error) {
+	var txs []PaymentTransaction
+	err := r.db.WithContext(ctx).
+		Where("provider_id = ? AND status = ? AND updated_at >= ? AND updated_at < ?", providerID, status, from, to).
+		Order("updated_at ASC").
+		Find(&txs).Error
+	return txs, err
+}
+
+// GetByReferenceNumberForUpdate reads a transaction with SELECT ... FOR UPDATE.
+func (r *paymentRepository) GetByReferenceNumberForUpdate(ctx context.Context, referenceNumber string) (*PaymentTransaction, error) {
+	var ptx PaymentTransaction
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("reference_number = ?", referenceNumber).
+		First(&ptx).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ptx, nil
+}
+
+// RunInTransaction runs fn inside a database transaction, committing if fn returns nil.
+func (r *paymentRepository) RunInTransaction(ctx context.Context, fn func(repo PaymentRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(r.WithTx(tx))
+	})
+}
+
+// RecordAudit appends entry within the repository's transaction.
+func (r *paymentRepository) RecordAudit(ctx context.Context, recorder audit.Recorder, entry audit.Entry) error {
+	return audit.RecordTx(ctx, r.db.WithContext(ctx), recorder, entry)
+}
+
+// CreateRefund appends an entry to the refund ledger.
+func (r *paymentRepository) CreateRefund(ctx context.Context, refund *PaymentRefund) error {
+	return r.db.WithContext(ctx).Create(refund).Error
+}
+
+// GetRefund retrieves a ledger entry by ID.
+func (r *paymentRepository) GetRefund(ctx context.Context, id string) (*PaymentRefund, error) {
+	var refund PaymentRefund
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&refund).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// UpdateRefund performs a conditional update, so that a refund is sent to the gateway
+// and finalised at most once even when two officers act on it concurrently.
+func (r *paymentRepository) UpdateRefund(ctx context.Context, refund *PaymentRefund, expected RefundStatus) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(refund).
+		Where("status = ?", expected).
+		Select("status", "approved_by", "rejected_by", "gateway_refund_id", "failure_reason", "updated_at").
+		Updates(refund)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ListRefunds returns the ledger entries of a transaction, oldest first.
+func (r *paymentRepository) ListRefunds(ctx context.Context, transactionID string) ([]PaymentRefund, error) {
+	var refunds []PaymentRefund
+	err := r.db.WithContext(ctx).
+		Where("transaction_id = ?", transactionID).
+		Order("created_at ASC").
+		Find(&refunds).Error
+	return refunds, err
+}
+
+// CreateSettlementReport persists the outcome of a settlement import.
+func (r *paymentRepository) CreateSettlementReport(ctx context.Context, report *SettlementReport) error {
+	return r.db.WithContext(ctx).Create(report).Error
+}
+
+// GetSettlementReport retrieves a settlement report by ID.
+func (r *paymentRepository) GetSettlementReport(ctx context.Context, id string) (*SettlementReport, error) {
+	var report SettlementReport
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// NextReceiptSequence increments and returns the receipt counter of year.
+func (r *paymentRepository) NextReceiptSequence(ctx context.Context, year int) (int64, error) {
+	var next int64
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO payment_receipt_counters (year, last_number) VALUES (?, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = payment_receipt_counters.last_number + 1
+		RETURNING last_number`, year).Scan(&next).Error
+	return next, err
+}
+
+// CreateReceipt persists an issued receipt.
+func (r *paymentRepository) CreateReceipt(ctx context.Context, receipt *PaymentReceipt) error {
+	return r.db.WithContext(ctx).Create(receipt).Error
+}
+
+// GetReceiptByReference retrieves the receipt of a transaction by its reference number.
+func (r *paymentRepository) GetReceiptByReference(ctx context.Context, referenceNumber string) (*PaymentReceipt, error) {
+	var receipt PaymentReceipt
+	if err := r.db.WithContext(ctx).Where("reference_number = ?", referenceNumber).First(&receipt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &receipt, nil
+}
@@ -2,8 +2,18 @@ package paymentsv2
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/OpenNSW/nsw/internal/audit"
+	"github.com/OpenNSW/nsw/internal/uploads"
 )
 
 // PaymentService defines the high-level orchestration for payments.
@@ -19,33 +29,209 @@ type PaymentService interface {
 
 	// ProcessWebhook handles asynchronous notifications from payment gateways.
 	ProcessWebhook(ctx context.Context, providerID string, body []byte, headers map[string][]string) error
+
+	// RegisterEventHandler registers the handler notified when a payment reaches a final status.
+	RegisterEventHandler(handler EventHandler)
+
+	// Reconcile queries providers for PENDING transactions and expires those past their expiry date.
+	Reconcile(ctx context.Context) (*ReconciliationResult, error)
+
+	// ImportSettlement reconciles a provider's daily settlement file (CSV) against NSW records.
+	ImportSettlement(ctx context.Context, providerID string, settlementDate time.Time, file io.Reader) (*SettlementReport, error)
+
+	// GetSettlementReport returns a previously produced settlement report, or nil if not found.
+	GetSettlementReport(ctx context.Context, id string) (*SettlementReport, error)
+
+	// RequestRefund records a refund of all or part of a confirmed payment, pending approval.
+	RequestRefund(ctx context.Context, req RefundRequest) (*PaymentRefund, error)
+
+	// ApproveRefund approves a requested refund and returns the money through the gateway.
+	ApproveRefund(ctx context.Context, d RefundDecision) (*PaymentRefund, error)
+
+	// RejectRefund declines a requested refund.
+	RejectRefund(ctx context.Context, d RefundDecision) (*PaymentRefund, error)
+
+	// GetTransaction returns the transaction of a reference number.
+	GetTransaction(ctx context.Context, referenceNumber string) (*PaymentTransaction, error)
+
+	// ListRefunds returns the refund ledger of a transaction.
+	ListRefunds(ctx context.Context, referenceNumber string) ([]PaymentRefund, error)
+
+	// ConvertAmount returns the base-currency equivalent of amount at the current rate,
+	// or nil when currency is the base currency.
+	ConvertAmount(ctx context.Context, amount decimal.Decimal, currency string) (*CurrencyConversion, error)
+
+	// IssueReceipt issues the receipt of a paid transaction, or returns the one already issued.
+	IssueReceipt(ctx context.Context, req IssueReceiptRequest) (*PaymentReceipt, error)
+
+	// GetReceipt returns the receipt issued for a transaction.
+	GetReceipt(ctx context.Context, referenceNumber string) (*PaymentReceipt, error)
+
+	// OpenReceipt streams the PDF or JSON rendition of the receipt issued for a transaction.
+	OpenReceipt(ctx context.Context, referenceNumber string, format ReceiptFormat) (*PaymentReceipt, io.ReadCloser, error)
 }
 
+// EventHandler receives InternalPaymentEvents once a transaction has reached a final
+// status, whether reported by a webhook or corrected by reconciliation. The Task Engine
+// registers one to advance the owning PAYMENT task.
+type EventHandler func(ctx context.Context, event InternalPaymentEvent) error
+
 type paymentService struct {
-	repo     PaymentRepository
-	registry PaymentRegistry
+	repo         PaymentRepository
+	registry     PaymentRegistry
+	rates        ExchangeRateProvider
+	storage      uploads.StorageDriver
+	newReference ReferenceGenerator
+	eventHandler EventHandler
+	auditLog     audit.Recorder
 }
 
-// NewPaymentService initializes a new payment service.
-func NewPaymentService(repo PaymentRepository, registry PaymentRegistry) PaymentService {
+// NewPaymentService initializes a new payment service. rates may be nil, in which case
+// amounts are never converted and every payment is recorded in its own currency only.
+// storage holds issued receipts; when it is nil, receipts are not issued. Status changes
+// are recorded in auditLog, which may be nil.
+func NewPaymentService(repo PaymentRepository, registry PaymentRegistry, rates ExchangeRateProvider, storage uploads.StorageDriver, auditLog audit.Recorder) PaymentService {
 	return &paymentService{
-		repo:     repo,
-		registry: registry,
+		repo:         repo,
+		registry:     registry,
+		rates:        rates,
+		storage:      storage,
+		newReference: NewReferenceNumber,
+		auditLog:     auditLog,
 	}
 }
 
+// RegisterEventHandler registers the handler notified of final payment outcomes.
+func (s *paymentService) RegisterEventHandler(handler EventHandler) {
+	s.eventHandler = handler
+}
+
 func (s *paymentService) ListAvailableMethods(ctx context.Context) ([]PaymentProviderInfo, error) {
 	return s.registry.ListInfo(), nil
 }
 
+// CreateCheckoutSession reserves a unique NSW reference, dispatches the session to the
+// selected provider and records the gateway session against the transaction.
+// The PENDING row is persisted before the provider is called so that a reference is
+// never handed to a gateway without being owned by exactly one transaction.
 func (s *paymentService) CreateCheckoutSession(ctx context.Context, req CreateCheckoutRequest) (*CreateCheckoutResponse, error) {
-	// TODO: Implement multi-provider orchestration logic:
-	// 1. Select provider from registry
-	// 2. Generate a unique NSW ReferenceNumber (e.g., NSW-PR-YYYY-XXXXX)
-	// 3. Call provider.CreateSession(ctx, req, generatedRef)
-	// 4. Persist transaction via repo including the generatedRef
-	// 5. Return CreateCheckoutResponse containing the generatedRef
-	return nil, fmt.Errorf("multi-provider checkout orchestration not yet implemented")
+	taskID := req.Metadata["task_id"]
+	if taskID == "" {
+		return nil, fmt.Errorf("task_id is required in metadata")
+	}
+
+	providerID, provider, err := s.resolveProvider(req.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The rate is fixed now; a foreign-currency payment is not started without one.
+	conversion, err := s.ConvertAmount(ctx, req.Amount, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.reserveTransaction(ctx, req, providerID, taskID, conversion)
+	if err != nil {
+		return nil, err
+	}
+
+	req.ProviderID = providerID
+	req.ReferenceNumber = tx.ReferenceNumber
+	resp, err := provider.CreateSession(ctx, req)
+	if err != nil {
+		tx.Status = PaymentStatusFailed
+		tx.GatewayMetadata["failure_reason"] = err.Error()
+		if updateErr := s.repo.Update(ctx, tx); updateErr != nil {
+			slog.ErrorContext(ctx, "failed to mark payment transaction as failed", "reference", tx.ReferenceNumber, "error", updateErr)
+		}
+		return nil, fmt.Errorf("provider %s failed to create session: %w", providerID, err)
+	}
+
+	tx.SessionID = resp.SessionID
+	if err := s.repo.Update(ctx, tx); err != nil {
+		return nil, fmt.Errorf("failed to record gateway session: %w", err)
+	}
+
+	slog.InfoContext(ctx, "created checkout session", "provider", providerID, "reference", tx.ReferenceNumber, "session_id", resp.SessionID)
+
+	resp.ReferenceNumber = tx.ReferenceNumber
+	resp.ProviderID = providerID
+	resp.Conversion = conversion
+	if resp.ExpiresIn == 0 {
+		resp.ExpiresIn = int(time.Until(req.ExpiresAt).Seconds())
+	}
+	return resp, nil
+}
+
+// ConvertAmount returns the base-currency equivalent of amount at the current rate.
+func (s *paymentService) ConvertAmount(ctx context.Context, amount decimal.Decimal, currency string) (*CurrencyConversion, error) {
+	conversion, err := convert(ctx, s.rates, amount, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s to base currency: %w", currency, err)
+	}
+	return conversion, nil
+}
+
+// resolveProvider returns the requested provider, or the registry default when no ID is given.
+func (s *paymentService) resolveProvider(providerID string) (string, PaymentProvider, error) {
+	if providerID == "" {
+		infos := s.registry.ListInfo()
+		if len(infos) == 0 {
+			return "", nil, fmt.Errorf("%w: no active payment methods configured", ErrProviderNotFound)
+		}
+		providerID = infos[0].ID
+	}
+	provider, err := s.registry.Get(providerID)
+	if err != nil {
+		return "", nil, fmt.Errorf("provider %s not available: %w", providerID, err)
+	}
+	return providerID, provider, nil
+}
+
+// reserveTransaction persists a PENDING transaction under a freshly generated reference,
+// retrying with a new reference when the unique constraint reports a collision.
+func (s *paymentService) reserveTransaction(ctx context.Context, req CreateCheckoutRequest, providerID, taskID string, conversion *CurrencyConversion) (*PaymentTransaction, error) {
+	metadata := make(map[string]string, len(req.Metadata))
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+
+	for attempt := 1; attempt <= maxReferenceAttempts; attempt++ {
+		ref, err := s.newReference(time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		tx := &PaymentTransaction{
+			ID:              uuid.NewString(),
+			ReferenceNumber: ref,
+			TaskID:          taskID,
+			ProviderID:      providerID,
+			Amount:          req.Amount,
+			Currency:        req.Currency,
+			Status:          PaymentStatusPending,
+			ExpiryDate:      req.ExpiresAt,
+			GatewayMetadata: metadata,
+		}
+		if conversion != nil {
+			baseAmount, rate, asOf := conversion.BaseAmount, conversion.Rate, conversion.RateAsOf
+			tx.BaseAmount = &baseAmount
+			tx.BaseCurrency = conversion.BaseCurrency
+			tx.ExchangeRate = &rate
+			tx.RateSource = conversion.RateSource
+			tx.RateAsOf = &asOf
+		}
+		err = s.repo.Create(ctx, tx)
+		if err == nil {
+			return tx, nil
+		}
+		if !errors.Is(err, ErrDuplicateReference) {
+			return nil, fmt.Errorf("failed to create payment transaction: %w", err)
+		}
+		slog.WarnContext(ctx, "payment reference collision, regenerating", "reference", ref, "attempt", attempt)
+	}
+	return nil, fmt.Errorf("failed to allocate a unique payment reference after %d attempts", maxReferenceAttempts)
 }
 
 func (s *paymentService) ValidateReference(ctx context.Context, providerID string, req ValidateReferenceRequest) (*ValidateReferenceResponse, error) {
@@ -76,7 +262,201 @@ func (s *paymentService) ValidateReference(ctx context.Context, providerID strin
 	return provider.HandleValidateReference(ctx, tx)
 }
 
+// ProcessWebhook parses a gateway notification with the owning provider, applies the
+// status update idempotently and emits an InternalPaymentEvent for final outcomes.
 func (s *paymentService) ProcessWebhook(ctx context.Context, providerID string, body []byte, headers map[string][]string) error {
-	// TODO: Implement provider-based webhook processing
-	return fmt.Errorf("webhook processing not yet implemented for provider: %s", providerID)
+	provider, err := s.registry.Get(providerID)
+	if err != nil {
+		return fmt.Errorf("provider %s not found: %w", providerID, err)
+	}
+
+	payload, err := provider.ParseWebhook(ctx, body, headers)
+	if err != nil {
+		return fmt.Errorf("failed to parse webhook from provider %s: %w", providerID, err)
+	}
+
+	slog.InfoContext(ctx, "processing payment webhook", "provider", providerID, "reference", payload.ReferenceNumber, "status", payload.Status)
+
+	tx, err := s.repo.GetByReferenceNumber(ctx, payload.ReferenceNumber)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve payment by reference: %w", err)
+	}
+	if tx == nil {
+		return fmt.Errorf("%w: %s", ErrTransactionNotFound, payload.ReferenceNumber)
+	}
+	if tx.ProviderID != providerID {
+		// Another provider's payment is unknown to this one.
+		return fmt.Errorf("%w: %s belongs to provider %s, not %s", ErrTransactionNotFound, tx.ReferenceNumber, tx.ProviderID, providerID)
+	}
+
+	// Idempotency: a repeated notification, or one that would move a transaction out of
+	// a final status, is acknowledged without side effects. A gateway retrying after its
+	// outcome failed to reach the task delivers it again.
+	if !canTransition(tx.Status, payload.Status) {
+		if tx.EventPending && tx.Status == payload.Status {
+			return s.deliver(ctx, tx)
+		}
+		slog.InfoContext(ctx, "webhook ignored (idempotent)", "reference", tx.ReferenceNumber, "current_status", tx.Status, "reported_status", payload.Status)
+		return nil
+	}
+
+	return s.applyStatus(ctx, tx, payload, StatusSourceWebhook)
+}
+
+// applyStatus moves tx to payload.Status and notifies the event handler when the new
+// status is one the owning task must react to. The event is marked pending in the same
+// update, so an outcome the task fails to receive is delivered again by a gateway retry
+// or by reconciliation. A confirmation for an amount or currency other than the
+// transaction's is held as AMOUNT_MISMATCH instead. If another webhook or reconciliation
+// pass changed the transaction first, the update is skipped.
+func (s *paymentService) applyStatus(ctx context.Context, tx *PaymentTransaction, payload *WebhookPayload, source string) error {
+	previous := tx.Status
+	tx.Status = payload.Status
+	if tx.Status == PaymentStatusSuccess && !paidInFull(tx, payload) {
+		tx.Status = PaymentStatusAmountMismatch
+	}
+	tx.EventPending = tx.Status != PaymentStatusPending && tx.Status != PaymentStatusAmountMismatch
+	if payload.PaymentMethod != "" {
+		tx.PaymentMethod = payload.PaymentMethod
+	}
+	if tx.GatewayMetadata == nil {
+		tx.GatewayMetadata = make(map[string]string)
+	}
+	if payload.GatewayTransactionID != "" {
+		tx.GatewayMetadata["gateway_transaction_id"] = payload.GatewayTransactionID
+	}
+	if payload.Timestamp != "" {
+		tx.GatewayMetadata["webhook_timestamp"] = payload.Timestamp
+	}
+	tx.GatewayMetadata["status_source"] = source
+	if tx.Status == PaymentStatusAmountMismatch {
+		tx.GatewayMetadata["reported_amount"] = payload.Amount.String()
+		tx.GatewayMetadata["reported_currency"] = payload.Currency
+	}
+
+	var updated bool
+	err := s.repo.RunInTransaction(ctx, func(repo PaymentRepository) error {
+		var err error
+		updated, err = repo.CompareAndUpdate(ctx, tx, previous)
+		if err != nil {
+			return fmt.Errorf("failed to update payment transaction status: %w", err)
+		}
+		if !updated {
+			return nil
+		}
+		return s.recordStatusChange(ctx, repo, tx, previous, source, payload)
+	})
+	if err != nil {
+		return err
+	}
+	if !updated {
+		slog.InfoContext(ctx, "payment transaction changed concurrently, update skipped", "reference", tx.ReferenceNumber, "expected_status", previous)
+		return nil
+	}
+
+	slog.InfoContext(ctx, "payment transaction updated successfully", "reference", tx.ReferenceNumber, "from", previous, "status", tx.Status, "source", source)
+
+	if tx.Status == PaymentStatusAmountMismatch {
+		slog.ErrorContext(ctx, "payment confirmed for an unexpected amount, held for reconciliation", "reference", tx.ReferenceNumber,
+			"expected", tx.Amount.String()+" "+tx.Currency, "reported", payload.Amount.String()+" "+payload.Currency, "source", source)
+	}
+	if !tx.EventPending {
+		return nil
+	}
+	return s.deliver(ctx, tx)
+}
+
+// paidInFull reports whether a confirmation is for the transaction's amount and currency.
+func paidInFull(tx *PaymentTransaction, payload *WebhookPayload) bool {
+	return payload.Amount.Equal(tx.Amount) && strings.EqualFold(payload.Currency, tx.Currency)
+}
+
+// deliver emits the final outcome of tx and clears its pending event. When the event
+// handler fails, the event stays pending for the next attempt.
+func (s *paymentService) deliver(ctx context.Context, tx *PaymentTransaction) error {
+	if err := s.emit(ctx, tx); err != nil {
+		return err
+	}
+	tx.EventPending = false
+	if err := s.repo.MarkEventDelivered(ctx, tx.ID); err != nil {
+		// The task has the outcome; delivering it again is acknowledged as a repeat.
+		slog.ErrorContext(ctx, "failed to mark payment event delivered", "reference", tx.ReferenceNumber, "error", err)
+	}
+	return nil
+}
+
+// recordStatusChange appends a status change of tx, made on the strength of payload, to the
+// audit log within the transaction of repo that makes it. Webhook reports are attributed
+// to the provider that sent them; the other sources to the principal in ctx.
+func (s *paymentService) recordStatusChange(ctx context.Context, repo PaymentRepository, tx *PaymentTransaction, previous PaymentStatus, source string, payload any) error {
+	entry := audit.Entry{
+		Action:       audit.ActionPaymentStatusChange,
+		TaskID:       tx.TaskID,
+		ResourceType: audit.ResourcePayment,
+		ResourceID:   tx.ReferenceNumber,
+		Operation:    source,
+		StateBefore:  string(previous),
+		StateAfter:   string(tx.Status),
+		PayloadHash:  audit.HashPayload(payload),
+		Details:      map[string]any{"providerId": tx.ProviderID, "amount": tx.Amount.String(), "currency": tx.Currency},
+	}
+	if source == StatusSourceWebhook {
+		entry.ActorKind, entry.ActorID = audit.ActorProvider, tx.ProviderID
+	}
+	return repo.RecordAudit(ctx, s.auditLog, entry)
+}
+
+// emit notifies the registered EventHandler of the final outcome of tx, as recorded on
+// the transaction by applyStatus or a refund. A refunded transaction is reported as
+// confirmed first, in case its confirmation was still pending when it was refunded, and
+// then with each of its refunds; the task acknowledges events it has recorded as repeats.
+func (s *paymentService) emit(ctx context.Context, tx *PaymentTransaction) error {
+	if s.eventHandler == nil {
+		slog.WarnContext(ctx, "no payment event handler registered, task will not be advanced", "reference", tx.ReferenceNumber, "task_id", tx.TaskID)
+		return nil
+	}
+
+	var eventType string
+	switch tx.Status {
+	case PaymentStatusSuccess, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+		eventType = PaymentEventConfirmed
+	case PaymentStatusExpired:
+		eventType = PaymentEventExpired
+	default:
+		eventType = PaymentEventFailed
+	}
+	event := InternalPaymentEvent{
+		EventType: eventType,
+		Data: EventData{
+			TaskID:               tx.TaskID,
+			ReferenceNumber:      tx.ReferenceNumber,
+			GatewayTransactionID: tx.GatewayMetadata["gateway_transaction_id"],
+			Status:               tx.Status,
+			AmountPaid:           tx.Amount,
+			Currency:             tx.Currency,
+			ConfirmedAt:          tx.GatewayMetadata["webhook_timestamp"],
+			Source:               tx.GatewayMetadata["status_source"],
+		},
+	}
+	if err := s.eventHandler(ctx, event); err != nil {
+		return fmt.Errorf("failed to deliver %s event for task %s: %w", eventType, tx.TaskID, err)
+	}
+	if tx.Status == PaymentStatusPartiallyRefunded || tx.Status == PaymentStatusRefunded {
+		return s.emitRefunds(ctx, tx)
+	}
+	return nil
+}
+
+// canTransition reports whether a gateway report may move a transaction from one status
+// to another. EXPIRED can still become SUCCESS: a late confirmation means the trader was
+// charged after NSW gave up waiting.
+func canTransition(from, to PaymentStatus) bool {
+	switch from {
+	case PaymentStatusPending:
+		return to == PaymentStatusSuccess || to == PaymentStatusFailed || to == PaymentStatusExpired
+	case PaymentStatusExpired:
+		return to == PaymentStatusSuccess
+	default:
+		return false
+	}
 }
@@ -0,0 +1,583 @@
+package paymentsv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/audit"
+)
+
+type mockRepository struct {
+	txs       map[string]*PaymentTransaction
+	reports   map[string]*SettlementReport
+	refunds   []PaymentRefund
+	receipts  map[string]*PaymentReceipt
+	counters  map[int]int64
+	createErr error
+	getErr    error
+	updateErr error
+	// inTransaction is set while RunInTransaction runs fn.
+	inTransaction bool
+}
+
+func newMockRepository() *mockRepository {
+	return &mockRepository{
+		txs:      make(map[string]*PaymentTransaction),
+		reports:  make(map[string]*SettlementReport),
+		receipts: make(map[string]*PaymentReceipt),
+		counters: make(map[int]int64),
+	}
+}
+
+func (m *mockRepository) Create(ctx context.Context, tx *PaymentTransaction) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	if _, exists := m.txs[tx.ReferenceNumber]; exists {
+		return ErrDuplicateReference
+	}
+	m.txs[tx.ReferenceNumber] = tx
+	return nil
+}
+
+// GetByReferenceNumber returns a copy, as a database read would.
+func (m *mockRepository) GetByReferenceNumber(ctx context.Context, ref string) (*PaymentTransaction, error) {
+	if m.getErr != nil {
+		return nil, m.getErr
+	}
+	tx, ok := m.txs[ref]
+	if !ok {
+		return nil, nil
+	}
+	clone := *tx
+	return &clone, nil
+}
+
+func (m *mockRepository) GetByTaskID(ctx context.Context, taskID string) (*PaymentTransaction, error) {
+	for _, tx := range m.txs {
+		if tx.TaskID == taskID {
+			return tx, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) Update(ctx context.Context, tx *PaymentTransaction) error {
+	if m.updateErr != nil {
+		return m.updateErr
+	}
+	m.txs[tx.ReferenceNumber] = tx
+	return nil
+}
+
+func (m *mockRepository) UpdateStatus(ctx context.Context, ref string, status PaymentStatus) error {
+	if tx, ok := m.txs[ref]; ok {
+		tx.Status = status
+	}
+	return nil
+}
+
+func (m *mockRepository) CompareAndUpdate(ctx context.Context, tx *PaymentTransaction, expected PaymentStatus) (bool, error) {
+	if m.updateErr != nil {
+		return false, m.updateErr
+	}
+	stored, ok := m.txs[tx.ReferenceNumber]
+	if !ok || stored.Status != expected {
+		return false, nil
+	}
+	clone := *tx
+	clone.UpdatedAt = time.Now()
+	m.txs[tx.ReferenceNumber] = &clone
+	return true, nil
+}
+
+func (m *mockRepository) MarkEventDelivered(ctx context.Context, id string) error {
+	for _, tx := range m.txs {
+		if tx.ID == id {
+			tx.EventPending = false
+		}
+	}
+	return nil
+}
+
+func (m *mockRepository) ListEventPending(ctx context.Context, before time.Time, limit int) ([]PaymentTransaction, error) {
+	var txs []PaymentTransaction
+	for _, tx := range m.txs {
+		if tx.EventPending && tx.UpdatedAt.Before(before) && len(txs) < limit {
+			txs = append(txs, *tx)
+		}
+	}
+	return txs, nil
+}
+
+func (m *mockRepository) ListByStatus(ctx context.Context, status PaymentStatus, limit int) ([]PaymentTransaction, error) {
+	var txs []PaymentTransaction
+	for _, tx := range m.txs {
+		if tx.Status == status && len(txs) < limit {
+			txs = append(txs, *tx)
+		}
+	}
+	return txs, nil
+}
+
+func (m *mockRepository) ListByProviderAndStatus(ctx context.Context, providerID string, status PaymentStatus, from, to time.Time) ([]PaymentTransaction, error) {
+	var txs []PaymentTransaction
+	for _, tx := range m.txs {
+		if tx.ProviderID == providerID && tx.Status == status && !tx.UpdatedAt.Before(from) && tx.UpdatedAt.Before(to) {
+			txs = append(txs, *tx)
+		}
+	}
+	return txs, nil
+}
+
+func (m *mockRepository) CreateSettlementReport(ctx context.Context, report *SettlementReport) error {
+	m.reports[report.ID] = report
+	return nil
+}
+
+func (m *mockRepository) GetSettlementReport(ctx context.Context, id string) (*SettlementReport, error) {
+	return m.reports[id], nil
+}
+
+func (m *mockRepository) GetByReferenceNumberForUpdate(ctx context.Context, ref string) (*PaymentTransaction, error) {
+	return m.GetByReferenceNumber(ctx, ref)
+}
+
+// RunInTransaction has no rollback: callers under test commit whatever fn wrote.
+func (m *mockRepository) RunInTransaction(ctx context.Context, fn func(repo PaymentRepository) error) error {
+	m.inTransaction = true
+	defer func() { m.inTransaction = false }()
+	return fn(m)
+}
+
+func (m *mockRepository) RecordAudit(ctx context.Context, recorder audit.Recorder, entry audit.Entry) error {
+	return audit.Record(ctx, recorder, entry)
+}
+
+func (m *mockRepository) CreateRefund(ctx context.Context, refund *PaymentRefund) error {
+	m.refunds = append(m.refunds, *refund)
+	return nil
+}
+
+func (m *mockRepository) GetRefund(ctx context.Context, id string) (*PaymentRefund, error) {
+	for _, r := range m.refunds {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockRepository) UpdateRefund(ctx context.Context, refund *PaymentRefund, expected RefundStatus) (bool, error) {
+	for i, r := range m.refunds {
+		if r.ID == refund.ID {
+			if r.Status != expected {
+				return false, nil
+			}
+			m.refunds[i] = *refund
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRepository) ListRefunds(ctx context.Context, transactionID string) ([]PaymentRefund, error) {
+	var refunds []PaymentRefund
+	for _, r := range m.refunds {
+		if r.TransactionID == transactionID {
+			refunds = append(refunds, r)
+		}
+	}
+	return refunds, nil
+}
+
+func (m *mockRepository) NextReceiptSequence(ctx context.Context, year int) (int64, error) {
+	m.counters[year]++
+	return m.counters[year], nil
+}
+
+func (m *mockRepository) CreateReceipt(ctx context.Context, receipt *PaymentReceipt) error {
+	m.receipts[receipt.ReferenceNumber] = receipt
+	return nil
+}
+
+func (m *mockRepository) GetReceiptByReference(ctx context.Context, ref string) (*PaymentReceipt, error) {
+	return m.receipts[ref], nil
+}
+
+func (m *mockRepository) WithTx(tx *gorm.DB) PaymentRepository {
+	return m
+}
+
+// mockRegistry serves a fixed set of active providers in the given order.
+type mockRegistry struct {
+	order     []string
+	providers map[string]PaymentProvider
+}
+
+func (r *mockRegistry) Get(id string) (PaymentProvider, error) {
+	if p, ok := r.providers[id]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, id)
+}
+
+func (r *mockRegistry) Provider(id string) (PaymentProvider, error) {
+	return r.Get(id)
+}
+
+func (r *mockRegistry) ListInfo() []PaymentProviderInfo {
+	infos := make([]PaymentProviderInfo, 0, len(r.order))
+	for _, id := range r.order {
+		infos = append(infos, PaymentProviderInfo{ID: id, IsActive: true})
+	}
+	return infos
+}
+
+func (r *mockRegistry) GetDefault() (PaymentProvider, error) {
+	return r.Get(r.order[0])
+}
+
+// auditRecorderFunc adapts a function to audit.Recorder.
+type auditRecorderFunc func(ctx context.Context, entry *audit.Entry) error
+
+func (f auditRecorderFunc) Append(ctx context.Context, entry *audit.Entry) error {
+	return f(ctx, entry)
+}
+
+func newTestService(repo *mockRepository, provider *stubProvider) *paymentService {
+	registry := &mockRegistry{order: []string{"mock"}, providers: map[string]PaymentProvider{"mock": provider}}
+	return NewPaymentService(repo, registry, nil, nil, nil).(*paymentService)
+}
+
+func successfulProvider() *stubProvider {
+	return &stubProvider{
+		createFn: func(ctx context.Context, req CreateCheckoutRequest) (*CreateCheckoutResponse, error) {
+			return &CreateCheckoutResponse{SessionID: "sess-1", CheckoutURL: "https://gw.example/" + req.ReferenceNumber}, nil
+		},
+	}
+}
+
+func TestNewReferenceNumber(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	pattern := regexp.MustCompile(`^NSW-PR-2026-[2-9A-HJ-NP-Z]{5}$`)
+	for i := 0; i < 100; i++ {
+		ref, err := NewReferenceNumber(now)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !pattern.MatchString(ref) {
+			t.Fatalf("reference %q does not match NSW-PR-YYYY-XXXXX", ref)
+		}
+	}
+}
+
+func TestCreateCheckoutSession(t *testing.T) {
+	req := CreateCheckoutRequest{
+		Amount:    decimal.NewFromFloat(100.0),
+		Currency:  "LKR",
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+		Metadata:  map[string]string{"task_id": "TASK-123"},
+	}
+
+	t.Run("success with default provider", func(t *testing.T) {
+		repo := newMockRepository()
+		service := newTestService(repo, successfulProvider())
+
+		resp, err := service.CreateCheckoutSession(context.Background(), req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.ProviderID != "mock" || resp.SessionID != "sess-1" {
+			t.Fatalf("unexpected response: %+v", resp)
+		}
+		tx := repo.txs[resp.ReferenceNumber]
+		if tx == nil {
+			t.Fatalf("expected transaction stored under %s", resp.ReferenceNumber)
+		}
+		if tx.Status != PaymentStatusPending || tx.ProviderID != "mock" || tx.SessionID != "sess-1" || tx.TaskID != "TASK-123" {
+			t.Fatalf("unexpected transaction: %+v", tx)
+		}
+		if resp.CheckoutURL != "https://gw.example/"+resp.ReferenceNumber {
+			t.Fatalf("expected provider to receive the generated reference, got %s", resp.CheckoutURL)
+		}
+	})
+
+	t.Run("missing task_id", func(t *testing.T) {
+		service := newTestService(newMockRepository(), successfulProvider())
+		reqMissing := req
+		reqMissing.Metadata = nil
+		if _, err := service.CreateCheckoutSession(context.Background(), reqMissing); err == nil {
+			t.Fatal("expected error for missing task_id, got nil")
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		service := newTestService(newMockRepository(), successfulProvider())
+		reqUnknown := req
+		reqUnknown.ProviderID = "unknown"
+		if _, err := service.CreateCheckoutSession(context.Background(), reqUnknown); err == nil {
+			t.Fatal("expected error for unknown provider, got nil")
+		}
+	})
+
+	t.Run("regenerates reference on collision", func(t *testing.T) {
+		repo := newMockRepository()
+		service := newTestService(repo, successfulProvider())
+		repo.txs["NSW-PR-2026-TAKEN"] = &PaymentTransaction{ReferenceNumber: "NSW-PR-2026-TAKEN"}
+		candidates := []string{"NSW-PR-2026-TAKEN", "NSW-PR-2026-FREE2"}
+		service.newReference = func(time.Time) (string, error) {
+			ref := candidates[0]
+			candidates = candidates[1:]
+			return ref, nil
+		}
+
+		resp, err := service.CreateCheckoutSession(context.Background(), req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.ReferenceNumber != "NSW-PR-2026-FREE2" {
+			t.Fatalf("expected regenerated reference, got %s", resp.ReferenceNumber)
+		}
+	})
+
+	t.Run("gives up after repeated collisions", func(t *testing.T) {
+		repo := newMockRepository()
+		service := newTestService(repo, successfulProvider())
+		repo.txs["NSW-PR-2026-TAKEN"] = &PaymentTransaction{ReferenceNumber: "NSW-PR-2026-TAKEN"}
+		service.newReference = func(time.Time) (string, error) { return "NSW-PR-2026-TAKEN", nil }
+
+		if _, err := service.CreateCheckoutSession(context.Background(), req); err == nil {
+			t.Fatal("expected error after exhausting reference attempts, got nil")
+		}
+	})
+
+	t.Run("provider error marks transaction failed", func(t *testing.T) {
+		repo := newMockRepository()
+		provider := &stubProvider{
+			createFn: func(ctx context.Context, req CreateCheckoutRequest) (*CreateCheckoutResponse, error) {
+				return nil, fmt.Errorf("gateway down")
+			},
+		}
+		service := newTestService(repo, provider)
+
+		if _, err := service.CreateCheckoutSession(context.Background(), req); err == nil {
+			t.Fatal("expected error for provider failure, got nil")
+		}
+		for _, tx := range repo.txs {
+			if tx.Status != PaymentStatusFailed {
+				t.Fatalf("expected reserved transaction to be FAILED, got %s", tx.Status)
+			}
+		}
+	})
+}
+
+func TestProcessWebhook(t *testing.T) {
+	newFixture := func(status PaymentStatus) (*paymentService, *mockRepository, *[]InternalPaymentEvent) {
+		repo := newMockRepository()
+		repo.txs["NSW-PR-2026-AB234"] = &PaymentTransaction{
+			ID:              "id-NSW-PR-2026-AB234",
+			ReferenceNumber: "NSW-PR-2026-AB234",
+			TaskID:          "TASK-123",
+			ProviderID:      "mock",
+			Status:          PaymentStatusPending,
+			Amount:          decimal.NewFromFloat(100.0),
+			Currency:        "LKR",
+		}
+		provider := &stubProvider{
+			parseFn: func(ctx context.Context, body []byte, headers map[string][]string) (*WebhookPayload, error) {
+				return &WebhookPayload{ReferenceNumber: string(body), Status: status, GatewayTransactionID: "GW-1", Amount: decimal.NewFromInt(100), Currency: "LKR"}, nil
+			},
+		}
+		service := newTestService(repo, provider)
+		var events []InternalPaymentEvent
+		service.RegisterEventHandler(func(ctx context.Context, event InternalPaymentEvent) error {
+			events = append(events, event)
+			return nil
+		})
+		return service, repo, &events
+	}
+
+	t.Run("success emits confirmed event", func(t *testing.T) {
+		service, repo, events := newFixture(PaymentStatusSuccess)
+
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("NSW-PR-2026-AB234"), nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if repo.txs["NSW-PR-2026-AB234"].Status != PaymentStatusSuccess {
+			t.Fatalf("expected status SUCCESS, got %s", repo.txs["NSW-PR-2026-AB234"].Status)
+		}
+		if len(*events) != 1 || (*events)[0].EventType != PaymentEventConfirmed || (*events)[0].Data.TaskID != "TASK-123" {
+			t.Fatalf("expected one PAYMENT_CONFIRMED event for TASK-123, got %+v", *events)
+		}
+		if !(*events)[0].Data.AmountPaid.Equal(decimal.NewFromFloat(100.0)) {
+			t.Fatalf("expected amount paid to be the transaction amount, got %s", (*events)[0].Data.AmountPaid)
+		}
+		if repo.txs["NSW-PR-2026-AB234"].EventPending {
+			t.Fatal("expected delivered event to be cleared")
+		}
+	})
+
+	t.Run("confirmation for another amount is held", func(t *testing.T) {
+		for name, payload := range map[string]WebhookPayload{
+			"short payment":  {Amount: decimal.NewFromInt(10), Currency: "LKR"},
+			"other currency": {Amount: decimal.NewFromInt(100), Currency: "USD"},
+			"no amount":      {},
+		} {
+			t.Run(name, func(t *testing.T) {
+				service, repo, events := newFixture(PaymentStatusSuccess)
+				service.registry.(*mockRegistry).providers["mock"] = &stubProvider{
+					parseFn: func(ctx context.Context, body []byte, headers map[string][]string) (*WebhookPayload, error) {
+						p := payload
+						p.ReferenceNumber, p.Status = string(body), PaymentStatusSuccess
+						return &p, nil
+					},
+				}
+
+				if err := service.ProcessWebhook(context.Background(), "mock", []byte("NSW-PR-2026-AB234"), nil); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				tx := repo.txs["NSW-PR-2026-AB234"]
+				if tx.Status != PaymentStatusAmountMismatch || tx.EventPending {
+					t.Fatalf("expected AMOUNT_MISMATCH without a pending event, got %s (pending %v)", tx.Status, tx.EventPending)
+				}
+				if len(*events) != 0 {
+					t.Fatalf("expected the task not to be advanced, got %+v", *events)
+				}
+			})
+		}
+	})
+
+	t.Run("status change is audited as the provider", func(t *testing.T) {
+		service, repo, _ := newFixture(PaymentStatusSuccess)
+		var entries []audit.Entry
+		service.auditLog = auditRecorderFunc(func(ctx context.Context, entry *audit.Entry) error {
+			if !repo.inTransaction {
+				t.Error("expected the entry to be written in the status update's transaction")
+			}
+			entries = append(entries, *entry)
+			return nil
+		})
+
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("NSW-PR-2026-AB234"), nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("expected one audit entry, got %d", len(entries))
+		}
+		entry := entries[0]
+		if entry.Action != audit.ActionPaymentStatusChange || entry.ActorKind != audit.ActorProvider || entry.ActorID != "mock" {
+			t.Fatalf("unexpected audit entry: %+v", entry)
+		}
+		if entry.StateBefore != string(PaymentStatusPending) || entry.StateAfter != string(PaymentStatusSuccess) || entry.TaskID != "TASK-123" {
+			t.Fatalf("unexpected audit states: %+v", entry)
+		}
+	})
+
+	t.Run("unaudited status change is not delivered", func(t *testing.T) {
+		service, _, events := newFixture(PaymentStatusSuccess)
+		service.auditLog = auditRecorderFunc(func(ctx context.Context, entry *audit.Entry) error {
+			return errors.New("audit log unavailable")
+		})
+
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("NSW-PR-2026-AB234"), nil); err == nil {
+			t.Fatal("expected the webhook to fail so the gateway retries it")
+		}
+		if len(*events) != 0 {
+			t.Fatalf("expected no events, got %+v", *events)
+		}
+	})
+
+	t.Run("failure emits failed event", func(t *testing.T) {
+		service, _, events := newFixture(PaymentStatusFailed)
+
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("NSW-PR-2026-AB234"), nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(*events) != 1 || (*events)[0].EventType != PaymentEventFailed {
+			t.Fatalf("expected one PAYMENT_FAILED event, got %+v", *events)
+		}
+	})
+
+	t.Run("idempotent replay", func(t *testing.T) {
+		service, _, events := newFixture(PaymentStatusSuccess)
+
+		for i := 0; i < 2; i++ {
+			if err := service.ProcessWebhook(context.Background(), "mock", []byte("NSW-PR-2026-AB234"), nil); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		if len(*events) != 1 {
+			t.Fatalf("expected a single event for replayed webhook, got %d", len(*events))
+		}
+	})
+
+	t.Run("unknown reference", func(t *testing.T) {
+		service, _, _ := newFixture(PaymentStatusSuccess)
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("UNKNOWN"), nil); err == nil {
+			t.Fatal("expected error for unknown reference, got nil")
+		}
+	})
+
+	t.Run("provider mismatch", func(t *testing.T) {
+		service, repo, events := newFixture(PaymentStatusSuccess)
+		repo.txs["NSW-PR-2026-AB234"].ProviderID = "lankapay"
+
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("NSW-PR-2026-AB234"), nil); err == nil {
+			t.Fatal("expected error for provider mismatch, got nil")
+		}
+		if len(*events) != 0 {
+			t.Fatalf("expected no events, got %+v", *events)
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		service, _, _ := newFixture(PaymentStatusSuccess)
+		if err := service.ProcessWebhook(context.Background(), "unknown", []byte("NSW-PR-2026-AB234"), nil); err == nil {
+			t.Fatal("expected error for unknown provider, got nil")
+		}
+	})
+
+	t.Run("event handler error", func(t *testing.T) {
+		service, repo, _ := newFixture(PaymentStatusSuccess)
+		service.RegisterEventHandler(func(ctx context.Context, event InternalPaymentEvent) error {
+			return fmt.Errorf("task rejected")
+		})
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("NSW-PR-2026-AB234"), nil); err == nil {
+			t.Fatal("expected error when event delivery fails, got nil")
+		}
+		if !repo.txs["NSW-PR-2026-AB234"].EventPending {
+			t.Fatal("expected undelivered event to stay pending")
+		}
+	})
+
+	t.Run("retry delivers pending event", func(t *testing.T) {
+		service, repo, events := newFixture(PaymentStatusSuccess)
+		handler := service.eventHandler
+		service.RegisterEventHandler(func(ctx context.Context, event InternalPaymentEvent) error {
+			return fmt.Errorf("task unavailable")
+		})
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("NSW-PR-2026-AB234"), nil); err == nil {
+			t.Fatal("expected error when event delivery fails, got nil")
+		}
+
+		// The gateway retries after the 5xx.
+		service.RegisterEventHandler(handler)
+		if err := service.ProcessWebhook(context.Background(), "mock", []byte("NSW-PR-2026-AB234"), nil); err != nil {
+			t.Fatalf("expected retry to deliver the event, got %v", err)
+		}
+		if len(*events) != 1 || (*events)[0].EventType != PaymentEventConfirmed || (*events)[0].Data.GatewayTransactionID != "GW-1" {
+			t.Fatalf("expected one PAYMENT_CONFIRMED event, got %+v", *events)
+		}
+		if repo.txs["NSW-PR-2026-AB234"].EventPending {
+			t.Fatal("expected delivered event to be cleared")
+		}
+	})
+}
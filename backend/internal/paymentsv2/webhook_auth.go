@@ -0,0 +1,266 @@
+package paymentsv2
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrWebhookUnauthorized is returned when a gateway request fails signature,
+	// timestamp or replay checks. Handlers map it to HTTP 401.
+	ErrWebhookUnauthorized = errors.New("payment webhook authentication failed")
+	// ErrWebhookReplayed is returned by a NonceStore when a nonce has already been seen.
+	ErrWebhookReplayed = errors.New("payment webhook nonce already used")
+)
+
+// Gateway endpoints recorded in the webhook audit log.
+const (
+	WebhookEndpointWebhook  = "webhook"
+	WebhookEndpointValidate = "validate"
+)
+
+// WebhookSchemeHMACSHA256 signs "<timestamp>.<raw body>" with a shared secret.
+const WebhookSchemeHMACSHA256 = "hmac-sha256"
+
+const (
+	defaultSignatureHeader = "X-NSW-Signature"
+	defaultTimestampHeader = "X-NSW-Timestamp"
+	defaultMaxSkew         = 5 * time.Minute
+)
+
+// WebhookAuthConfig is the "webhook" block of a payment_methods.json entry.
+// The secret itself never appears in the file; SecretEnv names the environment
+// variable that holds it.
+type WebhookAuthConfig struct {
+	Scheme          string `json:"scheme"`
+	SecretEnv       string `json:"secret_env"`
+	SignatureHeader string `json:"signature_header,omitempty"`
+	TimestampHeader string `json:"timestamp_header,omitempty"`
+	NonceHeader     string `json:"nonce_header,omitempty"` // Optional: the signature is used as nonce when empty
+	MaxSkewSeconds  int    `json:"max_skew_seconds,omitempty"`
+}
+
+// VerifiedWebhook identifies an authenticated gateway request for replay detection.
+type VerifiedWebhook struct {
+	Nonce string
+	// ExpiresAt is when the request would fail the timestamp check anyway, so the
+	// nonce does not need to be remembered any longer.
+	ExpiresAt time.Time
+}
+
+// WebhookVerifier authenticates raw gateway requests for one provider.
+// Providers with a gateway-specific scheme can implement it themselves; the registry
+// then uses the provider instead of building a verifier from configuration.
+type WebhookVerifier interface {
+	VerifyWebhook(body []byte, headers map[string][]string, now time.Time) (*VerifiedWebhook, error)
+}
+
+// WebhookVerifierSource resolves the verifier of an active provider.
+type WebhookVerifierSource interface {
+	Verifier(providerID string) (WebhookVerifier, error)
+}
+
+// NonceStore remembers verified nonces until they expire.
+type NonceStore interface {
+	// Remember records nonce for providerID and returns ErrWebhookReplayed if it was already recorded.
+	Remember(ctx context.Context, providerID, nonce string, expiresAt time.Time) error
+	// Forget removes a recorded nonce, so that the same request is accepted again.
+	Forget(ctx context.Context, providerID, nonce string) error
+}
+
+// HMACVerifier verifies HMAC-SHA256 signatures over "<timestamp>.<raw body>".
+// The signature header carries the hex digest, optionally prefixed with "sha256=",
+// and the timestamp header carries Unix seconds.
+type HMACVerifier struct {
+	Secret          []byte
+	SignatureHeader string
+	TimestampHeader string
+	NonceHeader     string
+	MaxSkew         time.Duration
+}
+
+// NewHMACVerifier builds an HMACVerifier from a payment method's webhook block,
+// reading the secret from the configured environment variable.
+func NewHMACVerifier(cfg WebhookAuthConfig) (*HMACVerifier, error) {
+	if cfg.SecretEnv == "" {
+		return nil, fmt.Errorf("webhook.secret_env is required for scheme %s", WebhookSchemeHMACSHA256)
+	}
+	secret := os.Getenv(cfg.SecretEnv)
+	if secret == "" {
+		return nil, fmt.Errorf("webhook secret environment variable %s is not set", cfg.SecretEnv)
+	}
+	if cfg.MaxSkewSeconds < 0 {
+		return nil, fmt.Errorf("webhook.max_skew_seconds must not be negative")
+	}
+
+	v := &HMACVerifier{
+		Secret:          []byte(secret),
+		SignatureHeader: cfg.SignatureHeader,
+		TimestampHeader: cfg.TimestampHeader,
+		NonceHeader:     cfg.NonceHeader,
+		MaxSkew:         time.Duration(cfg.MaxSkewSeconds) * time.Second,
+	}
+	if v.SignatureHeader == "" {
+		v.SignatureHeader = defaultSignatureHeader
+	}
+	if v.TimestampHeader == "" {
+		v.TimestampHeader = defaultTimestampHeader
+	}
+	if v.MaxSkew == 0 {
+		v.MaxSkew = defaultMaxSkew
+	}
+	return v, nil
+}
+
+// newWebhookVerifier builds the verifier for a payment method entry.
+func newWebhookVerifier(cfg WebhookAuthConfig) (WebhookVerifier, error) {
+	switch cfg.Scheme {
+	case WebhookSchemeHMACSHA256:
+		return NewHMACVerifier(cfg)
+	case "":
+		return nil, fmt.Errorf("webhook.scheme is required")
+	default:
+		return nil, fmt.Errorf("unsupported webhook.scheme %q", cfg.Scheme)
+	}
+}
+
+// VerifyWebhook checks the timestamp window and the signature in constant time.
+func (v *HMACVerifier) VerifyWebhook(body []byte, headers map[string][]string, now time.Time) (*VerifiedWebhook, error) {
+	h := http.Header(headers)
+
+	rawTimestamp := h.Get(v.TimestampHeader)
+	if rawTimestamp == "" {
+		return nil, fmt.Errorf("missing %s header", v.TimestampHeader)
+	}
+	unix, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header", v.TimestampHeader)
+	}
+	signedAt := time.Unix(unix, 0)
+	if skew := now.Sub(signedAt); skew > v.MaxSkew || skew < -v.MaxSkew {
+		return nil, fmt.Errorf("timestamp outside allowed window of %s", v.MaxSkew)
+	}
+
+	rawSignature := strings.TrimPrefix(h.Get(v.SignatureHeader), "sha256=")
+	if rawSignature == "" {
+		return nil, fmt.Errorf("missing %s header", v.SignatureHeader)
+	}
+	signature, err := hex.DecodeString(rawSignature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header", v.SignatureHeader)
+	}
+	if !hmac.Equal(signature, v.Sign(rawTimestamp, body)) {
+		return nil, fmt.Errorf("signature mismatch")
+	}
+
+	nonce := strings.ToLower(rawSignature)
+	if v.NonceHeader != "" {
+		if nonce = h.Get(v.NonceHeader); nonce == "" {
+			return nil, fmt.Errorf("missing %s header", v.NonceHeader)
+		}
+	}
+	return &VerifiedWebhook{Nonce: nonce, ExpiresAt: signedAt.Add(v.MaxSkew)}, nil
+}
+
+// Sign returns the HMAC-SHA256 of "<timestamp>.<body>".
+func (v *HMACVerifier) Sign(timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, v.Secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// WebhookRequest is a raw gateway request to authenticate.
+type WebhookRequest struct {
+	ProviderID string
+	Endpoint   string
+	RemoteAddr string
+	Body       []byte
+	Headers    map[string][]string
+}
+
+// WebhookAuthenticator guards the unauthenticated gateway endpoints. It verifies the
+// provider's signature, rejects replayed nonces and audits every rejection.
+type WebhookAuthenticator struct {
+	verifiers WebhookVerifierSource
+	nonces    NonceStore
+	audit     WebhookAuditRepository
+	now       func() time.Time
+}
+
+// NewWebhookAuthenticator creates a WebhookAuthenticator.
+func NewWebhookAuthenticator(verifiers WebhookVerifierSource, nonces NonceStore, audit WebhookAuditRepository) *WebhookAuthenticator {
+	return &WebhookAuthenticator{
+		verifiers: verifiers,
+		nonces:    nonces,
+		audit:     audit,
+		now:       time.Now,
+	}
+}
+
+// Authenticate returns the verified request if req carries a valid, fresh signature for
+// its provider. Its nonce is remembered, so the same request is rejected as a replay
+// until the caller releases it. Authentication failures wrap ErrWebhookUnauthorized;
+// unknown or inactive providers return the registry error unchanged.
+func (a *WebhookAuthenticator) Authenticate(ctx context.Context, req WebhookRequest) (*VerifiedWebhook, error) {
+	verifier, err := a.verifiers.Verifier(req.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	verified, err := verifier.VerifyWebhook(req.Body, req.Headers, a.now())
+	if err != nil {
+		return nil, a.reject(ctx, req, err)
+	}
+
+	if err := a.nonces.Remember(ctx, req.ProviderID, verified.Nonce, verified.ExpiresAt); err != nil {
+		if errors.Is(err, ErrWebhookReplayed) {
+			return nil, a.reject(ctx, req, err)
+		}
+		return nil, fmt.Errorf("failed to record webhook nonce: %w", err)
+	}
+	return verified, nil
+}
+
+// Release forgets the nonce of an authenticated request that could not be processed, so
+// that the gateway's retry of the same request is not rejected as a replay. Processing is
+// idempotent, so accepting the request again is safe.
+func (a *WebhookAuthenticator) Release(ctx context.Context, providerID string, verified *VerifiedWebhook) {
+	if err := a.nonces.Forget(ctx, providerID, verified.Nonce); err != nil {
+		slog.ErrorContext(ctx, "failed to release payment webhook nonce, a retry will be rejected as a replay", "provider", providerID, "error", err)
+	}
+}
+
+// reject records the failed request in the audit log and returns an unauthorized error.
+func (a *WebhookAuthenticator) reject(ctx context.Context, req WebhookRequest, cause error) error {
+	slog.WarnContext(ctx, "rejected unauthenticated payment gateway request",
+		"provider", req.ProviderID, "endpoint", req.Endpoint, "remote_addr", req.RemoteAddr, "reason", cause)
+
+	digest := sha256.Sum256(req.Body)
+	entry := &WebhookAuditLog{
+		ID:         uuid.NewString(),
+		ProviderID: req.ProviderID,
+		Endpoint:   req.Endpoint,
+		Reason:     cause.Error(),
+		RemoteAddr: req.RemoteAddr,
+		Headers:    req.Headers,
+		BodySHA256: hex.EncodeToString(digest[:]),
+	}
+	if err := a.audit.Create(ctx, entry); err != nil {
+		slog.ErrorContext(ctx, "failed to write payment webhook audit log", "provider", req.ProviderID, "error", err)
+	}
+	return fmt.Errorf("%w: %v", ErrWebhookUnauthorized, cause)
+}
@@ -0,0 +1,213 @@
+package paymentsv2
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type memoryNonceStore struct {
+	seen map[string]time.Time
+}
+
+func newMemoryNonceStore() *memoryNonceStore {
+	return &memoryNonceStore{seen: make(map[string]time.Time)}
+}
+
+func (s *memoryNonceStore) Remember(ctx context.Context, providerID, nonce string, expiresAt time.Time) error {
+	key := providerID + "/" + nonce
+	if _, ok := s.seen[key]; ok {
+		return ErrWebhookReplayed
+	}
+	s.seen[key] = expiresAt
+	return nil
+}
+
+func (s *memoryNonceStore) Forget(ctx context.Context, providerID, nonce string) error {
+	delete(s.seen, providerID+"/"+nonce)
+	return nil
+}
+
+type memoryAuditRepository struct {
+	entries []*WebhookAuditLog
+}
+
+func (r *memoryAuditRepository) Create(ctx context.Context, entry *WebhookAuditLog) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+type staticVerifierSource map[string]WebhookVerifier
+
+func (s staticVerifierSource) Verifier(providerID string) (WebhookVerifier, error) {
+	if v, ok := s[providerID]; ok {
+		return v, nil
+	}
+	return nil, ErrProviderNotFound
+}
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestVerifier() *HMACVerifier {
+	return &HMACVerifier{
+		Secret:          []byte("s3cret"),
+		SignatureHeader: defaultSignatureHeader,
+		TimestampHeader: defaultTimestampHeader,
+		MaxSkew:         5 * time.Minute,
+	}
+}
+
+// signedHeaders returns headers carrying a valid signature of body at signedAt.
+func signedHeaders(v *HMACVerifier, body []byte, signedAt time.Time) map[string][]string {
+	ts := strconv.FormatInt(signedAt.Unix(), 10)
+	return map[string][]string{
+		"X-Nsw-Timestamp": {ts},
+		"X-Nsw-Signature": {"sha256=" + hex.EncodeToString(v.Sign(ts, body))},
+	}
+}
+
+func TestHMACVerifier_VerifyWebhook(t *testing.T) {
+	v := newTestVerifier()
+	body := []byte(`{"reference_number":"NSW-PR-2026-AB234","status":"SUCCESS"}`)
+
+	t.Run("valid signature", func(t *testing.T) {
+		verified, err := v.VerifyWebhook(body, signedHeaders(v, body, testNow.Add(-time.Minute)), testNow)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if verified.Nonce == "" || !verified.ExpiresAt.Equal(testNow.Add(4*time.Minute)) {
+			t.Fatalf("unexpected verified webhook: %+v", verified)
+		}
+	})
+
+	t.Run("nonce header", func(t *testing.T) {
+		withNonce := *v
+		withNonce.NonceHeader = "X-Nsw-Nonce"
+		headers := signedHeaders(v, body, testNow)
+		if _, err := withNonce.VerifyWebhook(body, headers, testNow); err == nil {
+			t.Fatal("expected error for missing nonce header, got nil")
+		}
+		headers["X-Nsw-Nonce"] = []string{"n-1"}
+		verified, err := withNonce.VerifyWebhook(body, headers, testNow)
+		if err != nil || verified.Nonce != "n-1" {
+			t.Fatalf("expected nonce n-1, got %+v, %v", verified, err)
+		}
+	})
+
+	failures := map[string]func() map[string][]string{
+		"tampered body": func() map[string][]string {
+			return signedHeaders(v, []byte(`{"status":"FAILED"}`), testNow)
+		},
+		"wrong secret": func() map[string][]string {
+			other := newTestVerifier()
+			other.Secret = []byte("other")
+			return signedHeaders(other, body, testNow)
+		},
+		"stale timestamp": func() map[string][]string {
+			return signedHeaders(v, body, testNow.Add(-6*time.Minute))
+		},
+		"future timestamp": func() map[string][]string {
+			return signedHeaders(v, body, testNow.Add(6*time.Minute))
+		},
+		"missing signature": func() map[string][]string {
+			h := signedHeaders(v, body, testNow)
+			delete(h, "X-Nsw-Signature")
+			return h
+		},
+		"missing timestamp": func() map[string][]string {
+			h := signedHeaders(v, body, testNow)
+			delete(h, "X-Nsw-Timestamp")
+			return h
+		},
+		"non-hex signature": func() map[string][]string {
+			h := signedHeaders(v, body, testNow)
+			h["X-Nsw-Signature"] = []string{"not-hex"}
+			return h
+		},
+	}
+	for name, headers := range failures {
+		t.Run(name, func(t *testing.T) {
+			if _, err := v.VerifyWebhook(body, headers(), testNow); err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestWebhookAuthenticator_Authenticate(t *testing.T) {
+	v := newTestVerifier()
+	body := []byte(`{"reference_number":"NSW-PR-2026-AB234","status":"SUCCESS"}`)
+
+	newAuthenticator := func() (*WebhookAuthenticator, *memoryAuditRepository) {
+		audit := &memoryAuditRepository{}
+		a := NewWebhookAuthenticator(staticVerifierSource{"mock": v}, newMemoryNonceStore(), audit)
+		a.now = func() time.Time { return testNow }
+		return a, audit
+	}
+
+	t.Run("accepts once and rejects replay", func(t *testing.T) {
+		a, audit := newAuthenticator()
+		req := WebhookRequest{ProviderID: "mock", Endpoint: WebhookEndpointWebhook, Body: body, Headers: signedHeaders(v, body, testNow)}
+
+		if _, err := a.Authenticate(context.Background(), req); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := a.Authenticate(context.Background(), req); !errors.Is(err, ErrWebhookUnauthorized) {
+			t.Fatalf("expected ErrWebhookUnauthorized for replay, got %v", err)
+		}
+		if len(audit.entries) != 1 || audit.entries[0].Reason != ErrWebhookReplayed.Error() {
+			t.Fatalf("expected one replay audit entry, got %+v", audit.entries)
+		}
+	})
+
+	t.Run("released nonce is accepted again", func(t *testing.T) {
+		a, _ := newAuthenticator()
+		req := WebhookRequest{ProviderID: "mock", Endpoint: WebhookEndpointWebhook, Body: body, Headers: signedHeaders(v, body, testNow)}
+
+		verified, err := a.Authenticate(context.Background(), req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		a.Release(context.Background(), "mock", verified)
+		if _, err := a.Authenticate(context.Background(), req); err != nil {
+			t.Fatalf("expected released request to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("audits invalid signature", func(t *testing.T) {
+		a, audit := newAuthenticator()
+		req := WebhookRequest{
+			ProviderID: "mock",
+			Endpoint:   WebhookEndpointValidate,
+			RemoteAddr: "203.0.113.7:4242",
+			Body:       body,
+			Headers:    signedHeaders(v, []byte("other"), testNow),
+		}
+
+		if _, err := a.Authenticate(context.Background(), req); !errors.Is(err, ErrWebhookUnauthorized) {
+			t.Fatalf("expected ErrWebhookUnauthorized, got %v", err)
+		}
+		if len(audit.entries) != 1 {
+			t.Fatalf("expected one audit entry, got %d", len(audit.entries))
+		}
+		entry := audit.entries[0]
+		if entry.ProviderID != "mock" || entry.Endpoint != WebhookEndpointValidate || entry.RemoteAddr != "203.0.113.7:4242" || len(entry.BodySHA256) != 64 {
+			t.Fatalf("unexpected audit entry: %+v", entry)
+		}
+	})
+
+	t.Run("unknown provider is not audited", func(t *testing.T) {
+		a, audit := newAuthenticator()
+		req := WebhookRequest{ProviderID: "unknown", Body: body, Headers: signedHeaders(v, body, testNow)}
+
+		if _, err := a.Authenticate(context.Background(), req); !errors.Is(err, ErrProviderNotFound) {
+			t.Fatalf("expected ErrProviderNotFound, got %v", err)
+		}
+		if len(audit.entries) != 0 {
+			t.Fatalf("expected no audit entries, got %+v", audit.entries)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package paymentsv2
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookAuditRepository persists rejected gateway requests.
+type WebhookAuditRepository interface {
+	Create(ctx context.Context, entry *WebhookAuditLog) error
+}
+
+type webhookAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookAuditRepository creates a new instance of WebhookAuditRepository.
+func NewWebhookAuditRepository(db *gorm.DB) WebhookAuditRepository {
+	return &webhookAuditRepository{db: db}
+}
+
+// Create appends an audit entry.
+func (r *webhookAuditRepository) Create(ctx context.Context, entry *WebhookAuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+type nonceStore struct {
+	db *gorm.DB
+}
+
+// NewNonceStore creates a database-backed NonceStore. The primary key on
+// (provider_id, nonce) makes replay detection safe across server replicas.
+func NewNonceStore(db *gorm.DB) NonceStore {
+	return &nonceStore{db: db}
+}
+
+// Remember inserts the nonce and reports ErrWebhookReplayed when it already exists.
+// Expired nonces of the provider are purged on the way, since their requests can no
+// longer pass the timestamp check.
+func (s *nonceStore) Remember(ctx context.Context, providerID, nonce string, expiresAt time.Time) error {
+	db := s.db.WithContext(ctx)
+
+	if err := db.Where("provider_id = ? AND expires_at < ?", providerID, time.Now()).Delete(&WebhookNonce{}).Error; err != nil {
+		return fmt.Errorf("failed to purge expired webhook nonces: %w", err)
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&WebhookNonce{
+		ProviderID: providerID,
+		Nonce:      nonce,
+		ExpiresAt:  expiresAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookReplayed
+	}
+	return nil
+}
+
+// Forget deletes the nonce.
+func (s *nonceStore) Forget(ctx context.Context, providerID, nonce string) error {
+	return s.db.WithContext(ctx).Where("provider_id = ? AND nonce = ?", providerID, nonce).Delete(&WebhookNonce{}).Error
+}
@@ -9,3 +9,10 @@ var ErrUserNotFound = errors.New("user not found")
 
 // ErrInvalidUserID is returned when an invalid user ID is provided.
 var ErrInvalidUserID = errors.New("invalid user ID")
+
+// ErrInvalidProfile is returned when a profile fails validation.
+var ErrInvalidProfile = errors.New("invalid profile")
+
+// ErrVerifiedFieldChanged is returned when a trader tries to change a field that was verified,
+// e.g. by a completed pre-consignment.
+var ErrVerifiedFieldChanged = errors.New("verified profile field cannot be changed")
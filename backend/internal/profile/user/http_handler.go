@@ -0,0 +1,152 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/utils"
+)
+
+// Me is the response of GET /api/v1/me: the identity of the requesting user and their profile.
+type Me struct {
+	ID          string    `json:"id"`
+	Email       string    `json:"email"`
+	PhoneNumber string    `json:"phoneNumber"`
+	OUID        string    `json:"ouId"`
+	Roles       []string  `json:"roles"`
+	Profile     Profile   `json:"profile"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// HTTPHandler exposes the profile of the requesting user.
+type HTTPHandler struct {
+	service Service
+}
+
+// NewHTTPHandler creates a new HTTPHandler for user profiles
+func NewHTTPHandler(service Service) *HTTPHandler {
+	return &HTTPHandler{service: service}
+}
+
+// HandleGetMe handles GET /api/v1/me
+func (h *HTTPHandler) HandleGetMe(w http.ResponseWriter, r *http.Request) {
+	authUser, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	record, err := h.service.GetUser(authUser.ID)
+	if err != nil {
+		writeServiceError(w, r, "failed to retrieve user profile", err)
+		return
+	}
+	profile, _, err := decodeProfile(record.Data)
+	if err != nil {
+		writeServiceError(w, r, "failed to retrieve user profile", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, Me{
+		ID:          record.ID,
+		Email:       record.Email,
+		PhoneNumber: record.PhoneNumber,
+		OUID:        record.OUID,
+		Roles:       authUser.Roles,
+		Profile:     profile,
+		UpdatedAt:   record.UpdatedAt,
+	})
+}
+
+// HandleUpdateMe handles PUT /api/v1/me
+func (h *HTTPHandler) HandleUpdateMe(w http.ResponseWriter, r *http.Request) {
+	authUser, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	var profile Profile
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profile); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	updated, err := h.service.UpdateProfile(authUser.ID, profile)
+	if err != nil {
+		writeServiceError(w, r, "failed to update user profile", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, updated)
+}
+
+// HandleGetMeHistory handles GET /api/v1/me/history
+func (h *HTTPHandler) HandleGetMeHistory(w http.ResponseWriter, r *http.Request) {
+	authUser, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	offset, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	history, err := h.service.GetProfileHistory(authUser.ID, offset, limit)
+	if err != nil {
+		writeServiceError(w, r, "failed to retrieve profile history", err)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, history)
+}
+
+// requireUser returns the requesting user, writing an error response unless the request was
+// made by a user with a persisted record.
+func requireUser(w http.ResponseWriter, r *http.Request) (*auth.UserContext, bool) {
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil || (authCtx.User == nil && authCtx.Client == nil) {
+		writeJSONError(w, http.StatusUnauthorized, "authentication required")
+		return nil, false
+	}
+	if authCtx.User == nil {
+		writeJSONError(w, http.StatusForbidden, "profiles are only available to users")
+		return nil, false
+	}
+	if authCtx.User.ID == "" {
+		// The auth middleware could not resolve the user record.
+		writeJSONError(w, http.StatusServiceUnavailable, "user profile is unavailable")
+		return nil, false
+	}
+	return authCtx.User, true
+}
+
+func writeServiceError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrInvalidProfile):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrVerifiedFieldChanged):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), message, "error", err)
+		writeJSONError(w, http.StatusInternalServerError, message)
+	}
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+// writeJSONError sets Content-Type: application/json and writes a consistent JSON error body.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSONResponse(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,112 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// fakeService serves a single user record from memory.
+type fakeService struct {
+	Service
+	record    *Record
+	updateErr error
+}
+
+func (f *fakeService) GetUser(id string) (*Record, error) {
+	if f.record == nil || f.record.ID != id {
+		return nil, ErrUserNotFound
+	}
+	return f.record, nil
+}
+
+func (f *fakeService) UpdateProfile(_ string, profile Profile) (*Profile, error) {
+	if f.updateErr != nil {
+		return nil, f.updateErr
+	}
+	return &profile, nil
+}
+
+func serveProfileRequest(handler http.HandlerFunc, method, body string, authCtx *auth.AuthContext) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "http://example.com/api/v1/me", strings.NewReader(body))
+	if authCtx != nil {
+		req = req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, authCtx))
+	}
+	recorder := httptest.NewRecorder()
+	handler(recorder, req)
+	return recorder
+}
+
+func traderContext() *auth.AuthContext {
+	return &auth.AuthContext{User: &auth.UserContext{ID: "user-123", Roles: []string{auth.RoleTrader}}}
+}
+
+func TestHTTPHandler_HandleGetMe(t *testing.T) {
+	service := &fakeService{record: &Record{ID: "user-123", Email: "trader@example.com", Data: []byte(`{"profile": {"companyName": "ABC"}}`)}}
+	handler := NewHTTPHandler(service)
+
+	recorder := serveProfileRequest(handler.HandleGetMe, http.MethodGet, "", traderContext())
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var me Me
+	if err := json.Unmarshal(recorder.Body.Bytes(), &me); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if me.ID != "user-123" || me.Email != "trader@example.com" || me.Profile.CompanyName != "ABC" || len(me.Roles) != 1 {
+		t.Fatalf("unexpected response: %#v", me)
+	}
+}
+
+func TestHTTPHandler_RequiresUser(t *testing.T) {
+	handler := NewHTTPHandler(&fakeService{})
+
+	tests := []struct {
+		name    string
+		authCtx *auth.AuthContext
+		want    int
+	}{
+		{"unauthenticated", nil, http.StatusUnauthorized},
+		{"client", &auth.AuthContext{Client: &auth.ClientContext{ClientID: "NPQS_TO_NSW"}}, http.StatusForbidden},
+		{"unresolved user record", &auth.AuthContext{User: &auth.UserContext{IDPUserID: "idp-123"}}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serveProfileRequest(handler.HandleGetMe, http.MethodGet, "", tt.authCtx)
+			if recorder.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_HandleUpdateMe(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		updateErr error
+		want      int
+	}{
+		{"success", `{"companyName": "ABC Exports Ltd"}`, nil, http.StatusOK},
+		{"unknown field", `{"company": "ABC Exports Ltd"}`, nil, http.StatusBadRequest},
+		{"invalid profile", `{"email": "x"}`, fmt.Errorf("%w: email is invalid", ErrInvalidProfile), http.StatusBadRequest},
+		{"verified field changed", `{"tin": "TIN2"}`, fmt.Errorf("%w: tin", ErrVerifiedFieldChanged), http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHTTPHandler(&fakeService{updateErr: tt.updateErr})
+			recorder := serveProfileRequest(handler.HandleUpdateMe, http.MethodPut, tt.body, traderContext())
+			if recorder.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Profile is the company profile of a trader, stored under the "profile" key of Record.Data.
+type Profile struct {
+	CompanyName  string    `json:"companyName"`
+	BusinessType string    `json:"businessType"`
+	BRNumber     string    `json:"brNumber"`
+	TIN          string    `json:"tin"`
+	VATNumber    string    `json:"vatNumber"`
+	Address      string    `json:"address"`
+	Email        string    `json:"email"`
+	PhoneNumber  string    `json:"phoneNumber"`
+	Contacts     []Contact `json:"contacts"`
+	// VerifiedFields lists the fields written from a verified source, such as a completed
+	// pre-consignment. Traders cannot change them.
+	VerifiedFields []string `json:"verifiedFields,omitempty"`
+}
+
+// Contact is a person traders list as a point of contact for their company.
+type Contact struct {
+	Name        string `json:"name"`
+	Role        string `json:"role,omitempty"`
+	Email       string `json:"email,omitempty"`
+	PhoneNumber string `json:"phoneNumber,omitempty"`
+}
+
+// BusinessTypes are the accepted values of Profile.BusinessType.
+var BusinessTypes = []string{"Sole Proprietorship", "Partnership", "Private Limited", "Public Limited"}
+
+const (
+	maxProfileTextLength = 255
+	maxContacts          = 10
+
+	// profileDataKey is the key of Record.Data holding the profile. Other keys are preserved.
+	profileDataKey = "profile"
+)
+
+var (
+	identifierPattern  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 ./-]{0,49}$`)
+	phoneNumberPattern = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{5,19}$`)
+)
+
+// profileField gives access to a scalar profile field by its JSON name.
+type profileField struct {
+	name string
+	get  func(*Profile) *string
+}
+
+var profileFields = []profileField{
+	{"companyName", func(p *Profile) *string { return &p.CompanyName }},
+	{"businessType", func(p *Profile) *string { return &p.BusinessType }},
+	{"brNumber", func(p *Profile) *string { return &p.BRNumber }},
+	{"tin", func(p *Profile) *string { return &p.TIN }},
+	{"vatNumber", func(p *Profile) *string { return &p.VATNumber }},
+	{"address", func(p *Profile) *string { return &p.Address }},
+	{"email", func(p *Profile) *string { return &p.Email }},
+	{"phoneNumber", func(p *Profile) *string { return &p.PhoneNumber }},
+}
+
+// normalize trims surrounding whitespace from every field.
+func (p *Profile) normalize() {
+	if p.Contacts == nil {
+		p.Contacts = []Contact{}
+	}
+	for _, field := range profileFields {
+		value := field.get(p)
+		*value = strings.TrimSpace(*value)
+	}
+	for i := range p.Contacts {
+		contact := &p.Contacts[i]
+		contact.Name = strings.TrimSpace(contact.Name)
+		contact.Role = strings.TrimSpace(contact.Role)
+		contact.Email = strings.TrimSpace(contact.Email)
+		contact.PhoneNumber = strings.TrimSpace(contact.PhoneNumber)
+	}
+}
+
+// Validate checks the profile against the profile schema. Empty fields are allowed, so
+// traders can fill in their profile over time.
+func (p *Profile) Validate() error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidProfile, fmt.Sprintf(format, args...))
+	}
+
+	for _, field := range profileFields {
+		if len(*field.get(p)) > maxProfileTextLength {
+			return invalid("%s must be at most %d characters", field.name, maxProfileTextLength)
+		}
+	}
+	if p.BusinessType != "" && !slices.Contains(BusinessTypes, p.BusinessType) {
+		return invalid("businessType must be one of %s", strings.Join(BusinessTypes, ", "))
+	}
+	registrationNumbers := []struct{ name, value string }{{"brNumber", p.BRNumber}, {"tin", p.TIN}, {"vatNumber", p.VATNumber}}
+	for _, number := range registrationNumbers {
+		if number.value != "" && !identifierPattern.MatchString(number.value) {
+			return invalid("%s %q is not a valid registration number", number.name, number.value)
+		}
+	}
+	if p.Email != "" && !validEmail(p.Email) {
+		return invalid("email %q is not a valid email address", p.Email)
+	}
+	if p.PhoneNumber != "" && !phoneNumberPattern.MatchString(p.PhoneNumber) {
+		return invalid("phoneNumber %q is not a valid phone number", p.PhoneNumber)
+	}
+
+	if len(p.Contacts) > maxContacts {
+		return invalid("at most %d contacts are allowed", maxContacts)
+	}
+	for i, contact := range p.Contacts {
+		switch {
+		case contact.Name == "":
+			return invalid("contacts[%d].name is required", i)
+		case len(contact.Name) > maxProfileTextLength || len(contact.Role) > maxProfileTextLength:
+			return invalid("contacts[%d] fields must be at most %d characters", i, maxProfileTextLength)
+		case contact.Email == "" && contact.PhoneNumber == "":
+			return invalid("contacts[%d] requires an email or a phone number", i)
+		case contact.Email != "" && !validEmail(contact.Email):
+			return invalid("contacts[%d].email %q is not a valid email address", i, contact.Email)
+		case contact.PhoneNumber != "" && !phoneNumberPattern.MatchString(contact.PhoneNumber):
+			return invalid("contacts[%d].phoneNumber %q is not a valid phone number", i, contact.PhoneNumber)
+		}
+	}
+	return nil
+}
+
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// changedFields lists the fields that differ between before and after.
+func changedFields(before, after *Profile) []string {
+	var changed []string
+	for _, field := range profileFields {
+		if *field.get(before) != *field.get(after) {
+			changed = append(changed, field.name)
+		}
+	}
+	if !slices.Equal(before.Contacts, after.Contacts) {
+		changed = append(changed, "contacts")
+	}
+	if !slices.Equal(before.VerifiedFields, after.VerifiedFields) {
+		changed = append(changed, "verifiedFields")
+	}
+	return changed
+}
+
+// ProfileChangeSource identifies what changed a profile.
+type ProfileChangeSource string
+
+const (
+	// ProfileChangeSourceTrader marks changes made by the trader through the API.
+	ProfileChangeSourceTrader ProfileChangeSource = "trader"
+	// ProfileChangeSourceVerified marks verified fields written by the platform, e.g. on
+	// completion of a pre-consignment.
+	ProfileChangeSourceVerified ProfileChangeSource = "verified"
+)
+
+// ProfileChange is an entry of the append-only history of a user's profile.
+type ProfileChange struct {
+	ID            int64               `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	UserID        string              `gorm:"type:varchar(100);column:user_id;not null" json:"userId"`
+	Source        ProfileChangeSource `gorm:"type:varchar(20);column:source;not null" json:"source"`
+	ChangedBy     string              `gorm:"type:varchar(255);column:changed_by;not null" json:"changedBy"`
+	ChangedFields []string            `gorm:"type:jsonb;column:changed_fields;not null;serializer:json" json:"changedFields"`
+	Profile       Profile             `gorm:"type:jsonb;column:profile;not null;serializer:json" json:"profile"`
+	CreatedAt     time.Time           `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+// TableName specifies the database table for this model.
+func (c *ProfileChange) TableName() string {
+	return "user_profile_history"
+}
+
+// ProfileHistory is a page of a user's profile changes, newest first.
+type ProfileHistory struct {
+	TotalCount int64           `json:"totalCount"`
+	Items      []ProfileChange `json:"items"`
+	Offset     int64           `json:"offset"`
+	Limit      int64           `json:"limit"`
+}
+
+// decodeProfile reads the profile from a record's data. Records without one have an empty profile.
+func decodeProfile(data json.RawMessage) (Profile, map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return Profile{}, nil, fmt.Errorf("failed to parse user data: %w", err)
+		}
+	}
+	var profile Profile
+	if raw, ok := fields[profileDataKey]; ok {
+		if err := json.Unmarshal(raw, &profile); err != nil {
+			return Profile{}, nil, fmt.Errorf("failed to parse user profile: %w", err)
+		}
+	}
+	if profile.Contacts == nil {
+		profile.Contacts = []Contact{}
+	}
+	return profile, fields, nil
+}
+
+// encodeProfile stores profile in a record's data, keeping its other keys.
+func encodeProfile(fields map[string]json.RawMessage, profile *Profile) ([]byte, error) {
+	raw, err := json.Marshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode user profile: %w", err)
+	}
+	fields[profileDataKey] = raw
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode user data: %w", err)
+	}
+	return data, nil
+}
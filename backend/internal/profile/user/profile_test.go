@@ -0,0 +1,85 @@
+package user
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestProfile_Validate(t *testing.T) {
+	valid := Profile{
+		CompanyName:  "ABC Exports Ltd",
+		BusinessType: "Private Limited",
+		BRNumber:     "PV 12345",
+		TIN:          "TIN123456",
+		Address:      "123 Main Street, Colombo 01",
+		Email:        "info@abcexports.com",
+		PhoneNumber:  "+94 11 2345678",
+		Contacts:     []Contact{{Name: "Jane Perera", Role: "Director", Email: "jane@abcexports.com"}},
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(p *Profile)
+		wantErr bool
+	}{
+		{"valid", func(p *Profile) {}, false},
+		{"empty profile", func(p *Profile) { *p = Profile{} }, false},
+		{"unknown business type", func(p *Profile) { p.BusinessType = "Cooperative" }, true},
+		{"invalid TIN", func(p *Profile) { p.TIN = "TIN#123" }, true},
+		{"invalid email", func(p *Profile) { p.Email = "not-an-email" }, true},
+		{"invalid phone number", func(p *Profile) { p.PhoneNumber = "call me" }, true},
+		{"contact without name", func(p *Profile) { p.Contacts[0].Name = "" }, true},
+		{"contact without email or phone", func(p *Profile) { p.Contacts[0].Email = "" }, true},
+		{"too many contacts", func(p *Profile) { p.Contacts = make([]Contact, maxContacts+1) }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := valid
+			profile.Contacts = slices.Clone(valid.Contacts)
+			tt.mutate(&profile)
+			err := profile.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidProfile) {
+				t.Fatalf("expected ErrInvalidProfile, got %v", err)
+			}
+		})
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	before := Profile{CompanyName: "ABC", TIN: "TIN1", Contacts: []Contact{}}
+	after := Profile{CompanyName: "ABC", TIN: "TIN2", Contacts: []Contact{{Name: "Jane", Email: "jane@abc.com"}}, VerifiedFields: []string{"tin"}}
+
+	got := changedFields(&before, &after)
+	want := []string{"tin", "contacts", "verifiedFields"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := changedFields(&before, &before); len(got) != 0 {
+		t.Fatalf("expected no changes, got %v", got)
+	}
+}
+
+func TestDecodeProfile_KeepsOtherData(t *testing.T) {
+	profile, fields, err := decodeProfile([]byte(`{"preferences": {"lang": "si"}, "profile": {"companyName": "ABC"}}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profile.CompanyName != "ABC" || profile.Contacts == nil {
+		t.Fatalf("unexpected profile: %#v", profile)
+	}
+
+	profile.TIN = "TIN1"
+	data, err := encodeProfile(fields, &profile)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := `{"preferences":{"lang":"si"},"profile":{"companyName":"ABC","businessType":"","brNumber":"","tin":"TIN1","vatNumber":"","address":"","email":"","phoneNumber":"","contacts":[]}}`
+	if string(data) != want {
+		t.Fatalf("expected %s, got %s", want, data)
+	}
+}
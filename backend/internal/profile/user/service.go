@@ -4,10 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/utils"
 )
 
 // Service defines operations for user profile management.
@@ -27,6 +30,24 @@ type Service interface {
 	// Returns ErrUserNotFound if the user does not exist.
 	UpdateUserData(id string, data []byte) error
 
+	// GetProfile retrieves the company profile of a user.
+	// Returns ErrUserNotFound if the user does not exist.
+	GetProfile(id string) (*Profile, error)
+
+	// UpdateProfile replaces the company profile of a user with the one the user submitted.
+	// Returns ErrInvalidProfile if it fails validation and ErrVerifiedFieldChanged if it
+	// changes a verified field. Every change is recorded in the profile history.
+	UpdateProfile(id string, profile Profile) (*Profile, error)
+
+	// ApplyVerifiedProfile merges the non-empty fields of verified into the company profile of a
+	// user and marks them verified. Values that fail validation are skipped and logged. changedBy
+	// identifies the source, e.g. a pre-consignment. When tx is non-nil, the update is made in the
+	// caller's transaction.
+	ApplyVerifiedProfile(tx *gorm.DB, id string, verified Profile, changedBy string) error
+
+	// GetProfileHistory retrieves a page of the profile changes of a user, newest first.
+	GetProfileHistory(id string, offset, limit *int) (*ProfileHistory, error)
+
 	// Health checks if the service can access the database.
 	Health() error
 }
@@ -132,6 +153,157 @@ func (s *service) UpdateUserData(userID string, data []byte) error {
 	return nil
 }
 
+// GetProfile retrieves the company profile stored in a user record.
+func (s *service) GetProfile(id string) (*Profile, error) {
+	record, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	profile, _, err := decodeProfile(record.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// UpdateProfile replaces the company profile of a user, keeping its verified fields.
+func (s *service) UpdateProfile(id string, profile Profile) (*Profile, error) {
+	profile.normalize()
+	return s.changeProfile(s.db, id, ProfileChangeSourceTrader, id, func(current Profile) (Profile, error) {
+		for _, field := range profileFields {
+			if slices.Contains(current.VerifiedFields, field.name) && *field.get(&profile) != *field.get(&current) {
+				return Profile{}, fmt.Errorf("%w: %s", ErrVerifiedFieldChanged, field.name)
+			}
+		}
+		profile.VerifiedFields = current.VerifiedFields
+		return profile, nil
+	})
+}
+
+// ApplyVerifiedProfile merges verified fields into the company profile of a user. A value that
+// would not pass validation, e.g. two phone numbers in one field, is left out rather than failing
+// the whole update, since the source is a completed workflow that cannot be resubmitted.
+func (s *service) ApplyVerifiedProfile(tx *gorm.DB, id string, verified Profile, changedBy string) error {
+	if tx == nil {
+		tx = s.db
+	}
+	verified.normalize()
+	_, err := s.changeProfile(tx, id, ProfileChangeSourceVerified, changedBy, func(current Profile) (Profile, error) {
+		next := current
+		next.VerifiedFields = slices.Clone(current.VerifiedFields)
+		for _, field := range profileFields {
+			value := *field.get(&verified)
+			if value == "" {
+				continue
+			}
+			var single Profile
+			*field.get(&single) = value
+			if err := single.Validate(); err != nil {
+				slog.Warn("skipping invalid verified profile field", "id", id, "field", field.name, "changedBy", changedBy, "error", err)
+				continue
+			}
+			*field.get(&next) = value
+			if !slices.Contains(next.VerifiedFields, field.name) {
+				next.VerifiedFields = append(next.VerifiedFields, field.name)
+			}
+		}
+		slices.Sort(next.VerifiedFields)
+		return next, nil
+	})
+	return err
+}
+
+// changeProfile applies change to the profile of a user under a row lock, validates the result
+// and records it in the profile history. Nothing is written when the profile is unchanged.
+func (s *service) changeProfile(db *gorm.DB, id string, source ProfileChangeSource, changedBy string, change func(current Profile) (Profile, error)) (*Profile, error) {
+	if id == "" {
+		return nil, ErrInvalidUserID
+	}
+
+	var updated Profile
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var record Record
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&record)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("database query failed: %w", result.Error)
+		}
+
+		current, fields, err := decodeProfile(record.Data)
+		if err != nil {
+			return err
+		}
+		next, err := change(current)
+		if err != nil {
+			return err
+		}
+		if err := next.Validate(); err != nil {
+			return err
+		}
+
+		changed := changedFields(&current, &next)
+		if len(changed) == 0 {
+			updated = current
+			return nil
+		}
+
+		data, err := encodeProfile(fields, &next)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&Record{}).Where("id = ?", id).Update("data", data).Error; err != nil {
+			return fmt.Errorf("database update failed: %w", err)
+		}
+		entry := &ProfileChange{
+			UserID:        id,
+			Source:        source,
+			ChangedBy:     changedBy,
+			ChangedFields: changed,
+			Profile:       next,
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return fmt.Errorf("failed to record profile change: %w", err)
+		}
+		updated = next
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) && !errors.Is(err, ErrInvalidProfile) && !errors.Is(err, ErrVerifiedFieldChanged) {
+			slog.Error("failed to update user profile", "id", id, "source", source, "error", err)
+		}
+		return nil, err
+	}
+
+	slog.Debug("user profile updated", "id", id, "source", source)
+	return &updated, nil
+}
+
+// GetProfileHistory retrieves a page of the profile changes of a user, newest first.
+func (s *service) GetProfileHistory(id string, offset, limit *int) (*ProfileHistory, error) {
+	if id == "" {
+		return nil, ErrInvalidUserID
+	}
+	finalOffset, finalLimit := utils.GetPaginationParams(offset, limit)
+
+	var totalCount int64
+	if err := s.db.Model(&ProfileChange{}).Where("user_id = ?", id).Count(&totalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count profile changes: %w", err)
+	}
+	items := []ProfileChange{}
+	if err := s.db.Where("user_id = ?", id).Order("id DESC").Offset(finalOffset).Limit(finalLimit).Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve profile changes: %w", err)
+	}
+
+	return &ProfileHistory{
+		TotalCount: totalCount,
+		Items:      items,
+		Offset:     int64(finalOffset),
+		Limit:      int64(finalLimit),
+	}, nil
+}
+
 // getUserIDByIDP checks if a user record exists for the given idpUserID.
 // Returns nil, nil if the user does not exist.
 func (s *service) getUserIDByIDP(idpUserId string) (*string, error) {
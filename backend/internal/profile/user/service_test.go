@@ -1,6 +1,7 @@
 package user
 
 import (
+	"database/sql/driver"
 	"errors"
 	"strings"
 	"testing"
@@ -276,6 +277,181 @@ func TestService_UpdateUserData_Success(t *testing.T) {
 	}
 }
 
+// --- Profile ---
+
+func expectLockedUser(mock sqlmock.Sqlmock, userID string, data string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT .* FROM "user_records" WHERE id = \$1 .*FOR UPDATE`).
+		WithArgs(userID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "idp_user_id", "data"}).AddRow(userID, "idp-123", []byte(data)))
+}
+
+func TestService_UpdateProfile_Success(t *testing.T) {
+	db, mock := setupTestDB(t)
+	svc := NewService(db)
+
+	expectLockedUser(mock, "user-123", `{"profile": {"companyName": "ABC", "tin": "TIN1", "verifiedFields": ["tin"]}}`)
+	mock.ExpectExec(`UPDATE "user_records" SET`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "user-123"). // data, updated_at, id
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`INSERT INTO "user_profile_history"`).
+		WithArgs("user-123", ProfileChangeSourceTrader, "user-123", `["companyName"]`, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	profile, err := svc.UpdateProfile("user-123", Profile{CompanyName: " ABC Exports Ltd ", TIN: "TIN1"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profile.CompanyName != "ABC Exports Ltd" || len(profile.VerifiedFields) != 1 {
+		t.Fatalf("unexpected profile: %#v", profile)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestService_UpdateProfile_Unchanged(t *testing.T) {
+	db, mock := setupTestDB(t)
+	svc := NewService(db)
+
+	expectLockedUser(mock, "user-123", `{"profile": {"companyName": "ABC"}}`)
+	mock.ExpectCommit()
+
+	if _, err := svc.UpdateProfile("user-123", Profile{CompanyName: "ABC"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestService_UpdateProfile_VerifiedFieldChanged(t *testing.T) {
+	db, mock := setupTestDB(t)
+	svc := NewService(db)
+
+	expectLockedUser(mock, "user-123", `{"profile": {"tin": "TIN1", "verifiedFields": ["tin"]}}`)
+	mock.ExpectRollback()
+
+	if _, err := svc.UpdateProfile("user-123", Profile{TIN: "TIN2"}); !errors.Is(err, ErrVerifiedFieldChanged) {
+		t.Fatalf("expected ErrVerifiedFieldChanged, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestService_UpdateProfile_Invalid(t *testing.T) {
+	db, mock := setupTestDB(t)
+	svc := NewService(db)
+
+	expectLockedUser(mock, "user-123", `{}`)
+	mock.ExpectRollback()
+
+	if _, err := svc.UpdateProfile("user-123", Profile{Email: "not-an-email"}); !errors.Is(err, ErrInvalidProfile) {
+		t.Fatalf("expected ErrInvalidProfile, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestService_ApplyVerifiedProfile(t *testing.T) {
+	db, mock := setupTestDB(t)
+	svc := NewService(db)
+
+	expectLockedUser(mock, "user-123", `{"profile": {"companyName": "ABC", "address": "Colombo"}}`)
+	var data []byte
+	mock.ExpectExec(`UPDATE "user_records" SET`).
+		WithArgs(captureBytes{&data}, sqlmock.AnyArg(), "user-123"). // data, updated_at, id
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`INSERT INTO "user_profile_history"`).
+		WithArgs("user-123", ProfileChangeSourceVerified, "pre-consignment/pc-1", `["companyName","tin","verifiedFields"]`, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	if err := svc.ApplyVerifiedProfile(nil, "user-123", Profile{CompanyName: "ABC Exports Ltd", TIN: "TIN1"}, "pre-consignment/pc-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	profile, _, err := decodeProfile(data)
+	if err != nil {
+		t.Fatalf("failed to decode stored profile: %v", err)
+	}
+	if profile.CompanyName != "ABC Exports Ltd" || profile.Address != "Colombo" || strings.Join(profile.VerifiedFields, ",") != "companyName,tin" {
+		t.Fatalf("unexpected stored profile: %#v", profile)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestService_ApplyVerifiedProfile_SkipsInvalidFields(t *testing.T) {
+	db, mock := setupTestDB(t)
+	svc := NewService(db)
+
+	expectLockedUser(mock, "user-123", `{"profile": {"companyName": "ABC"}}`)
+	var data []byte
+	mock.ExpectExec(`UPDATE "user_records" SET`).
+		WithArgs(captureBytes{&data}, sqlmock.AnyArg(), "user-123").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`INSERT INTO "user_profile_history"`).
+		WithArgs("user-123", ProfileChangeSourceVerified, "pre-consignment/pc-1", `["tin","verifiedFields"]`, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	verified := Profile{TIN: "TIN1", PhoneNumber: "011 2345678 / 077 1234567"}
+	if err := svc.ApplyVerifiedProfile(nil, "user-123", verified, "pre-consignment/pc-1"); err != nil {
+		t.Fatalf("expected invalid field to be skipped, got %v", err)
+	}
+	profile, _, err := decodeProfile(data)
+	if err != nil {
+		t.Fatalf("failed to decode stored profile: %v", err)
+	}
+	if profile.PhoneNumber != "" || profile.TIN != "TIN1" || strings.Join(profile.VerifiedFields, ",") != "tin" {
+		t.Fatalf("unexpected stored profile: %#v", profile)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestService_GetProfileHistory(t *testing.T) {
+	db, mock := setupTestDB(t)
+	svc := NewService(db)
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "user_profile_history" WHERE user_id = \$1`).
+		WithArgs("user-123").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT \* FROM "user_profile_history" WHERE user_id = \$1 ORDER BY id DESC LIMIT \$2`).
+		WithArgs("user-123", 50).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "source", "changed_by", "changed_fields", "profile", "created_at"}).
+			AddRow(1, "user-123", "trader", "user-123", []byte(`["tin"]`), []byte(`{"tin": "TIN1"}`), now))
+
+	history, err := svc.GetProfileHistory("user-123", nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if history.TotalCount != 1 || len(history.Items) != 1 || history.Items[0].Profile.TIN != "TIN1" {
+		t.Fatalf("unexpected history: %#v", history)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+// captureBytes is a sqlmock argument matcher that stores the []byte argument it matches.
+type captureBytes struct {
+	dst *[]byte
+}
+
+func (c captureBytes) Match(v driver.Value) bool {
+	b, ok := v.([]byte)
+	if ok {
+		*c.dst = b
+	}
+	return ok
+}
+
 // --- Health ---
 
 func TestService_Health_Success(t *testing.T) {
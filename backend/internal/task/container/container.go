@@ -17,7 +17,8 @@ type Container struct {
 	globalState            map[string]any
 	localState             persistence.Manager
 	taskStore              persistence.TaskStoreInterface
-	pluginState            string // Cache for plugin-level business state
+	version                *persistence.Version // Shared with localState
+	pluginState            string               // Cache for plugin-level business state
 	fsm                    *plugin.PluginFSM
 	mu                     sync.RWMutex
 }
@@ -40,8 +41,9 @@ func (c *Container) CanTransition(action string) bool {
 	return c.fsm.CanTransition(c.GetPluginState(), action)
 }
 
-// Transition applies the FSM transition for action, updating in-memory state and
-// persisting both plugin state and task state to the store.
+// Transition applies the FSM transition for action, persisting plugin state and task state
+// to the store in one compare-and-swap update and then updating in-memory state. It fails
+// with persistence.ErrVersionConflict if the task was updated since the container read it.
 func (c *Container) Transition(action string) error {
 	if c.fsm == nil {
 		return nil
@@ -50,18 +52,40 @@ func (c *Container) Transition(action string) error {
 	if err != nil {
 		return err
 	}
+	update := persistence.StateUpdate{PluginState: &outcome.NextPluginState}
+	if outcome.NextTaskState != "" {
+		update.State = &outcome.NextTaskState
+	}
+	err = c.version.Update(func(expected int64) (int64, error) {
+		return c.taskStore.UpdateStateIfVersion(c.TaskID, expected, update)
+	})
+	if err != nil {
+		return err
+	}
 	c.mu.Lock()
 	c.pluginState = outcome.NextPluginState
 	if outcome.NextTaskState != "" {
 		c.State = outcome.NextTaskState
 	}
 	c.mu.Unlock()
-	if err := c.taskStore.UpdatePluginState(c.TaskID, outcome.NextPluginState); err != nil {
+	return nil
+}
+
+// Fail moves the task to FAILED in pluginState outside the plugin FSM, for the platform to end
+// a task its plugin would not, persisting both states in one compare-and-swap update.
+func (c *Container) Fail(pluginState string) error {
+	failed := plugin.Failed
+	update := persistence.StateUpdate{State: &failed, PluginState: &pluginState}
+	err := c.version.Update(func(expected int64) (int64, error) {
+		return c.taskStore.UpdateStateIfVersion(c.TaskID, expected, update)
+	})
+	if err != nil {
 		return err
 	}
-	if outcome.NextTaskState != "" {
-		return c.taskStore.UpdateStatus(c.TaskID, &outcome.NextTaskState)
-	}
+	c.mu.Lock()
+	c.pluginState = pluginState
+	c.State = failed
+	c.mu.Unlock()
 	return nil
 }
 
@@ -140,10 +164,19 @@ func (c *Container) GetPluginState() string {
 	return c.pluginState
 }
 
+// Version returns the version of the task row the container's state corresponds to.
+func (c *Container) Version() int64 {
+	if c.version == nil {
+		return 0
+	}
+	return c.version.Load()
+}
+
 // NewContainer creates a new container for a task with a given Executable plugin and FSM.
 // initialState is the task-level state to restore (InProgress for new tasks, or the
-// persisted state when rebuilding from the store after a cache miss).
-func NewContainer(taskId string, workflowId string, workflowNodeTemplateId string, initialState plugin.State, globalStore map[string]any, localStore persistence.Manager, taskStore persistence.TaskStoreInterface, executable plugin.Plugin, fsm *plugin.PluginFSM) *Container {
+// persisted state when rebuilding from the store after a cache miss). version is the
+// version of the task row that state was read at, shared with localStore.
+func NewContainer(taskId string, workflowId string, workflowNodeTemplateId string, initialState plugin.State, globalStore map[string]any, localStore persistence.Manager, taskStore persistence.TaskStoreInterface, version *persistence.Version, executable plugin.Plugin, fsm *plugin.PluginFSM) *Container {
 	c := &Container{
 		TaskID:                 taskId,
 		WorkflowID:             workflowId,
@@ -153,6 +186,7 @@ func NewContainer(taskId string, workflowId string, workflowNodeTemplateId strin
 		globalState:            globalStore,
 		localState:             localStore,
 		taskStore:              taskStore,
+		version:                version,
 		fsm:                    fsm,
 	}
 
@@ -3,69 +3,148 @@ package manager
 import (
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/OpenNSW/nsw/internal/task/container"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
 )
 
+// defaultCacheCapacity is the cache capacity used when none is configured.
+const defaultCacheCapacity = 100
+
+// CacheConfig configures the container cache of a TaskManager.
+type CacheConfig struct {
+	// Capacity is the number of containers kept; the least recently used one is evicted
+	// beyond it. Zero or less means defaultCacheCapacity.
+	Capacity int
+	// IdleTTL evicts containers not used for this long; zero keeps them until evicted
+	// for capacity. Idle containers are evicted lazily, whenever the cache is next used.
+	IdleTTL time.Duration
+}
+
+// Reasons a container is evicted from the cache.
+const (
+	EvictionCapacity = "capacity"
+	EvictionIdle     = "idle"
+)
+
+// CacheStats are the counters of the container cache since the process started.
+type CacheStats struct {
+	Capacity       int     `json:"capacity"`
+	IdleTTLSeconds float64 `json:"idleTtlSeconds"` // 0 when idle eviction is disabled
+	Size           int     `json:"size"`
+	Hits           uint64  `json:"hits"`
+	Misses         uint64  `json:"misses"` // Each miss rebuilds the container from persistence
+	// Evictions counts evicted containers by reason (EvictionCapacity, EvictionIdle).
+	Evictions map[string]uint64 `json:"evictions"`
+}
+
+// CachedContainer describes a container held in the cache.
+type CachedContainer struct {
+	TaskID                 string       `json:"taskId"`
+	WorkflowID             string       `json:"workflowId"`
+	WorkflowNodeTemplateID string       `json:"workflowNodeTemplateId"`
+	TaskState              plugin.State `json:"taskState"`
+	PluginState            string       `json:"pluginState"`
+	CachedAt               time.Time    `json:"cachedAt"`
+	LastAccess             time.Time    `json:"lastAccess"`
+}
+
 // cacheNode represents a node in the LRU cache doubly linked list
 type cacheNode struct {
-	taskID    string
-	container *container.Container
-	prev      *cacheNode
-	next      *cacheNode
+	taskID     string
+	container  *container.Container
+	cachedAt   time.Time
+	lastAccess time.Time
+	prev       *cacheNode
+	next       *cacheNode
 }
 
 // containerCache is a fixed-length LRU cache for storing active containers
 type containerCache struct {
 	capacity int
+	idleTTL  time.Duration
 	cache    map[string]*cacheNode
 	head     *cacheNode // Most recently used
 	tail     *cacheNode // Least recently used
 	mu       sync.RWMutex
+	now      func() time.Time
+
+	hits      uint64
+	misses    uint64
+	evictions map[string]uint64
 }
 
-// newContainerCache creates a new LRU cache with the specified capacity
-func newContainerCache(capacity int) *containerCache {
+// newContainerCache creates a new LRU cache with the specified capacity. Containers idle for
+// longer than idleTTL are evicted; zero disables idle eviction.
+func newContainerCache(capacity int, idleTTL time.Duration) *containerCache {
 	if capacity <= 0 {
-		capacity = 100 // Default capacity
+		capacity = defaultCacheCapacity
 	}
 	return &containerCache{
-		capacity: capacity,
-		cache:    make(map[string]*cacheNode),
+		capacity:  capacity,
+		idleTTL:   idleTTL,
+		cache:     make(map[string]*cacheNode),
+		now:       time.Now,
+		evictions: make(map[string]uint64),
 	}
 }
 
-// Get retrieves a container from cache and marks it as recently used
+// Get retrieves a container from cache and marks it as recently used. Lookups are counted
+// as hits or misses.
 func (c *containerCache) Get(taskID string) (*container.Container, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.evictIdle()
 	node, exists := c.cache[taskID]
 	if !exists {
+		c.misses++
 		return nil, false
 	}
 
 	// Move to front (most recently used)
+	c.hits++
+	node.lastAccess = c.now()
 	c.moveToFront(node)
 	return node.container, true
 }
 
+// Peek is Get without counting the lookup, for checks that do not serve a request.
+func (c *containerCache) Peek(taskID string) (*container.Container, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictIdle()
+	node, exists := c.cache[taskID]
+	if !exists {
+		return nil, false
+	}
+	return node.container, true
+}
+
 // Set adds or updates a container in the cache
 func (c *containerCache) Set(taskID string, cont *container.Container) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.evictIdle()
+	now := c.now()
+
 	// If already exists, update and move to front
 	if node, exists := c.cache[taskID]; exists {
 		node.container = cont
+		node.lastAccess = now
 		c.moveToFront(node)
 		return
 	}
 
 	// Create new node
 	newNode := &cacheNode{
-		taskID:    taskID,
-		container: cont,
+		taskID:     taskID,
+		container:  cont,
+		cachedAt:   now,
+		lastAccess: now,
 	}
 
 	// Add to cache map and front of list
@@ -74,7 +153,7 @@ func (c *containerCache) Set(taskID string, cont *container.Container) {
 
 	// Evict least recently used if over capacity
 	if len(c.cache) > c.capacity {
-		c.evictLRU()
+		c.evict(c.tail, EvictionCapacity)
 	}
 }
 
@@ -109,6 +188,46 @@ func (c *containerCache) Len() int {
 	return len(c.cache)
 }
 
+// Stats returns the cache counters.
+func (c *containerCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictIdle()
+	evictions := make(map[string]uint64, 2)
+	evictions[EvictionCapacity] = c.evictions[EvictionCapacity]
+	evictions[EvictionIdle] = c.evictions[EvictionIdle]
+	return CacheStats{
+		Capacity:       c.capacity,
+		IdleTTLSeconds: c.idleTTL.Seconds(),
+		Size:           len(c.cache),
+		Hits:           c.hits,
+		Misses:         c.misses,
+		Evictions:      evictions,
+	}
+}
+
+// Snapshot describes the cached containers, most recently used first.
+func (c *containerCache) Snapshot() []CachedContainer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictIdle()
+	snapshot := make([]CachedContainer, 0, len(c.cache))
+	for node := c.head; node != nil; node = node.next {
+		snapshot = append(snapshot, CachedContainer{
+			TaskID:                 node.taskID,
+			WorkflowID:             node.container.WorkflowID,
+			WorkflowNodeTemplateID: node.container.WorkflowNodeTemplateID,
+			TaskState:              node.container.GetTaskState(),
+			PluginState:            node.container.GetPluginState(),
+			CachedAt:               node.cachedAt,
+			LastAccess:             node.lastAccess,
+		})
+	}
+	return snapshot
+}
+
 // moveToFront moves a node to the front of the list (most recently used)
 func (c *containerCache) moveToFront(node *cacheNode) {
 	if c.head == node {
@@ -148,17 +267,30 @@ func (c *containerCache) removeNode(node *cacheNode) {
 	}
 }
 
-// evictLRU removes the least recently used item from the cache
-func (c *containerCache) evictLRU() {
-	if c.tail == nil {
+// evictIdle evicts containers idle for longer than the idle TTL. The list is ordered by
+// last access, so they are all at its tail.
+func (c *containerCache) evictIdle() {
+	if c.idleTTL <= 0 {
+		return
+	}
+	cutoff := c.now().Add(-c.idleTTL)
+	for c.tail != nil && c.tail.lastAccess.Before(cutoff) {
+		c.evict(c.tail, EvictionIdle)
+	}
+}
+
+// evict removes node from the cache and counts the eviction under reason.
+func (c *containerCache) evict(node *cacheNode, reason string) {
+	if node == nil {
 		return
 	}
 
-	lruNode := c.tail
-	c.removeNode(lruNode)
-	delete(c.cache, lruNode.taskID)
+	c.removeNode(node)
+	delete(c.cache, node.taskID)
+	c.evictions[reason]++
 
 	slog.Debug("evicted container from cache",
-		"taskID", lruNode.taskID,
+		"taskID", node.taskID,
+		"reason", reason,
 		"cacheSize", len(c.cache))
 }
@@ -1,20 +1,37 @@
 package manager
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/task/persistence"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/task/policy"
 )
 
 // HTTPHandler encapsulates the HTTP transport logic for TaskManager
 type HTTPHandler struct {
-	manager TaskManager
+	manager    TaskManager
+	authorizer Authorizer
+}
+
+// Authorizer decides whether the caller in ctx may perform action on a task.
+// A *policy.DeniedError means the caller may not; any other error means the check failed.
+type Authorizer interface {
+	AuthorizeExecute(ctx context.Context, taskID, action string) error
 }
 
-// NewHTTPHandler creates a new HTTPHandler for the task manager
-func NewHTTPHandler(manager TaskManager) *HTTPHandler {
-	return &HTTPHandler{manager: manager}
+// NewHTTPHandler creates a new HTTPHandler for the task manager.
+// authorizer may be nil, in which case task actions are not authorized beyond authentication.
+func NewHTTPHandler(manager TaskManager, authorizer Authorizer) *HTTPHandler {
+	return &HTTPHandler{manager: manager, authorizer: authorizer}
 }
 
 // HandleGetTask is an HTTP handler for fetching task information via GET request
@@ -27,6 +44,9 @@ func (h *HTTPHandler) HandleGetTask(w http.ResponseWriter, r *http.Request) {
 
 	result, err := h.manager.GetTaskRenderInfo(r.Context(), taskId)
 	if err != nil {
+		if writeConflict(w, err) {
+			return
+		}
 		// Differentiate between invalid ID/NotFound and internal errors, if necessary.
 		status := http.StatusInternalServerError
 		if strings.HasPrefix(err.Error(), "taskID is invalid") {
@@ -54,8 +74,41 @@ func (h *HTTPHandler) HandleExecuteTask(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Payment outcomes are driven by verified gateway webhooks; user principals
+	// must never be able to send them through the public API.
+	if req.Payload != nil && isSystemOnlyAction(req.Payload.Action) {
+		authCtx := auth.GetAuthContext(r.Context())
+		if authCtx == nil || authCtx.System == nil {
+			writeJSONError(w, http.StatusForbidden, "action "+req.Payload.Action+" cannot be performed by this principal")
+			return
+		}
+	}
+
+	if h.authorizer != nil {
+		var action string
+		if req.Payload != nil {
+			action = req.Payload.Action
+		}
+		if err := h.authorizer.AuthorizeExecute(r.Context(), req.TaskID, action); err != nil {
+			var denied *policy.DeniedError
+			switch {
+			case errors.As(err, &denied):
+				writeJSONError(w, http.StatusForbidden, denied.Reason)
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				writeJSONError(w, http.StatusNotFound, "task "+req.TaskID+" not found")
+			default:
+				slog.ErrorContext(r.Context(), "failed to authorize task action", "taskID", req.TaskID, "action", action, "error", err)
+				writeJSONError(w, http.StatusInternalServerError, "failed to authorize task action")
+			}
+			return
+		}
+	}
+
 	result, err := h.manager.ExecuteTask(r.Context(), req)
 	if err != nil {
+		if writeConflict(w, err) {
+			return
+		}
 		status := http.StatusInternalServerError
 		if string(err.Error()) == "task_id is required" {
 			status = http.StatusBadRequest
@@ -70,6 +123,69 @@ func (h *HTTPHandler) HandleExecuteTask(w http.ResponseWriter, r *http.Request)
 	writeJSONResponse(w, http.StatusOK, result.ApiResponse)
 }
 
+// HandleListFormTasks lists the live tasks rendering a form and the version each is pinned to,
+// so administrators can see who is still on an old version after publishing a new one.
+func (h *HTTPHandler) HandleListFormTasks(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "listing form tasks requires an administrator")
+		return
+	}
+
+	tasks, err := h.manager.ListFormTasks(r.Context(), r.PathValue("formId"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if tasks == nil {
+		tasks = []persistence.FormTask{}
+	}
+	writeJSONResponse(w, http.StatusOK, tasks)
+}
+
+// TaskCacheResponse is the body returned by HandleGetTaskCache.
+type TaskCacheResponse struct {
+	Stats      CacheStats        `json:"stats"`
+	Containers []CachedContainer `json:"containers"`
+}
+
+// HandleGetTaskCache dumps the task container cache: its counters and the containers it
+// holds, most recently used first.
+func (h *HTTPHandler) HandleGetTaskCache(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "inspecting the task cache requires an administrator")
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, TaskCacheResponse{
+		Stats:      h.manager.CacheStats(),
+		Containers: h.manager.CachedContainers(),
+	})
+}
+
+// PaymentFeeDryRunRequest is the body of a fee dry run.
+type PaymentFeeDryRunRequest struct {
+	Config        plugin.PaymentConfig `json:"config"`
+	GlobalContext map[string]any       `json:"globalContext"`
+}
+
+// HandlePaymentFeeDryRun resolves a PAYMENT task configuration's breakdown against a
+// sample global context, so fee schedules can be tested before they are deployed.
+// No task is created and no payment is started.
+func (h *HTTPHandler) HandlePaymentFeeDryRun(w http.ResponseWriter, r *http.Request) {
+	var req PaymentFeeDryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	quote, err := plugin.CalculateFees(req.Config, req.GlobalContext)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, quote)
+}
+
 func writeJSONResponse(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -78,6 +194,21 @@ func writeJSONResponse(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
+// writeConflict writes a 409 carrying the task's current state if err is a ConflictError,
+// and reports whether it did.
+func writeConflict(w http.ResponseWriter, err error) bool {
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+	writeJSONResponse(w, http.StatusConflict, ExecuteTaskResponse{
+		Success: false,
+		Result:  conflict,
+		Error:   conflict.Error(),
+	})
+	return true
+}
+
 func writeJSONError(w http.ResponseWriter, status int, message string) {
 	writeJSONResponse(w, status, ExecuteTaskResponse{
 		Success: false,
@@ -2,18 +2,28 @@ package manager
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/task/container"
+	"github.com/OpenNSW/nsw/internal/task/persistence"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/task/policy"
 )
 
 func TestHTTPHandler_HandleExecuteTask(t *testing.T) {
 	t.Run("Invalid Method", func(t *testing.T) {
 		tm := &taskManager{}
-		handler := NewHTTPHandler(tm)
+		handler := NewHTTPHandler(tm, nil)
 		req := httptest.NewRequest(http.MethodGet, "/execute", nil)
 		w := httptest.NewRecorder()
 
@@ -25,7 +35,7 @@ func TestHTTPHandler_HandleExecuteTask(t *testing.T) {
 
 	t.Run("Invalid Body", func(t *testing.T) {
 		tm := &taskManager{}
-		handler := NewHTTPHandler(tm)
+		handler := NewHTTPHandler(tm, nil)
 		req := httptest.NewRequest(http.MethodPost, "/execute", bytes.NewBufferString("invalid json"))
 		w := httptest.NewRecorder()
 
@@ -34,12 +44,121 @@ func TestHTTPHandler_HandleExecuteTask(t *testing.T) {
 		resp := w.Result()
 		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 	})
+
+	t.Run("Payment Outcome From Trader Rejected", func(t *testing.T) {
+		tm := &taskManager{}
+		handler := NewHTTPHandler(tm, nil)
+		for _, action := range []string{"PAYMENT_SUCCESS", "PAYMENT_FAILED"} {
+			body := `{"task_id":"task-1","payload":{"action":"` + action + `"}}`
+			req := httptest.NewRequest(http.MethodPost, "/execute", bytes.NewBufferString(body))
+			authCtx := &auth.AuthContext{User: &auth.UserContext{ID: "trader-1"}}
+			req = req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, authCtx))
+			w := httptest.NewRecorder()
+
+			handler.HandleExecuteTask(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Result().StatusCode, action)
+		}
+	})
+
+	t.Run("Payment Outcome From Client Rejected", func(t *testing.T) {
+		tm := &taskManager{}
+		handler := NewHTTPHandler(tm, nil)
+		for _, action := range []string{"PAYMENT_SUCCESS", "PAYMENT_REFUNDED"} {
+			body := `{"task_id":"task-1","payload":{"action":"` + action + `"}}`
+			req := httptest.NewRequest(http.MethodPost, "/execute", bytes.NewBufferString(body))
+			authCtx := &auth.AuthContext{Client: &auth.ClientContext{ClientID: "NPQS_TO_NSW"}}
+			req = req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, authCtx))
+			w := httptest.NewRecorder()
+
+			handler.HandleExecuteTask(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Result().StatusCode, action)
+		}
+	})
+
+	t.Run("Payment Outcome Without Principal Rejected", func(t *testing.T) {
+		tm := &taskManager{}
+		handler := NewHTTPHandler(tm, nil)
+		body := `{"task_id":"task-1","payload":{"action":"PAYMENT_SUCCESS"}}`
+		req := httptest.NewRequest(http.MethodPost, "/execute", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		handler.HandleExecuteTask(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Result().StatusCode)
+	})
+
+	t.Run("Denied By Policy", func(t *testing.T) {
+		tm := &taskManager{}
+		authorizer := authorizerFunc(func(ctx context.Context, taskID, action string) error {
+			assert.Equal(t, "task-1", taskID)
+			assert.Equal(t, "SUBMIT_FORM", action)
+			return &policy.DeniedError{Reason: "user is not the trader or assigned CHA of this task's consignment"}
+		})
+		handler := NewHTTPHandler(tm, authorizer)
+		body := `{"task_id":"task-1","payload":{"action":"SUBMIT_FORM"}}`
+		req := httptest.NewRequest(http.MethodPost, "/execute", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		handler.HandleExecuteTask(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "not the trader or assigned CHA")
+	})
+
+	t.Run("Unknown Task Rejected By Policy", func(t *testing.T) {
+		tm := &taskManager{}
+		authorizer := authorizerFunc(func(ctx context.Context, taskID, action string) error {
+			return fmt.Errorf("failed to read task %s: %w", taskID, gorm.ErrRecordNotFound)
+		})
+		handler := NewHTTPHandler(tm, authorizer)
+		body := `{"task_id":"task-1","payload":{"action":"SUBMIT_FORM"}}`
+		req := httptest.NewRequest(http.MethodPost, "/execute", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		handler.HandleExecuteTask(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+// conflictingTaskManager fails every task action with a ConflictError.
+type conflictingTaskManager struct {
+	TaskManager
+}
+
+func (conflictingTaskManager) ExecuteTask(_ context.Context, req ExecuteTaskRequest) (*plugin.ExecutionResponse, error) {
+	return nil, &ConflictError{TaskID: req.TaskID, State: plugin.InProgress, PluginState: "DRAFT", Version: 7}
+}
+
+func TestHTTPHandler_HandleExecuteTask_Conflict(t *testing.T) {
+	handler := NewHTTPHandler(conflictingTaskManager{}, nil)
+	body := `{"task_id":"task-1","payload":{"action":"SAVE_AS_DRAFT"}}`
+	req := httptest.NewRequest(http.MethodPost, "/execute", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	handler.HandleExecuteTask(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{
+		"success": false,
+		"error": "task task-1 was modified concurrently; it is now at version 7",
+		"result": {"taskId": "task-1", "state": "IN_PROGRESS", "pluginState": "DRAFT", "version": 7}
+	}`, w.Body.String())
+}
+
+// authorizerFunc adapts a function to the Authorizer interface.
+type authorizerFunc func(ctx context.Context, taskID, action string) error
+
+func (f authorizerFunc) AuthorizeExecute(ctx context.Context, taskID, action string) error {
+	return f(ctx, taskID, action)
 }
 
 func TestHTTPHandler_HandleGetTask(t *testing.T) {
 	t.Run("Missing TaskID", func(t *testing.T) {
 		tm, _, _, _ := setupTest(t)
-		handler := NewHTTPHandler(tm)
+		handler := NewHTTPHandler(tm, nil)
 		req := httptest.NewRequest(http.MethodGet, "/tasks/", nil)
 		// No path value set
 		w := httptest.NewRecorder()
@@ -52,11 +171,12 @@ func TestHTTPHandler_HandleGetTask(t *testing.T) {
 
 	t.Run("Invalid TaskID string", func(t *testing.T) {
 		tm, _, mockStore, _ := setupTest(t)
-		handler := NewHTTPHandler(tm)
+		handler := NewHTTPHandler(tm, nil)
 		req := httptest.NewRequest(http.MethodGet, "/tasks/invalid", nil)
 		req.SetPathValue("id", "invalid")
 		w := httptest.NewRecorder()
 
+		mockStore.expectLock(t, "invalid")
 		mockStore.On("GetByID", "invalid").Return(nil, errors.New("not found")).Once()
 
 		handler.HandleGetTask(w, req)
@@ -65,3 +185,107 @@ func TestHTTPHandler_HandleGetTask(t *testing.T) {
 		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 	})
 }
+
+func TestHTTPHandler_HandlePaymentFeeDryRun(t *testing.T) {
+	handler := NewHTTPHandler(&taskManager{}, nil)
+
+	t.Run("Resolves Breakdown", func(t *testing.T) {
+		body := `{"config":{"currency":"LKR","breakdown":[
+			{"description":"Inspection","category":"ADDITION","type":"TIERED","basis":"{consignment.weight}",
+			 "tiers":[{"upTo":"100","fixed":"500"},{"rate":"2"}]}]},
+			"globalContext":{"consignment":{"weight":250}}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/payments/fees/dry-run", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		handler.HandlePaymentFeeDryRun(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+		assert.Contains(t, w.Body.String(), `"totalAmount":"500"`)
+	})
+
+	t.Run("Invalid Rules Rejected", func(t *testing.T) {
+		body := `{"config":{"breakdown":[{"description":"Fee","category":"ADDITION","type":"FORMULA"}]}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/payments/fees/dry-run", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		handler.HandlePaymentFeeDryRun(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+		assert.Contains(t, w.Body.String(), "unsupported type")
+	})
+}
+
+func TestHTTPHandler_HandleListFormTasks(t *testing.T) {
+	serve := func(handler *HTTPHandler, authCtx *auth.AuthContext) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/forms/form-1/tasks", nil)
+		req.SetPathValue("formId", "form-1")
+		req = req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, authCtx))
+		w := httptest.NewRecorder()
+		handler.HandleListFormTasks(w, req)
+		return w
+	}
+
+	t.Run("Lists Pinned Versions", func(t *testing.T) {
+		tm, _, mockStore, _ := setupTest(t)
+		mockStore.On("ListFormTasks", "form-1").Return([]persistence.FormTask{
+			{TaskID: "task-1", WorkflowID: "wf-1", State: plugin.InProgress, PluginState: "DRAFT", FormVersion: "1.0"},
+			{TaskID: "task-2", WorkflowID: "wf-2", State: plugin.InProgress, PluginState: "INITIALIZED", FormVersion: "2.0"},
+		}, nil).Once()
+
+		w := serve(NewHTTPHandler(tm, nil), &auth.AuthContext{User: &auth.UserContext{ID: "admin-1", Roles: []string{auth.RoleAdmin}}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[
+			{"taskId": "task-1", "workflowId": "wf-1", "state": "IN_PROGRESS", "pluginState": "DRAFT", "formVersion": "1.0"},
+			{"taskId": "task-2", "workflowId": "wf-2", "state": "IN_PROGRESS", "pluginState": "INITIALIZED", "formVersion": "2.0"}
+		]`, w.Body.String())
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Non-Admin Rejected", func(t *testing.T) {
+		tm, _, mockStore, _ := setupTest(t)
+
+		w := serve(NewHTTPHandler(tm, nil), &auth.AuthContext{User: &auth.UserContext{ID: "trader-1", Roles: []string{"exporter"}}})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockStore.AssertNotCalled(t, "ListFormTasks", "form-1")
+	})
+}
+
+func TestHTTPHandler_HandleGetTaskCache(t *testing.T) {
+	serve := func(handler *HTTPHandler, authCtx *auth.AuthContext) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/task-cache", nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.AuthContextKey, authCtx))
+		w := httptest.NewRecorder()
+		handler.HandleGetTaskCache(w, req)
+		return w
+	}
+
+	t.Run("Dumps Cache", func(t *testing.T) {
+		tm, _, _, _ := setupTest(t)
+		now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		tm.containerCache.now = func() time.Time { return now }
+		tm.containerCache.Set("task-1", &container.Container{TaskID: "task-1", WorkflowID: "wf-1", State: plugin.InProgress})
+		tm.containerCache.Get("task-1")
+		tm.containerCache.Get("task-2")
+
+		w := serve(NewHTTPHandler(tm, nil), &auth.AuthContext{User: &auth.UserContext{ID: "admin-1", Roles: []string{auth.RoleAdmin}}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{
+			"stats": {"capacity": 10, "idleTtlSeconds": 0, "size": 1, "hits": 1, "misses": 1, "evictions": {"capacity": 0, "idle": 0}},
+			"containers": [
+				{"taskId": "task-1", "workflowId": "wf-1", "workflowNodeTemplateId": "", "taskState": "IN_PROGRESS", "pluginState": "",
+				 "cachedAt": "2026-01-01T00:00:00Z", "lastAccess": "2026-01-01T00:00:00Z"}
+			]
+		}`, w.Body.String())
+	})
+
+	t.Run("Non-Admin Rejected", func(t *testing.T) {
+		tm, _, _, _ := setupTest(t)
+
+		w := serve(NewHTTPHandler(tm, nil), &auth.AuthContext{User: &auth.UserContext{ID: "trader-1", Roles: []string{"exporter"}}})
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
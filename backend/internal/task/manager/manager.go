@@ -3,12 +3,14 @@ package manager
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
-	"sync"
 
+	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 
+	"github.com/OpenNSW/nsw/internal/audit"
 	"github.com/OpenNSW/nsw/internal/task/container"
 	"github.com/OpenNSW/nsw/internal/task/persistence"
 	"github.com/OpenNSW/nsw/internal/task/plugin"
@@ -27,6 +29,25 @@ type InitTaskRequest struct {
 	Type                   plugin.Type `json:"type"`
 	GlobalState            map[string]any
 	Config                 json.RawMessage `json:"config"`
+	// SLA is the SLA policy of the node template, kept with the task; see package sla.
+	SLA json.RawMessage `json:"sla,omitempty"`
+}
+
+// ConflictError reports that a task could not be updated because it was modified
+// concurrently. It carries the task's current state so the caller can reconcile and retry.
+type ConflictError struct {
+	TaskID      string       `json:"taskId"`
+	State       plugin.State `json:"state"`
+	PluginState string       `json:"pluginState"`
+	Version     int64        `json:"version"`
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("task %s was modified concurrently; it is now at version %d", e.TaskID, e.Version)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return persistence.ErrVersionConflict
 }
 
 type InitTaskResponse struct {
@@ -49,6 +70,12 @@ type WorkflowUpdateHandler func(ctx context.Context, taskID string, state *plugi
 // TODO: these functions should return an error?
 type WorkflowDoneHandler func(ctx context.Context, workflowID, taskID string, outputs map[string]any)
 
+// StateObserver is told the state a task has entered after each change, e.g. to run its SLA
+// clock. Changes of a task are reported one at a time, in order.
+type StateObserver interface {
+	Observe(ctx context.Context, taskID string, state plugin.State, pluginState string) error
+}
+
 // TaskManager handles task execution and status management
 // Architecture: Trader Portal → Workflow Engine → Task Manager
 // - Workflow Manager triggers Task Manager to get task info (e.g., form schema)
@@ -61,11 +88,24 @@ type TaskManager interface {
 	// Core Domain Methods
 	ExecuteTask(ctx context.Context, req ExecuteTaskRequest) (*plugin.ExecutionResponse, error)
 	GetTaskRenderInfo(ctx context.Context, taskID string) (*plugin.ApiResponse, error)
+	// FailTask fails a task whatever its plugin state, e.g. one that breached its SLA, and
+	// notifies the workflow manager. reason is recorded in the audit log.
+	FailTask(ctx context.Context, taskID, reason string) error
+
+	// ListFormTasks lists the live tasks rendering a form and the form version each is pinned to.
+	ListFormTasks(ctx context.Context, formID string) ([]persistence.FormTask, error)
+
+	// CacheStats returns the counters of the task container cache.
+	CacheStats() CacheStats
+	// CachedContainers describes the containers currently cached, most recently used first.
+	CachedContainers() []CachedContainer
 
 	// RegisterUpstreamDoneCallback registers the callback used when task is done.
 	RegisterUpstreamDoneCallback(callback WorkflowDoneHandler)
 	// RegisterUpstreamUpdateCallback registers the callback used when task state changes.
 	RegisterUpstreamUpdateCallback(callback WorkflowUpdateHandler)
+	// RegisterStateObserver registers the observer told of task state changes.
+	RegisterStateObserver(observer StateObserver)
 }
 
 // ExecuteTaskRequest represents the request body for task execution
@@ -81,26 +121,40 @@ type taskManager struct {
 	workflowUpdateHandler WorkflowUpdateHandler          // Handler used to notify Workflow Manager of task updates
 	workflowDoneHandler   WorkflowDoneHandler            // Handler used to notify Workflow Manager of task completions
 	containerCache        *containerCache                // LRU cache for active containers
-	containerBuildMu      sync.Mutex                     // Protects container creation to prevent duplicates
+	containerBuilds       singleflight.Group             // Collapses concurrent rebuilds of the same container
+	auditLog              audit.Recorder                 // Records executed task actions; may be nil
+	stateObserver         StateObserver                  // Told of task state changes; may be nil
 }
 
-// NewTaskManager creates a new TaskManager instance with persistence data store.
-func NewTaskManager(db *gorm.DB, factory plugin.TaskFactory) (TaskManager, error) {
+// NewTaskManager creates a new TaskManager instance with persistence data store. Executed
+// task actions are recorded in auditLog, which may be nil. Active containers are cached as
+// configured by cacheCfg.
+func NewTaskManager(db *gorm.DB, factory plugin.TaskFactory, auditLog audit.Recorder, cacheCfg CacheConfig) (TaskManager, error) {
 	store, err := persistence.NewTaskStore(db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create task store: %w", err)
 	}
 
-	// Initialize container cache with capacity of 100 active containers
-	cache := newContainerCache(100)
+	cache := newContainerCache(cacheCfg.Capacity, cacheCfg.IdleTTL)
 
 	return &taskManager{
 		factory:        factory,
 		store:          store,
 		containerCache: cache,
+		auditLog:       auditLog,
 	}, nil
 }
 
+// CacheStats returns the counters of the task container cache.
+func (tm *taskManager) CacheStats() CacheStats {
+	return tm.containerCache.Stats()
+}
+
+// CachedContainers describes the containers currently cached, most recently used first.
+func (tm *taskManager) CachedContainers() []CachedContainer {
+	return tm.containerCache.Snapshot()
+}
+
 // RegisterUpstreamUpdateCallback registers the callback used for task updates.
 func (tm *taskManager) RegisterUpstreamUpdateCallback(callback WorkflowUpdateHandler) {
 	tm.workflowUpdateHandler = callback
@@ -111,37 +165,80 @@ func (tm *taskManager) RegisterUpstreamDoneCallback(callback WorkflowDoneHandler
 	tm.workflowDoneHandler = callback
 }
 
+// RegisterStateObserver registers the observer told of task state changes.
+func (tm *taskManager) RegisterStateObserver(observer StateObserver) {
+	tm.stateObserver = observer
+}
+
 // GetTaskRenderInfo retrieves task rendering info (core logic)
 func (tm *taskManager) GetTaskRenderInfo(ctx context.Context, taskID string) (*plugin.ApiResponse, error) {
 	if taskID == "" {
 		return nil, fmt.Errorf("taskID is required")
 	}
 
-	activeTask, err := tm.getTask(ctx, taskID)
+	// Rendering can write too: a payment session that has run past its TTL is timed out
+	// and a stale gateway session rotated. It is serialized with actions on the task.
+	unlock, err := tm.store.LockTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	activeTask, err := tm.getCurrentTask(ctx, taskID)
 	if err != nil {
 		return nil, fmt.Errorf("task %s not found: %w", taskID, err)
 	}
 
 	result, err := activeTask.GetRenderInfo(ctx)
 	if err != nil {
+		if errors.Is(err, persistence.ErrVersionConflict) {
+			return nil, tm.conflict(taskID)
+		}
 		return nil, fmt.Errorf("failed to get render info for task %s: %w", taskID, err)
 	}
 
 	return result, nil
 }
 
+// ListFormTasks lists the live tasks rendering a form and the form version each is pinned to
+func (tm *taskManager) ListFormTasks(ctx context.Context, formID string) ([]persistence.FormTask, error) {
+	if formID == "" {
+		return nil, fmt.Errorf("formID is required")
+	}
+	tasks, err := tm.store.ListFormTasks(formID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks for form %s: %w", formID, err)
+	}
+	return tasks, nil
+}
+
 // ExecuteTask is the core logic for executing a task
 func (tm *taskManager) ExecuteTask(ctx context.Context, req ExecuteTaskRequest) (*plugin.ExecutionResponse, error) {
 	if req.TaskID == "" {
 		return nil, fmt.Errorf("task_id is required")
 	}
 
-	activeTask, err := tm.getTask(ctx, req.TaskID)
+	// Actions on a task are serialized across replicas.
+	unlock, err := tm.store.LockTask(ctx, req.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	activeTask, err := tm.getCurrentTask(ctx, req.TaskID)
 	if err != nil {
 		return nil, fmt.Errorf("task %s not found: %w", req.TaskID, err)
 	}
 
+	pluginStateBefore, taskStateBefore := activeTask.GetPluginState(), activeTask.GetTaskState()
 	result, err := tm.execute(ctx, activeTask, req.Payload)
+	if errors.Is(err, persistence.ErrVersionConflict) {
+		slog.WarnContext(ctx, "task was modified concurrently",
+			"taskID", req.TaskID,
+			"workflowID", req.WorkflowID,
+			"error", err)
+		return nil, tm.conflict(req.TaskID)
+	}
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to execute task",
 			"taskID", req.TaskID,
@@ -149,9 +246,134 @@ func (tm *taskManager) ExecuteTask(ctx context.Context, req ExecuteTaskRequest)
 			"error", err)
 		return nil, fmt.Errorf("failed to execute task: %w", err)
 	}
+	// The plugin's writes are not made in one transaction the entry could join, so an action
+	// that cannot be audited is reported as failed even though it has taken effect.
+	if err := tm.recordExecution(ctx, activeTask, req.Payload, pluginStateBefore, taskStateBefore); err != nil {
+		slog.ErrorContext(ctx, "task action not audited",
+			"taskID", req.TaskID,
+			"workflowID", req.WorkflowID,
+			"error", err)
+		return nil, fmt.Errorf("failed to execute task: %w", err)
+	}
 	return result, nil
 }
 
+// FailTask fails a task whatever its plugin state. The task is left in plugin state
+// SLA_BREACHED, from which no plugin accepts further actions.
+func (tm *taskManager) FailTask(ctx context.Context, taskID, reason string) error {
+	if taskID == "" {
+		return fmt.Errorf("taskID is required")
+	}
+
+	unlock, err := tm.store.LockTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	activeTask, err := tm.getCurrentTask(ctx, taskID)
+	if err != nil {
+		return fmt.Errorf("task %s not found: %w", taskID, err)
+	}
+	taskStateBefore, pluginStateBefore := activeTask.GetTaskState(), activeTask.GetPluginState()
+	if taskStateBefore == plugin.Completed || taskStateBefore == plugin.Failed {
+		return fmt.Errorf("task %s has already ended in state %s", taskID, taskStateBefore)
+	}
+
+	if err := activeTask.Fail(plugin.PluginStateSLABreached); err != nil {
+		if errors.Is(err, persistence.ErrVersionConflict) {
+			return tm.conflict(taskID)
+		}
+		return fmt.Errorf("failed to fail task %s: %w", taskID, err)
+	}
+	slog.WarnContext(ctx, "task failed by the platform", "taskID", taskID, "workflowID", activeTask.WorkflowID, "reason", reason)
+
+	auditErr := audit.Record(ctx, tm.auditLog, audit.Entry{
+		Action:        audit.ActionTaskExecute,
+		ConsignmentID: activeTask.WorkflowID,
+		TaskID:        taskID,
+		ResourceType:  audit.ResourceTask,
+		ResourceID:    taskID,
+		Operation:     "FAIL",
+		StateBefore:   pluginStateBefore,
+		StateAfter:    activeTask.GetPluginState(),
+		Details: map[string]any{
+			"taskStateBefore": taskStateBefore,
+			"taskStateAfter":  activeTask.GetTaskState(),
+			"reason":          reason,
+		},
+	})
+
+	// The task has failed; its workflow is told so even when the failure could not be audited.
+	tm.notifyStateObserver(ctx, activeTask)
+	tm.notifyWorkflowDoneHandler(ctx, activeTask.WorkflowID, taskID, nil)
+	if auditErr != nil {
+		return fmt.Errorf("failed to fail task %s: %w", taskID, auditErr)
+	}
+	return nil
+}
+
+// recordExecution appends an executed task action to the audit log. OGA verification and
+// feedback are recorded as OGA reviews.
+func (tm *taskManager) recordExecution(ctx context.Context, activeTask *container.Container, payload *plugin.ExecutionRequest, pluginStateBefore string, taskStateBefore plugin.State) error {
+	entry := audit.Entry{
+		Action:        audit.ActionTaskExecute,
+		ConsignmentID: activeTask.WorkflowID,
+		TaskID:        activeTask.TaskID,
+		ResourceType:  audit.ResourceTask,
+		ResourceID:    activeTask.TaskID,
+		StateBefore:   pluginStateBefore,
+		StateAfter:    activeTask.GetPluginState(),
+		Details: map[string]any{
+			"taskStateBefore": taskStateBefore,
+			"taskStateAfter":  activeTask.GetTaskState(),
+		},
+	}
+	if payload != nil {
+		entry.Operation = payload.Action
+		entry.PayloadHash = audit.HashPayload(payload)
+		if payload.Action == plugin.SimpleFormActionOgaVerify || payload.Action == plugin.SimpleFormActionOgaFeedback {
+			entry.Action = audit.ActionOGAReview
+			pinOGARecord(ctx, activeTask, payload.Action, entry.Details)
+		}
+	}
+	return audit.Record(ctx, tm.auditLog, entry)
+}
+
+// pinOGARecord adds the hash of the record an OGA review left in the task's local store to
+// details: the verdict, or the feedback round just appended. The audit chain then covers
+// the stored OGAFeedbackEntry rounds and verdicts, which are otherwise mutable task state.
+func pinOGARecord(ctx context.Context, activeTask *container.Container, action string, details map[string]any) {
+	switch action {
+	case plugin.SimpleFormActionOgaVerify:
+		record, err := activeTask.ReadFromLocalStore(plugin.SimpleFormStoreKeyOgaResponse)
+		if err != nil || record == nil {
+			slog.WarnContext(ctx, "failed to read OGA response for audit", "taskID", activeTask.TaskID, "error", err)
+			return
+		}
+		details["ogaResponseHash"] = audit.HashPayload(record)
+	case plugin.SimpleFormActionOgaFeedback:
+		raw, err := activeTask.ReadFromLocalStore(plugin.SimpleFormStoreKeyOgaFeedback)
+		if err != nil || raw == nil {
+			slog.WarnContext(ctx, "failed to read OGA feedback history for audit", "taskID", activeTask.TaskID, "error", err)
+			return
+		}
+		// The history is a []plugin.OGAFeedbackEntry, or its JSON form after a cache miss.
+		b, err := json.Marshal(raw)
+		if err != nil {
+			return
+		}
+		var history []plugin.OGAFeedbackEntry
+		if err := json.Unmarshal(b, &history); err != nil || len(history) == 0 {
+			slog.WarnContext(ctx, "failed to decode OGA feedback history for audit", "taskID", activeTask.TaskID, "error", err)
+			return
+		}
+		round := history[len(history)-1]
+		details["feedbackRound"] = round.Round
+		details["feedbackHash"] = audit.HashPayload(round)
+	}
+}
+
 // InitTask initializes a new task container, creates its execution record,
 // and starts the task. It builds the plugin executor, sets up local state management,
 // creates a container with the executor and state managers, persists the task record
@@ -160,7 +382,7 @@ func (tm *taskManager) ExecuteTask(ctx context.Context, req ExecuteTaskRequest)
 func (tm *taskManager) InitTask(ctx context.Context, request InitTaskRequest) (*InitTaskResponse, error) {
 
 	// Check if container already exists in cache
-	if existing, found := tm.containerCache.Get(request.TaskID); found {
+	if existing, found := tm.containerCache.Peek(request.TaskID); found {
 		slog.WarnContext(ctx, "task already initialized, reusing existing container",
 			"taskID", request.TaskID)
 		return tm.start(ctx, existing)
@@ -172,10 +394,12 @@ func (tm *taskManager) InitTask(ctx context.Context, request InitTaskRequest) (*
 		return nil, fmt.Errorf("failed to build executor: %w", err)
 	}
 
-	// Generate the state manager
+	// Generate the state manager. The record created below starts at version 1.
+	version := persistence.NewVersion(1)
 	localStateManager, err := persistence.NewLocalStateManager(
 		tm.store,
 		request.TaskID,
+		version,
 	)
 
 	if err != nil {
@@ -188,7 +412,7 @@ func (tm *taskManager) InitTask(ctx context.Context, request InitTaskRequest) (*
 		globalStateCopy[k] = v
 	}
 
-	activeTask := container.NewContainer(request.TaskID, request.WorkflowID, request.WorkflowNodeTemplateID, plugin.Initialized, globalStateCopy, localStateManager, tm.store, exec.Plugin, exec.FSM)
+	activeTask := container.NewContainer(request.TaskID, request.WorkflowID, request.WorkflowNodeTemplateID, plugin.Initialized, globalStateCopy, localStateManager, tm.store, version, exec.Plugin, exec.FSM)
 
 	// Convert request.Config to json.RawMessage
 	configBytes, err := json.Marshal(request.Config)
@@ -211,6 +435,8 @@ func (tm *taskManager) InitTask(ctx context.Context, request InitTaskRequest) (*
 		State:                  plugin.Initialized,
 		Config:                 configBytes,
 		GlobalContext:          globalContextBytes,
+		SLA:                    request.SLA,
+		Version:                1,
 	}
 
 	// Store in SQLite
@@ -234,6 +460,9 @@ func (tm *taskManager) start(ctx context.Context, activeTask *container.Containe
 	if err != nil {
 		return nil, fmt.Errorf("failed to start task: %w", err)
 	}
+	if result.NewState != nil {
+		tm.notifyStateObserver(ctx, activeTask)
+	}
 
 	// Notify the workflow manager of the initial state after starting the task (e.g., InProgress). This ensures that
 	//the workflow manager is aware of the task's state change immediately after initialization.
@@ -251,6 +480,7 @@ func (tm *taskManager) execute(ctx context.Context, activeTask *container.Contai
 	}
 
 	if result.NewState != nil {
+		tm.notifyStateObserver(ctx, activeTask)
 		if *result.NewState == plugin.Completed || *result.NewState == plugin.Failed {
 			tm.notifyWorkflowDoneHandler(ctx, activeTask.WorkflowID, activeTask.TaskID, result.Outputs)
 		} else {
@@ -262,26 +492,65 @@ func (tm *taskManager) execute(ctx context.Context, activeTask *container.Contai
 }
 
 // getTask retrieves a task from the cache or store and combines it with the in-memory executor and returns a task container.
-// Uses double-checked locking to prevent duplicate container creation.
+// Concurrent misses for the same task share one rebuild; misses for different tasks rebuild in parallel.
 func (tm *taskManager) getTask(ctx context.Context, taskID string) (*container.Container, error) {
-	// First check (unlocked, fast path for cache hits)
+	// Fast path for cache hits
 	if cachedContainer, found := tm.containerCache.Get(taskID); found {
 		slog.DebugContext(ctx, "container retrieved from cache",
 			"taskID", taskID)
 		return cachedContainer, nil
 	}
 
-	// Lock to prevent duplicate container creation
-	tm.containerBuildMu.Lock()
-	defer tm.containerBuildMu.Unlock()
+	result, err, _ := tm.containerBuilds.Do(taskID, func() (any, error) {
+		// Check again: a rebuild that finished since the miss above has cached the container.
+		if cachedContainer, found := tm.containerCache.Peek(taskID); found {
+			return cachedContainer, nil
+		}
+		// The rebuild is shared, so it does not end with the request that started it.
+		return tm.rebuildTask(context.WithoutCancel(ctx), taskID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*container.Container), nil
+}
+
+// getCurrentTask is getTask for callers holding the task lock. A cached container behind the
+// stored task, which another replica has updated since, is rebuilt.
+func (tm *taskManager) getCurrentTask(ctx context.Context, taskID string) (*container.Container, error) {
+	activeTask, err := tm.getTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := tm.store.GetVersion(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if activeTask.Version() == current {
+		return activeTask, nil
+	}
 
-	// Second check after acquiring lock (another goroutine may have created it)
-	if cachedContainer, found := tm.containerCache.Get(taskID); found {
-		slog.DebugContext(ctx, "container retrieved from cache after lock",
-			"taskID", taskID)
-		return cachedContainer, nil
+	slog.DebugContext(ctx, "cached container is stale, rebuilding",
+		"taskID", taskID,
+		"cachedVersion", activeTask.Version(),
+		"currentVersion", current)
+	tm.containerCache.Delete(taskID)
+	return tm.getTask(ctx, taskID)
+}
+
+// conflict evicts the container of a task that was modified concurrently, so the next
+// request rebuilds it, and returns a ConflictError carrying the task's current state.
+func (tm *taskManager) conflict(taskID string) error {
+	tm.containerCache.Delete(taskID)
+	conflict := &ConflictError{TaskID: taskID}
+	if current, err := tm.store.GetByID(taskID); err == nil {
+		conflict.State, conflict.PluginState, conflict.Version = current.State, current.PluginState, current.Version
 	}
+	return conflict
+}
 
+// rebuildTask rebuilds a task container from persistence and caches it.
+func (tm *taskManager) rebuildTask(ctx context.Context, taskID string) (*container.Container, error) {
 	// Cache miss - rebuild from persistence
 	slog.DebugContext(ctx, "container not in cache, rebuilding from persistence",
 		"taskID", taskID)
@@ -307,10 +576,12 @@ func (tm *taskManager) getTask(ctx context.Context, taskID string) (*container.C
 		return nil, fmt.Errorf("failed to rebuild executor: %w", err)
 	}
 
+	version := persistence.NewVersion(execution.Version)
 	localState, err := persistence.NewLocalStateManagerWithCache(
 		tm.store,
 		execution.ID,
 		execution.LocalState,
+		version,
 	)
 
 	if err != nil {
@@ -328,7 +599,7 @@ func (tm *taskManager) getTask(ctx context.Context, taskID string) (*container.C
 	}
 
 	activeContainer := container.NewContainer(
-		execution.ID, execution.WorkflowID, execution.WorkflowNodeTemplateID, execution.State, globalContext, localState, tm.store, exec.Plugin, exec.FSM)
+		execution.ID, execution.WorkflowID, execution.WorkflowNodeTemplateID, execution.State, globalContext, localState, tm.store, version, exec.Plugin, exec.FSM)
 
 	// Cache the rebuilt container
 	tm.containerCache.Set(taskID, activeContainer)
@@ -339,6 +610,21 @@ func (tm *taskManager) getTask(ctx context.Context, taskID string) (*container.C
 	return activeContainer, nil
 }
 
+// notifyStateObserver reports the state a task has entered to the registered observer. A
+// failure is logged: the change has already taken effect.
+func (tm *taskManager) notifyStateObserver(ctx context.Context, activeTask *container.Container) {
+	if tm.stateObserver == nil {
+		return
+	}
+	if err := tm.stateObserver.Observe(ctx, activeTask.TaskID, activeTask.GetTaskState(), activeTask.GetPluginState()); err != nil {
+		slog.ErrorContext(ctx, "failed to report task state change",
+			"taskID", activeTask.TaskID,
+			"state", activeTask.GetTaskState(),
+			"pluginState", activeTask.GetPluginState(),
+			"error", err)
+	}
+}
+
 // notifyWorkflowUpdateHandler sends state updates to Workflow Manager via the registered handler.
 // TODO: `outcome` is only used for the old workflow manager, remove this after v1 workflow manager is fully deprecated.
 func (tm *taskManager) notifyWorkflowUpdateHandler(ctx context.Context, taskID string, state *plugin.State, extendedState *string, outputs map[string]any, outcome *string) {
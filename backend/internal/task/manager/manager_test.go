@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
@@ -13,6 +15,8 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/OpenNSW/nsw/internal/audit"
+	"github.com/OpenNSW/nsw/internal/auth"
 	"github.com/OpenNSW/nsw/internal/task/container"
 	"github.com/OpenNSW/nsw/internal/task/persistence"
 	"github.com/OpenNSW/nsw/internal/task/plugin"
@@ -99,6 +103,37 @@ func (m *MockTaskStore) GetPluginState(id string) (string, error) {
 	return args.Get(0).(string), args.Error(1)
 }
 
+func (m *MockTaskStore) ListFormTasks(formID string) ([]persistence.FormTask, error) {
+	args := m.Called(formID)
+	return args.Get(0).([]persistence.FormTask), args.Error(1)
+}
+
+func (m *MockTaskStore) UpdateStateIfVersion(id string, version int64, update persistence.StateUpdate) (int64, error) {
+	args := m.Called(id, version, update)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaskStore) GetVersion(id string) (int64, error) {
+	args := m.Called(id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaskStore) LockTask(ctx context.Context, id string) (func(), error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(func()), args.Error(1)
+}
+
+// expectLock expects the task lock to be taken and released once.
+func (m *MockTaskStore) expectLock(t *testing.T, id string) {
+	t.Helper()
+	released := false
+	m.On("LockTask", id).Return(func() { released = true }, nil).Once()
+	t.Cleanup(func() { assert.True(t, released, "task lock was not released") })
+}
+
 // MockPlugin
 type MockPlugin struct {
 	mock.Mock
@@ -132,6 +167,20 @@ func (m *MockPlugin) GetRenderInfo(ctx context.Context) (*plugin.ApiResponse, er
 	return args.Get(0).(*plugin.ApiResponse), args.Error(1)
 }
 
+// recordedAudit collects the entries appended to the audit log, or fails with err.
+type recordedAudit struct {
+	entries []audit.Entry
+	err     error
+}
+
+func (r *recordedAudit) Append(ctx context.Context, entry *audit.Entry) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
 func setupTest(t *testing.T) (*taskManager, *MockTaskFactory, *MockTaskStore, *MockPlugin) {
 	t.Helper()
 
@@ -142,7 +191,7 @@ func setupTest(t *testing.T) (*taskManager, *MockTaskFactory, *MockTaskStore, *M
 	tm := &taskManager{
 		factory:        mockFactory,
 		store:          mockStore,
-		containerCache: newContainerCache(10),
+		containerCache: newContainerCache(10, 0),
 	}
 
 	return tm, mockFactory, mockStore, mockPlugin
@@ -165,7 +214,7 @@ func TestInitTask(t *testing.T) {
 		// Pre-populate cache
 		mockPlugin.On("Init", mock.Anything).Return().Once()
 
-		newContainer := container.NewContainer(taskID, uuid.NewString(), uuid.NewString(), plugin.InProgress, nil, nil, nil, mockPlugin, nil)
+		newContainer := container.NewContainer(taskID, uuid.NewString(), uuid.NewString(), plugin.InProgress, nil, nil, nil, nil, mockPlugin, nil)
 		tm.containerCache.Set(taskID, newContainer)
 
 		// Expect Start to be called on the *existing* container's plugin
@@ -302,7 +351,10 @@ func TestExecuteTask(t *testing.T) {
 			Type:                   plugin.TaskTypeSimpleForm,
 			Config:                 json.RawMessage(`{}`),
 			GlobalContext:          json.RawMessage(`{}`),
+			Version:                3,
 		}
+		mockStore.expectLock(t, taskID)
+		mockStore.On("GetVersion", taskID).Return(int64(3), nil).Once()
 		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
 		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
 		mockStore.On("GetLocalState", taskID).Return(json.RawMessage(`{}`), nil).Once()
@@ -317,11 +369,67 @@ func TestExecuteTask(t *testing.T) {
 		}
 		mockPlugin.On("Execute", mock.Anything, reqBody.Payload).Return(execResp, nil).Once()
 		mockStore.On("UpdateStatus", taskID, &newState).Return(nil).Once()
+		auditLog := &recordedAudit{}
+		tm.auditLog = auditLog
 
 		result, err := tm.ExecuteTask(context.Background(), reqBody)
 
 		assert.NoError(t, err)
 		assert.Equal(t, execResp, result)
+		if assert.Len(t, auditLog.entries, 1) {
+			entry := auditLog.entries[0]
+			assert.Equal(t, audit.ActionTaskExecute, entry.Action)
+			assert.Equal(t, "submit", entry.Operation)
+			assert.Equal(t, workflowID, entry.ConsignmentID)
+			assert.Equal(t, taskID, entry.ResourceID)
+			assert.Equal(t, audit.HashPayload(reqBody.Payload), entry.PayloadHash)
+			assert.Equal(t, audit.ActorAnonymous, entry.ActorKind)
+		}
+	})
+
+	t.Run("OGA Review Audited", func(t *testing.T) {
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+		auditLog := &recordedAudit{}
+		tm.auditLog = auditLog
+
+		taskID := uuid.NewString()
+		workflowID := uuid.NewString()
+		reqBody := ExecuteTaskRequest{
+			TaskID:  taskID,
+			Payload: &plugin.ExecutionRequest{Action: plugin.SimpleFormActionOgaFeedback, Content: map[string]any{"feedback": "Correct the HS code"}},
+		}
+		taskInfo := &persistence.TaskInfo{
+			ID:         taskID,
+			WorkflowID: workflowID,
+			Type:       plugin.TaskTypeSimpleForm,
+			Config:     json.RawMessage(`{}`),
+			// The feedback round the plugin appended, as it is read back after a cache miss.
+			LocalState: json.RawMessage(`{"ogaFeedback":[{"content":{"feedback":"Correct the HS code"},"timestamp":"2026-03-01T09:00:00Z","round":1}]}`),
+			Version:    1,
+		}
+		mockStore.expectLock(t, taskID)
+		mockStore.On("GetVersion", taskID).Return(int64(1), nil).Once()
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetLocalState", taskID).Return(json.RawMessage(`{}`), nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("OGA_REVIEW", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		mockPlugin.On("Execute", mock.Anything, reqBody.Payload).Return(&plugin.ExecutionResponse{}, nil).Once()
+
+		ctx := context.WithValue(context.Background(), auth.AuthContextKey, &auth.AuthContext{Client: &auth.ClientContext{ClientID: "NPQS_TO_NSW"}})
+		_, err := tm.ExecuteTask(ctx, reqBody)
+
+		assert.NoError(t, err)
+		if assert.Len(t, auditLog.entries, 1) {
+			entry := auditLog.entries[0]
+			assert.Equal(t, audit.ActionOGAReview, entry.Action)
+			assert.Equal(t, audit.ActorClient, entry.ActorKind)
+			assert.Equal(t, "NPQS_TO_NSW", entry.ActorID)
+			assert.Equal(t, "OGA_REVIEW", entry.StateBefore)
+			assert.Equal(t, workflowID, entry.ConsignmentID)
+			assert.Equal(t, 1, entry.Details["feedbackRound"])
+			assert.NotEmpty(t, entry.Details["feedbackHash"])
+		}
 	})
 
 	t.Run("Execute Error", func(t *testing.T) {
@@ -338,10 +446,13 @@ func TestExecuteTask(t *testing.T) {
 
 		// Mock GetTask
 		taskInfo := &persistence.TaskInfo{
-			ID:     taskID,
-			Type:   plugin.TaskTypeSimpleForm,
-			Config: json.RawMessage(`{}`),
+			ID:      taskID,
+			Type:    plugin.TaskTypeSimpleForm,
+			Config:  json.RawMessage(`{}`),
+			Version: 1,
 		}
+		mockStore.expectLock(t, taskID)
+		mockStore.On("GetVersion", taskID).Return(int64(1), nil).Once()
 		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
 		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
 		mockStore.On("GetLocalState", taskID).Return(json.RawMessage(`{}`), nil).Once()
@@ -350,11 +461,108 @@ func TestExecuteTask(t *testing.T) {
 
 		// Mock Execute Error
 		mockPlugin.On("Execute", mock.Anything, reqBody.Payload).Return(nil, errors.New("exec error")).Once()
+		auditLog := &recordedAudit{}
+		tm.auditLog = auditLog
 
 		result, err := tm.ExecuteTask(context.Background(), reqBody)
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
+		assert.Empty(t, auditLog.entries, "failed actions are not audited")
+	})
+
+	t.Run("Unaudited Action Fails", func(t *testing.T) {
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+		taskID := uuid.NewString()
+		reqBody := ExecuteTaskRequest{TaskID: taskID, Payload: &plugin.ExecutionRequest{Action: "submit"}}
+
+		taskInfo := &persistence.TaskInfo{ID: taskID, Type: plugin.TaskTypeSimpleForm, Config: json.RawMessage(`{}`), Version: 1}
+		mockStore.expectLock(t, taskID)
+		mockStore.On("GetVersion", taskID).Return(int64(1), nil).Once()
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		mockPlugin.On("Execute", mock.Anything, reqBody.Payload).Return(&plugin.ExecutionResponse{}, nil).Once()
+		tm.auditLog = &recordedAudit{err: errors.New("audit log unavailable")}
+
+		result, err := tm.ExecuteTask(context.Background(), reqBody)
+
+		assert.ErrorContains(t, err, "audit log unavailable")
+		assert.Nil(t, result)
+	})
+
+	t.Run("Stale Cached Container Rebuilt", func(t *testing.T) {
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+		taskID := uuid.NewString()
+		reqBody := ExecuteTaskRequest{TaskID: taskID, Payload: &plugin.ExecutionRequest{Action: "submit"}}
+
+		// Cached at version 1; another replica has since moved the task to version 2.
+		stalePlugin := new(MockPlugin)
+		stalePlugin.On("Init", mock.Anything).Return().Once()
+		stale := container.NewContainer(taskID, "wf-1", "", plugin.InProgress, nil, nil, nil, persistence.NewVersion(1), stalePlugin, nil)
+		tm.containerCache.Set(taskID, stale)
+
+		taskInfo := &persistence.TaskInfo{ID: taskID, WorkflowID: "wf-1", Type: plugin.TaskTypeSimpleForm, Config: json.RawMessage(`{}`), Version: 2}
+		mockStore.expectLock(t, taskID)
+		mockStore.On("GetVersion", taskID).Return(int64(2), nil).Once()
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		mockPlugin.On("Execute", mock.Anything, reqBody.Payload).Return(&plugin.ExecutionResponse{}, nil).Once()
+
+		_, err := tm.ExecuteTask(context.Background(), reqBody)
+
+		assert.NoError(t, err)
+		stalePlugin.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything)
+		mockPlugin.AssertExpectations(t)
+		cached, found := tm.containerCache.Peek(taskID)
+		assert.True(t, found)
+		assert.Equal(t, int64(2), cached.Version())
+	})
+
+	t.Run("Version Conflict", func(t *testing.T) {
+		tm, _, mockStore, mockPlugin := setupTest(t)
+		taskID := uuid.NewString()
+		reqBody := ExecuteTaskRequest{TaskID: taskID, Payload: &plugin.ExecutionRequest{Action: "SAVE_AS_DRAFT"}}
+
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		cached := container.NewContainer(taskID, "wf-1", "", plugin.InProgress, nil, nil, nil, persistence.NewVersion(4), mockPlugin, nil)
+		tm.containerCache.Set(taskID, cached)
+
+		mockStore.expectLock(t, taskID)
+		mockStore.On("GetVersion", taskID).Return(int64(4), nil).Once()
+		// A write that raced this action, e.g. from a replica rendering the task, lands first.
+		mockPlugin.On("Execute", mock.Anything, reqBody.Payload).
+			Return(nil, fmt.Errorf("failed to save draft: %w", persistence.ErrVersionConflict)).Once()
+		mockStore.On("GetByID", taskID).Return(&persistence.TaskInfo{ID: taskID, State: plugin.InProgress, PluginState: "DRAFT", Version: 5}, nil).Once()
+		auditLog := &recordedAudit{}
+		tm.auditLog = auditLog
+
+		result, err := tm.ExecuteTask(context.Background(), reqBody)
+
+		assert.Nil(t, result)
+		var conflict *ConflictError
+		if assert.ErrorAs(t, err, &conflict) {
+			assert.Equal(t, &ConflictError{TaskID: taskID, State: plugin.InProgress, PluginState: "DRAFT", Version: 5}, conflict)
+		}
+		assert.ErrorIs(t, err, persistence.ErrVersionConflict)
+		_, found := tm.containerCache.Peek(taskID)
+		assert.False(t, found, "the stale container should be evicted")
+		assert.Empty(t, auditLog.entries)
+	})
+
+	t.Run("Lock Error", func(t *testing.T) {
+		tm, _, mockStore, _ := setupTest(t)
+		taskID := uuid.NewString()
+		mockStore.On("LockTask", taskID).Return(nil, errors.New("lock timeout")).Once()
+
+		result, err := tm.ExecuteTask(context.Background(), ExecuteTaskRequest{TaskID: taskID})
+
+		assert.Nil(t, result)
+		assert.ErrorContains(t, err, "lock timeout")
+		mockStore.AssertNotCalled(t, "GetByID", taskID)
 	})
 
 	t.Run("Missing TaskID", func(t *testing.T) {
@@ -369,6 +577,115 @@ func TestExecuteTask(t *testing.T) {
 	})
 }
 
+// recordedObserver collects the task states reported to the state observer.
+type recordedObserver struct {
+	states []string
+}
+
+func (o *recordedObserver) Observe(_ context.Context, taskID string, state plugin.State, pluginState string) error {
+	o.states = append(o.states, taskID+":"+string(state)+":"+pluginState)
+	return nil
+}
+
+func TestGetTaskRenderInfo(t *testing.T) {
+	t.Run("Stale Cached Container Rebuilt", func(t *testing.T) {
+		tm, mockFactory, mockStore, mockPlugin := setupTest(t)
+		taskID := uuid.NewString()
+
+		// Cached at version 1; another replica has since moved the task to version 2.
+		stalePlugin := new(MockPlugin)
+		stalePlugin.On("Init", mock.Anything).Return().Once()
+		stale := container.NewContainer(taskID, "wf-1", "", plugin.InProgress, nil, nil, nil, persistence.NewVersion(1), stalePlugin, nil)
+		tm.containerCache.Set(taskID, stale)
+
+		taskInfo := &persistence.TaskInfo{ID: taskID, WorkflowID: "wf-1", Type: plugin.TaskTypeSimpleForm, Config: json.RawMessage(`{}`), Version: 2}
+		mockStore.expectLock(t, taskID)
+		mockStore.On("GetVersion", taskID).Return(int64(2), nil).Once()
+		mockStore.On("GetByID", taskID).Return(taskInfo, nil).Once()
+		mockFactory.On("BuildExecutor", mock.Anything, taskInfo.Type, taskInfo.Config).Return(plugin.Executor{Plugin: mockPlugin}, nil).Once()
+		mockStore.On("GetPluginState", taskID).Return("", nil).Once()
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		rendered := &plugin.ApiResponse{Success: true}
+		mockPlugin.On("GetRenderInfo", mock.Anything).Return(rendered, nil).Once()
+
+		result, err := tm.GetTaskRenderInfo(context.Background(), taskID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, rendered, result)
+		stalePlugin.AssertNotCalled(t, "GetRenderInfo", mock.Anything)
+	})
+
+	t.Run("Lock Timeout", func(t *testing.T) {
+		tm, _, mockStore, _ := setupTest(t)
+		taskID := uuid.NewString()
+		mockStore.On("LockTask", taskID).Return(nil, errors.New("lock timeout")).Once()
+
+		result, err := tm.GetTaskRenderInfo(context.Background(), taskID)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		mockStore.AssertNotCalled(t, "GetByID", taskID)
+	})
+}
+
+func TestFailTask(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		tm, _, mockStore, mockPlugin := setupTest(t)
+		taskID := uuid.NewString()
+
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		mockStore.On("GetPluginState", taskID).Return("OGA_ACKNOWLEDGED", nil).Once()
+		cached := container.NewContainer(taskID, "wf-1", "", plugin.InProgress, nil, nil, mockStore, persistence.NewVersion(4), mockPlugin, nil)
+		tm.containerCache.Set(taskID, cached)
+
+		mockStore.expectLock(t, taskID)
+		mockStore.On("GetVersion", taskID).Return(int64(4), nil).Once()
+		failed, breached := plugin.Failed, plugin.PluginStateSLABreached
+		mockStore.On("UpdateStateIfVersion", taskID, int64(4), persistence.StateUpdate{State: &failed, PluginState: &breached}).Return(int64(5), nil).Once()
+
+		auditLog := &recordedAudit{}
+		observer := &recordedObserver{}
+		var doneTaskID string
+		tm.auditLog = auditLog
+		tm.RegisterStateObserver(observer)
+		tm.RegisterUpstreamDoneCallback(func(_ context.Context, _ string, taskID string, _ map[string]any) {
+			doneTaskID = taskID
+		})
+
+		err := tm.FailTask(context.Background(), taskID, "SLA breached")
+
+		assert.NoError(t, err)
+		assert.Equal(t, plugin.Failed, cached.GetTaskState())
+		assert.Equal(t, int64(5), cached.Version())
+		assert.Equal(t, []string{taskID + ":FAILED:SLA_BREACHED"}, observer.states)
+		assert.Equal(t, taskID, doneTaskID)
+		if assert.Len(t, auditLog.entries, 1) {
+			entry := auditLog.entries[0]
+			assert.Equal(t, "FAIL", entry.Operation)
+			assert.Equal(t, "OGA_ACKNOWLEDGED", entry.StateBefore)
+			assert.Equal(t, plugin.PluginStateSLABreached, entry.StateAfter)
+		}
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Already Ended", func(t *testing.T) {
+		tm, _, mockStore, mockPlugin := setupTest(t)
+		taskID := uuid.NewString()
+
+		mockPlugin.On("Init", mock.Anything).Return().Once()
+		cached := container.NewContainer(taskID, "wf-1", "", plugin.Completed, nil, nil, nil, persistence.NewVersion(4), mockPlugin, nil)
+		tm.containerCache.Set(taskID, cached)
+
+		mockStore.expectLock(t, taskID)
+		mockStore.On("GetVersion", taskID).Return(int64(4), nil).Once()
+
+		err := tm.FailTask(context.Background(), taskID, "SLA breached")
+
+		assert.ErrorContains(t, err, "has already ended")
+		mockStore.AssertNotCalled(t, "UpdateStateIfVersion", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
 func TestNotifyWorkflowManager(t *testing.T) {
 	t.Run("Callback Nil", func(t *testing.T) {
 		tm := &taskManager{
@@ -409,7 +726,7 @@ func TestGetTask_CacheRebuild(t *testing.T) {
 		mockPlugin.On("Init", mock.Anything).Return().Once()
 
 		// Pre-populate cache
-		newContainer := container.NewContainer(taskID, uuid.NewString(), uuid.NewString(), plugin.InProgress, nil, nil, nil, mockPlugin, nil)
+		newContainer := container.NewContainer(taskID, uuid.NewString(), uuid.NewString(), plugin.InProgress, nil, nil, nil, nil, mockPlugin, nil)
 		tm.containerCache.Set(taskID, newContainer)
 
 		// Act
@@ -478,7 +795,7 @@ func TestNewTaskManager(t *testing.T) {
 	// Here persistence.NewTaskStore(db) likely just returns struct.
 
 	mockFactory := &MockTaskFactory{}
-	tm, err := NewTaskManager(gormDB, mockFactory)
+	tm, err := NewTaskManager(gormDB, mockFactory, nil, CacheConfig{Capacity: 10})
 	assert.NoError(t, err)
 	assert.NotNil(t, tm)
 
@@ -491,7 +808,7 @@ func TestNewTaskManager(t *testing.T) {
 
 func TestContainerCache(t *testing.T) {
 	t.Run("LRU Eviction", func(t *testing.T) {
-		cache := newContainerCache(2)
+		cache := newContainerCache(2, 0)
 
 		c1 := &container.Container{TaskID: uuid.NewString()}
 		c2 := &container.Container{TaskID: uuid.NewString()}
@@ -518,7 +835,7 @@ func TestContainerCache(t *testing.T) {
 	})
 
 	t.Run("Delete", func(t *testing.T) {
-		cache := newContainerCache(10)
+		cache := newContainerCache(10, 0)
 		c1 := &container.Container{TaskID: uuid.NewString()}
 		cache.Set(c1.TaskID, c1)
 
@@ -529,11 +846,78 @@ func TestContainerCache(t *testing.T) {
 	})
 
 	t.Run("Clear", func(t *testing.T) {
-		cache := newContainerCache(10)
+		cache := newContainerCache(10, 0)
 		c1 := &container.Container{TaskID: uuid.NewString()}
 		cache.Set(c1.TaskID, c1)
 
 		cache.Clear()
 		assert.Equal(t, 0, cache.Len())
 	})
+
+	t.Run("Counts Hits, Misses And Evictions", func(t *testing.T) {
+		cache := newContainerCache(1, 0)
+		c1 := &container.Container{TaskID: uuid.NewString()}
+		c2 := &container.Container{TaskID: uuid.NewString()}
+
+		cache.Set(c1.TaskID, c1)
+		cache.Get(c1.TaskID)
+		cache.Set(c2.TaskID, c2) // Evicts c1
+		cache.Get(c1.TaskID)
+		cache.Peek(c2.TaskID) // Not counted
+
+		stats := cache.Stats()
+		assert.Equal(t, uint64(1), stats.Hits)
+		assert.Equal(t, uint64(1), stats.Misses)
+		assert.Equal(t, map[string]uint64{EvictionCapacity: 1, EvictionIdle: 0}, stats.Evictions)
+		assert.Equal(t, 1, stats.Size)
+		assert.Equal(t, 1, stats.Capacity)
+	})
+
+	t.Run("Idle Eviction", func(t *testing.T) {
+		cache := newContainerCache(10, time.Minute)
+		now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		cache.now = func() time.Time { return now }
+		c1 := &container.Container{TaskID: uuid.NewString()}
+		c2 := &container.Container{TaskID: uuid.NewString()}
+
+		cache.Set(c1.TaskID, c1)
+		now = now.Add(40 * time.Second)
+		cache.Set(c2.TaskID, c2)
+		now = now.Add(30 * time.Second)
+
+		// c1 has been idle for 70s, c2 for 30s.
+		_, found := cache.Get(c1.TaskID)
+		assert.False(t, found, "c1 should be evicted as idle")
+		_, found = cache.Get(c2.TaskID)
+		assert.True(t, found, "c2 should remain")
+
+		// Using c2 keeps it cached past its original deadline.
+		now = now.Add(50 * time.Second)
+		_, found = cache.Get(c2.TaskID)
+		assert.True(t, found, "c2 should remain after being used")
+
+		stats := cache.Stats()
+		assert.Equal(t, uint64(1), stats.Evictions[EvictionIdle])
+		assert.Equal(t, 1, stats.Size)
+		assert.Equal(t, float64(60), stats.IdleTTLSeconds)
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		cache := newContainerCache(10, 0)
+		now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		cache.now = func() time.Time { return now }
+		c1 := &container.Container{TaskID: "task-1", WorkflowID: "wf-1", State: plugin.InProgress}
+		c2 := &container.Container{TaskID: "task-2", WorkflowID: "wf-2", State: plugin.Completed}
+
+		cache.Set(c1.TaskID, c1)
+		now = now.Add(time.Second)
+		cache.Set(c2.TaskID, c2)
+		now = now.Add(time.Second)
+		cache.Get(c1.TaskID)
+
+		assert.Equal(t, []CachedContainer{
+			{TaskID: "task-1", WorkflowID: "wf-1", TaskState: plugin.InProgress, CachedAt: now.Add(-2 * time.Second), LastAccess: now},
+			{TaskID: "task-2", WorkflowID: "wf-2", TaskState: plugin.Completed, CachedAt: now.Add(-time.Second), LastAccess: now.Add(-time.Second)},
+		}, cache.Snapshot())
+	})
 }
@@ -0,0 +1,38 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteCacheMetrics writes stats in the Prometheus text exposition format.
+func WriteCacheMetrics(w io.Writer, stats CacheStats) error {
+	_, err := fmt.Fprintf(w, `# HELP nsw_task_container_cache_hits_total Task container lookups served from the cache.
+# TYPE nsw_task_container_cache_hits_total counter
+nsw_task_container_cache_hits_total %d
+# HELP nsw_task_container_cache_misses_total Task container lookups that rebuilt the container from persistence.
+# TYPE nsw_task_container_cache_misses_total counter
+nsw_task_container_cache_misses_total %d
+# HELP nsw_task_container_cache_evictions_total Task containers evicted from the cache, by reason.
+# TYPE nsw_task_container_cache_evictions_total counter
+nsw_task_container_cache_evictions_total{reason=%q} %d
+nsw_task_container_cache_evictions_total{reason=%q} %d
+# HELP nsw_task_container_cache_size Task containers currently cached.
+# TYPE nsw_task_container_cache_size gauge
+nsw_task_container_cache_size %d
+# HELP nsw_task_container_cache_capacity Task containers the cache holds before evicting.
+# TYPE nsw_task_container_cache_capacity gauge
+nsw_task_container_cache_capacity %d
+# HELP nsw_task_container_cache_idle_ttl_seconds Idle time after which a task container is evicted; 0 when disabled.
+# TYPE nsw_task_container_cache_idle_ttl_seconds gauge
+nsw_task_container_cache_idle_ttl_seconds %g
+`,
+		stats.Hits,
+		stats.Misses,
+		EvictionCapacity, stats.Evictions[EvictionCapacity],
+		EvictionIdle, stats.Evictions[EvictionIdle],
+		stats.Size,
+		stats.Capacity,
+		stats.IdleTTLSeconds)
+	return err
+}
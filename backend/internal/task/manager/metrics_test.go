@@ -0,0 +1,37 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCacheMetrics(t *testing.T) {
+	var b strings.Builder
+	err := WriteCacheMetrics(&b, CacheStats{
+		Capacity:       100,
+		IdleTTLSeconds: 1800,
+		Size:           42,
+		Hits:           7,
+		Misses:         3,
+		Evictions:      map[string]uint64{EvictionCapacity: 2, EvictionIdle: 5},
+	})
+	require.NoError(t, err)
+
+	out := b.String()
+	for _, line := range []string{
+		"# TYPE nsw_task_container_cache_hits_total counter",
+		"nsw_task_container_cache_hits_total 7",
+		"nsw_task_container_cache_misses_total 3",
+		`nsw_task_container_cache_evictions_total{reason="capacity"} 2`,
+		`nsw_task_container_cache_evictions_total{reason="idle"} 5`,
+		"# TYPE nsw_task_container_cache_size gauge",
+		"nsw_task_container_cache_size 42",
+		"nsw_task_container_cache_capacity 100",
+		"nsw_task_container_cache_idle_ttl_seconds 1800",
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+}
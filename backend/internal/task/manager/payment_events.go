@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/paymentsv2"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+// PaymentSystemActor identifies the payments webhook pipeline when it acts on tasks.
+const PaymentSystemActor = "payments-webhook"
+
+// systemOnlyActions can only be driven by the platform itself, never by a user or
+// M2M client calling the public task API. A trader must not be able to mark their
+// own fee as paid, nor an OGA client mark it paid or refunded.
+var systemOnlyActions = map[string]struct{}{
+	plugin.PaymentActionSuccess:  {},
+	plugin.PaymentActionFailed:   {},
+	plugin.PaymentActionRefunded: {},
+}
+
+// isSystemOnlyAction reports whether action may only be executed by a system actor.
+func isSystemOnlyAction(action string) bool {
+	_, ok := systemOnlyActions[action]
+	return ok
+}
+
+// NewPaymentEventHandler returns a paymentsv2.EventHandler that pushes the outcome of
+// a verified gateway webhook, or a reconciliation correction, into the owning PAYMENT
+// task as a system actor. An expired transaction is reported to the task as a failure,
+// and a refund of a completed payment is recorded on the task.
+func NewPaymentEventHandler(tm TaskManager) paymentsv2.EventHandler {
+	return func(ctx context.Context, event paymentsv2.InternalPaymentEvent) error {
+		if event.Data.TaskID == "" {
+			return fmt.Errorf("payment event %s is not linked to a task", event.EventType)
+		}
+
+		var action string
+		switch event.EventType {
+		case paymentsv2.PaymentEventConfirmed:
+			action = plugin.PaymentActionSuccess
+		case paymentsv2.PaymentEventFailed, paymentsv2.PaymentEventExpired:
+			action = plugin.PaymentActionFailed
+		case paymentsv2.PaymentEventRefunded:
+			action = plugin.PaymentActionRefunded
+		default:
+			return fmt.Errorf("unsupported payment event type %q", event.EventType)
+		}
+
+		content := map[string]any{
+			"referenceNumber":      event.Data.ReferenceNumber,
+			"gatewayTransactionId": event.Data.GatewayTransactionID,
+			"amountPaid":           event.Data.AmountPaid.String(),
+			"currency":             event.Data.Currency,
+			"confirmedAt":          event.Data.ConfirmedAt,
+			"status":               string(event.Data.Status),
+			"source":               event.Data.Source,
+		}
+		if event.Data.RefundID != "" {
+			content["refundId"] = event.Data.RefundID
+		}
+		if event.Data.RefundedAmount != nil {
+			content["refundedAmount"] = event.Data.RefundedAmount.String()
+		}
+
+		_, err := tm.ExecuteTask(auth.WithSystemActor(ctx, PaymentSystemActor), ExecuteTaskRequest{
+			TaskID: event.Data.TaskID,
+			Payload: &plugin.ExecutionRequest{
+				Action:  action,
+				Content: content,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		slog.InfoContext(ctx, "payment task advanced from payment event",
+			"taskID", event.Data.TaskID,
+			"reference", event.Data.ReferenceNumber,
+			"action", action,
+			"source", event.Data.Source)
+		return nil
+	}
+}
@@ -0,0 +1,137 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/paymentsv2"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+// recordingTaskManager captures ExecuteTask calls made by the payment event handler.
+type recordingTaskManager struct {
+	TaskManager
+	ctx  context.Context
+	req  ExecuteTaskRequest
+	err  error
+	hits int
+}
+
+func (r *recordingTaskManager) ExecuteTask(ctx context.Context, req ExecuteTaskRequest) (*plugin.ExecutionResponse, error) {
+	r.ctx = ctx
+	r.req = req
+	r.hits++
+	return &plugin.ExecutionResponse{}, r.err
+}
+
+func paymentEvent(eventType, taskID string) paymentsv2.InternalPaymentEvent {
+	return paymentsv2.InternalPaymentEvent{
+		EventType: eventType,
+		Data: paymentsv2.EventData{
+			TaskID:          taskID,
+			ReferenceNumber: "NSW-PR-2026-AB234",
+		},
+	}
+}
+
+func TestPaymentEventHandler(t *testing.T) {
+	t.Run("confirmed maps to PAYMENT_SUCCESS as system actor", func(t *testing.T) {
+		tm := &recordingTaskManager{}
+		handle := NewPaymentEventHandler(tm)
+
+		err := handle(context.Background(), paymentEvent(paymentsv2.PaymentEventConfirmed, "task-1"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "task-1", tm.req.TaskID)
+		require.NotNil(t, tm.req.Payload)
+		assert.Equal(t, plugin.PaymentActionSuccess, tm.req.Payload.Action)
+		content := tm.req.Payload.Content.(map[string]any)
+		assert.Equal(t, "NSW-PR-2026-AB234", content["referenceNumber"])
+		authCtx := auth.GetAuthContext(tm.ctx)
+		require.NotNil(t, authCtx)
+		require.NotNil(t, authCtx.System)
+		assert.Equal(t, PaymentSystemActor, authCtx.System.Actor)
+		assert.Nil(t, authCtx.User)
+	})
+
+	t.Run("failed maps to PAYMENT_FAILED", func(t *testing.T) {
+		tm := &recordingTaskManager{}
+		handle := NewPaymentEventHandler(tm)
+
+		err := handle(context.Background(), paymentEvent(paymentsv2.PaymentEventFailed, "task-1"))
+
+		require.NoError(t, err)
+		assert.Equal(t, plugin.PaymentActionFailed, tm.req.Payload.Action)
+	})
+
+	t.Run("expired maps to PAYMENT_FAILED with status", func(t *testing.T) {
+		tm := &recordingTaskManager{}
+		handle := NewPaymentEventHandler(tm)
+		event := paymentEvent(paymentsv2.PaymentEventExpired, "task-1")
+		event.Data.Status = paymentsv2.PaymentStatusExpired
+		event.Data.Source = paymentsv2.StatusSourceReconciliation
+
+		err := handle(context.Background(), event)
+
+		require.NoError(t, err)
+		assert.Equal(t, plugin.PaymentActionFailed, tm.req.Payload.Action)
+		content := tm.req.Payload.Content.(map[string]any)
+		assert.Equal(t, "EXPIRED", content["status"])
+		assert.Equal(t, paymentsv2.StatusSourceReconciliation, content["source"])
+	})
+
+	t.Run("refunded maps to PAYMENT_REFUNDED with refund details", func(t *testing.T) {
+		tm := &recordingTaskManager{}
+		handle := NewPaymentEventHandler(tm)
+		event := paymentEvent(paymentsv2.PaymentEventRefunded, "task-1")
+		amount := decimal.RequireFromString("25.50")
+		event.Data.Status = paymentsv2.PaymentStatusPartiallyRefunded
+		event.Data.Source = paymentsv2.StatusSourceRefund
+		event.Data.RefundID = "rf-1"
+		event.Data.RefundedAmount = &amount
+
+		err := handle(context.Background(), event)
+
+		require.NoError(t, err)
+		assert.Equal(t, plugin.PaymentActionRefunded, tm.req.Payload.Action)
+		content := tm.req.Payload.Content.(map[string]any)
+		assert.Equal(t, "rf-1", content["refundId"])
+		assert.Equal(t, "25.5", content["refundedAmount"])
+		assert.Equal(t, "PARTIALLY_REFUNDED", content["status"])
+	})
+
+	t.Run("unknown event rejected", func(t *testing.T) {
+		tm := &recordingTaskManager{}
+		handle := NewPaymentEventHandler(tm)
+
+		err := handle(context.Background(), paymentEvent("PAYMENT_PENDING", "task-1"))
+
+		assert.Error(t, err)
+		assert.Zero(t, tm.hits)
+	})
+
+	t.Run("missing task rejected", func(t *testing.T) {
+		tm := &recordingTaskManager{}
+		handle := NewPaymentEventHandler(tm)
+
+		err := handle(context.Background(), paymentEvent(paymentsv2.PaymentEventConfirmed, ""))
+
+		assert.Error(t, err)
+		assert.Zero(t, tm.hits)
+	})
+
+	t.Run("execute error propagated", func(t *testing.T) {
+		tm := &recordingTaskManager{err: errors.New("fsm rejected")}
+		handle := NewPaymentEventHandler(tm)
+
+		err := handle(context.Background(), paymentEvent(paymentsv2.PaymentEventConfirmed, "task-1"))
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,29 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/task/sla"
+)
+
+// slaTaskActions applies SLA escalations through the task manager.
+type slaTaskActions struct {
+	tm TaskManager
+}
+
+// NewSLATaskActions returns the sla.TaskActions the SLA monitor escalates through. Actions run
+// under the task lock like any other, so an escalation racing an officer's review either
+// lands first or is rejected by the plugin FSM.
+func NewSLATaskActions(tm TaskManager) sla.TaskActions {
+	return &slaTaskActions{tm: tm}
+}
+
+func (a *slaTaskActions) ExecuteAction(ctx context.Context, taskID string, request plugin.ExecutionRequest) error {
+	_, err := a.tm.ExecuteTask(ctx, ExecuteTaskRequest{TaskID: taskID, Payload: &request})
+	return err
+}
+
+func (a *slaTaskActions) FailTask(ctx context.Context, taskID, reason string) error {
+	return a.tm.FailTask(ctx, taskID, reason)
+}
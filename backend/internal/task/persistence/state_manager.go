@@ -14,19 +14,22 @@ type Manager interface {
 }
 
 // LocalStateManager implements the Manager interface for task-specific local state
-// It persists state to the TaskStore's local_state column
+// It persists state to the TaskStore's local_state column, expecting the task to be at
+// version; a write based on stale state fails with ErrVersionConflict.
 type LocalStateManager struct {
 	taskStore TaskStoreInterface
 	taskID    string
 	cache     map[string]any // In-memory cache for performance
+	version   *Version
 }
 
 // NewLocalStateManager creates a new LocalStateManager for a specific task
-func NewLocalStateManager(taskStore TaskStoreInterface, taskID string) (*LocalStateManager, error) {
+func NewLocalStateManager(taskStore TaskStoreInterface, taskID string, version *Version) (*LocalStateManager, error) {
 	manager := &LocalStateManager{
 		taskStore: taskStore,
 		taskID:    taskID,
 		cache:     make(map[string]any),
+		version:   version,
 	}
 
 	// Load existing state from database
@@ -37,7 +40,7 @@ func NewLocalStateManager(taskStore TaskStoreInterface, taskID string) (*LocalSt
 	return manager, nil
 }
 
-func NewLocalStateManagerWithCache(taskStore TaskStoreInterface, taskID string, cache json.RawMessage) (*LocalStateManager, error) {
+func NewLocalStateManagerWithCache(taskStore TaskStoreInterface, taskID string, cache json.RawMessage, version *Version) (*LocalStateManager, error) {
 	cacheMap := make(map[string]any)
 
 	if len(cache) > 0 && string(cache) != "null" {
@@ -50,6 +53,7 @@ func NewLocalStateManagerWithCache(taskStore TaskStoreInterface, taskID string,
 		taskStore: taskStore,
 		taskID:    taskID,
 		cache:     cacheMap,
+		version:   version,
 	}, nil
 }
 
@@ -62,13 +66,24 @@ func (m *LocalStateManager) GetState(key string) (any, error) {
 	return value, nil
 }
 
-// SetState sets a value in local state and persists to database
+// SetState sets a value in local state and persists to database. If the write fails the
+// previous value is restored, so the cache never holds state the database does not.
 func (m *LocalStateManager) SetState(key string, value any) error {
+	previous, existed := m.cache[key]
+
 	// Update cache
 	m.cache[key] = value
 
 	// Persist to database (write-through)
-	return m.persistToDB()
+	if err := m.persistToDB(); err != nil {
+		if existed {
+			m.cache[key] = previous
+		} else {
+			delete(m.cache, key)
+		}
+		return err
+	}
+	return nil
 }
 
 // loadFromDB loads the local state from the database into cache
@@ -105,7 +120,10 @@ func (m *LocalStateManager) persistToDB() error {
 	}
 
 	// Write to database
-	if err := m.taskStore.UpdateLocalState(m.taskID, localStateJSON); err != nil {
+	err = m.version.Update(func(expected int64) (int64, error) {
+		return m.taskStore.UpdateStateIfVersion(m.taskID, expected, StateUpdate{LocalState: localStateJSON})
+	})
+	if err != nil {
 		return fmt.Errorf("failed to update local state in database: %w", err)
 	}
 
@@ -1,6 +1,7 @@
 package persistence
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -21,8 +22,11 @@ type TaskInfo struct {
 	Config                 json.RawMessage `gorm:"type:jsonb;column:config;serializer:json" json:"config"`
 	LocalState             json.RawMessage `gorm:"type:jsonb;column:local_state;serializer:json" json:"localState"`
 	GlobalContext          json.RawMessage `gorm:"type:jsonb;column:global_context;serializer:json" json:"globalContext"`
+	SLA                    json.RawMessage `gorm:"type:jsonb;column:sla;serializer:json" json:"sla,omitempty"` // SLA policy copied from the node template; see package sla
 	CreatedAt              time.Time       `gorm:"type:timestamptz;column:created_at;not null" json:"createdAt"`
 	UpdatedAt              time.Time       `gorm:"type:timestamptz;column:updated_at;not null" json:"updatedAt"`
+	// Version is bumped by every state update; see Version.
+	Version int64 `gorm:"column:version;not null;default:1" json:"version"`
 }
 
 // TableName returns the table name for TaskInfo
@@ -33,6 +37,9 @@ func (TaskInfo) TableName() string {
 // TaskStore handles database operations for task infos
 type TaskStore struct {
 	db *gorm.DB
+	// lockSlots bounds the connections held by task locks, so holders always leave
+	// connections for the work they do under the lock; nil if the pool is unbounded.
+	lockSlots chan struct{}
 }
 
 type TaskStoreInterface interface {
@@ -47,6 +54,34 @@ type TaskStoreInterface interface {
 	GetLocalState(string) (json.RawMessage, error)
 	UpdatePluginState(string, string) error
 	GetPluginState(string) (string, error)
+	ListFormTasks(formID string) ([]FormTask, error)
+	UpdateStateIfVersion(id string, version int64, update StateUpdate) (int64, error)
+	GetVersion(string) (int64, error)
+	LockTask(ctx context.Context, id string) (unlock func(), err error)
+}
+
+// StateUpdate is the part of a task's state written by UpdateStateIfVersion. Nil fields
+// are left unchanged.
+type StateUpdate struct {
+	State       *plugin.State
+	PluginState *string
+	LocalState  json.RawMessage
+}
+
+// Task locks are advisory locks keyed by (taskLockClass, hashtext(task ID)), a key space
+// separate from the single bigint keys used elsewhere.
+const (
+	taskLockClass   = 0x4e535754 // "NSWT"
+	taskLockTimeout = "30s"
+)
+
+// FormTask is a live SIMPLE_FORM task together with the form version it is pinned to.
+type FormTask struct {
+	TaskID      string       `gorm:"column:id" json:"taskId"`
+	WorkflowID  string       `gorm:"column:workflow_id" json:"workflowId"`
+	State       plugin.State `gorm:"column:state" json:"state"`
+	PluginState string       `gorm:"column:plugin_state" json:"pluginState"`
+	FormVersion string       `gorm:"column:form_version" json:"formVersion"`
 }
 
 // NewTaskStore creates a new TaskStore with the provided database connection
@@ -55,7 +90,13 @@ func NewTaskStore(db *gorm.DB) (*TaskStore, error) {
 		return nil, fmt.Errorf("database connection cannot be nil")
 	}
 
-	return &TaskStore{db: db}, nil
+	store := &TaskStore{db: db}
+	if sqlDB, err := db.DB(); err == nil {
+		if maxOpen := sqlDB.Stats().MaxOpenConnections; maxOpen > 0 {
+			store.lockSlots = make(chan struct{}, max(maxOpen/2, 1))
+		}
+	}
+	return store, nil
 }
 
 // Create inserts a new task execution record
@@ -74,7 +115,10 @@ func (s *TaskStore) GetByID(id string) (*TaskInfo, error) {
 
 // UpdateStatus updates the status of a task execution
 func (s *TaskStore) UpdateStatus(id string, status *plugin.State) error {
-	return s.db.Model(&TaskInfo{}).Where("id = ?", id).Update("state", &status).Error
+	return s.db.Model(&TaskInfo{}).Where("id = ?", id).Updates(map[string]any{
+		"state":   &status,
+		"version": gorm.Expr("version + 1"),
+	}).Error
 }
 
 // Update updates a task execution record
@@ -107,7 +151,10 @@ func (s *TaskStore) GetByStatus(status plugin.State) ([]TaskInfo, error) {
 
 // UpdateLocalState updates the local state of a task execution
 func (s *TaskStore) UpdateLocalState(id string, localState json.RawMessage) error {
-	return s.db.Model(&TaskInfo{}).Where("id = ?", id).Update("local_state", localState).Error
+	return s.db.Model(&TaskInfo{}).Where("id = ?", id).Updates(map[string]any{
+		"local_state": localState,
+		"version":     gorm.Expr("version + 1"),
+	}).Error
 }
 
 // GetLocalState retrieves the local state of a task execution
@@ -121,7 +168,10 @@ func (s *TaskStore) GetLocalState(id string) (json.RawMessage, error) {
 
 // UpdatePluginState updates the plugin state of a task execution
 func (s *TaskStore) UpdatePluginState(id string, pluginState string) error {
-	return s.db.Model(&TaskInfo{}).Where("id = ?", id).Update("plugin_state", pluginState).Error
+	return s.db.Model(&TaskInfo{}).Where("id = ?", id).Updates(map[string]any{
+		"plugin_state": pluginState,
+		"version":      gorm.Expr("version + 1"),
+	}).Error
 }
 
 // GetPluginState retrieves the plugin state of a task execution
@@ -133,6 +183,95 @@ func (s *TaskStore) GetPluginState(id string) (string, error) {
 	return taskInfo.PluginState, nil
 }
 
+// UpdateStateIfVersion applies update if the task is still at version and returns the
+// version it moved to. It returns ErrVersionConflict if the task has been updated since, or
+// gorm.ErrRecordNotFound if it does not exist.
+func (s *TaskStore) UpdateStateIfVersion(id string, version int64, update StateUpdate) (int64, error) {
+	columns := map[string]any{"version": version + 1}
+	if update.State != nil {
+		columns["state"] = *update.State
+	}
+	if update.PluginState != nil {
+		columns["plugin_state"] = *update.PluginState
+	}
+	if update.LocalState != nil {
+		columns["local_state"] = update.LocalState
+	}
+
+	result := s.db.Model(&TaskInfo{}).Where("id = ? AND version = ?", id, version).Updates(columns)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.GetVersion(id); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("task %s is no longer at version %d: %w", id, version, ErrVersionConflict)
+	}
+	return version + 1, nil
+}
+
+// GetVersion retrieves the version of a task execution
+func (s *TaskStore) GetVersion(id string) (int64, error) {
+	var taskInfo TaskInfo
+	if err := s.db.Select("version").First(&taskInfo, "id = ?", id).Error; err != nil {
+		return 0, err
+	}
+	return taskInfo.Version, nil
+}
+
+// LockTask takes a lock on the task, serializing its holders across replicas until unlock
+// is called. The lock is a transaction-scoped advisory lock, so it is also released if the
+// holder's connection is lost. Waiting for it fails after taskLockTimeout.
+func (s *TaskStore) LockTask(ctx context.Context, id string) (func(), error) {
+	release := func() {}
+	if s.lockSlots != nil {
+		select {
+		case s.lockSlots <- struct{}{}:
+			release = func() { <-s.lockSlots }
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to lock task %s: %w", id, ctx.Err())
+		}
+	}
+
+	// The lock is held by the transaction, which must outlive a cancelled request so that
+	// the work done under the lock is not left unserialized.
+	tx := s.db.WithContext(context.WithoutCancel(ctx)).Begin()
+	if tx.Error != nil {
+		release()
+		return nil, fmt.Errorf("failed to begin task lock transaction: %w", tx.Error)
+	}
+	if err := tx.Exec("SET LOCAL lock_timeout = '" + taskLockTimeout + "'").Error; err != nil {
+		tx.Rollback()
+		release()
+		return nil, fmt.Errorf("failed to set task lock timeout: %w", err)
+	}
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", taskLockClass, id).Error; err != nil {
+		tx.Rollback()
+		release()
+		return nil, fmt.Errorf("failed to lock task %s: %w", id, err)
+	}
+	return func() {
+		tx.Rollback()
+		release()
+	}, nil
+}
+
+// ListFormTasks retrieves the SIMPLE_FORM tasks that have not finished and render formID.
+// FormVersion is empty for tasks that have not loaded the form yet.
+func (s *TaskStore) ListFormTasks(formID string) ([]FormTask, error) {
+	var tasks []FormTask
+	if err := s.db.Model(&TaskInfo{}).
+		Select("id, workflow_id, state, plugin_state, COALESCE(local_state->>?, '') AS form_version", plugin.FormVersionStoreKey).
+		Where("type = ? AND config->>'formId' = ?", plugin.TaskTypeSimpleForm, formID).
+		Where("state NOT IN ?", []plugin.State{plugin.Completed, plugin.Failed}).
+		Order("created_at").
+		Scan(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
 // Close closes the database connection
 func (s *TaskStore) Close() error {
 	sqlDB, err := s.db.DB()
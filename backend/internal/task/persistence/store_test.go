@@ -0,0 +1,137 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+func setupTestStore(t *testing.T) (*TaskStore, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	store, err := NewTaskStore(gormDB)
+	if err != nil {
+		t.Fatalf("failed to create task store: %v", err)
+	}
+	return store, mock
+}
+
+func TestTaskStore_UpdateStateIfVersion(t *testing.T) {
+	t.Run("Applies Update At Expected Version", func(t *testing.T) {
+		store, mock := setupTestStore(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "task_infos" SET "plugin_state"=\$1,"state"=\$2,"version"=\$3,"updated_at"=\$4 WHERE id = \$5 AND version = \$6`).
+			WithArgs("SUBMITTED", plugin.Completed, int64(4), sqlmock.AnyArg(), "task-1", int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		state, pluginState := plugin.Completed, "SUBMITTED"
+		version, err := store.UpdateStateIfVersion("task-1", 3, StateUpdate{State: &state, PluginState: &pluginState})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if version != 4 {
+			t.Fatalf("version = %d, want 4", version)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Conflict When Version Moved", func(t *testing.T) {
+		store, mock := setupTestStore(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "task_infos" SET "local_state"=\$1,"version"=\$2,"updated_at"=\$3 WHERE id = \$4 AND version = \$5`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`SELECT "version" FROM "task_infos" WHERE id = \$1`).
+			WithArgs("task-1", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(5))
+
+		_, err := store.UpdateStateIfVersion("task-1", 3, StateUpdate{LocalState: json.RawMessage(`{}`)})
+		if !errors.Is(err, ErrVersionConflict) {
+			t.Fatalf("error = %v, want ErrVersionConflict", err)
+		}
+	})
+
+	t.Run("Missing Task", func(t *testing.T) {
+		store, mock := setupTestStore(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "task_infos"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`SELECT "version" FROM "task_infos"`).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+		_, err := store.UpdateStateIfVersion("task-1", 3, StateUpdate{LocalState: json.RawMessage(`{}`)})
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("error = %v, want gorm.ErrRecordNotFound", err)
+		}
+	})
+}
+
+func TestTaskStore_LockTask(t *testing.T) {
+	store, mock := setupTestStore(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`SET LOCAL lock_timeout = '30s'`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1, hashtext\(\$2\)\)`).
+		WithArgs(taskLockClass, "task-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	unlock, err := store.LockTask(context.Background(), "task-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	unlock()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// conflictingStore fails every compare-and-swap update with ErrVersionConflict.
+type conflictingStore struct {
+	TaskStoreInterface
+}
+
+func (conflictingStore) UpdateStateIfVersion(string, int64, StateUpdate) (int64, error) {
+	return 0, ErrVersionConflict
+}
+
+func TestLocalStateManager_SetStateRestoresCacheOnConflict(t *testing.T) {
+	manager, err := NewLocalStateManagerWithCache(conflictingStore{}, "task-1", json.RawMessage(`{"trader:form":"v1"}`), NewVersion(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := manager.SetState("trader:form", "v2"); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("error = %v, want ErrVersionConflict", err)
+	}
+	if err := manager.SetState("submissionResponse", "ok"); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("error = %v, want ErrVersionConflict", err)
+	}
+
+	if value, _ := manager.GetState("trader:form"); value != "v1" {
+		t.Fatalf("trader:form = %v, want the value before the failed write", value)
+	}
+	if value, _ := manager.GetState("submissionResponse"); value != nil {
+		t.Fatalf("submissionResponse = %v, want it removed after the failed write", value)
+	}
+	if version := manager.version.Load(); version != 2 {
+		t.Fatalf("version = %d, want 2", version)
+	}
+}
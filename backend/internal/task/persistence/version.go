@@ -0,0 +1,45 @@
+package persistence
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrVersionConflict is returned when a compare-and-swap update finds that the task was
+// modified since its version was read.
+var ErrVersionConflict = errors.New("task was modified concurrently")
+
+// Version is the version of a task_infos row that an in-memory task last read or wrote.
+// Every write bumps the row's version; a compare-and-swap write succeeds only if the row
+// still has the version the writer expects, so a write based on stale state is rejected
+// instead of overwriting a newer one. A task's container and its LocalStateManager share
+// one Version so each write expects the version the previous one produced.
+type Version struct {
+	mu    sync.Mutex
+	value int64
+}
+
+// NewVersion returns a Version holding the version a task row was read at.
+func NewVersion(value int64) *Version {
+	return &Version{value: value}
+}
+
+// Load returns the version last read or written.
+func (v *Version) Load() int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.value
+}
+
+// Update runs write with the expected version and keeps the version it returns. Writes
+// through the same Version are serialized.
+func (v *Version) Update(write func(expected int64) (int64, error)) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	next, err := write(v.value)
+	if err != nil {
+		return err
+	}
+	v.value = next
+	return nil
+}
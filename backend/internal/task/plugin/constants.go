@@ -17,3 +17,7 @@ const (
 	Completed   State = "COMPLETED"
 	Failed      State = "FAILED"
 )
+
+// PluginStateSLABreached is the plugin state of a task the platform failed because it breached
+// its SLA. No plugin has transitions out of it.
+const PluginStateSLABreached = "SLA_BREACHED"
@@ -8,7 +8,7 @@ import (
 
 	"github.com/OpenNSW/nsw/internal/config"
 	"github.com/OpenNSW/nsw/internal/form"
-	"github.com/OpenNSW/nsw/internal/payments"
+	"github.com/OpenNSW/nsw/internal/paymentsv2"
 	"github.com/OpenNSW/nsw/pkg/remote"
 	"gorm.io/gorm"
 )
@@ -28,12 +28,12 @@ type TaskFactory interface {
 type taskFactory struct {
 	config         *config.Config
 	formService    form.FormService
-	paymentService payments.PaymentService
+	paymentService paymentsv2.PaymentService
 	remoteManager  *remote.Manager
 }
 
 // NewTaskFactory creates a new TaskFactory instance and initializes the remote services manager.
-func NewTaskFactory(cfg *config.Config, db *gorm.DB, paymentService payments.PaymentService) TaskFactory {
+func NewTaskFactory(cfg *config.Config, db *gorm.DB, paymentService paymentsv2.PaymentService) TaskFactory {
 	rm := remote.NewManager()
 	if err := rm.LoadServices(cfg.Server.ServicesConfigPath); err != nil {
 		slog.Warn("factory: failed to load external services configuration",
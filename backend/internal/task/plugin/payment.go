@@ -3,11 +3,13 @@ package plugin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"log/slog"
 	"time"
 
-	"github.com/OpenNSW/nsw/internal/payments"
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/paymentsv2"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
@@ -18,6 +20,7 @@ const (
 	PaymentActionInitiate = "INITIATE_PAYMENT"
 	PaymentActionSuccess  = "PAYMENT_SUCCESS"
 	PaymentActionFailed   = "PAYMENT_FAILED"
+	PaymentActionRefunded = "PAYMENT_REFUNDED"
 )
 
 // paymentFSMTimeout is an internal FSM action triggered by the lazy TTL+Threshold
@@ -43,6 +46,9 @@ const (
 const (
 	paymentStoreSession      = "payment:session"
 	paymentStoreTransactions = "payment:transactions"
+	paymentStoreRefunds      = "payment:refunds"
+	paymentStoreReceipt      = "payment:receipt"
+	paymentStoreAttempts     = "payment:attempts"
 )
 
 // ── Config & Models ───────────────────────────────────────────────────────────
@@ -59,11 +65,13 @@ type BreakdownType string
 const (
 	TypeFixed      BreakdownType = "FIXED"
 	TypePercentage BreakdownType = "PERCENTAGE"
+	TypeTiered     BreakdownType = "TIERED"
 )
 
 type ApplyOn string
 
 // BreakdownItem represents a single line item in the task configuration.
+// Tiers, clamps, conditions and rounding are described in payment_fees.go.
 type BreakdownItem struct {
 	Description string            `json:"description"`
 	Category    BreakdownCategory `json:"category"`
@@ -71,6 +79,13 @@ type BreakdownItem struct {
 	Quantity    string            `json:"quantity,omitempty"`  // Placeholder or fixed value
 	UnitPrice   string            `json:"unitPrice,omitempty"` // Placeholder or fixed value
 	Value       string            `json:"value,omitempty"`     // Percentage value (placeholder or fixed)
+	Basis       string            `json:"basis,omitempty"`     // TIERED: quantity or value the tiers apply to (placeholder or fixed)
+	TierMode    TierMode          `json:"tierMode,omitempty"`  // TIERED: VOLUME (default) or GRADUATED
+	Tiers       []FeeTier         `json:"tiers,omitempty"`     // TIERED: ascending by upTo
+	Min         string            `json:"min,omitempty"`       // Optional: lower bound of the amount (placeholder or fixed)
+	Max         string            `json:"max,omitempty"`       // Optional: upper bound of the amount (placeholder or fixed)
+	When        []FeeCondition    `json:"when,omitempty"`      // Optional: item applies only if every condition holds
+	Rounding    *FeeRounding      `json:"rounding,omitempty"`  // Optional: rounding of this item's amount
 }
 
 // ResolvedBreakdownItem is the calculated result sent to the UI.
@@ -85,9 +100,10 @@ type ResolvedBreakdownItem struct {
 
 // PaymentConfig holds the task-level configuration supplied at workflow definition time.
 type PaymentConfig struct {
-	Currency    string          `json:"currency"` // Currency of the payment (e.g. "LKR")
-	TTL         int             `json:"ttl"`      // Time-to-live for a payment session in seconds
-	OrgID       string          `json:"orgId"`    // Organization ID
+	Currency    string          `json:"currency"`          // Currency of the payment (e.g. "LKR")
+	TTL         int             `json:"ttl"`               // Time-to-live for a payment session in seconds
+	OrgID       string          `json:"orgId"`             // Organization ID
+	OrgName     string          `json:"orgName,omitempty"` // Agency name printed on the receipt
 	ServiceType string          `json:"serviceType,omitempty"`
 	Breakdown   []BreakdownItem `json:"breakdown"`
 }
@@ -101,6 +117,24 @@ type PaymentSession struct {
 	SelectedMethodID string     `json:"selectedMethodId,omitempty"`
 	GeneratedAt      time.Time  `json:"generatedAt"`
 	InitiatedAt      *time.Time `json:"initiatedAt,omitempty"` // set when INITIATE_PAYMENT is received
+
+	// Conversion is the exchange-rate snapshot taken when INITIATE_PAYMENT is received.
+	// It is nil for payments in the base currency.
+	Conversion *paymentsv2.CurrencyConversion `json:"conversion,omitempty"`
+
+	// Breakdown and TotalAmount are the fees charged, as calculated when INITIATE_PAYMENT
+	// is received. The receipt itemises them; later changes to the global context do not.
+	Breakdown   []ResolvedBreakdownItem `json:"breakdown,omitempty"`
+	TotalAmount *decimal.Decimal        `json:"totalAmount,omitempty"`
+}
+
+// PaymentAttempt is the fee snapshot of an initiated payment, kept per reference number
+// so that a late confirmation of an earlier attempt is receipted with what it charged.
+type PaymentAttempt struct {
+	ReferenceNumber string                         `json:"referenceNumber"`
+	Conversion      *paymentsv2.CurrencyConversion `json:"conversion,omitempty"`
+	Breakdown       []ResolvedBreakdownItem        `json:"breakdown,omitempty"`
+	TotalAmount     decimal.Decimal                `json:"totalAmount"`
 }
 
 // PaymentTransaction is an append-only history entry for completed (failed/timed-out)
@@ -110,10 +144,32 @@ type PaymentTransaction struct {
 	ReferenceNumber string    `json:"referenceNumber"`
 	InitiatedAt     time.Time `json:"initiatedAt"`
 	ResolvedAt      time.Time `json:"resolvedAt"`
-	Status          string    `json:"status"` // "FAILED" or "TIMEOUT"
+	Status          string    `json:"status"` // "FAILED", "EXPIRED" or "TIMEOUT"
 	Round           int       `json:"round"`
 }
 
+// PaymentRefund is an append-only history entry for a refund of the completed payment,
+// as reported by the Payment Service.
+type PaymentRefund struct {
+	RefundID        string          `json:"refundId"`
+	ReferenceNumber string          `json:"referenceNumber"`
+	Amount          decimal.Decimal `json:"amount"`
+	Currency        string          `json:"currency"`
+	PaymentStatus   string          `json:"paymentStatus"` // "PARTIALLY_REFUNDED" or "REFUNDED"
+	RecordedAt      time.Time       `json:"recordedAt"`
+}
+
+// PaymentReceipt identifies the official receipt issued for the completed payment. The
+// keys can be fetched through the uploads API; the Payment Service also serves the files
+// at GET /api/v1/payments/transactions/{referenceNumber}/receipt.
+type PaymentReceipt struct {
+	ReceiptNumber   string    `json:"receiptNumber"`
+	ReferenceNumber string    `json:"referenceNumber"`
+	PDFKey          string    `json:"pdfKey"`
+	JSONKey         string    `json:"jsonKey"`
+	IssuedAt        time.Time `json:"issuedAt"`
+}
+
 // PaymentRenderContent is the payload returned inside GetRenderInfoResponse.Content
 // when the plugin is in IDLE or IN_PROGRESS.
 type PaymentRenderContent struct {
@@ -125,6 +181,13 @@ type PaymentRenderContent struct {
 	OrgID            string                  `json:"orgId,omitempty"`
 	Service          any                     `json:"service,omitempty"`
 	SelectedMethodID string                  `json:"selectedMethodId,omitempty"`
+	Refunds          []PaymentRefund         `json:"refunds,omitempty"` // COMPLETED only
+	RefundedAmount   *decimal.Decimal        `json:"refundedAmount,omitempty"`
+	Receipt          *PaymentReceipt         `json:"receipt,omitempty"` // COMPLETED only
+
+	// Conversion shows the base-currency equivalent of a foreign-currency fee: the
+	// snapshot of the initiated payment, or an indicative conversion before that.
+	Conversion *paymentsv2.CurrencyConversion `json:"conversion,omitempty"`
 }
 
 // ── FSM ───────────────────────────────────────────────────────────────────────
@@ -138,16 +201,24 @@ type PaymentRenderContent struct {
 //	IDLE            ──INITIATE_PAYMENT─────► IN_PROGRESS   [IN_PROGRESS]
 //	IN_PROGRESS     ──INITIATE_PAYMENT─────► IN_PROGRESS   [IN_PROGRESS]
 //	IN_PROGRESS     ──PAYMENT_SUCCESS──────► COMPLETED     [COMPLETED]
+//	IDLE            ──PAYMENT_SUCCESS──────► COMPLETED     [COMPLETED]     (late confirmation)
 //	IN_PROGRESS     ──PAYMENT_FAILED───────► IDLE          [IN_PROGRESS]
 //	IN_PROGRESS     ──PAYMENT_TIMEOUT──────► IDLE          [IN_PROGRESS]
+//	COMPLETED       ──PAYMENT_REFUNDED─────► COMPLETED     [no task state change]
+//
+// PAYMENT_SUCCESS from IDLE covers a payment the gateway confirmed after the attempt had
+// already failed or timed out, e.g. when reconciliation recovers a lost webhook.
+// PAYMENT_REFUNDED only records the refund; a refunded task stays COMPLETED.
 func NewPaymentFSM() *PluginFSM {
 	return NewPluginFSM(map[TransitionKey]TransitionOutcome{
 		{"", FSMActionStart}:                               {string(paymentIdle), ""},
 		{string(paymentIdle), PaymentActionInitiate}:       {string(paymentInProgress), InProgress},
 		{string(paymentInProgress), PaymentActionInitiate}: {string(paymentInProgress), InProgress}, // Support Switching
 		{string(paymentInProgress), PaymentActionSuccess}:  {string(paymentCompleted), Completed},
+		{string(paymentIdle), PaymentActionSuccess}:        {string(paymentCompleted), Completed}, // Late confirmation
 		{string(paymentInProgress), PaymentActionFailed}:   {string(paymentIdle), Initialized},
 		{string(paymentInProgress), paymentFSMTimeout}:     {string(paymentIdle), Initialized},
+		{string(paymentCompleted), PaymentActionRefunded}:  {string(paymentCompleted), ""},
 	})
 }
 
@@ -157,15 +228,20 @@ func NewPaymentFSM() *PluginFSM {
 type PaymentTask struct {
 	api            API
 	config         PaymentConfig
-	paymentService payments.PaymentService
+	paymentService paymentsv2.PaymentService
 }
 
 // NewPaymentTask creates a PaymentTask from the raw JSON configuration.
-func NewPaymentTask(raw json.RawMessage, paymentService payments.PaymentService) (*PaymentTask, error) {
+func NewPaymentTask(raw json.RawMessage, paymentService paymentsv2.PaymentService) (*PaymentTask, error) {
 	var cfg PaymentConfig
 	if err := json.Unmarshal(raw, &cfg); err != nil {
 		return nil, fmt.Errorf("payment: invalid config: %w", err)
 	}
+	// Fee calculation skips unknown types and treats anything but a deduction as an
+	// addition, so a misspelt rule must fail here rather than change the amount charged.
+	if err := ValidatePaymentConfig(cfg); err != nil {
+		return nil, fmt.Errorf("payment: invalid config: %w", err)
+	}
 	return &PaymentTask{
 		config:         cfg,
 		paymentService: paymentService,
@@ -200,13 +276,33 @@ func (t *PaymentTask) Start(_ context.Context) (*ExecutionResponse, error) {
 func (t *PaymentTask) GetRenderInfo(ctx context.Context) (*ApiResponse, error) {
 	pluginState := t.api.GetPluginState()
 
-	resolvedBreakdown, totalAmount, err := t.calculateBreakdown(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("payment: failed to calculate breakdown: %w", err)
-	}
-
-	// Terminal state — nothing actionable to render.
+	// Terminal state — nothing actionable to render beyond the fees paid, any refunds and
+	// the receipt.
 	if pluginState == string(paymentCompleted) {
+		refunds, err := t.readRefunds(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("payment: failed to read refunds: %w", err)
+		}
+		session, err := t.readSession(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("payment: failed to read session: %w", err)
+		}
+		resolvedBreakdown, totalAmount, err := t.paidBreakdown(ctx, session)
+		if err != nil {
+			return nil, fmt.Errorf("payment: failed to calculate breakdown: %w", err)
+		}
+		receipt, err := t.readReceipt(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("payment: failed to read receipt: %w", err)
+		}
+		var refundedAmount *decimal.Decimal
+		if len(refunds) > 0 {
+			total := decimal.Zero
+			for _, r := range refunds {
+				total = total.Add(r.Amount)
+			}
+			refundedAmount = &total
+		}
 		return &ApiResponse{
 			Success: true,
 			Data: GetRenderInfoResponse{
@@ -214,16 +310,25 @@ func (t *PaymentTask) GetRenderInfo(ctx context.Context) (*ApiResponse, error) {
 				PluginState: pluginState,
 				State:       t.api.GetTaskState(),
 				Content: PaymentRenderContent{
-					TotalAmount: totalAmount,
-					Currency:    t.config.Currency,
-					Breakdown:   resolvedBreakdown,
-					OrgID:       t.config.OrgID,
-					Service:     t.config.ServiceType,
+					TotalAmount:    totalAmount,
+					Currency:       t.config.Currency,
+					Breakdown:      resolvedBreakdown,
+					OrgID:          t.config.OrgID,
+					Service:        t.config.ServiceType,
+					Refunds:        refunds,
+					RefundedAmount: refundedAmount,
+					Receipt:        receipt,
+					Conversion:     session.Conversion,
 				},
 			},
 		}, nil
 	}
 
+	resolvedBreakdown, totalAmount, err := t.calculateBreakdown(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("payment: failed to calculate breakdown: %w", err)
+	}
+
 	session, err := t.readSession(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("payment: failed to read session: %w", err)
@@ -254,6 +359,15 @@ func (t *PaymentTask) GetRenderInfo(ctx context.Context) (*ApiResponse, error) {
 		}
 	}
 
+	// An initiated payment shows the rate it was recorded at; otherwise the current rate.
+	conversion := session.Conversion
+	if conversion == nil {
+		conversion, err = t.paymentService.ConvertAmount(ctx, totalAmount, t.config.Currency)
+		if err != nil {
+			slog.WarnContext(ctx, "payment: base-currency equivalent unavailable", "taskID", t.api.GetTaskID(), "error", err)
+		}
+	}
+
 	gatewayURL := session.CheckoutURL
 	return &ApiResponse{
 		Success: true,
@@ -270,6 +384,7 @@ func (t *PaymentTask) GetRenderInfo(ctx context.Context) (*ApiResponse, error) {
 				OrgID:            t.config.OrgID,
 				Service:          t.config.ServiceType,
 				SelectedMethodID: session.SelectedMethodID,
+				Conversion:       conversion,
 			},
 		},
 	}, nil
@@ -286,9 +401,11 @@ func (t *PaymentTask) Execute(ctx context.Context, request *ExecutionRequest) (*
 	case PaymentActionInitiate:
 		return t.initiateHandler(ctx, request.Content)
 	case PaymentActionSuccess:
-		return t.successHandler(ctx)
+		return t.successHandler(ctx, request.Content)
 	case PaymentActionFailed:
-		return t.failedHandler(ctx)
+		return t.failedHandler(ctx, request.Content)
+	case PaymentActionRefunded:
+		return t.refundedHandler(ctx, request.Content)
 	default:
 		return nil, fmt.Errorf("payment: unknown action %q", request.Action)
 	}
@@ -322,31 +439,27 @@ func (t *PaymentTask) initiateHandler(ctx context.Context, content any) (*Execut
 		}, fmt.Errorf("payment: session expired, cannot initiate payment")
 	}
 
-	// Extract methodId from content
-	methodID := "lankapay" // Default
+	// Extract methodId from content; an empty ID lets the registry pick its default method.
+	methodID := ""
 	if contentMap, ok := content.(map[string]any); ok {
 		if id, ok := contentMap["methodId"].(string); ok {
 			methodID = id
 		}
 	}
 
-	_, totalAmount, err := t.calculateBreakdown(ctx)
+	breakdown, totalAmount, err := t.calculateBreakdown(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("payment: failed to calculate total amount: %w", err)
 	}
 
-	// Create real checkout session via PaymentService
-	resp, err := t.paymentService.CreateCheckoutSession(ctx, payments.CreateCheckoutRequest{
-		Amount:          totalAmount,
-		Currency:        t.config.Currency,
-		ReferenceNumber: session.ReferenceNumber,
-		Metadata: map[string]string{
-			"task_id":      t.api.GetTaskID(),
-			"method_id":    methodID,
-			"org_id":       t.config.OrgID,
-			"service_type": t.config.ServiceType,
-		},
-		ExpiresAt: time.Now().Add(t.ttlDuration()),
+	// Create the checkout session with the selected provider. The Payment Service
+	// assigns the NSW reference number for the attempt.
+	resp, err := t.paymentService.CreateCheckoutSession(ctx, paymentsv2.CreateCheckoutRequest{
+		ProviderID: methodID,
+		Amount:     totalAmount,
+		Currency:   t.config.Currency,
+		Metadata:   t.checkoutMetadata(ctx),
+		ExpiresAt:  time.Now().Add(t.ttlDuration()),
 	})
 
 	if err != nil {
@@ -363,9 +476,19 @@ func (t *PaymentTask) initiateHandler(ctx context.Context, content any) (*Execut
 		}
 	}
 
+	if resp.ProviderID != "" {
+		methodID = resp.ProviderID
+	}
 	session.InitiatedAt = &now
 	session.CheckoutURL = resp.CheckoutURL
+	session.ReferenceNumber = resp.ReferenceNumber
 	session.SelectedMethodID = methodID
+	session.Conversion = resp.Conversion
+	session.Breakdown = breakdown
+	session.TotalAmount = &totalAmount
+	if err := t.recordAttempt(ctx, session); err != nil {
+		return nil, err
+	}
 	if err := t.api.WriteToLocalStore(paymentStoreSession, session); err != nil {
 		return nil, fmt.Errorf("payment: failed to persist initiated session: %w", err)
 	}
@@ -392,163 +515,63 @@ func (t *PaymentTask) initiateHandler(ctx context.Context, content any) (*Execut
 
 // ── Helpers ───────────────────────────────────────────────────────────────────
 
-func (t *PaymentTask) calculateBreakdown(ctx context.Context) ([]ResolvedBreakdownItem, decimal.Decimal, error) {
-	var resolved []ResolvedBreakdownItem
-	subtotal := decimal.Zero
-
-	var finalTotal decimal.Decimal
-	// Phase 1: Fixed Items
-	for _, item := range t.config.Breakdown {
-		if item.Type != TypeFixed {
-			continue
-		}
-
-		qty := t.resolveValue(item.Quantity, decimal.NewFromInt(1))
-		price := t.resolveValue(item.UnitPrice, decimal.Zero)
-		amount := qty.Mul(price)
-
-		if item.Category == CategoryAddition {
-			subtotal = subtotal.Add(amount)
-		} else {
-			subtotal = subtotal.Sub(amount)
-		}
-
-		resolved = append(resolved, ResolvedBreakdownItem{
-			Description: t.resolveString(item.Description),
-			Category:    item.Category,
-			Type:        item.Type,
-			Quantity:    qty,
-			UnitPrice:   price,
-			Amount:      amount.Round(2),
-		})
-	}
-
-	finalTotal = subtotal
-
-	// Phase 2: Percentage Items
-	for _, item := range t.config.Breakdown {
-		if item.Type != TypePercentage {
-			continue
-		}
-
-		percentage := t.resolveValue(item.Value, decimal.Zero)
-		amount := finalTotal.Mul(percentage).Div(decimal.NewFromInt(100))
-
-		if item.Category == CategoryAddition {
-			finalTotal = finalTotal.Add(amount)
-		} else {
-			finalTotal = finalTotal.Sub(amount)
-		}
-
-		resolved = append(resolved, ResolvedBreakdownItem{
-			Description: t.resolveString(item.Description),
-			Category:    item.Category,
-			Type:        item.Type,
-			Amount:      amount.Round(2),
-		})
+// calculateBreakdown resolves the configured breakdown against the task's global context.
+func (t *PaymentTask) calculateBreakdown(_ context.Context) ([]ResolvedBreakdownItem, decimal.Decimal, error) {
+	quote, err := calculateFees(t.config, t.api.ReadFromGlobalStore)
+	if err != nil {
+		return nil, decimal.Zero, err
 	}
-
-	return resolved, finalTotal.Round(2), nil
+	return quote.Breakdown, quote.TotalAmount, nil
 }
 
-func (t *PaymentTask) resolveValue(val string, fallback decimal.Decimal) decimal.Decimal {
-	if val == "" {
-		return fallback
+// paidBreakdown returns the fees snapshotted when the session's payment was initiated.
+// Sessions initiated before snapshots were recorded fall back to the current calculation.
+func (t *PaymentTask) paidBreakdown(ctx context.Context, session *PaymentSession) ([]ResolvedBreakdownItem, decimal.Decimal, error) {
+	if session.TotalAmount != nil {
+		return session.Breakdown, *session.TotalAmount, nil
 	}
+	return t.calculateBreakdown(ctx)
+}
 
-	// If placeholder {path:default}
-	if strings.HasPrefix(val, "{") && strings.HasSuffix(val, "}") {
-		inner := val[1 : len(val)-1]
-		parts := strings.Split(inner, ":")
-		path := parts[0]
-
-		if len(parts) > 1 {
-			if d, err := decimal.NewFromString(parts[1]); err == nil {
-				fallback = d
-			}
-		}
-
-		resolved := t.lookupGlobal(path)
-		if resolved == nil {
-			return fallback
-		}
+// successHandler processes PAYMENT_SUCCESS: issues the official receipt and transitions
+// to COMPLETED. A receipt that cannot be issued fails the action, so the confirmation is
+// redelivered rather than completing the task without one; issuing is idempotent per
+// reference number. A confirmation the Payment Service delivers again after the task
+// completed is acknowledged without effect.
+func (t *PaymentTask) successHandler(ctx context.Context, content any) (*ExecutionResponse, error) {
+	contentMap, _ := content.(map[string]any)
+	ref, _ := contentMap["referenceNumber"].(string)
 
-		switch v := resolved.(type) {
-		case float64:
-			return decimal.NewFromFloat(v)
-		case string:
-			if d, err := decimal.NewFromString(v); err == nil {
-				return d
-			}
-		case int:
-			return decimal.NewFromInt(int64(v))
-		case int64:
-			return decimal.NewFromInt(v)
+	if !t.api.CanTransition(PaymentActionSuccess) {
+		if ref != "" && t.api.GetPluginState() == string(paymentCompleted) {
+			return ignoredResponse("Payment confirmation already recorded"), nil
 		}
-		return fallback
+		return nil, fmt.Errorf("payment: action %q not permitted in state %q",
+			PaymentActionSuccess, t.api.GetPluginState())
 	}
 
-	// Literal value
-	if d, err := decimal.NewFromString(val); err == nil {
-		return d
+	session, err := t.readSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("payment: failed to read session: %w", err)
 	}
-	return fallback
-}
-
-func (t *PaymentTask) resolveString(val string) string {
-	// Simple regex-free placeholder replacement
-	for {
-		start := strings.Index(val, "{")
-		end := strings.Index(val, "}")
-		if start == -1 || end == -1 || end < start {
-			break
+	// A late confirmation refers to an attempt the session has already moved on from; the
+	// fees it charged are those snapshotted when that attempt was initiated.
+	if ref != "" && ref != session.ReferenceNumber {
+		amountPaid, _ := contentMap["amountPaid"].(string)
+		session, err = t.confirmedAttempt(ctx, session, ref, amountPaid)
+		if err != nil {
+			return nil, err
 		}
-
-		placeholder := val[start : end+1]
-		inner := val[start+1 : end]
-		parts := strings.Split(inner, ":")
-		path := parts[0]
-
-		resolved := t.lookupGlobal(path)
-		replacement := ""
-		if resolved != nil {
-			replacement = fmt.Sprintf("%v", resolved)
-		} else if len(parts) > 1 {
-			replacement = parts[1]
+		if err := t.api.WriteToLocalStore(paymentStoreSession, session); err != nil {
+			return nil, fmt.Errorf("payment: failed to persist confirmed session: %w", err)
 		}
-
-		val = strings.Replace(val, placeholder, replacement, 1)
-	}
-	return val
-}
-
-func (t *PaymentTask) lookupGlobal(path string) any {
-	keys := strings.Split(path, ".")
-	val, ok := t.api.ReadFromGlobalStore(keys[0])
-	if !ok {
-		return nil
 	}
 
-	current := val
-	for i := 1; i < len(keys); i++ {
-		if m, ok := current.(map[string]any); ok {
-			current, ok = m[keys[i]]
-			if !ok {
-				return nil
-			}
-		} else {
-			return nil
+	if session.ReferenceNumber != "" {
+		if err := t.issueReceipt(ctx, session); err != nil {
+			return nil, err
 		}
 	}
-	return current
-}
-
-// successHandler processes PAYMENT_SUCCESS: transitions to COMPLETED.
-func (t *PaymentTask) successHandler(_ context.Context) (*ExecutionResponse, error) {
-	if !t.api.CanTransition(PaymentActionSuccess) {
-		return nil, fmt.Errorf("payment: action %q not permitted in state %q",
-			PaymentActionSuccess, t.api.GetPluginState())
-	}
 
 	if err := t.api.Transition(PaymentActionSuccess); err != nil {
 		return nil, err
@@ -565,8 +588,17 @@ func (t *PaymentTask) successHandler(_ context.Context) (*ExecutionResponse, err
 
 // failedHandler processes PAYMENT_FAILED: records the failed transaction,
 // generates a new session, and transitions back to IDLE.
-func (t *PaymentTask) failedHandler(ctx context.Context) (*ExecutionResponse, error) {
+// A failure reported for a reference other than the active session's (e.g. an attempt
+// abandoned when the trader switched methods) is acknowledged without a transition, as is
+// a failure for an attempt the task has already resolved (e.g. by its own timeout check).
+func (t *PaymentTask) failedHandler(ctx context.Context, content any) (*ExecutionResponse, error) {
+	contentMap, _ := content.(map[string]any)
+	ref, _ := contentMap["referenceNumber"].(string)
+
 	if !t.api.CanTransition(PaymentActionFailed) {
+		if ref != "" {
+			return ignoredResponse("Payment failure ignored, attempt already resolved"), nil
+		}
 		return nil, fmt.Errorf("payment: action %q not permitted in state %q",
 			PaymentActionFailed, t.api.GetPluginState())
 	}
@@ -576,12 +608,22 @@ func (t *PaymentTask) failedHandler(ctx context.Context) (*ExecutionResponse, er
 		return nil, fmt.Errorf("payment: failed to read session: %w", err)
 	}
 
+	if ref != "" && session.ReferenceNumber != "" && ref != session.ReferenceNumber {
+		return ignoredResponse("Payment failure ignored for superseded reference"), nil
+	}
+
+	// EXPIRED is reported by reconciliation when the gateway never confirmed the attempt.
+	status := "FAILED"
+	if reported, _ := contentMap["status"].(string); reported == string(paymentsv2.PaymentStatusExpired) {
+		status = reported
+	}
+
 	// Record the failed transaction in history.
 	initiatedAt := time.Now()
 	if session.InitiatedAt != nil {
 		initiatedAt = *session.InitiatedAt
 	}
-	if err := t.recordTransaction(ctx, session.TransactionID, session.ReferenceNumber, initiatedAt, "FAILED"); err != nil {
+	if err := t.recordTransaction(ctx, session.TransactionID, session.ReferenceNumber, initiatedAt, status); err != nil {
 		return nil, fmt.Errorf("payment: failed to record failed transaction: %w", err)
 	}
 
@@ -604,15 +646,234 @@ func (t *PaymentTask) failedHandler(ctx context.Context) (*ExecutionResponse, er
 	}, nil
 }
 
+// ignoredResponse acknowledges a payment outcome that does not affect the active attempt.
+func ignoredResponse(message string) *ExecutionResponse {
+	return &ExecutionResponse{
+		Message: message,
+		ApiResponse: &ApiResponse{
+			Success: true,
+			Data:    map[string]any{"message": message + "."},
+		},
+	}
+}
+
+// refundedHandler processes PAYMENT_REFUNDED: appends the refund to the task's refund
+// history. A refund that is already recorded is acknowledged again without a new entry.
+func (t *PaymentTask) refundedHandler(ctx context.Context, content any) (*ExecutionResponse, error) {
+	if !t.api.CanTransition(PaymentActionRefunded) {
+		return nil, fmt.Errorf("payment: action %q not permitted in state %q",
+			PaymentActionRefunded, t.api.GetPluginState())
+	}
+
+	contentMap, _ := content.(map[string]any)
+	refundID, _ := contentMap["refundId"].(string)
+	if refundID == "" {
+		return nil, fmt.Errorf("payment: refundId is required")
+	}
+	rawAmount, _ := contentMap["refundedAmount"].(string)
+	amount, err := decimal.NewFromString(rawAmount)
+	if err != nil {
+		return nil, fmt.Errorf("payment: invalid refundedAmount %q: %w", rawAmount, err)
+	}
+
+	refunds, err := t.readRefunds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("payment: failed to read refunds: %w", err)
+	}
+	for _, r := range refunds {
+		if r.RefundID == refundID {
+			return refundedResponse(), nil
+		}
+	}
+
+	ref, _ := contentMap["referenceNumber"].(string)
+	currency, _ := contentMap["currency"].(string)
+	status, _ := contentMap["status"].(string)
+	refunds = append(refunds, PaymentRefund{
+		RefundID:        refundID,
+		ReferenceNumber: ref,
+		Amount:          amount,
+		Currency:        currency,
+		PaymentStatus:   status,
+		RecordedAt:      time.Now(),
+	})
+	if err := t.api.WriteToLocalStore(paymentStoreRefunds, refunds); err != nil {
+		return nil, fmt.Errorf("payment: failed to persist refunds: %w", err)
+	}
+
+	if err := t.api.Transition(PaymentActionRefunded); err != nil {
+		return nil, err
+	}
+	return refundedResponse(), nil
+}
+
+// refundedResponse acknowledges a PAYMENT_REFUNDED.
+func refundedResponse() *ExecutionResponse {
+	return &ExecutionResponse{
+		Message: "Payment refund recorded",
+		ApiResponse: &ApiResponse{
+			Success: true,
+			Data:    map[string]any{"message": "Payment refund recorded."},
+		},
+	}
+}
+
 // ── Helpers ───────────────────────────────────────────────────────────────────
 
+// checkoutMetadata links the checkout to the task and records the agency and the payer
+// for the receipt.
+func (t *PaymentTask) checkoutMetadata(ctx context.Context) map[string]string {
+	metadata := map[string]string{
+		"task_id":                      t.api.GetTaskID(),
+		paymentsv2.MetadataOrgID:       t.config.OrgID,
+		paymentsv2.MetadataServiceType: t.config.ServiceType,
+	}
+	if authCtx := auth.GetAuthContext(ctx); authCtx != nil && authCtx.User != nil {
+		metadata[paymentsv2.MetadataPayerID] = authCtx.User.ID
+		metadata[paymentsv2.MetadataPayerEmail] = authCtx.User.Email
+	}
+	return metadata
+}
+
+// issueReceipt asks the Payment Service for the receipt of the session's payment,
+// itemising the fees snapshotted at initiation, and records it in local store. With
+// receipts disabled, the task goes without one.
+func (t *PaymentTask) issueReceipt(ctx context.Context, session *PaymentSession) error {
+	breakdown, _, err := t.paidBreakdown(ctx, session)
+	if err != nil {
+		return fmt.Errorf("payment: failed to calculate breakdown for receipt: %w", err)
+	}
+	lines := make([]paymentsv2.ReceiptLine, 0, len(breakdown))
+	for _, item := range breakdown {
+		lines = append(lines, paymentsv2.ReceiptLine{
+			Description: item.Description,
+			Category:    string(item.Category),
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Amount:      item.Amount,
+		})
+	}
+
+	issued, err := t.paymentService.IssueReceipt(ctx, paymentsv2.IssueReceiptRequest{
+		ReferenceNumber: session.ReferenceNumber,
+		AgencyName:      t.agencyName(session),
+		Breakdown:       lines,
+	})
+	if err != nil {
+		if errors.Is(err, paymentsv2.ErrReceiptsDisabled) {
+			return nil
+		}
+		return fmt.Errorf("payment: failed to issue receipt for %s: %w", session.ReferenceNumber, err)
+	}
+
+	receipt := &PaymentReceipt{
+		ReceiptNumber:   issued.ReceiptNumber,
+		ReferenceNumber: issued.ReferenceNumber,
+		PDFKey:          issued.PDFKey,
+		JSONKey:         issued.JSONKey,
+		IssuedAt:        issued.IssuedAt,
+	}
+	if err := t.api.WriteToLocalStore(paymentStoreReceipt, receipt); err != nil {
+		return fmt.Errorf("payment: failed to persist receipt: %w", err)
+	}
+	return nil
+}
+
 // newSession creates a fresh PaymentSession with a new UUID and the current timestamp.
+// The reference number is assigned by the Payment Service when the payment is initiated.
 func (t *PaymentTask) newSession() PaymentSession {
 	return PaymentSession{
-		TransactionID:   uuid.NewString(),
-		ReferenceNumber: fmt.Sprintf("NSW-PAY-%s", uuid.NewString()[:8]),
-		GeneratedAt:     time.Now(),
+		TransactionID: uuid.NewString(),
+		OrgName:       t.config.OrgName,
+		GeneratedAt:   time.Now(),
+	}
+}
+
+// agencyName returns the agency printed on the session's receipt. Sessions created before
+// the config named the agency take the name from the config.
+func (t *PaymentTask) agencyName(session *PaymentSession) string {
+	if session.OrgName != "" {
+		return session.OrgName
 	}
+	return t.config.OrgName
+}
+
+// confirmedAttempt returns the session as confirmed for the earlier attempt ref, with the
+// fee snapshot recorded when that attempt was initiated. Without a snapshot the receipt
+// is issued for the amount the Payment Service confirmed, without an itemised breakdown,
+// rather than with the fees of another attempt.
+func (t *PaymentTask) confirmedAttempt(ctx context.Context, session *PaymentSession, ref, amountPaid string) (*PaymentSession, error) {
+	attempts, err := t.readAttempts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("payment: failed to read payment attempts: %w", err)
+	}
+	confirmed := *session
+	confirmed.ReferenceNumber = ref
+	confirmed.Conversion = nil
+	confirmed.Breakdown = nil
+	confirmed.TotalAmount = nil
+	for _, attempt := range attempts {
+		if attempt.ReferenceNumber == ref {
+			total := attempt.TotalAmount
+			confirmed.Conversion = attempt.Conversion
+			confirmed.Breakdown = attempt.Breakdown
+			confirmed.TotalAmount = &total
+			return &confirmed, nil
+		}
+	}
+	slog.WarnContext(ctx, "payment: no fee snapshot for confirmed attempt; receipt is not itemised",
+		"taskId", t.api.GetTaskID(), "referenceNumber", ref)
+	total, err := decimal.NewFromString(amountPaid)
+	if err != nil {
+		return nil, fmt.Errorf("payment: confirmation of %s carries no valid amount: %w", ref, err)
+	}
+	confirmed.TotalAmount = &total
+	return &confirmed, nil
+}
+
+// readAttempts reads and deserialises the fee snapshots of initiated payments from local store.
+func (t *PaymentTask) readAttempts(_ context.Context) ([]PaymentAttempt, error) {
+	raw, err := t.api.ReadFromLocalStore(paymentStoreAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	// Fast path.
+	if a, ok := raw.([]PaymentAttempt); ok {
+		return a, nil
+	}
+
+	// Slow path: JSON round-trip.
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("payment: failed to marshal stored attempts: %w", err)
+	}
+	var a []PaymentAttempt
+	if err := json.Unmarshal(b, &a); err != nil {
+		return nil, fmt.Errorf("payment: failed to unmarshal stored attempts: %w", err)
+	}
+	return a, nil
+}
+
+// recordAttempt appends the fee snapshot of the session's initiated payment to local store.
+func (t *PaymentTask) recordAttempt(ctx context.Context, session *PaymentSession) error {
+	attempts, err := t.readAttempts(ctx)
+	if err != nil {
+		return fmt.Errorf("payment: failed to read payment attempts: %w", err)
+	}
+	attempts = append(attempts, PaymentAttempt{
+		ReferenceNumber: session.ReferenceNumber,
+		Conversion:      session.Conversion,
+		Breakdown:       session.Breakdown,
+		TotalAmount:     *session.TotalAmount,
+	})
+	if err := t.api.WriteToLocalStore(paymentStoreAttempts, attempts); err != nil {
+		return fmt.Errorf("payment: failed to persist payment attempts: %w", err)
+	}
+	return nil
 }
 
 // ttlDuration returns the configured TTL as a time.Duration.
@@ -695,3 +956,57 @@ func (t *PaymentTask) recordTransaction(ctx context.Context, transactionID, refe
 	}
 	return nil
 }
+
+// readRefunds reads and deserialises the refund history from local store.
+func (t *PaymentTask) readRefunds(_ context.Context) ([]PaymentRefund, error) {
+	raw, err := t.api.ReadFromLocalStore(paymentStoreRefunds)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	// Fast path.
+	if r, ok := raw.([]PaymentRefund); ok {
+		return r, nil
+	}
+
+	// Slow path: JSON round-trip.
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("payment: failed to marshal stored refunds: %w", err)
+	}
+	var r []PaymentRefund
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("payment: failed to unmarshal stored refunds: %w", err)
+	}
+	return r, nil
+}
+
+// readReceipt reads and deserialises the issued receipt from local store.
+func (t *PaymentTask) readReceipt(_ context.Context) (*PaymentReceipt, error) {
+	raw, err := t.api.ReadFromLocalStore(paymentStoreReceipt)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	// Fast path.
+	if r, ok := raw.(*PaymentReceipt); ok {
+		return r, nil
+	}
+
+	// Slow path: JSON round-trip.
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("payment: failed to marshal stored receipt: %w", err)
+	}
+	var r PaymentReceipt
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("payment: failed to unmarshal stored receipt: %w", err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,515 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ── Fee Rules ─────────────────────────────────────────────────────────────────
+//
+// A breakdown is resolved in two phases. FIXED and TIERED items are summed first;
+// PERCENTAGE items then apply, in order, to the running total. Every item may be
+// restricted by conditions on the global context, clamped to a minimum and maximum,
+// and rounded by its own rule.
+
+// TierMode selects how a TIERED item charges its basis.
+type TierMode string
+
+const (
+	// TierModeVolume charges the whole basis at the first tier it fits in.
+	TierModeVolume TierMode = "VOLUME"
+	// TierModeGraduated charges each portion of the basis at the tier it falls in,
+	// like income tax brackets.
+	TierModeGraduated TierMode = "GRADUATED"
+)
+
+// FeeTier is one band of a TIERED item. A band covers the basis up to and including
+// UpTo. The last band has no UpTo and covers everything above.
+type FeeTier struct {
+	UpTo  string `json:"upTo,omitempty"`  // Placeholder or fixed value
+	Fixed string `json:"fixed,omitempty"` // Flat amount charged when the band applies
+	Rate  string `json:"rate,omitempty"`  // Amount per unit of basis in the band
+}
+
+// ConditionOperator compares a global-context value with a FeeCondition.
+type ConditionOperator string
+
+const (
+	ConditionEquals         ConditionOperator = "EQ"
+	ConditionNotEquals      ConditionOperator = "NEQ"
+	ConditionIn             ConditionOperator = "IN"
+	ConditionNotIn          ConditionOperator = "NOT_IN"
+	ConditionGreaterThan    ConditionOperator = "GT"
+	ConditionGreaterOrEqual ConditionOperator = "GTE"
+	ConditionLessThan       ConditionOperator = "LT"
+	ConditionLessOrEqual    ConditionOperator = "LTE"
+	ConditionStartsWith     ConditionOperator = "STARTS_WITH" // Value or any of Values, e.g. HS code chapters
+	ConditionExists         ConditionOperator = "EXISTS"
+)
+
+// FeeCondition is a predicate on the global context, addressed by a dotted path such
+// as "consignment.hsCode". A path that does not resolve only satisfies NEQ and NOT_IN.
+type FeeCondition struct {
+	Path     string            `json:"path"`
+	Operator ConditionOperator `json:"operator"`
+	Value    any               `json:"value,omitempty"`
+	Values   []any             `json:"values,omitempty"` // IN, NOT_IN and STARTS_WITH
+}
+
+// RoundingMode selects how an amount is rounded to FeeRounding.Places.
+type RoundingMode string
+
+const (
+	RoundingHalfUp   RoundingMode = "HALF_UP" // Half away from zero
+	RoundingHalfEven RoundingMode = "HALF_EVEN"
+	RoundingUp       RoundingMode = "UP"   // Away from zero
+	RoundingDown     RoundingMode = "DOWN" // Towards zero
+)
+
+// FeeRounding is the rounding rule of a breakdown item. An item with a rule contributes
+// its rounded amount to the total; an item without one is shown rounded to 2 places but
+// contributes its exact amount.
+type FeeRounding struct {
+	Places int32        `json:"places"`
+	Mode   RoundingMode `json:"mode,omitempty"` // Default HALF_UP
+}
+
+// FeeQuote is a fully resolved breakdown.
+type FeeQuote struct {
+	Breakdown   []ResolvedBreakdownItem `json:"breakdown"`
+	TotalAmount decimal.Decimal         `json:"totalAmount"`
+	Currency    string                  `json:"currency"`
+	Excluded    []string                `json:"excluded,omitempty"` // Descriptions of items whose conditions did not hold
+}
+
+// CalculateFees resolves the breakdown of cfg against globalContext without a task,
+// so fee schedules can be tried out before they are deployed.
+func CalculateFees(cfg PaymentConfig, globalContext map[string]any) (*FeeQuote, error) {
+	if err := ValidatePaymentConfig(cfg); err != nil {
+		return nil, err
+	}
+	return calculateFees(cfg, func(key string) (any, bool) {
+		v, ok := globalContext[key]
+		return v, ok
+	})
+}
+
+// ValidatePaymentConfig checks the breakdown rules of cfg.
+func ValidatePaymentConfig(cfg PaymentConfig) error {
+	for i, item := range cfg.Breakdown {
+		if err := validateBreakdownItem(item); err != nil {
+			return fmt.Errorf("breakdown[%d] %q: %w", i, item.Description, err)
+		}
+	}
+	return nil
+}
+
+func validateBreakdownItem(item BreakdownItem) error {
+	switch item.Category {
+	case CategoryAddition, CategoryDeduction:
+	default:
+		return fmt.Errorf("unsupported category %q", item.Category)
+	}
+	switch item.Type {
+	case TypeFixed, TypePercentage:
+	case TypeTiered:
+		if item.Basis == "" {
+			return fmt.Errorf("basis is required for type %s", TypeTiered)
+		}
+		if len(item.Tiers) == 0 {
+			return fmt.Errorf("tiers are required for type %s", TypeTiered)
+		}
+		switch item.TierMode {
+		case "", TierModeVolume, TierModeGraduated:
+		default:
+			return fmt.Errorf("unsupported tierMode %q", item.TierMode)
+		}
+		for i, tier := range item.Tiers {
+			if last := i == len(item.Tiers)-1; (tier.UpTo == "") != last {
+				return fmt.Errorf("every tier but the last needs upTo, and the last must be open-ended")
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %q", item.Type)
+	}
+	for _, c := range item.When {
+		if c.Path == "" {
+			return fmt.Errorf("condition path is required")
+		}
+		if !isKnownOperator(c.Operator) {
+			return fmt.Errorf("unsupported condition operator %q", c.Operator)
+		}
+	}
+	if item.Rounding != nil {
+		if item.Rounding.Places < 0 {
+			return fmt.Errorf("rounding places must not be negative")
+		}
+		if !isKnownRoundingMode(item.Rounding.Mode) {
+			return fmt.Errorf("unsupported rounding mode %q", item.Rounding.Mode)
+		}
+	}
+	return nil
+}
+
+// calculateFees resolves the breakdown of cfg, reading placeholders and condition paths
+// through read.
+func calculateFees(cfg PaymentConfig, read func(key string) (any, bool)) (*FeeQuote, error) {
+	fc := feeContext{read: read}
+	quote := &FeeQuote{Currency: cfg.Currency}
+	total := decimal.Zero
+
+	apply := func(item BreakdownItem, resolved ResolvedBreakdownItem, amount decimal.Decimal) error {
+		amount, shown, err := fc.finalizeAmount(item, amount)
+		if err != nil {
+			return err
+		}
+		resolved.Amount = shown
+		if item.Category == CategoryDeduction {
+			total = total.Sub(amount)
+		} else {
+			total = total.Add(amount)
+		}
+		quote.Breakdown = append(quote.Breakdown, resolved)
+		return nil
+	}
+
+	// Phase 1: Fixed and tiered items
+	for _, item := range cfg.Breakdown {
+		if item.Type != TypeFixed && item.Type != TypeTiered {
+			continue
+		}
+		ok, err := fc.applies(item)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			quote.Excluded = append(quote.Excluded, fc.resolveString(item.Description))
+			continue
+		}
+
+		resolved := ResolvedBreakdownItem{
+			Description: fc.resolveString(item.Description),
+			Category:    item.Category,
+			Type:        item.Type,
+		}
+		var amount decimal.Decimal
+		if item.Type == TypeTiered {
+			basis := fc.resolveValue(item.Basis, decimal.Zero)
+			amount, err = fc.tieredAmount(item, basis)
+			if err != nil {
+				return nil, fmt.Errorf("breakdown item %q: %w", item.Description, err)
+			}
+			resolved.Quantity = basis
+		} else {
+			resolved.Quantity = fc.resolveValue(item.Quantity, decimal.NewFromInt(1))
+			resolved.UnitPrice = fc.resolveValue(item.UnitPrice, decimal.Zero)
+			amount = resolved.Quantity.Mul(resolved.UnitPrice)
+		}
+		if err := apply(item, resolved, amount); err != nil {
+			return nil, err
+		}
+	}
+
+	// Phase 2: Percentage items
+	for _, item := range cfg.Breakdown {
+		if item.Type != TypePercentage {
+			continue
+		}
+		ok, err := fc.applies(item)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			quote.Excluded = append(quote.Excluded, fc.resolveString(item.Description))
+			continue
+		}
+
+		percentage := fc.resolveValue(item.Value, decimal.Zero)
+		resolved := ResolvedBreakdownItem{
+			Description: fc.resolveString(item.Description),
+			Category:    item.Category,
+			Type:        item.Type,
+		}
+		if err := apply(item, resolved, total.Mul(percentage).Div(decimal.NewFromInt(100))); err != nil {
+			return nil, err
+		}
+	}
+
+	quote.TotalAmount = total.Round(2)
+	return quote, nil
+}
+
+// feeContext resolves placeholders and condition paths against a global context.
+type feeContext struct {
+	read func(key string) (any, bool)
+}
+
+// finalizeAmount clamps amount to the item's bounds and applies its rounding rule. It
+// returns the amount that counts towards the total and the amount shown for the item.
+func (fc feeContext) finalizeAmount(item BreakdownItem, amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, error) {
+	if item.Min != "" {
+		if min := fc.resolveValue(item.Min, amount); amount.LessThan(min) {
+			amount = min
+		}
+	}
+	if item.Max != "" {
+		if max := fc.resolveValue(item.Max, amount); amount.GreaterThan(max) {
+			amount = max
+		}
+	}
+	if item.Rounding == nil {
+		return amount, amount.Round(2), nil
+	}
+	rounded := roundAmount(amount, *item.Rounding)
+	return rounded, rounded, nil
+}
+
+// tieredAmount charges basis according to the item's tiers.
+func (fc feeContext) tieredAmount(item BreakdownItem, basis decimal.Decimal) (decimal.Decimal, error) {
+	amount := decimal.Zero
+	lower := decimal.Zero
+	for i, tier := range item.Tiers {
+		open := tier.UpTo == ""
+		upTo := fc.resolveValue(tier.UpTo, decimal.Zero)
+		if !open && i > 0 && !upTo.GreaterThan(lower) {
+			return decimal.Zero, fmt.Errorf("tier upTo values must be ascending")
+		}
+		fixed := fc.resolveValue(tier.Fixed, decimal.Zero)
+		rate := fc.resolveValue(tier.Rate, decimal.Zero)
+		last := open || !basis.GreaterThan(upTo)
+
+		if item.TierMode == TierModeGraduated {
+			top := basis
+			if !open && upTo.LessThan(top) {
+				top = upTo
+			}
+			if top.GreaterThan(lower) {
+				amount = amount.Add(fixed).Add(top.Sub(lower).Mul(rate))
+			}
+		} else if last {
+			amount = fixed.Add(basis.Mul(rate))
+		}
+		if last {
+			return amount, nil
+		}
+		lower = upTo
+	}
+	return decimal.Zero, fmt.Errorf("basis %s exceeds the last tier", basis.String())
+}
+
+// applies reports whether every condition of the item holds.
+func (fc feeContext) applies(item BreakdownItem) (bool, error) {
+	for _, c := range item.When {
+		ok, err := fc.evaluate(c)
+		if err != nil {
+			return false, fmt.Errorf("breakdown item %q: %w", item.Description, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// evaluate reports whether a single condition holds.
+func (fc feeContext) evaluate(c FeeCondition) (bool, error) {
+	actual := fc.lookup(c.Path)
+	switch c.Operator {
+	case ConditionExists:
+		return actual != nil, nil
+	case ConditionEquals:
+		return actual != nil && valuesEqual(actual, c.Value), nil
+	case ConditionNotEquals:
+		return actual == nil || !valuesEqual(actual, c.Value), nil
+	case ConditionIn, ConditionNotIn:
+		found := false
+		for _, v := range c.Values {
+			if actual != nil && valuesEqual(actual, v) {
+				found = true
+				break
+			}
+		}
+		return found == (c.Operator == ConditionIn), nil
+	case ConditionStartsWith:
+		if actual == nil {
+			return false, nil
+		}
+		s := fmt.Sprintf("%v", actual)
+		prefixes := c.Values
+		if c.Value != nil {
+			prefixes = append([]any{c.Value}, prefixes...)
+		}
+		for _, p := range prefixes {
+			if strings.HasPrefix(s, fmt.Sprintf("%v", p)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case ConditionGreaterThan, ConditionGreaterOrEqual, ConditionLessThan, ConditionLessOrEqual:
+		a, okA := toDecimal(actual)
+		b, okB := toDecimal(c.Value)
+		if !okB {
+			return false, fmt.Errorf("condition %s on %q needs a numeric value", c.Operator, c.Path)
+		}
+		if !okA {
+			return false, nil
+		}
+		cmp := a.Cmp(b)
+		switch c.Operator {
+		case ConditionGreaterThan:
+			return cmp > 0, nil
+		case ConditionGreaterOrEqual:
+			return cmp >= 0, nil
+		case ConditionLessThan:
+			return cmp < 0, nil
+		default:
+			return cmp <= 0, nil
+		}
+	default:
+		return false, fmt.Errorf("unsupported condition operator %q", c.Operator)
+	}
+}
+
+// resolveValue resolves a literal or a `{path:default}` placeholder to a decimal.
+func (fc feeContext) resolveValue(val string, fallback decimal.Decimal) decimal.Decimal {
+	if val == "" {
+		return fallback
+	}
+
+	// If placeholder {path:default}
+	if strings.HasPrefix(val, "{") && strings.HasSuffix(val, "}") {
+		inner := val[1 : len(val)-1]
+		parts := strings.Split(inner, ":")
+		path := parts[0]
+
+		if len(parts) > 1 {
+			if d, err := decimal.NewFromString(parts[1]); err == nil {
+				fallback = d
+			}
+		}
+
+		if d, ok := toDecimal(fc.lookup(path)); ok {
+			return d
+		}
+		return fallback
+	}
+
+	// Literal value
+	if d, err := decimal.NewFromString(val); err == nil {
+		return d
+	}
+	return fallback
+}
+
+// resolveString replaces `{path:default}` placeholders in val.
+func (fc feeContext) resolveString(val string) string {
+	// Simple regex-free placeholder replacement
+	for {
+		start := strings.Index(val, "{")
+		end := strings.Index(val, "}")
+		if start == -1 || end == -1 || end < start {
+			break
+		}
+
+		placeholder := val[start : end+1]
+		inner := val[start+1 : end]
+		parts := strings.Split(inner, ":")
+		path := parts[0]
+
+		resolved := fc.lookup(path)
+		replacement := ""
+		if resolved != nil {
+			replacement = fmt.Sprintf("%v", resolved)
+		} else if len(parts) > 1 {
+			replacement = parts[1]
+		}
+
+		val = strings.Replace(val, placeholder, replacement, 1)
+	}
+	return val
+}
+
+// lookup resolves a dotted path in the global context, or returns nil.
+func (fc feeContext) lookup(path string) any {
+	keys := strings.Split(path, ".")
+	val, ok := fc.read(keys[0])
+	if !ok {
+		return nil
+	}
+
+	current := val
+	for i := 1; i < len(keys); i++ {
+		if m, ok := current.(map[string]any); ok {
+			current, ok = m[keys[i]]
+			if !ok {
+				return nil
+			}
+		} else {
+			return nil
+		}
+	}
+	return current
+}
+
+// toDecimal converts a JSON-decoded or literal value to a decimal.
+func toDecimal(v any) (decimal.Decimal, bool) {
+	switch v := v.(type) {
+	case float64:
+		return decimal.NewFromFloat(v), true
+	case string:
+		if d, err := decimal.NewFromString(v); err == nil {
+			return d, true
+		}
+	case int:
+		return decimal.NewFromInt(int64(v)), true
+	case int64:
+		return decimal.NewFromInt(v), true
+	case decimal.Decimal:
+		return v, true
+	}
+	return decimal.Zero, false
+}
+
+// valuesEqual compares numerically when both sides are numbers, and as text otherwise.
+func valuesEqual(a, b any) bool {
+	if da, ok := toDecimal(a); ok {
+		if db, ok := toDecimal(b); ok {
+			return da.Equal(db)
+		}
+	}
+	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
+}
+
+// roundAmount applies a rounding rule.
+func roundAmount(amount decimal.Decimal, rule FeeRounding) decimal.Decimal {
+	switch rule.Mode {
+	case RoundingHalfEven:
+		return amount.RoundBank(rule.Places)
+	case RoundingUp:
+		return amount.RoundUp(rule.Places)
+	case RoundingDown:
+		return amount.RoundDown(rule.Places)
+	default:
+		return amount.Round(rule.Places)
+	}
+}
+
+func isKnownOperator(op ConditionOperator) bool {
+	switch op {
+	case ConditionEquals, ConditionNotEquals, ConditionIn, ConditionNotIn,
+		ConditionGreaterThan, ConditionGreaterOrEqual, ConditionLessThan, ConditionLessOrEqual,
+		ConditionStartsWith, ConditionExists:
+		return true
+	}
+	return false
+}
+
+func isKnownRoundingMode(mode RoundingMode) bool {
+	switch mode {
+	case "", RoundingHalfUp, RoundingHalfEven, RoundingUp, RoundingDown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func feeConfig(items ...BreakdownItem) PaymentConfig {
+	return PaymentConfig{Currency: "LKR", Breakdown: items}
+}
+
+func TestCalculateFees_FixedAndPercentage(t *testing.T) {
+	cfg := feeConfig(
+		BreakdownItem{Description: "Levy for {cusdec.id}", Category: CategoryAddition, Type: TypeFixed, Quantity: "{cusdec.qty:1}", UnitPrice: "100"},
+		BreakdownItem{Description: "Discount", Category: CategoryDeduction, Type: TypeFixed, UnitPrice: "50"},
+		BreakdownItem{Description: "VAT", Category: CategoryAddition, Type: TypePercentage, Value: "18"},
+	)
+
+	quote, err := CalculateFees(cfg, map[string]any{"cusdec": map[string]any{"id": "CD-1", "qty": 3.0}})
+
+	require.NoError(t, err)
+	require.Len(t, quote.Breakdown, 3)
+	assert.Equal(t, "Levy for CD-1", quote.Breakdown[0].Description)
+	assert.True(t, decimal.NewFromInt(300).Equal(quote.Breakdown[0].Amount))
+	assert.True(t, decimal.NewFromInt(45).Equal(quote.Breakdown[2].Amount))
+	assert.True(t, decimal.NewFromInt(295).Equal(quote.TotalAmount))
+	assert.Equal(t, "LKR", quote.Currency)
+}
+
+func TestCalculateFees_Tiered(t *testing.T) {
+	tiers := []FeeTier{
+		{UpTo: "100", Fixed: "500"},
+		{UpTo: "1000", Rate: "2"},
+		{Rate: "1"},
+	}
+	tests := []struct {
+		name  string
+		mode  TierMode
+		basis float64
+		want  string
+	}{
+		{"volume first tier", TierModeVolume, 80, "500"},
+		{"volume tier boundary is inclusive", TierModeVolume, 100, "500"},
+		{"volume second tier", TierModeVolume, 400, "800"},
+		{"volume open tier", TierModeVolume, 5000, "5000"},
+		{"graduated first tier", TierModeGraduated, 80, "500"},
+		{"graduated spans tiers", TierModeGraduated, 400, "1100"},        // 500 + 300*2
+		{"graduated reaches open tier", TierModeGraduated, 1500, "2800"}, // 500 + 900*2 + 500*1
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := feeConfig(BreakdownItem{
+				Description: "Inspection", Category: CategoryAddition, Type: TypeTiered,
+				Basis: "{consignment.weight}", TierMode: tt.mode, Tiers: tiers,
+			})
+
+			quote, err := CalculateFees(cfg, map[string]any{"consignment": map[string]any{"weight": tt.basis}})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, quote.TotalAmount.String())
+			assert.True(t, decimal.NewFromFloat(tt.basis).Equal(quote.Breakdown[0].Quantity))
+		})
+	}
+}
+
+func TestCalculateFees_MinMax(t *testing.T) {
+	cfg := feeConfig(
+		BreakdownItem{Description: "Goods", Category: CategoryAddition, Type: TypeFixed, UnitPrice: "{value}"},
+		BreakdownItem{Description: "Cess", Category: CategoryAddition, Type: TypePercentage, Value: "1", Min: "250", Max: "{cap:1000}"},
+	)
+
+	for value, wantCess := range map[string]string{"1000": "250", "50000": "500", "900000": "1000"} {
+		quote, err := CalculateFees(cfg, map[string]any{"value": value})
+		require.NoError(t, err)
+		assert.Equal(t, wantCess, quote.Breakdown[1].Amount.String(), "value %s", value)
+	}
+}
+
+func TestCalculateFees_Conditions(t *testing.T) {
+	cfg := feeConfig(
+		BreakdownItem{Description: "Base", Category: CategoryAddition, Type: TypeFixed, UnitPrice: "100"},
+		BreakdownItem{Description: "Plant quarantine", Category: CategoryAddition, Type: TypeFixed, UnitPrice: "40",
+			When: []FeeCondition{{Path: "consignment.hsCode", Operator: ConditionStartsWith, Values: []any{"06", "07"}}}},
+		BreakdownItem{Description: "Bulk surcharge", Category: CategoryAddition, Type: TypeFixed, UnitPrice: "25",
+			When: []FeeCondition{
+				{Path: "consignment.weight", Operator: ConditionGreaterThan, Value: 1000},
+				{Path: "consignment.mode", Operator: ConditionIn, Values: []any{"SEA", "RAIL"}},
+			}},
+		BreakdownItem{Description: "Exemption", Category: CategoryDeduction, Type: TypePercentage, Value: "100",
+			When: []FeeCondition{{Path: "trader.exempt", Operator: ConditionEquals, Value: true}}},
+	)
+
+	t.Run("matching conditions include items", func(t *testing.T) {
+		quote, err := CalculateFees(cfg, map[string]any{
+			"consignment": map[string]any{"hsCode": "0703.10", "weight": 1500.0, "mode": "SEA"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "165", quote.TotalAmount.String())
+		assert.Equal(t, []string{"Exemption"}, quote.Excluded)
+	})
+
+	t.Run("missing paths exclude items", func(t *testing.T) {
+		quote, err := CalculateFees(cfg, map[string]any{"trader": map[string]any{"exempt": true}})
+		require.NoError(t, err)
+		assert.Equal(t, "0", quote.TotalAmount.String())
+		assert.Equal(t, []string{"Plant quarantine", "Bulk surcharge"}, quote.Excluded)
+	})
+}
+
+func TestCalculateFees_Rounding(t *testing.T) {
+	item := func(rounding *FeeRounding) BreakdownItem {
+		return BreakdownItem{Description: "Fee", Category: CategoryAddition, Type: TypeFixed, Quantity: "3", UnitPrice: "10.125", Rounding: rounding}
+	}
+	tests := []struct {
+		name     string
+		rounding *FeeRounding
+		want     string
+	}{
+		{"default", nil, "30.38"},
+		{"half up", &FeeRounding{Places: 1}, "30.4"},
+		{"half even", &FeeRounding{Places: 2, Mode: RoundingHalfEven}, "30.38"},
+		{"up to whole units", &FeeRounding{Places: 0, Mode: RoundingUp}, "31"},
+		{"down", &FeeRounding{Places: 1, Mode: RoundingDown}, "30.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := CalculateFees(feeConfig(item(tt.rounding)), nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, quote.Breakdown[0].Amount.String())
+			assert.Equal(t, tt.want, quote.TotalAmount.String())
+		})
+	}
+}
+
+func TestValidatePaymentConfig(t *testing.T) {
+	valid := BreakdownItem{Description: "Fee", Category: CategoryAddition, Type: TypeFixed, UnitPrice: "1"}
+	tests := []struct {
+		name   string
+		mutate func(*BreakdownItem)
+	}{
+		{"unknown type", func(i *BreakdownItem) { i.Type = "FORMULA" }},
+		{"unknown category", func(i *BreakdownItem) { i.Category = "" }},
+		{"tiered without basis", func(i *BreakdownItem) { i.Type = TypeTiered; i.Tiers = []FeeTier{{Rate: "1"}} }},
+		{"tiered without open tier", func(i *BreakdownItem) {
+			i.Type, i.Basis, i.Tiers = TypeTiered, "{w}", []FeeTier{{UpTo: "10", Rate: "1"}}
+		}},
+		{"unknown operator", func(i *BreakdownItem) { i.When = []FeeCondition{{Path: "a", Operator: "LIKE"}} }},
+		{"unknown rounding", func(i *BreakdownItem) { i.Rounding = &FeeRounding{Places: 2, Mode: "CEIL"} }},
+	}
+	require.NoError(t, ValidatePaymentConfig(feeConfig(valid)))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := valid
+			tt.mutate(&item)
+			assert.Error(t, ValidatePaymentConfig(feeConfig(item)))
+		})
+	}
+}
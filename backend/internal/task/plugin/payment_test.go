@@ -4,41 +4,152 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"testing"
 	"time"
 
-	"github.com/OpenNSW/nsw/internal/payments"
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/paymentsv2"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-// MockPaymentService is a mock implementation of the payments.PaymentService interface
+// MockPaymentService is a mock implementation of the paymentsv2.PaymentService interface
 type MockPaymentService struct {
 	mock.Mock
 }
 
-func (m *MockPaymentService) CreateCheckoutSession(ctx context.Context, req payments.CreateCheckoutRequest) (*payments.CreateCheckoutResponse, error) {
-	args := m.Called(ctx, req)
+func (m *MockPaymentService) ListAvailableMethods(ctx context.Context) ([]paymentsv2.PaymentProviderInfo, error) {
+	args := m.Called(ctx)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*payments.CreateCheckoutResponse), args.Error(1)
+	return args.Get(0).([]paymentsv2.PaymentProviderInfo), args.Error(1)
 }
 
-func (m *MockPaymentService) ValidateReference(ctx context.Context, req payments.ValidateReferenceRequest) (*payments.ValidateReferenceResponse, error) {
+func (m *MockPaymentService) CreateCheckoutSession(ctx context.Context, req paymentsv2.CreateCheckoutRequest) (*paymentsv2.CreateCheckoutResponse, error) {
 	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*payments.ValidateReferenceResponse), args.Error(1)
+	return args.Get(0).(*paymentsv2.CreateCheckoutResponse), args.Error(1)
+}
+
+func (m *MockPaymentService) ValidateReference(ctx context.Context, providerID string, req paymentsv2.ValidateReferenceRequest) (*paymentsv2.ValidateReferenceResponse, error) {
+	args := m.Called(ctx, providerID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.ValidateReferenceResponse), args.Error(1)
 }
 
-func (m *MockPaymentService) ProcessWebhook(ctx context.Context, payload payments.WebhookPayload) error {
-	args := m.Called(ctx, payload)
+func (m *MockPaymentService) ProcessWebhook(ctx context.Context, providerID string, body []byte, headers map[string][]string) error {
+	args := m.Called(ctx, providerID, body, headers)
 	return args.Error(0)
 }
 
+func (m *MockPaymentService) RegisterEventHandler(handler paymentsv2.EventHandler) {
+	m.Called(handler)
+}
+
+func (m *MockPaymentService) Reconcile(ctx context.Context) (*paymentsv2.ReconciliationResult, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.ReconciliationResult), args.Error(1)
+}
+
+func (m *MockPaymentService) ImportSettlement(ctx context.Context, providerID string, settlementDate time.Time, file io.Reader) (*paymentsv2.SettlementReport, error) {
+	args := m.Called(ctx, providerID, settlementDate, file)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.SettlementReport), args.Error(1)
+}
+
+func (m *MockPaymentService) GetSettlementReport(ctx context.Context, id string) (*paymentsv2.SettlementReport, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.SettlementReport), args.Error(1)
+}
+
+func (m *MockPaymentService) RequestRefund(ctx context.Context, req paymentsv2.RefundRequest) (*paymentsv2.PaymentRefund, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.PaymentRefund), args.Error(1)
+}
+
+func (m *MockPaymentService) ApproveRefund(ctx context.Context, d paymentsv2.RefundDecision) (*paymentsv2.PaymentRefund, error) {
+	args := m.Called(ctx, d)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.PaymentRefund), args.Error(1)
+}
+
+func (m *MockPaymentService) RejectRefund(ctx context.Context, d paymentsv2.RefundDecision) (*paymentsv2.PaymentRefund, error) {
+	args := m.Called(ctx, d)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.PaymentRefund), args.Error(1)
+}
+
+func (m *MockPaymentService) GetTransaction(ctx context.Context, referenceNumber string) (*paymentsv2.PaymentTransaction, error) {
+	args := m.Called(ctx, referenceNumber)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.PaymentTransaction), args.Error(1)
+}
+
+func (m *MockPaymentService) ListRefunds(ctx context.Context, referenceNumber string) ([]paymentsv2.PaymentRefund, error) {
+	args := m.Called(ctx, referenceNumber)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]paymentsv2.PaymentRefund), args.Error(1)
+}
+
+func (m *MockPaymentService) ConvertAmount(ctx context.Context, amount decimal.Decimal, currency string) (*paymentsv2.CurrencyConversion, error) {
+	args := m.Called(ctx, amount, currency)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.CurrencyConversion), args.Error(1)
+}
+
+func (m *MockPaymentService) IssueReceipt(ctx context.Context, req paymentsv2.IssueReceiptRequest) (*paymentsv2.PaymentReceipt, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.PaymentReceipt), args.Error(1)
+}
+
+func (m *MockPaymentService) GetReceipt(ctx context.Context, referenceNumber string) (*paymentsv2.PaymentReceipt, error) {
+	args := m.Called(ctx, referenceNumber)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*paymentsv2.PaymentReceipt), args.Error(1)
+}
+
+func (m *MockPaymentService) OpenReceipt(ctx context.Context, referenceNumber string, format paymentsv2.ReceiptFormat) (*paymentsv2.PaymentReceipt, io.ReadCloser, error) {
+	args := m.Called(ctx, referenceNumber, format)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*paymentsv2.PaymentReceipt), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
 // ── FSM Tests ─────────────────────────────────────────────────────────────────
 
 func TestNewPaymentFSM(t *testing.T) {
@@ -57,12 +168,15 @@ func TestNewPaymentFSM(t *testing.T) {
 		{"SUCCESS from IN_PROGRESS", "IN_PROGRESS", PaymentActionSuccess, "COMPLETED", Completed, true},
 		{"FAILED from IN_PROGRESS", "IN_PROGRESS", PaymentActionFailed, "IDLE", Initialized, true},
 		{"TIMEOUT from IN_PROGRESS", "IN_PROGRESS", paymentFSMTimeout, "IDLE", Initialized, true},
+		{"SUCCESS from IDLE (late confirmation)", "IDLE", PaymentActionSuccess, "COMPLETED", Completed, true},
+		{"REFUNDED from COMPLETED", "COMPLETED", PaymentActionRefunded, "COMPLETED", "", true},
 
 		// Invalid transitions
 		{"INITIATE from empty", "", PaymentActionInitiate, "", "", false},
-		{"SUCCESS from IDLE", "IDLE", PaymentActionSuccess, "", "", false},
+		{"SUCCESS from COMPLETED", "COMPLETED", PaymentActionSuccess, "", "", false},
 		{"FAILED from IDLE", "IDLE", PaymentActionFailed, "", "", false},
 		{"INITIATE from COMPLETED", "COMPLETED", PaymentActionInitiate, "", "", false},
+		{"REFUNDED from IN_PROGRESS", "IN_PROGRESS", PaymentActionRefunded, "", "", false},
 	}
 
 	for _, tt := range tests {
@@ -100,6 +214,21 @@ func TestNewPaymentTask(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "invalid config")
 	})
+
+	t.Run("InvalidBreakdown", func(t *testing.T) {
+		for name, item := range map[string]string{
+			"UnknownType":       `{"description": "Fee", "category": "ADDITION", "type": "FLAT", "unitPrice": "100"}`,
+			"MisspeltCategory":  `{"description": "Rebate", "category": "DEDUCTON", "type": "FIXED", "unitPrice": "10"}`,
+			"TieredWithoutTier": `{"description": "Duty", "category": "ADDITION", "type": "TIERED", "basis": "value"}`,
+		} {
+			t.Run(name, func(t *testing.T) {
+				cfg := `{"currency": "USD", "breakdown": [` + item + `]}`
+				_, err := NewPaymentTask(json.RawMessage(cfg), mockSvc)
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid config")
+			})
+		}
+	})
 }
 
 // ── Start Tests ───────────────────────────────────────────────────────────────
@@ -127,6 +256,7 @@ func TestPaymentStart(t *testing.T) {
 		assert.NotEmpty(t, session.TransactionID)
 		assert.False(t, session.GeneratedAt.IsZero())
 		assert.Nil(t, session.InitiatedAt)
+		assert.Equal(t, "Sri Lanka Customs", session.OrgName)
 
 		mockAPI.AssertExpectations(t)
 	})
@@ -198,9 +328,12 @@ func TestPaymentGetRenderInfo_IDLE(t *testing.T) {
 		GeneratedAt:   time.Now(), // fresh session, within TTL
 	}
 
+	indicative := &paymentsv2.CurrencyConversion{Amount: decimal.NewFromInt(100), Currency: "USD", BaseAmount: decimal.NewFromInt(29950), BaseCurrency: "LKR"}
+
 	mockAPI.On("GetPluginState").Return("IDLE")
 	mockAPI.On("GetTaskState").Return(InProgress)
 	mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(session, nil)
+	mockSvc.On("ConvertAmount", mock.Anything, mock.MatchedBy(decimal.NewFromInt(100).Equal), "USD").Return(indicative, nil).Once()
 
 	resp, err := task.GetRenderInfo(context.Background())
 
@@ -214,8 +347,10 @@ func TestPaymentGetRenderInfo_IDLE(t *testing.T) {
 	content := data.Content.(PaymentRenderContent)
 	assert.True(t, decimal.NewFromFloat(100.0).Equal(content.TotalAmount))
 	assert.Equal(t, "USD", content.Currency)
+	assert.Equal(t, indicative, content.Conversion)
 
 	mockAPI.AssertExpectations(t)
+	mockSvc.AssertExpectations(t)
 }
 
 func TestPaymentGetRenderInfo_Completed(t *testing.T) {
@@ -226,6 +361,11 @@ func TestPaymentGetRenderInfo_Completed(t *testing.T) {
 
 	mockAPI.On("GetPluginState").Return("COMPLETED")
 	mockAPI.On("GetTaskState").Return(Completed)
+	mockAPI.On("ReadFromLocalStore", paymentStoreRefunds).Return(nil, nil)
+	mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(PaymentSession{TransactionID: "txn-1", ReferenceNumber: "NSW-PR-2026-AB234"}, nil)
+	mockAPI.On("ReadFromLocalStore", paymentStoreReceipt).Return(map[string]any{
+		"receiptNumber": "NSW-RC-2026-000001", "referenceNumber": "NSW-PR-2026-AB234", "pdfKey": "r.pdf", "jsonKey": "r.json",
+	}, nil)
 
 	resp, err := task.GetRenderInfo(context.Background())
 
@@ -237,10 +377,48 @@ func TestPaymentGetRenderInfo_Completed(t *testing.T) {
 	assert.True(t, decimal.NewFromFloat(100.0).Equal(content.TotalAmount))
 	assert.Equal(t, "USD", content.Currency)
 	assert.Equal(t, "COMPLETED", data.PluginState)
+	assert.Empty(t, content.Refunds)
+	assert.Nil(t, content.RefundedAmount)
+	require.NotNil(t, content.Receipt)
+	assert.Equal(t, "NSW-RC-2026-000001", content.Receipt.ReceiptNumber)
+	assert.Equal(t, "r.pdf", content.Receipt.PDFKey)
 
 	mockAPI.AssertExpectations(t)
 }
 
+func TestPaymentGetRenderInfo_InProgressUsesRateSnapshot(t *testing.T) {
+	mockAPI := new(MockAPI)
+	mockSvc := new(MockPaymentService)
+	task := newTestPaymentTask(mockSvc)
+	task.Init(mockAPI)
+
+	// Sessions come back as generic JSON after a cache miss.
+	initiatedAt := time.Now()
+	stored := map[string]any{
+		"transactionId":   "txn-1",
+		"referenceNumber": "NSW-PR-2026-AB234",
+		"generatedAt":     initiatedAt.Format(time.RFC3339Nano),
+		"initiatedAt":     initiatedAt.Format(time.RFC3339Nano),
+		"conversion": map[string]any{
+			"amount": "100", "currency": "USD", "base_amount": "29950", "base_currency": "LKR",
+			"rate": "299.5", "rate_source": "CBSL", "rate_as_of": "2026-10-16T00:00:00Z",
+		},
+	}
+
+	mockAPI.On("GetPluginState").Return("IN_PROGRESS")
+	mockAPI.On("GetTaskState").Return(InProgress)
+	mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(stored, nil)
+
+	resp, err := task.GetRenderInfo(context.Background())
+	require.NoError(t, err)
+
+	content := resp.Data.(GetRenderInfoResponse).Content.(PaymentRenderContent)
+	require.NotNil(t, content.Conversion)
+	assert.Equal(t, "29950", content.Conversion.BaseAmount.String())
+	assert.Equal(t, "CBSL", content.Conversion.RateSource)
+	mockSvc.AssertNotCalled(t, "ConvertAmount", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestPaymentGetRenderInfo_SessionRotation(t *testing.T) {
 	mockAPI := new(MockAPI)
 	mockSvc := new(MockPaymentService)
@@ -257,6 +435,7 @@ func TestPaymentGetRenderInfo_SessionRotation(t *testing.T) {
 	mockAPI.On("GetTaskState").Return(InProgress)
 	mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(expiredSession, nil)
 	mockAPI.On("WriteToLocalStore", paymentStoreSession, mock.AnythingOfType("*plugin.PaymentSession")).Return(nil).Once()
+	mockSvc.On("ConvertAmount", mock.Anything, mock.Anything, "USD").Return(nil, nil)
 
 	resp, err := task.GetRenderInfo(context.Background())
 
@@ -299,6 +478,7 @@ func TestPaymentGetRenderInfo_TimeoutTransition(t *testing.T) {
 
 		// Expect session rotation (session is also past TTL).
 		mockAPI.On("WriteToLocalStore", paymentStoreSession, mock.AnythingOfType("*plugin.PaymentSession")).Return(nil).Once()
+		mockSvc.On("ConvertAmount", mock.Anything, mock.Anything, "USD").Return(nil, nil)
 
 		resp, err := task.GetRenderInfo(context.Background())
 
@@ -385,14 +565,24 @@ func TestPaymentExecute_InitiatePayment(t *testing.T) {
 		mockAPI.On("GetTaskID").Return("task-123").Once()
 		mockAPI.On("GetPluginState").Return("IDLE").Once()
 
-		mockSvc.On("CreateCheckoutSession", mock.Anything, mock.MatchedBy(func(req payments.CreateCheckoutRequest) bool {
-			return req.Amount.Equal(decimal.NewFromFloat(100.0)) && req.Currency == "USD"
-		})).Return(&payments.CreateCheckoutResponse{
-			SessionID:   "sess-123",
-			CheckoutURL: "https://pay.example.com/sess-123",
+		mockSvc.On("CreateCheckoutSession", mock.Anything, mock.MatchedBy(func(req paymentsv2.CreateCheckoutRequest) bool {
+			return req.Amount.Equal(decimal.NewFromFloat(100.0)) && req.Currency == "USD" && req.ProviderID == "mock" &&
+				req.Metadata[paymentsv2.MetadataPayerID] == "trader-1" && req.Metadata[paymentsv2.MetadataOrgID] == "CUSTOMS"
+		})).Return(&paymentsv2.CreateCheckoutResponse{
+			ReferenceNumber: "NSW-PR-2026-AB234",
+			ProviderID:      "mock",
+			SessionID:       "sess-123",
+			CheckoutURL:     "https://pay.example.com/sess-123",
+			Conversion:      &paymentsv2.CurrencyConversion{Currency: "USD", BaseCurrency: "LKR", Rate: decimal.RequireFromString("299.5")},
 		}, nil).Once()
 
 		var capturedSession *PaymentSession
+		var capturedAttempts []PaymentAttempt
+		mockAPI.On("ReadFromLocalStore", paymentStoreAttempts).Return(nil, nil).Once()
+		mockAPI.On("WriteToLocalStore", paymentStoreAttempts, mock.AnythingOfType("[]plugin.PaymentAttempt")).
+			Run(func(args mock.Arguments) {
+				capturedAttempts = args.Get(1).([]PaymentAttempt)
+			}).Return(nil).Once()
 		mockAPI.On("WriteToLocalStore", paymentStoreSession, mock.AnythingOfType("*plugin.PaymentSession")).
 			Run(func(args mock.Arguments) {
 				capturedSession = args.Get(1).(*PaymentSession)
@@ -402,10 +592,11 @@ func TestPaymentExecute_InitiatePayment(t *testing.T) {
 		initiatedAt := time.Now().Format(time.RFC3339)
 		req := &ExecutionRequest{
 			Action:  PaymentActionInitiate,
-			Content: map[string]any{"initiatedAt": initiatedAt},
+			Content: map[string]any{"initiatedAt": initiatedAt, "methodId": "mock"},
 		}
 
-		resp, err := task.Execute(context.Background(), req)
+		ctx := context.WithValue(context.Background(), auth.AuthContextKey, &auth.AuthContext{User: &auth.UserContext{ID: "trader-1"}})
+		resp, err := task.Execute(ctx, req)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, resp)
@@ -415,6 +606,19 @@ func TestPaymentExecute_InitiatePayment(t *testing.T) {
 		// Verify InitiatedAt was set on the session.
 		assert.NotNil(t, capturedSession.InitiatedAt)
 		assert.Equal(t, "https://pay.example.com/sess-123", capturedSession.CheckoutURL)
+		assert.Equal(t, "NSW-PR-2026-AB234", capturedSession.ReferenceNumber)
+		assert.Equal(t, "mock", capturedSession.SelectedMethodID)
+		require.NotNil(t, capturedSession.Conversion)
+		assert.Equal(t, "299.5", capturedSession.Conversion.Rate.String())
+		// The fees charged are snapshotted for the receipt.
+		require.NotNil(t, capturedSession.TotalAmount)
+		assert.True(t, decimal.NewFromInt(100).Equal(*capturedSession.TotalAmount))
+		require.Len(t, capturedSession.Breakdown, 1)
+		// The snapshot is also kept per reference number for a late confirmation.
+		require.Len(t, capturedAttempts, 1)
+		assert.Equal(t, "NSW-PR-2026-AB234", capturedAttempts[0].ReferenceNumber)
+		assert.True(t, decimal.NewFromInt(100).Equal(capturedAttempts[0].TotalAmount))
+		assert.Equal(t, "299.5", capturedAttempts[0].Conversion.Rate.String())
 
 		mockAPI.AssertExpectations(t)
 		mockSvc.AssertExpectations(t)
@@ -482,16 +686,208 @@ func TestPaymentExecute_PaymentSuccess(t *testing.T) {
 		task.Init(mockAPI)
 
 		mockAPI.On("CanTransition", PaymentActionSuccess).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(PaymentSession{ReferenceNumber: "NSW-PR-2026-AB234", OrgName: "Sri Lanka Customs"}, nil).Once()
 		mockAPI.On("Transition", PaymentActionSuccess).Return(nil).Once()
+		mockSvc.On("IssueReceipt", mock.Anything, mock.MatchedBy(func(req paymentsv2.IssueReceiptRequest) bool {
+			return req.ReferenceNumber == "NSW-PR-2026-AB234" && req.AgencyName == "Sri Lanka Customs" &&
+				len(req.Breakdown) == 1 && req.Breakdown[0].Amount.Equal(decimal.NewFromInt(100))
+		})).Return(&paymentsv2.PaymentReceipt{ReceiptNumber: "NSW-RC-2026-000001", ReferenceNumber: "NSW-PR-2026-AB234", PDFKey: "r.pdf", JSONKey: "r.json"}, nil).Once()
 
-		req := &ExecutionRequest{Action: PaymentActionSuccess}
+		var stored *PaymentReceipt
+		mockAPI.On("WriteToLocalStore", paymentStoreReceipt, mock.AnythingOfType("*plugin.PaymentReceipt")).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*PaymentReceipt) }).Return(nil).Once()
+
+		req := &ExecutionRequest{Action: PaymentActionSuccess, Content: map[string]any{"referenceNumber": "NSW-PR-2026-AB234"}}
 		resp, err := task.Execute(context.Background(), req)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, resp)
 		assert.Equal(t, "Payment completed successfully", resp.Message)
 		assert.True(t, resp.ApiResponse.Success)
+		require.NotNil(t, stored)
+		assert.Equal(t, "NSW-RC-2026-000001", stored.ReceiptNumber)
+
+		mockAPI.AssertExpectations(t)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("ReceiptFromInitiationSnapshot", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		// The fees were 80 when the payment was initiated; the global context has changed since.
+		paid := decimal.NewFromInt(80)
+		session := PaymentSession{
+			ReferenceNumber: "NSW-PR-2026-AB234",
+			Breakdown:       []ResolvedBreakdownItem{{Description: "Fee", Category: CategoryAddition, Type: TypeFixed, Quantity: decimal.NewFromInt(1), UnitPrice: paid, Amount: paid}},
+			TotalAmount:     &paid,
+		}
+		mockAPI.On("CanTransition", PaymentActionSuccess).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(session, nil).Once()
+		mockAPI.On("Transition", PaymentActionSuccess).Return(nil).Once()
+		mockSvc.On("IssueReceipt", mock.Anything, mock.MatchedBy(func(req paymentsv2.IssueReceiptRequest) bool {
+			return len(req.Breakdown) == 1 && req.Breakdown[0].Amount.Equal(paid)
+		})).Return(&paymentsv2.PaymentReceipt{ReceiptNumber: "NSW-RC-2026-000002", ReferenceNumber: "NSW-PR-2026-AB234"}, nil).Once()
+		mockAPI.On("WriteToLocalStore", paymentStoreReceipt, mock.AnythingOfType("*plugin.PaymentReceipt")).Return(nil).Once()
+
+		_, err := task.Execute(context.Background(), &ExecutionRequest{Action: PaymentActionSuccess})
+
+		require.NoError(t, err)
+		mockAPI.AssertExpectations(t)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("AgencyNameFromConfig", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		// A session created before the config named the agency.
+		mockAPI.On("CanTransition", PaymentActionSuccess).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(PaymentSession{ReferenceNumber: "NSW-PR-2026-AB234"}, nil).Once()
+		mockAPI.On("Transition", PaymentActionSuccess).Return(nil).Once()
+		mockSvc.On("IssueReceipt", mock.Anything, mock.MatchedBy(func(req paymentsv2.IssueReceiptRequest) bool {
+			return req.AgencyName == "Sri Lanka Customs"
+		})).Return(&paymentsv2.PaymentReceipt{ReceiptNumber: "NSW-RC-2026-000003", ReferenceNumber: "NSW-PR-2026-AB234"}, nil).Once()
+		mockAPI.On("WriteToLocalStore", paymentStoreReceipt, mock.AnythingOfType("*plugin.PaymentReceipt")).Return(nil).Once()
+
+		_, err := task.Execute(context.Background(), &ExecutionRequest{Action: PaymentActionSuccess})
+
+		require.NoError(t, err)
+		mockAPI.AssertExpectations(t)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("LateConfirmationReceiptsItsAttempt", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		// The trader switched methods: the live session charges 120 in USD, the confirmed
+		// earlier attempt charged 80 in LKR. Stored attempts come back as generic JSON.
+		current := decimal.NewFromInt(120)
+		session := PaymentSession{
+			ReferenceNumber: "NSW-PR-2026-NEW01",
+			Conversion:      &paymentsv2.CurrencyConversion{Currency: "USD", BaseCurrency: "LKR", Rate: decimal.RequireFromString("299.5")},
+			Breakdown:       []ResolvedBreakdownItem{{Description: "Fee", Category: CategoryAddition, Type: TypeFixed, Quantity: decimal.NewFromInt(1), UnitPrice: current, Amount: current}},
+			TotalAmount:     &current,
+		}
+		attempts := []any{
+			map[string]any{
+				"referenceNumber": "NSW-PR-2026-OLD01",
+				"totalAmount":     "80",
+				"breakdown": []any{map[string]any{
+					"description": "Fee", "category": "ADDITION", "type": "FIXED", "quantity": "1", "unitPrice": "80", "amount": "80",
+				}},
+			},
+			map[string]any{"referenceNumber": "NSW-PR-2026-NEW01", "totalAmount": "120"},
+		}
+		mockAPI.On("CanTransition", PaymentActionSuccess).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(session, nil).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreAttempts).Return(attempts, nil).Once()
+		var confirmed *PaymentSession
+		mockAPI.On("WriteToLocalStore", paymentStoreSession, mock.AnythingOfType("*plugin.PaymentSession")).
+			Run(func(args mock.Arguments) { confirmed = args.Get(1).(*PaymentSession) }).Return(nil).Once()
+		mockSvc.On("IssueReceipt", mock.Anything, mock.MatchedBy(func(req paymentsv2.IssueReceiptRequest) bool {
+			return req.ReferenceNumber == "NSW-PR-2026-OLD01" && len(req.Breakdown) == 1 && req.Breakdown[0].Amount.Equal(decimal.NewFromInt(80))
+		})).Return(&paymentsv2.PaymentReceipt{ReceiptNumber: "NSW-RC-2026-000004", ReferenceNumber: "NSW-PR-2026-OLD01"}, nil).Once()
+		mockAPI.On("WriteToLocalStore", paymentStoreReceipt, mock.AnythingOfType("*plugin.PaymentReceipt")).Return(nil).Once()
+		mockAPI.On("Transition", PaymentActionSuccess).Return(nil).Once()
+
+		req := &ExecutionRequest{Action: PaymentActionSuccess, Content: map[string]any{"referenceNumber": "NSW-PR-2026-OLD01", "amountPaid": "80"}}
+		_, err := task.Execute(context.Background(), req)
+
+		require.NoError(t, err)
+		require.NotNil(t, confirmed)
+		assert.Equal(t, "NSW-PR-2026-OLD01", confirmed.ReferenceNumber)
+		assert.Nil(t, confirmed.Conversion)
+		require.NotNil(t, confirmed.TotalAmount)
+		assert.True(t, decimal.NewFromInt(80).Equal(*confirmed.TotalAmount))
+		mockAPI.AssertExpectations(t)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("LateConfirmationWithoutSnapshot", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		// An attempt initiated before snapshots were kept: the receipt is not itemised with
+		// the live session's fees, and the total is the confirmed amount.
+		current := decimal.NewFromInt(120)
+		session := PaymentSession{
+			ReferenceNumber: "NSW-PR-2026-NEW01",
+			Breakdown:       []ResolvedBreakdownItem{{Description: "Fee", Amount: current}},
+			TotalAmount:     &current,
+		}
+		mockAPI.On("CanTransition", PaymentActionSuccess).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(session, nil).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreAttempts).Return(nil, nil).Once()
+		mockAPI.On("GetTaskID").Return("task-123").Maybe()
+		var confirmed *PaymentSession
+		mockAPI.On("WriteToLocalStore", paymentStoreSession, mock.AnythingOfType("*plugin.PaymentSession")).
+			Run(func(args mock.Arguments) { confirmed = args.Get(1).(*PaymentSession) }).Return(nil).Once()
+		mockSvc.On("IssueReceipt", mock.Anything, mock.MatchedBy(func(req paymentsv2.IssueReceiptRequest) bool {
+			return req.ReferenceNumber == "NSW-PR-2026-OLD01" && len(req.Breakdown) == 0
+		})).Return(&paymentsv2.PaymentReceipt{ReceiptNumber: "NSW-RC-2026-000005", ReferenceNumber: "NSW-PR-2026-OLD01"}, nil).Once()
+		mockAPI.On("WriteToLocalStore", paymentStoreReceipt, mock.AnythingOfType("*plugin.PaymentReceipt")).Return(nil).Once()
+		mockAPI.On("Transition", PaymentActionSuccess).Return(nil).Once()
+
+		req := &ExecutionRequest{Action: PaymentActionSuccess, Content: map[string]any{"referenceNumber": "NSW-PR-2026-OLD01", "amountPaid": "80"}}
+		_, err := task.Execute(context.Background(), req)
 
+		require.NoError(t, err)
+		require.NotNil(t, confirmed.TotalAmount)
+		assert.True(t, decimal.NewFromInt(80).Equal(*confirmed.TotalAmount))
+		assert.Empty(t, confirmed.Breakdown)
+		mockAPI.AssertExpectations(t)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("ReceiptFailureFailsConfirmation", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		// Late confirmation: the session was rotated after the attempt failed.
+		mockAPI.On("CanTransition", PaymentActionSuccess).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(PaymentSession{TransactionID: "txn-2"}, nil).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreAttempts).Return([]PaymentAttempt{{ReferenceNumber: "NSW-PR-2026-OLD01", TotalAmount: decimal.NewFromInt(100)}}, nil).Once()
+		mockAPI.On("WriteToLocalStore", paymentStoreSession, mock.MatchedBy(func(s *PaymentSession) bool {
+			return s.ReferenceNumber == "NSW-PR-2026-OLD01"
+		})).Return(nil).Once()
+		mockSvc.On("IssueReceipt", mock.Anything, mock.Anything).Return(nil, errors.New("storage down")).Once()
+
+		req := &ExecutionRequest{Action: PaymentActionSuccess, Content: map[string]any{"referenceNumber": "NSW-PR-2026-OLD01"}}
+		resp, err := task.Execute(context.Background(), req)
+
+		assert.ErrorContains(t, err, "storage down")
+		assert.Nil(t, resp)
+		mockAPI.AssertNotCalled(t, "Transition", PaymentActionSuccess)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("ReceiptsDisabled", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		mockAPI.On("CanTransition", PaymentActionSuccess).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(PaymentSession{ReferenceNumber: "NSW-PR-2026-AB234"}, nil).Once()
+		mockSvc.On("IssueReceipt", mock.Anything, mock.Anything).Return(nil, paymentsv2.ErrReceiptsDisabled).Once()
+		mockAPI.On("Transition", PaymentActionSuccess).Return(nil).Once()
+
+		resp, err := task.Execute(context.Background(), &ExecutionRequest{Action: PaymentActionSuccess})
+
+		require.NoError(t, err)
+		assert.True(t, resp.ApiResponse.Success)
 		mockAPI.AssertExpectations(t)
 	})
 
@@ -514,6 +910,24 @@ func TestPaymentExecute_PaymentSuccess(t *testing.T) {
 		mockAPI.AssertExpectations(t)
 	})
 
+	t.Run("RedeliveredAfterCompletion", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		mockAPI.On("CanTransition", PaymentActionSuccess).Return(false).Once()
+		mockAPI.On("GetPluginState").Return("COMPLETED").Once()
+
+		req := &ExecutionRequest{Action: PaymentActionSuccess, Content: map[string]any{"referenceNumber": "NSW-PR-2026-AB234"}}
+		resp, err := task.Execute(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.True(t, resp.ApiResponse.Success)
+		mockSvc.AssertNotCalled(t, "IssueReceipt", mock.Anything, mock.Anything)
+		mockAPI.AssertExpectations(t)
+	})
+
 	t.Run("TransitionError", func(t *testing.T) {
 		mockAPI := new(MockAPI)
 		mockSvc := new(MockPaymentService)
@@ -521,6 +935,7 @@ func TestPaymentExecute_PaymentSuccess(t *testing.T) {
 		task.Init(mockAPI)
 
 		mockAPI.On("CanTransition", PaymentActionSuccess).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(PaymentSession{}, nil).Once()
 		mockAPI.On("Transition", PaymentActionSuccess).Return(errors.New("transition failed")).Once()
 
 		req := &ExecutionRequest{Action: PaymentActionSuccess}
@@ -693,6 +1108,94 @@ func TestPaymentExecute_PaymentFailed(t *testing.T) {
 
 		mockAPI.AssertExpectations(t)
 	})
+
+	t.Run("SupersededReference", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		initiatedAt := time.Now().Add(-1 * time.Minute)
+		session := PaymentSession{
+			TransactionID:   "txn-current",
+			ReferenceNumber: "NSW-PR-2026-NEW22",
+			GeneratedAt:     time.Now().Add(-2 * time.Minute),
+			InitiatedAt:     &initiatedAt,
+		}
+
+		mockAPI.On("CanTransition", PaymentActionFailed).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(session, nil).Once()
+
+		req := &ExecutionRequest{
+			Action:  PaymentActionFailed,
+			Content: map[string]any{"referenceNumber": "NSW-PR-2026-OLD22"},
+		}
+		resp, err := task.Execute(context.Background(), req)
+
+		assert.NoError(t, err)
+		assert.True(t, resp.ApiResponse.Success)
+		assert.Contains(t, resp.Message, "superseded")
+		mockAPI.AssertNotCalled(t, "Transition", PaymentActionFailed)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("AlreadyResolvedAttempt", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		mockAPI.On("CanTransition", PaymentActionFailed).Return(false).Once()
+
+		req := &ExecutionRequest{
+			Action:  PaymentActionFailed,
+			Content: map[string]any{"referenceNumber": "NSW-PR-2026-OLD22", "status": "EXPIRED"},
+		}
+		resp, err := task.Execute(context.Background(), req)
+
+		assert.NoError(t, err)
+		assert.True(t, resp.ApiResponse.Success)
+		assert.Contains(t, resp.Message, "already resolved")
+		mockAPI.AssertNotCalled(t, "Transition", PaymentActionFailed)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("ExpiredRecordedAsExpired", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		initiatedAt := time.Now().Add(-2 * time.Minute)
+		session := PaymentSession{
+			TransactionID:   "txn-789",
+			ReferenceNumber: "NSW-PR-2026-AB234",
+			GeneratedAt:     time.Now().Add(-3 * time.Minute),
+			InitiatedAt:     &initiatedAt,
+		}
+
+		mockAPI.On("CanTransition", PaymentActionFailed).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(session, nil).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreTransactions).Return(nil, nil).Once()
+		var capturedTxns []PaymentTransaction
+		mockAPI.On("WriteToLocalStore", paymentStoreTransactions, mock.AnythingOfType("[]plugin.PaymentTransaction")).
+			Run(func(args mock.Arguments) {
+				capturedTxns = args.Get(1).([]PaymentTransaction)
+			}).Return(nil).Once()
+		mockAPI.On("WriteToLocalStore", paymentStoreSession, mock.AnythingOfType("*plugin.PaymentSession")).Return(nil).Once()
+		mockAPI.On("Transition", PaymentActionFailed).Return(nil).Once()
+
+		req := &ExecutionRequest{
+			Action:  PaymentActionFailed,
+			Content: map[string]any{"referenceNumber": "NSW-PR-2026-AB234", "status": "EXPIRED"},
+		}
+		_, err := task.Execute(context.Background(), req)
+
+		assert.NoError(t, err)
+		require.Len(t, capturedTxns, 1)
+		assert.Equal(t, "EXPIRED", capturedTxns[0].Status)
+		mockAPI.AssertExpectations(t)
+	})
 }
 
 func TestPaymentHelpers_readSession(t *testing.T) {
@@ -897,12 +1400,13 @@ func TestPaymentExecute_UnknownAction(t *testing.T) {
 // ── Helper ────────────────────────────────────────────────────────────────────
 
 // newTestPaymentTask creates a PaymentTask with a standard test configuration.
-func newTestPaymentTask(mockSvc payments.PaymentService) *PaymentTask {
+func newTestPaymentTask(mockSvc paymentsv2.PaymentService) *PaymentTask {
 	return &PaymentTask{
 		config: PaymentConfig{
 			Currency: "USD",
 			TTL:      300, // 5 minutes
 			OrgID:    "CUSTOMS",
+			OrgName:  "Sri Lanka Customs",
 			Breakdown: []BreakdownItem{
 				{
 					Description: "Test Fee",
@@ -915,3 +1419,138 @@ func newTestPaymentTask(mockSvc payments.PaymentService) *PaymentTask {
 		paymentService: mockSvc,
 	}
 }
+
+func TestPaymentGetRenderInfo_CompletedWithRefunds(t *testing.T) {
+	mockAPI := new(MockAPI)
+	mockSvc := new(MockPaymentService)
+	task := newTestPaymentTask(mockSvc)
+	task.Init(mockAPI)
+
+	// Stored refunds come back as generic JSON after a cache miss.
+	stored := []any{
+		map[string]any{"refundId": "rf-1", "referenceNumber": "REF-1", "amount": "30", "currency": "USD", "paymentStatus": "PARTIALLY_REFUNDED"},
+		map[string]any{"refundId": "rf-2", "referenceNumber": "REF-1", "amount": "70", "currency": "USD", "paymentStatus": "REFUNDED"},
+	}
+	mockAPI.On("GetPluginState").Return("COMPLETED")
+	mockAPI.On("GetTaskState").Return(Completed)
+	mockAPI.On("ReadFromLocalStore", paymentStoreRefunds).Return(stored, nil)
+	mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(PaymentSession{TransactionID: "txn-1"}, nil)
+	mockAPI.On("ReadFromLocalStore", paymentStoreReceipt).Return(nil, nil)
+
+	resp, err := task.GetRenderInfo(context.Background())
+	require.NoError(t, err)
+
+	content := resp.Data.(GetRenderInfoResponse).Content.(PaymentRenderContent)
+	require.Len(t, content.Refunds, 2)
+	assert.Equal(t, "rf-2", content.Refunds[1].RefundID)
+	require.NotNil(t, content.RefundedAmount)
+	assert.True(t, decimal.NewFromInt(100).Equal(*content.RefundedAmount))
+
+	mockAPI.AssertExpectations(t)
+}
+
+func TestPaymentExecute_PaymentRefunded(t *testing.T) {
+	refundContent := map[string]any{
+		"refundId":        "rf-1",
+		"referenceNumber": "REF-1",
+		"refundedAmount":  "30.00",
+		"currency":        "USD",
+		"status":          "PARTIALLY_REFUNDED",
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		mockAPI.On("CanTransition", PaymentActionRefunded).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreRefunds).Return(nil, nil).Once()
+		mockAPI.On("WriteToLocalStore", paymentStoreRefunds, mock.MatchedBy(func(r []PaymentRefund) bool {
+			return len(r) == 1 && r[0].RefundID == "rf-1" && r[0].Amount.Equal(decimal.NewFromInt(30)) && r[0].PaymentStatus == "PARTIALLY_REFUNDED"
+		})).Return(nil).Once()
+		mockAPI.On("Transition", PaymentActionRefunded).Return(nil).Once()
+
+		resp, err := task.Execute(context.Background(), &ExecutionRequest{Action: PaymentActionRefunded, Content: refundContent})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Payment refund recorded", resp.Message)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("AlreadyRecorded", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		mockAPI.On("CanTransition", PaymentActionRefunded).Return(true).Once()
+		mockAPI.On("ReadFromLocalStore", paymentStoreRefunds).Return([]PaymentRefund{{RefundID: "rf-1"}}, nil).Once()
+
+		resp, err := task.Execute(context.Background(), &ExecutionRequest{Action: PaymentActionRefunded, Content: refundContent})
+
+		require.NoError(t, err)
+		assert.True(t, resp.ApiResponse.Success)
+		mockAPI.AssertNotCalled(t, "WriteToLocalStore", mock.Anything, mock.Anything)
+		mockAPI.AssertNotCalled(t, "Transition", mock.Anything)
+	})
+
+	t.Run("InvalidTransition", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		mockAPI.On("CanTransition", PaymentActionRefunded).Return(false).Once()
+		mockAPI.On("GetPluginState").Return("IN_PROGRESS").Once()
+
+		resp, err := task.Execute(context.Background(), &ExecutionRequest{Action: PaymentActionRefunded, Content: refundContent})
+
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("MissingRefundID", func(t *testing.T) {
+		mockAPI := new(MockAPI)
+		mockSvc := new(MockPaymentService)
+		task := newTestPaymentTask(mockSvc)
+		task.Init(mockAPI)
+
+		mockAPI.On("CanTransition", PaymentActionRefunded).Return(true).Once()
+
+		_, err := task.Execute(context.Background(), &ExecutionRequest{Action: PaymentActionRefunded, Content: map[string]any{"refundedAmount": "10"}})
+
+		assert.ErrorContains(t, err, "refundId is required")
+	})
+}
+
+func TestPaymentGetRenderInfo_CompletedShowsPaidSnapshot(t *testing.T) {
+	mockAPI := new(MockAPI)
+	mockSvc := new(MockPaymentService)
+	task := newTestPaymentTask(mockSvc)
+	task.Init(mockAPI)
+
+	paid := decimal.NewFromInt(80)
+	session := PaymentSession{
+		ReferenceNumber: "NSW-PR-2026-AB234",
+		Breakdown:       []ResolvedBreakdownItem{{Description: "Fee", Amount: paid}},
+		TotalAmount:     &paid,
+	}
+	mockAPI.On("GetPluginState").Return("COMPLETED")
+	mockAPI.On("GetTaskState").Return(Completed)
+	mockAPI.On("ReadFromLocalStore", paymentStoreRefunds).Return(nil, nil)
+	mockAPI.On("ReadFromLocalStore", paymentStoreSession).Return(session, nil)
+	mockAPI.On("ReadFromLocalStore", paymentStoreReceipt).Return(nil, nil)
+
+	resp, err := task.GetRenderInfo(context.Background())
+	require.NoError(t, err)
+
+	content := resp.Data.(GetRenderInfoResponse).Content.(PaymentRenderContent)
+	assert.True(t, paid.Equal(content.TotalAmount))
+	require.Len(t, content.Breakdown, 1)
+	// Viewing never issues a receipt; that happens only on confirmation.
+	assert.Nil(t, content.Receipt)
+	mockSvc.AssertNotCalled(t, "IssueReceipt", mock.Anything, mock.Anything)
+	mockAPI.AssertNotCalled(t, "WriteToLocalStore", mock.Anything, mock.Anything)
+}
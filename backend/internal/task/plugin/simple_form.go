@@ -11,6 +11,7 @@ import (
 
 	"github.com/OpenNSW/nsw/internal/config"
 	"github.com/OpenNSW/nsw/internal/form"
+	formmodel "github.com/OpenNSW/nsw/internal/form/model"
 	"github.com/OpenNSW/nsw/pkg/jsonform"
 	"github.com/OpenNSW/nsw/pkg/remote"
 )
@@ -25,6 +26,12 @@ const (
 	SimpleFormActionOgaFeedback = "OGA_VERIFICATION_FEEDBACK"
 )
 
+// Local store keys under which SimpleForm keeps the OGA's verdict and its feedback rounds.
+const (
+	SimpleFormStoreKeyOgaResponse = "ogaResponse"
+	SimpleFormStoreKeyOgaFeedback = "ogaFeedback"
+)
+
 // Resolved FSM actions for conditional transitions.
 // The plugin's resolveAction method maps public API actions to these before FSM dispatch.
 const (
@@ -50,6 +57,9 @@ const (
 
 const TasksAPIPath = "/api/v1/tasks"
 
+// FormVersionStoreKey is the local store key holding the form version a task is pinned to.
+const FormVersionStoreKey = "form:version"
+
 // submissionFailedErr wraps an HTTP submission error to signal that Execute should
 // transition the plugin to SUBMISSION_FAILED. This distinguishes a real external-call
 // failure (where the remote system may have already recorded the data) from earlier
@@ -59,6 +69,15 @@ type submissionFailedErr struct{ cause error }
 func (e submissionFailedErr) Error() string { return e.cause.Error() }
 func (e submissionFailedErr) Unwrap() error { return e.cause }
 
+// formValidationErr signals that the submitted data failed schema validation. Execute
+// returns the response with its field errors to the caller without an error and leaves
+// the plugin in its current state, since the problem is not on the system side.
+type formValidationErr struct{ count int }
+
+func (e formValidationErr) Error() string {
+	return fmt.Sprintf("form data failed schema validation with %d error(s)", e.count)
+}
+
 // Config contains the JSON Form configuration
 type Config struct {
 	FormID                  string            `json:"formId"`                  // Unique identifier for the form
@@ -133,6 +152,7 @@ type SimpleForm struct {
 	cfg           *config.Config
 	formService   form.FormService
 	remoteManager *remote.Manager
+	formVersion   string // version of the form definition loaded from the registry
 }
 
 // NewSimpleFormFSM returns the state graph for SimpleForm.
@@ -231,6 +251,7 @@ func (s *SimpleForm) GetRenderInfo(ctx context.Context) (*ApiResponse, error) {
 			"uiSchema": s.config.UISchema,
 			"formData": formData,
 			"schema":   s.config.Schema,
+			"version":  s.formVersion,
 		},
 	}
 
@@ -238,9 +259,9 @@ func (s *SimpleForm) GetRenderInfo(ctx context.Context) (*ApiResponse, error) {
 		s.attachFormDisplay(ctx, content, "submissionResponse", displayFormID(s.config.Submission.Response), "submissionResponseForm")
 	}
 	if s.config.Callback != nil {
-		s.attachFormDisplay(ctx, content, "ogaResponse", displayFormID(s.config.Callback.Response), "ogaReviewForm")
+		s.attachFormDisplay(ctx, content, SimpleFormStoreKeyOgaResponse, displayFormID(s.config.Callback.Response), "ogaReviewForm")
 	}
-	if feedbackData, err := s.api.ReadFromLocalStore("ogaFeedback"); err == nil && feedbackData != nil {
+	if feedbackData, err := s.api.ReadFromLocalStore(SimpleFormStoreKeyOgaFeedback); err == nil && feedbackData != nil {
 		content["ogaFeedback"] = feedbackData
 	}
 
@@ -280,6 +301,9 @@ func (s *SimpleForm) Execute(ctx context.Context, request *ExecutionRequest) (*E
 		return nil, fmt.Errorf("fsm: action %q not permitted in state %q", request.Action, s.api.GetPluginState())
 	}
 	resp, err := s.dispatch(ctx, action, request.Content)
+	if errors.As(err, &formValidationErr{}) {
+		return resp, nil
+	}
 	if err != nil {
 		// If the HTTP call to the external system failed, transition to SUBMISSION_FAILED
 		// so the task has a recoverable state rather than being stuck (zombie state).
@@ -382,10 +406,6 @@ func (s *SimpleForm) submitHandler(ctx context.Context, content any) (*Execution
 		}, err
 	}
 
-	if err := s.api.WriteToLocalStore("trader:form", formData); err != nil {
-		slog.Warn("failed to write form data to local store", "error", err)
-	}
-
 	if err := s.populateFromRegistry(ctx); err != nil {
 		return &ExecutionResponse{
 			ApiResponse: &ApiResponse{
@@ -395,6 +415,34 @@ func (s *SimpleForm) submitHandler(ctx context.Context, content any) (*Execution
 		}, err
 	}
 
+	// The portal validates too, but the submission is forwarded to OGAs as-is, so the
+	// schema is enforced here before anything is stored or sent.
+	fieldErrors, err := jsonform.Validate(s.config.Schema, formData)
+	if err != nil {
+		return &ExecutionResponse{
+			ApiResponse: &ApiResponse{
+				Success: false,
+				Error:   &ApiError{Code: "INVALID_FORM_DATA", Message: "Failed to parse schema."},
+			},
+		}, err
+	}
+	if len(fieldErrors) > 0 {
+		return &ExecutionResponse{
+			ApiResponse: &ApiResponse{
+				Success: false,
+				Error: &ApiError{
+					Code:    "FORM_VALIDATION_FAILED",
+					Message: "Form data does not match the form schema.",
+					Details: fieldErrors,
+				},
+			},
+		}, formValidationErr{count: len(fieldErrors)}
+	}
+
+	if err := s.api.WriteToLocalStore("trader:form", formData); err != nil {
+		slog.Warn("failed to write form data to local store", "error", err)
+	}
+
 	var parsedSchema jsonform.JSONSchema
 	if err := json.Unmarshal(s.config.Schema, &parsedSchema); err != nil {
 		return &ExecutionResponse{
@@ -604,7 +652,7 @@ func (s *SimpleForm) evaluateEmissions() *string {
 // localStoreKeys lists every key written to the local store during a SimpleForm lifecycle.
 // They are namespaced as top-level keys in the context map, so condition field paths take
 // the form "<storeKey>.<field>", e.g. "ogaResponse.decision" or "trader:form.species".
-var localStoreKeys = []string{"trader:form", "submissionResponse", "ogaResponse"}
+var localStoreKeys = []string{"trader:form", "submissionResponse", SimpleFormStoreKeyOgaResponse}
 
 // buildLocalContext reads all known local store entries and assembles them into a single
 // namespaced map for emission evaluation.
@@ -657,7 +705,7 @@ func (s *SimpleForm) ogaFeedbackHandler(_ context.Context, content any) (*Execut
 		Round:     len(history) + 1,
 	})
 
-	if err := s.api.WriteToLocalStore("ogaFeedback", history); err != nil {
+	if err := s.api.WriteToLocalStore(SimpleFormStoreKeyOgaFeedback, history); err != nil {
 		return nil, err
 	}
 	return &ExecutionResponse{
@@ -669,7 +717,7 @@ func (s *SimpleForm) ogaFeedbackHandler(_ context.Context, content any) (*Execut
 // readOGAFeedbackHistory reads and deserializes the OGA feedback history from local store.
 // It handles the JSON round-trip that occurs on a cache miss ([]interface{} → []OGAFeedbackEntry).
 func (s *SimpleForm) readOGAFeedbackHistory() ([]OGAFeedbackEntry, error) {
-	raw, err := s.api.ReadFromLocalStore("ogaFeedback")
+	raw, err := s.api.ReadFromLocalStore(SimpleFormStoreKeyOgaFeedback)
 	if err != nil || raw == nil {
 		return nil, err
 	}
@@ -690,7 +738,7 @@ func (s *SimpleForm) parseAndStoreOgaResponse(content any) (map[string]any, erro
 	if err != nil {
 		return nil, fmt.Errorf("invalid verification data: %w", err)
 	}
-	if err := s.api.WriteToLocalStore("ogaResponse", verificationData); err != nil {
+	if err := s.api.WriteToLocalStore(SimpleFormStoreKeyOgaResponse, verificationData); err != nil {
 		return nil, err
 	}
 	return verificationData, nil
@@ -703,7 +751,7 @@ func (s *SimpleForm) resolveFormData(ctx context.Context, state SimpleFormState)
 	switch state {
 	case SimpleFormInitialized:
 		return s.prepopulateFormData(ctx, s.config.FormData)
-	case TraderSavedAsDraft, TraderSubmitted, OGAAcknowledged, OGAFeedbackProvided, OGAReviewed, SubmissionFailed:
+	case TraderSavedAsDraft, TraderSubmitted, OGAAcknowledged, OGAFeedbackProvided, OGAReviewed, SubmissionFailed, PluginStateSLABreached:
 		return s.api.ReadFromLocalStore("trader:form")
 	default:
 		return s.config.FormData, nil
@@ -744,17 +792,39 @@ func (s *SimpleForm) attachFormDisplay(ctx context.Context, content map[string]a
 	}
 }
 
+// populateFromRegistry loads the form definition the task is pinned to. The first load
+// pins the form's current version in the local store, so later edits to the form do not
+// change what this task renders or validates against.
 func (s *SimpleForm) populateFromRegistry(ctx context.Context) error {
 	if s.formService == nil {
 		return fmt.Errorf("form service is required to populate form definition")
 	}
-	def, err := s.formService.GetFormByID(ctx, s.config.FormID)
+
+	pinned, err := s.api.ReadFromLocalStore(FormVersionStoreKey)
 	if err != nil {
-		return fmt.Errorf("failed to get form definition for formId %s: %w", s.config.FormID, err)
+		return fmt.Errorf("failed to read pinned form version: %w", err)
+	}
+
+	var def *formmodel.FormResponse
+	if version, _ := pinned.(string); version != "" {
+		def, err = s.formService.GetFormVersion(ctx, s.config.FormID, version)
+		if err != nil {
+			return fmt.Errorf("failed to get version %s of form definition for formId %s: %w", version, s.config.FormID, err)
+		}
+	} else {
+		def, err = s.formService.GetFormByID(ctx, s.config.FormID)
+		if err != nil {
+			return fmt.Errorf("failed to get form definition for formId %s: %w", s.config.FormID, err)
+		}
+		if err := s.api.WriteToLocalStore(FormVersionStoreKey, def.Version); err != nil {
+			return fmt.Errorf("failed to pin form version: %w", err)
+		}
 	}
+
 	s.config.Title = def.Name
 	s.config.Schema = def.Schema
 	s.config.UISchema = def.UISchema
+	s.formVersion = def.Version
 	return nil
 }
 
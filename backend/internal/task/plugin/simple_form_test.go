@@ -8,6 +8,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	formmodel "github.com/OpenNSW/nsw/internal/form/model"
+	"github.com/OpenNSW/nsw/pkg/jsonform"
 )
 
 // MockAPI is a mock implementation of the API interface for testing plugins
@@ -148,3 +152,116 @@ func TestSimpleForm_Execute_SaveAsDraft(t *testing.T) {
 		mockAPI.AssertExpectations(t)
 	})
 }
+
+func TestSimpleForm_Execute_SubmitValidation(t *testing.T) {
+	formService := &mockFormService{
+		getFormVersion: func(ctx context.Context, formID, version string) (*formmodel.FormResponse, error) {
+			return &formmodel.FormResponse{
+				ID:      formID,
+				Name:    "Export Declaration",
+				Version: version,
+				Schema: json.RawMessage(`{
+					"type": "object",
+					"required": ["exporter"],
+					"properties": {
+						"exporter": {"type": "string"},
+						"email": {"type": "string", "format": "email"}
+					}
+				}`),
+			}, nil
+		},
+	}
+
+	newForm := func(t *testing.T) (*SimpleForm, *MockAPI) {
+		sf, err := NewSimpleForm(json.RawMessage(`{"formId": "export-declaration"}`), nil, formService, nil)
+		assert.NoError(t, err)
+		mockAPI := new(MockAPI)
+		mockAPI.On("ReadFromLocalStore", FormVersionStoreKey).Return("1.0", nil)
+		sf.Init(mockAPI)
+		return sf, mockAPI
+	}
+
+	t.Run("Invalid data is rejected with field errors", func(t *testing.T) {
+		sf, mockAPI := newForm(t)
+		req := &ExecutionRequest{
+			Action:  SimpleFormActionSubmit,
+			Content: map[string]interface{}{"email": "not-an-email"},
+		}
+
+		mockAPI.On("CanTransition", simpleFormFSMSubmitComplete).Return(true).Once()
+		// Neither the submission nor the transition may happen.
+
+		resp, err := sf.Execute(context.Background(), req)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, resp)
+		assert.False(t, resp.ApiResponse.Success)
+		assert.Equal(t, "FORM_VALIDATION_FAILED", resp.ApiResponse.Error.Code)
+		fieldErrors, ok := resp.ApiResponse.Error.Details.([]jsonform.FieldError)
+		assert.True(t, ok)
+		require.Len(t, fieldErrors, 2)
+		assert.ElementsMatch(t, []string{"exporter", "email"}, []string{fieldErrors[0].Field, fieldErrors[1].Field})
+
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("Valid data is submitted", func(t *testing.T) {
+		sf, mockAPI := newForm(t)
+		data := map[string]interface{}{"exporter": "Organic Farms", "email": "exports@example.com"}
+		req := &ExecutionRequest{Action: SimpleFormActionSubmit, Content: data}
+
+		mockAPI.On("CanTransition", simpleFormFSMSubmitComplete).Return(true).Once()
+		mockAPI.On("WriteToLocalStore", "trader:form", data).Return(nil).Once()
+		mockAPI.On("Transition", simpleFormFSMSubmitComplete).Return(nil).Once()
+
+		resp, err := sf.Execute(context.Background(), req)
+
+		assert.NoError(t, err)
+		assert.True(t, resp.ApiResponse.Success)
+
+		mockAPI.AssertExpectations(t)
+	})
+}
+
+func TestSimpleForm_PopulateFromRegistry_PinsFormVersion(t *testing.T) {
+	versions := map[string]string{
+		"1.0": `{"type": "object", "properties": {"exporter": {"type": "string"}}}`,
+		"2.0": `{"type": "object", "properties": {"exporter": {"type": "string"}, "vessel": {"type": "string"}}}`,
+	}
+	formService := &mockFormService{
+		getFormByID: func(ctx context.Context, formID string) (*formmodel.FormResponse, error) {
+			return &formmodel.FormResponse{ID: formID, Name: "Export Declaration", Version: "2.0", Schema: json.RawMessage(versions["2.0"])}, nil
+		},
+		getFormVersion: func(ctx context.Context, formID, version string) (*formmodel.FormResponse, error) {
+			return &formmodel.FormResponse{ID: formID, Name: "Export Declaration", Version: version, Schema: json.RawMessage(versions[version])}, nil
+		},
+	}
+
+	t.Run("First load pins the current version", func(t *testing.T) {
+		sf, err := NewSimpleForm(json.RawMessage(`{"formId": "export-declaration"}`), nil, formService, nil)
+		assert.NoError(t, err)
+		mockAPI := new(MockAPI)
+		sf.Init(mockAPI)
+
+		mockAPI.On("ReadFromLocalStore", FormVersionStoreKey).Return(nil, nil).Once()
+		mockAPI.On("WriteToLocalStore", FormVersionStoreKey, "2.0").Return(nil).Once()
+
+		assert.NoError(t, sf.populateFromRegistry(context.Background()))
+		assert.JSONEq(t, versions["2.0"], string(sf.config.Schema))
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("Pinned task keeps its version after a new one is published", func(t *testing.T) {
+		sf, err := NewSimpleForm(json.RawMessage(`{"formId": "export-declaration"}`), nil, formService, nil)
+		assert.NoError(t, err)
+		mockAPI := new(MockAPI)
+		sf.Init(mockAPI)
+
+		mockAPI.On("ReadFromLocalStore", FormVersionStoreKey).Return("1.0", nil).Once()
+
+		assert.NoError(t, sf.populateFromRegistry(context.Background()))
+		assert.JSONEq(t, versions["1.0"], string(sf.config.Schema))
+		assert.Equal(t, "1.0", sf.formVersion)
+		mockAPI.AssertNotCalled(t, "WriteToLocalStore", FormVersionStoreKey, mock.Anything)
+	})
+}
@@ -36,6 +36,10 @@ const (
 	waitForEventFSMComplete    = "OGA_VERIFICATION"
 )
 
+// WaitForEventActionRetry is the public action that re-notifies the external service
+// after a failed notification.
+const WaitForEventActionRetry = waitForEventFSMRetry
+
 // WaitForEventDisplay holds optional UI display metadata for the portal
 type WaitForEventDisplay struct {
 	Title       any `json:"title"`
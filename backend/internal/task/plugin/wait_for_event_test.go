@@ -55,7 +55,8 @@ func (a *wfeAPI) calledWith(action string) bool {
 }
 
 type mockFormService struct {
-	getFormByID func(ctx context.Context, formID string) (*formmodel.FormResponse, error)
+	getFormByID    func(ctx context.Context, formID string) (*formmodel.FormResponse, error)
+	getFormVersion func(ctx context.Context, formID, version string) (*formmodel.FormResponse, error)
 }
 
 func (m *mockFormService) GetFormByID(ctx context.Context, formID string) (*formmodel.FormResponse, error) {
@@ -65,6 +66,41 @@ func (m *mockFormService) GetFormByID(ctx context.Context, formID string) (*form
 	return nil, nil
 }
 
+func (m *mockFormService) GetFormVersion(ctx context.Context, formID, version string) (*formmodel.FormResponse, error) {
+	if m.getFormVersion != nil {
+		return m.getFormVersion(ctx, formID, version)
+	}
+	return nil, nil
+}
+
+func (m *mockFormService) ListFormVersions(ctx context.Context, formID string) ([]formmodel.FormVersion, error) {
+	return nil, nil
+}
+
+func (m *mockFormService) PublishFormVersion(ctx context.Context, formID, publishedBy string, req formmodel.PublishFormVersionRequest) (*formmodel.FormVersion, error) {
+	return nil, nil
+}
+
+func (m *mockFormService) ListForms(ctx context.Context) ([]formmodel.Form, error) {
+	return nil, nil
+}
+
+func (m *mockFormService) GetForm(ctx context.Context, formID string) (*formmodel.Form, error) {
+	return nil, nil
+}
+
+func (m *mockFormService) CreateForm(ctx context.Context, req formmodel.FormRequest) (*formmodel.Form, error) {
+	return nil, nil
+}
+
+func (m *mockFormService) UpdateForm(ctx context.Context, formID string, req formmodel.FormRequest) (*formmodel.Form, error) {
+	return nil, nil
+}
+
+func (m *mockFormService) DeleteForm(ctx context.Context, formID string) error {
+	return nil
+}
+
 func newWFETask(t *testing.T, serverURL string) (*WaitForEventTask, *wfeAPI) {
 	t.Helper()
 
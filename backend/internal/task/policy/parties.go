@@ -0,0 +1,141 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/organization"
+)
+
+// actingRoles are the organisation roles whose members act on consignments; viewers only read them.
+var actingRoles = []organization.Role{organization.RoleAdmin, organization.RoleClerk}
+
+// orgMember is a member of an organisation that is a party to a consignment.
+type orgMember struct {
+	UserID string            `gorm:"column:user_id"`
+	Role   organization.Role `gorm:"column:role"`
+}
+
+// splitByRole returns the IDs of the members who act on consignments and of those who only read them.
+func splitByRole(members []orgMember) (acting, viewing []string) {
+	for _, m := range members {
+		if slices.Contains(actingRoles, m.Role) {
+			acting = append(acting, m.UserID)
+		} else {
+			viewing = append(viewing, m.UserID)
+		}
+	}
+	return acting, viewing
+}
+
+// ErrWorkflowNotFound is returned when no consignment or pre-consignment has the workflow's ID.
+var ErrWorkflowNotFound = errors.New("workflow owner not found")
+
+// dbPartyResolver reads parties straight from the consignment and organisation tables.
+// Workflows share the ID of the consignment or pre-consignment they run for.
+type dbPartyResolver struct {
+	db *gorm.DB
+}
+
+// NewDBPartyResolver creates a PartyResolver backed by the consignment tables.
+func NewDBPartyResolver(db *gorm.DB) PartyResolver {
+	return &dbPartyResolver{db: db}
+}
+
+func (r *dbPartyResolver) ResolveParties(ctx context.Context, workflowID string) (*Parties, error) {
+	db := r.db.WithContext(ctx)
+	var rows []struct {
+		TraderID string  `gorm:"column:trader_id"`
+		CHAID    *string `gorm:"column:cha_id"`
+		CHAEmail *string `gorm:"column:cha_email"`
+	}
+	if err := db.
+		Table("consignments AS c").
+		Select("c.trader_id AS trader_id, c.cha_id AS cha_id, cha.email AS cha_email").
+		Joins("LEFT JOIN customs_house_agents AS cha ON cha.id = c.cha_id").
+		Where("c.id = ?", workflowID).
+		Limit(1).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read consignment: %w", err)
+	}
+	if len(rows) > 0 {
+		parties := &Parties{TraderID: rows[0].TraderID}
+		if rows[0].CHAEmail != nil {
+			parties.CHAEmail = *rows[0].CHAEmail
+		}
+		staff, err := traderStaff(db, parties.TraderID)
+		if err != nil {
+			return nil, err
+		}
+		delegates, err := delegates(db, workflowID, rows[0].CHAID)
+		if err != nil {
+			return nil, err
+		}
+		var staffViewers, delegateViewers []string
+		parties.TraderStaffIDs, staffViewers = splitByRole(staff)
+		parties.DelegateIDs, delegateViewers = splitByRole(delegates)
+		parties.ViewerIDs = append(staffViewers, delegateViewers...)
+		return parties, nil
+	}
+
+	var traderIDs []string
+	if err := db.
+		Table("pre_consignments").
+		Where("id = ?", workflowID).
+		Limit(1).
+		Pluck("trader_id", &traderIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to read pre-consignment: %w", err)
+	}
+	if len(traderIDs) > 0 {
+		staff, err := traderStaff(db, traderIDs[0])
+		if err != nil {
+			return nil, err
+		}
+		parties := &Parties{TraderID: traderIDs[0]}
+		parties.TraderStaffIDs, parties.ViewerIDs = splitByRole(staff)
+		return parties, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, workflowID)
+}
+
+// traderStaff returns the members of the trader organisations traderID belongs to.
+func traderStaff(db *gorm.DB, traderID string) ([]orgMember, error) {
+	var members []orgMember
+	if err := db.
+		Table("organization_members AS me").
+		Distinct("peer.user_id", "peer.role").
+		Joins("JOIN organizations o ON o.id = me.organization_id AND o.kind = ?", organization.KindTrader).
+		Joins("JOIN organization_members peer ON peer.organization_id = me.organization_id").
+		Where("me.user_id = ?", traderID).
+		Scan(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to read trader organizations: %w", err)
+	}
+	return members, nil
+}
+
+// delegates returns the members of the organisation bound to the consignment's CHA and of
+// the organisations holding an active delegation over the consignment.
+func delegates(db *gorm.DB, consignmentID string, chaID *string) ([]orgMember, error) {
+	delegated := db.
+		Table("consignment_delegations").
+		Select("organization_id").
+		Where("consignment_id = ? AND revoked_at IS NULL AND starts_at <= NOW() AND expires_at > NOW()", consignmentID)
+	query := db.
+		Table("organization_members AS m").
+		Distinct("m.user_id", "m.role").
+		Joins("JOIN organizations o ON o.id = m.organization_id")
+	if chaID != nil {
+		query = query.Where("o.cha_id = ? OR o.id IN (?)", *chaID, delegated)
+	} else {
+		query = query.Where("o.id IN (?)", delegated)
+	}
+	var members []orgMember
+	if err := query.Scan(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to read CHA delegates: %w", err)
+	}
+	return members, nil
+}
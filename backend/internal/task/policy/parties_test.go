@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockResolver(t *testing.T) (PartyResolver, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm: %v", err)
+	}
+	return NewDBPartyResolver(gormDB), mock
+}
+
+func TestDBPartyResolver_Consignment(t *testing.T) {
+	resolver, mock := newMockResolver(t)
+
+	mock.ExpectQuery(`SELECT c.trader_id AS trader_id, c.cha_id AS cha_id, cha.email AS cha_email FROM consignments AS c LEFT JOIN customs_house_agents`).
+		WithArgs("consignment-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"trader_id", "cha_id", "cha_email"}).AddRow("trader-1", "cha-1", "agent@cha.example.com"))
+	mock.ExpectQuery(`SELECT DISTINCT peer.user_id,peer.role FROM organization_members AS me JOIN organizations o .* WHERE me.user_id = \$2`).
+		WithArgs("trader", "trader-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role"}).AddRow("trader-1", "admin").AddRow("clerk-1", "clerk").AddRow("viewer-1", "viewer"))
+	mock.ExpectQuery(`SELECT DISTINCT m.user_id,m.role FROM organization_members AS m JOIN organizations o .* WHERE o.cha_id = \$1 OR o.id IN \(SELECT organization_id FROM "consignment_delegations" WHERE consignment_id = \$2 AND revoked_at IS NULL .*\)`).
+		WithArgs("cha-1", "consignment-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role"}).AddRow("delegate-1", "clerk").AddRow("delegate-viewer-1", "viewer"))
+
+	parties, err := resolver.ResolveParties(context.Background(), "consignment-1")
+	if err != nil {
+		t.Fatalf("expected parties, got %v", err)
+	}
+	if parties.TraderID != "trader-1" || parties.CHAEmail != "agent@cha.example.com" {
+		t.Fatalf("unexpected parties: %+v", parties)
+	}
+	if !slices.Equal(parties.TraderStaffIDs, []string{"trader-1", "clerk-1"}) || !slices.Equal(parties.DelegateIDs, []string{"delegate-1"}) {
+		t.Fatalf("unexpected organisation parties: %+v", parties)
+	}
+	if !slices.Equal(parties.ViewerIDs, []string{"viewer-1", "delegate-viewer-1"}) {
+		t.Fatalf("unexpected organisation viewers: %+v", parties)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDBPartyResolver_PreConsignment(t *testing.T) {
+	resolver, mock := newMockResolver(t)
+
+	mock.ExpectQuery(`FROM consignments AS c`).
+		WithArgs("pre-consignment-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"trader_id", "cha_id", "cha_email"}))
+	mock.ExpectQuery(`SELECT "trader_id" FROM "pre_consignments" WHERE id = \$1`).
+		WithArgs("pre-consignment-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"trader_id"}).AddRow("trader-1"))
+	mock.ExpectQuery(`FROM organization_members AS me`).
+		WithArgs("trader", "trader-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role"}).AddRow("clerk-1", "clerk"))
+
+	parties, err := resolver.ResolveParties(context.Background(), "pre-consignment-1")
+	if err != nil {
+		t.Fatalf("expected parties, got %v", err)
+	}
+	if parties.TraderID != "trader-1" || !slices.Equal(parties.TraderStaffIDs, []string{"clerk-1"}) || parties.DelegateIDs != nil {
+		t.Fatalf("unexpected parties: %+v", parties)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
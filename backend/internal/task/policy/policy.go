@@ -0,0 +1,344 @@
+// Package policy decides whether a principal may perform an action on a task.
+//
+// It sits between the task HTTP handler and the task manager: every action is mapped to
+// the kinds of principal allowed to send it, and traders and CHAs must additionally be
+// parties to the consignment (or pre-consignment) that owns the task's workflow, directly
+// or through an organisation (see package organization).
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/task/persistence"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+// PrincipalKind is a role a principal plays with respect to a task.
+type PrincipalKind string
+
+const (
+	// Trader is the user who owns the consignment or pre-consignment.
+	Trader PrincipalKind = "trader"
+	// CHA is a user acting for the customs house agent assigned to the consignment.
+	CHA PrincipalKind = "cha"
+	// Client is an M2M client, such as an OGA system.
+	Client PrincipalKind = "client"
+	// System is the platform acting on its own behalf; it is never produced by the HTTP auth middleware.
+	System PrincipalKind = "system"
+)
+
+// Rule lists who may invoke an action. When Client is allowed and ClientIDs is non-empty,
+// only those client IDs are. With CheckTaskCode, clients must also have been granted the
+// task's code (see auth.ClientContext.MayCompleteTask).
+type Rule struct {
+	Kinds         []PrincipalKind
+	ClientIDs     []string
+	CheckTaskCode bool
+}
+
+// DeniedError is returned when a principal may not perform an action. Reason is safe to
+// return to the caller.
+type DeniedError struct {
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	return "forbidden: " + e.Reason
+}
+
+func deny(format string, args ...any) error {
+	return &DeniedError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Parties are the users allowed to act on a workflow as its trader or CHA.
+type Parties struct {
+	TraderID string
+	// CHAEmail identifies the assigned CHA; CHA users are matched by email, as the
+	// consignment listing does. Empty for pre-consignments.
+	CHAEmail string
+	// TraderStaffIDs are the admins and clerks of the trader's organisations, who act as the trader.
+	TraderStaffIDs []string
+	// DelegateIDs are the admins and clerks of the organisation of the assigned CHA and of
+	// organisations holding an active delegation over the consignment, who act as the CHA.
+	DelegateIDs []string
+	// ViewerIDs are the viewers of those organisations, who read the workflow but do not act on it.
+	ViewerIDs []string
+}
+
+// PartyResolver resolves the parties of the consignment or pre-consignment behind a workflow.
+type PartyResolver interface {
+	ResolveParties(ctx context.Context, workflowID string) (*Parties, error)
+}
+
+// TaskReader reads the persisted record of a task.
+type TaskReader interface {
+	GetByID(id string) (*persistence.TaskInfo, error)
+}
+
+// DefaultRules maps every action that may be sent through the task API to the principals
+// allowed to send it. OGA verification actions are limited to ogaClientIDs. Payment
+// outcomes are reported by the payments pipeline only, as a system actor; no user or
+// client may mark a fee paid, failed or refunded.
+func DefaultRules(ogaClientIDs []string) map[string]Rule {
+	parties := Rule{Kinds: []PrincipalKind{Trader, CHA}}
+	oga := Rule{Kinds: []PrincipalKind{Client}, ClientIDs: ogaClientIDs, CheckTaskCode: true}
+	payments := Rule{Kinds: []PrincipalKind{System}}
+	return map[string]Rule{
+		plugin.SimpleFormActionDraft:       parties,
+		plugin.SimpleFormActionSubmit:      parties,
+		plugin.SimpleFormActionOgaVerify:   oga,
+		plugin.SimpleFormActionOgaFeedback: oga,
+		plugin.PaymentActionInitiate:       parties,
+		plugin.PaymentActionSuccess:        payments,
+		plugin.PaymentActionFailed:         payments,
+		plugin.PaymentActionRefunded:       payments,
+		plugin.WaitForEventActionRetry:     parties,
+	}
+}
+
+// Policy authorizes task actions.
+type Policy struct {
+	rules   map[string]Rule
+	tasks   TaskReader
+	parties PartyResolver
+}
+
+// New creates a Policy enforcing rules. Actions without a rule are denied.
+func New(rules map[string]Rule, tasks TaskReader, parties PartyResolver) *Policy {
+	return &Policy{rules: rules, tasks: tasks, parties: parties}
+}
+
+// AuthorizeExecute checks that the principal in ctx may perform action on taskID. It
+// returns a *DeniedError when the principal may not, and any other error when the check
+// itself could not be made.
+func (p *Policy) AuthorizeExecute(ctx context.Context, taskID, action string) error {
+	rule, ok := p.rules[action]
+	if !ok {
+		return deny("action %s cannot be performed through the task API", action)
+	}
+
+	authCtx := auth.GetAuthContext(ctx)
+	switch {
+	case authCtx == nil:
+		return deny("action %s requires an authenticated principal", action)
+
+	case authCtx.System != nil:
+		if slices.Contains(rule.Kinds, System) {
+			return nil
+		}
+		return deny("action %s cannot be performed by the system", action)
+
+	case authCtx.Client != nil:
+		if !slices.Contains(rule.Kinds, Client) {
+			return deny("action %s cannot be performed by a client", action)
+		}
+		if len(rule.ClientIDs) > 0 && !slices.Contains(rule.ClientIDs, authCtx.Client.ClientID) {
+			return deny("client %s is not allowed to perform action %s", authCtx.Client.ClientID, action)
+		}
+		if rule.CheckTaskCode {
+			taskCode, err := p.taskCode(taskID)
+			if err != nil {
+				return err
+			}
+			if !authCtx.Client.MayCompleteTask(taskCode) {
+				return deny("client %s is not allowed to complete tasks with code %q", authCtx.Client.ClientID, taskCode)
+			}
+		}
+		return nil
+
+	case authCtx.User != nil:
+		allowTrader, allowCHA := slices.Contains(rule.Kinds, Trader), slices.Contains(rule.Kinds, CHA)
+		if !allowTrader && !allowCHA {
+			return deny("action %s cannot be performed by a user", action)
+		}
+		parties, err := p.taskParties(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		if parties.actsFor(authCtx.User, allowTrader, allowCHA) {
+			return nil
+		}
+		return deny("user is not the trader, assigned CHA or their delegate for this task's consignment")
+	}
+	return deny("action %s requires an authenticated principal", action)
+}
+
+// AuthorizeRead checks that the principal in ctx may read taskID and what was recorded for
+// it, such as its payments. Users must be a party to the task's consignment or
+// pre-consignment, as for AuthorizeExecute with a trader or CHA action, or a viewer in one of
+// the organisations acting for them; administrators may read every task. The system and M2M clients may read every task, as route permissions
+// already limit which clients reach a read. It returns a *DeniedError when the principal
+// may not, and any other error when the check itself could not be made.
+func (p *Policy) AuthorizeRead(ctx context.Context, taskID string) error {
+	return authorizeRead(ctx, "task", func() (*Parties, error) { return p.taskParties(ctx, taskID) })
+}
+
+// AuthorizeReadWorkflow checks that the principal in ctx may read the consignment or
+// pre-consignment behind workflowID, with the same rules as AuthorizeRead.
+func (p *Policy) AuthorizeReadWorkflow(ctx context.Context, workflowID string) error {
+	return authorizeRead(ctx, "workflow", func() (*Parties, error) { return p.workflowParties(ctx, workflowID) })
+}
+
+// authorizeRead applies the read rules to the principal in ctx, resolving the parties only
+// for users that are not administrators.
+func authorizeRead(ctx context.Context, subject string, parties func() (*Parties, error)) error {
+	authCtx := auth.GetAuthContext(ctx)
+	switch {
+	case authCtx == nil:
+		return deny("reading a %s requires an authenticated principal", subject)
+	case authCtx.System != nil || authCtx.Client != nil || auth.IsAdmin(ctx):
+		return nil
+	case authCtx.User != nil:
+		ps, err := parties()
+		if err != nil {
+			return err
+		}
+		if ps.isParty(authCtx.User) {
+			return nil
+		}
+		return deny("user is not a party to this %s's consignment", subject)
+	}
+	return deny("reading a %s requires an authenticated principal", subject)
+}
+
+// MayReadTask reports whether the principal in ctx may read taskID. It adapts
+// AuthorizeRead for packages that do not depend on this one.
+func (p *Policy) MayReadTask(ctx context.Context, taskID string) (bool, error) {
+	err := p.AuthorizeRead(ctx, taskID)
+	var denied *DeniedError
+	if errors.As(err, &denied) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MayReadWorkflow reports whether the principal in ctx may read the consignment or
+// pre-consignment behind workflowID. It adapts AuthorizeReadWorkflow as MayReadTask does.
+func (p *Policy) MayReadWorkflow(ctx context.Context, workflowID string) (bool, error) {
+	err := p.AuthorizeReadWorkflow(ctx, workflowID)
+	var denied *DeniedError
+	if errors.As(err, &denied) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// AuthorizeActOnWorkflow checks that the principal in ctx may act on the consignment or
+// pre-consignment behind workflowID outside of its tasks, such as choosing a consignment's
+// HS codes. Only users acting as its trader or CHA may, as for AuthorizeExecute with a trader
+// or CHA action; organisation viewers may not. It returns a *DeniedError when the principal
+// may not, and any other error when the check itself could not be made.
+func (p *Policy) AuthorizeActOnWorkflow(ctx context.Context, workflowID string) error {
+	authCtx := auth.GetAuthContext(ctx)
+	if authCtx == nil || authCtx.User == nil {
+		return deny("acting on a consignment requires a user")
+	}
+	parties, err := p.workflowParties(ctx, workflowID)
+	if err != nil {
+		return err
+	}
+	if parties.actsFor(authCtx.User, true, true) {
+		return nil
+	}
+	return deny("user is not the trader, assigned CHA or their delegate for this consignment")
+}
+
+// MayActOnWorkflow reports whether the principal in ctx may act on the consignment or
+// pre-consignment behind workflowID. It adapts AuthorizeActOnWorkflow as MayReadTask does.
+func (p *Policy) MayActOnWorkflow(ctx context.Context, workflowID string) (bool, error) {
+	err := p.AuthorizeActOnWorkflow(ctx, workflowID)
+	var denied *DeniedError
+	if errors.As(err, &denied) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// actsFor reports whether user acts as the trader (when asTrader), being the trader or one
+// of their acting staff, or as the CHA (when asCHA), being the assigned CHA or one of its
+// acting delegates.
+func (ps *Parties) actsFor(user *auth.UserContext, asTrader, asCHA bool) bool {
+	return (asTrader && ps.TraderID != "" && ps.TraderID == user.ID) ||
+		(asTrader && slices.Contains(ps.TraderStaffIDs, user.ID)) ||
+		(asCHA && ps.CHAEmail != "" && strings.EqualFold(ps.CHAEmail, user.Email)) ||
+		(asCHA && slices.Contains(ps.DelegateIDs, user.ID))
+}
+
+// isParty reports whether user is the trader, the assigned CHA, or one of their staff or
+// delegates in any organisation role.
+func (ps *Parties) isParty(user *auth.UserContext) bool {
+	return (ps.TraderID != "" && ps.TraderID == user.ID) ||
+		slices.Contains(ps.TraderStaffIDs, user.ID) ||
+		(ps.CHAEmail != "" && strings.EqualFold(ps.CHAEmail, user.Email)) ||
+		slices.Contains(ps.DelegateIDs, user.ID) ||
+		slices.Contains(ps.ViewerIDs, user.ID)
+}
+
+// taskCode reads the code the task is known by at the external service, from its
+// submission.request.taskCode configuration.
+func (p *Policy) taskCode(taskID string) (string, error) {
+	if taskID == "" {
+		return "", deny("task_id is required")
+	}
+	task, err := p.tasks.GetByID(taskID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read task %s: %w", taskID, err)
+	}
+	var config struct {
+		Submission *struct {
+			Request *struct {
+				TaskCode string `json:"taskCode"`
+			} `json:"request"`
+		} `json:"submission"`
+	}
+	if len(task.Config) > 0 {
+		if err := json.Unmarshal(task.Config, &config); err != nil {
+			return "", fmt.Errorf("failed to parse config of task %s: %w", taskID, err)
+		}
+	}
+	if config.Submission == nil || config.Submission.Request == nil || config.Submission.Request.TaskCode == "" {
+		return "", deny("task %s has no task code", taskID)
+	}
+	return config.Submission.Request.TaskCode, nil
+}
+
+// taskParties resolves the parties of the workflow a task belongs to. The workflow is read
+// from the task record rather than the request, so callers cannot point at a workflow they own.
+func (p *Policy) taskParties(ctx context.Context, taskID string) (*Parties, error) {
+	if taskID == "" {
+		return nil, deny("task_id is required")
+	}
+	task, err := p.tasks.GetByID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task %s: %w", taskID, err)
+	}
+	parties, err := p.parties.ResolveParties(ctx, task.WorkflowID)
+	if errors.Is(err, ErrWorkflowNotFound) {
+		return nil, deny("task %s does not belong to a consignment", taskID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve parties of workflow %s: %w", task.WorkflowID, err)
+	}
+	return parties, nil
+}
+
+// workflowParties resolves the parties of the consignment or pre-consignment behind workflowID.
+func (p *Policy) workflowParties(ctx context.Context, workflowID string) (*Parties, error) {
+	if workflowID == "" {
+		return nil, deny("workflow ID is required")
+	}
+	parties, err := p.parties.ResolveParties(ctx, workflowID)
+	if errors.Is(err, ErrWorkflowNotFound) {
+		return nil, deny("workflow %s does not belong to a consignment", workflowID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve parties of workflow %s: %w", workflowID, err)
+	}
+	return parties, nil
+}
@@ -0,0 +1,262 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/task/persistence"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+type stubTasks map[string]persistence.TaskInfo
+
+func (s stubTasks) GetByID(id string) (*persistence.TaskInfo, error) {
+	task, ok := s[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	task.ID = id
+	return &task, nil
+}
+
+func task(workflowID, taskCode string) persistence.TaskInfo {
+	config := json.RawMessage(`{}`)
+	if taskCode != "" {
+		config = json.RawMessage(`{"submission": {"request": {"taskCode": "` + taskCode + `"}}}`)
+	}
+	return persistence.TaskInfo{WorkflowID: workflowID, Config: config}
+}
+
+type stubParties map[string]Parties // workflow ID -> parties
+
+func (s stubParties) ResolveParties(ctx context.Context, workflowID string) (*Parties, error) {
+	parties, ok := s[workflowID]
+	if !ok {
+		return nil, ErrWorkflowNotFound
+	}
+	return &parties, nil
+}
+
+func withUser(id, email string) context.Context {
+	return context.WithValue(context.Background(), auth.AuthContextKey, &auth.AuthContext{User: &auth.UserContext{ID: id, Email: email}})
+}
+
+func withClient(clientID string, taskCodes ...string) context.Context {
+	return context.WithValue(context.Background(), auth.AuthContextKey, &auth.AuthContext{Client: &auth.ClientContext{ClientID: clientID, TaskCodes: taskCodes}})
+}
+
+func TestPolicy_AuthorizeExecute(t *testing.T) {
+	p := New(
+		DefaultRules([]string{"NPQS_TO_NSW"}),
+		stubTasks{
+			"task-1": task("consignment-1", "npqs_phytosanitary_v1"),
+			"task-2": task("pre-consignment-1", ""),
+			"task-3": task("orphan", ""),
+		},
+		stubParties{
+			"consignment-1": {
+				TraderID:       "trader-1",
+				CHAEmail:       "agent@cha.example.com",
+				TraderStaffIDs: []string{"trader-1", "clerk-1"},
+				DelegateIDs:    []string{"delegate-1"},
+				ViewerIDs:      []string{"viewer-1"},
+			},
+			"pre-consignment-1": {TraderID: "trader-1", TraderStaffIDs: []string{"clerk-1"}},
+		},
+	)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		taskID  string
+		action  string
+		allowed bool
+	}{
+		{"trader submits own consignment", withUser("trader-1", "trader@example.com"), "task-1", plugin.SimpleFormActionSubmit, true},
+		{"trader drafts own pre-consignment", withUser("trader-1", "trader@example.com"), "task-2", plugin.SimpleFormActionDraft, true},
+		{"assigned CHA submits", withUser("cha-user", "Agent@CHA.example.com"), "task-1", plugin.SimpleFormActionSubmit, true},
+		{"other trader submits", withUser("trader-2", "other@example.com"), "task-1", plugin.SimpleFormActionSubmit, false},
+		{"trader organisation clerk submits", withUser("clerk-1", "clerk@example.com"), "task-1", plugin.SimpleFormActionSubmit, true},
+		{"trader organisation clerk drafts pre-consignment", withUser("clerk-1", "clerk@example.com"), "task-2", plugin.SimpleFormActionDraft, true},
+		{"CHA delegate submits", withUser("delegate-1", "delegate@cha2.example.com"), "task-1", plugin.SimpleFormActionSubmit, true},
+		{"organisation viewer submits", withUser("viewer-1", "viewer@example.com"), "task-1", plugin.SimpleFormActionSubmit, false},
+		{"CHA delegate verifies as OGA", withUser("delegate-1", "delegate@cha2.example.com"), "task-1", plugin.SimpleFormActionOgaVerify, false},
+		{"task without consignment", withUser("trader-1", "trader@example.com"), "task-3", plugin.SimpleFormActionSubmit, false},
+		{"trader verifies as OGA", withUser("trader-1", "trader@example.com"), "task-1", plugin.SimpleFormActionOgaVerify, false},
+		{"allowed OGA client verifies", withClient("NPQS_TO_NSW", "npqs_*"), "task-1", plugin.SimpleFormActionOgaVerify, true},
+		{"OGA client without task code grant verifies", withClient("NPQS_TO_NSW", "fcau_*"), "task-1", plugin.SimpleFormActionOgaVerify, false},
+		{"OGA client verifies task without code", withClient("NPQS_TO_NSW", "*"), "task-2", plugin.SimpleFormActionOgaVerify, false},
+		{"other client verifies", withClient("IRD_TO_NSW", "*"), "task-1", plugin.SimpleFormActionOgaFeedback, false},
+		{"client submits trader form", withClient("NPQS_TO_NSW"), "task-1", plugin.SimpleFormActionSubmit, false},
+		{"system reports payment", auth.WithSystemActor(context.Background(), "payments"), "task-1", plugin.PaymentActionSuccess, true},
+		{"trader reports payment", withUser("trader-1", "trader@example.com"), "task-1", plugin.PaymentActionSuccess, false},
+		{"OGA client reports payment", withClient("NPQS_TO_NSW", "*"), "task-1", plugin.PaymentActionSuccess, false},
+		{"OGA client refunds payment", withClient("NPQS_TO_NSW", "*"), "task-1", plugin.PaymentActionRefunded, false},
+		{"unknown action", withUser("trader-1", "trader@example.com"), "task-1", "APPROVE_EVERYTHING", false},
+		{"no principal", context.Background(), "task-1", plugin.SimpleFormActionSubmit, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.AuthorizeExecute(tt.ctx, tt.taskID, tt.action)
+			if tt.allowed {
+				if err != nil {
+					t.Fatalf("expected action to be allowed, got %v", err)
+				}
+				return
+			}
+			var denied *DeniedError
+			if !errors.As(err, &denied) || denied.Reason == "" {
+				t.Fatalf("expected a DeniedError with a reason, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicy_AuthorizeExecute_UnknownTask(t *testing.T) {
+	p := New(DefaultRules(nil), stubTasks{}, stubParties{})
+
+	err := p.AuthorizeExecute(withUser("trader-1", ""), "missing", plugin.SimpleFormActionSubmit)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected record not found, got %v", err)
+	}
+}
+
+func TestPolicy_AuthorizeRead(t *testing.T) {
+	p := New(
+		DefaultRules(nil),
+		stubTasks{
+			"task-1": task("consignment-1", ""),
+			"task-3": task("orphan", ""),
+		},
+		stubParties{
+			"consignment-1": {
+				TraderID:       "trader-1",
+				CHAEmail:       "agent@cha.example.com",
+				TraderStaffIDs: []string{"trader-1", "clerk-1"},
+				DelegateIDs:    []string{"delegate-1"},
+				ViewerIDs:      []string{"viewer-1"},
+			},
+		},
+	)
+	admin := context.WithValue(context.Background(), auth.AuthContextKey, &auth.AuthContext{User: &auth.UserContext{ID: "admin-1", Roles: []string{auth.RoleAdmin}}})
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		taskID  string
+		allowed bool
+	}{
+		{"trader", withUser("trader-1", "trader@example.com"), "task-1", true},
+		{"trader organisation clerk", withUser("clerk-1", "clerk@example.com"), "task-1", true},
+		{"assigned CHA", withUser("cha-user", "agent@cha.example.com"), "task-1", true},
+		{"CHA delegate", withUser("delegate-1", "delegate@cha2.example.com"), "task-1", true},
+		{"organisation viewer", withUser("viewer-1", "viewer@example.com"), "task-1", true},
+		{"other trader", withUser("trader-2", "other@example.com"), "task-1", false},
+		{"task without consignment", withUser("trader-1", "trader@example.com"), "task-3", false},
+		{"administrator", admin, "task-1", true},
+		{"client", withClient("IRD_TO_NSW"), "task-1", true},
+		{"system", auth.WithSystemActor(context.Background(), "payments"), "task-1", true},
+		{"no principal", context.Background(), "task-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := p.MayReadTask(tt.ctx, tt.taskID)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if allowed != tt.allowed {
+				t.Fatalf("expected allowed=%v, got %v (%v)", tt.allowed, allowed, p.AuthorizeRead(tt.ctx, tt.taskID))
+			}
+		})
+	}
+}
+
+func TestPolicy_MayReadWorkflow(t *testing.T) {
+	p := New(
+		DefaultRules(nil),
+		stubTasks{},
+		stubParties{
+			"pre-consignment-1": {TraderID: "trader-1", TraderStaffIDs: []string{"trader-1", "clerk-1"}},
+		},
+	)
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		workflowID string
+		allowed    bool
+	}{
+		{"trader", withUser("trader-1", "trader@example.com"), "pre-consignment-1", true},
+		{"trader organisation clerk", withUser("clerk-1", "clerk@example.com"), "pre-consignment-1", true},
+		{"other trader", withUser("trader-2", "other@example.com"), "pre-consignment-1", false},
+		{"unknown workflow", withUser("trader-1", "trader@example.com"), "missing", false},
+		{"client", withClient("IRD_TO_NSW"), "pre-consignment-1", true},
+		{"no principal", context.Background(), "pre-consignment-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := p.MayReadWorkflow(tt.ctx, tt.workflowID)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if allowed != tt.allowed {
+				t.Fatalf("expected allowed=%v, got %v (%v)", tt.allowed, allowed, p.AuthorizeReadWorkflow(tt.ctx, tt.workflowID))
+			}
+		})
+	}
+}
+
+func TestPolicy_MayActOnWorkflow(t *testing.T) {
+	p := New(
+		DefaultRules(nil),
+		stubTasks{},
+		stubParties{
+			"consignment-1": {
+				TraderID:       "trader-1",
+				CHAEmail:       "agent@cha.example.com",
+				TraderStaffIDs: []string{"trader-1", "clerk-1"},
+				DelegateIDs:    []string{"delegate-1"},
+				ViewerIDs:      []string{"viewer-1"},
+			},
+		},
+	)
+	admin := context.WithValue(context.Background(), auth.AuthContextKey, &auth.AuthContext{User: &auth.UserContext{ID: "admin-1", Roles: []string{auth.RoleAdmin}}})
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		workflowID string
+		allowed    bool
+	}{
+		{"trader", withUser("trader-1", "trader@example.com"), "consignment-1", true},
+		{"trader organisation clerk", withUser("clerk-1", "clerk@example.com"), "consignment-1", true},
+		{"assigned CHA", withUser("cha-user", "Agent@CHA.example.com"), "consignment-1", true},
+		{"CHA delegate", withUser("delegate-1", "delegate@cha2.example.com"), "consignment-1", true},
+		{"organisation viewer", withUser("viewer-1", "viewer@example.com"), "consignment-1", false},
+		{"other trader", withUser("trader-2", "other@example.com"), "consignment-1", false},
+		{"unknown consignment", withUser("trader-1", "trader@example.com"), "missing", false},
+		{"administrator", admin, "consignment-1", false},
+		{"client", withClient("IRD_TO_NSW"), "consignment-1", false},
+		{"no principal", context.Background(), "consignment-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := p.MayActOnWorkflow(tt.ctx, tt.workflowID)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if allowed != tt.allowed {
+				t.Fatalf("expected allowed=%v, got %v (%v)", tt.allowed, allowed, p.AuthorizeActOnWorkflow(tt.ctx, tt.workflowID))
+			}
+		})
+	}
+}
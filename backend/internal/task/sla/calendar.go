@@ -0,0 +1,155 @@
+package sla
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCalendarDays bounds the days Add walks, so a calendar with too few business days
+// cannot stall it.
+const maxCalendarDays = 3660
+
+// CalendarConfig is the root document of business_calendar.json.
+type CalendarConfig struct {
+	Timezone string    `json:"timezone"` // IANA zone business days are counted in, e.g. "Asia/Colombo"
+	Weekend  []string  `json:"weekend"`  // Weekday names, e.g. ["Saturday", "Sunday"]
+	Holidays []Holiday `json:"holidays"`
+}
+
+// Holiday is a public holiday on which the business clock stops.
+type Holiday struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Name string `json:"name"`
+}
+
+// Calendar tells business days from weekends and public holidays. Operators keep the
+// holidays in a file and refresh it at runtime with Reload.
+type Calendar struct {
+	path string
+
+	mu       sync.RWMutex
+	location *time.Location
+	weekend  map[time.Weekday]bool
+	holidays map[string]string // YYYY-MM-DD → name
+}
+
+// NewFileCalendar loads the business calendar file at path.
+func NewFileCalendar(path string) (*Calendar, error) {
+	c := &Calendar{path: path}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload re-reads the business calendar file. If the file cannot be read or is invalid, the
+// previously loaded calendar is kept and an error is returned.
+func (c *Calendar) Reload() error {
+	raw, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("failed to read business calendar file %s: %w", c.path, err)
+	}
+	var cfg CalendarConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return fmt.Errorf("failed to parse business calendar file %s: %w", c.path, err)
+	}
+	if err := c.load(cfg); err != nil {
+		return fmt.Errorf("invalid business calendar file %s: %w", c.path, err)
+	}
+	return nil
+}
+
+func (c *Calendar) load(cfg CalendarConfig) error {
+	location := time.UTC
+	if cfg.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", cfg.Timezone)
+		}
+	}
+
+	weekend := make(map[time.Weekday]bool, len(cfg.Weekend))
+	for _, name := range cfg.Weekend {
+		day, ok := parseWeekday(name)
+		if !ok {
+			return fmt.Errorf("unknown weekday %q", name)
+		}
+		weekend[day] = true
+	}
+	if len(weekend) == 7 {
+		return fmt.Errorf("every day of the week is a weekend day")
+	}
+
+	holidays := make(map[string]string, len(cfg.Holidays))
+	for _, h := range cfg.Holidays {
+		if _, err := time.Parse(time.DateOnly, h.Date); err != nil {
+			return fmt.Errorf("holiday %q has invalid date %q, want YYYY-MM-DD", h.Name, h.Date)
+		}
+		holidays[h.Date] = h.Name
+	}
+
+	c.mu.Lock()
+	c.location, c.weekend, c.holidays = location, weekend, holidays
+	c.mu.Unlock()
+	return nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), strings.TrimSpace(name)) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// IsBusinessDay reports whether the day t falls on, in the calendar's timezone, is neither a
+// weekend day nor a holiday.
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.isBusinessDay(t.In(c.location))
+}
+
+func (c *Calendar) isBusinessDay(local time.Time) bool {
+	if c.weekend[local.Weekday()] {
+		return false
+	}
+	_, holiday := c.holidays[local.Format(time.DateOnly)]
+	return !holiday
+}
+
+// Add returns the time d of business time after start: time on weekend days and holidays
+// does not count. A start outside business days counts from the beginning of the next one.
+func (c *Calendar) Add(start time.Time, d time.Duration) time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	t := start.In(c.location)
+	for range maxCalendarDays {
+		y, m, day := t.Date()
+		midnight := time.Date(y, m, day+1, 0, 0, 0, 0, c.location)
+		if c.isBusinessDay(t) {
+			left := midnight.Sub(t)
+			if d <= left {
+				return t.Add(d)
+			}
+			d -= left
+		}
+		t = midnight
+	}
+	return t.Add(d)
+}
+
+// deadline returns the time d after start, in business time when business is set and a
+// calendar is configured and in calendar time otherwise.
+func deadline(calendar *Calendar, business bool, start time.Time, d Duration) time.Time {
+	if business && calendar != nil {
+		return calendar.Add(start, time.Duration(d))
+	}
+	return start.Add(time.Duration(d))
+}
@@ -0,0 +1,99 @@
+package sla
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCalendarFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "business_calendar.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write calendar file: %v", err)
+	}
+	return path
+}
+
+const testCalendar = `{
+	"timezone": "UTC",
+	"weekend": ["Saturday", "Sunday"],
+	"holidays": [{"date": "2026-10-19", "name": "Test holiday"}]
+}`
+
+func TestCalendarAdd(t *testing.T) {
+	calendar, err := NewFileCalendar(writeCalendarFile(t, testCalendar))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		start time.Time
+		d     time.Duration
+		want  time.Time
+	}{
+		{
+			name:  "within a business day",
+			start: time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC),
+			d:     6 * time.Hour,
+			want:  time.Date(2026, 10, 15, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "across the weekend and a holiday",
+			start: time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC),
+			d:     24 * time.Hour,
+			want:  time.Date(2026, 10, 20, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "started on a weekend day",
+			start: time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC),
+			d:     time.Hour,
+			want:  time.Date(2026, 10, 20, 1, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar.Add(tt.start, tt.d); !got.Equal(tt.want) {
+				t.Fatalf("Add(%s, %s) = %s, want %s", tt.start, tt.d, got, tt.want)
+			}
+		})
+	}
+
+	if got := deadline(calendar, false, tests[1].start, Duration(24*time.Hour)); !got.Equal(tests[1].start.Add(24 * time.Hour)) {
+		t.Fatalf("calendar-time deadline = %s, want %s", got, tests[1].start.Add(24*time.Hour))
+	}
+}
+
+func TestCalendarReload_KeepsPreviousCalendarOnError(t *testing.T) {
+	path := writeCalendarFile(t, testCalendar)
+	calendar, err := NewFileCalendar(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	holiday := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	if calendar.IsBusinessDay(holiday) {
+		t.Fatal("expected the holiday not to be a business day")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"weekend": ["Caturday"]}`), 0o600); err != nil {
+		t.Fatalf("failed to rewrite calendar file: %v", err)
+	}
+	if err := calendar.Reload(); err == nil {
+		t.Fatal("expected an error for an unknown weekday")
+	}
+	if calendar.IsBusinessDay(holiday) {
+		t.Fatal("expected the previous calendar to be kept after a failed reload")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"timezone": "UTC", "weekend": ["Sunday"]}`), 0o600); err != nil {
+		t.Fatalf("failed to rewrite calendar file: %v", err)
+	}
+	if err := calendar.Reload(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !calendar.IsBusinessDay(holiday) || calendar.IsBusinessDay(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)) {
+		t.Fatal("expected the reloaded calendar to apply")
+	}
+}
@@ -0,0 +1,76 @@
+package sla
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+)
+
+// defaultReportPeriod is the period a report covers when the request gives no from.
+const defaultReportPeriod = 30 * 24 * time.Hour
+
+// HTTPHandler exposes SLA reports to administrators.
+type HTTPHandler struct {
+	store *Store
+}
+
+// NewHTTPHandler creates a new HTTPHandler for SLA reports
+func NewHTTPHandler(store *Store) *HTTPHandler {
+	return &HTTPHandler{store: store}
+}
+
+// HandleReport handles GET /api/v1/admin/reports/sla
+// Optional filters: agency, and the RFC 3339 time range from (inclusive) and to (exclusive)
+// the clocks started in. The range defaults to the 30 days up to now.
+func (h *HTTPHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r.Context()) {
+		writeJSONError(w, http.StatusForbidden, "SLA reports are only available to administrators")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := ReportFilter{Agency: query.Get("agency"), To: time.Now().UTC()}
+	for name, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "query param "+name+" must be an RFC 3339 timestamp")
+			return
+		}
+		*dst = t
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.Add(-defaultReportPeriod)
+	}
+	if !filter.To.After(filter.From) {
+		writeJSONError(w, http.StatusBadRequest, "query param to must be after from")
+		return
+	}
+
+	report, err := h.store.Report(r.Context(), filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to build SLA report", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to build SLA report")
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, report)
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+// writeJSONError sets Content-Type: application/json and writes a consistent JSON error body.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSONResponse(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,286 @@
+package sla
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/OpenNSW/nsw/internal/audit"
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/pkg/notification"
+)
+
+// MonitorActor identifies the SLA monitor when it acts on tasks.
+const MonitorActor = "sla-monitor"
+
+// checkBatchSize bounds the clocks examined per pass.
+const checkBatchSize = 200
+
+// TaskActions applies the escalations that act on a task.
+type TaskActions interface {
+	// ExecuteAction executes a task action as the SLA monitor.
+	ExecuteAction(ctx context.Context, taskID string, request plugin.ExecutionRequest) error
+	// FailTask fails a task whatever its plugin state, recording reason.
+	FailTask(ctx context.Context, taskID, reason string) error
+}
+
+// Notifier sends breach notifications. *notification.Manager implements it.
+type Notifier interface {
+	SendEmail(ctx context.Context, payload notification.EmailPayload)
+}
+
+// CheckResult summarises one monitor pass.
+type CheckResult struct {
+	Checked  int `json:"checked"`
+	Breached int `json:"breached"` // Clocks found past their deadline for the first time
+	Fired    int `json:"fired"`    // Escalations fired
+	Failed   int `json:"failed"`   // Escalations or clocks that could not be processed
+}
+
+// Monitor fires the escalations of clocks that are past their deadline.
+type Monitor struct {
+	store    *Store
+	actions  TaskActions
+	notifier Notifier
+	auditLog audit.Recorder
+}
+
+// NewMonitor creates a Monitor. Fired escalations are recorded in auditLog. notifier and
+// auditLog may be nil; NOTIFY escalations are then skipped.
+func NewMonitor(store *Store, actions TaskActions, notifier Notifier, auditLog audit.Recorder) *Monitor {
+	return &Monitor{store: store, actions: actions, notifier: notifier, auditLog: auditLog}
+}
+
+// Check examines the running clocks whose next escalation time has come: each is marked
+// breached and the escalations now due are fired in order. Each escalation is claimed on the
+// clock before it is fired, so passes are safe to run concurrently on several replicas and
+// an escalation whose action fails is not retried.
+func (m *Monitor) Check(ctx context.Context) (*CheckResult, error) {
+	now := m.store.now().UTC()
+	due, err := m.store.listDue(ctx, now, checkBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CheckResult{}
+	for i := range due {
+		result.Checked++
+		if err := m.process(ctx, &due[i], now, result); err != nil {
+			result.Failed++
+			slog.ErrorContext(ctx, "failed to process SLA clock", "timerID", due[i].ID, "taskID", due[i].TaskID, "error", err)
+		}
+	}
+
+	if result.Breached > 0 || result.Fired > 0 || result.Failed > 0 {
+		slog.InfoContext(ctx, "SLA check completed",
+			"checked", result.Checked, "breached", result.Breached, "fired", result.Fired, "failed", result.Failed)
+	}
+	return result, nil
+}
+
+// process marks a due clock breached and fires its escalations that are due by now.
+func (m *Monitor) process(ctx context.Context, t *dueTimer, now time.Time, result *CheckResult) error {
+	policy, err := ParsePolicy(t.SLA)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		// The task has no SLA any more; the clock has nothing left to do.
+		_, err := m.store.advance(ctx, &t.Timer, t.Escalations, nil)
+		return err
+	}
+
+	for {
+		var escalation *Escalation
+		fired := t.Escalations
+		if at := m.escalationAt(policy, &t.Timer, fired); at != nil && !at.After(now) {
+			escalation = &policy.Escalations[fired]
+			fired++
+		}
+		next := m.escalationAt(policy, &t.Timer, fired)
+
+		claimed, err := m.store.advance(ctx, &t.Timer, fired, next)
+		if err != nil || !claimed {
+			// Another replica has advanced the clock, or the task has left the covered states.
+			return err
+		}
+		if t.BreachedAt == nil {
+			t.BreachedAt = &t.DueAt
+			result.Breached++
+			slog.InfoContext(ctx, "task breached its SLA", "taskID", t.TaskID, "workflowID", t.WorkflowID, "dueAt", t.DueAt)
+		}
+		t.Escalations, t.NextEscalationAt = fired, next
+		if escalation == nil {
+			return nil
+		}
+
+		if err := m.escalate(ctx, &t.Timer, *escalation); err != nil {
+			result.Failed++
+			slog.ErrorContext(ctx, "SLA escalation failed",
+				"taskID", t.TaskID, "escalation", fired-1, "action", escalation.Action, "error", err)
+			continue
+		}
+		result.Fired++
+	}
+}
+
+// escalationAt returns when the i-th escalation of policy fires for t, or nil if it has none.
+func (m *Monitor) escalationAt(policy *Policy, t *Timer, i int) *time.Time {
+	if i >= len(policy.Escalations) {
+		return nil
+	}
+	at := deadline(m.store.calendar, policy.BusinessCalendar, t.DueAt, policy.Escalations[i].After)
+	return &at
+}
+
+// escalate fires an escalation for the task of t and records it in the audit log.
+func (m *Monitor) escalate(ctx context.Context, t *Timer, e Escalation) error {
+	ctx = auth.WithSystemActor(ctx, MonitorActor)
+	details := map[string]any{
+		"timerId":     t.ID,
+		"escalation":  t.Escalations - 1,
+		"pluginState": t.PluginState,
+		"dueAt":       t.DueAt,
+	}
+
+	switch e.Action {
+	case EscalationNotify:
+		recipients, err := m.notify(ctx, t, e)
+		if err != nil {
+			return err
+		}
+		details["recipients"] = recipients
+	case EscalationEmitOutcome:
+		if err := m.actions.ExecuteAction(ctx, t.TaskID, *e.Execute); err != nil {
+			return fmt.Errorf("failed to execute %s: %w", e.Execute.Action, err)
+		}
+		details["taskAction"] = e.Execute.Action
+	case EscalationFail:
+		reason := fmt.Sprintf("SLA breached: due at %s", t.DueAt.Format(time.RFC3339))
+		if err := m.actions.FailTask(ctx, t.TaskID, reason); err != nil {
+			return fmt.Errorf("failed to fail task: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown escalation action %q", e.Action)
+	}
+
+	return audit.Record(ctx, m.auditLog, audit.Entry{
+		Action:        audit.ActionTaskEscalate,
+		ConsignmentID: t.WorkflowID,
+		TaskID:        t.TaskID,
+		ResourceType:  audit.ResourceTask,
+		ResourceID:    t.TaskID,
+		Operation:     string(e.Action),
+		Details:       details,
+	})
+}
+
+// notify emails the breach to the agency contacts and recipients of e and returns the
+// addresses emailed.
+func (m *Monitor) notify(ctx context.Context, t *Timer, e Escalation) ([]string, error) {
+	if m.notifier == nil {
+		slog.WarnContext(ctx, "no notifier configured, skipping SLA breach notification", "taskID", t.TaskID)
+		return nil, nil
+	}
+	contacts, err := m.store.contacts(ctx, t.TaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := append([]string{}, e.Recipients...)
+	for _, target := range e.Notify {
+		address := contacts.OfficerEmail
+		if target == NotifySupervisor {
+			address = contacts.SupervisorEmail
+		}
+		if address == nil || *address == "" {
+			slog.WarnContext(ctx, "agency has no address for SLA breach notification",
+				"taskID", t.TaskID, "agency", contacts.AgencyCode, "notify", target)
+			continue
+		}
+		recipients = append(recipients, *address)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients for SLA breach notification of agency %q", contacts.AgencyCode)
+	}
+
+	templateID := e.TemplateID
+	if templateID == "" {
+		templateID = DefaultNotifyTemplate
+	}
+	// Sending is asynchronous and outlives the pass.
+	m.notifier.SendEmail(context.WithoutCancel(ctx), notification.EmailPayload{
+		BasePayload: notification.BasePayload{
+			TemplateID: templateID,
+			TemplateData: map[string]any{
+				"TaskID":      t.TaskID,
+				"TaskName":    contacts.TaskName,
+				"WorkflowID":  t.WorkflowID,
+				"PluginState": t.PluginState,
+				"StartedAt":   t.StartedAt.Format(time.RFC3339),
+				"DueAt":       t.DueAt.Format(time.RFC3339),
+				"AgencyCode":  contacts.AgencyCode,
+				"AgencyName":  contacts.AgencyName,
+			},
+			Metadata: map[string]string{"taskId": t.TaskID, "workflowId": t.WorkflowID},
+		},
+		Recipients: recipients,
+	})
+	return recipients, nil
+}
+
+// Worker runs Monitor.Check periodically in the background.
+type Worker struct {
+	monitor  *Monitor
+	interval time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewWorker creates a worker that checks every interval.
+func NewWorker(monitor *Monitor, interval time.Duration) *Worker {
+	return &Worker{
+		monitor:  monitor,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the background loop. It returns immediately.
+func (w *Worker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		slog.Info("SLA monitor started", "interval", w.interval)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := w.monitor.Check(ctx); err != nil {
+					slog.ErrorContext(ctx, "SLA check failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop cancels the loop and waits for an in-flight pass to finish.
+func (w *Worker) Stop() {
+	w.once.Do(func() {
+		if w.cancel == nil {
+			close(w.done)
+			return
+		}
+		w.cancel()
+		<-w.done
+	})
+}
@@ -0,0 +1,143 @@
+package sla
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/OpenNSW/nsw/internal/audit"
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/pkg/notification"
+)
+
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db, DriverName: "postgres"}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a gorm database", err)
+	}
+	return gdb, mock
+}
+
+type fakeTaskActions struct {
+	executed []plugin.ExecutionRequest
+	failed   []string
+}
+
+func (a *fakeTaskActions) ExecuteAction(_ context.Context, _ string, request plugin.ExecutionRequest) error {
+	a.executed = append(a.executed, request)
+	return nil
+}
+
+func (a *fakeTaskActions) FailTask(_ context.Context, taskID, _ string) error {
+	a.failed = append(a.failed, taskID)
+	return nil
+}
+
+type fakeNotifier struct {
+	sent []notification.EmailPayload
+}
+
+func (n *fakeNotifier) SendEmail(_ context.Context, payload notification.EmailPayload) {
+	n.sent = append(n.sent, payload)
+}
+
+type recordedAudit struct {
+	entries []audit.Entry
+}
+
+func (r *recordedAudit) Append(_ context.Context, entry *audit.Entry) error {
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func TestMonitorCheck_FiresDueEscalationsInOrder(t *testing.T) {
+	db, mock := setupTestDB(t)
+	store := NewStore(db, nil)
+	dueAt := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return dueAt.Add(36 * time.Hour) }
+	actions := &fakeTaskActions{}
+	notifier := &fakeNotifier{}
+	auditLog := &recordedAudit{}
+	monitor := NewMonitor(store, actions, notifier, auditLog)
+
+	policy := `{"duration": "2d", "escalations": [
+		{"action": "NOTIFY", "notify": ["OFFICER", "SUPERVISOR"]},
+		{"after": "1d", "action": "FAIL"},
+		{"after": "3d", "action": "NOTIFY", "recipients": ["director@example.gov"]}]}`
+	mock.ExpectQuery(`SELECT s\.\*, t\.sla FROM task_sla_timers s JOIN task_infos t ON t\.id = s\.task_id WHERE s\.stopped_at IS NULL AND s\.next_escalation_at <= \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "task_id", "workflow_id", "plugin_state", "started_at", "due_at", "escalations", "next_escalation_at", "sla"}).
+			AddRow("timer-1", "task-1", "consignment-1", "OGA_ACKNOWLEDGED", dueAt.Add(-48*time.Hour), dueAt, 0, dueAt, []byte(policy)))
+
+	// NOTIFY at the deadline, to the officer only since the agency has no supervisor address.
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "task_sla_timers" SET "breached_at"=COALESCE\(breached_at, due_at\),"escalations"=\$1,"next_escalation_at"=\$2 WHERE id = \$3 AND escalations = \$4 AND stopped_at IS NULL`).
+		WithArgs(1, dueAt.Add(24*time.Hour), "timer-1", 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT .* FROM task_infos t LEFT JOIN workflow_node_templates n`).
+		WithArgs("task-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"task_name", "agency_code", "agency_name", "officer_email", "supervisor_email"}).
+			AddRow("Phytosanitary review", "NPQS", "National Plant Quarantine Service", "officer@npqs.example.gov", nil))
+
+	// FAIL a day later; the last escalation is not due yet.
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "task_sla_timers" SET`).
+		WithArgs(2, dueAt.Add(72*time.Hour), "timer-1", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Nothing more is due: the next escalation stays scheduled, or the failed task has stopped
+	// the clock in the meantime.
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "task_sla_timers" SET`).
+		WithArgs(2, dueAt.Add(72*time.Hour), "timer-1", 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	result, err := monitor.Check(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if *result != (CheckResult{Checked: 1, Breached: 1, Fired: 2}) {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+
+	if len(notifier.sent) != 1 {
+		t.Fatalf("expected one notification, got %d", len(notifier.sent))
+	}
+	email := notifier.sent[0]
+	if len(email.Recipients) != 1 || email.Recipients[0] != "officer@npqs.example.gov" || email.TemplateID != DefaultNotifyTemplate {
+		t.Fatalf("unexpected notification %+v", email)
+	}
+	if email.TemplateData["TaskName"] != "Phytosanitary review" {
+		t.Fatalf("unexpected template data %+v", email.TemplateData)
+	}
+	if len(actions.failed) != 1 || actions.failed[0] != "task-1" || len(actions.executed) != 0 {
+		t.Fatalf("unexpected task actions %+v", actions)
+	}
+
+	if len(auditLog.entries) != 2 {
+		t.Fatalf("expected two audit entries, got %d", len(auditLog.entries))
+	}
+	for i, op := range []EscalationAction{EscalationNotify, EscalationFail} {
+		entry := auditLog.entries[i]
+		if entry.Action != audit.ActionTaskEscalate || entry.Operation != string(op) || entry.TaskID != "task-1" || entry.ActorID != MonitorActor {
+			t.Fatalf("unexpected audit entry %d: %+v", i, entry)
+		}
+	}
+}
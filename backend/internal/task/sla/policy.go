@@ -0,0 +1,182 @@
+// Package sla runs the service-level clocks of tasks. A node template may declare an SLA
+// Policy: how long its tasks may stay in some plugin states (e.g. OGA_ACKNOWLEDGED), counted
+// in calendar time or in business time on a Calendar, and the escalations fired once the
+// deadline passes. The task manager reports every task state change to a Store, which starts
+// and stops the task's clock; a Monitor fires the escalations of clocks past their deadline.
+package sla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+// Duration is a length of time written as a Go duration ("36h", "90m") optionally led by a
+// number of days ("3d", "2d12h"). A day is 24 hours; under the business calendar only
+// business days count towards it.
+type Duration time.Duration
+
+// ParseDuration parses a Duration.
+func ParseDuration(s string) (Duration, error) {
+	rest := strings.TrimSpace(s)
+	var days int64
+	if i := strings.IndexByte(rest, 'd'); i >= 0 {
+		n, err := strconv.ParseInt(rest[:i], 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		days, rest = n, rest[i+1:]
+		if rest == "" {
+			return Duration(time.Duration(days) * 24 * time.Hour), nil
+		}
+	}
+	d, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return Duration(time.Duration(days)*24*time.Hour + d), nil
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"3d\" or \"36h\"")
+	}
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// EscalationAction is what an escalation does to a task that breached its SLA.
+type EscalationAction string
+
+const (
+	// EscalationNotify emails the agency officer or supervisor, or listed recipients.
+	EscalationNotify EscalationAction = "NOTIFY"
+	// EscalationEmitOutcome executes a task action as the SLA monitor, e.g. an OGA_VERIFICATION
+	// whose content makes the task emit a default outcome.
+	EscalationEmitOutcome EscalationAction = "EMIT_OUTCOME"
+	// EscalationFail fails the task, which fails its workflow node.
+	EscalationFail EscalationAction = "FAIL"
+)
+
+// NotifyTarget is an agency contact a NOTIFY escalation addresses. The addresses are those
+// recorded against the agency named in the task configuration.
+type NotifyTarget string
+
+const (
+	NotifyOfficer    NotifyTarget = "OFFICER"
+	NotifySupervisor NotifyTarget = "SUPERVISOR"
+)
+
+// DefaultNotifyTemplate is the email template NOTIFY escalations use unless they name another.
+const DefaultNotifyTemplate = "sla_breach"
+
+// Policy is the SLA of the tasks started from a node template.
+type Policy struct {
+	Duration         Duration     `json:"duration"`                   // Time allowed in the covered states
+	BusinessCalendar bool         `json:"businessCalendar,omitempty"` // Count only business days (weekends and holidays are skipped)
+	States           []string     `json:"states,omitempty"`           // Plugin states the clock runs in; any state while the task is IN_PROGRESS when empty
+	Escalations      []Escalation `json:"escalations,omitempty"`      // Fired in order once the deadline has passed
+}
+
+// Escalation is an action fired a while after a task breached its SLA.
+type Escalation struct {
+	After      Duration                 `json:"after,omitempty"`      // Time past the deadline; 0 fires at the breach
+	Action     EscalationAction         `json:"action"`               // NOTIFY, EMIT_OUTCOME or FAIL
+	Notify     []NotifyTarget           `json:"notify,omitempty"`     // NOTIFY: agency contacts to email
+	Recipients []string                 `json:"recipients,omitempty"` // NOTIFY: further addresses to email
+	TemplateID string                   `json:"templateId,omitempty"` // NOTIFY: email template; DefaultNotifyTemplate when empty
+	Execute    *plugin.ExecutionRequest `json:"execute,omitempty"`    // EMIT_OUTCOME: task action to execute
+}
+
+// ParsePolicy decodes and validates the SLA policy of a node template or task. It returns
+// nil for an empty or null policy.
+func ParsePolicy(raw json.RawMessage) (*Policy, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var p Policy
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("invalid SLA policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate reports every problem with the policy.
+func (p *Policy) Validate() error {
+	var errs []error
+	if p.Duration <= 0 {
+		errs = append(errs, errors.New("duration must be positive"))
+	}
+	for i, state := range p.States {
+		if strings.TrimSpace(state) == "" {
+			errs = append(errs, fmt.Errorf("states[%d] is empty", i))
+		}
+	}
+	for i, e := range p.Escalations {
+		if e.After < 0 {
+			errs = append(errs, fmt.Errorf("escalations[%d].after cannot be negative", i))
+		}
+		if i > 0 && e.After < p.Escalations[i-1].After {
+			errs = append(errs, fmt.Errorf("escalations[%d] fires before escalations[%d]", i, i-1))
+		}
+		switch e.Action {
+		case EscalationNotify:
+			if len(e.Notify) == 0 && len(e.Recipients) == 0 {
+				errs = append(errs, fmt.Errorf("escalations[%d] notifies nobody", i))
+			}
+			for j, target := range e.Notify {
+				if target != NotifyOfficer && target != NotifySupervisor {
+					errs = append(errs, fmt.Errorf("escalations[%d].notify[%d] must be OFFICER or SUPERVISOR", i, j))
+				}
+			}
+		case EscalationEmitOutcome:
+			if e.Execute == nil || e.Execute.Action == "" {
+				errs = append(errs, fmt.Errorf("escalations[%d].execute.action is required", i))
+			}
+		case EscalationFail:
+		default:
+			errs = append(errs, fmt.Errorf("escalations[%d].action must be NOTIFY, EMIT_OUTCOME or FAIL", i))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid SLA policy: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// covers reports whether the clock runs while a task is in state and pluginState.
+func (p *Policy) covers(state plugin.State, pluginState string) bool {
+	if state != plugin.InProgress {
+		return false
+	}
+	if len(p.States) == 0 {
+		return true
+	}
+	for _, s := range p.States {
+		if s == pluginState {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package sla
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "3d", want: 72 * time.Hour},
+		{in: "2d12h", want: 60 * time.Hour},
+		{in: "36h", want: 36 * time.Hour},
+		{in: "90m", want: 90 * time.Minute},
+		{in: "d", wantErr: true},
+		{in: "-1d", wantErr: true},
+		{in: "3 days", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDuration(%q) = %s, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || time.Duration(got) != tt.want {
+			t.Errorf("ParseDuration(%q) = %s, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy(json.RawMessage(`{
+		"duration": "3d",
+		"businessCalendar": true,
+		"states": ["OGA_ACKNOWLEDGED"],
+		"escalations": [
+			{"action": "NOTIFY", "notify": ["OFFICER", "SUPERVISOR"]},
+			{"after": "1d", "action": "EMIT_OUTCOME", "execute": {"action": "OGA_VERIFICATION", "content": {"decision": "REJECTED"}}},
+			{"after": "2d", "action": "FAIL"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if time.Duration(policy.Duration) != 72*time.Hour || !policy.BusinessCalendar || len(policy.Escalations) != 3 {
+		t.Fatalf("unexpected policy %+v", policy)
+	}
+	if !policy.covers(plugin.InProgress, "OGA_ACKNOWLEDGED") ||
+		policy.covers(plugin.InProgress, "OGA_REVIEWED") ||
+		policy.covers(plugin.Completed, "OGA_ACKNOWLEDGED") {
+		t.Fatal("unexpected covered states")
+	}
+
+	if policy, err := ParsePolicy(json.RawMessage(`null`)); policy != nil || err != nil {
+		t.Fatalf("ParsePolicy(null) = %+v, %v, want nil, nil", policy, err)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	_, err := ParsePolicy(json.RawMessage(`{
+		"escalations": [
+			{"after": "2d", "action": "NOTIFY"},
+			{"after": "1d", "action": "EMIT_OUTCOME"},
+			{"action": "ESCALATE"}
+		]
+	}`))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"duration must be positive",
+		"escalations[0] notifies nobody",
+		"escalations[1] fires before escalations[0]",
+		"escalations[1].execute.action is required",
+		"escalations[2].action must be NOTIFY, EMIT_OUTCOME or FAIL",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package sla
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxReportBreaches bounds the breaches listed in a report; the agency counts cover all.
+const maxReportBreaches = 500
+
+// breachedClause matches clocks that ran past their deadline, before stopping or by now.
+const breachedClause = "s.due_at < COALESCE(s.stopped_at, ?)"
+
+// ReportFilter selects the clocks a report covers: those started in [From, To) of tasks of
+// Agency, or of every agency when Agency is empty.
+type ReportFilter struct {
+	Agency string
+	From   time.Time
+	To     time.Time
+}
+
+// AgencySummary counts the clocks of the tasks of one agency.
+type AgencySummary struct {
+	Agency       string `gorm:"column:agency" json:"agency"` // Agency code from the task configuration; empty for tasks of no agency
+	AgencyName   string `gorm:"column:agency_name" json:"agencyName,omitempty"`
+	Tracked      int64  `gorm:"column:tracked" json:"tracked"`
+	Met          int64  `gorm:"column:met" json:"met"`
+	Breached     int64  `gorm:"column:breached" json:"breached"`
+	OpenBreaches int64  `gorm:"column:open_breaches" json:"openBreaches"` // Breached clocks whose task is still waiting
+	Running      int64  `gorm:"column:running" json:"running"`
+}
+
+// Breach is a clock that ran past its deadline.
+type Breach struct {
+	Timer
+	Agency   string `gorm:"column:agency" json:"agency"`
+	TaskName string `gorm:"column:task_name" json:"taskName"`
+}
+
+// Report describes how the tasks of each agency kept to their SLAs.
+type Report struct {
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Agencies []AgencySummary `json:"agencies"`
+	Breaches []Breach        `json:"breaches"` // Most recent first, at most maxReportBreaches
+}
+
+// Report summarises the clocks selected by filter per agency and lists their breaches.
+func (s *Store) Report(ctx context.Context, filter ReportFilter) (*Report, error) {
+	now := s.now().UTC()
+	base := s.db.WithContext(ctx).
+		Table("task_sla_timers s").
+		Joins("JOIN task_infos t ON t.id = s.task_id").
+		Where("s.started_at >= ? AND s.started_at < ?", filter.From, filter.To)
+	if filter.Agency != "" {
+		base = base.Where("t.config->>'agency' = ?", filter.Agency)
+	}
+
+	report := &Report{From: filter.From, To: filter.To, Agencies: []AgencySummary{}, Breaches: []Breach{}}
+	err := base.Session(&gorm.Session{}).
+		Select(`COALESCE(t.config->>'agency', '') AS agency, COALESCE(MAX(a.name), '') AS agency_name,
+			COUNT(*) AS tracked,
+			COUNT(*) FILTER (WHERE s.stopped_at IS NOT NULL AND NOT `+breachedClause+`) AS met,
+			COUNT(*) FILTER (WHERE `+breachedClause+`) AS breached,
+			COUNT(*) FILTER (WHERE s.stopped_at IS NULL AND s.due_at < ?) AS open_breaches,
+			COUNT(*) FILTER (WHERE s.stopped_at IS NULL AND s.due_at >= ?) AS running`, now, now, now, now).
+		Joins("LEFT JOIN oga_agencies a ON a.code = t.config->>'agency'").
+		Group("1").
+		Order("1").
+		Scan(&report.Agencies).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarise SLA clocks: %w", err)
+	}
+
+	err = base.Session(&gorm.Session{}).
+		Select("s.*, COALESCE(t.config->>'agency', '') AS agency, COALESCE(n.name, t.workflow_node_template_id) AS task_name").
+		Joins("LEFT JOIN workflow_node_templates n ON n.id = t.workflow_node_template_id").
+		Where(breachedClause, now).
+		Order("s.due_at DESC").
+		Limit(maxReportBreaches).
+		Scan(&report.Breaches).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SLA breaches: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,211 @@
+package sla
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+)
+
+// Status is where a clock stands against its deadline.
+type Status string
+
+const (
+	StatusRunning  Status = "RUNNING"  // Still running and not yet due
+	StatusMet      Status = "MET"      // Stopped before the deadline
+	StatusBreached Status = "BREACHED" // Ran past the deadline, whether or not it has stopped since
+)
+
+// Timer is the SLA clock of one period a task spent in the states its SLA covers.
+type Timer struct {
+	ID               string     `gorm:"type:uuid;column:id;primaryKey" json:"id"`
+	TaskID           string     `gorm:"type:text;column:task_id;not null" json:"taskId"`
+	WorkflowID       string     `gorm:"type:text;column:workflow_id;not null" json:"workflowId"`
+	PluginState      string     `gorm:"type:varchar(100);column:plugin_state" json:"pluginState"` // Plugin state the clock started in
+	StartedAt        time.Time  `gorm:"column:started_at;not null" json:"startedAt"`
+	DueAt            time.Time  `gorm:"column:due_at;not null" json:"dueAt"`
+	StoppedAt        *time.Time `gorm:"column:stopped_at" json:"stoppedAt,omitempty"`
+	BreachedAt       *time.Time `gorm:"column:breached_at" json:"breachedAt,omitempty"`
+	Escalations      int        `gorm:"column:escalations;not null" json:"escalations"` // Escalations fired so far
+	NextEscalationAt *time.Time `gorm:"column:next_escalation_at" json:"-"`
+}
+
+func (Timer) TableName() string {
+	return "task_sla_timers"
+}
+
+// Status reports where the clock stands at now.
+func (t *Timer) Status(now time.Time) Status {
+	end := now
+	if t.StoppedAt != nil {
+		end = *t.StoppedAt
+	}
+	switch {
+	case t.BreachedAt != nil || end.After(t.DueAt):
+		return StatusBreached
+	case t.StoppedAt != nil:
+		return StatusMet
+	default:
+		return StatusRunning
+	}
+}
+
+// taskSLA is the part of a task record the clocks are driven by.
+type taskSLA struct {
+	ID         string          `gorm:"column:id"`
+	WorkflowID string          `gorm:"column:workflow_id"`
+	SLA        json.RawMessage `gorm:"column:sla;serializer:json"`
+}
+
+func (taskSLA) TableName() string {
+	return "task_infos"
+}
+
+// Store keeps the SLA clocks of tasks.
+type Store struct {
+	db       *gorm.DB
+	calendar *Calendar
+	now      func() time.Time
+}
+
+// NewStore creates a Store. Deadlines of policies that use the business calendar are counted
+// on calendar, which may be nil, in which case every day counts.
+func NewStore(db *gorm.DB, calendar *Calendar) *Store {
+	return &Store{db: db, calendar: calendar, now: time.Now}
+}
+
+// Observe runs the clock of a task from the state it has just entered: a clock is started
+// when the task enters a state its SLA covers and stopped when it leaves them. Tasks without
+// an SLA are ignored. Callers serialize the changes of a task, as the task manager does.
+func (s *Store) Observe(ctx context.Context, taskID string, state plugin.State, pluginState string) error {
+	db := s.db.WithContext(ctx)
+	var task taskSLA
+	if err := db.Select("id", "workflow_id", "sla").Take(&task, "id = ?", taskID).Error; err != nil {
+		return fmt.Errorf("failed to load SLA of task %s: %w", taskID, err)
+	}
+	policy, err := ParsePolicy(task.SLA)
+	if err != nil || policy == nil {
+		return err
+	}
+
+	var running Timer
+	err = db.Where("task_id = ? AND stopped_at IS NULL", taskID).Take(&running).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to load SLA clock of task %s: %w", taskID, err)
+	}
+	isRunning := err == nil
+	now := s.now().UTC()
+
+	switch covered := policy.covers(state, pluginState); {
+	case covered && !isRunning:
+		due := deadline(s.calendar, policy.BusinessCalendar, now, policy.Duration)
+		timer := &Timer{
+			ID:               uuid.NewString(),
+			TaskID:           taskID,
+			WorkflowID:       task.WorkflowID,
+			PluginState:      pluginState,
+			StartedAt:        now,
+			DueAt:            due,
+			NextEscalationAt: &due,
+		}
+		if err := db.Create(timer).Error; err != nil {
+			return fmt.Errorf("failed to start SLA clock of task %s: %w", taskID, err)
+		}
+	case !covered && isRunning:
+		err := db.Model(&Timer{}).Where("id = ?", running.ID).Updates(map[string]any{
+			"stopped_at":         now,
+			"breached_at":        gorm.Expr("COALESCE(breached_at, CASE WHEN due_at < ? THEN due_at END)", now),
+			"next_escalation_at": nil,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to stop SLA clock of task %s: %w", taskID, err)
+		}
+	}
+	return nil
+}
+
+// WorkflowTimers returns the latest clock of each task of a workflow, by task ID.
+func WorkflowTimers(ctx context.Context, db *gorm.DB, workflowID string) (map[string]Timer, error) {
+	var timers []Timer
+	if err := db.WithContext(ctx).Where("workflow_id = ?", workflowID).Order("started_at").Find(&timers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load SLA clocks of workflow %s: %w", workflowID, err)
+	}
+	latest := make(map[string]Timer, len(timers))
+	for _, t := range timers {
+		latest[t.TaskID] = t
+	}
+	return latest, nil
+}
+
+// dueTimer is a running clock the monitor has to act on, with the task it belongs to.
+type dueTimer struct {
+	Timer
+	SLA json.RawMessage `gorm:"column:sla;serializer:json"`
+}
+
+// listDue returns up to limit running clocks whose next escalation time has come.
+func (s *Store) listDue(ctx context.Context, now time.Time, limit int) ([]dueTimer, error) {
+	var due []dueTimer
+	err := s.db.WithContext(ctx).
+		Table("task_sla_timers s").
+		Select("s.*, t.sla").
+		Joins("JOIN task_infos t ON t.id = s.task_id").
+		Where("s.stopped_at IS NULL AND s.next_escalation_at <= ?", now).
+		Order("s.next_escalation_at").
+		Limit(limit).
+		Find(&due).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due SLA clocks: %w", err)
+	}
+	return due, nil
+}
+
+// advance records on a running clock that it has breached and that escalations have been
+// fired, scheduling the next escalation at next. The update only applies while the clock runs
+// and still has the escalation count the caller read, so on several replicas each
+// escalation is claimed once; advance reports whether it was.
+func (s *Store) advance(ctx context.Context, t *Timer, escalations int, next *time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&Timer{}).
+		Where("id = ? AND escalations = ? AND stopped_at IS NULL", t.ID, t.Escalations).
+		Updates(map[string]any{
+			"escalations":        escalations,
+			"breached_at":        gorm.Expr("COALESCE(breached_at, due_at)"),
+			"next_escalation_at": next,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to advance SLA clock %s: %w", t.ID, result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// taskContacts is the node template and agency of a task, read for breach notifications.
+type taskContacts struct {
+	TaskName        string  `gorm:"column:task_name"`
+	AgencyCode      string  `gorm:"column:agency_code"`
+	AgencyName      string  `gorm:"column:agency_name"`
+	OfficerEmail    *string `gorm:"column:officer_email"`
+	SupervisorEmail *string `gorm:"column:supervisor_email"`
+}
+
+// contacts returns the node template name and agency contacts of a task. The agency is the
+// one named in the task configuration.
+func (s *Store) contacts(ctx context.Context, taskID string) (*taskContacts, error) {
+	var c taskContacts
+	err := s.db.WithContext(ctx).
+		Table("task_infos t").
+		Select("COALESCE(n.name, t.workflow_node_template_id) AS task_name, COALESCE(t.config->>'agency', '') AS agency_code, COALESCE(a.name, '') AS agency_name, a.officer_email, a.supervisor_email").
+		Joins("LEFT JOIN workflow_node_templates n ON n.id = t.workflow_node_template_id").
+		Joins("LEFT JOIN oga_agencies a ON a.code = t.config->>'agency'").
+		Where("t.id = ?", taskID).
+		Take(&c).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contacts of task %s: %w", taskID, err)
+	}
+	return &c, nil
+}
@@ -21,7 +21,7 @@ import (
 func TestDownloadContent_LocalDriver_Success(t *testing.T) {
 	tempDir := t.TempDir()
 	driver, _ := drivers.NewLocalFSDriver(tempDir, "/api/v1/uploads", "local-dev-secret", 15*time.Minute)
-	service := NewUploadService(driver)
+	service := NewUploadService(driver, nil)
 	handler := NewHTTPHandler(service)
 
 	ctx := context.Background()
@@ -68,7 +68,7 @@ func withAuthContext(ctx context.Context, ac *auth.AuthContext) context.Context
 }
 
 func TestDownload_MissingKey(t *testing.T) {
-	handler := NewHTTPHandler(NewUploadService(&MockDriver{}))
+	handler := NewHTTPHandler(NewUploadService(&MockDriver{}, nil))
 
 	req := httptest.NewRequest(http.MethodGet, "/files/", nil)
 	// Auth present, but no path value for "key".
@@ -87,7 +87,7 @@ func TestDownload_MissingKey(t *testing.T) {
 
 func TestDownload_Success(t *testing.T) {
 	mock := &MockDriver{}
-	handler := NewHTTPHandler(NewUploadService(mock))
+	handler := NewHTTPHandler(NewUploadService(mock, nil))
 
 	// Build request with auth context and path value.
 	mux := http.NewServeMux()
@@ -128,7 +128,7 @@ func TestDownload_GenerateURLError(t *testing.T) {
 	mock := &MockDriver{
 		GenerateURLErr: errors.New("presign failure"),
 	}
-	handler := NewHTTPHandler(NewUploadService(mock))
+	handler := NewHTTPHandler(NewUploadService(mock, nil))
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /files/{key}", handler.Download)
@@ -153,7 +153,7 @@ func TestDownload_GenerateURLError(t *testing.T) {
 }
 
 func TestDownload_InvalidKeyFormat(t *testing.T) {
-	handler := NewHTTPHandler(NewUploadService(&MockDriver{}))
+	handler := NewHTTPHandler(NewUploadService(&MockDriver{}, nil))
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /files/{key}", handler.Download)
@@ -174,7 +174,7 @@ func TestDownload_InvalidKeyFormat(t *testing.T) {
 }
 
 func TestUpload_Unauthorized(t *testing.T) {
-	handler := NewHTTPHandler(NewUploadService(&MockDriver{}))
+	handler := NewHTTPHandler(NewUploadService(&MockDriver{}, nil))
 
 	body := map[string]any{
 		"filename":  "test.pdf",
@@ -196,7 +196,7 @@ func TestUpload_Unauthorized(t *testing.T) {
 
 func TestUpload_Success(t *testing.T) {
 	mock := &MockDriver{}
-	handler := NewHTTPHandler(NewUploadService(mock))
+	handler := NewHTTPHandler(NewUploadService(mock, nil))
 
 	body := map[string]any{
 		"filename":  "test.pdf",
@@ -235,7 +235,7 @@ func TestUpload_Success(t *testing.T) {
 func TestUploadContentLocal_Success(t *testing.T) {
 	tempDir := t.TempDir()
 	driver, _ := drivers.NewLocalFSDriver(tempDir, "/api/v1/uploads", "local-dev-secret", 15*time.Minute)
-	service := NewUploadService(driver)
+	service := NewUploadService(driver, nil)
 	handler := NewHTTPHandler(service)
 
 	key := "550e8400-e29b-41d4-a716-446655440000.pdf"
@@ -282,7 +282,7 @@ func TestUploadContentLocal_Success(t *testing.T) {
 }
 
 func TestDelete_Unauthorized(t *testing.T) {
-	handler := NewHTTPHandler(NewUploadService(&MockDriver{}))
+	handler := NewHTTPHandler(NewUploadService(&MockDriver{}, nil))
 
 	req := httptest.NewRequest(http.MethodDelete, "/uploads/550e8400-e29b-41d4-a716-446655440000.pdf", nil)
 	req.SetPathValue("key", "550e8400-e29b-41d4-a716-446655440000.pdf")
@@ -297,7 +297,7 @@ func TestDelete_Unauthorized(t *testing.T) {
 
 func TestDownloadContent_NonLocalDriver_NotFound(t *testing.T) {
 	// For non-local drivers, DownloadContent should be disabled and return 404
-	handler := NewHTTPHandler(NewUploadService(&MockDriver{}))
+	handler := NewHTTPHandler(NewUploadService(&MockDriver{}, nil))
 
 	req := httptest.NewRequest(http.MethodGet, "/uploads/550e8400-e29b-41d4-a716-446655440000.pdf/content", nil)
 	req.SetPathValue("key", "550e8400-e29b-41d4-a716-446655440000.pdf")
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"path/filepath"
 
+	"github.com/OpenNSW/nsw/internal/audit"
 	"github.com/OpenNSW/nsw/internal/uploads/drivers"
 	"github.com/google/uuid"
 )
@@ -14,10 +15,12 @@ import (
 // UploadService coordinates file uploads and manages metadata
 type UploadService struct {
 	Driver StorageDriver
+	// auditLog records prepared uploads and deletes; may be nil.
+	auditLog audit.Recorder
 }
 
-func NewUploadService(driver StorageDriver) *UploadService {
-	return &UploadService{Driver: driver}
+func NewUploadService(driver StorageDriver, auditLog audit.Recorder) *UploadService {
+	return &UploadService{Driver: driver, auditLog: auditLog}
 }
 
 // Upload handles the preparation of a file upload by generating a unique key
@@ -45,6 +48,17 @@ func (s *UploadService) Upload(ctx context.Context, filename string, size int64,
 		MimeType:  mime,
 	}
 
+	// The upload URL is only handed out once the upload is audited.
+	err = audit.Record(ctx, s.auditLog, audit.Entry{
+		Action:       audit.ActionUploadCreate,
+		ResourceType: audit.ResourceUpload,
+		ResourceID:   key,
+		PayloadHash:  audit.HashPayload(map[string]any{"filename": filename, "size": size, "mime": mime}),
+		Details:      map[string]any{"filename": filename, "size": size, "mimeType": mime},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare upload: %w", err)
+	}
 	slog.InfoContext(ctx, "File upload prepared", "id", id, "key", key)
 	return metadata, nil
 }
@@ -66,5 +80,12 @@ func (s *UploadService) Delete(ctx context.Context, key string) error {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
 	slog.InfoContext(ctx, "File deleted successfully", "key", key)
+	if err := audit.Record(ctx, s.auditLog, audit.Entry{
+		Action:       audit.ActionUploadDelete,
+		ResourceType: audit.ResourceUpload,
+		ResourceID:   key,
+	}); err != nil {
+		return fmt.Errorf("file deleted but not audited: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,246 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// Node and gateway types of a workflow_template_v2 definition.
+const (
+	nodeTypeStart   = "START"
+	nodeTypeTask    = "TASK"
+	nodeTypeGateway = "GATEWAY"
+	nodeTypeEnd     = "END"
+)
+
+var gatewayTypes = map[string]bool{
+	"PARALLEL_SPLIT":  true,
+	"PARALLEL_JOIN":   true,
+	"EXCLUSIVE_SPLIT": true,
+	"EXCLUSIVE_JOIN":  true,
+}
+
+// definition mirrors the JSON of a workflow definition. It is decoded here rather than
+// through the Temporal runtime's types so that every node and edge can be checked,
+// including those the runtime would reject on load.
+type definition struct {
+	ID    string           `json:"id"`
+	Nodes []definitionNode `json:"nodes"`
+	Edges []definitionEdge `json:"edges"`
+}
+
+type definitionNode struct {
+	ID             string `json:"id"`
+	Type           string `json:"type"`
+	TaskTemplateID string `json:"task_template_id"`
+	GatewayType    string `json:"gateway_type"`
+}
+
+type definitionEdge struct {
+	ID       string `json:"id"`
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
+}
+
+func lintTemplateV2(r *reporter, t *TemplateV2, nodeTemplates map[string]*model.WorkflowNodeTemplate) {
+	path := fmt.Sprintf("workflow_template_v2[%s]", t.ID)
+	if len(t.Definition) == 0 {
+		r.addf(path+".workflow_definition", "workflow definition is empty")
+		return
+	}
+	var def definition
+	if err := json.Unmarshal(t.Definition, &def); err != nil {
+		r.addf(path+".workflow_definition", "invalid workflow definition: %v", err)
+		return
+	}
+	if def.ID != "" && def.ID != t.ID {
+		r.addf(path+".workflow_definition.id", "definition id %q does not match template id", def.ID)
+	}
+
+	nodes := make(map[string]*definitionNode, len(def.Nodes))
+	var starts, ends []string
+	for i := range def.Nodes {
+		node := &def.Nodes[i]
+		nodePath := fmt.Sprintf("%s.nodes[%d]", path, i)
+		if node.ID == "" {
+			r.addf(nodePath+".id", "node has no id")
+			continue
+		}
+		if nodes[node.ID] != nil {
+			r.addf(nodePath+".id", "duplicate node id %q", node.ID)
+			continue
+		}
+		nodes[node.ID] = node
+
+		switch node.Type {
+		case nodeTypeStart:
+			starts = append(starts, node.ID)
+		case nodeTypeEnd:
+			ends = append(ends, node.ID)
+		case nodeTypeTask:
+			switch {
+			case node.TaskTemplateID == "":
+				r.addf(nodePath+".task_template_id", "task node has no task template")
+			case nodeTemplates[node.TaskTemplateID] == nil:
+				r.addf(nodePath+".task_template_id", "unknown node template %q", node.TaskTemplateID)
+			}
+		case nodeTypeGateway:
+			if !gatewayTypes[node.GatewayType] {
+				r.addf(nodePath+".gateway_type", "unknown gateway type %q", node.GatewayType)
+			}
+		default:
+			r.addf(nodePath+".type", "unknown node type %q", node.Type)
+		}
+	}
+	switch {
+	case len(starts) == 0:
+		r.addf(path+".nodes", "definition has no START node")
+	case len(starts) > 1:
+		r.addf(path+".nodes", "definition has %d START nodes: %s", len(starts), strings.Join(starts, ", "))
+	}
+	if len(ends) == 0 {
+		r.addf(path+".nodes", "definition has no END node")
+	}
+
+	edgeIDs := make(map[string]bool, len(def.Edges))
+	outgoing := make(map[string][]string, len(nodes))
+	for i, edge := range def.Edges {
+		edgePath := fmt.Sprintf("%s.edges[%d]", path, i)
+		if edge.ID == "" {
+			r.addf(edgePath+".id", "edge has no id")
+		} else if edgeIDs[edge.ID] {
+			r.addf(edgePath+".id", "duplicate edge id %q", edge.ID)
+		}
+		edgeIDs[edge.ID] = true
+
+		source, target := nodes[edge.SourceID], nodes[edge.TargetID]
+		if source == nil {
+			r.addf(edgePath+".source_id", "unknown node %q", edge.SourceID)
+		}
+		if target == nil {
+			r.addf(edgePath+".target_id", "unknown node %q", edge.TargetID)
+		}
+		if source == nil || target == nil {
+			continue
+		}
+		if target.Type == nodeTypeStart {
+			r.addf(edgePath+".target_id", "edge leads into START node %q", target.ID)
+		}
+		if source.Type == nodeTypeEnd {
+			r.addf(edgePath+".source_id", "edge leaves END node %q", source.ID)
+		}
+		outgoing[source.ID] = append(outgoing[source.ID], target.ID)
+	}
+
+	ids := make([]string, 0, len(nodes))
+	for i := range def.Nodes {
+		if node := &def.Nodes[i]; node.ID != "" && nodes[node.ID] == node {
+			ids = append(ids, node.ID)
+		}
+	}
+
+	for _, cycle := range findCycles(ids, outgoing) {
+		r.addf(path+".edges", "cycle: %s", joinCycle(cycle))
+	}
+
+	var reachable map[string]bool
+	if len(starts) == 1 {
+		reachable = reachableFrom(starts[0], outgoing)
+	}
+	for i := range def.Nodes {
+		node := &def.Nodes[i]
+		if node.ID == "" || nodes[node.ID] != node {
+			continue
+		}
+		nodePath := fmt.Sprintf("%s.nodes[%d]", path, i)
+		if reachable != nil && !reachable[node.ID] {
+			r.addf(nodePath, "node %q is not reachable from the START node", node.ID)
+		}
+		if node.Type != nodeTypeEnd && len(outgoing[node.ID]) == 0 {
+			r.addf(nodePath, "node %q has no outgoing edges and is not an END node", node.ID)
+		}
+	}
+}
+
+func reachableFrom(start string, edges map[string][]string) map[string]bool {
+	seen := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[id] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return seen
+}
+
+// findCycles returns the cycles found by a depth-first search of edges, visiting ids in
+// order. Each cycle is listed from the node where the search entered it; a cycle reachable
+// through several entry points is reported once.
+func findCycles(ids []string, edges map[string][]string) [][]string {
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make(map[string]int, len(ids))
+	var stack []string
+	var cycles [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = onStack
+		stack = append(stack, id)
+		for _, next := range edges[id] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case onStack:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == next {
+						cycles = append(cycles, append([]string(nil), stack[i:]...))
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+	for _, id := range ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+	return cycles
+}
+
+func joinCycle(cycle []string) string {
+	return strings.Join(append(cycle, cycle[0]), " -> ")
+}
+
+// TaskTemplateIDs returns the node templates the TASK nodes of a workflow definition refer
+// to, in order and without duplicates. A definition that cannot be decoded refers to none;
+// Lint reports it.
+func TaskTemplateIDs(workflowDefinition json.RawMessage) []string {
+	var def definition
+	if err := json.Unmarshal(workflowDefinition, &def); err != nil {
+		return nil
+	}
+	seen := make(map[string]bool, len(def.Nodes))
+	var ids []string
+	for _, node := range def.Nodes {
+		if node.Type == nodeTypeTask && node.TaskTemplateID != "" && !seen[node.TaskTemplateID] {
+			seen[node.TaskTemplateID] = true
+			ids = append(ids, node.TaskTemplateID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,263 @@
+// Package lint checks workflow templates before they are run. Node templates are checked
+// for known plugin types, plugin configurations that the task factory can build, and
+// dependencies and unlock configurations that reference existing templates without
+// cycles, and valid SLA policies. Legacy workflow templates are checked for missing node templates, and
+// workflow_template_v2 definitions for a well-formed graph: one start node, reachable
+// nodes, no cycles and no dead ends.
+//
+// Every problem is reported as an Issue whose Path locates it, e.g.
+// workflow_template_v2[fcau-v1].nodes[3].task_template_id.
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/task/sla"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// Issue is a single problem found in a template.
+type Issue struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return i.Path + ": " + i.Message
+}
+
+// TemplateV2 is a workflow_template_v2 row. Its definition is kept as raw JSON so that
+// malformed definitions can be reported rather than failing to load.
+type TemplateV2 struct {
+	ID         string          `gorm:"type:text;column:id;primaryKey" json:"id"`
+	Name       string          `gorm:"type:varchar(100);column:name" json:"name"`
+	Version    string          `gorm:"type:varchar(50);column:version" json:"version"`
+	Definition json.RawMessage `gorm:"type:jsonb;column:workflow_definition;serializer:json" json:"workflow_definition"`
+}
+
+func (t *TemplateV2) TableName() string {
+	return "workflow_template_v2"
+}
+
+// Bundle is the set of templates linted together. References are resolved within the
+// bundle, so it should hold every node template the workflow templates use.
+type Bundle struct {
+	NodeTemplates       []model.WorkflowNodeTemplate `json:"nodeTemplates"`
+	WorkflowTemplates   []model.WorkflowTemplate     `json:"workflowTemplates"`
+	WorkflowTemplatesV2 []TemplateV2                 `json:"workflowTemplatesV2"`
+}
+
+// Merge adds the templates in other to b. A template in other replaces the template in b
+// with the same ID.
+func (b *Bundle) Merge(other Bundle) {
+	b.NodeTemplates = mergeByID(b.NodeTemplates, other.NodeTemplates, func(t model.WorkflowNodeTemplate) string { return t.ID })
+	b.WorkflowTemplates = mergeByID(b.WorkflowTemplates, other.WorkflowTemplates, func(t model.WorkflowTemplate) string { return t.ID })
+	b.WorkflowTemplatesV2 = mergeByID(b.WorkflowTemplatesV2, other.WorkflowTemplatesV2, func(t TemplateV2) string { return t.ID })
+}
+
+func mergeByID[T any](base, overrides []T, id func(T) string) []T {
+	index := make(map[string]int, len(base))
+	for i, item := range base {
+		index[id(item)] = i
+	}
+	for _, item := range overrides {
+		if i, ok := index[id(item)]; ok {
+			base[i] = item
+			continue
+		}
+		index[id(item)] = len(base)
+		base = append(base, item)
+	}
+	return base
+}
+
+// Linter checks a Bundle of templates.
+type Linter struct {
+	factory plugin.TaskFactory
+}
+
+// NewLinter creates a Linter that builds plugin configurations with factory.
+// factory may be nil, in which case plugin configurations are not checked.
+func NewLinter(factory plugin.TaskFactory) *Linter {
+	return &Linter{factory: factory}
+}
+
+// Lint checks every template in b and returns the issues found, ordered by path.
+// No plugin is started: executors are built and discarded.
+func (l *Linter) Lint(ctx context.Context, b Bundle) []Issue {
+	var r reporter
+	nodeTemplates := make(map[string]*model.WorkflowNodeTemplate, len(b.NodeTemplates))
+	for i := range b.NodeTemplates {
+		t := &b.NodeTemplates[i]
+		if _, dup := nodeTemplates[t.ID]; dup {
+			r.addf(nodeTemplatePath(t.ID), "duplicate node template id")
+		}
+		nodeTemplates[t.ID] = t
+	}
+
+	for i := range b.NodeTemplates {
+		l.lintNodeTemplate(ctx, &r, &b.NodeTemplates[i], nodeTemplates)
+	}
+	lintDependencyCycles(&r, b.NodeTemplates)
+	for i := range b.WorkflowTemplates {
+		lintWorkflowTemplate(&r, &b.WorkflowTemplates[i], nodeTemplates)
+	}
+	for i := range b.WorkflowTemplatesV2 {
+		lintTemplateV2(&r, &b.WorkflowTemplatesV2[i], nodeTemplates)
+	}
+
+	sort.SliceStable(r.issues, func(i, j int) bool { return r.issues[i].Path < r.issues[j].Path })
+	return r.issues
+}
+
+func (l *Linter) lintNodeTemplate(ctx context.Context, r *reporter, t *model.WorkflowNodeTemplate, nodeTemplates map[string]*model.WorkflowNodeTemplate) {
+	path := nodeTemplatePath(t.ID)
+	if t.ID == "" {
+		r.addf(path, "node template has no id")
+	}
+
+	switch t.Type {
+	case model.WorkFlowNodeTypeEndNode:
+	case plugin.TaskTypeSimpleForm, plugin.TaskTypeWaitForEvent, plugin.TaskTypePayment:
+		if l.factory != nil {
+			if _, err := l.factory.BuildExecutor(ctx, t.Type, t.Config); err != nil {
+				r.addf(path+".config", "invalid %s configuration: %v", t.Type, err)
+			}
+		}
+	default:
+		r.addf(path+".type", "unknown node template type %q", t.Type)
+	}
+
+	for i, dep := range t.DependsOn {
+		depPath := fmt.Sprintf("%s.depends_on[%d]", path, i)
+		switch {
+		case dep == t.ID:
+			r.addf(depPath, "node template depends on itself")
+		case nodeTemplates[dep] == nil:
+			r.addf(depPath, "unknown node template %q", dep)
+		}
+	}
+
+	if len(t.SLA) > 0 {
+		if t.Type == model.WorkFlowNodeTypeEndNode {
+			r.addf(path+".sla", "end nodes cannot have an SLA")
+		} else if _, err := sla.ParsePolicy(t.SLA); err != nil {
+			r.addf(path+".sla", "%v", err)
+		}
+	}
+
+	if t.UnlockConfiguration != nil {
+		unlockPath := path + ".unlockConfiguration"
+		if err := t.UnlockConfiguration.Validate(); err != nil {
+			r.addf(unlockPath, "%v", err)
+		}
+		for _, ref := range unlockReferences(t.UnlockConfiguration) {
+			if nodeTemplates[ref.id] == nil {
+				r.addf(unlockPath+ref.path, "unknown node template %q", ref.id)
+			}
+		}
+	}
+}
+
+type unlockReference struct {
+	path string
+	id   string
+}
+
+// unlockReferences lists the node templates an unlock configuration refers to.
+func unlockReferences(uc *model.UnlockConfig) []unlockReference {
+	var refs []unlockReference
+	for i, group := range uc.AnyOf {
+		for j, cond := range group.AllOf {
+			if cond.NodeTemplateID != "" {
+				refs = append(refs, unlockReference{fmt.Sprintf(".anyOf[%d].allOf[%d].nodeTemplateId", i, j), cond.NodeTemplateID})
+			}
+		}
+	}
+	var walk func(expr model.UnlockExpression, path string)
+	walk = func(expr model.UnlockExpression, path string) {
+		if expr.NodeTemplateID != "" {
+			refs = append(refs, unlockReference{path + ".nodeTemplateId", expr.NodeTemplateID})
+		}
+		for i, child := range expr.AnyOf {
+			walk(child, fmt.Sprintf("%s.anyOf[%d]", path, i))
+		}
+		for i, child := range expr.AllOf {
+			walk(child, fmt.Sprintf("%s.allOf[%d]", path, i))
+		}
+	}
+	if uc.Expression != nil {
+		walk(*uc.Expression, ".expression")
+	}
+	return refs
+}
+
+// lintDependencyCycles reports each cycle in the depends_on graph once, at the node
+// template where it was first entered.
+func lintDependencyCycles(r *reporter, templates []model.WorkflowNodeTemplate) {
+	edges := make(map[string][]string, len(templates))
+	ids := make([]string, 0, len(templates))
+	for _, t := range templates {
+		ids = append(ids, t.ID)
+		for _, dep := range t.DependsOn {
+			// Self-dependencies are reported by lintNodeTemplate.
+			if dep != t.ID {
+				edges[t.ID] = append(edges[t.ID], dep)
+			}
+		}
+	}
+	for _, cycle := range findCycles(ids, edges) {
+		r.addf(nodeTemplatePath(cycle[0])+".depends_on", "dependency cycle: %s", joinCycle(cycle))
+	}
+}
+
+func lintWorkflowTemplate(r *reporter, t *model.WorkflowTemplate, nodeTemplates map[string]*model.WorkflowNodeTemplate) {
+	path := fmt.Sprintf("workflow_templates[%s]", t.ID)
+	if len(t.NodeTemplates) == 0 {
+		r.addf(path+".nodes", "workflow template has no nodes")
+	}
+
+	inWorkflow := make(map[string]bool, len(t.NodeTemplates))
+	for i, id := range t.NodeTemplates {
+		nodePath := fmt.Sprintf("%s.nodes[%d]", path, i)
+		if inWorkflow[id] {
+			r.addf(nodePath, "node template %q is listed more than once", id)
+		}
+		inWorkflow[id] = true
+		if nodeTemplates[id] == nil {
+			r.addf(nodePath, "unknown node template %q", id)
+		}
+	}
+
+	for i, id := range t.NodeTemplates {
+		nt := nodeTemplates[id]
+		if nt == nil {
+			continue
+		}
+		for _, dep := range nt.DependsOn {
+			if !inWorkflow[dep] {
+				r.addf(fmt.Sprintf("%s.nodes[%d]", path, i), "node template %q depends on %q, which is not in the workflow", id, dep)
+			}
+		}
+	}
+
+	if t.EndNodeTemplateID != nil && !inWorkflow[*t.EndNodeTemplateID] {
+		r.addf(path+".endNodeTemplateId", "end node template %q is not in the workflow", *t.EndNodeTemplateID)
+	}
+}
+
+func nodeTemplatePath(id string) string {
+	return fmt.Sprintf("workflow_node_templates[%s]", id)
+}
+
+type reporter struct {
+	issues []Issue
+}
+
+func (r *reporter) addf(path, format string, args ...any) {
+	r.issues = append(r.issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,244 @@
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/OpenNSW/nsw/internal/task/plugin"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// fakeFactory rejects configurations that are not JSON objects, as the plugin
+// constructors do when unmarshalling into their config structs.
+type fakeFactory struct{}
+
+func (fakeFactory) BuildExecutor(_ context.Context, _ plugin.Type, config json.RawMessage) (plugin.Executor, error) {
+	var v map[string]any
+	if err := json.Unmarshal(config, &v); err != nil {
+		return plugin.Executor{}, errors.New("failed to unmarshal config")
+	}
+	return plugin.Executor{}, nil
+}
+
+func nodeTemplate(id string, taskType plugin.Type, dependsOn ...string) model.WorkflowNodeTemplate {
+	return model.WorkflowNodeTemplate{
+		BaseModel: model.BaseModel{ID: id},
+		Type:      taskType,
+		Config:    json.RawMessage(`{}`),
+		DependsOn: dependsOn,
+	}
+}
+
+func slaNodeTemplate(id string, taskType plugin.Type, dependsOn ...string) model.WorkflowNodeTemplate {
+	t := nodeTemplate(id, taskType, dependsOn...)
+	t.SLA = json.RawMessage(`{"duration": "2d", "businessCalendar": true, "escalations": [{"action": "NOTIFY", "notify": ["OFFICER"]}, {"after": "1d", "action": "FAIL"}]}`)
+	return t
+}
+
+func templateV2(id, definition string) TemplateV2 {
+	return TemplateV2{ID: id, Definition: json.RawMessage(definition)}
+}
+
+func paths(issues []Issue) []string {
+	out := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		out = append(out, issue.Path)
+	}
+	return out
+}
+
+const validDefinition = `{
+	"id": "wf",
+	"nodes": [
+		{"id": "start", "type": "START"},
+		{"id": "form", "type": "TASK", "task_template_id": "nt-form"},
+		{"id": "gw", "type": "GATEWAY", "gateway_type": "EXCLUSIVE_SPLIT"},
+		{"id": "pay", "type": "TASK", "task_template_id": "nt-pay"},
+		{"id": "end", "type": "END"}
+	],
+	"edges": [
+		{"id": "e1", "source_id": "start", "target_id": "form"},
+		{"id": "e2", "source_id": "form", "target_id": "gw"},
+		{"id": "e3", "source_id": "gw", "target_id": "pay", "condition": "x == 1"},
+		{"id": "e4", "source_id": "gw", "target_id": "end", "condition": "x != 1"},
+		{"id": "e5", "source_id": "pay", "target_id": "end"}
+	]
+}`
+
+func TestLinter_ValidBundle(t *testing.T) {
+	bundle := Bundle{
+		NodeTemplates: []model.WorkflowNodeTemplate{
+			slaNodeTemplate("nt-form", plugin.TaskTypeSimpleForm),
+			nodeTemplate("nt-pay", plugin.TaskTypePayment, "nt-form"),
+			nodeTemplate("nt-end", model.WorkFlowNodeTypeEndNode, "nt-pay"),
+		},
+		WorkflowTemplates: []model.WorkflowTemplate{{
+			BaseModel:     model.BaseModel{ID: "legacy"},
+			NodeTemplates: model.StringArray{"nt-form", "nt-pay", "nt-end"},
+		}},
+		WorkflowTemplatesV2: []TemplateV2{templateV2("wf", validDefinition)},
+	}
+
+	if issues := NewLinter(fakeFactory{}).Lint(context.Background(), bundle); len(issues) != 0 {
+		t.Fatalf("unexpected issues: %v", issues)
+	}
+}
+
+func TestLinter_NodeTemplates(t *testing.T) {
+	broken := nodeTemplate("nt-broken", plugin.TaskTypeSimpleForm)
+	broken.Config = json.RawMessage(`"not an object"`)
+	stateCompleted := "COMPLETED"
+	unlocked := nodeTemplate("nt-unlock", plugin.TaskTypeSimpleForm)
+	unlocked.UnlockConfiguration = &model.UnlockConfig{
+		Expression: &model.UnlockExpression{AnyOf: []model.UnlockExpression{
+			{NodeTemplateID: "nt-a", State: &stateCompleted},
+			{NodeTemplateID: "nt-missing", State: &stateCompleted},
+		}},
+	}
+	badSLA := nodeTemplate("nt-sla", plugin.TaskTypeSimpleForm)
+	badSLA.SLA = json.RawMessage(`{"duration": "3d", "escalations": [{"action": "NOTIFY"}]}`)
+
+	bundle := Bundle{
+		NodeTemplates: []model.WorkflowNodeTemplate{
+			nodeTemplate("nt-a", plugin.TaskTypeSimpleForm, "nt-b"),
+			nodeTemplate("nt-b", plugin.TaskTypeSimpleForm, "nt-a"),
+			nodeTemplate("nt-self", plugin.TaskTypeSimpleForm, "nt-self"),
+			nodeTemplate("nt-unknown", "SEND_EMAIL", "nt-gone"),
+			broken,
+			unlocked,
+			badSLA,
+		},
+		WorkflowTemplates: []model.WorkflowTemplate{{
+			BaseModel:     model.BaseModel{ID: "legacy"},
+			NodeTemplates: model.StringArray{"nt-a", "nt-gone"},
+		}},
+	}
+
+	issues := NewLinter(fakeFactory{}).Lint(context.Background(), bundle)
+	want := []string{
+		"workflow_node_templates[nt-a].depends_on",
+		"workflow_node_templates[nt-broken].config",
+		"workflow_node_templates[nt-self].depends_on[0]",
+		"workflow_node_templates[nt-sla].sla",
+		"workflow_node_templates[nt-unknown].depends_on[0]",
+		"workflow_node_templates[nt-unknown].type",
+		"workflow_node_templates[nt-unlock].unlockConfiguration.expression.anyOf[1].nodeTemplateId",
+		"workflow_templates[legacy].nodes[0]",
+		"workflow_templates[legacy].nodes[1]",
+	}
+	if got := paths(issues); !reflect.DeepEqual(got, want) {
+		t.Fatalf("issue paths = %v\nwant %v\nissues: %v", got, want, issues)
+	}
+	if issues[0].Message != "dependency cycle: nt-a -> nt-b -> nt-a" {
+		t.Fatalf("cycle message = %q", issues[0].Message)
+	}
+}
+
+func TestLinter_WorkflowDefinitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		want       []string
+	}{
+		{
+			name:       "Malformed JSON",
+			definition: `{"nodes": [`,
+			want:       []string{"workflow_template_v2[wf].workflow_definition"},
+		},
+		{
+			name: "Unknown Task Template And Gateway Type",
+			definition: `{"nodes": [
+				{"id": "start", "type": "START"},
+				{"id": "task", "type": "TASK", "task_template_id": "nt-missing"},
+				{"id": "gw", "type": "GATEWAY", "gateway_type": "INCLUSIVE"},
+				{"id": "end", "type": "END"}
+			], "edges": [
+				{"id": "e1", "source_id": "start", "target_id": "task"},
+				{"id": "e2", "source_id": "task", "target_id": "gw"},
+				{"id": "e3", "source_id": "gw", "target_id": "end"}
+			]}`,
+			want: []string{
+				"workflow_template_v2[wf].nodes[1].task_template_id",
+				"workflow_template_v2[wf].nodes[2].gateway_type",
+			},
+		},
+		{
+			name: "Cycle, Unreachable Node And Dead End",
+			definition: `{"nodes": [
+				{"id": "start", "type": "START"},
+				{"id": "a", "type": "TASK", "task_template_id": "nt-form"},
+				{"id": "b", "type": "TASK", "task_template_id": "nt-form"},
+				{"id": "orphan", "type": "TASK", "task_template_id": "nt-form"},
+				{"id": "end", "type": "END"}
+			], "edges": [
+				{"id": "e1", "source_id": "start", "target_id": "a"},
+				{"id": "e2", "source_id": "a", "target_id": "b"},
+				{"id": "e3", "source_id": "b", "target_id": "a"},
+				{"id": "e4", "source_id": "b", "target_id": "end"},
+				{"id": "e4", "source_id": "end", "target_id": "ghost"}
+			]}`,
+			want: []string{
+				"workflow_template_v2[wf].edges",
+				"workflow_template_v2[wf].edges[4].id",
+				"workflow_template_v2[wf].edges[4].target_id",
+				"workflow_template_v2[wf].nodes[3]",
+				"workflow_template_v2[wf].nodes[3]",
+			},
+		},
+		{
+			name:       "Missing Start And End",
+			definition: `{"nodes": [{"id": "a", "type": "TASK", "task_template_id": "nt-form"}], "edges": []}`,
+			want: []string{
+				"workflow_template_v2[wf].nodes",
+				"workflow_template_v2[wf].nodes",
+				"workflow_template_v2[wf].nodes[0]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := Bundle{
+				NodeTemplates:       []model.WorkflowNodeTemplate{nodeTemplate("nt-form", plugin.TaskTypeSimpleForm)},
+				WorkflowTemplatesV2: []TemplateV2{templateV2("wf", tt.definition)},
+			}
+			issues := NewLinter(nil).Lint(context.Background(), bundle)
+			if got := paths(issues); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("issue paths = %v\nwant %v\nissues: %v", got, tt.want, issues)
+			}
+		})
+	}
+}
+
+func TestLoadFile_MergeOverridesByID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.json")
+	file := `{
+		"nodeTemplates": [{"id": "nt-form", "type": "PAYMENT", "config": {}, "depends_on": []}],
+		"workflowTemplatesV2": [{"id": "wf", "workflow_definition": {"nodes": []}}]
+	}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fromFile, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bundle := Bundle{NodeTemplates: []model.WorkflowNodeTemplate{
+		nodeTemplate("nt-form", plugin.TaskTypeSimpleForm),
+		nodeTemplate("nt-other", plugin.TaskTypeSimpleForm),
+	}}
+	bundle.Merge(fromFile)
+
+	if len(bundle.NodeTemplates) != 2 || bundle.NodeTemplates[0].Type != plugin.TaskTypePayment {
+		t.Fatalf("node templates = %+v, want nt-form replaced by the file's PAYMENT template", bundle.NodeTemplates)
+	}
+	if len(bundle.WorkflowTemplatesV2) != 1 || string(bundle.WorkflowTemplatesV2[0].Definition) != `{"nodes": []}` {
+		t.Fatalf("workflow templates v2 = %+v", bundle.WorkflowTemplatesV2)
+	}
+}
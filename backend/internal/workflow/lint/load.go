@@ -0,0 +1,41 @@
+package lint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+// Load reads every node template, legacy workflow template and workflow_template_v2
+// definition from db.
+func Load(ctx context.Context, db *gorm.DB) (Bundle, error) {
+	var b Bundle
+	db = db.WithContext(ctx)
+	if err := db.Order("id").Find(&b.NodeTemplates).Error; err != nil {
+		return Bundle{}, fmt.Errorf("failed to load workflow node templates: %w", err)
+	}
+	if err := db.Order("id").Find(&b.WorkflowTemplates).Error; err != nil {
+		return Bundle{}, fmt.Errorf("failed to load workflow templates: %w", err)
+	}
+	if err := db.Order("id").Find(&b.WorkflowTemplatesV2).Error; err != nil {
+		return Bundle{}, fmt.Errorf("failed to load workflow_template_v2 templates: %w", err)
+	}
+	return b, nil
+}
+
+// LoadFile reads a Bundle from a JSON file with the keys nodeTemplates, workflowTemplates
+// and workflowTemplatesV2, each holding templates in their API JSON form.
+func LoadFile(path string) (Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Bundle{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return b, nil
+}
@@ -357,6 +357,7 @@ func (m *workflowManager) registerNodesWithTaskManager(ctx context.Context, work
 			Type:                   nodeTemplate.Type,
 			GlobalState:            globalContext,
 			Config:                 nodeTemplate.Config,
+			SLA:                    nodeTemplate.SLA,
 		}
 		response, err := initTaskCallback(ctx, initTaskRequest)
 		if err != nil {
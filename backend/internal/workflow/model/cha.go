@@ -6,6 +6,8 @@ type CHA struct {
 	Name        string `gorm:"type:varchar(255);column:name;not null" json:"name"`
 	Description string `gorm:"type:text;column:description" json:"description"`
 	Email       string `gorm:"type:varchar(255);column:email" json:"email,omitempty"`
+	// OUID is the IdP organisation unit whose users act for this CHA.
+	OUID *string `gorm:"type:varchar(255);column:ou_id" json:"ouId,omitempty"`
 }
 
 func (c *CHA) TableName() string {
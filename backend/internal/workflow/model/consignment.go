@@ -130,7 +130,7 @@ type ConsignmentListResult struct {
 }
 
 // ConsignmentFilter will be used when querying consignments as batch.
-// For GET /consignments?role=trader use TraderID; for role=cha use ChaID (e.g. from query param cha_id).
+// TraderID and ChaID are ignored when listing on behalf of a ConsignmentViewer, whose roles scope the query.
 type ConsignmentFilter struct {
 	TraderID *string           `json:"traderId,omitempty"`
 	ChaID    *string           `json:"chaId,omitempty"`
@@ -139,3 +139,24 @@ type ConsignmentFilter struct {
 	Offset   *int              `json:"offset,omitempty"`
 	Limit    *int              `json:"limit,omitempty"`
 }
+
+// ConsignmentViewerRole is a capacity in which a user may read consignments.
+type ConsignmentViewerRole string
+
+const (
+	// ConsignmentViewerTrader sees the consignments the user lodged.
+	ConsignmentViewerTrader ConsignmentViewerRole = "trader"
+	// ConsignmentViewerCHA sees the consignments assigned to the CHA the user acts for.
+	ConsignmentViewerCHA ConsignmentViewerRole = "cha"
+	// ConsignmentViewerOGA sees the consignments whose workflows include tasks of the user's agency.
+	ConsignmentViewerOGA ConsignmentViewerRole = "oga"
+)
+
+// ConsignmentViewer is a user reading consignments. A consignment is visible when it is
+// visible to the user in any of Roles.
+type ConsignmentViewer struct {
+	UserID string
+	Email  string
+	OUID   string
+	Roles  []ConsignmentViewerRole
+}
@@ -0,0 +1,37 @@
+package model
+
+import "encoding/json"
+
+// IsDraft reports whether the template can still be changed.
+func (l TemplateLifecycle) IsDraft() bool {
+	return l.Status == TemplateStatusDraft
+}
+
+// NodeTemplateRequest is the body of a request to create or update a draft node template.
+type NodeTemplateRequest struct {
+	ID                  string                   `json:"id"` // Optional on create; generated when empty. Ignored on update.
+	Name                string                   `json:"name"`
+	Description         string                   `json:"description"`
+	Type                WorkflowNodeTemplateType `json:"type"`
+	Config              json.RawMessage          `json:"config"`
+	DependsOn           StringArray              `json:"depends_on"`
+	UnlockConfiguration *UnlockConfig            `json:"unlockConfiguration,omitempty"`
+	SLA                 json.RawMessage          `json:"sla,omitempty"`
+	BasedOnID           *string                  `json:"basedOnId,omitempty"` // Published node template this draft revises
+}
+
+// WorkflowTemplateRequest is the body of a request to create or update a draft workflow template.
+type WorkflowTemplateRequest struct {
+	ID                 string          `json:"id"` // Optional on create; generated when empty. Ignored on update.
+	Name               string          `json:"name"`
+	Version            string          `json:"version"`
+	WorkflowDefinition json.RawMessage `json:"workflow_definition"`
+	BasedOnID          *string         `json:"basedOnId,omitempty"` // Published workflow template this draft revises
+}
+
+// WorkflowTemplateMapRequest is the body of a request to create or update an HS code mapping.
+type WorkflowTemplateMapRequest struct {
+	HSCodeID           string          `json:"hsCodeId"`
+	ConsignmentFlow    ConsignmentFlow `json:"consignmentFlow"`
+	WorkflowTemplateID string          `json:"workflowTemplateId"`
+}
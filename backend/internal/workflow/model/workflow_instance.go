@@ -24,6 +24,14 @@ type Workflow struct {
 	GlobalContext map[string]any `gorm:"type:jsonb;column:global_context;serializer:json;not null" json:"globalContext"`
 	EndNodeID     *string        `gorm:"type:text;column:end_node_id" json:"endNodeId,omitempty"`
 
+	// Template pinning. Workflows run by the workflow engine record the workflow_template_v2 they
+	// were started from; a migration repoints them and records how their nodes map to it.
+	WorkflowTemplateID      *string           `gorm:"type:text;column:workflow_template_id" json:"workflowTemplateId,omitempty"`                        // Template the workflow runs
+	WorkflowTemplateVersion *string           `gorm:"type:varchar(50);column:workflow_template_version" json:"workflowTemplateVersion,omitempty"`       // Version of that template
+	NodeMapping             map[string]string `gorm:"type:jsonb;column:node_mapping;serializer:json" json:"nodeMapping,omitempty"`                      // Engine node ID → node ID in the template, where they differ
+	TaskTemplateOverrides   map[string]string `gorm:"type:jsonb;column:task_template_overrides;serializer:json" json:"taskTemplateOverrides,omitempty"` // Engine node ID → node template it starts, set by migrations
+	MigratedAt              *time.Time        `gorm:"type:timestamptz;column:migrated_at" json:"migratedAt,omitempty"`                                  // When the workflow was last migrated
+
 	// Relationships
 	WorkflowNodes []WorkflowNode `gorm:"foreignKey:WorkflowID;references:ID" json:"workflowNodes,omitempty"`
 }
@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+// WorkflowInstanceSummary describes a workflow pinned to a workflow template.
+type WorkflowInstanceSummary struct {
+	ID                      string         `json:"id"`
+	Status                  WorkflowStatus `json:"status"`
+	WorkflowTemplateID      string         `json:"workflowTemplateId"`
+	WorkflowTemplateVersion string         `json:"workflowTemplateVersion"`
+	MigratedAt              *time.Time     `json:"migratedAt,omitempty"`
+	CreatedAt               time.Time      `json:"createdAt"`
+}
+
+// WorkflowMigrationRequest is the body of a request to move running workflows from one published
+// workflow template to another.
+type WorkflowMigrationRequest struct {
+	FromTemplateID string            `json:"fromTemplateId"`
+	ToTemplateID   string            `json:"toTemplateId"`
+	NodeMapping    map[string]string `json:"nodeMapping,omitempty"` // Old node ID → new node ID; nodes whose ID is unchanged may be left out
+	WorkflowIDs    []string          `json:"workflowIds,omitempty"` // Workflows to migrate; all running workflows on the old template when empty
+	DryRun         bool              `json:"dryRun"`                // Report the changes without applying them
+}
+
+// WorkflowNodeMigrationAction is what a migration does to a node of a running workflow.
+type WorkflowNodeMigrationAction string
+
+const (
+	WorkflowNodeMigrationReplace  WorkflowNodeMigrationAction = "REPLACE"  // Node has not started; it will start the new node template
+	WorkflowNodeMigrationKeep     WorkflowNodeMigrationAction = "KEEP"     // Node has already started; its task keeps the old node template
+	WorkflowNodeMigrationRenumber WorkflowNodeMigrationAction = "RENUMBER" // Only the node's ID changes
+)
+
+// WorkflowNodeChange is a task node whose ID or node template differs between the two templates.
+type WorkflowNodeChange struct {
+	NodeID             string `json:"nodeId"`
+	NewNodeID          string `json:"newNodeId"`
+	FromTaskTemplateID string `json:"fromTaskTemplateId,omitempty"`
+	ToTaskTemplateID   string `json:"toTaskTemplateId,omitempty"`
+}
+
+// WorkflowNodeMigration is a changed node of one running workflow.
+type WorkflowNodeMigration struct {
+	WorkflowNodeChange
+	Status string                      `json:"status"` // Status of the node in the workflow engine
+	Action WorkflowNodeMigrationAction `json:"action"`
+}
+
+// WorkflowMigrationDiff lists the changes a migration makes to one running workflow.
+type WorkflowMigrationDiff struct {
+	WorkflowID string                  `json:"workflowId"`
+	Nodes      []WorkflowNodeMigration `json:"nodes"`
+}
+
+// WorkflowMigrationResult reports a migration, or with DryRun what it would do.
+type WorkflowMigrationResult struct {
+	FromTemplateID string                  `json:"fromTemplateId"`
+	FromVersion    string                  `json:"fromVersion"`
+	ToTemplateID   string                  `json:"toTemplateId"`
+	ToVersion      string                  `json:"toVersion"`
+	DryRun         bool                    `json:"dryRun"`
+	Changes        []WorkflowNodeChange    `json:"changes"`   // Differences between the two templates
+	Workflows      []WorkflowMigrationDiff `json:"workflows"` // Effect on each migrated workflow
+}
@@ -31,6 +31,8 @@ type WorkflowNodeTemplate struct {
 	Config              json.RawMessage          `gorm:"type:jsonb;column:config;not null;serializer:json" json:"config"`                             // Configuration specific to the workflow node type
 	DependsOn           StringArray              `gorm:"type:jsonb;column:depends_on;not null;serializer:json" json:"depends_on"`                     // Array of workflow node template IDs this node depends on
 	UnlockConfiguration *UnlockConfig            `gorm:"type:jsonb;column:unlock_configuration;serializer:json" json:"unlockConfiguration,omitempty"` // Optional conditional unlock configuration (supports nested AND/OR boolean expressions). If nil, DependsOn uses AND-all logic.
+	SLA                 json.RawMessage          `gorm:"type:jsonb;column:sla;serializer:json" json:"sla,omitempty"`                                  // Optional SLA policy of the tasks started from the template (see package sla)
+	TemplateLifecycle
 }
 
 func (wnt *WorkflowNodeTemplate) TableName() string {
@@ -76,6 +78,17 @@ type WorkflowNodeResponseDTO struct {
 	ExtendedState        *string                         `json:"extendedState,omitempty"` // Optional extended state information (e.g., error details)
 	Outcome              *string                         `json:"outcome,omitempty"`       // Outcome sub-state when COMPLETED
 	DependsOn            []string                        `json:"depends_on"`              // Array of workflow node IDs this node depends on
+	SLA                  *WorkflowNodeSLADTO             `json:"sla,omitempty"`           // Latest SLA clock of the node's task, if its template has an SLA
+}
+
+// WorkflowNodeSLADTO represents the SLA clock of a workflow node in the response.
+type WorkflowNodeSLADTO struct {
+	Status      string  `json:"status"`               // RUNNING, MET or BREACHED
+	StartedAt   string  `json:"startedAt"`            // When the clock started
+	DueAt       string  `json:"dueAt"`                // Deadline of the task
+	StoppedAt   *string `json:"stoppedAt,omitempty"`  // When the task left the covered states
+	BreachedAt  *string `json:"breachedAt,omitempty"` // When the deadline passed, once it has
+	Escalations int     `json:"escalations"`          // Escalations fired so far
 }
 
 type WorkflowEdgeResponseDTO struct {
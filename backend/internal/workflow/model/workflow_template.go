@@ -1,6 +1,27 @@
 package model
 
-import wmv2 "github.com/OpenNSW/go-temporal-workflow"
+import (
+	"time"
+
+	wmv2 "github.com/OpenNSW/go-temporal-workflow"
+)
+
+// TemplateStatus is the lifecycle state of a node or workflow template.
+type TemplateStatus string
+
+const (
+	TemplateStatusDraft     TemplateStatus = "DRAFT"     // Template can be edited or deleted and is not used by new workflows
+	TemplateStatusPublished TemplateStatus = "PUBLISHED" // Template is immutable and can be used by new workflows
+)
+
+// TemplateLifecycle tracks the draft → published lifecycle of a template. A published template is
+// never changed; it is revised by publishing a new draft based on it.
+type TemplateLifecycle struct {
+	Status      TemplateStatus `gorm:"type:varchar(20);column:status;not null;default:PUBLISHED" json:"status"`
+	BasedOnID   *string        `gorm:"type:text;column:based_on_id" json:"basedOnId,omitempty"`            // Published template this one revises
+	PublishedAt *time.Time     `gorm:"type:timestamptz;column:published_at" json:"publishedAt,omitempty"`  // When the template was published
+	PublishedBy *string        `gorm:"type:varchar(255);column:published_by" json:"publishedBy,omitempty"` // Principal that published the template
+}
 
 type WorkflowTemplate struct {
 	BaseModel
@@ -25,6 +46,7 @@ type WorkflowTemplateV2 struct {
 	Name               string                  `gorm:"type:varchar(100);column:name;not null" json:"name"`      // Name of the workflow template
 	Version            string                  `gorm:"type:varchar(50);column:version;not null" json:"version"` // Version of the workflow template
 	WorkflowDefinition wmv2.WorkflowDefinition `gorm:"type:jsonb;column:workflow_definition;not null;serializer:json" json:"workflow_definition"`
+	TemplateLifecycle
 }
 
 func (wt *WorkflowTemplateV2) TableName() string {
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log/slog"
@@ -14,12 +15,21 @@ import (
 )
 
 type ConsignmentRouter struct {
-	cs  *service.ConsignmentService
-	cha *service.CHAService
+	cs     *service.ConsignmentService
+	access ConsignmentAccess
 }
 
-func NewConsignmentRouter(cs *service.ConsignmentService, cha *service.CHAService) *ConsignmentRouter {
-	return &ConsignmentRouter{cs: cs, cha: cha}
+// ConsignmentAccess decides whether the principal in ctx may act on the consignment behind a
+// workflow: its trader, their organisation's acting staff, the assigned CHA or its delegates.
+// The Task Engine's authorization policy implements it.
+type ConsignmentAccess interface {
+	MayActOnWorkflow(ctx context.Context, workflowID string) (bool, error)
+}
+
+// NewConsignmentRouter creates a ConsignmentRouter. Consignments are initialized only by the
+// users access lets act on them; when access is nil, none are.
+func NewConsignmentRouter(cs *service.ConsignmentService, access ConsignmentAccess) *ConsignmentRouter {
+	return &ConsignmentRouter{cs: cs, access: access}
 }
 
 // HandleCreateConsignment handles POST /api/v1/consignments
@@ -67,8 +77,10 @@ func (c *ConsignmentRouter) HandleCreateConsignment(w http.ResponseWriter, r *ht
 }
 
 // HandleGetConsignments handles GET /api/v1/consignments
-// Query params: role=trader | role=cha (defaults to trader).
-// When role=cha the CHA is resolved from the authenticated user's email.
+// Query params: role=trader | role=cha | role=oga (defaults to trader).
+// role=trader lists the user's own consignments and requires the Trader role; role=cha lists
+// those assigned to the user's CHA and requires the CHA role; role=oga lists those with tasks
+// of the agency whose organisation unit the user belongs to.
 // Pagination: offset, limit. Optional filters: state, flow.
 func (c *ConsignmentRouter) HandleGetConsignments(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -78,10 +90,15 @@ func (c *ConsignmentRouter) HandleGetConsignments(w http.ResponseWriter, r *http
 		return
 	}
 
-	// TODO: Proper AuthZ need to be implemented.
-	role := r.URL.Query().Get("role")
+	role := model.ConsignmentViewerRole(r.URL.Query().Get("role"))
 	if role == "" {
-		role = "trader"
+		role = model.ConsignmentViewerTrader
+	}
+	switch role {
+	case model.ConsignmentViewerTrader, model.ConsignmentViewerCHA, model.ConsignmentViewerOGA:
+	default:
+		http.Error(w, "query param role must be trader, cha or oga", http.StatusBadRequest)
+		return
 	}
 	offset, limit, err := utils.ParsePaginationParams(r)
 	if err != nil {
@@ -103,28 +120,12 @@ func (c *ConsignmentRouter) HandleGetConsignments(w http.ResponseWriter, r *http
 		filter.Flow = &flow
 	}
 
-	// Role-Based Identity Resolution
-	switch role {
-	case "cha":
-		cha, err := c.cha.GetCHAByEmail(ctx, authCtx.User.Email)
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				http.Error(w, "CHA profile not found", http.StatusForbidden)
-				return
-			}
-			slog.Error("failed to retrieve CHA profile", "email", authCtx.User.Email, "error", err)
-			http.Error(w, "failed to resolve default CHA profile", http.StatusInternalServerError)
+	consignments, err := c.cs.ListConsignmentsForViewer(ctx, consignmentViewer(authCtx.User, role), filter)
+	if err != nil {
+		if errors.Is(err, service.ErrConsignmentAccessDenied) {
+			http.Error(w, "user cannot list consignments as "+string(role), http.StatusForbidden)
 			return
 		}
-		filter.ChaID = &cha.ID
-	case "trader":
-		filter.TraderID = &authCtx.User.ID
-	default:
-		http.Error(w, "query param role must be trader or cha", http.StatusBadRequest)
-		return
-	}
-	consignments, err := c.cs.ListConsignments(ctx, filter)
-	if err != nil {
 		slog.Error("failed to retrieve consignments", "error", err)
 		http.Error(w, "failed to retrieve consignments", http.StatusInternalServerError)
 		return
@@ -154,8 +155,19 @@ func (c *ConsignmentRouter) HandleInitializeConsignment(w http.ResponseWriter, r
 		return
 	}
 	consignmentID := consignmentIDStr
-	// TODO: Need to Call GetConsignmentByID and check whether the Consignment.ChaID and authContext.UserID are equal
-	// Otherwise Forbidden
+	// Consignments share their workflow's ID. One the user may not act on is reported as not
+	// found, as for reads, so its existence is not disclosed.
+	allowed, err := c.mayAct(ctx, consignmentID)
+	if err != nil {
+		slog.Error("failed to authorize consignment initialization", "consignmentId", consignmentID, "error", err)
+		http.Error(w, "failed to initialize consignment", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "consignment not found", http.StatusNotFound)
+		return
+	}
+
 	var req model.InitializeConsignmentDTO
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
@@ -205,9 +217,18 @@ func (c *ConsignmentRouter) HandleGetConsignmentByID(w http.ResponseWriter, r *h
 	// Parse UUID
 	consignmentID := consignmentIDStr
 
-	// Get consignment from service
-	consignment, err := c.cs.GetConsignmentByID(r.Context(), consignmentID)
+	// Get consignment from service, limited to those the user may see in any of their roles
+	viewer := consignmentViewer(authCtx.User, model.ConsignmentViewerTrader, model.ConsignmentViewerCHA, model.ConsignmentViewerOGA)
+	consignment, err := c.cs.GetConsignmentByIDForViewer(r.Context(), consignmentID, viewer)
 	if err != nil {
+		if errors.Is(err, service.ErrConsignmentAccessDenied) {
+			http.Error(w, "user cannot view consignments", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "consignment not found", http.StatusNotFound)
+			return
+		}
 		slog.Error("failed to retrieve consignment", "error", err)
 		http.Error(w, "failed to retrieve consignment: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -222,3 +243,27 @@ func (c *ConsignmentRouter) HandleGetConsignmentByID(w http.ResponseWriter, r *h
 		return
 	}
 }
+
+// mayAct reports whether the principal in ctx may act on consignmentID.
+func (c *ConsignmentRouter) mayAct(ctx context.Context, consignmentID string) (bool, error) {
+	if c.access == nil {
+		return false, nil
+	}
+	return c.access.MayActOnWorkflow(ctx, consignmentID)
+}
+
+// consignmentViewer describes user as a consignment viewer in those of roles the user holds.
+// Trader and CHA views require the matching IdP role; the OGA view is granted by the user's
+// organisation unit, which the service maps to an agency.
+func consignmentViewer(user *auth.UserContext, roles ...model.ConsignmentViewerRole) model.ConsignmentViewer {
+	viewer := model.ConsignmentViewer{UserID: user.ID, Email: user.Email, OUID: user.OUID}
+	for _, role := range roles {
+		switch {
+		case role == model.ConsignmentViewerTrader && user.HasRole(auth.RoleTrader),
+			role == model.ConsignmentViewerCHA && user.HasRole(auth.RoleCHA),
+			role == model.ConsignmentViewerOGA && user.OUID != "":
+			viewer.Roles = append(viewer.Roles, role)
+		}
+	}
+	return viewer
+}
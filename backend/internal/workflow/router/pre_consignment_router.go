@@ -1,7 +1,9 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
@@ -9,17 +11,28 @@ import (
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 	"github.com/OpenNSW/nsw/internal/workflow/service"
 	"github.com/OpenNSW/nsw/utils"
+	"gorm.io/gorm"
 )
 
 // PreConsignmentRouter handles HTTP routing for pre-consignment endpoints.
 type PreConsignmentRouter struct {
-	pcs *service.PreConsignmentService
+	pcs    *service.PreConsignmentService
+	access WorkflowAccess
 }
 
-// NewPreConsignmentRouter creates a new PreConsignmentRouter.
-func NewPreConsignmentRouter(pcs *service.PreConsignmentService) *PreConsignmentRouter {
+// WorkflowAccess decides whether the principal in ctx may read the consignment or
+// pre-consignment behind a workflow. The Task Engine's authorization policy implements it.
+type WorkflowAccess interface {
+	MayReadWorkflow(ctx context.Context, workflowID string) (bool, error)
+}
+
+// NewPreConsignmentRouter creates a new PreConsignmentRouter. Traders always read their own
+// pre-consignments; other users, such as the staff of the trader's organisations, read those
+// access lets them read. When access is nil, only the owning trader does.
+func NewPreConsignmentRouter(pcs *service.PreConsignmentService, access WorkflowAccess) *PreConsignmentRouter {
 	return &PreConsignmentRouter{
-		pcs: pcs,
+		pcs:    pcs,
+		access: access,
 	}
 }
 
@@ -138,10 +151,26 @@ func (r *PreConsignmentRouter) HandleGetPreConsignmentByID(w http.ResponseWriter
 
 	preConsignment, err := r.pcs.GetPreConsignmentByID(req.Context(), preConsignmentID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "pre-consignment not found", http.StatusNotFound)
+			return
+		}
 		slog.Error("failed to retrieve pre-consignment", "error", err)
 		http.Error(w, "failed to retrieve pre-consignment: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// A pre-consignment the user may not read is reported as not found so its existence is
+	// not disclosed.
+	allowed, err := r.mayRead(ctx, authCtx.User, preConsignment)
+	if err != nil {
+		slog.Error("failed to authorize pre-consignment read", "preConsignmentId", preConsignmentID, "error", err)
+		http.Error(w, "failed to retrieve pre-consignment", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "pre-consignment not found", http.StatusNotFound)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -151,3 +180,16 @@ func (r *PreConsignmentRouter) HandleGetPreConsignmentByID(w http.ResponseWriter
 		return
 	}
 }
+
+// mayRead reports whether user may read preConsignment: its trader may, and anyone else only
+// when access resolves them as a party to it. Pre-consignment workflows share the
+// pre-consignment's ID.
+func (r *PreConsignmentRouter) mayRead(ctx context.Context, user *auth.UserContext, preConsignment *model.PreConsignmentResponseDTO) (bool, error) {
+	if preConsignment.TraderID == user.ID {
+		return true, nil
+	}
+	if r.access == nil {
+		return false, nil
+	}
+	return r.access.MayReadWorkflow(ctx, preConsignment.ID)
+}
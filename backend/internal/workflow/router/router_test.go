@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -20,8 +22,7 @@ import (
 	"gorm.io/gorm/logger"
 
 	"github.com/OpenNSW/nsw/internal/auth"
-	taskManager "github.com/OpenNSW/nsw/internal/task/manager"
-	workflowManagerV1 "github.com/OpenNSW/nsw/internal/workflow/manager"
+	"github.com/OpenNSW/nsw/internal/workflow/lint"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 	"github.com/OpenNSW/nsw/internal/workflow/service"
 )
@@ -83,32 +84,6 @@ func (m *MockTemplateProvider) GetEndNodeTemplate(ctx context.Context) (*model.W
 	return args.Get(0).(*model.WorkflowNodeTemplate), args.Error(1)
 }
 
-// MockWorkflowManager implements manager.Manager for testing.
-type MockWorkflowManager struct {
-	mock.Mock
-}
-
-func (m *MockWorkflowManager) StartWorkflowInstance(ctx context.Context, tx *gorm.DB, workflowID string, workflowTemplates []model.WorkflowTemplate, globalContext map[string]any, handler workflowManagerV1.WorkflowEventHandler) error {
-	args := m.Called(ctx, tx, workflowID, workflowTemplates, globalContext, handler)
-	return args.Error(0)
-}
-
-func (m *MockWorkflowManager) GetWorkflowInstance(ctx context.Context, workflowID string) (*model.Workflow, error) {
-	args := m.Called(ctx, workflowID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*model.Workflow), args.Error(1)
-}
-
-func (m *MockWorkflowManager) RegisterTaskHandler(_ workflowManagerV1.TaskInitHandler) error {
-	return nil
-}
-
-func (m *MockWorkflowManager) HandleTaskUpdate(_ context.Context, _ taskManager.WorkflowManagerNotification) error {
-	return nil
-}
-
 // MockWMV2 implements workflowManagerV2.TemporalManager for testing.
 type MockWMV2 struct {
 	mock.Mock
@@ -161,7 +136,8 @@ func setupRouterTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
 func withAuthContext(ctx context.Context, userID string) context.Context {
 	authCtx := &auth.AuthContext{
 		User: &auth.UserContext{
-			ID: userID,
+			ID:    userID,
+			Roles: []string{auth.RoleTrader},
 		},
 	}
 	return context.WithValue(ctx, auth.AuthContextKey, authCtx)
@@ -170,7 +146,7 @@ func withAuthContext(ctx context.Context, userID string) context.Context {
 func TestConsignmentRouter_HandleGetConsignmentByID(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
 	mockWM := new(MockWMV2)
-	svc := service.NewConsignmentService(db, nil)
+	svc := service.NewConsignmentService(db, nil, nil)
 	require.NoError(t, svc.RegisterWorkflowManager(mockWM))
 	r := NewConsignmentRouter(svc, nil)
 
@@ -193,7 +169,7 @@ func TestConsignmentRouter_HandleGetConsignmentByID(t *testing.T) {
 
 func TestConsignmentRouter_HandleGetConsignments(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
-	svc := service.NewConsignmentService(db, nil)
+	svc := service.NewConsignmentService(db, nil, nil)
 	r := NewConsignmentRouter(svc, nil)
 
 	traderID := "trader1"
@@ -210,9 +186,50 @@ func TestConsignmentRouter_HandleGetConsignments(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestConsignmentRouter_HandleGetConsignmentByID_OutOfScope(t *testing.T) {
+	db, sqlMock := setupRouterTestDB(t)
+	r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+
+	consignmentID := uuid.NewString()
+	sqlMock.ExpectQuery(`(?i)SELECT .* FROM "consignments" WHERE .*consignments.trader_id = \$1.* AND id = \$3`).
+		WithArgs("trader2", "trader2", consignmentID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	req, _ := http.NewRequest("GET", "/api/v1/consignments/"+consignmentID, nil)
+	req.SetPathValue("id", consignmentID)
+	req = req.WithContext(withAuthContext(req.Context(), "trader2"))
+
+	w := httptest.NewRecorder()
+	r.HandleGetConsignmentByID(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestConsignmentRouter_HandleGetConsignments_RoleNotHeld(t *testing.T) {
+	db, _ := setupRouterTestDB(t)
+	r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/consignments?role=cha", nil)
+	req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+	w := httptest.NewRecorder()
+	r.HandleGetConsignments(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestConsignmentRouter_HandleGetConsignments_InvalidRole(t *testing.T) {
+	db, _ := setupRouterTestDB(t)
+	r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
+
+	req, _ := http.NewRequest("GET", "/api/v1/consignments?role=admin", nil)
+	req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+	w := httptest.NewRecorder()
+	r.HandleGetConsignments(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestConsignmentRouter_HandleCreateConsignment(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
-	svc := service.NewConsignmentService(db, nil)
+	svc := service.NewConsignmentService(db, nil, nil)
 	r := NewConsignmentRouter(svc, nil)
 
 	traderID := "trader1"
@@ -257,48 +274,109 @@ func TestHSCodeRouter_HandleGetAllHSCodes(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+// stubWorkflowAccess lets the listed users read and act on every workflow.
+type stubWorkflowAccess []string
+
+func (s stubWorkflowAccess) MayReadWorkflow(ctx context.Context, workflowID string) (bool, error) {
+	authCtx := auth.GetAuthContext(ctx)
+	return authCtx != nil && authCtx.User != nil && slices.Contains(s, authCtx.User.ID), nil
+}
+
+func (s stubWorkflowAccess) MayActOnWorkflow(ctx context.Context, workflowID string) (bool, error) {
+	return s.MayReadWorkflow(ctx, workflowID)
+}
+
+func TestConsignmentRouter_HandleInitializeConsignment_Access(t *testing.T) {
+	db, _ := setupRouterTestDB(t)
+	r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), stubWorkflowAccess{"cha1"})
+
+	initialize := func(userID string) int {
+		id := uuid.NewString()
+		req, _ := http.NewRequest("PUT", "/api/v1/consignments/"+id, strings.NewReader(`{"hsCodeIds":[]}`))
+		req.SetPathValue("id", id)
+		req = req.WithContext(withAuthContext(req.Context(), userID))
+		w := httptest.NewRecorder()
+		r.HandleInitializeConsignment(w, req)
+		return w.Code
+	}
+
+	// A party gets past the ownership check to body validation; anyone else is told the
+	// consignment does not exist before the body is read.
+	assert.Equal(t, http.StatusBadRequest, initialize("cha1"))
+	assert.Equal(t, http.StatusNotFound, initialize("trader2"))
+}
+
 func TestPreConsignmentRouter_HandleGetPreConsignmentByID(t *testing.T) {
-	db, sqlMock := setupRouterTestDB(t)
-	mockWM := new(MockWorkflowManager)
-	svc := service.NewPreConsignmentService(db, nil, mockWM)
-	r := NewPreConsignmentRouter(svc)
+	setup := func(t *testing.T, id, traderID string) *PreConsignmentRouter {
+		db, sqlMock := setupRouterTestDB(t)
+		mockWM := new(MockWMV2)
+		svc := service.NewPreConsignmentService(db, nil, nil)
+		require.NoError(t, svc.RegisterWorkflowManager(mockWM))
+
+		templateID := uuid.NewString()
+		sqlMock.MatchExpectationsInOrder(false)
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "pre_consignment_template_id"}).AddRow(id, traderID, templateID))
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignment_templates\"").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(templateID, "Template"))
+		mockWM.On("GetStatus", mock.Anything, id).Return((*workflowManagerV2.WorkflowInstance)(nil), nil)
+		return NewPreConsignmentRouter(svc, stubWorkflowAccess{"clerk1"})
+	}
 
-	id := uuid.NewString()
-	templateID := uuid.NewString()
-	nodeTemplateID := uuid.NewString()
+	t.Run("Owner", func(t *testing.T) {
+		id := uuid.NewString()
+		r := setup(t, id, "trader1")
 
-	sqlMock.MatchExpectationsInOrder(false)
-	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "pre_consignment_template_id"}).AddRow(id, templateID))
-	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignment_templates\"").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(templateID, "Template"))
+		req, _ := http.NewRequest("GET", "/api/v1/pre-consignments/"+id, nil)
+		req.SetPathValue("preConsignmentId", id)
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+		w := httptest.NewRecorder()
+		r.HandleGetPreConsignmentByID(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
 
-	mockWM.On("GetWorkflowInstance", mock.Anything, id).Return(&model.Workflow{
-		BaseModel: model.BaseModel{ID: id},
-		Status:    model.WorkflowStatusInProgress,
-		WorkflowNodes: []model.WorkflowNode{
-			{
-				BaseModel:              model.BaseModel{ID: uuid.NewString()},
-				WorkflowNodeTemplateID: nodeTemplateID,
-				State:                  model.WorkflowNodeStateReady,
-				WorkflowNodeTemplate: model.WorkflowNodeTemplate{
-					BaseModel: model.BaseModel{ID: nodeTemplateID},
-					Type:      "TEST",
-				},
-			},
-		},
-	}, nil)
+	t.Run("Another Trader", func(t *testing.T) {
+		id := uuid.NewString()
+		r := setup(t, id, "trader2")
 
-	req, _ := http.NewRequest("GET", "/api/v1/pre-consignments/"+id, nil)
-	req.SetPathValue("preConsignmentId", id)
-	req = req.WithContext(withAuthContext(req.Context(), "trader1"))
-	w := httptest.NewRecorder()
-	r.HandleGetPreConsignmentByID(w, req)
-	assert.Equal(t, http.StatusOK, w.Code)
+		req, _ := http.NewRequest("GET", "/api/v1/pre-consignments/"+id, nil)
+		req.SetPathValue("preConsignmentId", id)
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+		w := httptest.NewRecorder()
+		r.HandleGetPreConsignmentByID(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Trader Organisation Staff", func(t *testing.T) {
+		id := uuid.NewString()
+		r := setup(t, id, "trader1")
+
+		req, _ := http.NewRequest("GET", "/api/v1/pre-consignments/"+id, nil)
+		req.SetPathValue("preConsignmentId", id)
+		req = req.WithContext(withAuthContext(req.Context(), "clerk1"))
+		w := httptest.NewRecorder()
+		r.HandleGetPreConsignmentByID(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		db, sqlMock := setupRouterTestDB(t)
+		svc := service.NewPreConsignmentService(db, nil, nil)
+		sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").WillReturnError(gorm.ErrRecordNotFound)
+		r := NewPreConsignmentRouter(svc, stubWorkflowAccess{"clerk1"})
+
+		id := uuid.NewString()
+		req, _ := http.NewRequest("GET", "/api/v1/pre-consignments/"+id, nil)
+		req.SetPathValue("preConsignmentId", id)
+		req = req.WithContext(withAuthContext(req.Context(), "trader1"))
+		w := httptest.NewRecorder()
+		r.HandleGetPreConsignmentByID(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
 }
 
 func TestPreConsignmentRouter_HandleGetTraderPreConsignments(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
 	svc := service.NewPreConsignmentService(db, nil, nil)
-	r := NewPreConsignmentRouter(svc)
+	r := NewPreConsignmentRouter(svc, nil)
 
 	traderID := "trader1"
 	sqlMock.MatchExpectationsInOrder(false)
@@ -317,13 +395,14 @@ func TestPreConsignmentRouter_HandleGetTraderPreConsignments(t *testing.T) {
 func TestPreConsignmentRouter_HandleCreatePreConsignment(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
 	tp := new(MockTemplateProvider)
-	mockWM := new(MockWorkflowManager)
-	svc := service.NewPreConsignmentService(db, tp, mockWM)
-	r := NewPreConsignmentRouter(svc)
+	mockWM := new(MockWMV2)
+	svc := service.NewPreConsignmentService(db, tp, nil)
+	require.NoError(t, svc.RegisterWorkflowManager(mockWM))
+	r := NewPreConsignmentRouter(svc, nil)
 
 	traderID := "trader1"
 	templateID := uuid.NewString()
-	nodeTemplateID := "00000000-0000-0000-0000-000000000001"
+	workflowTemplateID := "pre-consignment-basic-details-v1"
 	preConsignmentID := uuid.NewString()
 
 	payload := model.CreatePreConsignmentDTO{
@@ -331,49 +410,37 @@ func TestPreConsignmentRouter_HandleCreatePreConsignment(t *testing.T) {
 	}
 	body, _ := json.Marshal(payload)
 
-	tp.On("GetWorkflowTemplateByID", mock.Anything, mock.Anything).Return(&model.WorkflowTemplate{BaseModel: model.BaseModel{ID: templateID}, NodeTemplates: []string{nodeTemplateID}}, nil)
+	tp.On("GetWorkflowTemplateByIDV2", mock.Anything, workflowTemplateID).Return(&model.WorkflowTemplateV2{BaseModel: model.BaseModel{ID: workflowTemplateID}}, nil)
 
 	sqlMock.MatchExpectationsInOrder(false)
-	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignment_templates\"").WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_template_id", "depends_on"}).AddRow(templateID, uuid.NewString(), []byte("[]")))
+	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignment_templates\"").WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_template_id", "workflow_template_v2_id", "depends_on"}).AddRow(templateID, uuid.NewString(), workflowTemplateID, []byte("[]")))
 
 	sqlMock.ExpectBegin()
 	sqlMock.ExpectExec("(?i)INSERT INTO \"pre_consignments\"").WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectExec("(?i)INSERT INTO \"workflows\"").WillReturnResult(sqlmock.NewResult(1, 1))
 
-	mockWM.On("StartWorkflowInstance", mock.Anything, mock.Anything, mock.AnythingOfType("string"), mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockWM.On("StartWorkflow", mock.Anything, mock.AnythingOfType("string"), mock.Anything, map[string]any{}).Return(nil)
 
 	sqlMock.ExpectCommit()
 
 	// Post-commit reloads
-	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "pre_consignment_template_id"}).AddRow(preConsignmentID, templateID))
+	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "pre_consignment_template_id"}).AddRow(preConsignmentID, traderID, templateID))
 	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignment_templates\"").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(templateID, "Template"))
 
-	mockWM.On("GetWorkflowInstance", mock.Anything, mock.AnythingOfType("string")).Return(&model.Workflow{
-		BaseModel: model.BaseModel{ID: preConsignmentID},
-		Status:    model.WorkflowStatusInProgress,
-		WorkflowNodes: []model.WorkflowNode{
-			{
-				BaseModel:              model.BaseModel{ID: uuid.NewString()},
-				WorkflowNodeTemplateID: nodeTemplateID,
-				State:                  model.WorkflowNodeStateReady,
-				WorkflowNodeTemplate: model.WorkflowNodeTemplate{
-					BaseModel: model.BaseModel{ID: nodeTemplateID},
-					Type:      "TEST",
-				},
-			},
-		},
-	}, nil)
+	mockWM.On("GetStatus", mock.Anything, mock.AnythingOfType("string")).Return((*workflowManagerV2.WorkflowInstance)(nil), nil)
 
 	req, _ := http.NewRequest("POST", "/api/v1/pre-consignments", bytes.NewBuffer(body))
 	req = req.WithContext(withAuthContext(req.Context(), traderID))
 	w := httptest.NewRecorder()
 	r.HandleCreatePreConsignment(w, req)
 	assert.Equal(t, http.StatusCreated, w.Code)
+	mockWM.AssertExpectations(t)
 }
 
 func TestPreConsignmentRouter_HandleCreatePreConsignment_InvalidPayload(t *testing.T) {
 	db, _ := setupRouterTestDB(t)
 	svc := service.NewPreConsignmentService(db, nil, nil)
-	r := NewPreConsignmentRouter(svc)
+	r := NewPreConsignmentRouter(svc, nil)
 
 	req, _ := http.NewRequest("POST", "/api/v1/pre-consignments", bytes.NewBufferString("invalid json"))
 	req = req.WithContext(withAuthContext(req.Context(), "trader1"))
@@ -384,7 +451,7 @@ func TestPreConsignmentRouter_HandleCreatePreConsignment_InvalidPayload(t *testi
 
 func TestConsignmentRouter_HandleGetConsignmentByID_InvalidID(t *testing.T) {
 	db, _ := setupRouterTestDB(t)
-	svc := service.NewConsignmentService(db, nil)
+	svc := service.NewConsignmentService(db, nil, nil)
 	r := NewConsignmentRouter(svc, nil)
 
 	req, _ := http.NewRequest("GET", "/api/v1/consignments/invalid-uuid", nil)
@@ -397,7 +464,7 @@ func TestConsignmentRouter_HandleGetConsignmentByID_InvalidID(t *testing.T) {
 
 func TestConsignmentRouter_HandleGetConsignments_PaginationError(t *testing.T) {
 	db, _ := setupRouterTestDB(t)
-	svc := service.NewConsignmentService(db, nil)
+	svc := service.NewConsignmentService(db, nil, nil)
 	r := NewConsignmentRouter(svc, nil)
 
 	req, _ := http.NewRequest("GET", "/api/v1/consignments?limit=invalid", nil)
@@ -411,7 +478,7 @@ func TestConsignmentRouter_HandleGetConsignments_PaginationError(t *testing.T) {
 
 func TestConsignmentRouter_HandleGetConsignmentByID_ServiceError(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
-	svc := service.NewConsignmentService(db, nil)
+	svc := service.NewConsignmentService(db, nil, nil)
 	r := NewConsignmentRouter(svc, nil)
 
 	id := uuid.NewString()
@@ -429,7 +496,7 @@ func TestConsignmentRouter_HandleGetConsignmentByID_ServiceError(t *testing.T) {
 func TestPreConsignmentRouter_HandleGetTraderPreConsignments_PaginationError(t *testing.T) {
 	db, _ := setupRouterTestDB(t)
 	svc := service.NewPreConsignmentService(db, nil, nil)
-	r := NewPreConsignmentRouter(svc)
+	r := NewPreConsignmentRouter(svc, nil)
 
 	req, _ := http.NewRequest("GET", "/api/v1/pre-consignments/templates?limit=invalid", nil)
 	req = req.WithContext(withAuthContext(req.Context(), "trader1"))
@@ -455,7 +522,7 @@ func TestHSCodeRouter_HandleGetAllHSCodes_ServiceError(t *testing.T) {
 
 func TestConsignmentRouter_HandleGetConsignments_ServiceError(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
-	svc := service.NewConsignmentService(db, nil)
+	svc := service.NewConsignmentService(db, nil, nil)
 	r := NewConsignmentRouter(svc, nil)
 
 	sqlMock.ExpectQuery("(?i)SELECT count").WillReturnError(fmt.Errorf("db error"))
@@ -469,7 +536,7 @@ func TestConsignmentRouter_HandleGetConsignments_ServiceError(t *testing.T) {
 
 func TestConsignmentRouter_HandleCreateConsignment_InvalidPayload(t *testing.T) {
 	db, _ := setupRouterTestDB(t)
-	r := NewConsignmentRouter(service.NewConsignmentService(db, nil), nil)
+	r := NewConsignmentRouter(service.NewConsignmentService(db, nil, nil), nil)
 
 	req, _ := http.NewRequest("POST", "/api/v1/consignments", bytes.NewBufferString("invalid json"))
 	req = req.WithContext(withAuthContext(req.Context(), "trader1"))
@@ -481,7 +548,7 @@ func TestConsignmentRouter_HandleCreateConsignment_InvalidPayload(t *testing.T)
 func TestPreConsignmentRouter_HandleGetTraderPreConsignments_ServiceError(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
 	svc := service.NewPreConsignmentService(db, nil, nil)
-	r := NewPreConsignmentRouter(svc)
+	r := NewPreConsignmentRouter(svc, nil)
 
 	sqlMock.ExpectQuery("(?i)SELECT count").WillReturnError(fmt.Errorf("db error"))
 
@@ -494,7 +561,7 @@ func TestPreConsignmentRouter_HandleGetTraderPreConsignments_ServiceError(t *tes
 
 func TestPreConsignmentRouter_HandleGetPreConsignmentByID_InvalidID(t *testing.T) {
 	db, _ := setupRouterTestDB(t)
-	r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil))
+	r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil), nil)
 
 	req, _ := http.NewRequest("GET", "/api/v1/pre-consignments/invalid-uuid", nil)
 	req.SetPathValue("preConsignmentId", "invalid-uuid")
@@ -506,7 +573,7 @@ func TestPreConsignmentRouter_HandleGetPreConsignmentByID_InvalidID(t *testing.T
 
 func TestPreConsignmentRouter_HandleGetPreConsignmentByID_ServiceError(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
-	r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil))
+	r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, nil, nil), nil)
 
 	id := uuid.NewString()
 	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignments\"").WillReturnError(fmt.Errorf("db error"))
@@ -532,7 +599,7 @@ func TestHSCodeRouter_HandleGetAllHSCodes_PaginationError(t *testing.T) {
 func TestPreConsignmentRouter_HandleCreatePreConsignment_ServiceError(t *testing.T) {
 	db, sqlMock := setupRouterTestDB(t)
 	tp := new(MockTemplateProvider)
-	r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, tp, nil))
+	r := NewPreConsignmentRouter(service.NewPreConsignmentService(db, tp, nil), nil)
 
 	templateID := uuid.NewString()
 	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"pre_consignment_templates\"").WillReturnError(fmt.Errorf("db error"))
@@ -545,3 +612,68 @@ func TestPreConsignmentRouter_HandleCreatePreConsignment_ServiceError(t *testing
 	r.HandleCreatePreConsignment(w, req)
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
+
+func withAdminContext(ctx context.Context) context.Context {
+	authCtx := &auth.AuthContext{User: &auth.UserContext{ID: "admin-1", Roles: []string{auth.RoleAdmin}}}
+	return context.WithValue(ctx, auth.AuthContextKey, authCtx)
+}
+
+func TestTemplateAdminRouter_RequiresAdmin(t *testing.T) {
+	db, _ := setupRouterTestDB(t)
+	router := NewTemplateAdminRouter(service.NewTemplateAdminService(db, lint.NewLinter(nil), nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/workflow-templates", nil)
+	req = req.WithContext(withAuthContext(req.Context(), "trader-1"))
+	rr := httptest.NewRecorder()
+	router.HandleListWorkflowTemplates(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestTemplateAdminRouter_HandlePublishWorkflowTemplate_ReturnsIssues(t *testing.T) {
+	db, sqlMock := setupRouterTestDB(t)
+	router := NewTemplateAdminRouter(service.NewTemplateAdminService(db, lint.NewLinter(nil), nil))
+
+	definition := `{"nodes": [{"id": "start", "type": "START"}, {"id": "end", "type": "END"}], "edges": []}`
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectQuery("(?i)SELECT .* FROM \"workflow_template_v2\"").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version", "workflow_definition", "status"}).
+			AddRow("wf-2", "Export", "2", definition, "DRAFT"))
+	sqlMock.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/workflow-templates/wf-2/publish", nil)
+	req.SetPathValue("id", "wf-2")
+	req = req.WithContext(withAdminContext(req.Context()))
+	rr := httptest.NewRecorder()
+	router.HandlePublishWorkflowTemplate(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	var body struct {
+		Issues []lint.Issue `json:"issues"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	require.Len(t, body.Issues, 2)
+	assert.Equal(t, "workflow_template_v2[wf-2].nodes[0]", body.Issues[0].Path) // start leads nowhere
+	assert.Equal(t, "workflow_template_v2[wf-2].nodes[1]", body.Issues[1].Path) // end is unreachable
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestWorkflowInstanceRouter_HandleMigrateWorkflows_RejectsSameTemplate(t *testing.T) {
+	db, _ := setupRouterTestDB(t)
+	wis := service.NewWorkflowInstanceService(db, nil)
+	require.NoError(t, wis.RegisterWorkflowManager(new(MockWMV2)))
+	router := NewWorkflowInstanceRouter(wis)
+
+	body := `{"fromTemplateId": "wf-1", "toTemplateId": "wf-1", "dryRun": true}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/workflow-migrations", strings.NewReader(body))
+	req = req.WithContext(withAdminContext(req.Context()))
+	rr := httptest.NewRecorder()
+	router.HandleMigrateWorkflows(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/workflow-migrations", strings.NewReader(body))
+	req = req.WithContext(withAuthContext(req.Context(), "trader-1"))
+	rr = httptest.NewRecorder()
+	router.HandleMigrateWorkflows(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
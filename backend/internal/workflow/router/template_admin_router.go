@@ -0,0 +1,309 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/OpenNSW/nsw/internal/auth"
+	"github.com/OpenNSW/nsw/internal/workflow/lint"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/internal/workflow/service"
+)
+
+// TemplateAdminRouter exposes the admin API for authoring and publishing node templates,
+// workflow templates and their HS code mappings. Every handler requires an administrator.
+type TemplateAdminRouter struct {
+	tas *service.TemplateAdminService
+}
+
+func NewTemplateAdminRouter(tas *service.TemplateAdminService) *TemplateAdminRouter {
+	return &TemplateAdminRouter{tas: tas}
+}
+
+// templateErrorResponse is the body of an error response. Issues lists the validation
+// issues that prevented a publish.
+type templateErrorResponse struct {
+	Error  string       `json:"error"`
+	Issues []lint.Issue `json:"issues,omitempty"`
+}
+
+// HandleListNodeTemplates handles GET /api/v1/admin/node-templates
+// Optional query param: status (DRAFT or PUBLISHED)
+func (t *TemplateAdminRouter) HandleListNodeTemplates(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	templates, err := t.tas.ListNodeTemplates(r.Context(), model.TemplateStatus(r.URL.Query().Get("status")))
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	if templates == nil {
+		templates = []model.WorkflowNodeTemplate{}
+	}
+	writeAdminJSON(w, http.StatusOK, templates)
+}
+
+// HandleGetNodeTemplate handles GET /api/v1/admin/node-templates/{id}
+func (t *TemplateAdminRouter) HandleGetNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	template, err := t.tas.GetNodeTemplate(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, template)
+}
+
+// HandleCreateNodeTemplate handles POST /api/v1/admin/node-templates
+func (t *TemplateAdminRouter) HandleCreateNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	var req model.NodeTemplateRequest
+	if !requireAdmin(w, r) || !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	template, err := t.tas.CreateNodeTemplate(r.Context(), req)
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusCreated, template)
+}
+
+// HandleUpdateNodeTemplate handles PUT /api/v1/admin/node-templates/{id}
+func (t *TemplateAdminRouter) HandleUpdateNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	var req model.NodeTemplateRequest
+	if !requireAdmin(w, r) || !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	template, err := t.tas.UpdateNodeTemplate(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, template)
+}
+
+// HandleDeleteNodeTemplate handles DELETE /api/v1/admin/node-templates/{id}
+func (t *TemplateAdminRouter) HandleDeleteNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	if err := t.tas.DeleteNodeTemplate(r.Context(), r.PathValue("id")); err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandlePublishNodeTemplate handles POST /api/v1/admin/node-templates/{id}/publish
+func (t *TemplateAdminRouter) HandlePublishNodeTemplate(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	template, err := t.tas.PublishNodeTemplate(r.Context(), r.PathValue("id"), principalID(r))
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, template)
+}
+
+// HandleListWorkflowTemplates handles GET /api/v1/admin/workflow-templates
+// Optional query param: status (DRAFT or PUBLISHED)
+func (t *TemplateAdminRouter) HandleListWorkflowTemplates(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	templates, err := t.tas.ListWorkflowTemplates(r.Context(), model.TemplateStatus(r.URL.Query().Get("status")))
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	if templates == nil {
+		templates = []model.WorkflowTemplateV2{}
+	}
+	writeAdminJSON(w, http.StatusOK, templates)
+}
+
+// HandleGetWorkflowTemplate handles GET /api/v1/admin/workflow-templates/{id}
+func (t *TemplateAdminRouter) HandleGetWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	template, err := t.tas.GetWorkflowTemplate(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, template)
+}
+
+// HandleCreateWorkflowTemplate handles POST /api/v1/admin/workflow-templates
+func (t *TemplateAdminRouter) HandleCreateWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	var req model.WorkflowTemplateRequest
+	if !requireAdmin(w, r) || !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	template, err := t.tas.CreateWorkflowTemplate(r.Context(), req)
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusCreated, template)
+}
+
+// HandleUpdateWorkflowTemplate handles PUT /api/v1/admin/workflow-templates/{id}
+func (t *TemplateAdminRouter) HandleUpdateWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	var req model.WorkflowTemplateRequest
+	if !requireAdmin(w, r) || !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	template, err := t.tas.UpdateWorkflowTemplate(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, template)
+}
+
+// HandleDeleteWorkflowTemplate handles DELETE /api/v1/admin/workflow-templates/{id}
+func (t *TemplateAdminRouter) HandleDeleteWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	if err := t.tas.DeleteWorkflowTemplate(r.Context(), r.PathValue("id")); err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandlePublishWorkflowTemplate handles POST /api/v1/admin/workflow-templates/{id}/publish
+func (t *TemplateAdminRouter) HandlePublishWorkflowTemplate(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	template, err := t.tas.PublishWorkflowTemplate(r.Context(), r.PathValue("id"), principalID(r))
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, template)
+}
+
+// HandleListWorkflowTemplateMaps handles GET /api/v1/admin/workflow-template-maps
+func (t *TemplateAdminRouter) HandleListWorkflowTemplateMaps(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	maps, err := t.tas.ListWorkflowTemplateMaps(r.Context())
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	if maps == nil {
+		maps = []model.WorkflowTemplateMapV2{}
+	}
+	writeAdminJSON(w, http.StatusOK, maps)
+}
+
+// HandleCreateWorkflowTemplateMap handles POST /api/v1/admin/workflow-template-maps
+func (t *TemplateAdminRouter) HandleCreateWorkflowTemplateMap(w http.ResponseWriter, r *http.Request) {
+	var req model.WorkflowTemplateMapRequest
+	if !requireAdmin(w, r) || !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	mapping, err := t.tas.CreateWorkflowTemplateMap(r.Context(), req)
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusCreated, mapping)
+}
+
+// HandleUpdateWorkflowTemplateMap handles PUT /api/v1/admin/workflow-template-maps/{id}
+func (t *TemplateAdminRouter) HandleUpdateWorkflowTemplateMap(w http.ResponseWriter, r *http.Request) {
+	var req model.WorkflowTemplateMapRequest
+	if !requireAdmin(w, r) || !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	mapping, err := t.tas.UpdateWorkflowTemplateMap(r.Context(), r.PathValue("id"), req)
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, mapping)
+}
+
+// HandleDeleteWorkflowTemplateMap handles DELETE /api/v1/admin/workflow-template-maps/{id}
+func (t *TemplateAdminRouter) HandleDeleteWorkflowTemplateMap(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	if err := t.tas.DeleteWorkflowTemplateMap(r.Context(), r.PathValue("id")); err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireAdmin writes a 403 and returns false unless the caller is an administrator.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if auth.IsAdmin(r.Context()) {
+		return true
+	}
+	writeAdminJSON(w, http.StatusForbidden, templateErrorResponse{Error: "authoring templates requires an administrator"})
+	return false
+}
+
+func decodeAdminRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	defer func() { _ = r.Body.Close() }()
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeAdminJSON(w, http.StatusBadRequest, templateErrorResponse{Error: "invalid request body: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+func writeTemplateError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid *service.TemplateValidationError
+	switch {
+	case errors.As(err, &invalid):
+		writeAdminJSON(w, http.StatusBadRequest, templateErrorResponse{Error: "template failed validation", Issues: invalid.Issues})
+	case errors.Is(err, service.ErrTemplateNotFound):
+		writeAdminJSON(w, http.StatusNotFound, templateErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrTemplateExists), errors.Is(err, service.ErrTemplatePublished):
+		writeAdminJSON(w, http.StatusConflict, templateErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrInvalidTemplate), errors.Is(err, service.ErrInvalidMigration):
+		writeAdminJSON(w, http.StatusBadRequest, templateErrorResponse{Error: err.Error()})
+	default:
+		slog.ErrorContext(r.Context(), "template admin request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		writeAdminJSON(w, http.StatusInternalServerError, templateErrorResponse{Error: "template admin request failed"})
+	}
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		slog.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+// principalID identifies the user or client making the request.
+func principalID(r *http.Request) string {
+	authCtx := auth.GetAuthContext(r.Context())
+	switch {
+	case authCtx == nil:
+		return ""
+	case authCtx.User != nil:
+		return authCtx.User.ID
+	case authCtx.Client != nil:
+		return authCtx.Client.ClientID
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/internal/workflow/service"
+)
+
+// WorkflowInstanceRouter exposes the admin API for the workflows running on each workflow
+// template and for migrating them to another template. Every handler requires an administrator.
+type WorkflowInstanceRouter struct {
+	wis *service.WorkflowInstanceService
+}
+
+func NewWorkflowInstanceRouter(wis *service.WorkflowInstanceService) *WorkflowInstanceRouter {
+	return &WorkflowInstanceRouter{wis: wis}
+}
+
+// HandleListWorkflowInstances handles GET /api/v1/admin/workflow-templates/{id}/instances
+// Optional query param: status (IN_PROGRESS, COMPLETED or FAILED)
+func (t *WorkflowInstanceRouter) HandleListWorkflowInstances(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	instances, err := t.wis.ListWorkflowInstances(r.Context(), r.PathValue("id"), model.WorkflowStatus(r.URL.Query().Get("status")))
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, instances)
+}
+
+// HandleMigrateWorkflows handles POST /api/v1/admin/workflow-migrations
+// With "dryRun": true the response lists the changes without applying them.
+func (t *WorkflowInstanceRouter) HandleMigrateWorkflows(w http.ResponseWriter, r *http.Request) {
+	var req model.WorkflowMigrationRequest
+	if !requireAdmin(w, r) || !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	result, err := t.wis.MigrateWorkflows(r.Context(), req, principalID(r))
+	if err != nil {
+		writeTemplateError(w, r, err)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, result)
+}
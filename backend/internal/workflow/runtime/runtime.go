@@ -24,6 +24,15 @@ type temporalManagerFactory func(
 	completionHandler workflowmanager.WorkflowCompletionHandler,
 ) workflowmanager.TemporalManager
 
+// WorkflowInstances keeps the records of the workflows the runtime runs.
+type WorkflowInstances interface {
+	// TaskTemplateID returns the node template a task node starts, given the one named by the
+	// definition the workflow was started with.
+	TaskTemplateID(ctx context.Context, workflowID, nodeID, taskTemplateID string) (string, error)
+	// CompleteWorkflow records that a workflow has completed.
+	CompleteWorkflow(ctx context.Context, workflowID string) error
+}
+
 // Runtime owns Temporal workflow manager lifecycle for the application runtime.
 type Runtime struct {
 	manager       workflowmanager.TemporalManager
@@ -31,7 +40,8 @@ type Runtime struct {
 }
 
 // NewRuntime creates, wires, and starts the workflow runtime.
-func NewRuntime(temporalClient client.Client, tm taskmanager.TaskManager, templateProvider service.TemplateProvider, upstreamService UpstreamService) (*Runtime, error) {
+// instances may be nil, in which case tasks always start the node template named by the definition.
+func NewRuntime(temporalClient client.Client, tm taskmanager.TaskManager, templateProvider service.TemplateProvider, instances WorkflowInstances, upstreamService UpstreamService) (*Runtime, error) {
 	if temporalClient == nil {
 		return nil, fmt.Errorf("temporal client is required")
 	}
@@ -48,17 +58,27 @@ func NewRuntime(temporalClient client.Client, tm taskmanager.TaskManager, templa
 		)
 	}
 
-	return newRuntimeWithFactory(tm, templateProvider, createManager, upstreamService)
+	return newRuntimeWithFactory(tm, templateProvider, instances, createManager, upstreamService)
 }
 
-func newRuntimeWithFactory(tm taskmanager.TaskManager, templateProvider service.TemplateProvider, createManager temporalManagerFactory, upstreamService UpstreamService) (*Runtime, error) {
+func newRuntimeWithFactory(tm taskmanager.TaskManager, templateProvider service.TemplateProvider, instances WorkflowInstances, createManager temporalManagerFactory, upstreamService UpstreamService) (*Runtime, error) {
 	runtimeCtx, runtimeCancel := context.WithCancel(context.Background())
 
 	activationHandler := func(payload workflowmanager.TaskPayload) error {
 		activationCtx, cancel := context.WithTimeout(runtimeCtx, activationTimeout)
 		defer cancel()
 
-		template, err := templateProvider.GetWorkflowNodeTemplateByID(activationCtx, payload.TaskTemplateID)
+		taskTemplateID := payload.TaskTemplateID
+		if instances != nil {
+			// A migration may have moved the node to another node template.
+			var err error
+			taskTemplateID, err = instances.TaskTemplateID(activationCtx, payload.WorkflowID, payload.NodeID, payload.TaskTemplateID)
+			if err != nil {
+				return fmt.Errorf("error resolving node template of task %s: %w", payload.NodeID, err)
+			}
+		}
+
+		template, err := templateProvider.GetWorkflowNodeTemplateByID(activationCtx, taskTemplateID)
 		if err != nil {
 			return fmt.Errorf("error getting workflow node template: %w", err)
 		}
@@ -73,6 +93,7 @@ func newRuntimeWithFactory(tm taskmanager.TaskManager, templateProvider service.
 			GlobalState:            payload.Inputs,
 			Type:                   template.Type,
 			Config:                 template.Config,
+			SLA:                    template.SLA,
 		}
 
 		if _, err := tm.InitTask(activationCtx, tmRequest); err != nil {
@@ -90,6 +111,11 @@ func newRuntimeWithFactory(tm taskmanager.TaskManager, templateProvider service.
 				return fmt.Errorf("error calling upstream completion handler: %w", err)
 			}
 		}
+		if instances != nil {
+			if err := instances.CompleteWorkflow(runtimeCtx, workflowID); err != nil {
+				return fmt.Errorf("error recording workflow completion: %w", err)
+			}
+		}
 
 		return nil
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 
 	workflowmanager "github.com/OpenNSW/go-temporal-workflow"
@@ -11,8 +12,10 @@ import (
 	"github.com/stretchr/testify/require"
 
 	taskManager "github.com/OpenNSW/nsw/internal/task/manager"
+	"github.com/OpenNSW/nsw/internal/task/persistence"
 	"github.com/OpenNSW/nsw/internal/task/plugin"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
+	"github.com/OpenNSW/nsw/internal/workflow/service"
 )
 
 type fakeTemporalManager struct {
@@ -119,6 +122,23 @@ func (s *fakeUpstreamService) CompletionHandler(workflowID string, finalContext
 	return s.err
 }
 
+type fakeWorkflowInstances struct {
+	overrides map[string]string
+	completed []string
+}
+
+func (i *fakeWorkflowInstances) TaskTemplateID(_ context.Context, _ string, nodeID, taskTemplateID string) (string, error) {
+	if override, ok := i.overrides[nodeID]; ok {
+		return override, nil
+	}
+	return taskTemplateID, nil
+}
+
+func (i *fakeWorkflowInstances) CompleteWorkflow(_ context.Context, workflowID string) error {
+	i.completed = append(i.completed, workflowID)
+	return nil
+}
+
 func (m *fakeTaskManager) InitTask(ctx context.Context, request taskManager.InitTaskRequest) (*taskManager.InitTaskResponse, error) {
 	m.lastInitCtx = ctx
 	m.lastInitReq = request
@@ -133,22 +153,40 @@ func (m *fakeTaskManager) ExecuteTask(_ context.Context, _ taskManager.ExecuteTa
 	return nil, nil
 }
 
+func (m *fakeTaskManager) FailTask(_ context.Context, _ string, _ string) error {
+	return nil
+}
+
 func (m *fakeTaskManager) GetTaskRenderInfo(_ context.Context, _ string) (*plugin.ApiResponse, error) {
 	return nil, nil
 }
 
+func (m *fakeTaskManager) ListFormTasks(_ context.Context, _ string) ([]persistence.FormTask, error) {
+	return nil, nil
+}
+
+func (m *fakeTaskManager) CacheStats() taskManager.CacheStats {
+	return taskManager.CacheStats{}
+}
+
+func (m *fakeTaskManager) CachedContainers() []taskManager.CachedContainer {
+	return nil
+}
+
 func (m *fakeTaskManager) RegisterUpstreamDoneCallback(callback taskManager.WorkflowDoneHandler) {
 	m.doneCallback = callback
 }
 
 func (m *fakeTaskManager) RegisterUpstreamUpdateCallback(_ taskManager.WorkflowUpdateHandler) {}
 
+func (m *fakeTaskManager) RegisterStateObserver(_ taskManager.StateObserver) {}
+
 func TestNewRuntime_StartWorkerFailureReturnsError(t *testing.T) {
 	fakeManager := &fakeTemporalManager{startErr: errors.New("start failed")}
 	taskMgr := &fakeTaskManager{}
 	templateProvider := &fakeTemplateProvider{template: &model.WorkflowNodeTemplate{}}
 
-	_, err := newRuntimeWithFactory(taskMgr, templateProvider, func(
+	_, err := newRuntimeWithFactory(taskMgr, templateProvider, nil, func(
 		_ workflowmanager.TaskActivationHandler,
 		_ workflowmanager.WorkflowCompletionHandler,
 	) workflowmanager.TemporalManager {
@@ -184,7 +222,7 @@ func TestNewRuntime_ActivationHandlerInitializesTask(t *testing.T) {
 	}}
 
 	var activationHandler func(payload workflowmanager.TaskPayload) error
-	runtime, err := newRuntimeWithFactory(taskMgr, templateProvider, func(
+	runtime, err := newRuntimeWithFactory(taskMgr, templateProvider, nil, func(
 		activation workflowmanager.TaskActivationHandler,
 		_ workflowmanager.WorkflowCompletionHandler,
 	) workflowmanager.TemporalManager {
@@ -213,12 +251,42 @@ func TestNewRuntime_ActivationHandlerInitializesTask(t *testing.T) {
 	assert.Equal(t, map[string]any{"a": "b"}, taskMgr.lastInitReq.GlobalState)
 }
 
+func TestNewRuntime_ActivationHandlerUsesMigratedNodeTemplate(t *testing.T) {
+	taskMgr := &fakeTaskManager{}
+	templateProvider := &fakeTemplateProvider{template: &model.WorkflowNodeTemplate{
+		BaseModel: model.BaseModel{ID: "template-2"},
+		Type:      plugin.Type("test"),
+	}}
+	instances := &fakeWorkflowInstances{overrides: map[string]string{"node-1": "template-2"}}
+
+	var activationHandler func(payload workflowmanager.TaskPayload) error
+	var completionHandler workflowmanager.WorkflowCompletionHandler
+	runtime, err := newRuntimeWithFactory(taskMgr, templateProvider, instances, func(
+		activation workflowmanager.TaskActivationHandler,
+		completion workflowmanager.WorkflowCompletionHandler,
+	) workflowmanager.TemporalManager {
+		activationHandler = activation
+		completionHandler = completion
+		return &fakeTemporalManager{}
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = runtime.Close() })
+
+	err = activationHandler(workflowmanager.TaskPayload{NodeID: "node-1", WorkflowID: "wf-1", TaskTemplateID: "template-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "template-2", templateProvider.lastID)
+	assert.Equal(t, "template-2", taskMgr.lastInitReq.WorkflowNodeTemplateID)
+
+	require.NoError(t, completionHandler("wf-1", nil))
+	assert.Equal(t, []string{"wf-1"}, instances.completed)
+}
+
 func TestNewRuntime_TaskDoneCallbackDelegatesToWorkflowManager(t *testing.T) {
 	fakeManager := &fakeTemporalManager{}
 	taskMgr := &fakeTaskManager{}
 	templateProvider := &fakeTemplateProvider{template: &model.WorkflowNodeTemplate{}}
 
-	runtime, err := newRuntimeWithFactory(taskMgr, templateProvider, func(
+	runtime, err := newRuntimeWithFactory(taskMgr, templateProvider, nil, func(
 		_ workflowmanager.TaskActivationHandler,
 		_ workflowmanager.WorkflowCompletionHandler,
 	) workflowmanager.TemporalManager {
@@ -243,7 +311,7 @@ func TestNewRuntime_CompletionHandlerDelegatesToUpstreamService(t *testing.T) {
 	upstreamService := &fakeUpstreamService{}
 
 	var completionHandler workflowmanager.WorkflowCompletionHandler
-	runtime, err := newRuntimeWithFactory(taskMgr, templateProvider, func(
+	runtime, err := newRuntimeWithFactory(taskMgr, templateProvider, nil, func(
 		_ workflowmanager.TaskActivationHandler,
 		completion workflowmanager.WorkflowCompletionHandler,
 	) workflowmanager.TemporalManager {
@@ -260,3 +328,24 @@ func TestNewRuntime_CompletionHandlerDelegatesToUpstreamService(t *testing.T) {
 	assert.Equal(t, "wf-1", upstreamService.workflowID)
 	assert.Equal(t, map[string]any{"status": "done"}, upstreamService.finalContext)
 }
+
+func TestUpstreams_CompletionHandlerDispatchesToOwner(t *testing.T) {
+	notOwner := &fakeUpstreamService{err: fmt.Errorf("%w: consignment wf-1 not found", service.ErrUnknownWorkflow)}
+	owner := &fakeUpstreamService{}
+	never := &fakeUpstreamService{}
+
+	err := Upstreams{notOwner, owner, never}.CompletionHandler("wf-1", map[string]any{"status": "done"})
+	require.NoError(t, err)
+	assert.True(t, notOwner.completionCalled)
+	assert.True(t, owner.completionCalled)
+	assert.Equal(t, "wf-1", owner.workflowID)
+	assert.False(t, never.completionCalled)
+
+	failing := &fakeUpstreamService{err: errors.New("db down")}
+	err = Upstreams{failing, never}.CompletionHandler("wf-2", nil)
+	assert.EqualError(t, err, "db down")
+	assert.False(t, never.completionCalled)
+
+	err = Upstreams{notOwner}.CompletionHandler("wf-3", nil)
+	assert.ErrorIs(t, err, service.ErrUnknownWorkflow)
+}
@@ -1,5 +1,28 @@
 package runtime
 
+import (
+	"errors"
+	"fmt"
+
+	"github.com/OpenNSW/nsw/internal/workflow/service"
+)
+
 type UpstreamService interface {
 	CompletionHandler(workflowID string, finalContext map[string]any) error
 }
+
+// Upstreams offers a completed workflow to each service in turn until one owns it. A service
+// reports a workflow it does not own with service.ErrUnknownWorkflow.
+type Upstreams []UpstreamService
+
+// CompletionHandler implements UpstreamService.
+func (u Upstreams) CompletionHandler(workflowID string, finalContext map[string]any) error {
+	for _, upstream := range u {
+		err := upstream.CompletionHandler(workflowID, finalContext)
+		if errors.Is(err, service.ErrUnknownWorkflow) {
+			continue
+		}
+		return err
+	}
+	return fmt.Errorf("%w: no upstream service owns workflow %s", service.ErrUnknownWorkflow, workflowID)
+}
@@ -2,18 +2,28 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log/slog"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 
 	workflowmanager "github.com/OpenNSW/go-temporal-workflow"
 
+	"github.com/OpenNSW/nsw/internal/audit"
+	"github.com/OpenNSW/nsw/internal/task/sla"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 	"github.com/OpenNSW/nsw/utils"
 )
 
+// ErrConsignmentAccessDenied is returned when a viewer holds no role that grants access to consignments.
+var ErrConsignmentAccessDenied = errors.New("consignment access denied")
+
+// ErrUnknownWorkflow is returned by a service's completion handler for a workflow that is not
+// one of its own, so the runtime can offer the completion to the next service.
+var ErrUnknownWorkflow = errors.New("workflow does not belong to this service")
+
 // ConsignmentService handles consignment-related operations.
 // It coordinates between workflow templates, nodes, and the workflow manager.
 // It also implements WorkflowEventHandler for domain-specific lifecycle callbacks.
@@ -21,13 +31,16 @@ type ConsignmentService struct {
 	db               *gorm.DB
 	templateProvider TemplateProvider
 	wm               workflowmanager.Manager
+	auditLog         audit.Recorder // Records consignment creation and initialization; may be nil
 }
 
 // NewConsignmentService creates a new instance of ConsignmentService.
-func NewConsignmentService(db *gorm.DB, templateProvider TemplateProvider) *ConsignmentService {
+// auditLog may be nil, in which case no audit entries are written.
+func NewConsignmentService(db *gorm.DB, templateProvider TemplateProvider, auditLog audit.Recorder) *ConsignmentService {
 	return &ConsignmentService{
 		db:               db,
 		templateProvider: templateProvider,
+		auditLog:         auditLog,
 	}
 }
 
@@ -74,8 +87,22 @@ func (s *ConsignmentService) CreateConsignmentShell(ctx context.Context, flow mo
 		State:    model.ConsignmentStateInitialized,
 		Items:    []model.ConsignmentItem{},
 	}
-	if err := s.db.WithContext(ctx).Create(consignment).Error; err != nil {
-		return nil, fmt.Errorf("failed to create consignment: %w", err)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(consignment).Error; err != nil {
+			return fmt.Errorf("failed to create consignment: %w", err)
+		}
+		return audit.RecordTx(ctx, tx, s.auditLog, audit.Entry{
+			Action:        audit.ActionConsignmentCreate,
+			ConsignmentID: consignment.ID,
+			ResourceType:  audit.ResourceConsignment,
+			ResourceID:    consignment.ID,
+			StateAfter:    string(consignment.State),
+			PayloadHash:   audit.HashPayload(map[string]any{"flow": flow, "chaId": chaID, "traderId": traderID}),
+			Details:       map[string]any{"flow": flow, "chaId": chaID, "traderId": traderID},
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 	// Reload for response (no workflow nodes at stage 1)
 	if err := s.db.WithContext(ctx).First(consignment, "id = ?", consignment.ID).Error; err != nil {
@@ -148,6 +175,27 @@ func (s *ConsignmentService) InitializeConsignmentByID(
 		return nil, fmt.Errorf("no workflow template found for HS code %s and flow %s", hsCodeIDs[0], consignment.Flow)
 	}
 
+	// Pin the workflow to the template it starts from.
+	if err := tx.Create(newWorkflowInstance(consignment.ID, wt, globalContext)).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to record workflow: %w", err)
+	}
+
+	err = audit.RecordTx(ctx, tx, s.auditLog, audit.Entry{
+		Action:        audit.ActionConsignmentInitialize,
+		ConsignmentID: consignment.ID,
+		ResourceType:  audit.ResourceConsignment,
+		ResourceID:    consignment.ID,
+		StateBefore:   string(model.ConsignmentStateInitialized),
+		StateAfter:    string(consignment.State),
+		PayloadHash:   audit.HashPayload(map[string]any{"hsCodeIds": hsCodeIDs, "globalContext": globalContext}),
+		Details:       map[string]any{"hsCodeIds": hsCodeIDs, "workflowTemplateId": wt.ID},
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	if err := s.wm.StartWorkflow(ctx, consignment.ID, wt.WorkflowDefinition, globalContext); err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to register workflow: %w", err)
@@ -165,7 +213,7 @@ func (s *ConsignmentService) InitializeConsignmentByID(
 
 	var workflowInstance *workflowmanager.WorkflowInstance
 
-	workflowInstance, err = s.wm.GetStatus(ctx, consignment.ID)
+	workflowInstance, err = workflowStatus(ctx, s.db, s.wm, consignment.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get workflow details: %w", err)
 	}
@@ -187,8 +235,24 @@ func (s *ConsignmentService) InitializeConsignmentByID(
 
 // GetConsignmentByID retrieves a consignment by its ID from the database.
 func (s *ConsignmentService) GetConsignmentByID(ctx context.Context, consignmentID string) (*model.ConsignmentDetailDTO, error) {
+	return s.getConsignment(ctx, s.db.WithContext(ctx), consignmentID)
+}
+
+// GetConsignmentByIDForViewer retrieves a consignment by its ID if it is visible to viewer.
+// A consignment outside the viewer's scope is reported as gorm.ErrRecordNotFound, so its
+// existence is not disclosed.
+func (s *ConsignmentService) GetConsignmentByIDForViewer(ctx context.Context, consignmentID string, viewer model.ConsignmentViewer) (*model.ConsignmentDetailDTO, error) {
+	query, err := scopeToViewer(s.db.WithContext(ctx), viewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.getConsignment(ctx, query, consignmentID)
+}
+
+// getConsignment loads a consignment matched by query and builds its detail DTO.
+func (s *ConsignmentService) getConsignment(ctx context.Context, query *gorm.DB, consignmentID string) (*model.ConsignmentDetailDTO, error) {
 	var consignment model.Consignment
-	result := s.db.WithContext(ctx).First(&consignment, "id = ?", consignmentID)
+	result := query.First(&consignment, "id = ?", consignmentID)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to retrieve consignment with ID %s: %w", consignmentID, result.Error)
 	}
@@ -197,7 +261,7 @@ func (s *ConsignmentService) GetConsignmentByID(ctx context.Context, consignment
 	var workflowInstance *workflowmanager.WorkflowInstance
 	var err error
 	if consignment.State != model.ConsignmentStateInitialized {
-		workflowInstance, err = s.wm.GetStatus(ctx, consignment.ID)
+		workflowInstance, err = workflowStatus(ctx, s.db, s.wm, consignment.ID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get workflow details: %w", err)
 		}
@@ -230,6 +294,16 @@ func (s *ConsignmentService) ListConsignments(ctx context.Context, filter model.
 	return s.listConsignmentsWithBaseQuery(ctx, baseQuery, filter)
 }
 
+// ListConsignmentsForViewer returns the consignments visible to viewer, applying the state,
+// flow and pagination options of filter.
+func (s *ConsignmentService) ListConsignmentsForViewer(ctx context.Context, viewer model.ConsignmentViewer, filter model.ConsignmentFilter) (*model.ConsignmentListResult, error) {
+	baseQuery, err := scopeToViewer(s.db.WithContext(ctx).Model(&model.Consignment{}), viewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.listConsignmentsWithBaseQuery(ctx, baseQuery, filter)
+}
+
 // GetConsignmentsByTraderID retrieves consignments associated with a specific trader ID with optional filtering.
 func (s *ConsignmentService) GetConsignmentsByTraderID(ctx context.Context, traderID string, offset *int, limit *int, filter model.ConsignmentFilter) (*model.ConsignmentListResult, error) {
 	filter.TraderID = &traderID
@@ -382,10 +456,59 @@ func (s *ConsignmentService) listConsignmentsWithBaseQuery(ctx context.Context,
 	}, nil
 }
 
+// scopeToViewer restricts a consignments query to the rows visible to viewer in any of its roles.
+func scopeToViewer(query *gorm.DB, viewer model.ConsignmentViewer) (*gorm.DB, error) {
+	var clauses []string
+	var args []any
+	for _, role := range viewer.Roles {
+		switch role {
+		case model.ConsignmentViewerTrader:
+			if viewer.UserID == "" {
+				continue
+			}
+			// Staff of a trader organisation see the consignments of all its members.
+			clauses = append(clauses,
+				"consignments.trader_id = ?",
+				"consignments.trader_id IN (SELECT peer.user_id FROM organization_members me JOIN organizations o ON o.id = me.organization_id AND o.kind = 'trader' JOIN organization_members peer ON peer.organization_id = me.organization_id WHERE me.user_id = ?)")
+			args = append(args, viewer.UserID, viewer.UserID)
+		case model.ConsignmentViewerCHA:
+			// Users act for the CHA registered against their organisation unit. CHAs not yet
+			// mapped to an OU are matched by email, as before OUs were recorded.
+			if viewer.OUID != "" || viewer.Email != "" {
+				clauses = append(clauses, "consignments.cha_id IN (SELECT id FROM customs_house_agents WHERE ou_id = ? OR (ou_id IS NULL AND lower(email) = lower(?)))")
+				args = append(args, viewer.OUID, viewer.Email)
+			}
+			// Members of a CHA organisation act for the CHA it is bound to, and for the
+			// consignments delegated to it while the delegation is active.
+			if viewer.UserID != "" {
+				clauses = append(clauses,
+					"consignments.cha_id IN (SELECT o.cha_id FROM organizations o JOIN organization_members me ON me.organization_id = o.id WHERE me.user_id = ? AND o.cha_id IS NOT NULL)",
+					"consignments.id IN (SELECT d.consignment_id FROM consignment_delegations d JOIN organization_members me ON me.organization_id = d.organization_id WHERE me.user_id = ? AND d.revoked_at IS NULL AND d.starts_at <= NOW() AND d.expires_at > NOW())")
+				args = append(args, viewer.UserID, viewer.UserID)
+			}
+		case model.ConsignmentViewerOGA:
+			if viewer.OUID == "" {
+				continue
+			}
+			clauses = append(clauses, "consignments.id IN (SELECT t.workflow_id FROM task_infos t JOIN oga_agencies a ON a.code = t.config->>'agency' WHERE a.ou_id = ?)")
+			args = append(args, viewer.OUID)
+		default:
+			return nil, fmt.Errorf("%w: unknown viewer role %q", ErrConsignmentAccessDenied, role)
+		}
+	}
+	if len(clauses) == 0 {
+		return nil, fmt.Errorf("%w: user has no role that grants access to consignments", ErrConsignmentAccessDenied)
+	}
+	return query.Where("("+strings.Join(clauses, ") OR (")+")", args...), nil
+}
+
 // markConsignmentAsFinished updates the consignment state to FINISHED.
 func (s *ConsignmentService) markConsignmentAsFinished(tx *gorm.DB, consignmentID string) error {
 	var consignment model.Consignment
 	if err := tx.First(&consignment, "id = ?", consignmentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: consignment %s not found", ErrUnknownWorkflow, consignmentID)
+		}
 		return fmt.Errorf("failed to retrieve consignment %s: %w", consignmentID, err)
 	}
 	consignment.State = model.ConsignmentStateFinished
@@ -459,70 +582,13 @@ func (s *ConsignmentService) buildConsignmentDetailDTO(
 		return nil, err
 	}
 
-	nodeResponseDTOs := make([]model.WorkflowNodeResponseDTO, 0)
-	edgeResponseDTOs := make([]model.WorkflowEdgeResponseDTO, 0)
-
+	nodeResponseDTOs, edgeResponseDTOs, err := buildWorkflowGraphDTOs(ctx, s.templateProvider, consignment.ID, workflowV2)
+	if err != nil {
+		return nil, err
+	}
 	if workflowV2 != nil {
-		taskTemplateIDs := make([]string, 0, len(workflowV2.NodeInfo))
-		for _, node := range workflowV2.NodeInfo {
-			if node.Type == workflowmanager.NodeTypeTask {
-				taskTemplateIDs = append(taskTemplateIDs, node.TaskTemplateID)
-			}
-		}
-		taskTemplates, err := s.templateProvider.GetWorkflowNodeTemplatesByIDs(ctx, taskTemplateIDs)
-		if err != nil {
-			return nil, fmt.Errorf("failed to retrieve workflow node templates for consignment %s: %w", consignment.ID, err)
-		}
-		taskTemplateMap := make(map[string]model.WorkflowNodeTemplate)
-		for _, taskTemplate := range taskTemplates {
-			taskTemplateMap[taskTemplate.ID] = taskTemplate
-		}
-		for _, node := range workflowV2.NodeInfo {
-			var taskName, taskDescription, taskType string
-			var nodeState model.WorkflowNodeState
-			if node.Type == workflowmanager.NodeTypeTask {
-				taskTemplate, ok := taskTemplateMap[node.TaskTemplateID]
-				if !ok {
-					slog.Error("failed to retrieve workflow node template for", "consignment_id", consignment.ID, "node_id", node.ID, "task_template_id", node.TaskTemplateID)
-					return nil, fmt.Errorf("failed to retrieve workflow node template %s for node %s", node.TaskTemplateID, node.ID)
-				}
-				taskName = taskTemplate.Name
-				taskDescription = taskTemplate.Description
-				taskType = string(taskTemplate.Type)
-			} else {
-				taskType = string(node.Type)
-			}
-			// TODO: clean up translations once the frontend is updated.
-			switch node.Status {
-			case workflowmanager.NodeStatusRunning:
-				nodeState = model.WorkflowNodeStateInProgress
-			case workflowmanager.NodeStatusCompleted:
-				nodeState = model.WorkflowNodeStateCompleted
-			case workflowmanager.NodeStatusFailed:
-				nodeState = model.WorkflowNodeStateFailed
-			case workflowmanager.NodeStatusNotStarted:
-				nodeState = model.WorkflowNodeStateLocked
-			}
-			nodeResponseDTOs = append(nodeResponseDTOs, model.WorkflowNodeResponseDTO{
-				ID:        node.ID,
-				CreatedAt: node.CreatedAt.Format(time.RFC3339),
-				UpdatedAt: node.UpdatedAt.Format(time.RFC3339),
-				WorkflowNodeTemplate: model.WorkflowNodeTemplateResponseDTO{
-					Name:        taskName,
-					Description: taskDescription,
-					Type:        taskType,
-				},
-				State:     nodeState,
-				DependsOn: []string{}, // TODO: should be removed or should be populated based on the workflow definition (not currently stored in DB for v2 workflows)
-			})
-		}
-		for _, edge := range workflowV2.Edges {
-			edgeResponseDTOs = append(edgeResponseDTOs, model.WorkflowEdgeResponseDTO{
-				ID:        edge.ID,
-				SourceID:  edge.SourceID,
-				TargetID:  edge.TargetID,
-				Condition: edge.Condition,
-			})
+		if err := s.attachNodeSLAs(ctx, consignment.ID, nodeResponseDTOs); err != nil {
+			return nil, err
 		}
 	}
 
@@ -540,6 +606,38 @@ func (s *ConsignmentService) buildConsignmentDetailDTO(
 	}, nil
 }
 
+// attachNodeSLAs sets the SLA clock of each node whose task has one.
+func (s *ConsignmentService) attachNodeSLAs(ctx context.Context, workflowID string, nodes []model.WorkflowNodeResponseDTO) error {
+	timers, err := sla.WorkflowTimers(ctx, s.db, workflowID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range nodes {
+		timer, ok := timers[nodes[i].ID]
+		if !ok {
+			continue
+		}
+		nodes[i].SLA = &model.WorkflowNodeSLADTO{
+			Status:      string(timer.Status(now)),
+			StartedAt:   timer.StartedAt.Format(time.RFC3339),
+			DueAt:       timer.DueAt.Format(time.RFC3339),
+			StoppedAt:   formatOptionalTime(timer.StoppedAt),
+			BreachedAt:  formatOptionalTime(timer.BreachedAt),
+			Escalations: timer.Escalations,
+		}
+	}
+	return nil
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
+
 // buildConsignmentItemResponseDTOs builds a slice of ConsignmentItemResponseDTO from ConsignmentItems.
 func (s *ConsignmentService) buildConsignmentItemResponseDTOs(items []model.ConsignmentItem, hsLoader *hsCodeBatchLoader) ([]model.ConsignmentItemResponseDTO, error) {
 	itemResponseDTOs := make([]model.ConsignmentItemResponseDTO, 0, len(items))
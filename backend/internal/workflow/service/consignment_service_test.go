@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql/driver"
 	"errors"
 	"testing"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 
 	workflowManagerV2 "github.com/OpenNSW/go-temporal-workflow"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
@@ -108,7 +110,7 @@ func (m *MockWMV2) GetStatus(ctx context.Context, workflowID string) (*workflowM
 func TestConsignmentService_GetConsignmentByID(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockWM := new(MockWMV2)
-	svc := NewConsignmentService(db, nil)
+	svc := NewConsignmentService(db, nil, nil)
 	require.NoError(t, svc.RegisterWorkflowManager(mockWM))
 
 	ctx := context.Background()
@@ -136,9 +138,54 @@ func TestConsignmentService_GetConsignmentByID(t *testing.T) {
 	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
 
+func TestConsignmentService_GetConsignmentByID_NodeSLA(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	mockWM := new(MockWMV2)
+	templateProvider := new(MockTemplateProvider)
+	svc := NewConsignmentService(db, templateProvider, nil)
+	require.NoError(t, svc.RegisterWorkflowManager(mockWM))
+
+	ctx := context.Background()
+	consignmentID := uuid.NewString()
+	startedAt := time.Now().Add(-72 * time.Hour).UTC()
+	dueAt := startedAt.Add(48 * time.Hour)
+
+	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE id = \$1 ORDER BY "consignments"."id" LIMIT \$2`).
+		WithArgs(consignmentID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "flow", "trader_id", "state", "created_at", "updated_at", "items"}).
+			AddRow(consignmentID, "IMPORT", "trader1", "IN_PROGRESS", time.Now(), time.Now(), []byte(`[]`)))
+	mockWM.On("GetStatus", ctx, consignmentID).Return(&workflowManagerV2.WorkflowInstance{NodeInfo: []workflowManagerV2.NodeInfo{
+		{ID: "node-review", Type: workflowManagerV2.NodeTypeTask, TaskTemplateID: "nt-review", Status: workflowManagerV2.NodeStatusRunning},
+		{ID: "node-pay", Type: workflowManagerV2.NodeTypeTask, TaskTemplateID: "nt-pay", Status: workflowManagerV2.NodeStatusNotStarted},
+	}}, nil)
+	sqlMock.ExpectQuery(`SELECT "id","task_template_overrides" FROM "workflows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "task_template_overrides"}))
+	templateProvider.On("GetWorkflowNodeTemplatesByIDs", ctx, []string{"nt-review", "nt-pay"}).Return([]model.WorkflowNodeTemplate{
+		{BaseModel: model.BaseModel{ID: "nt-review"}, Name: "OGA review"},
+		{BaseModel: model.BaseModel{ID: "nt-pay"}, Name: "Payment"},
+	}, nil)
+	sqlMock.ExpectQuery(`SELECT \* FROM "task_sla_timers" WHERE workflow_id = \$1 ORDER BY started_at`).
+		WithArgs(consignmentID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "task_id", "workflow_id", "plugin_state", "started_at", "due_at", "escalations"}).
+			AddRow(uuid.NewString(), "node-review", consignmentID, "OGA_ACKNOWLEDGED", startedAt, dueAt, 1))
+
+	result, err := svc.GetConsignmentByID(ctx, consignmentID)
+	require.NoError(t, err)
+	require.Len(t, result.WorkflowNodes, 2)
+	review := result.WorkflowNodes[0]
+	require.NotNil(t, review.SLA)
+	assert.Equal(t, "BREACHED", review.SLA.Status)
+	assert.Equal(t, dueAt.Format(time.RFC3339), review.SLA.DueAt)
+	assert.Equal(t, 1, review.SLA.Escalations)
+	assert.Nil(t, result.WorkflowNodes[1].SLA)
+	mockWM.AssertExpectations(t)
+	templateProvider.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
 func TestConsignmentService_GetConsignmentsByTraderID_Empty(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
-	svc := NewConsignmentService(db, nil)
+	svc := NewConsignmentService(db, nil, nil)
 	ctx := context.Background()
 	traderID := "trader1"
 
@@ -158,7 +205,7 @@ func TestConsignmentService_GetConsignmentsByTraderID_Empty(t *testing.T) {
 
 func TestConsignmentService_GetConsignmentsByTraderID_CountError(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
-	svc := NewConsignmentService(db, nil)
+	svc := NewConsignmentService(db, nil, nil)
 	ctx := context.Background()
 	traderID := "trader1"
 
@@ -172,3 +219,84 @@ func TestConsignmentService_GetConsignmentsByTraderID_CountError(t *testing.T) {
 	assert.Nil(t, result)
 	assert.NoError(t, sqlMock.ExpectationsWereMet())
 }
+
+func TestConsignmentService_ListConsignmentsForViewer_Scopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		viewer model.ConsignmentViewer
+		query  string
+		args   []driver.Value
+	}{
+		{
+			name:   "trader",
+			viewer: model.ConsignmentViewer{UserID: "trader1", Roles: []model.ConsignmentViewerRole{model.ConsignmentViewerTrader}},
+			query:  `SELECT count\(\*\) FROM "consignments" WHERE \(consignments.trader_id = \$1\) OR \(consignments.trader_id IN \(SELECT peer.user_id FROM organization_members me .*WHERE me.user_id = \$2\)\)`,
+			args:   []driver.Value{"trader1", "trader1"},
+		},
+		{
+			name:   "cha",
+			viewer: model.ConsignmentViewer{Email: "agent@cha.example.com", OUID: "ou-cha", Roles: []model.ConsignmentViewerRole{model.ConsignmentViewerCHA}},
+			query:  `SELECT count\(\*\) FROM "consignments" WHERE \(consignments.cha_id IN \(SELECT id FROM customs_house_agents WHERE ou_id = \$1 OR \(ou_id IS NULL AND lower\(email\) = lower\(\$2\)\)\)\)`,
+			args:   []driver.Value{"ou-cha", "agent@cha.example.com"},
+		},
+		{
+			name:   "cha organisation member",
+			viewer: model.ConsignmentViewer{UserID: "agent1", Roles: []model.ConsignmentViewerRole{model.ConsignmentViewerCHA}},
+			query:  `SELECT count\(\*\) FROM "consignments" WHERE \(consignments.cha_id IN \(SELECT o.cha_id FROM organizations o .*WHERE me.user_id = \$1 AND o.cha_id IS NOT NULL\)\) OR \(consignments.id IN \(SELECT d.consignment_id FROM consignment_delegations d .*WHERE me.user_id = \$2 AND d.revoked_at IS NULL AND d.starts_at <= NOW\(\) AND d.expires_at > NOW\(\)\)\)`,
+			args:   []driver.Value{"agent1", "agent1"},
+		},
+		{
+			name:   "oga",
+			viewer: model.ConsignmentViewer{OUID: "ou-npqs", Roles: []model.ConsignmentViewerRole{model.ConsignmentViewerOGA}},
+			query:  `SELECT count\(\*\) FROM "consignments" WHERE \(consignments.id IN \(SELECT t.workflow_id FROM task_infos t JOIN oga_agencies a ON a.code = t.config->>'agency' WHERE a.ou_id = \$1\)\)`,
+			args:   []driver.Value{"ou-npqs"},
+		},
+		{
+			name:   "trader and cha",
+			viewer: model.ConsignmentViewer{UserID: "user1", Email: "user1@example.com", Roles: []model.ConsignmentViewerRole{model.ConsignmentViewerTrader, model.ConsignmentViewerCHA}},
+			query:  `SELECT count\(\*\) FROM "consignments" WHERE \(consignments.trader_id = \$1\) OR \(consignments.trader_id IN .*\) OR \(consignments.cha_id IN .*\)`,
+			args:   []driver.Value{"user1", "user1", "", "user1@example.com", "user1", "user1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, sqlMock := setupTestDB(t)
+			svc := NewConsignmentService(db, nil, nil)
+
+			sqlMock.ExpectQuery(tt.query).
+				WithArgs(tt.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+			result, err := svc.ListConsignmentsForViewer(context.Background(), tt.viewer, model.ConsignmentFilter{})
+			require.NoError(t, err)
+			assert.Equal(t, int64(0), result.TotalCount)
+			assert.NoError(t, sqlMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestConsignmentService_ListConsignmentsForViewer_NoRoles(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	svc := NewConsignmentService(db, nil, nil)
+
+	_, err := svc.ListConsignmentsForViewer(context.Background(), model.ConsignmentViewer{UserID: "user1"}, model.ConsignmentFilter{})
+	assert.ErrorIs(t, err, ErrConsignmentAccessDenied)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestConsignmentService_GetConsignmentByIDForViewer_OutOfScope(t *testing.T) {
+	db, sqlMock := setupTestDB(t)
+	svc := NewConsignmentService(db, nil, nil)
+	consignmentID := uuid.NewString()
+
+	sqlMock.ExpectQuery(`SELECT \* FROM "consignments" WHERE \(\(consignments.trader_id = \$1\) OR \(.*\)\) AND id = \$3`).
+		WithArgs("trader2", "trader2", consignmentID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	viewer := model.ConsignmentViewer{UserID: "trader2", Roles: []model.ConsignmentViewerRole{model.ConsignmentViewerTrader}}
+	result, err := svc.GetConsignmentByIDForViewer(context.Background(), consignmentID, viewer)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.Nil(t, result)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -2,59 +2,162 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"gorm.io/gorm"
 
-	workflowmanager "github.com/OpenNSW/nsw/internal/workflow/manager"
+	workflowmanager "github.com/OpenNSW/go-temporal-workflow"
+
+	"github.com/OpenNSW/nsw/internal/profile/user"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 	"github.com/OpenNSW/nsw/utils"
 )
 
+// TraderProfileSyncer writes the business details verified by a completed pre-consignment into
+// the trader's profile. It is implemented by user.Service.
+type TraderProfileSyncer interface {
+	ApplyVerifiedProfile(tx *gorm.DB, id string, verified user.Profile, changedBy string) error
+}
+
 // PreConsignmentService provides operations related to pre-consignments.
-// It also implements WorkflowEventHandler for domain-specific lifecycle callbacks.
+// Pre-consignment workflows run on the Temporal workflow manager; CompletionHandler is called
+// by the workflow runtime when one completes.
 type PreConsignmentService struct {
 	db               *gorm.DB
 	templateProvider TemplateProvider
-	workflowManager  workflowmanager.Manager
+	wm               workflowmanager.Manager
+	profiles         TraderProfileSyncer
 }
 
 // NewPreConsignmentService creates a new instance of PreConsignmentService with the provided dependencies.
-func NewPreConsignmentService(db *gorm.DB, templateProvider TemplateProvider, workflowManager workflowmanager.Manager) *PreConsignmentService {
+// profiles is optional; without it, completed pre-consignments are not synced to trader profiles.
+func NewPreConsignmentService(db *gorm.DB, templateProvider TemplateProvider, profiles TraderProfileSyncer) *PreConsignmentService {
 	return &PreConsignmentService{
 		db:               db,
 		templateProvider: templateProvider,
-		workflowManager:  workflowManager,
+		profiles:         profiles,
 	}
 }
 
-// --- WorkflowEventHandler implementation ---
+// RegisterWorkflowManager registers the workflow manager
+func (s *PreConsignmentService) RegisterWorkflowManager(wm workflowmanager.Manager) error {
+	if s.wm != nil {
+		return fmt.Errorf("workflow manager already registered for PreConsignmentService")
+	}
+	if wm == nil {
+		return fmt.Errorf("workflow manager cannot be nil")
+	}
+	s.wm = wm
+	return nil
+}
 
-// OnWorkflowStatusChanged handles workflow lifecycle state propagation to pre-consignment domain state.
-func (s *PreConsignmentService) OnWorkflowStatusChanged(_ context.Context, tx *gorm.DB, workflowID string, _ model.WorkflowStatus, toStatus model.WorkflowStatus, workflow *model.Workflow) error {
-	var preConsignment model.PreConsignment
-	if err := tx.First(&preConsignment, "id = ?", workflowID).Error; err != nil {
-		return fmt.Errorf("failed to retrieve pre-consignment %s: %w", workflowID, err)
+// CompletionHandler is called by the workflow runtime when a workflow completes. If the workflow
+// is a pre-consignment, it is marked COMPLETED, its final context is synced to the trader's
+// profile, and the pre-consignments depending on it are unlocked once all their dependencies are
+// completed. Workflows that are not pre-consignments are reported with ErrUnknownWorkflow.
+func (s *PreConsignmentService) CompletionHandler(workflowID string, finalContext map[string]any) error {
+	return s.completePreConsignment(context.Background(), workflowID, finalContext)
+}
+
+// completePreConsignment records the completion of the pre-consignment workflowID. A completion
+// delivered again for a pre-consignment that is already COMPLETED is ignored.
+func (s *PreConsignmentService) completePreConsignment(ctx context.Context, workflowID string, finalContext map[string]any) error {
+	if finalContext == nil {
+		finalContext = make(map[string]any)
 	}
 
-	switch toStatus {
-	case model.WorkflowStatusCompleted:
+	var preConsignment model.PreConsignment
+	var unlocked []model.PreConsignmentTemplate
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&preConsignment, "id = ?", workflowID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: pre-consignment %s not found", ErrUnknownWorkflow, workflowID)
+			}
+			return fmt.Errorf("failed to retrieve pre-consignment %s: %w", workflowID, err)
+		}
+		if preConsignment.State == model.PreConsignmentStateCompleted {
+			return nil
+		}
+
 		preConsignment.State = model.PreConsignmentStateCompleted
+		preConsignment.TraderContext = finalContext
 		if err := tx.Save(&preConsignment).Error; err != nil {
 			return fmt.Errorf("failed to update pre-consignment %s state to COMPLETED: %w", workflowID, err)
 		}
-		if workflow == nil {
-			return fmt.Errorf("workflow payload cannot be nil for completed state")
-		}
-		if err := s.syncTraderContextToAuth(tx, &preConsignment, workflow.GlobalContext); err != nil {
+		if err := s.syncTraderContextToAuth(tx, &preConsignment, finalContext); err != nil {
 			return fmt.Errorf("failed to sync trader context to auth: %w", err)
 		}
+
+		var err error
+		unlocked, err = s.unlockedDependants(tx, preConsignment.TraderID, preConsignment.PreConsignmentTemplateID)
+		return err
+	})
+	if err != nil {
+		return err
 	}
 
+	for _, template := range unlocked {
+		slog.InfoContext(ctx, "pre-consignment unlocked",
+			"traderId", preConsignment.TraderID,
+			"preConsignmentTemplateId", template.ID,
+			"completedPreConsignmentId", preConsignment.ID)
+	}
 	return nil
 }
 
+// unlockedDependants returns the pre-consignment templates that depend on completedTemplateID and
+// whose dependencies the trader has now all completed, excluding those the trader has started.
+func (s *PreConsignmentService) unlockedDependants(tx *gorm.DB, traderID, completedTemplateID string) ([]model.PreConsignmentTemplate, error) {
+	containsCompleted, err := json.Marshal([]string{completedTemplateID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode template ID: %w", err)
+	}
+	var dependants []model.PreConsignmentTemplate
+	if err := tx.Where("depends_on @> ?::jsonb", string(containsCompleted)).Find(&dependants).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve pre-consignment templates depending on %s: %w", completedTemplateID, err)
+	}
+	if len(dependants) == 0 {
+		return nil, nil
+	}
+
+	var existing []model.PreConsignment
+	if err := tx.Select("pre_consignment_template_id", "state").
+		Where("trader_id = ?", traderID).
+		Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve pre-consignments for trader %s: %w", traderID, err)
+	}
+	started := make(map[string]bool, len(existing))
+	completed := make(map[string]bool, len(existing))
+	for _, pc := range existing {
+		started[pc.PreConsignmentTemplateID] = true
+		if pc.State == model.PreConsignmentStateCompleted {
+			completed[pc.PreConsignmentTemplateID] = true
+		}
+	}
+
+	unlocked := make([]model.PreConsignmentTemplate, 0, len(dependants))
+	for _, template := range dependants {
+		if started[template.ID] {
+			continue
+		}
+		ready := true
+		for _, depID := range template.DependsOn {
+			if !completed[depID] {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			unlocked = append(unlocked, template)
+		}
+	}
+	return unlocked, nil
+}
+
 // GetTraderPreConsignments retrieves a paginated list of pre-consignment templates and computes their state
 // based on the trader's existing pre-consignments and their dependencies.
 func (s *PreConsignmentService) GetTraderPreConsignments(ctx context.Context, traderID string, offset *int, limit *int) (model.TraderPreConsignmentsResponseDTO, error) {
@@ -181,24 +284,23 @@ func (s *PreConsignmentService) initializePreConsignmentInTx(
 		return nil, fmt.Errorf("pre-consignment template %s not found: %w", createReq.PreConsignmentTemplateID, err)
 	}
 
-	// Validate dependencies are met
-	if len(pcTemplate.DependsOn) > 0 {
-		var completedCount int64
-		if err := s.db.WithContext(ctx).Model(&model.PreConsignment{}).
-			Where("trader_id = ? AND pre_consignment_template_id IN ? AND state = ?",
-				traderId, pcTemplate.DependsOn, model.PreConsignmentStateCompleted).
-			Count(&completedCount).Error; err != nil {
-			return nil, fmt.Errorf("failed to check dependency completion: %w", err)
-		}
-		if int(completedCount) < len(pcTemplate.DependsOn) {
-			return nil, fmt.Errorf("dependency pre-consignments are not all completed")
-		}
+	if pcTemplate.WorkflowTemplateV2ID == nil || *pcTemplate.WorkflowTemplateV2ID == "" {
+		return nil, fmt.Errorf("pre-consignment template %s has no workflow template", pcTemplate.ID)
+	}
+
+	// Validate dependencies are met, and start from the context they completed with
+	workflowContext, err := s.dependencyContext(ctx, traderId, pcTemplate.DependsOn)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range initialTraderContext {
+		workflowContext[key] = value
 	}
 
 	// Fetch the workflow template referenced by the pre-consignment template
-	workflowTemplate, err := s.templateProvider.GetWorkflowTemplateByID(ctx, pcTemplate.WorkflowTemplateID)
+	workflowTemplate, err := s.templateProvider.GetWorkflowTemplateByIDV2(ctx, *pcTemplate.WorkflowTemplateV2ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get workflow template %s: %w", pcTemplate.WorkflowTemplateID, err)
+		return nil, fmt.Errorf("failed to get workflow template %s: %w", *pcTemplate.WorkflowTemplateV2ID, err)
 	}
 
 	// Begin transaction
@@ -214,14 +316,19 @@ func (s *PreConsignmentService) initializePreConsignmentInTx(
 		TraderID:                 traderId,
 		PreConsignmentTemplateID: createReq.PreConsignmentTemplateID,
 		State:                    model.PreConsignmentStateInProgress,
+		TraderContext:            map[string]any{},
 	}
 	if err := tx.Create(preConsignment).Error; err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to create pre-consignment: %w", err)
 	}
 
-	// Register workflow with the manager (creates Workflow entity + nodes + registers with TM)
-	if err := s.workflowManager.StartWorkflowInstance(ctx, tx, preConsignment.ID, []model.WorkflowTemplate{*workflowTemplate}, initialTraderContext, s); err != nil {
+	// The pre-consignment ID doubles as the workflow ID, pinned to the template it starts from
+	if err := tx.Create(newWorkflowInstance(preConsignment.ID, workflowTemplate, workflowContext)).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to record workflow: %w", err)
+	}
+	if err := s.wm.StartWorkflow(ctx, preConsignment.ID, workflowTemplate.WorkflowDefinition, workflowContext); err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to register workflow: %w", err)
 	}
@@ -240,13 +347,45 @@ func (s *PreConsignmentService) initializePreConsignmentInTx(
 	}
 
 	// Get workflow details for response
-	wf, err := s.workflowManager.GetWorkflowInstance(ctx, preConsignment.ID)
+	workflowInstance, err := workflowStatus(ctx, s.db, s.wm, preConsignment.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get workflow details: %w", err)
 	}
 
-	responseDTO := s.buildPreConsignmentResponseDTO(preConsignment, wf)
-	return responseDTO, nil
+	return s.buildPreConsignmentResponseDTO(ctx, preConsignment, workflowInstance)
+}
+
+// dependencyContext checks that the trader has completed every pre-consignment template in
+// dependsOn and returns the merged final contexts of those pre-consignments, so a dependant
+// starts with the details its dependencies collected.
+func (s *PreConsignmentService) dependencyContext(ctx context.Context, traderID string, dependsOn []string) (map[string]any, error) {
+	workflowContext := make(map[string]any)
+	if len(dependsOn) == 0 {
+		return workflowContext, nil
+	}
+
+	var dependencies []model.PreConsignment
+	if err := s.db.WithContext(ctx).
+		Where("trader_id = ? AND pre_consignment_template_id IN ? AND state = ?",
+			traderID, dependsOn, model.PreConsignmentStateCompleted).
+		Order("updated_at ASC").
+		Find(&dependencies).Error; err != nil {
+		return nil, fmt.Errorf("failed to check dependency completion: %w", err)
+	}
+
+	completed := make(map[string]bool, len(dependencies))
+	for _, dependency := range dependencies {
+		completed[dependency.PreConsignmentTemplateID] = true
+		for key, value := range dependency.TraderContext {
+			workflowContext[key] = value
+		}
+	}
+	for _, depID := range dependsOn {
+		if !completed[depID] {
+			return nil, fmt.Errorf("dependency pre-consignments are not all completed")
+		}
+	}
+	return workflowContext, nil
 }
 
 // GetPreConsignmentsByTraderID retrieves all pre-consignments for a trader (excluding LOCKED state).
@@ -267,11 +406,14 @@ func (s *PreConsignmentService) GetPreConsignmentsByTraderID(ctx context.Context
 	responseDTOs := make([]model.PreConsignmentResponseDTO, 0, len(preConsignments))
 	for i := range preConsignments {
 		// Get workflow details for each pre-consignment
-		wf, err := s.workflowManager.GetWorkflowInstance(ctx, preConsignments[i].ID)
+		workflowInstance, err := workflowStatus(ctx, s.db, s.wm, preConsignments[i].ID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get workflow details for pre-consignment %s: %w", preConsignments[i].ID, err)
 		}
-		responseDTO := s.buildPreConsignmentResponseDTO(&preConsignments[i], wf)
+		responseDTO, err := s.buildPreConsignmentResponseDTO(ctx, &preConsignments[i], workflowInstance)
+		if err != nil {
+			return nil, err
+		}
 		responseDTOs = append(responseDTOs, *responseDTO)
 	}
 
@@ -288,55 +430,60 @@ func (s *PreConsignmentService) GetPreConsignmentByID(ctx context.Context, preCo
 		return nil, fmt.Errorf("failed to retrieve pre-consignment with ID %s: %w", preConsignmentID, result.Error)
 	}
 
-	wf, err := s.workflowManager.GetWorkflowInstance(ctx, preConsignment.ID)
+	workflowInstance, err := workflowStatus(ctx, s.db, s.wm, preConsignment.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get workflow details: %w", err)
 	}
 
-	responseDTO := s.buildPreConsignmentResponseDTO(&preConsignment, wf)
-	return responseDTO, nil
+	return s.buildPreConsignmentResponseDTO(ctx, &preConsignment, workflowInstance)
 }
 
 // syncTraderContextToAuth synchronizes the trader context (from the workflow's global context) to the user profile.
 // This is called when a pre-consignment is completed to persist accumulated context.
-// It updates the Data field on the company profile Record.
-// TODO: Once the Company Profile Service is implemented, we should merge the pre-consignment results with the existing company profile data instead of overwriting it.
+// The business details it carries are merged into the trader's profile as verified fields.
 // TODO: This function name and signature may need to be refactored as well once we have a clearer picture of the data flow and ownership between pre-consignment, company profile, and user profile.
-func (s *PreConsignmentService) syncTraderContextToAuth(_ *gorm.DB, _ *model.PreConsignment, _ map[string]any) error {
-	return nil
+func (s *PreConsignmentService) syncTraderContextToAuth(tx *gorm.DB, preConsignment *model.PreConsignment, globalContext map[string]any) error {
+	if s.profiles == nil {
+		slog.Warn("trader profile sync is not configured; skipping", "preConsignmentId", preConsignment.ID)
+		return nil
+	}
+	verified := traderProfileFromContext(globalContext)
+	err := s.profiles.ApplyVerifiedProfile(tx, preConsignment.TraderID, verified, "pre-consignment/"+preConsignment.ID)
+	if errors.Is(err, user.ErrInvalidProfile) {
+		// The profile update runs in a savepoint and is rolled back on its own; the workflow has
+		// completed regardless and cannot be resubmitted, so the completion must stand.
+		slog.Warn("trader profile not synced from pre-consignment, profile fails validation",
+			"preConsignmentId", preConsignment.ID, "traderId", preConsignment.TraderID, "error", err)
+		return nil
+	}
+	return err
 }
 
-// buildPreConsignmentResponseDTO builds a PreConsignmentResponseDTO from a PreConsignment.
-// The workflow parameter provides the workflow nodes and global context (trader context).
-func (s *PreConsignmentService) buildPreConsignmentResponseDTO(preConsignment *model.PreConsignment, workflow *model.Workflow) *model.PreConsignmentResponseDTO {
-	var nodeResponseDTOs []model.WorkflowNodeResponseDTO
-	if workflow != nil {
-		nodeResponseDTOs = make([]model.WorkflowNodeResponseDTO, 0, len(workflow.WorkflowNodes))
-		for _, node := range workflow.WorkflowNodes {
-			nodeResponseDTOs = append(nodeResponseDTOs, model.WorkflowNodeResponseDTO{
-				ID:        node.ID,
-				CreatedAt: node.CreatedAt.Format(time.RFC3339),
-				UpdatedAt: node.UpdatedAt.Format(time.RFC3339),
-				WorkflowNodeTemplate: model.WorkflowNodeTemplateResponseDTO{
-					Name:        node.WorkflowNodeTemplate.Name,
-					Description: node.WorkflowNodeTemplate.Description,
-					Type:        string(node.WorkflowNodeTemplate.Type),
-				},
-				State:         node.State,
-				ExtendedState: node.ExtendedState,
-				Outcome:       node.Outcome,
-				DependsOn:     node.DependsOn,
-			})
-		}
+// traderProfileFromContext reads the profile fields written to the global context by the
+// pre-consignment forms (see their x-globalContext.writeTo keys).
+func traderProfileFromContext(globalContext map[string]any) user.Profile {
+	str := func(key string) string {
+		value, _ := globalContext[key].(string)
+		return value
 	}
-	if nodeResponseDTOs == nil {
-		nodeResponseDTOs = []model.WorkflowNodeResponseDTO{}
+	return user.Profile{
+		CompanyName:  str("bi:businessName"),
+		BusinessType: str("bi:businessType"),
+		Address:      str("bi:businessAddress"),
+		Email:        str("bi:email"),
+		PhoneNumber:  str("bi:phoneNumber"),
+		BRNumber:     str("br:registrationNumber"),
+		TIN:          str("br:tinNumber"),
+		VATNumber:    str("br:vatNumber"),
 	}
+}
 
-	// Populate TraderContext from the Workflow's GlobalContext for backward compatibility
-	var traderContext map[string]any
-	if workflow != nil {
-		traderContext = workflow.GlobalContext
+// buildPreConsignmentResponseDTO builds a PreConsignmentResponseDTO from a PreConsignment.
+// The workflow instance provides the workflow nodes and edges.
+func (s *PreConsignmentService) buildPreConsignmentResponseDTO(ctx context.Context, preConsignment *model.PreConsignment, workflowInstance *workflowmanager.WorkflowInstance) (*model.PreConsignmentResponseDTO, error) {
+	nodeResponseDTOs, edgeResponseDTOs, err := buildWorkflowGraphDTOs(ctx, s.templateProvider, preConsignment.ID, workflowInstance)
+	if err != nil {
+		return nil, err
 	}
 
 	dependsOn := preConsignment.PreConsignmentTemplate.DependsOn
@@ -348,7 +495,7 @@ func (s *PreConsignmentService) buildPreConsignmentResponseDTO(preConsignment *m
 		ID:            preConsignment.ID,
 		TraderID:      preConsignment.TraderID,
 		State:         preConsignment.State,
-		TraderContext: traderContext,
+		TraderContext: preConsignment.TraderContext,
 		CreatedAt:     preConsignment.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:     preConsignment.UpdatedAt.Format(time.RFC3339),
 		PreConsignmentTemplate: model.PreConsignmentTemplateResponseDTO{
@@ -358,5 +505,6 @@ func (s *PreConsignmentService) buildPreConsignmentResponseDTO(preConsignment *m
 			DependsOn:   dependsOn,
 		},
 		WorkflowNodes: nodeResponseDTOs,
-	}
+		Edges:         edgeResponseDTOs,
+	}, nil
 }
@@ -3,51 +3,35 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	workflowManagerV2 "github.com/OpenNSW/go-temporal-workflow"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
 
-	taskManager "github.com/OpenNSW/nsw/internal/task/manager"
-	workflowManagerV1 "github.com/OpenNSW/nsw/internal/workflow/manager"
+	"github.com/OpenNSW/nsw/internal/profile/user"
 	"github.com/OpenNSW/nsw/internal/workflow/model"
 )
 
-// MockWorkflowManager implements v1 workflow manager for pre-consignment tests.
-type MockWorkflowManager struct {
-	mock.Mock
-}
-
-func (m *MockWorkflowManager) StartWorkflowInstance(ctx context.Context, tx *gorm.DB, workflowID string, workflowTemplates []model.WorkflowTemplate, globalContext map[string]any, handler workflowManagerV1.WorkflowEventHandler) error {
-	args := m.Called(ctx, tx, workflowID, workflowTemplates, globalContext, handler)
-	return args.Error(0)
-}
-
-func (m *MockWorkflowManager) GetWorkflowInstance(ctx context.Context, workflowID string) (*model.Workflow, error) {
-	args := m.Called(ctx, workflowID)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*model.Workflow), args.Error(1)
-}
-
-func (m *MockWorkflowManager) RegisterTaskHandler(_ workflowManagerV1.TaskInitHandler) error {
-	return nil
-}
-
-func (m *MockWorkflowManager) HandleTaskUpdate(_ context.Context, _ taskManager.WorkflowManagerNotification) error {
-	return nil
+// newTestPreConsignmentService creates a PreConsignmentService with mockWM registered as its workflow manager.
+func newTestPreConsignmentService(t *testing.T, db *gorm.DB, templateProvider TemplateProvider, mockWM *MockWMV2, profiles TraderProfileSyncer) *PreConsignmentService {
+	t.Helper()
+	svc := NewPreConsignmentService(db, templateProvider, profiles)
+	require.NoError(t, svc.RegisterWorkflowManager(mockWM))
+	return svc
 }
 
 func TestPreConsignmentService_InitializePreConsignment(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTP := new(MockTemplateProvider)
-	mockWM := new(MockWorkflowManager)
-	svc := NewPreConsignmentService(db, mockTP, mockWM)
+	mockWM := new(MockWMV2)
+	svc := newTestPreConsignmentService(t, db, mockTP, mockWM, nil)
 
 	ctx := context.Background()
 	traderID := "trader1"
@@ -58,26 +42,31 @@ func TestPreConsignmentService_InitializePreConsignment(t *testing.T) {
 	initialContext := map[string]any{"key": "value"}
 
 	// Get PreConsignmentTemplate
-	workflowTemplateID := uuid.NewString()
+	workflowTemplateID := "pre-consignment-basic-details-v1"
 	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE id = \$1`).
 		WithArgs(templateID, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_template_id", "depends_on"}).
-			AddRow(templateID, workflowTemplateID, []byte("[]")))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_template_id", "workflow_template_v2_id", "depends_on"}).
+			AddRow(templateID, uuid.NewString(), workflowTemplateID, []byte("[]")))
 
 	// Get Workflow Template
-	workflowTemplate := &model.WorkflowTemplate{
-		BaseModel:     model.BaseModel{ID: workflowTemplateID},
-		Name:          "Test WF Template",
-		NodeTemplates: model.StringArray{},
+	workflowTemplate := &model.WorkflowTemplateV2{
+		BaseModel: model.BaseModel{ID: workflowTemplateID},
+		Name:      "Test WF Template",
+		WorkflowDefinition: workflowManagerV2.WorkflowDefinition{
+			ID:   workflowTemplateID,
+			Name: "Test WF Template",
+		},
 	}
-	mockTP.On("GetWorkflowTemplateByID", ctx, workflowTemplateID).Return(workflowTemplate, nil)
+	mockTP.On("GetWorkflowTemplateByIDV2", ctx, workflowTemplateID).Return(workflowTemplate, nil)
 
 	sqlMock.ExpectBegin()
 	sqlMock.ExpectExec(`INSERT INTO "pre_consignments"`).
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectExec(`INSERT INTO "workflows"`).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	mockWM.On("StartWorkflowInstance", ctx, mock.Anything, mock.AnythingOfType("string"), mock.Anything, initialContext, mock.Anything).Return(nil)
+	mockWM.On("StartWorkflow", ctx, mock.AnythingOfType("string"), workflowTemplate.WorkflowDefinition, initialContext).Return(nil)
 	sqlMock.ExpectCommit()
 
 	// Reload pre-consignment with template
@@ -91,38 +80,63 @@ func TestPreConsignmentService_InitializePreConsignment(t *testing.T) {
 		WithArgs(templateID).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(templateID, "Test PC Template"))
 
-	// GetWorkflowInstance for building response DTO
-	nodeTemplateID := uuid.NewString()
-	mockWM.On("GetWorkflowInstance", ctx, mock.AnythingOfType("string")).Return(&model.Workflow{
-		BaseModel:     model.BaseModel{ID: pcID},
-		Status:        model.WorkflowStatusInProgress,
-		GlobalContext: map[string]any{"key": "value"},
-		WorkflowNodes: []model.WorkflowNode{
-			{
-				BaseModel:              model.BaseModel{ID: uuid.NewString()},
-				WorkflowNodeTemplateID: nodeTemplateID,
-				State:                  model.WorkflowNodeStateReady,
-				WorkflowNodeTemplate: model.WorkflowNodeTemplate{
-					BaseModel: model.BaseModel{ID: nodeTemplateID},
-					Name:      "Test Node",
-					Type:      "SIMPLE_FORM",
-				},
-			},
-		},
-	}, nil)
+	// GetStatus for building response DTO
+	mockWM.On("GetStatus", ctx, mock.AnythingOfType("string")).Return(&workflowManagerV2.WorkflowInstance{}, nil)
+	sqlMock.ExpectQuery(`SELECT "id","task_template_overrides" FROM "workflows"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "task_template_overrides"}))
+	mockTP.On("GetWorkflowNodeTemplatesByIDs", ctx, []string{}).Return([]model.WorkflowNodeTemplate{}, nil)
 
 	resp, err := svc.InitializePreConsignment(ctx, createReq, traderID, initialContext)
 	assert.NoError(t, err)
-	assert.NotNil(t, resp)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, pcID, resp.ID)
+		assert.Empty(t, resp.WorkflowNodes)
+	}
 	mockTP.AssertExpectations(t)
 	mockWM.AssertExpectations(t)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestPreConsignmentService_DependencyContext(t *testing.T) {
+	ctx := context.Background()
+	first, second := uuid.NewString(), uuid.NewString()
+	query := `SELECT \* FROM "pre_consignments" WHERE trader_id = \$1 AND pre_consignment_template_id IN \(\$2,\$3\) AND state = \$4 ORDER BY updated_at ASC`
+
+	t.Run("Merges Completed Contexts", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		svc := NewPreConsignmentService(db, nil, nil)
+		sqlMock.ExpectQuery(query).
+			WithArgs("trader1", first, second, model.PreConsignmentStateCompleted).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "pre_consignment_template_id", "trader_context"}).
+				AddRow(uuid.NewString(), first, []byte(`{"bi:businessName":"ABC Exports Ltd","shared":"first"}`)).
+				AddRow(uuid.NewString(), second, []byte(`{"br:tinNumber":"TIN123456","shared":"second"}`)))
+
+		workflowContext, err := svc.dependencyContext(ctx, "trader1", []string{first, second})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"bi:businessName": "ABC Exports Ltd",
+			"br:tinNumber":    "TIN123456",
+			"shared":          "second",
+		}, workflowContext)
+	})
+
+	t.Run("Dependency Not Completed", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		svc := NewPreConsignmentService(db, nil, nil)
+		sqlMock.ExpectQuery(query).
+			WithArgs("trader1", first, second, model.PreConsignmentStateCompleted).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "pre_consignment_template_id", "trader_context"}).
+				AddRow(uuid.NewString(), first, []byte(`{}`)))
+
+		_, err := svc.dependencyContext(ctx, "trader1", []string{first, second})
+		assert.ErrorContains(t, err, "dependency pre-consignments are not all completed")
+	})
 }
 
 func TestPreConsignmentService_InitializePreConsignment_TemplateNotFound(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTP := new(MockTemplateProvider)
-	mockWM := new(MockWorkflowManager)
-	svc := NewPreConsignmentService(db, mockTP, mockWM)
+	svc := NewPreConsignmentService(db, mockTP, nil)
 
 	ctx := context.Background()
 	templateID := uuid.NewString()
@@ -143,8 +157,7 @@ func TestPreConsignmentService_InitializePreConsignment_TemplateNotFound(t *test
 func TestPreConsignmentService_InitializePreConsignment_WorkflowTemplateFetchError(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTP := new(MockTemplateProvider)
-	mockWM := new(MockWorkflowManager)
-	svc := NewPreConsignmentService(db, mockTP, mockWM)
+	svc := NewPreConsignmentService(db, mockTP, nil)
 
 	ctx := context.Background()
 	templateID := uuid.NewString()
@@ -155,10 +168,10 @@ func TestPreConsignmentService_InitializePreConsignment_WorkflowTemplateFetchErr
 
 	sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE id = \$1`).
 		WithArgs(templateID, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_template_id", "depends_on"}).
-			AddRow(templateID, workflowTemplateID, []byte("[]")))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workflow_template_id", "workflow_template_v2_id", "depends_on"}).
+			AddRow(templateID, uuid.NewString(), workflowTemplateID, []byte("[]")))
 
-	mockTP.On("GetWorkflowTemplateByID", ctx, workflowTemplateID).Return(nil, errors.New("wf error"))
+	mockTP.On("GetWorkflowTemplateByIDV2", ctx, workflowTemplateID).Return(nil, errors.New("wf error"))
 
 	resp, err := svc.InitializePreConsignment(ctx, createReq, "trader1", nil)
 	assert.Error(t, err)
@@ -168,13 +181,12 @@ func TestPreConsignmentService_InitializePreConsignment_WorkflowTemplateFetchErr
 
 func TestPreConsignmentService_GetPreConsignmentByID(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
-	mockWM := new(MockWorkflowManager)
-	svc := NewPreConsignmentService(db, nil, mockWM)
+	mockWM := new(MockWMV2)
+	svc := newTestPreConsignmentService(t, db, nil, mockWM, nil)
 
 	ctx := context.Background()
 	pcID := uuid.NewString()
 	templateID := uuid.NewString()
-	nodeTemplateID := uuid.NewString()
 
 	t.Run("Success", func(t *testing.T) {
 		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1 ORDER BY "pre_consignments"."id" LIMIT \$2`).
@@ -186,22 +198,7 @@ func TestPreConsignmentService_GetPreConsignmentByID(t *testing.T) {
 			WithArgs(templateID).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(templateID, "Template"))
 
-		mockWM.On("GetWorkflowInstance", ctx, pcID).Return(&model.Workflow{
-			BaseModel: model.BaseModel{ID: pcID},
-			Status:    model.WorkflowStatusInProgress,
-			WorkflowNodes: []model.WorkflowNode{
-				{
-					BaseModel:              model.BaseModel{ID: uuid.NewString()},
-					WorkflowNodeTemplateID: nodeTemplateID,
-					State:                  model.WorkflowNodeStateReady,
-					WorkflowNodeTemplate: model.WorkflowNodeTemplate{
-						BaseModel: model.BaseModel{ID: nodeTemplateID},
-						Name:      "Node Template",
-						Type:      "SIMPLE_FORM",
-					},
-				},
-			},
-		}, nil).Once()
+		mockWM.On("GetStatus", ctx, pcID).Return((*workflowManagerV2.WorkflowInstance)(nil), nil).Once()
 
 		resp, err := svc.GetPreConsignmentByID(ctx, pcID)
 		assert.NoError(t, err)
@@ -224,8 +221,8 @@ func TestPreConsignmentService_GetPreConsignmentByID(t *testing.T) {
 
 func TestPreConsignmentService_GetPreConsignmentsByTraderID(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
-	mockWM := new(MockWorkflowManager)
-	svc := NewPreConsignmentService(db, nil, mockWM)
+	mockWM := new(MockWMV2)
+	svc := newTestPreConsignmentService(t, db, nil, mockWM, nil)
 
 	ctx := context.Background()
 	traderID := "trader1"
@@ -233,7 +230,6 @@ func TestPreConsignmentService_GetPreConsignmentsByTraderID(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		pcID := uuid.NewString()
 		templateID := uuid.NewString()
-		nodeTemplateID := uuid.NewString()
 
 		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE trader_id = \$1 AND state != \$2`).
 			WithArgs(traderID, model.PreConsignmentStateLocked).
@@ -244,22 +240,7 @@ func TestPreConsignmentService_GetPreConsignmentsByTraderID(t *testing.T) {
 			WithArgs(templateID).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(templateID, "Test PC Template"))
 
-		mockWM.On("GetWorkflowInstance", ctx, pcID).Return(&model.Workflow{
-			BaseModel: model.BaseModel{ID: pcID},
-			Status:    model.WorkflowStatusInProgress,
-			WorkflowNodes: []model.WorkflowNode{
-				{
-					BaseModel:              model.BaseModel{ID: uuid.NewString()},
-					WorkflowNodeTemplateID: nodeTemplateID,
-					State:                  model.WorkflowNodeStateReady,
-					WorkflowNodeTemplate: model.WorkflowNodeTemplate{
-						BaseModel: model.BaseModel{ID: nodeTemplateID},
-						Name:      "Node",
-						Type:      "SIMPLE_FORM",
-					},
-				},
-			},
-		}, nil).Once()
+		mockWM.On("GetStatus", ctx, pcID).Return((*workflowManagerV2.WorkflowInstance)(nil), nil).Once()
 
 		results, err := svc.GetPreConsignmentsByTraderID(ctx, traderID)
 		assert.NoError(t, err)
@@ -282,8 +263,7 @@ func TestPreConsignmentService_GetPreConsignmentsByTraderID(t *testing.T) {
 func TestPreConsignmentService_GetTraderPreConsignments(t *testing.T) {
 	db, sqlMock := setupTestDB(t)
 	mockTP := new(MockTemplateProvider)
-	mockWM := new(MockWorkflowManager)
-	svc := NewPreConsignmentService(db, mockTP, mockWM)
+	svc := NewPreConsignmentService(db, mockTP, nil)
 
 	ctx := context.Background()
 	traderID := "trader1"
@@ -325,3 +305,123 @@ func TestPreConsignmentService_GetTraderPreConsignments_CountError(t *testing.T)
 	assert.Error(t, err)
 	assert.Equal(t, model.TraderPreConsignmentsResponseDTO{}, result)
 }
+
+type fakeProfileSyncer struct {
+	userID    string
+	verified  user.Profile
+	changedBy string
+	err       error
+}
+
+func (f *fakeProfileSyncer) ApplyVerifiedProfile(_ *gorm.DB, id string, verified user.Profile, changedBy string) error {
+	f.userID, f.verified, f.changedBy = id, verified, changedBy
+	return f.err
+}
+
+func TestPreConsignmentService_CompletionHandler(t *testing.T) {
+	basicDetails, verification, other := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	finalContext := map[string]any{"bi:businessName": "ABC Exports Ltd"}
+
+	t.Run("Marks Completed And Unlocks Dependants", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		profiles := &fakeProfileSyncer{}
+		svc := NewPreConsignmentService(db, nil, profiles)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1`).
+			WithArgs("pc-1", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state", "pre_consignment_template_id"}).
+				AddRow("pc-1", "trader1", "IN_PROGRESS", basicDetails))
+		sqlMock.ExpectExec(`UPDATE "pre_consignments" SET .*"state"=\$\d+,"trader_context"=\$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignment_templates" WHERE depends_on @> \$1::jsonb`).
+			WithArgs(`["` + basicDetails + `"]`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "depends_on"}).
+				AddRow(verification, []byte(`["`+basicDetails+`"]`)).
+				AddRow(other, []byte(`["`+basicDetails+`","`+uuid.NewString()+`"]`)))
+		sqlMock.ExpectQuery(`SELECT "pre_consignment_template_id","state" FROM "pre_consignments" WHERE trader_id = \$1`).
+			WithArgs("trader1").
+			WillReturnRows(sqlmock.NewRows([]string{"pre_consignment_template_id", "state"}).
+				AddRow(basicDetails, "COMPLETED"))
+		sqlMock.ExpectCommit()
+
+		require.NoError(t, svc.CompletionHandler("pc-1", finalContext))
+		assert.Equal(t, "trader1", profiles.userID)
+		assert.Equal(t, "ABC Exports Ltd", profiles.verified.CompanyName)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Already Completed", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		profiles := &fakeProfileSyncer{}
+		svc := NewPreConsignmentService(db, nil, profiles)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "trader_id", "state", "pre_consignment_template_id"}).
+				AddRow("pc-1", "trader1", "COMPLETED", basicDetails))
+		sqlMock.ExpectCommit()
+
+		require.NoError(t, svc.CompletionHandler("pc-1", finalContext))
+		assert.Empty(t, profiles.userID)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Not A Pre-Consignment", func(t *testing.T) {
+		db, sqlMock := setupTestDB(t)
+		svc := NewPreConsignmentService(db, nil, nil)
+
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "pre_consignments" WHERE id = \$1`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		sqlMock.ExpectRollback()
+
+		err := svc.CompletionHandler("consignment-1", finalContext)
+		assert.ErrorIs(t, err, ErrUnknownWorkflow)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestPreConsignmentService_SyncTraderContextToAuth(t *testing.T) {
+	profiles := &fakeProfileSyncer{}
+	svc := NewPreConsignmentService(nil, nil, profiles)
+	preConsignment := &model.PreConsignment{BaseModel: model.BaseModel{ID: "pc-1"}, TraderID: "trader1"}
+	globalContext := map[string]any{
+		"bi:businessName":       "ABC Exports Ltd",
+		"bi:businessType":       "Private Limited",
+		"br:registrationNumber": "BR123456",
+		"br:tinNumber":          "TIN123456",
+		"br:tinCertificate":     "uploads/tin.pdf",
+		"other":                 42,
+	}
+
+	err := svc.syncTraderContextToAuth(nil, preConsignment, globalContext)
+	assert.NoError(t, err)
+	assert.Equal(t, "trader1", profiles.userID)
+	assert.Equal(t, "pre-consignment/pc-1", profiles.changedBy)
+	assert.Equal(t, user.Profile{
+		CompanyName:  "ABC Exports Ltd",
+		BusinessType: "Private Limited",
+		BRNumber:     "BR123456",
+		TIN:          "TIN123456",
+	}, profiles.verified)
+}
+
+func TestPreConsignmentService_SyncTraderContextToAuth_InvalidProfile(t *testing.T) {
+	preConsignment := &model.PreConsignment{BaseModel: model.BaseModel{ID: "pc-1"}, TraderID: "trader1"}
+
+	// An invalid profile must not roll back the completion of the workflow.
+	profiles := &fakeProfileSyncer{err: fmt.Errorf("%w: contacts[0].name is required", user.ErrInvalidProfile)}
+	svc := NewPreConsignmentService(nil, nil, profiles)
+	assert.NoError(t, svc.syncTraderContextToAuth(nil, preConsignment, map[string]any{}))
+
+	// Database failures still fail the completion.
+	profiles.err = errors.New("connection reset")
+	assert.Error(t, svc.syncTraderContextToAuth(nil, preConsignment, map[string]any{}))
+}
+
+func TestPreConsignmentService_SyncTraderContextToAuth_NotConfigured(t *testing.T) {
+	svc := NewPreConsignmentService(nil, nil, nil)
+	preConsignment := &model.PreConsignment{BaseModel: model.BaseModel{ID: "pc-1"}, TraderID: "trader1"}
+	assert.NoError(t, svc.syncTraderContextToAuth(nil, preConsignment, map[string]any{}))
+}
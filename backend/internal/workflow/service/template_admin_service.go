@@ -0,0 +1,659 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/OpenNSW/nsw/internal/audit"
+	"github.com/OpenNSW/nsw/internal/workflow/lint"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+var (
+	// ErrTemplateNotFound is returned when a node template, workflow template or mapping does not exist.
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrTemplateExists is returned when creating a template or mapping whose key is already taken.
+	ErrTemplateExists = errors.New("template already exists")
+	// ErrTemplatePublished is returned when changing or deleting a published template.
+	ErrTemplatePublished = errors.New("published templates cannot be changed")
+	// ErrInvalidTemplate is returned when a request is incomplete or a template fails validation.
+	ErrInvalidTemplate = errors.New("invalid template")
+)
+
+// TemplateValidationError lists the issues that prevent a template from being published.
+type TemplateValidationError struct {
+	Issues []lint.Issue
+}
+
+func (e *TemplateValidationError) Error() string {
+	messages := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		messages = append(messages, issue.String())
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidTemplate, strings.Join(messages, "; "))
+}
+
+func (e *TemplateValidationError) Unwrap() error {
+	return ErrInvalidTemplate
+}
+
+// TemplateAdminService authors node templates, workflow templates and the HS code mappings
+// that select a workflow template for new consignments.
+//
+// Templates are created as drafts, which can be edited and deleted. Publishing validates a
+// draft and makes it immutable, so workflows already running from it are never affected by
+// later edits. A draft may be based on a published template; publishing it moves the
+// mappings and pre-consignment templates that used that template over to the draft, so new
+// consignments pick it up while running ones keep the definition they started with.
+type TemplateAdminService struct {
+	db       *gorm.DB
+	linter   *lint.Linter
+	auditLog audit.Recorder // Records publishes and mapping changes; may be nil
+}
+
+// NewTemplateAdminService creates a new TemplateAdminService that validates templates with linter.
+// auditLog may be nil, in which case no audit entries are written.
+func NewTemplateAdminService(db *gorm.DB, linter *lint.Linter, auditLog audit.Recorder) *TemplateAdminService {
+	return &TemplateAdminService{db: db, linter: linter, auditLog: auditLog}
+}
+
+// ListNodeTemplates returns every node template, optionally only those with status.
+func (s *TemplateAdminService) ListNodeTemplates(ctx context.Context, status model.TemplateStatus) ([]model.WorkflowNodeTemplate, error) {
+	var templates []model.WorkflowNodeTemplate
+	if err := checkStatusFilter(status); err != nil {
+		return nil, err
+	}
+	query := s.db.WithContext(ctx).Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to list node templates: %w", err)
+	}
+	return templates, nil
+}
+
+// GetNodeTemplate retrieves a node template by its ID.
+func (s *TemplateAdminService) GetNodeTemplate(ctx context.Context, id string) (*model.WorkflowNodeTemplate, error) {
+	var template model.WorkflowNodeTemplate
+	if err := s.db.WithContext(ctx).First(&template, "id = ?", id).Error; err != nil {
+		return nil, notFound(err, "node template", id)
+	}
+	return &template, nil
+}
+
+// CreateNodeTemplate creates a draft node template.
+func (s *TemplateAdminService) CreateNodeTemplate(ctx context.Context, req model.NodeTemplateRequest) (*model.WorkflowNodeTemplate, error) {
+	if err := validateNodeTemplateRequest(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	template := &model.WorkflowNodeTemplate{BaseModel: newBaseModel(req.ID, now)}
+	applyNodeTemplateRequest(template, req)
+	template.TemplateLifecycle = model.TemplateLifecycle{Status: model.TemplateStatusDraft, BasedOnID: req.BasedOnID}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkBasedOn(tx, &model.WorkflowNodeTemplate{}, req.BasedOnID); err != nil {
+			return err
+		}
+		return createTemplate(tx, template, "node template", template.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// UpdateNodeTemplate replaces the content of a draft node template.
+func (s *TemplateAdminService) UpdateNodeTemplate(ctx context.Context, id string, req model.NodeTemplateRequest) (*model.WorkflowNodeTemplate, error) {
+	if err := validateNodeTemplateRequest(req); err != nil {
+		return nil, err
+	}
+
+	var template model.WorkflowNodeTemplate
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockDraft(tx, &template, "node template", id); err != nil {
+			return err
+		}
+		if err := checkBasedOn(tx, &model.WorkflowNodeTemplate{}, req.BasedOnID); err != nil {
+			return err
+		}
+		applyNodeTemplateRequest(&template, req)
+		template.BasedOnID = req.BasedOnID
+		if err := tx.Save(&template).Error; err != nil {
+			return fmt.Errorf("failed to update node template %s: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// DeleteNodeTemplate deletes a draft node template.
+func (s *TemplateAdminService) DeleteNodeTemplate(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var template model.WorkflowNodeTemplate
+		if err := lockDraft(tx, &template, "node template", id); err != nil {
+			return err
+		}
+		if err := tx.Delete(&template).Error; err != nil {
+			return fmt.Errorf("failed to delete node template %s: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// PublishNodeTemplate validates a draft node template and publishes it. Its plugin
+// configuration must build and the templates it depends on must be published.
+func (s *TemplateAdminService) PublishNodeTemplate(ctx context.Context, id, publishedBy string) (*model.WorkflowNodeTemplate, error) {
+	var template model.WorkflowNodeTemplate
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockDraft(tx, &template, "node template", id); err != nil {
+			return err
+		}
+
+		dependencies, err := publishedNodeTemplates(tx, template.DependsOn)
+		if err != nil {
+			return err
+		}
+		bundle := lint.Bundle{NodeTemplates: append(dependencies, template)}
+		if err := s.validate(ctx, bundle, fmt.Sprintf("workflow_node_templates[%s]", id)); err != nil {
+			return err
+		}
+
+		markPublished(&template.TemplateLifecycle, publishedBy)
+		if err := tx.Save(&template).Error; err != nil {
+			return fmt.Errorf("failed to publish node template %s: %w", id, err)
+		}
+		return audit.RecordTx(ctx, tx, s.auditLog, audit.Entry{
+			Action:       audit.ActionTemplatePublish,
+			ResourceType: audit.ResourceNodeTemplate,
+			ResourceID:   template.ID,
+			StateBefore:  string(model.TemplateStatusDraft),
+			StateAfter:   string(model.TemplateStatusPublished),
+			PayloadHash:  audit.HashPayload(template.Config),
+			Details:      map[string]any{"type": template.Type, "basedOnId": template.BasedOnID},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// ListWorkflowTemplates returns every workflow template, optionally only those with status.
+func (s *TemplateAdminService) ListWorkflowTemplates(ctx context.Context, status model.TemplateStatus) ([]model.WorkflowTemplateV2, error) {
+	var templates []model.WorkflowTemplateV2
+	if err := checkStatusFilter(status); err != nil {
+		return nil, err
+	}
+	query := s.db.WithContext(ctx).Order("id")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to list workflow templates: %w", err)
+	}
+	return templates, nil
+}
+
+// GetWorkflowTemplate retrieves a workflow template by its ID.
+func (s *TemplateAdminService) GetWorkflowTemplate(ctx context.Context, id string) (*model.WorkflowTemplateV2, error) {
+	var template model.WorkflowTemplateV2
+	if err := s.db.WithContext(ctx).First(&template, "id = ?", id).Error; err != nil {
+		return nil, notFound(err, "workflow template", id)
+	}
+	return &template, nil
+}
+
+// CreateWorkflowTemplate creates a draft workflow template.
+func (s *TemplateAdminService) CreateWorkflowTemplate(ctx context.Context, req model.WorkflowTemplateRequest) (*model.WorkflowTemplateV2, error) {
+	now := time.Now().UTC()
+	template := &model.WorkflowTemplateV2{BaseModel: newBaseModel(req.ID, now)}
+	if err := applyWorkflowTemplateRequest(template, req); err != nil {
+		return nil, err
+	}
+	template.TemplateLifecycle = model.TemplateLifecycle{Status: model.TemplateStatusDraft, BasedOnID: req.BasedOnID}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkBasedOn(tx, &model.WorkflowTemplateV2{}, req.BasedOnID); err != nil {
+			return err
+		}
+		return createTemplate(tx, template, "workflow template", template.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// UpdateWorkflowTemplate replaces the content of a draft workflow template.
+func (s *TemplateAdminService) UpdateWorkflowTemplate(ctx context.Context, id string, req model.WorkflowTemplateRequest) (*model.WorkflowTemplateV2, error) {
+	var template model.WorkflowTemplateV2
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockDraft(tx, &template, "workflow template", id); err != nil {
+			return err
+		}
+		if err := checkBasedOn(tx, &model.WorkflowTemplateV2{}, req.BasedOnID); err != nil {
+			return err
+		}
+		if err := applyWorkflowTemplateRequest(&template, req); err != nil {
+			return err
+		}
+		template.BasedOnID = req.BasedOnID
+		if err := tx.Save(&template).Error; err != nil {
+			return fmt.Errorf("failed to update workflow template %s: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// DeleteWorkflowTemplate deletes a draft workflow template.
+func (s *TemplateAdminService) DeleteWorkflowTemplate(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var template model.WorkflowTemplateV2
+		if err := lockDraft(tx, &template, "workflow template", id); err != nil {
+			return err
+		}
+		if err := tx.Delete(&template).Error; err != nil {
+			return fmt.Errorf("failed to delete workflow template %s: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// PublishWorkflowTemplate validates a draft workflow template and publishes it. Every task
+// node must use a published node template. If the draft is based on a published template,
+// the HS code mappings and pre-consignment templates using that template are moved to this
+// one, so workflows started afterwards use it.
+func (s *TemplateAdminService) PublishWorkflowTemplate(ctx context.Context, id, publishedBy string) (*model.WorkflowTemplateV2, error) {
+	var template model.WorkflowTemplateV2
+	var remapped, repointed int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockDraft(tx, &template, "workflow template", id); err != nil {
+			return err
+		}
+
+		definition, err := json.Marshal(template.WorkflowDefinition)
+		if err != nil {
+			return fmt.Errorf("failed to encode workflow definition: %w", err)
+		}
+		nodeTemplates, err := publishedNodeTemplates(tx, lint.TaskTemplateIDs(definition))
+		if err != nil {
+			return err
+		}
+		bundle := lint.Bundle{
+			NodeTemplates:       nodeTemplates,
+			WorkflowTemplatesV2: []lint.TemplateV2{{ID: template.ID, Name: template.Name, Version: template.Version, Definition: definition}},
+		}
+		if err := s.validate(ctx, bundle, fmt.Sprintf("workflow_template_v2[%s]", id)); err != nil {
+			return err
+		}
+
+		markPublished(&template.TemplateLifecycle, publishedBy)
+		if err := tx.Save(&template).Error; err != nil {
+			return fmt.Errorf("failed to publish workflow template %s: %w", id, err)
+		}
+
+		if template.BasedOnID != nil {
+			if remapped, repointed, err = replaceRevisedWorkflowTemplate(tx, &template); err != nil {
+				return err
+			}
+		}
+		return audit.RecordTx(ctx, tx, s.auditLog, audit.Entry{
+			Action:       audit.ActionTemplatePublish,
+			ResourceType: audit.ResourceWorkflowTemplate,
+			ResourceID:   template.ID,
+			StateBefore:  string(model.TemplateStatusDraft),
+			StateAfter:   string(model.TemplateStatusPublished),
+			PayloadHash:  audit.HashPayload(template.WorkflowDefinition),
+			Details: map[string]any{
+				"version":                      template.Version,
+				"basedOnId":                    template.BasedOnID,
+				"mappingsMoved":                remapped,
+				"preConsignmentTemplatesMoved": repointed,
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "workflow template published",
+		"templateId", template.ID, "version", template.Version, "basedOnId", template.BasedOnID,
+		"mappingsMoved", remapped, "preConsignmentTemplatesMoved", repointed)
+	return &template, nil
+}
+
+// replaceRevisedWorkflowTemplate moves the HS code mappings and pre-consignment templates of
+// the workflow template that template revises over to template, and returns how many of
+// each were moved.
+func replaceRevisedWorkflowTemplate(tx *gorm.DB, template *model.WorkflowTemplateV2) (int64, int64, error) {
+	if err := checkBasedOn(tx, &model.WorkflowTemplateV2{}, template.BasedOnID); err != nil {
+		return 0, 0, err
+	}
+	// Workflows pinned to the revised template keep running side by side with those started
+	// from this one, so the two must be told apart by version.
+	var sameVersion int64
+	if err := tx.Model(&model.WorkflowTemplateV2{}).Where("id = ? AND version = ?", *template.BasedOnID, template.Version).Count(&sameVersion).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to check version of workflow template %s: %w", *template.BasedOnID, err)
+	}
+	if sameVersion > 0 {
+		return 0, 0, &TemplateValidationError{Issues: []lint.Issue{{
+			Path:    fmt.Sprintf("workflow_template_v2[%s].version", template.ID),
+			Message: fmt.Sprintf("version %q is already used by workflow template %s, which this one revises", template.Version, *template.BasedOnID),
+		}}}
+	}
+	mappings := tx.Model(&model.WorkflowTemplateMapV2{}).
+		Where("workflow_template_id = ?", *template.BasedOnID).
+		Updates(map[string]any{"workflow_template_id": template.ID, "updated_at": *template.PublishedAt})
+	if mappings.Error != nil {
+		return 0, 0, fmt.Errorf("failed to move mappings to workflow template %s: %w", template.ID, mappings.Error)
+	}
+	preConsignmentTemplates := tx.Model(&model.PreConsignmentTemplate{}).
+		Where("workflow_template_v2_id = ?", *template.BasedOnID).
+		Updates(map[string]any{"workflow_template_v2_id": template.ID, "updated_at": *template.PublishedAt})
+	if preConsignmentTemplates.Error != nil {
+		return 0, 0, fmt.Errorf("failed to move pre-consignment templates to workflow template %s: %w", template.ID, preConsignmentTemplates.Error)
+	}
+	return mappings.RowsAffected, preConsignmentTemplates.RowsAffected, nil
+}
+
+// ListWorkflowTemplateMaps returns every HS code mapping with its HS code.
+func (s *TemplateAdminService) ListWorkflowTemplateMaps(ctx context.Context) ([]model.WorkflowTemplateMapV2, error) {
+	var maps []model.WorkflowTemplateMapV2
+	if err := s.db.WithContext(ctx).Preload("HSCode").Order("hs_code_id, consignment_flow").Find(&maps).Error; err != nil {
+		return nil, fmt.Errorf("failed to list workflow template mappings: %w", err)
+	}
+	return maps, nil
+}
+
+// CreateWorkflowTemplateMap maps an HS code and flow to a published workflow template.
+func (s *TemplateAdminService) CreateWorkflowTemplateMap(ctx context.Context, req model.WorkflowTemplateMapRequest) (*model.WorkflowTemplateMapV2, error) {
+	mapping := &model.WorkflowTemplateMapV2{}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := validateMapRequest(tx, "", req); err != nil {
+			return err
+		}
+		mapping.HSCodeID, mapping.ConsignmentFlow, mapping.WorkflowTemplateID = req.HSCodeID, req.ConsignmentFlow, req.WorkflowTemplateID
+		if err := tx.Omit(clause.Associations).Create(mapping).Error; err != nil {
+			return fmt.Errorf("failed to create workflow template mapping: %w", err)
+		}
+		return s.recordMapChange(ctx, tx, mapping, "create", "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// UpdateWorkflowTemplateMap changes an HS code mapping. Consignments whose workflow has
+// started keep the template they started with.
+func (s *TemplateAdminService) UpdateWorkflowTemplateMap(ctx context.Context, id string, req model.WorkflowTemplateMapRequest) (*model.WorkflowTemplateMapV2, error) {
+	var mapping model.WorkflowTemplateMapV2
+	var previous string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&mapping, "id = ?", id).Error; err != nil {
+			return notFound(err, "workflow template mapping", id)
+		}
+		if err := validateMapRequest(tx, id, req); err != nil {
+			return err
+		}
+		previous = mapping.WorkflowTemplateID
+		mapping.HSCodeID, mapping.ConsignmentFlow, mapping.WorkflowTemplateID = req.HSCodeID, req.ConsignmentFlow, req.WorkflowTemplateID
+		if err := tx.Omit(clause.Associations).Save(&mapping).Error; err != nil {
+			return fmt.Errorf("failed to update workflow template mapping %s: %w", id, err)
+		}
+		return s.recordMapChange(ctx, tx, &mapping, "update", previous)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+// DeleteWorkflowTemplateMap deletes an HS code mapping.
+func (s *TemplateAdminService) DeleteWorkflowTemplateMap(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var mapping model.WorkflowTemplateMapV2
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&mapping, "id = ?", id).Error; err != nil {
+			return notFound(err, "workflow template mapping", id)
+		}
+		if err := tx.Delete(&mapping).Error; err != nil {
+			return fmt.Errorf("failed to delete workflow template mapping %s: %w", id, err)
+		}
+		return s.recordMapChange(ctx, tx, &mapping, "delete", mapping.WorkflowTemplateID)
+	})
+}
+
+func (s *TemplateAdminService) recordMapChange(ctx context.Context, tx *gorm.DB, mapping *model.WorkflowTemplateMapV2, operation, previousTemplateID string) error {
+	entry := audit.Entry{
+		Action:       audit.ActionTemplateMapChange,
+		ResourceType: audit.ResourceTemplateMap,
+		ResourceID:   mapping.ID,
+		Operation:    operation,
+		StateBefore:  previousTemplateID,
+		Details: map[string]any{
+			"hsCodeId":        mapping.HSCodeID,
+			"consignmentFlow": mapping.ConsignmentFlow,
+		},
+	}
+	if operation != "delete" {
+		entry.StateAfter = mapping.WorkflowTemplateID
+	}
+	return audit.RecordTx(ctx, tx, s.auditLog, entry)
+}
+
+// validate lints bundle and fails with the issues found under path, the path of the template
+// being published. Issues in the published templates it refers to were checked when they
+// were published and are not repeated.
+func (s *TemplateAdminService) validate(ctx context.Context, bundle lint.Bundle, path string) error {
+	var issues []lint.Issue
+	for _, issue := range s.linter.Lint(ctx, bundle) {
+		if issue.Path == path || strings.HasPrefix(issue.Path, path+".") {
+			issues = append(issues, issue)
+		}
+	}
+	if len(issues) > 0 {
+		return &TemplateValidationError{Issues: issues}
+	}
+	return nil
+}
+
+func checkStatusFilter(status model.TemplateStatus) error {
+	switch status {
+	case "", model.TemplateStatusDraft, model.TemplateStatusPublished:
+		return nil
+	}
+	return fmt.Errorf("%w: status must be DRAFT or PUBLISHED", ErrInvalidTemplate)
+}
+
+func validateNodeTemplateRequest(req model.NodeTemplateRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTemplate)
+	}
+	if req.Type == "" {
+		return fmt.Errorf("%w: type is required", ErrInvalidTemplate)
+	}
+	return nil
+}
+
+func applyNodeTemplateRequest(template *model.WorkflowNodeTemplate, req model.NodeTemplateRequest) {
+	template.Name = strings.TrimSpace(req.Name)
+	template.Description = req.Description
+	template.Type = req.Type
+	template.Config = req.Config
+	if len(template.Config) == 0 {
+		template.Config = json.RawMessage(`{}`)
+	}
+	template.DependsOn = req.DependsOn
+	if template.DependsOn == nil {
+		template.DependsOn = model.StringArray{}
+	}
+	template.UnlockConfiguration = req.UnlockConfiguration
+	template.SLA = req.SLA
+}
+
+// applyWorkflowTemplateRequest copies req into template. The id and name inside the
+// definition are set to the template's, so a definition copied from another template
+// does not keep that template's identity.
+func applyWorkflowTemplateRequest(template *model.WorkflowTemplateV2, req model.WorkflowTemplateRequest) error {
+	name, version := strings.TrimSpace(req.Name), strings.TrimSpace(req.Version)
+	if name == "" || version == "" {
+		return fmt.Errorf("%w: name and version are required", ErrInvalidTemplate)
+	}
+	if len(req.WorkflowDefinition) == 0 {
+		return fmt.Errorf("%w: workflow_definition is required", ErrInvalidTemplate)
+	}
+
+	var definition map[string]any
+	if err := json.Unmarshal(req.WorkflowDefinition, &definition); err != nil {
+		return fmt.Errorf("%w: workflow_definition must be a JSON object: %v", ErrInvalidTemplate, err)
+	}
+	definition["id"], definition["name"] = template.ID, name
+	normalized, err := json.Marshal(definition)
+	if err != nil {
+		return fmt.Errorf("failed to encode workflow definition: %w", err)
+	}
+	if err := json.Unmarshal(normalized, &template.WorkflowDefinition); err != nil {
+		return fmt.Errorf("%w: invalid workflow_definition: %v", ErrInvalidTemplate, err)
+	}
+	template.Name, template.Version = name, version
+	return nil
+}
+
+func validateMapRequest(tx *gorm.DB, id string, req model.WorkflowTemplateMapRequest) error {
+	if req.HSCodeID == "" || req.WorkflowTemplateID == "" {
+		return fmt.Errorf("%w: hsCodeId and workflowTemplateId are required", ErrInvalidTemplate)
+	}
+	if req.ConsignmentFlow != model.ConsignmentFlowImport && req.ConsignmentFlow != model.ConsignmentFlowExport {
+		return fmt.Errorf("%w: consignmentFlow must be IMPORT or EXPORT", ErrInvalidTemplate)
+	}
+
+	var count int64
+	if err := tx.Model(&model.HSCode{}).Where("id = ?", req.HSCodeID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check HS code: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: HS code %s does not exist", ErrInvalidTemplate, req.HSCodeID)
+	}
+
+	var template model.WorkflowTemplateV2
+	if err := tx.Select("id", "status").First(&template, "id = ?", req.WorkflowTemplateID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: workflow template %s does not exist", ErrInvalidTemplate, req.WorkflowTemplateID)
+		}
+		return fmt.Errorf("failed to check workflow template: %w", err)
+	}
+	if template.Status != model.TemplateStatusPublished {
+		return fmt.Errorf("%w: workflow template %s is not published", ErrInvalidTemplate, req.WorkflowTemplateID)
+	}
+
+	query := tx.Model(&model.WorkflowTemplateMapV2{}).Where("hs_code_id = ? AND consignment_flow = ?", req.HSCodeID, req.ConsignmentFlow)
+	if id != "" {
+		query = query.Where("id <> ?", id)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check workflow template mappings: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: HS code %s already has a %s mapping", ErrTemplateExists, req.HSCodeID, req.ConsignmentFlow)
+	}
+	return nil
+}
+
+func newBaseModel(id string, now time.Time) model.BaseModel {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		id = uuid.NewString()
+	}
+	return model.BaseModel{ID: id, CreatedAt: now, UpdatedAt: now}
+}
+
+// createTemplate inserts a template whose ID was chosen by the caller. BaseModel's create
+// hook would replace it with a random one, so hooks are skipped.
+func createTemplate(tx *gorm.DB, template any, kind, id string) error {
+	var count int64
+	if err := tx.Model(template).Where("id = ?", id).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check %s %s: %w", kind, id, err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s %s", ErrTemplateExists, kind, id)
+	}
+	if err := tx.Session(&gorm.Session{SkipHooks: true}).Create(template).Error; err != nil {
+		return fmt.Errorf("failed to create %s: %w", kind, err)
+	}
+	return nil
+}
+
+// lockDraft loads the template with id into dest, locking it for update, and fails unless
+// it is a draft.
+func lockDraft(tx *gorm.DB, dest interface{ IsDraft() bool }, kind, id string) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(dest, "id = ?", id).Error; err != nil {
+		return notFound(err, kind, id)
+	}
+	if !dest.IsDraft() {
+		return fmt.Errorf("%s %s: %w", kind, id, ErrTemplatePublished)
+	}
+	return nil
+}
+
+// checkBasedOn checks that basedOnID, if set, names a published template in the table of kind.
+func checkBasedOn(tx *gorm.DB, kind any, basedOnID *string) error {
+	if basedOnID == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(kind).Where("id = ? AND status = ?", *basedOnID, model.TemplateStatusPublished).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check template %s: %w", *basedOnID, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: basedOnId %s is not a published template", ErrInvalidTemplate, *basedOnID)
+	}
+	return nil
+}
+
+// publishedNodeTemplates loads the published node templates among ids. Drafts and unknown
+// IDs are left out, so the linter reports references to them.
+func publishedNodeTemplates(tx *gorm.DB, ids []string) ([]model.WorkflowNodeTemplate, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var templates []model.WorkflowNodeTemplate
+	if err := tx.Where("id IN ? AND status = ?", ids, model.TemplateStatusPublished).Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to load node templates: %w", err)
+	}
+	return templates, nil
+}
+
+func markPublished(lifecycle *model.TemplateLifecycle, publishedBy string) {
+	now := time.Now().UTC()
+	lifecycle.Status = model.TemplateStatusPublished
+	lifecycle.PublishedAt = &now
+	if publishedBy != "" {
+		lifecycle.PublishedBy = &publishedBy
+	}
+}
+
+func notFound(err error, kind, id string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s %s: %w", kind, id, ErrTemplateNotFound)
+	}
+	return fmt.Errorf("failed to retrieve %s %s: %w", kind, id, err)
+}
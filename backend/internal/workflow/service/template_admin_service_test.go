@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/OpenNSW/nsw/internal/workflow/lint"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+var workflowTemplateV2Columns = []string{"id", "name", "version", "workflow_definition", "created_at", "updated_at", "status", "based_on_id", "published_at", "published_by"}
+
+const revisedDefinition = `{
+	"id": "wf-2",
+	"name": "Export",
+	"version": 2,
+	"nodes": [
+		{"id": "start", "type": "START"},
+		{"id": "form", "type": "TASK", "task_template_id": "nt-form"},
+		{"id": "end", "type": "END"}
+	],
+	"edges": [
+		{"id": "e1", "source_id": "start", "target_id": "form"},
+		{"id": "e2", "source_id": "form", "target_id": "end"}
+	]
+}`
+
+func TestTemplateAdminService_PublishWorkflowTemplate(t *testing.T) {
+	t.Run("Moves Mappings From The Revised Template", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		s := NewTemplateAdminService(db, lint.NewLinter(nil), nil)
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "workflow_template_v2" WHERE id = \$1 .* FOR UPDATE`).
+			WithArgs("wf-2", 1).
+			WillReturnRows(sqlmock.NewRows(workflowTemplateV2Columns).
+				AddRow("wf-2", "Export", "2", revisedDefinition, now, now, "DRAFT", "wf-1", nil, nil))
+		mock.ExpectQuery(`SELECT \* FROM "workflow_node_templates" WHERE id IN \(\$1\) AND status = \$2`).
+			WithArgs("nt-form", model.TemplateStatusPublished).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "config", "depends_on", "status"}).
+				AddRow("nt-form", "Form", "SIMPLE_FORM", `{}`, `[]`, "PUBLISHED"))
+		mock.ExpectExec(`UPDATE "workflow_template_v2" SET .*"status"=\$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "workflow_template_v2" WHERE id = \$1 AND status = \$2`).
+			WithArgs("wf-1", model.TemplateStatusPublished).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`SELECT count\(\*\) FROM "workflow_template_v2" WHERE id = \$1 AND version = \$2`).
+			WithArgs("wf-1", "2").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec(`UPDATE "workflow_template_maps_v2" SET .* WHERE workflow_template_id = \$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`UPDATE "pre_consignment_templates" SET .* WHERE workflow_template_v2_id = \$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		template, err := s.PublishWorkflowTemplate(context.Background(), "wf-2", "admin-1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if template.Status != model.TemplateStatusPublished || template.PublishedAt == nil || *template.PublishedBy != "admin-1" {
+			t.Fatalf("lifecycle = %+v, want published by admin-1", template.TemplateLifecycle)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Rejects Reference To Unpublished Node Template", func(t *testing.T) {
+		db, mock := setupTestDB(t)
+		s := NewTemplateAdminService(db, lint.NewLinter(nil), nil)
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "workflow_template_v2"`).
+			WillReturnRows(sqlmock.NewRows(workflowTemplateV2Columns).
+				AddRow("wf-2", "Export", "2", revisedDefinition, now, now, "DRAFT", nil, nil, nil))
+		mock.ExpectQuery(`SELECT \* FROM "workflow_node_templates"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		_, err := s.PublishWorkflowTemplate(context.Background(), "wf-2", "admin-1")
+		var invalid *TemplateValidationError
+		if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidTemplate) {
+			t.Fatalf("error = %v, want a TemplateValidationError", err)
+		}
+		if len(invalid.Issues) != 1 || invalid.Issues[0].Path != "workflow_template_v2[wf-2].nodes[1].task_template_id" {
+			t.Fatalf("issues = %v", invalid.Issues)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestTemplateAdminService_UpdateNodeTemplateRejectsPublished(t *testing.T) {
+	db, mock := setupTestDB(t)
+	s := NewTemplateAdminService(db, lint.NewLinter(nil), nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "workflow_node_templates" WHERE id = \$1 .* FOR UPDATE`).
+		WithArgs("nt-form", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "status"}).AddRow("nt-form", "Form", "SIMPLE_FORM", "PUBLISHED"))
+	mock.ExpectRollback()
+
+	_, err := s.UpdateNodeTemplate(context.Background(), "nt-form", model.NodeTemplateRequest{Name: "Form", Type: "SIMPLE_FORM"})
+	if !errors.Is(err, ErrTemplatePublished) {
+		t.Fatalf("error = %v, want ErrTemplatePublished", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemplateAdminService_CreateWorkflowTemplate(t *testing.T) {
+	db, mock := setupTestDB(t)
+	s := NewTemplateAdminService(db, lint.NewLinter(nil), nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "workflow_template_v2" WHERE id = \$1`).
+		WithArgs("wf-3").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`INSERT INTO "workflow_template_v2"`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	template, err := s.CreateWorkflowTemplate(context.Background(), model.WorkflowTemplateRequest{
+		ID:                 "wf-3",
+		Name:               "Export v3",
+		Version:            "3",
+		WorkflowDefinition: json.RawMessage(revisedDefinition),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if template.Status != model.TemplateStatusDraft {
+		t.Fatalf("status = %s, want DRAFT", template.Status)
+	}
+	definition, err := json.Marshal(template.WorkflowDefinition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var identity struct{ ID, Name string }
+	if err := json.Unmarshal(definition, &identity); err != nil {
+		t.Fatal(err)
+	}
+	if identity.ID != "wf-3" || identity.Name != "Export v3" {
+		t.Fatalf("definition identity = %+v, want the template's", identity)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemplateAdminService_CreateWorkflowTemplateMapRequiresPublishedTemplate(t *testing.T) {
+	db, mock := setupTestDB(t)
+	s := NewTemplateAdminService(db, lint.NewLinter(nil), nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "hs_codes" WHERE id = \$1`).
+		WithArgs("hs-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT "id","status" FROM "workflow_template_v2" WHERE id = \$1`).
+		WithArgs("wf-3", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow("wf-3", "DRAFT"))
+	mock.ExpectRollback()
+
+	_, err := s.CreateWorkflowTemplateMap(context.Background(), model.WorkflowTemplateMapRequest{
+		HSCodeID:           "hs-1",
+		ConsignmentFlow:    model.ConsignmentFlowExport,
+		WorkflowTemplateID: "wf-3",
+	})
+	if !errors.Is(err, ErrInvalidTemplate) {
+		t.Fatalf("error = %v, want ErrInvalidTemplate", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
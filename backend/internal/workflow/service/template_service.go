@@ -47,9 +47,10 @@ func (s *TemplateService) GetWorkflowTemplateByHSCodeIDAndFlowV2(
 		Model(&model.WorkflowTemplateV2{}).
 		Joins("JOIN workflow_template_maps_v2 ON workflow_template_v2.id = workflow_template_maps_v2.workflow_template_id").
 		Where(
-			"workflow_template_maps_v2.hs_code_id = ? AND workflow_template_maps_v2.consignment_flow = ?",
+			"workflow_template_maps_v2.hs_code_id = ? AND workflow_template_maps_v2.consignment_flow = ? AND workflow_template_v2.status = ?",
 			hsCodeID,
 			flow,
+			model.TemplateStatusPublished,
 		).
 		Order("workflow_template_v2.version DESC"). // optional but future-proof
 		First(&workflowTemplate)
@@ -0,0 +1,554 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	workflowmanager "github.com/OpenNSW/go-temporal-workflow"
+
+	"github.com/OpenNSW/nsw/internal/audit"
+	"github.com/OpenNSW/nsw/internal/workflow/lint"
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+// ErrInvalidMigration is returned when a workflow migration request cannot be carried out.
+var ErrInvalidMigration = errors.New("invalid workflow migration")
+
+// WorkflowInstanceService keeps track of the workflow template each running workflow is pinned
+// to, and migrates running workflows from one published template to another.
+//
+// The workflow engine is handed the template's definition when a workflow starts and keeps
+// interpreting that graph, so a published template can be revised without affecting the
+// workflows already running from it. A migration cannot change that graph. It moves workflows
+// to a template with the same nodes and edges, where nodes may be renamed and task nodes may use
+// other node templates, and records the node template each migrated node starts from then on.
+type WorkflowInstanceService struct {
+	db       *gorm.DB
+	wm       workflowmanager.Manager
+	auditLog audit.Recorder // Records migrated workflows; may be nil
+}
+
+// NewWorkflowInstanceService creates a new WorkflowInstanceService.
+// auditLog may be nil, in which case no audit entries are written.
+func NewWorkflowInstanceService(db *gorm.DB, auditLog audit.Recorder) *WorkflowInstanceService {
+	return &WorkflowInstanceService{db: db, auditLog: auditLog}
+}
+
+// RegisterWorkflowManager registers the workflow manager the status of running workflows is read from.
+func (s *WorkflowInstanceService) RegisterWorkflowManager(wm workflowmanager.Manager) error {
+	if s.wm != nil {
+		return fmt.Errorf("workflow manager already registered for WorkflowInstanceService")
+	}
+	if wm == nil {
+		return fmt.Errorf("workflow manager cannot be nil")
+	}
+	s.wm = wm
+	return nil
+}
+
+// newWorkflowInstance returns the record of a workflow with the given ID started from template.
+func newWorkflowInstance(id string, template *model.WorkflowTemplateV2, globalContext map[string]any) *model.Workflow {
+	if globalContext == nil {
+		globalContext = make(map[string]any)
+	}
+	workflow := &model.Workflow{
+		Status:                  model.WorkflowStatusInProgress,
+		GlobalContext:           globalContext,
+		WorkflowTemplateID:      &template.ID,
+		WorkflowTemplateVersion: &template.Version,
+	}
+	workflow.ID = id
+	return workflow
+}
+
+// ListWorkflowInstances returns the workflows pinned to the workflow template templateID,
+// optionally only those with status, newest first.
+func (s *WorkflowInstanceService) ListWorkflowInstances(ctx context.Context, templateID string, status model.WorkflowStatus) ([]model.WorkflowInstanceSummary, error) {
+	switch status {
+	case "", model.WorkflowStatusInProgress, model.WorkflowStatusCompleted, model.WorkflowStatusFailed:
+	default:
+		return nil, fmt.Errorf("%w: unknown workflow status %q", ErrInvalidTemplate, status)
+	}
+	var template model.WorkflowTemplateV2
+	if err := s.db.WithContext(ctx).Select("id").First(&template, "id = ?", templateID).Error; err != nil {
+		return nil, notFound(err, "workflow template", templateID)
+	}
+
+	query := s.db.WithContext(ctx).Where("workflow_template_id = ?", templateID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var workflows []model.Workflow
+	if err := query.Order("created_at DESC").Find(&workflows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list workflows of template %s: %w", templateID, err)
+	}
+	summaries := make([]model.WorkflowInstanceSummary, 0, len(workflows))
+	for _, workflow := range workflows {
+		summary := model.WorkflowInstanceSummary{
+			ID:                 workflow.ID,
+			Status:             workflow.Status,
+			WorkflowTemplateID: templateID,
+			MigratedAt:         workflow.MigratedAt,
+			CreatedAt:          workflow.CreatedAt,
+		}
+		if workflow.WorkflowTemplateVersion != nil {
+			summary.WorkflowTemplateVersion = *workflow.WorkflowTemplateVersion
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// TaskTemplateID returns the node template the node nodeID of the workflow workflowID starts.
+// taskTemplateID is the node template named by the definition the workflow engine runs, and is
+// returned unless a migration replaced it.
+func (s *WorkflowInstanceService) TaskTemplateID(ctx context.Context, workflowID, nodeID, taskTemplateID string) (string, error) {
+	overrides, err := taskTemplateOverrides(ctx, s.db, workflowID)
+	if err != nil {
+		return "", err
+	}
+	if override, ok := overrides[nodeID]; ok {
+		return override, nil
+	}
+	return taskTemplateID, nil
+}
+
+// CompleteWorkflow marks the workflow workflowID COMPLETED. Workflows without a record, or
+// already completed, are left alone.
+func (s *WorkflowInstanceService) CompleteWorkflow(ctx context.Context, workflowID string) error {
+	err := s.db.WithContext(ctx).Model(&model.Workflow{}).
+		Where("id = ? AND status = ?", workflowID, model.WorkflowStatusInProgress).
+		Updates(map[string]any{"status": model.WorkflowStatusCompleted, "updated_at": time.Now().UTC()}).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark workflow %s as completed: %w", workflowID, err)
+	}
+	return nil
+}
+
+// MigrateWorkflows moves running workflows from one published workflow template to another and
+// returns what changed for each of them. With req.DryRun nothing is changed.
+//
+// The templates must have the same graph once the old node IDs are mapped by req.NodeMapping:
+// every node has a counterpart of the same type and configuration, and the edges connect the
+// same counterparts. Only the node templates of task nodes may differ. Nodes that have not
+// started yet start the new node template; tasks that have already started keep theirs.
+func (s *WorkflowInstanceService) MigrateWorkflows(ctx context.Context, req model.WorkflowMigrationRequest, migratedBy string) (*model.WorkflowMigrationResult, error) {
+	if req.FromTemplateID == "" || req.ToTemplateID == "" {
+		return nil, fmt.Errorf("%w: fromTemplateId and toTemplateId are required", ErrInvalidMigration)
+	}
+	if req.FromTemplateID == req.ToTemplateID {
+		return nil, fmt.Errorf("%w: fromTemplateId and toTemplateId are the same", ErrInvalidMigration)
+	}
+	if s.wm == nil {
+		return nil, fmt.Errorf("workflow manager not registered for WorkflowInstanceService")
+	}
+
+	result := &model.WorkflowMigrationResult{
+		FromTemplateID: req.FromTemplateID,
+		ToTemplateID:   req.ToTemplateID,
+		DryRun:         req.DryRun,
+		Workflows:      []model.WorkflowMigrationDiff{},
+	}
+	var migrated []model.Workflow
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		from, err := publishedWorkflowTemplate(tx, req.FromTemplateID)
+		if err != nil {
+			return err
+		}
+		to, err := publishedWorkflowTemplate(tx, req.ToTemplateID)
+		if err != nil {
+			return err
+		}
+		result.FromVersion, result.ToVersion = from.Version, to.Version
+
+		plan, err := planMigration(from, to, req.NodeMapping)
+		if err != nil {
+			return err
+		}
+		result.Changes = plan.changes
+
+		workflows, err := lockRunningWorkflows(tx, req.FromTemplateID, req.WorkflowIDs)
+		if err != nil {
+			return err
+		}
+		// Statuses are read from the runtime before anything is written, so the audit chains of
+		// the migrated workflows are locked only while the rows are updated, in id order.
+		instances := make([]*workflowmanager.WorkflowInstance, len(workflows))
+		for i := range workflows {
+			if instances[i], err = s.wm.GetStatus(ctx, workflows[i].ID); err != nil {
+				return fmt.Errorf("failed to get status of workflow %s: %w", workflows[i].ID, err)
+			}
+		}
+
+		now := time.Now().UTC()
+		for i := range workflows {
+			workflow := &workflows[i]
+			diff, nodeMapping, overrides := plan.apply(workflow, instances[i])
+			result.Workflows = append(result.Workflows, diff)
+			if req.DryRun {
+				continue
+			}
+
+			err = tx.Model(workflow).Updates(map[string]any{
+				"workflow_template_id":      to.ID,
+				"workflow_template_version": to.Version,
+				"node_mapping":              encodeStringMap(nodeMapping),
+				"task_template_overrides":   encodeStringMap(overrides),
+				"migrated_at":               now,
+				"updated_at":                now,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("failed to migrate workflow %s: %w", workflow.ID, err)
+			}
+			err = audit.RecordTx(ctx, tx, s.auditLog, audit.Entry{
+				Action:        audit.ActionWorkflowMigrate,
+				ConsignmentID: workflow.ID,
+				ResourceType:  audit.ResourceWorkflow,
+				ResourceID:    workflow.ID,
+				StateBefore:   req.FromTemplateID,
+				StateAfter:    req.ToTemplateID,
+				PayloadHash:   audit.HashPayload(req),
+				Details: map[string]any{
+					"fromVersion": result.FromVersion,
+					"toVersion":   result.ToVersion,
+					"nodes":       diff.Nodes,
+				},
+			})
+			if err != nil {
+				return err
+			}
+			migrated = append(migrated, *workflow)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.DryRun {
+		slog.InfoContext(ctx, "workflows migrated",
+			"fromTemplateId", req.FromTemplateID, "toTemplateId", req.ToTemplateID,
+			"workflows", len(migrated), "migratedBy", migratedBy)
+	}
+	return result, nil
+}
+
+// publishedWorkflowTemplate loads the published workflow template id.
+func publishedWorkflowTemplate(tx *gorm.DB, id string) (*model.WorkflowTemplateV2, error) {
+	var template model.WorkflowTemplateV2
+	if err := tx.First(&template, "id = ?", id).Error; err != nil {
+		return nil, notFound(err, "workflow template", id)
+	}
+	if template.IsDraft() {
+		return nil, fmt.Errorf("%w: workflow template %s is a draft", ErrInvalidMigration, id)
+	}
+	return &template, nil
+}
+
+// lockRunningWorkflows loads and locks the running workflows pinned to templateID, limited to ids
+// if any are given. Every workflow in ids must be one of them.
+func lockRunningWorkflows(tx *gorm.DB, templateID string, ids []string) ([]model.Workflow, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("workflow_template_id = ? AND status = ?", templateID, model.WorkflowStatusInProgress)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	var workflows []model.Workflow
+	if err := query.Order("id").Find(&workflows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load workflows of template %s: %w", templateID, err)
+	}
+	if len(ids) > 0 && len(workflows) != len(ids) {
+		found := make(map[string]bool, len(workflows))
+		for _, workflow := range workflows {
+			found[workflow.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				return nil, fmt.Errorf("%w: workflow %s is not running on workflow template %s", ErrInvalidMigration, id, templateID)
+			}
+		}
+	}
+	return workflows, nil
+}
+
+// migrationPlan is how the nodes of one workflow template map onto another.
+type migrationPlan struct {
+	nodeMapping       map[string]string // Old node ID → new node ID, for every node
+	fromTaskTemplates map[string]string // Old node ID → node template, for task nodes
+	taskTemplates     map[string]string // New node ID → node template, for task nodes
+	changes           []model.WorkflowNodeChange
+}
+
+// graphNode is a node of a workflow definition, decoded generically so that every attribute
+// the workflow engine interprets is compared, not only those this package knows about.
+type graphNode struct {
+	id             string
+	taskTemplateID string
+	shape          string // Canonical JSON of the node without its ID and node template
+}
+
+// planMigration matches the nodes and edges of from to those of to. Old nodes are matched to
+// the new node named by mapping, or else to the new node with the same ID.
+func planMigration(from, to *model.WorkflowTemplateV2, mapping map[string]string) (*migrationPlan, error) {
+	fromNodes, fromEdges, err := decodeGraph(from)
+	if err != nil {
+		return nil, err
+	}
+	toNodes, toEdges, err := decodeGraph(to)
+	if err != nil {
+		return nil, err
+	}
+	fromPath := fmt.Sprintf("workflow_template_v2[%s]", from.ID)
+	toPath := fmt.Sprintf("workflow_template_v2[%s]", to.ID)
+
+	var issues []lint.Issue
+	addIssue := func(path, format string, args ...any) {
+		issues = append(issues, lint.Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	fromIndex := make(map[string]int, len(fromNodes))
+	for i, node := range fromNodes {
+		fromIndex[node.id] = i
+	}
+	for oldID := range mapping {
+		if _, ok := fromIndex[oldID]; !ok {
+			addIssue(fmt.Sprintf("nodeMapping[%s]", oldID), "node %q is not in workflow template %s", oldID, from.ID)
+		}
+	}
+	toIndex := make(map[string]int, len(toNodes))
+	for i, node := range toNodes {
+		toIndex[node.id] = i
+	}
+
+	plan := &migrationPlan{
+		nodeMapping:       make(map[string]string, len(fromNodes)),
+		fromTaskTemplates: make(map[string]string, len(fromNodes)),
+		taskTemplates:     make(map[string]string, len(toNodes)),
+		changes:           []model.WorkflowNodeChange{},
+	}
+	matchedBy := make(map[string]string, len(toNodes))
+	for i, oldNode := range fromNodes {
+		newID, mapped := mapping[oldNode.id]
+		if !mapped {
+			newID = oldNode.id
+		}
+		j, ok := toIndex[newID]
+		if !ok {
+			if mapped {
+				addIssue(fmt.Sprintf("nodeMapping[%s]", oldNode.id), "node %q is not in workflow template %s", newID, to.ID)
+			} else {
+				addIssue(fmt.Sprintf("%s.nodes[%d]", fromPath, i), "node %q is not in workflow template %s; map it with nodeMapping", oldNode.id, to.ID)
+			}
+			continue
+		}
+		if other, taken := matchedBy[newID]; taken {
+			addIssue(fmt.Sprintf("nodeMapping[%s]", oldNode.id), "nodes %q and %q both map to node %q", other, oldNode.id, newID)
+			continue
+		}
+		matchedBy[newID] = oldNode.id
+		newNode := toNodes[j]
+		if newNode.shape != oldNode.shape {
+			addIssue(fmt.Sprintf("%s.nodes[%d]", toPath, j), "node %q differs from node %q in more than its node template, which running workflows cannot change", newID, oldNode.id)
+			continue
+		}
+		plan.nodeMapping[oldNode.id] = newID
+		plan.fromTaskTemplates[oldNode.id] = oldNode.taskTemplateID
+		plan.taskTemplates[newID] = newNode.taskTemplateID
+		if newID != oldNode.id || newNode.taskTemplateID != oldNode.taskTemplateID {
+			plan.changes = append(plan.changes, model.WorkflowNodeChange{
+				NodeID:             oldNode.id,
+				NewNodeID:          newID,
+				FromTaskTemplateID: oldNode.taskTemplateID,
+				ToTaskTemplateID:   newNode.taskTemplateID,
+			})
+		}
+	}
+	for j, newNode := range toNodes {
+		if _, ok := matchedBy[newNode.id]; !ok {
+			addIssue(fmt.Sprintf("%s.nodes[%d]", toPath, j), "node %q has no counterpart in workflow template %s; running workflows cannot gain nodes", newNode.id, from.ID)
+		}
+	}
+
+	if len(issues) == 0 {
+		// Compare the edges as multisets, with the old endpoints mapped to the new nodes.
+		remaining := make(map[string]int, len(fromEdges))
+		for _, edge := range fromEdges {
+			edge["source_id"] = plan.nodeMapping[stringField(edge, "source_id")]
+			edge["target_id"] = plan.nodeMapping[stringField(edge, "target_id")]
+			remaining[canonicalJSON(edge)]++
+		}
+		for j, edge := range toEdges {
+			key := canonicalJSON(edge)
+			if remaining[key] == 0 {
+				addIssue(fmt.Sprintf("%s.edges[%d]", toPath, j), "edge from %q to %q has no counterpart in workflow template %s; running workflows cannot change their edges",
+					stringField(edge, "source_id"), stringField(edge, "target_id"), from.ID)
+				continue
+			}
+			remaining[key]--
+		}
+		for i, edge := range fromEdges {
+			if key := canonicalJSON(edge); remaining[key] > 0 {
+				remaining[key]--
+				addIssue(fmt.Sprintf("%s.edges[%d]", fromPath, i), "edge from %q to %q has no counterpart in workflow template %s; running workflows cannot change their edges",
+					stringField(edge, "source_id"), stringField(edge, "target_id"), to.ID)
+			}
+		}
+	}
+
+	if len(issues) > 0 {
+		sort.Slice(issues, func(i, j int) bool { return issues[i].Path < issues[j].Path })
+		return nil, &TemplateValidationError{Issues: issues}
+	}
+	return plan, nil
+}
+
+// apply works out the effect of the plan on a running workflow, given its status in the workflow
+// engine. It returns the workflow's diff together with its new node mapping and node template
+// overrides, both keyed by the node IDs the engine uses.
+func (p *migrationPlan) apply(workflow *model.Workflow, instance *workflowmanager.WorkflowInstance) (model.WorkflowMigrationDiff, map[string]string, map[string]string) {
+	// The engine still uses the node IDs of the template the workflow was started from; earlier
+	// migrations recorded which node of the pinned template each renamed one is.
+	engineIDs := make(map[string]string, len(workflow.NodeMapping))
+	for engineID, pinnedID := range workflow.NodeMapping {
+		engineIDs[pinnedID] = engineID
+	}
+	engineID := func(pinnedID string) string {
+		if id, ok := engineIDs[pinnedID]; ok {
+			return id
+		}
+		return pinnedID
+	}
+	engineNodes := make(map[string]workflowmanager.NodeInfo)
+	if instance != nil {
+		for _, node := range instance.NodeInfo {
+			engineNodes[node.ID] = node
+		}
+	}
+
+	diff := model.WorkflowMigrationDiff{WorkflowID: workflow.ID, Nodes: []model.WorkflowNodeMigration{}}
+	nodeMapping := make(map[string]string)
+	overrides := make(map[string]string)
+	for pinnedID, newID := range p.nodeMapping {
+		id := engineID(pinnedID)
+		if newID != id {
+			nodeMapping[id] = newID
+		}
+		// Without an override the engine starts the node template of its own definition. That is
+		// the one it reports, or for a node it does not report, the one of the pinned template.
+		current, overridden := workflow.TaskTemplateOverrides[id]
+		if node, ok := engineNodes[id]; ok {
+			current, overridden = node.TaskTemplateID, false
+		} else if !overridden {
+			current = p.fromTaskTemplates[pinnedID]
+		}
+		if taskTemplateID := p.taskTemplates[newID]; taskTemplateID != "" && (overridden || taskTemplateID != current) {
+			overrides[id] = taskTemplateID
+		}
+	}
+	for _, change := range p.changes {
+		status := engineNodes[engineID(change.NodeID)].Status
+		action := model.WorkflowNodeMigrationRenumber
+		if change.FromTaskTemplateID != change.ToTaskTemplateID {
+			action = model.WorkflowNodeMigrationKeep
+			if status == workflowmanager.NodeStatusNotStarted {
+				action = model.WorkflowNodeMigrationReplace
+			}
+		}
+		diff.Nodes = append(diff.Nodes, model.WorkflowNodeMigration{
+			WorkflowNodeChange: change,
+			Status:             string(status),
+			Action:             action,
+		})
+	}
+	return diff, nodeMapping, overrides
+}
+
+// decodeGraph returns the nodes and edges of a template's workflow definition.
+func decodeGraph(template *model.WorkflowTemplateV2) ([]graphNode, []map[string]any, error) {
+	raw, err := json.Marshal(template.WorkflowDefinition)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode workflow definition of %s: %w", template.ID, err)
+	}
+	var definition struct {
+		Nodes []map[string]any `json:"nodes"`
+		Edges []map[string]any `json:"edges"`
+	}
+	if err := json.Unmarshal(raw, &definition); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode workflow definition of %s: %w", template.ID, err)
+	}
+	nodes := make([]graphNode, 0, len(definition.Nodes))
+	for _, node := range definition.Nodes {
+		decoded := graphNode{id: stringField(node, "id"), taskTemplateID: stringField(node, "task_template_id")}
+		delete(node, "id")
+		delete(node, "task_template_id")
+		decoded.shape = canonicalJSON(node)
+		nodes = append(nodes, decoded)
+	}
+	for _, edge := range definition.Edges {
+		delete(edge, "id")
+	}
+	return nodes, definition.Edges, nil
+}
+
+func stringField(value map[string]any, key string) string {
+	s, _ := value[key].(string)
+	return s
+}
+
+// canonicalJSON encodes a decoded JSON value with its object keys sorted.
+func canonicalJSON(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(encoded)
+}
+
+// encodeStringMap encodes m for a jsonb column updated through a map of columns, which bypasses
+// the model's serializer.
+func encodeStringMap(m map[string]string) string {
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
+}
+
+// workflowStatus returns the status of the workflow workflowID from wm, with the node templates
+// of its task nodes replaced by those a migration assigned, so a migrated workflow is shown with
+// the node templates its tasks start.
+func workflowStatus(ctx context.Context, db *gorm.DB, wm workflowmanager.Manager, workflowID string) (*workflowmanager.WorkflowInstance, error) {
+	instance, err := wm.GetStatus(ctx, workflowID)
+	if err != nil || instance == nil {
+		return instance, err
+	}
+	overrides, err := taskTemplateOverrides(ctx, db, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range instance.NodeInfo {
+		if override, ok := overrides[instance.NodeInfo[i].ID]; ok {
+			instance.NodeInfo[i].TaskTemplateID = override
+		}
+	}
+	return instance, nil
+}
+
+// taskTemplateOverrides returns the node templates a migration assigned to the nodes of the
+// workflow workflowID, which is empty for workflows that were never migrated or have no record.
+func taskTemplateOverrides(ctx context.Context, db *gorm.DB, workflowID string) (map[string]string, error) {
+	var workflow model.Workflow
+	result := db.WithContext(ctx).Select("id", "task_template_overrides").Where("id = ?", workflowID).Limit(1).Find(&workflow)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retrieve workflow %s: %w", workflowID, result.Error)
+	}
+	return workflow.TaskTemplateOverrides, nil
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	workflowManagerV2 "github.com/OpenNSW/go-temporal-workflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/OpenNSW/nsw/internal/workflow/model"
+)
+
+const feeDefinition = `{
+	"id": "wf-1",
+	"nodes": [
+		{"id": "start", "type": "START"},
+		{"id": "fee", "type": "TASK", "task_template_id": "nt-fee-old"},
+		{"id": "form", "type": "TASK", "task_template_id": "nt-form"},
+		{"id": "end", "type": "END"}
+	],
+	"edges": [
+		{"id": "e1", "source_id": "start", "target_id": "fee"},
+		{"id": "e2", "source_id": "fee", "target_id": "form"},
+		{"id": "e3", "source_id": "form", "target_id": "end"}
+	]
+}`
+
+const correctedFeeDefinition = `{
+	"id": "wf-2",
+	"nodes": [
+		{"id": "start", "type": "START"},
+		{"id": "payment", "type": "TASK", "task_template_id": "nt-fee-new"},
+		{"id": "form", "type": "TASK", "task_template_id": "nt-form"},
+		{"id": "end", "type": "END"}
+	],
+	"edges": [
+		{"id": "e1", "source_id": "start", "target_id": "payment"},
+		{"id": "e2", "source_id": "payment", "target_id": "form"},
+		{"id": "e3", "source_id": "form", "target_id": "end"}
+	]
+}`
+
+func workflowTemplateV2(t *testing.T, id, definition string) *model.WorkflowTemplateV2 {
+	t.Helper()
+	template := &model.WorkflowTemplateV2{BaseModel: model.BaseModel{ID: id}}
+	require.NoError(t, json.Unmarshal([]byte(definition), &template.WorkflowDefinition))
+	return template
+}
+
+func TestPlanMigration(t *testing.T) {
+	from := workflowTemplateV2(t, "wf-1", feeDefinition)
+
+	t.Run("Renamed Node With New Template", func(t *testing.T) {
+		plan, err := planMigration(from, workflowTemplateV2(t, "wf-2", correctedFeeDefinition), map[string]string{"fee": "payment"})
+		require.NoError(t, err)
+		assert.Equal(t, []model.WorkflowNodeChange{{
+			NodeID:             "fee",
+			NewNodeID:          "payment",
+			FromTaskTemplateID: "nt-fee-old",
+			ToTaskTemplateID:   "nt-fee-new",
+		}}, plan.changes)
+	})
+
+	tests := []struct {
+		name       string
+		definition string
+		mapping    map[string]string
+		wantPaths  []string
+	}{
+		{
+			name:       "Unmapped Renamed Node",
+			definition: correctedFeeDefinition,
+			wantPaths:  []string{"workflow_template_v2[wf-1].nodes[1]", "workflow_template_v2[wf-2].nodes[1]"},
+		},
+		{
+			name:       "Mapping To Unknown Node",
+			definition: correctedFeeDefinition,
+			mapping:    map[string]string{"fee": "payment", "missing": "form"},
+			wantPaths:  []string{"nodeMapping[missing]"},
+		},
+		{
+			name: "Changed Edge",
+			definition: `{"nodes": [
+				{"id": "start", "type": "START"},
+				{"id": "fee", "type": "TASK", "task_template_id": "nt-fee-new"},
+				{"id": "form", "type": "TASK", "task_template_id": "nt-form"},
+				{"id": "end", "type": "END"}
+			], "edges": [
+				{"id": "e1", "source_id": "start", "target_id": "form"},
+				{"id": "e2", "source_id": "form", "target_id": "fee"},
+				{"id": "e3", "source_id": "fee", "target_id": "end"}
+			]}`,
+			wantPaths: []string{
+				"workflow_template_v2[wf-1].edges[0]", "workflow_template_v2[wf-1].edges[1]", "workflow_template_v2[wf-1].edges[2]",
+				"workflow_template_v2[wf-2].edges[0]", "workflow_template_v2[wf-2].edges[1]", "workflow_template_v2[wf-2].edges[2]",
+			},
+		},
+		{
+			name: "Changed Input Mapping",
+			definition: `{"nodes": [
+				{"id": "start", "type": "START"},
+				{"id": "fee", "type": "TASK", "task_template_id": "nt-fee-new", "input_mapping": {"amount": "fee"}},
+				{"id": "form", "type": "TASK", "task_template_id": "nt-form"},
+				{"id": "end", "type": "END"}
+			], "edges": [
+				{"id": "e1", "source_id": "start", "target_id": "fee"},
+				{"id": "e2", "source_id": "fee", "target_id": "form"},
+				{"id": "e3", "source_id": "form", "target_id": "end"}
+			]}`,
+			wantPaths: []string{"workflow_template_v2[wf-2].nodes[1]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := planMigration(from, workflowTemplateV2(t, "wf-2", tt.definition), tt.mapping)
+			var invalid *TemplateValidationError
+			require.True(t, errors.As(err, &invalid), "error = %v, want a TemplateValidationError", err)
+			paths := make([]string, 0, len(invalid.Issues))
+			for _, issue := range invalid.Issues {
+				paths = append(paths, issue.Path)
+			}
+			assert.Equal(t, tt.wantPaths, paths)
+		})
+	}
+}
+
+func TestWorkflowInstanceService_MigrateWorkflows(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	expectTemplates := func(mock sqlmock.Sqlmock) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "workflow_template_v2" WHERE id = \$1`).
+			WithArgs("wf-1", 1).
+			WillReturnRows(sqlmock.NewRows(workflowTemplateV2Columns).
+				AddRow("wf-1", "Export", "1", feeDefinition, now, now, "PUBLISHED", nil, now, nil))
+		mock.ExpectQuery(`SELECT \* FROM "workflow_template_v2" WHERE id = \$1`).
+			WithArgs("wf-2", 1).
+			WillReturnRows(sqlmock.NewRows(workflowTemplateV2Columns).
+				AddRow("wf-2", "Export", "2", correctedFeeDefinition, now, now, "PUBLISHED", "wf-1", now, nil))
+		mock.ExpectQuery(`SELECT \* FROM "workflows" WHERE workflow_template_id = \$1 AND status = \$2 ORDER BY id FOR UPDATE`).
+			WithArgs("wf-1", model.WorkflowStatusInProgress).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "workflow_template_id", "workflow_template_version"}).
+				AddRow("consignment-a", "IN_PROGRESS", "wf-1", "1").
+				AddRow("consignment-b", "IN_PROGRESS", "wf-1", "1"))
+	}
+	newService := func(t *testing.T) (*WorkflowInstanceService, sqlmock.Sqlmock) {
+		db, sqlMock := setupTestDB(t)
+		wm := new(MockWMV2)
+		wm.On("GetStatus", mock.Anything, "consignment-a").Return(&workflowManagerV2.WorkflowInstance{NodeInfo: []workflowManagerV2.NodeInfo{
+			{ID: "fee", Type: workflowManagerV2.NodeTypeTask, TaskTemplateID: "nt-fee-old", Status: workflowManagerV2.NodeStatusNotStarted},
+		}}, nil)
+		wm.On("GetStatus", mock.Anything, "consignment-b").Return(&workflowManagerV2.WorkflowInstance{NodeInfo: []workflowManagerV2.NodeInfo{
+			{ID: "fee", Type: workflowManagerV2.NodeTypeTask, TaskTemplateID: "nt-fee-old", Status: workflowManagerV2.NodeStatusRunning},
+		}}, nil)
+		s := NewWorkflowInstanceService(db, nil)
+		require.NoError(t, s.RegisterWorkflowManager(wm))
+		return s, sqlMock
+	}
+	req := model.WorkflowMigrationRequest{
+		FromTemplateID: "wf-1",
+		ToTemplateID:   "wf-2",
+		NodeMapping:    map[string]string{"fee": "payment"},
+	}
+
+	t.Run("Dry Run", func(t *testing.T) {
+		s, sqlMock := newService(t)
+		expectTemplates(sqlMock)
+		sqlMock.ExpectCommit()
+
+		dryRun := req
+		dryRun.DryRun = true
+		result, err := s.MigrateWorkflows(ctx, dryRun, "admin-1")
+		require.NoError(t, err)
+		assert.Equal(t, "1", result.FromVersion)
+		assert.Equal(t, "2", result.ToVersion)
+		require.Len(t, result.Workflows, 2)
+		assert.Equal(t, model.WorkflowNodeMigrationReplace, result.Workflows[0].Nodes[0].Action)
+		assert.Equal(t, model.WorkflowNodeMigrationKeep, result.Workflows[1].Nodes[0].Action)
+		assert.Equal(t, "RUNNING", result.Workflows[1].Nodes[0].Status)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Repoints Workflows", func(t *testing.T) {
+		s, sqlMock := newService(t)
+		expectTemplates(sqlMock)
+		for _, id := range []string{"consignment-a", "consignment-b"} {
+			sqlMock.ExpectExec(`UPDATE "workflows" SET .* WHERE "id" = \$7`).
+				WithArgs(sqlmock.AnyArg(), `{"fee":"payment"}`, `{"fee":"nt-fee-new"}`, sqlmock.AnyArg(), "wf-2", "2", id).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		sqlMock.ExpectCommit()
+
+		result, err := s.MigrateWorkflows(ctx, req, "admin-1")
+		require.NoError(t, err)
+		assert.False(t, result.DryRun)
+		assert.Len(t, result.Workflows, 2)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("Workflow Not On Template", func(t *testing.T) {
+		s, sqlMock := newService(t)
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_template_v2"`).
+			WillReturnRows(sqlmock.NewRows(workflowTemplateV2Columns).
+				AddRow("wf-1", "Export", "1", feeDefinition, now, now, "PUBLISHED", nil, now, nil))
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflow_template_v2"`).
+			WillReturnRows(sqlmock.NewRows(workflowTemplateV2Columns).
+				AddRow("wf-2", "Export", "2", correctedFeeDefinition, now, now, "PUBLISHED", "wf-1", now, nil))
+		sqlMock.ExpectQuery(`SELECT \* FROM "workflows" WHERE \(workflow_template_id = \$1 AND status = \$2\) AND id IN \(\$3\)`).
+			WithArgs("wf-1", model.WorkflowStatusInProgress, "consignment-z").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		sqlMock.ExpectRollback()
+
+		scoped := req
+		scoped.WorkflowIDs = []string{"consignment-z"}
+		_, err := s.MigrateWorkflows(ctx, scoped, "admin-1")
+		assert.ErrorIs(t, err, ErrInvalidMigration)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
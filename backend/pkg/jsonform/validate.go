@@ -0,0 +1,148 @@
+package jsonform
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// FieldError describes one constraint a submitted value failed.
+type FieldError struct {
+	// Field is the dot notation path of the value, as accepted by GetValueByPath.
+	// It is empty for the document root.
+	Field   string `json:"field"`
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
+}
+
+// schemaResource is the URL compiled schemas are registered under.
+const schemaResource = "form.schema.json"
+
+var (
+	compiledSchemas sync.Map // sha256 of the raw schema -> *jsonschema.Schema
+	errorPrinter    = message.NewPrinter(language.English)
+)
+
+// Validate validates data against a JSON Schema and returns one FieldError per violation.
+// Schemas without a $schema keyword are treated as draft 2020-12; draft-07 and 2019-09
+// are honoured when declared. Formats are always asserted. The returned error is only set
+// when the schema itself cannot be compiled.
+func Validate(schema json.RawMessage, data any) ([]FieldError, error) {
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round-trip through JSON so Go structs and maps are validated as the client sent them.
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data: %w", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data: %w", err)
+	}
+
+	err = compiled.Validate(instance)
+	if err == nil {
+		return nil, nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, fmt.Errorf("failed to validate data: %w", err)
+	}
+	return collectFieldErrors(validationErr, nil), nil
+}
+
+// CheckSchema reports whether a schema compiles, so it can be rejected before it is stored.
+func CheckSchema(schema json.RawMessage) error {
+	_, err := compileSchema(schema)
+	return err
+}
+
+// compileSchema compiles a schema once and caches it by content.
+func compileSchema(schema json.RawMessage) (*jsonschema.Schema, error) {
+	key := sha256.Sum256(schema)
+	if cached, ok := compiledSchemas.Load(key); ok {
+		return cached.(*jsonschema.Schema), nil
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+	c.AssertFormat()
+	// Form schemas are self-contained; $ref may only point inside the document.
+	c.UseLoader(jsonschema.SchemeURLLoader{})
+	if err := c.AddResource(schemaResource, doc); err != nil {
+		return nil, fmt.Errorf("failed to add schema: %w", err)
+	}
+	compiled, err := c.Compile(schemaResource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema: %w", err)
+	}
+
+	compiledSchemas.Store(key, compiled)
+	return compiled, nil
+}
+
+// collectFieldErrors flattens the leaves of a validation error tree. A missing required
+// property is reported against the property itself rather than its parent object.
+func collectFieldErrors(err *jsonschema.ValidationError, out []FieldError) []FieldError {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			out = collectFieldErrors(cause, out)
+		}
+		return out
+	}
+
+	if required, ok := err.ErrorKind.(*kind.Required); ok {
+		for _, name := range required.Missing {
+			out = append(out, FieldError{
+				Field:   fieldPath(append(append([]string(nil), err.InstanceLocation...), name)),
+				Keyword: "required",
+				Message: "is required",
+			})
+		}
+		return out
+	}
+
+	keywordPath := err.ErrorKind.KeywordPath()
+	keyword := ""
+	if len(keywordPath) > 0 {
+		keyword = keywordPath[len(keywordPath)-1]
+	}
+	return append(out, FieldError{
+		Field:   fieldPath(err.InstanceLocation),
+		Keyword: keyword,
+		Message: err.ErrorKind.LocalizedString(errorPrinter),
+	})
+}
+
+// fieldPath converts instance location tokens to dot notation, e.g. items[0].name.
+func fieldPath(tokens []string) string {
+	var b strings.Builder
+	for i, token := range tokens {
+		if _, err := strconv.Atoi(token); err == nil && i > 0 {
+			b.WriteString("[" + token + "]")
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(token)
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package jsonform
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const consignmentSchema = `{
+	"type": "object",
+	"required": ["exporter", "hsCode"],
+	"properties": {
+		"exporter": {"type": "string", "minLength": 3, "x-globalContext": {"writeTo": "exporter"}},
+		"email": {"type": "string", "format": "email"},
+		"hsCode": {"type": "string", "pattern": "^[0-9]{6}$"},
+		"mode": {"enum": ["SEA", "AIR"]},
+		"vessel": {"type": "string"},
+		"items": {"type": "array", "items": {"$ref": "#/$defs/item"}}
+	},
+	"if": {"properties": {"mode": {"const": "SEA"}}, "required": ["mode"]},
+	"then": {"required": ["vessel"]},
+	"$defs": {
+		"item": {
+			"type": "object",
+			"required": ["quantity"],
+			"properties": {"quantity": {"type": "number", "minimum": 1}}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		data   map[string]any
+		want   []FieldError
+	}{
+		{
+			name:   "Valid data",
+			schema: consignmentSchema,
+			data: map[string]any{
+				"exporter": "Organic Farms",
+				"email":    "exports@example.com",
+				"hsCode":   "090240",
+				"mode":     "AIR",
+				"items":    []any{map[string]any{"quantity": 2}},
+			},
+		},
+		{
+			name:   "Missing required fields",
+			schema: consignmentSchema,
+			data:   map[string]any{},
+			want: []FieldError{
+				{Field: "exporter", Keyword: "required", Message: "is required"},
+				{Field: "hsCode", Keyword: "required", Message: "is required"},
+			},
+		},
+		{
+			name:   "Format, pattern and enum",
+			schema: consignmentSchema,
+			data:   map[string]any{"exporter": "Organic Farms", "hsCode": "09.02", "email": "not-an-email", "mode": "RAIL"},
+			want: []FieldError{
+				{Field: "email", Keyword: "format"},
+				{Field: "hsCode", Keyword: "pattern"},
+				{Field: "mode", Keyword: "enum"},
+			},
+		},
+		{
+			name:   "Conditional requirement",
+			schema: consignmentSchema,
+			data:   map[string]any{"exporter": "Organic Farms", "hsCode": "090240", "mode": "SEA"},
+			want:   []FieldError{{Field: "vessel", Keyword: "required", Message: "is required"}},
+		},
+		{
+			name:   "Referenced array item schema",
+			schema: consignmentSchema,
+			data: map[string]any{
+				"exporter": "Organic Farms",
+				"hsCode":   "090240",
+				"items":    []any{map[string]any{"quantity": 2}, map[string]any{"quantity": 0}},
+			},
+			want: []FieldError{{Field: "items[1].quantity", Keyword: "minimum"}},
+		},
+		{
+			name:   "Draft-07 schema",
+			schema: `{"$schema": "http://json-schema.org/draft-07/schema#", "properties": {"port": {"type": "string", "format": "ipv4"}}}`,
+			data:   map[string]any{"port": "999.1.1.1"},
+			want:   []FieldError{{Field: "port", Keyword: "format"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Validate(json.RawMessage(tt.schema), tt.data)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Validate() = %+v, want %d errors", got, len(tt.want))
+			}
+			byField := make(map[string]FieldError, len(got))
+			for _, e := range got {
+				if e.Message == "" {
+					t.Errorf("Validate() returned %+v without a message", e)
+				}
+				byField[e.Field] = e
+			}
+			for _, want := range tt.want {
+				e, ok := byField[want.Field]
+				if !ok || e.Keyword != want.Keyword || (want.Message != "" && e.Message != want.Message) {
+					t.Errorf("Validate() = %+v, want %+v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_RejectsExternalRefs(t *testing.T) {
+	schema := json.RawMessage(`{"properties": {"name": {"$ref": "file:///etc/passwd"}}}`)
+	if _, err := Validate(schema, map[string]any{"name": "x"}); err == nil {
+		t.Fatal("Validate() expected an error for a schema referencing a file")
+	}
+}
+
+func TestFieldPath(t *testing.T) {
+	got := []string{
+		fieldPath(nil),
+		fieldPath([]string{"exporter"}),
+		fieldPath([]string{"items", "0", "quantity"}),
+	}
+	want := []string{"", "exporter", "items[0].quantity"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fieldPath() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+// Command review-log-verify walks the OGA review log of every task and checks that each
+// application's reviewer response and feedback rounds still match it. It reads the same
+// environment as the OGA server and exits with status 1 at the first broken link.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/OpenNSW/nsw/oga/internal"
+)
+
+func main() {
+	cfg, err := internal.LoadConfig()
+	if err != nil {
+		log.Fatalf("FATAL: failed to load configuration: %v", err)
+	}
+
+	store, err := internal.NewApplicationStore(cfg)
+	if err != nil {
+		log.Fatalf("failed to create application store: %v", err)
+	}
+	report, err := store.VerifyReviewLog(context.Background())
+	if closeErr := store.Close(); closeErr != nil {
+		log.Printf("failed to close application store: %v", closeErr)
+	}
+	if err != nil {
+		log.Fatalf("failed to verify review log: %v", err)
+	}
+
+	fmt.Printf("checked %d review log entries of %d tasks\n", report.Entries, report.Tasks)
+	if report.Break != nil {
+		fmt.Printf("BROKEN at task %s, entry %d: %s\n", report.Break.TaskID, report.Break.EntryID, report.Break.Reason)
+		os.Exit(1)
+	}
+	fmt.Println("review log intact")
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Review log entry kinds.
+const (
+	ReviewLogKindReview   = "review"   // A verdict; ContentHash covers ApplicationRecord.ReviewerResponse
+	ReviewLogKindFeedback = "feedback" // A feedback round; ContentHash covers the feedback.Entry
+	ReviewLogKindReset    = "reset"    // The application was re-injected, discarding earlier verdicts and rounds; ContentHash covers the new data
+)
+
+// ReviewLogEntry records one reviewer action on an application. ReviewerResponse and
+// OGAFeedbackHistory on ApplicationRecord are overwritten in place, so every change to them
+// is also appended here, chained to the previous entry of the same task: EntryHash covers
+// PrevHash and the entry's contents, so an edited or removed entry breaks the chain and an
+// edited application record no longer matches its latest entry. VerifyReviewLog checks both.
+type ReviewLogEntry struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement"`
+	TaskID      string    `gorm:"type:text;index;not null"`
+	WorkflowID  string    `gorm:"type:text;not null"`
+	Kind        string    `gorm:"type:varchar(20);not null"`
+	Round       int       `gorm:"not null;default:0"` // Feedback round; 0 for verdicts
+	Status      string    `gorm:"type:varchar(50);not null"`
+	ContentHash string    `gorm:"type:varchar(64);not null"`
+	CreatedAt   time.Time `gorm:"not null"`
+	PrevHash    string    `gorm:"type:varchar(64);not null"`
+	EntryHash   string    `gorm:"type:varchar(64);not null"`
+}
+
+// TableName returns the table name for ReviewLogEntry
+func (ReviewLogEntry) TableName() string {
+	return "review_log"
+}
+
+// hashJSON returns the hex SHA-256 of the JSON encoding of v.
+func hashJSON(v any) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// computeHash returns the hash linking e to the entry hashed e.PrevHash.
+func (e *ReviewLogEntry) computeHash() (string, error) {
+	return hashJSON(struct {
+		PrevHash    string `json:"prevHash"`
+		TaskID      string `json:"taskId"`
+		WorkflowID  string `json:"workflowId"`
+		Kind        string `json:"kind"`
+		Round       int    `json:"round"`
+		Status      string `json:"status"`
+		ContentHash string `json:"contentHash"`
+		CreatedAt   string `json:"createdAt"`
+	}{e.PrevHash, e.TaskID, e.WorkflowID, e.Kind, e.Round, e.Status, e.ContentHash, e.CreatedAt.UTC().Format(time.RFC3339Nano)})
+}
+
+// appendReviewLog links entry to the task's chain and inserts it within tx. The caller
+// holds a lock on the application row, which serializes appends per task.
+func appendReviewLog(tx *gorm.DB, entry *ReviewLogEntry, content any) error {
+	var err error
+	if entry.ContentHash, err = hashJSON(content); err != nil {
+		return fmt.Errorf("failed to hash review log content: %w", err)
+	}
+	// Stored timestamps keep microseconds at most.
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	var prev ReviewLogEntry
+	err = tx.Where("task_id = ?", entry.TaskID).Order("id DESC").Take(&prev).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to read review log: %w", err)
+	}
+	entry.PrevHash = prev.EntryHash
+	if entry.EntryHash, err = entry.computeHash(); err != nil {
+		return fmt.Errorf("failed to hash review log entry: %w", err)
+	}
+	return tx.Create(entry).Error
+}
+
+// lockApplication loads the application for update within tx.
+func lockApplication(tx *gorm.DB, taskID string) (*ApplicationRecord, error) {
+	var app ApplicationRecord
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&app, "task_id = ?", taskID).Error; err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// ReviewLogBreak describes the first broken link found by VerifyReviewLog.
+type ReviewLogBreak struct {
+	TaskID  string `json:"taskId"`
+	EntryID uint64 `json:"entryId,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// ReviewLogReport is the outcome of VerifyReviewLog.
+type ReviewLogReport struct {
+	Tasks   int             `json:"tasks"`   // Tasks whose chains were checked
+	Entries int             `json:"entries"` // Entries checked
+	Break   *ReviewLogBreak `json:"break,omitempty"`
+}
+
+// VerifyReviewLog walks the review log of every task, recomputing each entry hash and link,
+// and checks that each application's ReviewerResponse and feedback rounds still match the
+// entries that recorded them. It stops at the first broken link; the error is reserved for
+// failures to read the database. Applications deleted since are not cross-checked.
+func (s *ApplicationStore) VerifyReviewLog(ctx context.Context) (*ReviewLogReport, error) {
+	db := s.db.WithContext(ctx)
+	var taskIDs []string
+	if err := db.Model(&ReviewLogEntry{}).Distinct("task_id").Order("task_id").Pluck("task_id", &taskIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list review log tasks: %w", err)
+	}
+
+	report := &ReviewLogReport{}
+	for _, taskID := range taskIDs {
+		var entries []ReviewLogEntry
+		if err := db.Where("task_id = ?", taskID).Order("id").Find(&entries).Error; err != nil {
+			return nil, fmt.Errorf("failed to load review log of task %s: %w", taskID, err)
+		}
+		report.Tasks++
+
+		var lastReview *ReviewLogEntry
+		rounds := make(map[int]*ReviewLogEntry)
+		since := entries[0].CreatedAt
+		prevHash := ""
+		for i := range entries {
+			entry := &entries[i]
+			if entry.PrevHash != prevHash {
+				report.Break = &ReviewLogBreak{TaskID: taskID, EntryID: entry.ID, Reason: "prev hash does not match the preceding entry; an entry was removed or reordered"}
+				return report, nil
+			}
+			hash, err := entry.computeHash()
+			if err != nil {
+				return nil, err
+			}
+			if hash != entry.EntryHash {
+				report.Break = &ReviewLogBreak{TaskID: taskID, EntryID: entry.ID, Reason: "entry contents do not match its hash; the entry was edited"}
+				return report, nil
+			}
+			switch entry.Kind {
+			case ReviewLogKindReview:
+				lastReview = entry
+			case ReviewLogKindFeedback:
+				rounds[entry.Round] = entry
+			case ReviewLogKindReset:
+				lastReview = nil
+				rounds = make(map[int]*ReviewLogEntry)
+				since = entry.CreatedAt
+			}
+			prevHash = entry.EntryHash
+			report.Entries++
+		}
+
+		if brk, err := s.crossCheckApplication(ctx, taskID, lastReview, rounds, since); err != nil || brk != nil {
+			report.Break = brk
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// crossCheckApplication compares the stored application with the latest verdict and the
+// feedback rounds its review log recorded since the log began or the application was last
+// reset. Rounds given before since predate the review log and cannot be checked.
+func (s *ApplicationStore) crossCheckApplication(ctx context.Context, taskID string, lastReview *ReviewLogEntry, rounds map[int]*ReviewLogEntry, since time.Time) (*ReviewLogBreak, error) {
+	var app ApplicationRecord
+	err := s.db.WithContext(ctx).First(&app, "task_id = ?", taskID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load application %s: %w", taskID, err)
+	}
+
+	if lastReview != nil {
+		hash, err := hashJSON(map[string]any(app.ReviewerResponse))
+		if err != nil {
+			return nil, err
+		}
+		if hash != lastReview.ContentHash {
+			return &ReviewLogBreak{TaskID: taskID, EntryID: lastReview.ID, Reason: "reviewer response differs from the one recorded"}, nil
+		}
+	}
+
+	stored := make(map[int]bool, len(app.OGAFeedbackHistory))
+	for _, round := range app.OGAFeedbackHistory {
+		stored[round.Round] = true
+		entry, ok := rounds[round.Round]
+		if !ok {
+			if round.Timestamp.Before(since) {
+				continue
+			}
+			return &ReviewLogBreak{TaskID: taskID, Reason: fmt.Sprintf("feedback round %d was not recorded", round.Round)}, nil
+		}
+		hash, err := hashJSON(round)
+		if err != nil {
+			return nil, err
+		}
+		if hash != entry.ContentHash {
+			return &ReviewLogBreak{TaskID: taskID, EntryID: entry.ID, Reason: fmt.Sprintf("feedback round %d differs from the one recorded", round.Round)}, nil
+		}
+	}
+	for round, entry := range rounds {
+		if !stored[round] {
+			return &ReviewLogBreak{TaskID: taskID, EntryID: entry.ID, Reason: fmt.Sprintf("recorded feedback round %d is missing from the application", round)}, nil
+		}
+	}
+	return nil, nil
+}
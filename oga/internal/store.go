@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -81,16 +82,35 @@ func NewApplicationStore(cfg Config) (*ApplicationStore, error) {
 	}
 
 	// Auto-migrate the schema
-	if err := db.AutoMigrate(&ApplicationRecord{}); err != nil {
+	if err := db.AutoMigrate(&ApplicationRecord{}, &ReviewLogEntry{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	return &ApplicationStore{db: db}, nil
 }
 
-// CreateOrUpdate creates or updates an application record
+// CreateOrUpdate creates or updates an application record. Replacing an existing record
+// discards its verdict and feedback rounds, which is recorded in the review log.
 func (s *ApplicationStore) CreateOrUpdate(app *ApplicationRecord) error {
-	return s.db.Save(app).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		_, err := lockApplication(tx, app.TaskID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		existed := err == nil
+		if err := tx.Save(app).Error; err != nil {
+			return err
+		}
+		if !existed {
+			return nil
+		}
+		return appendReviewLog(tx, &ReviewLogEntry{
+			TaskID:     app.TaskID,
+			WorkflowID: app.WorkflowID,
+			Kind:       ReviewLogKindReset,
+			Status:     app.Status,
+		}, map[string]any(app.Data))
+	})
 }
 
 // GetByTaskID retrieves an application by task ID
@@ -177,6 +197,7 @@ func (s *ApplicationStore) ListWorkflows(ctx context.Context, search string, off
 	return summaries, total, nil
 }
 
+// UpdateStatus records the reviewer's verdict on an application and appends it to the review log.
 func (s *ApplicationStore) UpdateStatus(taskID string, status string, reviewerResponse map[string]any) error {
 	now := time.Now()
 
@@ -186,29 +207,39 @@ func (s *ApplicationStore) UpdateStatus(taskID string, status string, reviewerRe
 		return fmt.Errorf("failed to marshal reviewer response: %w", err)
 	}
 
-	result := s.db.Model(&ApplicationRecord{}).
-		Where("task_id = ?", taskID).
-		Updates(map[string]any{
-			"status":            status,
-			"reviewed_at":       now,
-			"updated_at":        now,
-			"reviewer_response": jsonResponse,
-		})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("application with task_id %s not found", taskID)
-	}
-	return nil
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		app, err := lockApplication(tx, taskID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("application with task_id %s not found", taskID)
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&ApplicationRecord{}).
+			Where("task_id = ?", taskID).
+			Updates(map[string]any{
+				"status":            status,
+				"reviewed_at":       now,
+				"updated_at":        now,
+				"reviewer_response": jsonResponse,
+			}).Error; err != nil {
+			return err
+		}
+		return appendReviewLog(tx, &ReviewLogEntry{
+			TaskID:     taskID,
+			WorkflowID: app.WorkflowID,
+			Kind:       ReviewLogKindReview,
+			Status:     status,
+		}, reviewerResponse)
+	})
 }
 
-// AppendFeedback appends a feedback entry to the application's history and sets
-// the status to FEEDBACK_REQUESTED.
+// AppendFeedback appends a feedback entry to the application's history and to the review
+// log, and sets the status to FEEDBACK_REQUESTED.
 func (s *ApplicationStore) AppendFeedback(taskID string, entry feedback.Entry) error {
 	return s.db.Transaction(func(tx *gorm.DB) error {
-		var app ApplicationRecord
-		if err := tx.First(&app, "task_id = ?", taskID).Error; err != nil {
+		app, err := lockApplication(tx, taskID)
+		if err != nil {
 			return err
 		}
 		updated := append(app.OGAFeedbackHistory, entry)
@@ -216,13 +247,22 @@ func (s *ApplicationStore) AppendFeedback(taskID string, entry feedback.Entry) e
 		if err != nil {
 			return fmt.Errorf("failed to marshal feedback history: %w", err)
 		}
-		return tx.Model(&ApplicationRecord{}).
+		if err := tx.Model(&ApplicationRecord{}).
 			Where("task_id = ?", taskID).
 			Updates(map[string]any{
 				"oga_feedback_history": string(updatedJSON),
 				"status":               "FEEDBACK_REQUESTED",
 				"updated_at":           time.Now(),
-			}).Error
+			}).Error; err != nil {
+			return err
+		}
+		return appendReviewLog(tx, &ReviewLogEntry{
+			TaskID:     taskID,
+			WorkflowID: app.WorkflowID,
+			Kind:       ReviewLogKindFeedback,
+			Round:      entry.Round,
+			Status:     "FEEDBACK_REQUESTED",
+		}, entry)
 	})
 }
 
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/OpenNSW/nsw/oga/internal/database"
 	"github.com/OpenNSW/nsw/oga/internal/feedback"
@@ -36,8 +38,8 @@ func newTestStore(t *testing.T) *ApplicationStore {
 
 	// For persistent backends, clean the table before each test.
 	if cfg.DB.Driver != "sqlite" || cfg.DB.Path != ":memory:" {
-		if err := store.db.Exec("TRUNCATE TABLE applications").Error; err != nil {
-			t.Fatalf("failed to truncate applications table: %v", err)
+		if err := store.db.Exec("TRUNCATE TABLE applications, review_log").Error; err != nil {
+			t.Fatalf("failed to truncate tables: %v", err)
 		}
 	}
 
@@ -459,3 +461,93 @@ func TestApplicationStore_UpdateDataAndResetStatus(t *testing.T) {
 		t.Errorf("expected updated data, got %v", app.Data)
 	}
 }
+
+// ---------- 7. Functional Testing: Review Log ----------
+
+// reviewedRecord seeds an application with a verdict and two feedback rounds.
+func reviewedRecord(t *testing.T, store *ApplicationStore, taskID string) {
+	t.Helper()
+	seedRecord(t, store, taskID, nil)
+	for round := 1; round <= 2; round++ {
+		entry := feedback.Entry{Content: map[string]any{"comment": fmt.Sprintf("round %d", round)}, Timestamp: time.Now().UTC(), Round: round}
+		if err := store.AppendFeedback(taskID, entry); err != nil {
+			t.Fatalf("AppendFeedback round %d failed: %v", round, err)
+		}
+	}
+	if err := store.UpdateStatus(taskID, "APPROVED", map[string]any{"decision": "APPROVED", "score": 4.5}); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+}
+
+func TestApplicationStore_ReviewLog_Chained(t *testing.T) {
+	store := newTestStore(t)
+	reviewedRecord(t, store, "task-log-1")
+
+	var entries []ReviewLogEntry
+	if err := store.db.Order("id").Find(&entries, "task_id = ?", "task-log-1").Error; err != nil {
+		t.Fatalf("failed to load review log: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 review log entries, got %d", len(entries))
+	}
+	if entries[0].PrevHash != "" || entries[1].PrevHash != entries[0].EntryHash || entries[2].PrevHash != entries[1].EntryHash {
+		t.Error("expected each entry to link to the one before it")
+	}
+	if entries[1].Kind != ReviewLogKindFeedback || entries[1].Round != 2 || entries[2].Kind != ReviewLogKindReview || entries[2].Status != "APPROVED" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+
+	report, err := store.VerifyReviewLog(context.Background())
+	if err != nil {
+		t.Fatalf("VerifyReviewLog failed: %v", err)
+	}
+	if report.Break != nil || report.Tasks != 1 || report.Entries != 3 {
+		t.Errorf("expected an intact log of 3 entries, got %+v (break %+v)", report, report.Break)
+	}
+}
+
+func TestApplicationStore_ReviewLog_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper string
+		want   string
+	}{
+		{"edited reviewer response", `UPDATE applications SET reviewer_response = '{"decision":"REJECTED","score":4.5}'`, "reviewer response differs"},
+		{"edited feedback round", `UPDATE applications SET oga_feedback_history = '[]'`, "missing from the application"},
+		{"edited log entry", `UPDATE review_log SET status = 'REJECTED' WHERE kind = 'review'`, "entry was edited"},
+		{"removed log entry", `DELETE FROM review_log WHERE round = 1`, "prev hash does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			reviewedRecord(t, store, "task-log-2")
+			if err := store.db.Exec(tt.tamper).Error; err != nil {
+				t.Fatalf("failed to tamper: %v", err)
+			}
+
+			report, err := store.VerifyReviewLog(context.Background())
+			if err != nil {
+				t.Fatalf("VerifyReviewLog failed: %v", err)
+			}
+			if report.Break == nil || !strings.Contains(report.Break.Reason, tt.want) {
+				t.Errorf("expected break %q, got %+v", tt.want, report.Break)
+			}
+		})
+	}
+}
+
+func TestApplicationStore_ReviewLog_Reinjection(t *testing.T) {
+	store := newTestStore(t)
+	reviewedRecord(t, store, "task-log-3")
+
+	// Re-injecting the task replaces the record and discards its verdict and rounds.
+	seedRecord(t, store, "task-log-3", JSONB{"resubmitted": true})
+
+	report, err := store.VerifyReviewLog(context.Background())
+	if err != nil {
+		t.Fatalf("VerifyReviewLog failed: %v", err)
+	}
+	if report.Break != nil || report.Entries != 4 {
+		t.Errorf("expected an intact log of 4 entries, got %+v (break %+v)", report, report.Break)
+	}
+}